@@ -9,6 +9,7 @@ import (
 	"embed"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"html"
@@ -33,15 +34,20 @@ import (
 
 	"github.com/go-pdf/fpdf"
 
+	"fleet-navigator/internal/benchmark"
 	"fleet-navigator/internal/chat"
 	"fleet-navigator/internal/custommodel"
 	"fleet-navigator/internal/experte"
+	"fleet-navigator/internal/gguf"
 	"fleet-navigator/internal/hardware"
+	"fleet-navigator/internal/integrity"
 	"fleet-navigator/internal/llamaserver"
 	"fleet-navigator/internal/llm"
 	"fleet-navigator/internal/middleware"
 	"fleet-navigator/internal/models"
+	"fleet-navigator/internal/observer"
 	"fleet-navigator/internal/prompts"
+	"fleet-navigator/internal/templates"
 	"fleet-navigator/internal/search"
 	"fleet-navigator/internal/security"
 	"fleet-navigator/internal/settings"
@@ -101,17 +107,22 @@ type App struct {
 	toolRegistry     *tools.Registry     // Tool Registry (WebSearch, FileSearch, etc.)
 	visionService    *vision.Service     // LLaVA Vision Service für Bildanalyse
 	promptsService      *prompts.Service      // System Prompts Service
+	templatesService    *templates.Service    // Prompt-Vorlagen mit Platzhaltern
 	settingsService     *settings.Service     // App Settings Service
 	userService         *user.Service         // User & Auth Service
 	customModelService  *custommodel.Service  // Custom Models Service
 	llamaServer         *llamaserver.Server       // llama.cpp Server Manager (Chat)
 	visionServer        *llamaserver.VisionServer // Separater Vision-Server (On-Demand)
+	modelPool           *llamaserver.ModelPool    // Weitere Modelle warm halten (Experten, Coder, E-Mail)
 	selectedModel       string                    // Aktuell ausgewähltes Modell für UI
 	hardwareMonitor     *hardware.Monitor         // Hardware Monitor (CPU, GPU, RAM)
 	searchService       *search.Service       // Web Search Service (Brave, SearXNG, DuckDuckGo)
 	voiceService        *voice.Service        // Voice Service (Whisper STT, Piper TTS)
 	setupService        *setup.Service        // Setup Wizard Service
 	setupHandler        *setup.APIHandler     // Setup API Handler
+	benchmarkService    *benchmark.Service    // Modell-Benchmarks und Leistungsdatenbank
+	observerService     *observer.Service     // Observer: Finanz- und Wirtschaftsdaten, Alarme
+	generations         sync.Map              // Laufende Chat-Generierungen: requestID -> context.CancelFunc
 }
 
 func main() {
@@ -183,6 +194,13 @@ func NewApp(config *Config) (*App, error) {
 		return nil, fmt.Errorf("ChatStore Fehler: %w", err)
 	}
 
+	// Verwaiste Anhänge (gelöschte Chats/Nachrichten) im Hintergrund aufräumen
+	go func() {
+		if _, err := chatStore.GarbageCollectAttachments(); err != nil {
+			log.Printf("Anhang-GC fehlgeschlagen: %v", err)
+		}
+	}()
+
 	// Chat Service (nur für Ollama-Provider relevant)
 	chatConfig := chat.LegacyConfig{
 		BaseURL: config.OllamaURL,
@@ -225,8 +243,13 @@ Bei rechtlichen Fragen weise darauf hin, dass du nur allgemeine Informationen ge
 	}
 	modelService := llm.NewModelService(modelServiceConfig)
 
+	// Web Search Service (Backend-Kette aus den Such-Einstellungen) - Chat, Tools und Mates nutzen dieselbe Instanz
+	searchService := search.NewService()
+	ws.SetSearchService(searchService)
+
 	// Tool Registry (WebSearch, FileSearch, WebFetch)
 	toolRegistry := tools.NewRegistry()
+	toolRegistry.SetWebSearchService(searchService)
 	log.Printf("Tool Registry initialisiert mit %d Tools", len(toolRegistry.List()))
 
 	// Vision Service (LLaVA) - nur bei Ollama prüfen
@@ -256,6 +279,16 @@ Bei rechtlichen Fragen weise darauf hin, dass du nur allgemeine Informationen ge
 		log.Printf("WARNUNG: System-Prompts konnten nicht initialisiert werden: %v", err)
 	}
 
+	// Prompt-Vorlagen Service
+	templatesRepo, err := templates.NewRepository(config.DataDir)
+	if err != nil {
+		return nil, fmt.Errorf("TemplatesRepository Fehler: %w", err)
+	}
+	templatesService := templates.NewService(templatesRepo)
+	if err := templatesService.InitializeDefaults(); err != nil {
+		log.Printf("WARNUNG: Prompt-Vorlagen konnten nicht initialisiert werden: %v", err)
+	}
+
 	// User & Auth Service
 	userRepo, err := user.NewRepository(config.DataDir)
 	if err != nil {
@@ -346,6 +379,15 @@ Bei rechtlichen Fragen weise darauf hin, dass du nur allgemeine Informationen ge
 
 	llamaSrv := llamaserver.NewServer(llamaConfig)
 
+	// LoRA-Adapter gegen GGUF-Dateien im Modell-Verzeichnis prüfen
+	customModelService.SetModelPathResolver(func(name string) string {
+		path, err := llamaSrv.FindModelByName(name)
+		if err != nil {
+			return ""
+		}
+		return path
+	})
+
 	// Unvollständige Downloads bereinigen (abgebrochene Downloads löschen)
 	cleanedCount := llamaSrv.CleanupIncompleteDownloads()
 	if cleanedCount > 0 {
@@ -415,6 +457,39 @@ Bei rechtlichen Fragen weise darauf hin, dass du nur allgemeine Informationen ge
 	setupSvc := setup.NewService(config.DataDir)
 	setupHandler := setup.NewAPIHandler(setupSvc)
 
+	// Modell-Benchmarks: Leistungsdatenbank dieses Rechners, fließt in die Modell-Empfehlungen ein
+	benchmarkRepo, err := benchmark.NewRepository(config.DataDir)
+	if err != nil {
+		return nil, fmt.Errorf("BenchmarkRepository Fehler: %w", err)
+	}
+	benchmarkSvc := benchmark.NewService(benchmarkRepo, llamaSrv)
+	setupSvc.SetPerformanceSource(func(modelID string) *setup.MeasuredPerformance {
+		res := benchmarkSvc.Performance(modelID)
+		if res == nil {
+			return nil
+		}
+		return &setup.MeasuredPerformance{
+			GenTPS:      res.GenTPS,
+			PromptTPS:   res.PromptTPS,
+			TTFTMs:      res.TTFTMs,
+			PeakVRAMMB:  res.PeakVRAMMB,
+			ContextSize: res.ContextSize,
+			MeasuredAt:  res.CreatedAt,
+		}
+	})
+
+	// Observer: Finanz- und Wirtschaftsdaten sammeln (eigene Datenbank observer.db)
+	observerSvc, err := observer.NewService(config.DataDir)
+	if err != nil {
+		return nil, fmt.Errorf("Observer Fehler: %w", err)
+	}
+	// Observer-Daten als Tools: Experten fragen gezielt die benötigten Reihen ab
+	for _, tool := range observer.NewTools(observerSvc) {
+		if err := toolRegistry.Register(tool); err != nil {
+			log.Printf("⚠️ Observer-Tool %s: %v", tool.Name(), err)
+		}
+	}
+
 	// Vision-Server Manager (On-Demand für Bildanalyse auf Port 2024)
 	visionServerConfig := llamaserver.DefaultVisionServerConfig(config.DataDir)
 	// Vision-Settings aus DB laden falls vorhanden
@@ -531,22 +606,52 @@ Bei rechtlichen Fragen weise darauf hin, dass du nur allgemeine Informationen ge
 		toolRegistry:     toolRegistry,
 		visionService:    visionService,
 		promptsService:   promptsService,
+		templatesService: templatesService,
 		settingsService:     settingsService,
 		userService:         userService,
 		customModelService:  customModelService,
 		llamaServer:         llamaSrv,
 		visionServer:        visionSrv,
+		modelPool:           llamaserver.NewModelPool(llamaSrv),
 		selectedModel:       config.OllamaModel,
 		hardwareMonitor:     hwMonitor,
-		searchService:       search.NewService(),
+		searchService:       searchService,
 		voiceService:        voice.NewService(config.DataDir),
 		setupService:        setupSvc,
 		setupHandler:        setupHandler,
+		benchmarkService:    benchmarkSvc,
+		observerService:     observerSvc,
+	}
+
+	// Multi-Query-Suche: Umformulierungen der Suchanfrage über das lokale Modell
+	searchService.SetQueryRewriter(app.searchQueryVariants)
+
+	// Observer-Alarme: Systemnachricht im festgelegten Chat bzw. Event an alle verbundenen Mates
+	observerSvc.OnAlertChat = func(chatID int64, text string) error {
+		chatObj, err := chatStore.GetChat(chatID)
+		if err != nil {
+			return err
+		}
+		if chatObj == nil {
+			return fmt.Errorf("Chat %d nicht gefunden", chatID)
+		}
+		_, err = chatStore.AddMessage(chatID, "SYSTEM", text, "observer", 0, nil, nil)
+		return err
+	}
+	observerSvc.OnAlertBroadcast = func(event observer.AlertEvent) {
+		ws.BroadcastJSON(map[string]interface{}{
+			"type": websocket.MsgEvent,
+			"payload": map[string]interface{}{
+				"event": "observer_alert",
+				"alert": event,
+			},
+		})
 	}
 
 	// Chat-Adapter mit Provider-Awareness konfigurieren
 	chatAdapter.SetProviderChecker(settingsService)
-	chatAdapter.SetLlamaServer(&llamaServerWrapper{server: llamaSrv})
+	chatAdapter.SetLlamaServer(&llamaServerWrapper{server: llamaSrv, pool: app.modelPool})
+	chatAdapter.SetMateModelResolver(app.mateModel)
 	log.Printf("Chat-Adapter konfiguriert mit Provider-Awareness (Settings + LlamaServer)")
 
 	// Voice Service initialisieren (Whisper STT + Piper TTS)
@@ -668,8 +773,15 @@ func (app *App) Run() {
 	mux.HandleFunc("/api/chat/all", app.handleChatAll)
 	mux.HandleFunc("/api/chat/history/", app.handleChatHistory)
 	mux.HandleFunc("/api/chat/send-stream", app.handleChatSendStream)
+	mux.HandleFunc("/api/chat/cancel/", app.handleChatCancel)
+	mux.HandleFunc("/api/chat/tags", app.handleChatTags)
+	mux.HandleFunc("/api/chat/tags/", app.handleChatTags)
 	mux.HandleFunc("/api/chat/", app.handleChatByID)
 
+	// Chat-Anhänge (Content-Addressed Blob-Speicher)
+	mux.HandleFunc("/api/attachments/gc", app.handleAttachmentsGC)
+	mux.HandleFunc("/api/attachments/", app.handleAttachmentByID)
+
 	// File Upload Endpoint
 	mux.HandleFunc("/api/files/upload", app.handleFileUpload)
 
@@ -736,15 +848,20 @@ func (app *App) Run() {
 	// GGUF Models Endpoints
 	mux.HandleFunc("/api/gguf-models", app.handleGgufModels)
 	mux.HandleFunc("/api/gguf-models/", app.handleGgufModelByID)
+	mux.HandleFunc("/api/lora-adapters", app.handleLoraAdapters)              // GET: Liste, POST: Adapter registrieren
+	mux.HandleFunc("/api/lora-adapters/active", app.handleLoraAdaptersActive) // GET/POST: Geladene Adapter und Stärken (ohne Neustart)
+	mux.HandleFunc("/api/lora-adapters/", app.handleLoraAdapterByID)
 
 	// Personal Info Endpoint (Frontend-Kompatibilität)
 	mux.HandleFunc("/api/personal-info", app.handlePersonalInfo)
 
 	// Templates Endpoints (Frontend-Kompatibilität)
 	mux.HandleFunc("/api/templates", app.handleTemplates)
+	mux.HandleFunc("/api/templates/", app.handleTemplateByID)
 
 	// Projects Endpoints (Frontend-Kompatibilität)
 	mux.HandleFunc("/api/projects", app.handleProjects)
+	mux.HandleFunc("/api/projects/", app.handleProjectByID)
 
 	// System Prompts Endpoints
 	mux.HandleFunc("/api/system-prompts", app.handleSystemPrompts)
@@ -775,9 +892,18 @@ func (app *App) Run() {
 	mux.HandleFunc("/api/llamaserver/restart", app.handleLlamaServerRestart)
 	mux.HandleFunc("/api/llamaserver/models", app.handleLlamaServerModels)
 	mux.HandleFunc("/api/llamaserver/models/recommended", app.handleLlamaServerModelsRecommended)
+	mux.HandleFunc("/api/llamaserver/models/verify", app.handleLlamaServerModelsVerify) // GET Prüfsummen-Katalog, POST installierte Modelle prüfen
 	mux.HandleFunc("/api/llamaserver/download", app.handleLlamaServerDownload)
 	mux.HandleFunc("/api/llamaserver/config", app.handleLlamaServerConfig)
 	mux.HandleFunc("/api/llamaserver/watchdog", app.handleLlamaServerWatchdog)
+	mux.HandleFunc("/api/llamaserver/queue", app.handleLlamaServerQueue) // GET Slot-Warteschlange (Metriken)
+	mux.HandleFunc("/api/llamaserver/pool", app.handleLlamaServerPool)   // GET Status, POST Konfiguration, DELETE ?model= Modell beenden
+	mux.HandleFunc("/api/llamaserver/draft", app.handleLlamaServerDraft)                    // GET Status + Kandidaten, POST Speculative Decoding konfigurieren
+	mux.HandleFunc("/api/llamaserver/draft/benchmark", app.handleLlamaServerDraftBenchmark) // POST A/B-Vergleich mit/ohne Draft-Modell
+	mux.HandleFunc("/api/llamaserver/slot-cache", app.handleLlamaServerSlotCache)           // GET gespeicherte Prompt-Caches, DELETE ?key= löschen
+	mux.HandleFunc("/api/llamaserver/benchmark", app.handleLlamaServerBenchmark)            // GET Fortschritt, POST Benchmark starten, DELETE abbrechen
+	mux.HandleFunc("/api/llamaserver/benchmark/results", app.handleLlamaServerBenchmarkResults) // GET ?model=&limit= Messungen dieses Rechners
+	mux.HandleFunc("/api/llamaserver/benchmark/compare", app.handleLlamaServerBenchmarkCompare) // GET letzte vs. vorige Messung je Modell und Context
 
 	// Context-Management
 	mux.HandleFunc("/api/llamaserver/context", app.handleLlamaServerContextChange)    // POST Context-Größe ändern (mit Neustart)
@@ -917,6 +1043,12 @@ func (app *App) Run() {
 	// Setup Wizard Endpoints
 	app.setupHandler.RegisterRoutes(mux)
 
+	// Observer Endpoints (Daten, Qualität, Alarme)
+	observer.NewHandlers(app.observerService).RegisterRoutes(mux)
+	if err := app.observerService.Start(); err != nil {
+		log.Printf("⚠️ Observer-Start fehlgeschlagen: %v", err)
+	}
+
 	// WebSocket Endpoint
 	mux.HandleFunc("/ws", app.wsServer.HandleWebSocket)
 	// Fleet-Mate WebSocket Endpoint (für Thunderbird Email-Mate Kompatibilität)
@@ -989,7 +1121,15 @@ func (app *App) Run() {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
+	// Observer beenden (Scheduler + Datenbank)
+	if app.observerService != nil {
+		app.observerService.Close()
+	}
+
 	// llama-server beenden falls läuft
+	if app.modelPool != nil {
+		app.modelPool.Close()
+	}
 	if app.llamaServer != nil {
 		log.Println("Beende llama-server...")
 		app.llamaServer.Stop()
@@ -1581,6 +1721,7 @@ func (app *App) handleExpertByID(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
+		app.clearExpertSlotCache(id) // System-Prompt kann sich geändert haben
 		writeJSON(w, expert)
 
 	case http.MethodDelete:
@@ -1588,6 +1729,7 @@ func (app *App) handleExpertByID(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
+		app.clearExpertSlotCache(id)
 		writeJSON(w, map[string]string{"status": "deleted"})
 
 	default:
@@ -1777,29 +1919,43 @@ func (app *App) handleChatNew(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var req struct {
-		Title string `json:"title"`
-		Model string `json:"model"`
+		Title     string `json:"title"`
+		Model     string `json:"model"`
+		ProjectID *int64 `json:"projectId"` // Optional: Chat direkt im Projekt anlegen
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		// Wenn kein Body, Default-Werte verwenden
-		req.Title = "Neuer Chat"
+		req.Title = ""
 		req.Model = app.selectedModel
 	}
 
-	if req.Title == "" {
-		req.Title = "Neuer Chat"
-	}
 	if req.Model == "" {
 		req.Model = app.selectedModel
 	}
 
-	chatObj, err := app.chatStore.CreateChat(req.Title, req.Model)
+	// Nur ein vom Client gesetzter Titel ist manuell - der Default wird
+	// nach dem ersten Austausch durch einen generierten Titel ersetzt
+	var chatObj *chat.Chat
+	var err error
+	if title := strings.TrimSpace(req.Title); title == "" || title == "Neuer Chat" {
+		chatObj, err = app.chatStore.CreateChatWithPlaceholderTitle("Neuer Chat", req.Model)
+	} else {
+		chatObj, err = app.chatStore.CreateChat(req.Title, req.Model)
+	}
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
+	if req.ProjectID != nil {
+		if err := app.chatStore.AssignChatToProject(chatObj.ID, req.ProjectID); err != nil {
+			log.Printf("⚠️ Chat %d konnte Projekt %d nicht zugeordnet werden: %v", chatObj.ID, *req.ProjectID, err)
+		} else if updated, err := app.chatStore.GetChat(chatObj.ID); err == nil && updated != nil {
+			chatObj = updated
+		}
+	}
+
 	w.WriteHeader(http.StatusCreated)
 	writeJSON(w, chatObj)
 }
@@ -1810,7 +1966,24 @@ func (app *App) handleChatAll(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	chats, err := app.chatStore.GetAllChats()
+	// Tag-Filter: ?tag=a&tag=b oder ?tags=a,b; ?match=all verlangt alle Tags (Default: any)
+	query := r.URL.Query()
+	var tagFilter []string
+	tagFilter = append(tagFilter, query["tag"]...)
+	if tagsParam := query.Get("tags"); tagsParam != "" {
+		tagFilter = append(tagFilter, strings.Split(tagsParam, ",")...)
+	}
+
+	filter := chat.ChatFilter{
+		Tags:     tagFilter,
+		MatchAll: query.Get("match") == "all",
+	}
+	// Projekt-Filter: ?projectId={id}
+	if pid, err := strconv.ParseInt(query.Get("projectId"), 10, 64); err == nil {
+		filter.ProjectID = &pid
+	}
+
+	chats, err := app.chatStore.GetChatsFiltered(filter)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -1819,6 +1992,38 @@ func (app *App) handleChatAll(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, chats)
 }
 
+// handleChatTags verwaltet die globale Tag-Liste
+// GET /api/chat/tags - Alle Tags mit Anzahl der Chats
+// DELETE /api/chat/tags/{id} - Tag überall entfernen
+func (app *App) handleChatTags(w http.ResponseWriter, r *http.Request) {
+	idStr := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, "/api/chat/tags"), "/")
+
+	switch r.Method {
+	case http.MethodGet:
+		tags, err := app.chatStore.ListTags()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, tags)
+
+	case http.MethodDelete:
+		id, err := strconv.ParseInt(idStr, 10, 64)
+		if err != nil {
+			http.Error(w, "Invalid tag ID", http.StatusBadRequest)
+			return
+		}
+		if err := app.chatStore.DeleteTag(id); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, map[string]string{"status": "deleted"})
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
 func (app *App) handleChatHistory(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -2011,6 +2216,53 @@ func (app *App) handleChatByID(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 
+	case "tags":
+		// GET /api/chat/{id}/tags - Tags des Chats
+		// PUT /api/chat/{id}/tags - Alle Tags ersetzen (manuelle Bearbeitung)
+		// POST /api/chat/{id}/tags - Tags hinzufügen
+		// DELETE /api/chat/{id}/tags/{name} - Tag entfernen
+		switch r.Method {
+		case http.MethodGet:
+			tags, err := app.chatStore.GetChatTags(id)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			writeJSON(w, tags)
+		case http.MethodPut, http.MethodPost:
+			var req struct {
+				Tags []string `json:"tags"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, "Invalid JSON", http.StatusBadRequest)
+				return
+			}
+			var tags []chat.Tag
+			if r.Method == http.MethodPut {
+				tags, err = app.chatStore.SetChatTags(id, req.Tags)
+			} else {
+				tags, err = app.chatStore.AddChatTags(id, req.Tags, chat.TagSourceManual)
+			}
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			writeJSON(w, tags)
+		case http.MethodDelete:
+			if len(parts) < 3 || parts[2] == "" {
+				http.Error(w, "Tag name is required", http.StatusBadRequest)
+				return
+			}
+			if err := app.chatStore.RemoveChatTag(id, parts[2]); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			writeJSON(w, map[string]string{"status": "deleted"})
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+		return
+
 	case "export":
 		// GET /api/chat/{id}/export
 		if r.Method == http.MethodGet {
@@ -2088,6 +2340,7 @@ func (app *App) handleChatSendStream(w http.ResponseWriter, r *http.Request) {
 		VisionChainEnabled   bool     `json:"visionChainEnabled"`   // Vision Chaining aktivieren
 		VisionModel          string   `json:"visionModel"`          // Vision-Modell für Chaining
 		ShowIntermediateOutput bool   `json:"showIntermediateOutput"` // Zwischenergebnisse anzeigen
+		ProjectID            *int64   `json:"projectId"`            // Optional: Neuen Chat in diesem Projekt anlegen
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -2114,7 +2367,9 @@ func (app *App) handleChatSendStream(w http.ResponseWriter, r *http.Request) {
 		if len(title) > 50 {
 			title = title[:50] + "..."
 		}
-		newChat, err := app.chatStore.CreateChat(title, model)
+		// Titel ist nur ein Platzhalter - nach dem ersten Austausch wird ein
+		// passender Titel vom lokalen Modell generiert (generateChatTitleAndTags)
+		newChat, err := app.chatStore.CreateChatWithPlaceholderTitle(title, model)
 		if err != nil {
 			log.Printf("Chat erstellen fehlgeschlagen: %v", err)
 			http.Error(w, "Fehler beim Erstellen des Chats", http.StatusInternalServerError)
@@ -2122,6 +2377,22 @@ func (app *App) handleChatSendStream(w http.ResponseWriter, r *http.Request) {
 		}
 		chatID = newChat.ID
 		log.Printf("Neuer Chat erstellt: ID=%d", chatID)
+
+		if req.ProjectID != nil {
+			if err := app.chatStore.AssignChatToProject(chatID, req.ProjectID); err != nil {
+				log.Printf("⚠️ Chat %d konnte Projekt %d nicht zugeordnet werden: %v", chatID, *req.ProjectID, err)
+			}
+		}
+	}
+
+	// Projekt des Chats: Standard-Experte, Projekt-Prompt und angeheftete Dokumente
+	project, projectErr := app.chatStore.GetChatProject(chatID)
+	if projectErr != nil {
+		log.Printf("⚠️ Projekt für Chat %d konnte nicht geladen werden: %v", chatID, projectErr)
+	}
+	if project != nil && project.DefaultExpertID != nil && (req.ExpertID == nil || *req.ExpertID <= 0) {
+		req.ExpertID = project.DefaultExpertID
+		log.Printf("📁 Projekt '%s': Standard-Experte %d verwendet", project.Name, *project.DefaultExpertID)
 	}
 
 	// System-Prompt: Vom Frontend gesendeten verwenden, oder Default aus DB laden
@@ -2167,7 +2438,7 @@ func (app *App) handleChatSendStream(w http.ResponseWriter, r *http.Request) {
 
 		// Suche mit Content-Fetching durchführen
 		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-		searchResults, err := app.searchService.Search(ctx, optimizedQuery, search.SearchOptions{
+		searchResults, subQueries, err := app.searchService.SearchWithReport(ctx, optimizedQuery, search.SearchOptions{
 			MaxResults:       5,
 			FetchFullContent: true,  // WICHTIG: Seiteninhalte lesen!
 			MaxContentLength: 4000,  // Max 4000 Zeichen pro Seite (erhöht)
@@ -2182,6 +2453,10 @@ func (app *App) handleChatSendStream(w http.ResponseWriter, r *http.Request) {
 			sourcesFooter = app.searchService.FormatSourcesFooter(searchResults)
 			log.Printf("Web-Suche: %d Ergebnisse gefunden, Query: '%s', Kontext: %d Zeichen",
 				len(searchResults), optimizedQuery, len(webSearchContext))
+			for _, sq := range subQueries {
+				log.Printf("Multi-Query: '%s' -> %d Treffer (%s), %d Quellen übernommen",
+					sq.Query, sq.Hits, strings.Join(sq.Backends, ", "), len(sq.Sources))
+			}
 
 			// Websuche-Zähler erhöhen und in DB speichern
 			if app.settingsService != nil {
@@ -2200,6 +2475,11 @@ func (app *App) handleChatSendStream(w http.ResponseWriter, r *http.Request) {
 		MaxTokens:   4096,
 	}
 
+	// LoRA-Adapter des Experten bzw. der GGUF-Konfiguration
+	var loraSelection []custommodel.LoraSelection
+	// Prompt-Cache des Experten (Slot-Zustand mit verarbeitetem System-Prompt)
+	var slotCacheKey string
+
 	// Wenn ein Experte ausgewählt ist, ChatContext verwenden
 	if req.ExpertID != nil && *req.ExpertID > 0 {
 		// Aktuelle Sprache aus Settings für Experten-Prompt-Übersetzung
@@ -2226,25 +2506,47 @@ func (app *App) handleChatSendStream(w http.ResponseWriter, r *http.Request) {
 			log.Printf("Expert Chat: %s (Model: %s, Mode: %v, Switched: %v, Temp: %.1f, TopP: %.1f, MaxTokens: %d)",
 				chatCtx.Expert.Name, model, chatCtx.ActiveMode, chatCtx.ModeSwitched,
 				samplingParams.Temperature, samplingParams.TopP, samplingParams.MaxTokens)
+
+			for _, l := range chatCtx.Expert.LoraAdapters {
+				loraSelection = append(loraSelection, custommodel.LoraSelection{AdapterID: l.AdapterID, Scale: l.Scale})
+			}
+			slotCacheKey = expertSlotCacheKey(chatCtx.Expert.ID)
 		}
 	}
 
-	// User-Nachricht speichern (mit fixem Experten und Modus)
-	// Attachments aus Bildern erstellen
-	var attachmentsJSON string
-	if len(req.Images) > 0 {
-		attachments := make([]map[string]string, len(req.Images))
-		for i, imgBase64 := range req.Images {
-			attachments[i] = map[string]string{
-				"type":    "image",
-				"content": imgBase64,
-				"name":    fmt.Sprintf("image_%d.png", i+1),
+	// GGUF-Konfiguration als Modell gewählt: Basismodell, Sampling und LoRA-Adapter übernehmen
+	if ggufConfig, err := app.customModelService.GetGgufConfigByName(model); err == nil && ggufConfig != nil {
+		model = ggufConfig.BaseModel
+		if req.ExpertID == nil || *req.ExpertID <= 0 {
+			samplingParams.Temperature = ggufConfig.Temperature
+			samplingParams.TopP = ggufConfig.TopP
+			samplingParams.MaxTokens = ggufConfig.MaxTokens
+			if ggufConfig.SystemPrompt != "" && (req.SystemPrompt == nil || *req.SystemPrompt == "") {
+				systemPrompt = ggufConfig.SystemPrompt
 			}
 		}
-		attachmentsBytes, _ := json.Marshal(attachments)
-		attachmentsJSON = string(attachmentsBytes)
+		if len(loraSelection) == 0 {
+			loraSelection = ggufConfig.LoraAdapters
+		}
+		log.Printf("GGUF-Konfiguration %s: Basismodell %s, %d LoRA-Adapter", ggufConfig.Name, model, len(loraSelection))
+	}
+
+	// User-Nachricht speichern (mit fixem Experten und Modus)
+	// Attachments aus Bildern erstellen (landen im Blob-Speicher, nicht als Base64 in der DB)
+	var attachments []chat.NewAttachment
+	for i, imgBase64 := range req.Images {
+		data, decodeErr := chat.DecodeBase64Attachment(imgBase64)
+		if decodeErr != nil {
+			log.Printf("⚠️ Bild %d konnte nicht dekodiert werden: %v", i+1, decodeErr)
+			continue
+		}
+		attachments = append(attachments, chat.NewAttachment{
+			Name: fmt.Sprintf("image_%d.png", i+1),
+			Type: "image",
+			Data: data,
+		})
 	}
-	_, err := app.chatStore.AddMessageWithAttachments(chatID, "USER", req.Message, "", 0, req.ExpertID, req.ModeID, attachmentsJSON)
+	_, err := app.chatStore.AddMessageWithAttachments(chatID, "USER", req.Message, "", 0, req.ExpertID, req.ModeID, attachments)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -2292,6 +2594,17 @@ WICHTIG: Der Benutzer hat obige Dokumente hochgeladen. Beziehe dich in deiner An
 		log.Printf("Dokument-Kontext hinzugefügt: %d Zeichen", len(req.DocumentContext))
 	}
 
+	// Projekt-Kontext (gemeinsamer Prompt + angeheftete Dokumente) hinzufügen
+	if project != nil {
+		projectContext, err := app.chatStore.BuildProjectContext(project)
+		if err != nil {
+			log.Printf("⚠️ Projekt-Kontext konnte nicht erstellt werden: %v", err)
+		} else if projectContext != "" {
+			finalSystemPrompt += "\n\n" + projectContext
+			log.Printf("📁 Projekt-Kontext '%s' hinzugefügt: %d Zeichen", project.Name, len(projectContext))
+		}
+	}
+
 	// Technische Selbstwahrnehmung: Modellname hinzufügen
 	// Das LLM kann bei Fragen wie "Auf welchem Modell basierst du?" korrekt antworten
 	currentModelName := model
@@ -2308,6 +2621,11 @@ Bei Fragen nach deinem Modell, deiner Technologie oder deiner Basis kannst du di
 Du bist eine KI und kein Mensch - sei ehrlich darüber wenn gefragt.`, currentModelName)
 	}
 
+	// Systemnachrichten (z.B. Observer-Alarme) stammen nicht vom Benutzer -
+	// sie gehen als Hinweis in den System-Prompt statt als User-Nachricht in die History
+	history, notices := buildConversationHistory(messages)
+	finalSystemPrompt += notices
+
 	conversationMessages = append(conversationMessages, chat.Message{
 		Role:    "system",
 		Content: finalSystemPrompt,
@@ -2318,16 +2636,7 @@ Du bist eine KI und kein Mensch - sei ehrlich darüber wenn gefragt.`, currentMo
 		strings.Contains(finalSystemPrompt, "IDENTITÄT")
 	log.Printf("System-Prompt: %d Zeichen, Anti-Halluzination: %v", len(finalSystemPrompt), hasAntiHallucination)
 
-	for _, m := range messages {
-		role := "user"
-		if m.Role == "ASSISTANT" {
-			role = "assistant"
-		}
-		conversationMessages = append(conversationMessages, chat.Message{
-			Role:    role,
-			Content: m.Content,
-		})
-	}
+	conversationMessages = append(conversationMessages, history...)
 
 	// SSE Headers setzen
 	w.Header().Set("Content-Type", "text/event-stream")
@@ -2343,6 +2652,15 @@ Du bist eine KI und kein Mensch - sei ehrlich darüber wenn gefragt.`, currentMo
 
 	// WICHTIG: Start-Event mit chatId senden (Frontend erwartet das!)
 	requestID := fmt.Sprintf("req-%d", time.Now().UnixNano())
+
+	// Generierung abbrechbar machen: über /api/chat/cancel/{requestId} oder
+	// automatisch wenn der Client die Verbindung schließt (r.Context() endet)
+	genCtx, cancelGen := context.WithCancel(r.Context())
+	app.generations.Store(requestID, cancelGen)
+	defer func() {
+		app.generations.Delete(requestID)
+		cancelGen()
+	}()
 	startData := map[string]interface{}{
 		"chatId":    chatID, // Wichtig: chatID verwenden, nicht req.ChatID (kann 0 sein!)
 		"requestId": requestID,
@@ -2465,7 +2783,7 @@ Du bist eine KI und kein Mensch - sei ehrlich darüber wenn gefragt.`, currentMo
 
 						// Kurze Vision-Analyse nur für visuelle Elemente
 						visualPrompt := "Beschreibe NUR die visuellen Elemente in diesem Dokument: Stempel, Unterschriften, Logos, Briefkopf. Ignoriere den Text - konzentriere dich nur auf visuelle Elemente. Antworte kurz und präzise auf Deutsch."
-						ctx, cancel := context.WithTimeout(r.Context(), 3*time.Minute)
+						ctx, cancel := context.WithTimeout(genCtx, 3*time.Minute)
 						visualAnalysis, visualErr := app.visionServer.AnalyzeImage(ctx, imageBase64, visualPrompt)
 						cancel()
 						if visualErr == nil && visualAnalysis != nil && len(visualAnalysis.Description) > 20 {
@@ -2490,7 +2808,7 @@ Du bist eine KI und kein Mensch - sei ehrlich darüber wenn gefragt.`, currentMo
 					flusher.Flush()
 
 					// Vision-Analyse über separaten Server durchführen
-					ctx, cancel := context.WithTimeout(r.Context(), 10*time.Minute)
+					ctx, cancel := context.WithTimeout(genCtx, 10*time.Minute)
 					analysis, err := app.visionServer.AnalyzeImage(ctx, imageBase64, "")
 					cancel()
 
@@ -2498,7 +2816,7 @@ Du bist eine KI und kein Mensch - sei ehrlich darüber wenn gefragt.`, currentMo
 						log.Printf("⚠️ Vision-Server Analyse fehlgeschlagen für Bild %d: %v", i+1, err)
 						// Fallback 1: Versuche alten visionService (Ollama)
 						if app.visionService != nil {
-							ctx2, cancel2 := context.WithTimeout(r.Context(), 5*time.Minute)
+							ctx2, cancel2 := context.WithTimeout(genCtx, 5*time.Minute)
 							ollamaAnalysis, ollamaErr := app.visionService.AnalyzeDocument(ctx2, imageBase64)
 							cancel2()
 							if ollamaErr == nil && ollamaAnalysis != nil {
@@ -2610,6 +2928,9 @@ Beantworte folgende Frage basierend auf der obigen Bildanalyse:
 	}
 
 	// Bei llama-server: Automatisch zum Experten-Modell wechseln wenn nötig
+	chatServer := app.llamaServer
+	releaseModel := func() {}
+	defer func() { releaseModel() }()
 	if activeProvider == "llama-server" && app.llamaServer != nil && model != "" {
 		status := app.llamaServer.GetStatus()
 		currentModel := strings.ToLower(status.ModelName)
@@ -2621,17 +2942,57 @@ Beantworte folgende Frage basierend auf der obigen Bildanalyse:
 			!strings.Contains(currentModel, targetModel) &&
 			!strings.Contains(targetModel, currentModel)
 
+		// Modell-Pool: Experten-Modell zusätzlich laden, das Hauptmodell bleibt geladen
+		swapAnnounced := false
+		if needsSwitch && app.modelPool.Enabled() {
+			resident := app.modelPool.Resident(model)
+			if !resident {
+				startData := map[string]interface{}{
+					"type":    "model_swap",
+					"status":  "starting",
+					"model":   model,
+					"message": fmt.Sprintf("🔄 Lade Modell %s...", model),
+				}
+				jsonData, _ := json.Marshal(startData)
+				fmt.Fprintf(w, "data: %s\n\n", jsonData)
+				flusher.Flush()
+				swapAnnounced = true
+			}
+
+			srv, release, poolErr := app.modelPool.Acquire(genCtx, model)
+			if poolErr == nil {
+				chatServer, releaseModel = srv, release
+				needsSwitch = false
+				if !resident {
+					completeData := map[string]interface{}{
+						"type":    "model_swap",
+						"status":  "complete",
+						"model":   model,
+						"message": fmt.Sprintf("✅ %s bereit!", model),
+					}
+					jsonData, _ := json.Marshal(completeData)
+					fmt.Fprintf(w, "data: %s\n\n", jsonData)
+					flusher.Flush()
+				}
+				log.Printf("📦 Modell-Pool: %s auf Port %d", model, srv.GetConfig().Port)
+			} else {
+				log.Printf("Modell-Pool: %v (wechsle Hauptmodell)", poolErr)
+			}
+		}
+
 		if needsSwitch {
 			// SSE Event: Model-Swap startet
-			startData := map[string]interface{}{
-				"type":    "model_swap",
-				"status":  "starting",
-				"model":   model,
-				"message": fmt.Sprintf("🔄 Lade Modell %s...", model),
-			}
-			jsonData, _ := json.Marshal(startData)
-			fmt.Fprintf(w, "data: %s\n\n", jsonData)
-			flusher.Flush()
+			if !swapAnnounced {
+				startData := map[string]interface{}{
+					"type":    "model_swap",
+					"status":  "starting",
+					"model":   model,
+					"message": fmt.Sprintf("🔄 Lade Modell %s...", model),
+				}
+				jsonData, _ := json.Marshal(startData)
+				fmt.Fprintf(w, "data: %s\n\n", jsonData)
+				flusher.Flush()
+			}
 
 			// Model wechseln MIT FALLBACK
 			switched, usedFallback, actualModel, switchErr := app.llamaServer.SwitchToModelWithFallback(model)
@@ -2692,7 +3053,7 @@ Beantworte folgende Frage basierend auf der obigen Bildanalyse:
 
 	if activeProvider == "ollama" {
 		// Ollama Provider
-		err = app.chatService.StreamChatWithMessages(model, conversationMessages, streamCallback)
+		err = app.chatService.StreamChatWithMessagesContext(genCtx, model, conversationMessages, streamCallback)
 	} else {
 		// llama-server Provider (Default)
 		// Konvertiere Messages für llama-server
@@ -2703,8 +3064,88 @@ Beantworte folgende Frage basierend auf der obigen Bildanalyse:
 				Content: msg.Content,
 			}
 		}
-		// Mit Sampling-Parametern aufrufen
-		err = app.llamaServer.StreamChatWithParams(llamaMessages, samplingParams, streamCallback)
+		// Interaktiver Chat hat Vorrang vor Mate-Jobs; Position in der Warteschlange an den Client melden
+		queueCtx := llamaserver.WithRequestInfo(genCtx, llamaserver.RequestInfo{
+			Priority: llamaserver.PriorityInteractive,
+			User:     app.queueUserKey(r),
+			OnQueued: func(position int) {
+				queueData := map[string]interface{}{
+					"type":     "queue",
+					"position": position,
+					"status":   "waiting",
+					"message":  fmt.Sprintf("⏳ Warteschlange: Position %d", position),
+				}
+				if position == 0 {
+					queueData["status"] = "started"
+					queueData["message"] = "▶️ Generierung startet"
+				}
+				jsonData, _ := json.Marshal(queueData)
+				fmt.Fprintf(w, "data: %s\n\n", jsonData)
+				flusher.Flush()
+			},
+		})
+		if len(loraSelection) > 0 {
+			queueCtx = app.loraContext(queueCtx, chatServer, loraSelection)
+		}
+		if slotCacheKey != "" {
+			queueCtx = llamaserver.WithSlotCache(queueCtx, slotCacheKey)
+		}
+		// Experten mit tool-fähigem Modell fragen Daten (z.B. Observer-Reihen) gezielt über Tools ab
+		var chatTools []llamaserver.Tool
+		if req.ExpertID != nil && *req.ExpertID > 0 && llamaserver.SupportsToolCalling(chatServer.GetStatus().ModelName) {
+			chatTools = app.chatToolDefinitions(req.WebSearchEnabled)
+		}
+		if len(chatTools) > 0 {
+			err = app.streamChatWithToolCalls(queueCtx, chatServer, llamaMessages, samplingParams, chatTools, streamCallback,
+				func(name string, result *tools.ToolResult) {
+					toolData := map[string]interface{}{
+						"type":    "tool_call",
+						"tool":    name,
+						"success": result.Success,
+						"message": fmt.Sprintf("🔧 %s", name),
+					}
+					jsonData, _ := json.Marshal(toolData)
+					fmt.Fprintf(w, "data: %s\n\n", jsonData)
+					flusher.Flush()
+				})
+		} else {
+			// Mit Sampling-Parametern aufrufen (abbrechbar über genCtx)
+			err = chatServer.StreamChatWithContext(queueCtx, llamaMessages, samplingParams, streamCallback)
+		}
+	}
+
+	// Abbruch: Teilantwort als "unterbrochen" speichern statt sie zu verwerfen
+	if genCtx.Err() != nil {
+		effectiveModeID := req.ModeID
+		if newModeID != nil {
+			effectiveModeID = newModeID
+		}
+		reason := "cancelled"
+		if r.Context().Err() != nil {
+			reason = "disconnected"
+		}
+		log.Printf("⏹️ Generierung %s abgebrochen (%s) nach %d Zeichen", requestID, reason, len(fullResponse))
+
+		if fullResponse != "" {
+			if _, saveErr := app.chatStore.AddInterruptedMessage(chatID, "ASSISTANT", fullResponse, model, len(fullResponse)/4, req.ExpertID, effectiveModeID); saveErr != nil {
+				log.Printf("WARNUNG: Teilantwort konnte nicht gespeichert werden: %v", saveErr)
+			}
+		}
+
+		// Nur sinnvoll wenn der Client noch verbunden ist (Cancel-Button)
+		if reason == "cancelled" {
+			interruptedData := map[string]interface{}{
+				"type":        "interrupted",
+				"requestId":   requestID,
+				"interrupted": true,
+				"tokens":      len(fullResponse) / 4,
+				"done":        true,
+			}
+			interruptedJSON, _ := json.Marshal(interruptedData)
+			fmt.Fprintf(w, "data: %s\n\n", interruptedJSON)
+			flusher.Flush()
+		}
+		return
 	}
 
 	if err != nil {
@@ -2801,64 +3242,286 @@ Beantworte folgende Frage basierend auf der obigen Bildanalyse:
 	if _, err := app.chatStore.AddMessage(chatID, "ASSISTANT", fullResponse, model, tokenCount, req.ExpertID, effectiveModeID); err != nil {
 		log.Printf("WARNUNG: Assistenten-Antwort konnte nicht gespeichert werden: %v", err)
 	}
+
+	// Nach dem ersten Austausch: Titel und Tags asynchron generieren
+	if app.chatStore.IsFirstExchange(chatID) {
+		go app.generateChatTitleAndTags(chatID, req.Message, fullResponse)
+	}
 }
 
-func (app *App) handleSelectedModel(w http.ResponseWriter, r *http.Request) {
-	switch r.Method {
-	case http.MethodGet:
-		// Wenn kein Modell gesetzt, 204 No Content
-		if app.selectedModel == "" {
-			w.WriteHeader(http.StatusNoContent)
-			return
+// maxSystemNotices begrenzt die Systemnachrichten (neueste zuerst) im System-Prompt
+const maxSystemNotices = 5
+
+// buildConversationHistory wandelt gespeicherte Nachrichten in die Chat-History um.
+// USER/ASSISTANT werden zu user/assistant; SYSTEM-Nachrichten (Observer-Alarme) werden
+// nicht als Benutzer-Nachricht gesendet, sondern als Hinweis-Block für den System-Prompt
+// zurückgegeben (leer wenn keine).
+func buildConversationHistory(messages []chat.StoredMessage) ([]chat.Message, string) {
+	history := make([]chat.Message, 0, len(messages))
+	var notices []string
+	for _, m := range messages {
+		switch m.Role {
+		case "SYSTEM":
+			notices = append(notices, fmt.Sprintf("- [%s] %s", m.CreatedAt.Format("02.01.2006 15:04"), m.Content))
+		case "ASSISTANT":
+			history = append(history, chat.Message{Role: "assistant", Content: m.Content})
+		default:
+			history = append(history, chat.Message{Role: "user", Content: m.Content})
 		}
-		// Nur den Model-Namen als String zurückgeben (wie Java-Backend)
-		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
-		w.Write([]byte(app.selectedModel))
+	}
 
-	case http.MethodPost:
-		// Frontend sendet text/plain, nicht JSON
-		contentType := r.Header.Get("Content-Type")
-		var modelName string
+	if len(notices) == 0 {
+		return history, ""
+	}
+	if len(notices) > maxSystemNotices {
+		notices = notices[len(notices)-maxSystemNotices:]
+	}
+	return history, "\n\n## SYSTEMHINWEISE IM CHAT\n" +
+		"Automatische Meldungen (z.B. Observer-Alarme), nicht vom Benutzer geschrieben:\n" +
+		strings.Join(notices, "\n")
+}
 
-		if strings.Contains(contentType, "text/plain") {
-			// Text/plain Body lesen
-			body := make([]byte, 1024)
-			n, _ := r.Body.Read(body)
-			modelName = strings.TrimSpace(string(body[:n]))
-		} else {
-			// Fallback: JSON
-			var req struct {
-				Model string `json:"model"`
-			}
-			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-				http.Error(w, "Invalid request", http.StatusBadRequest)
-				return
-			}
-			modelName = req.Model
+// maxToolRounds begrenzt die Tool-Runden pro Antwort (danach muss das Modell antworten)
+const maxToolRounds = 4
+
+// maxToolResultChars begrenzt ein Tool-Ergebnis im Prompt
+const maxToolResultChars = 8000
+
+// chatToolDefinitions liefert die Tools die der Navigator im Chat selbst ausführen kann.
+// Mate-Tools sind ausgenommen, Web-Tools nur bei aktivierter Web-Suche.
+// Sortiert, damit der Prompt (und damit der Prompt-Cache) stabil bleibt.
+func (app *App) chatToolDefinitions(webSearch bool) []llamaserver.Tool {
+	if app.toolRegistry == nil {
+		return nil
+	}
+	defs := app.toolRegistry.GetToolDefinitions()
+	sort.Slice(defs, func(i, j int) bool { return defs[i].Name < defs[j].Name })
+
+	var result []llamaserver.Tool
+	for _, def := range defs {
+		if def.RequiresMate {
+			continue
 		}
+		if !webSearch && (def.Name == "web_search" || def.Name == "web_fetch") {
+			continue
+		}
+		result = append(result, llamaserver.Tool{
+			Type: "function",
+			Function: llamaserver.ToolFunction{
+				Name:        def.Name,
+				Description: def.Description,
+				Parameters:  def.Parameters,
+			},
+		})
+	}
+	return result
+}
 
-		if modelName == "" {
-			http.Error(w, "Model is required", http.StatusBadRequest)
-			return
+// streamChatWithToolCalls führt eine Chat-Anfrage mit Tool-Calling durch:
+// Fordert das Modell Tools an, werden sie ausgeführt und die Ergebnisse als
+// "tool"-Nachrichten zurückgegeben, bis das Modell antwortet (max. maxToolRounds Runden).
+// Text wird wie bei StreamChatWithContext über onChunk gestreamt.
+func (app *App) streamChatWithToolCalls(ctx context.Context, srv *llamaserver.Server, messages []llamaserver.ChatMessage,
+	params llamaserver.SamplingParams, chatTools []llamaserver.Tool, onChunk func(content string, done bool),
+	onToolCall func(name string, result *tools.ToolResult)) error {
+
+	offered := make(map[string]bool, len(chatTools))
+	for _, t := range chatTools {
+		offered[t.Function.Name] = true
+	}
+
+	for round := 0; ; round++ {
+		roundTools := chatTools
+		if round >= maxToolRounds {
+			roundTools = nil // Letzte Runde ohne Tools: Antwort erzwingen
 		}
 
-		app.selectedModel = modelName
-		app.modelService.SetSelectedModel(modelName)
-		log.Printf("Ausgewähltes Modell geändert: %s", modelName)
-		w.WriteHeader(http.StatusOK)
+		resp, err := srv.StreamChatWithTools(ctx, messages, params, roundTools, func(content string, done bool) {
+			// "done" erst nach der letzten Runde melden
+			if content != "" {
+				onChunk(content, false)
+			}
+		})
+		if err != nil {
+			return err
+		}
+		if len(resp.ToolCalls) == 0 {
+			onChunk("", true)
+			return nil
+		}
 
-	default:
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		messages = append(messages, llamaserver.ChatMessage{
+			Role:      "assistant",
+			Content:   resp.Content,
+			ToolCalls: resp.ToolCalls,
+		})
+		for _, call := range resp.ToolCalls {
+			result := app.executeChatToolCall(ctx, call, offered)
+			if onToolCall != nil {
+				onToolCall(call.Function.Name, result)
+			}
+			content, _ := json.Marshal(result)
+			if len(content) > maxToolResultChars {
+				content = append(content[:maxToolResultChars], "…"...)
+			}
+			messages = append(messages, llamaserver.ChatMessage{
+				Role:       "tool",
+				Content:    string(content),
+				ToolCallID: call.ID,
+			})
+		}
 	}
 }
 
-func printBanner(config *Config) {
-	fmt.Println(`
-╔═══════════════════════════════════════════════════════════════╗
-║                                                               ║
-║   ███████╗██╗     ███████╗███████╗████████╗                  ║
-║   ██╔════╝██║     ██╔════╝██╔════╝╚══██╔══╝                  ║
-║   █████╗  ██║     █████╗  █████╗     ██║                     ║
+// executeChatToolCall führt einen Tool-Aufruf des Modells über die Tool-Registry aus.
+// Fehler gehen als Ergebnis an das Modell zurück, damit es darauf reagieren kann.
+func (app *App) executeChatToolCall(ctx context.Context, call llamaserver.ToolCall, offered map[string]bool) *tools.ToolResult {
+	name := call.Function.Name
+	if !offered[name] {
+		return &tools.ToolResult{Success: false, Error: fmt.Sprintf("Tool '%s' ist nicht verfügbar", name)}
+	}
+
+	params := map[string]interface{}{}
+	if strings.TrimSpace(call.Function.Arguments) != "" {
+		if err := json.Unmarshal([]byte(call.Function.Arguments), &params); err != nil {
+			return &tools.ToolResult{Success: false, Error: fmt.Sprintf("Ungültige Argumente: %v", err)}
+		}
+	}
+
+	start := time.Now()
+	result, err := app.toolRegistry.Execute(ctx, name, params)
+	if err != nil {
+		result = &tools.ToolResult{Success: false, Error: err.Error()}
+	}
+	log.Printf("🔧 Tool %s (%s): success=%v, %v", name, call.Function.Arguments, result.Success, time.Since(start).Round(time.Millisecond))
+	return result
+}
+
+// generateChatTitleAndTags erzeugt mit dem lokalen Modell einen kurzen Titel und
+// Tag-Vorschläge für einen Chat. Läuft im Hintergrund nach dem ersten Austausch.
+// Manuell umbenannte Chats behalten ihren Titel (siehe chat.SetGeneratedTitle).
+func (app *App) generateChatTitleAndTags(chatID int64, userMessage, assistantResponse string) {
+	if app.llamaServer == nil || !app.llamaServer.IsRunning() {
+		return
+	}
+
+	raw, err := app.llamaServer.QuickChatWithTimeout(chat.TitleAndTagsPrompt,
+		chat.BuildTitleRequest(userMessage, assistantResponse), 30*time.Second)
+	if err != nil {
+		log.Printf("Titel-Generierung für Chat %d fehlgeschlagen: %v", chatID, err)
+		return
+	}
+
+	title, tags := chat.ParseTitleSuggestion(raw)
+	if title != "" {
+		// SECURITY: HTML-Escape wie beim Platzhalter-Titel
+		changed, err := app.chatStore.SetGeneratedTitle(chatID, html.EscapeString(title))
+		if err != nil {
+			log.Printf("Generierter Titel konnte nicht gespeichert werden: %v", err)
+		} else if changed {
+			log.Printf("🏷️ Chat %d: Titel generiert: %q", chatID, title)
+		}
+	}
+	if len(tags) > 0 {
+		if _, err := app.chatStore.AddChatTags(chatID, tags, chat.TagSourceAuto); err != nil {
+			log.Printf("Tag-Vorschläge konnten nicht gespeichert werden: %v", err)
+		} else {
+			log.Printf("🏷️ Chat %d: Tags vorgeschlagen: %v", chatID, tags)
+		}
+	}
+}
+
+// handleChatCancel - POST /api/chat/cancel/{requestId}
+// Bricht eine laufende Generierung ab. Die Teilantwort wird als "unterbrochen"
+// gespeichert und der llama-server Slot freigegeben (Verbindung wird geschlossen).
+func (app *App) handleChatCancel(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	requestID := strings.TrimPrefix(r.URL.Path, "/api/chat/cancel/")
+	if requestID == "" {
+		http.Error(w, "requestId is required", http.StatusBadRequest)
+		return
+	}
+
+	value, ok := app.generations.Load(requestID)
+	if !ok {
+		// Bereits beendet oder unbekannt - kein Fehler für das Frontend
+		writeJSON(w, map[string]interface{}{
+			"success":   false,
+			"requestId": requestID,
+			"message":   "Keine laufende Generierung mit dieser ID",
+		})
+		return
+	}
+
+	value.(context.CancelFunc)()
+	log.Printf("⏹️ Abbruch angefordert: %s", requestID)
+
+	writeJSON(w, map[string]interface{}{
+		"success":   true,
+		"requestId": requestID,
+	})
+}
+
+func (app *App) handleSelectedModel(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		// Wenn kein Modell gesetzt, 204 No Content
+		if app.selectedModel == "" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		// Nur den Model-Namen als String zurückgeben (wie Java-Backend)
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Write([]byte(app.selectedModel))
+
+	case http.MethodPost:
+		// Frontend sendet text/plain, nicht JSON
+		contentType := r.Header.Get("Content-Type")
+		var modelName string
+
+		if strings.Contains(contentType, "text/plain") {
+			// Text/plain Body lesen
+			body := make([]byte, 1024)
+			n, _ := r.Body.Read(body)
+			modelName = strings.TrimSpace(string(body[:n]))
+		} else {
+			// Fallback: JSON
+			var req struct {
+				Model string `json:"model"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, "Invalid request", http.StatusBadRequest)
+				return
+			}
+			modelName = req.Model
+		}
+
+		if modelName == "" {
+			http.Error(w, "Model is required", http.StatusBadRequest)
+			return
+		}
+
+		app.selectedModel = modelName
+		app.modelService.SetSelectedModel(modelName)
+		log.Printf("Ausgewähltes Modell geändert: %s", modelName)
+		w.WriteHeader(http.StatusOK)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func printBanner(config *Config) {
+	fmt.Println(`
+╔═══════════════════════════════════════════════════════════════╗
+║                                                               ║
+║   ███████╗██╗     ███████╗███████╗████████╗                  ║
+║   ██╔════╝██║     ██╔════╝██╔════╝╚══██╔══╝                  ║
+║   █████╗  ██║     █████╗  █████╗     ██║                     ║
 ║   ██╔══╝  ██║     ██╔══╝  ██╔══╝     ██║                     ║
 ║   ██║     ███████╗███████╗███████╗   ██║                     ║
 ║   ╚═╝     ╚══════╝╚══════╝╚══════╝   ╚═╝                     ║
@@ -3153,7 +3816,7 @@ func (app *App) handleLLMPullModel(w http.ResponseWriter, r *http.Request) {
 		done := make(chan error, 1)
 
 		go func() {
-			done <- app.llamaServer.DownloadModel(downloadURL, entry.Filename, progressChan)
+			done <- app.llamaServer.DownloadModelWithChecksum(downloadURL, entry.Filename, entry.SHA256, progressChan)
 			close(progressChan)
 		}()
 
@@ -3250,17 +3913,41 @@ func (app *App) handleLLMModelDetails(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Metadaten aus dem GGUF-Header der lokalen Datei (Architektur, Context, Quantisierung, ...)
+	var ggufInfo *gguf.ModelInfo
+	if app.llamaServer != nil {
+		if modelPath, err := app.llamaServer.FindModelByName(modelName); err == nil {
+			if info, err := gguf.ReadInfo(modelPath); err == nil {
+				ggufInfo = info
+			} else {
+				log.Printf("GGUF-Header von %s nicht lesbar: %v", modelPath, err)
+			}
+		}
+	}
+
 	// Details vom Provider holen
 	details, err := app.modelService.GetModelDetails(modelName)
 	if err != nil {
-		// Fallback: Nur Registry-Info
-		entry := app.modelService.FindModelInRegistry(modelName)
-		if entry != nil {
-			writeJSON(w, entry)
+		if ggufInfo != nil {
+			details = map[string]interface{}{"name": modelName}
+		} else {
+			// Fallback: Nur Registry-Info
+			entry := app.modelService.FindModelInRegistry(modelName)
+			if entry != nil {
+				writeJSON(w, entry)
+				return
+			}
+			http.Error(w, err.Error(), http.StatusNotFound)
 			return
 		}
-		http.Error(w, err.Error(), http.StatusNotFound)
-		return
+	}
+
+	if ggufInfo != nil {
+		details["gguf"] = ggufInfo
+		details["architecture"] = ggufInfo.Architecture
+		details["context_length"] = ggufInfo.ContextLength
+		details["quantization"] = ggufInfo.Quantization
+		details["parameter_size"] = ggufInfo.SizeLabel
 	}
 
 	// Registry-Info hinzufuegen falls vorhanden
@@ -3280,9 +3967,10 @@ func (app *App) handleLLMChat(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var req struct {
-		Message string `json:"message"`
-		Model   string `json:"model,omitempty"`
-		Stream  bool   `json:"stream,omitempty"`
+		Message        string                      `json:"message"`
+		Model          string                      `json:"model,omitempty"`
+		Stream         bool                        `json:"stream,omitempty"`
+		ResponseFormat *llamaserver.ResponseFormat `json:"response_format,omitempty"` // json_object, json_schema oder grammar (nur llama-server)
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, "Invalid JSON", http.StatusBadRequest)
@@ -3294,6 +3982,17 @@ func (app *App) handleLLMChat(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if req.ResponseFormat != nil {
+		if err := req.ResponseFormat.Normalize(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if req.ResponseFormat.Type != llamaserver.FormatText {
+			app.handleLLMChatStructured(w, r, req.Message, *req.ResponseFormat)
+			return
+		}
+	}
+
 	model := req.Model
 	if model == "" {
 		model = app.modelService.GetSelectedModel()
@@ -3333,6 +4032,47 @@ func (app *App) handleLLMChat(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// handleLLMChatStructured beantwortet /api/llm/chat mit erzwungenem Ausgabeformat.
+// Die Antwort kommt als ein einzelnes SSE-Event; bei JSON-Formaten zusätzlich
+// dekodiert im Feld "structured".
+func (app *App) handleLLMChatStructured(w http.ResponseWriter, r *http.Request, message string, format llamaserver.ResponseFormat) {
+	if app.llamaServer == nil || !app.llamaServer.IsRunning() {
+		http.Error(w, "response_format erfordert einen laufenden llama-server", http.StatusServiceUnavailable)
+		return
+	}
+
+	// SSE Headers
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	ctx := llamaserver.WithRequestInfo(r.Context(), llamaserver.RequestInfo{
+		Priority: llamaserver.PriorityInteractive,
+		User:     app.queueUserKey(r),
+	})
+	messages := []llamaserver.ChatMessage{{Role: "user", Content: message}}
+
+	content, err := app.llamaServer.GenerateStructured(ctx, messages, format, llamaserver.SamplingParams{})
+	data := map[string]interface{}{
+		"content": content,
+		"done":    true,
+	}
+	if err != nil {
+		data["error"] = err.Error()
+	} else if format.Type == llamaserver.FormatJSONObject || format.Type == llamaserver.FormatJSONSchema {
+		data["structured"] = json.RawMessage(content)
+	}
+	jsonData, _ := json.Marshal(data)
+	fmt.Fprintf(w, "data: %s\n\n", jsonData)
+	flusher.Flush()
+}
+
 // handleLLMStatus gibt den Status des LLM-Systems zurueck
 func (app *App) handleLLMStatus(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
@@ -3426,7 +4166,7 @@ func (app *App) handleModelsPull(w http.ResponseWriter, r *http.Request) {
 
 		// Download im Hintergrund starten
 		go func() {
-			done <- app.llamaServer.DownloadModel(downloadURL, entry.Filename, progressChan)
+			done <- app.llamaServer.DownloadModelWithChecksum(downloadURL, entry.Filename, entry.SHA256, progressChan)
 			close(progressChan)
 		}()
 
@@ -3814,6 +4554,61 @@ func DetermineFileType(filename, contentType string) string {
 	return "unknown"
 }
 
+// handleAttachmentByID - GET /api/attachments/{hash}
+// Liefert einen Chat-Anhang aus dem Blob-Speicher aus.
+// Inhalte sind per SHA-256 adressiert und damit unveränderlich (lange Cache-Dauer).
+func (app *App) handleAttachmentByID(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	hash := strings.TrimPrefix(r.URL.Path, chat.AttachmentURLPrefix)
+	if !chat.IsValidAttachmentID(hash) {
+		http.Error(w, "Ungültige Anhang-ID", http.StatusBadRequest)
+		return
+	}
+
+	blob, file, err := app.chatStore.OpenAttachment(hash)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if blob == nil {
+		http.Error(w, "Anhang nicht gefunden", http.StatusNotFound)
+		return
+	}
+	defer file.Close()
+
+	w.Header().Set("Content-Type", blob.MimeType)
+	w.Header().Set("Cache-Control", "private, max-age=31536000, immutable")
+	w.Header().Set("ETag", `"`+blob.Hash+`"`)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	http.ServeContent(w, r, "", blob.CreatedAt, file)
+}
+
+// handleAttachmentsGC - POST /api/attachments/gc
+// Entfernt Anhänge die von keiner Nachricht mehr referenziert werden
+func (app *App) handleAttachmentsGC(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	result, err := app.chatStore.GarbageCollectAttachments()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, map[string]interface{}{
+		"success":      true,
+		"removedBlobs": result.RemovedBlobs,
+		"freedBytes":   result.FreedBytes,
+		"freed":        formatBytesForDisplay(result.FreedBytes),
+	})
+}
+
 // handleFileUpload - POST /api/files/upload
 // Handles file uploads for chat attachments (images, text, PDF)
 func (app *App) handleFileUpload(w http.ResponseWriter, r *http.Request) {
@@ -5156,118 +5951,662 @@ func (app *App) handleGgufModelByID(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// handlePersonalInfo - GET/POST /api/personal-info
-// Manages user's personal information (stub for frontend compatibility)
-func (app *App) handlePersonalInfo(w http.ResponseWriter, r *http.Request) {
-	switch r.Method {
-	case http.MethodGet:
-		// Return empty personal info
-		writeJSON(w, map[string]interface{}{
-			"name":     "",
-			"email":    "",
-			"company":  "",
-			"position": "",
-		})
-	case http.MethodPost, http.MethodPut:
-		// Accept but ignore for now
-		writeJSON(w, map[string]interface{}{"success": true})
-	default:
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-	}
+// expertSlotCacheKey ist der Prompt-Cache-Schlüssel eines Experten
+func expertSlotCacheKey(expertID int64) string {
+	return fmt.Sprintf("expert-%d", expertID)
 }
 
-// handleTemplates - GET/POST /api/templates
-// Manages prompt templates (stub for frontend compatibility)
-func (app *App) handleTemplates(w http.ResponseWriter, r *http.Request) {
-	switch r.Method {
-	case http.MethodGet:
-		// Return default templates
-		templates := []map[string]interface{}{
-			{
-				"id":          "default-brief",
-				"name":        "Geschäftsbrief",
-				"description": "Vorlage für formelle Geschäftsbriefe",
-				"category":    "dokumente",
-				"prompt":      "Erstelle einen formellen Geschäftsbrief mit folgendem Inhalt: ",
-			},
-			{
-				"id":          "default-email",
-				"name":        "E-Mail",
-				"description": "Vorlage für geschäftliche E-Mails",
-				"category":    "kommunikation",
-				"prompt":      "Schreibe eine professionelle E-Mail zu folgendem Thema: ",
-			},
-			{
-				"id":          "default-zusammenfassung",
-				"name":        "Zusammenfassung",
-				"description": "Text zusammenfassen",
-				"category":    "analyse",
-				"prompt":      "Fasse den folgenden Text zusammen: ",
-			},
-		}
-		writeJSON(w, templates)
-	case http.MethodPost:
-		// Accept but don't store templates (stub)
-		writeJSON(w, map[string]interface{}{
-			"success": true,
-			"message": "Template saved",
-		})
-	default:
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+// clearExpertSlotCache verwirft die gespeicherten Slot-Zustände eines Experten
+func (app *App) clearExpertSlotCache(expertID int64) {
+	if removed, err := app.llamaServer.ClearSlotCache(expertSlotCacheKey(expertID)); err != nil {
+		log.Printf("⚠️ Prompt-Cache von Experte %d nicht gelöscht: %v", expertID, err)
+	} else if removed > 0 {
+		log.Printf("🗑️ Prompt-Cache von Experte %d verworfen (%d Dateien)", expertID, removed)
 	}
 }
 
-// handleProjects - GET/POST /api/projects
-// Manages projects/workspaces (stub for frontend compatibility)
-func (app *App) handleProjects(w http.ResponseWriter, r *http.Request) {
-	switch r.Method {
-	case http.MethodGet:
-		// Return empty list of projects
-		writeJSON(w, []interface{}{})
-	case http.MethodPost:
-		// Accept but don't store projects (stub)
-		writeJSON(w, map[string]interface{}{
-			"success": true,
-			"message": "Projects not yet implemented in Go backend",
-		})
-	default:
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+// loraContext lädt die ausgewählten LoRA-Adapter (Neustart nur bei neuen Adaptern,
+// nachdem laufende Anfragen fertig sind) und aktiviert sie für eine Anfrage. Fehler werden geloggt, der Chat läuft ohne Adapter weiter.
+func (app *App) loraContext(ctx context.Context, server *llamaserver.Server, selection []custommodel.LoraSelection) context.Context {
+	loras, err := app.customModelService.ResolveLoras(server.GetConfig().ModelPath, selection)
+	if err != nil {
+		log.Printf("⚠️ LoRA-Adapter übersprungen: %v", err)
+		return ctx
+	}
+
+	paths := make([]string, len(loras))
+	scales := make([]llamaserver.LoraScale, len(loras))
+	for i, l := range loras {
+		paths[i] = l.Path
+		scales[i] = llamaserver.LoraScale{Path: l.Path, Scale: l.Scale}
+	}
+	if restarted, err := server.EnsureLoraAdapters(ctx, paths, app.registeredLoraPaths()); err != nil {
+		log.Printf("⚠️ LoRA-Adapter konnten nicht geladen werden: %v", err)
+		return ctx
+	} else if restarted {
+		log.Printf("🧩 llama-server für %d LoRA-Adapter neu gestartet", len(paths))
 	}
+	return llamaserver.WithLora(ctx, scales)
 }
 
-// ============== System Prompts API Handler ==============
+// registeredLoraPaths gibt die Dateipfade aller registrierten LoRA-Adapter zurück
+// (gelöschte Adapter werden beim nächsten llama-server-Start nicht mehr geladen)
+func (app *App) registeredLoraPaths() []string {
+	adapters, err := app.customModelService.GetAllLoraAdapters()
+	if err != nil {
+		log.Printf("⚠️ LoRA-Adapter nicht lesbar: %v", err)
+		return nil
+	}
+	paths := make([]string, len(adapters))
+	for i, a := range adapters {
+		paths[i] = a.FilePath
+	}
+	return paths
+}
 
-// handleSystemPrompts - GET/POST /api/system-prompts
-func (app *App) handleSystemPrompts(w http.ResponseWriter, r *http.Request) {
+// handleLoraAdapters - GET/POST /api/lora-adapters
+// Registrierte LoRA-Adapter auflisten bzw. neuen Adapter registrieren
+func (app *App) handleLoraAdapters(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
 	case http.MethodGet:
-		promptsList, err := app.promptsService.GetAll()
+		adapters, err := app.customModelService.GetAllLoraAdapters()
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
-		writeJSON(w, promptsList)
+		writeJSON(w, adapters)
 
 	case http.MethodPost:
-		var prompt prompts.SystemPromptTemplate
-		if err := json.NewDecoder(r.Body).Decode(&prompt); err != nil {
-			http.Error(w, "Invalid JSON", http.StatusBadRequest)
-			return
-		}
-
-		if prompt.Name == "" || prompt.Content == "" {
-			http.Error(w, "Name and content are required", http.StatusBadRequest)
+		var req custommodel.LoraAdapterCreateRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
 			return
 		}
 
-		if err := app.promptsService.Create(&prompt); err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+		adapter, err := app.customModelService.RegisterLoraAdapter(req)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
 
-		log.Printf("System-Prompt erstellt: %s (ID: %d)", prompt.Name, prompt.ID)
 		w.WriteHeader(http.StatusCreated)
-		writeJSON(w, prompt)
+		writeJSON(w, adapter)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleLoraAdapterByID - GET/PUT/DELETE /api/lora-adapters/{id}
+func (app *App) handleLoraAdapterByID(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(strings.TrimPrefix(r.URL.Path, "/api/lora-adapters/"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		adapter, err := app.customModelService.GetLoraAdapterByID(id)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if adapter == nil {
+			http.Error(w, "Not found", http.StatusNotFound)
+			return
+		}
+		writeJSON(w, adapter)
+
+	case http.MethodPut:
+		var req custommodel.LoraAdapterUpdateRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		adapter, err := app.customModelService.UpdateLoraAdapter(id, req)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		writeJSON(w, adapter)
+
+	case http.MethodDelete:
+		if err := app.customModelService.DeleteLoraAdapter(id); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, map[string]bool{"success": true})
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleLoraAdaptersActive - GET/POST /api/lora-adapters/active
+// GET: Im llama-server geladene Adapter mit globalen Stärken
+// POST: Globale Stärken setzen - lädt fehlende Adapter (Neustart), sonst ohne Neustart
+func (app *App) handleLoraAdaptersActive(w http.ResponseWriter, r *http.Request) {
+	if app.llamaServer == nil || !app.llamaServer.IsRunning() {
+		http.Error(w, "llama-server ist nicht aktiv", http.StatusServiceUnavailable)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		loaded, err := app.llamaServer.LoraAdapters(r.Context())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		registered, _ := app.customModelService.GetAllLoraAdapters()
+		byPath := make(map[string]custommodel.LoraAdapter, len(registered))
+		for _, a := range registered {
+			byPath[a.FilePath] = a
+		}
+
+		result := make([]map[string]interface{}, 0, len(loaded))
+		for _, l := range loaded {
+			entry := map[string]interface{}{"id": l.ID, "path": l.Path, "scale": l.Scale}
+			if a, ok := byPath[l.Path]; ok {
+				entry["adapterId"] = a.ID
+				entry["name"] = a.Name
+			}
+			result = append(result, entry)
+		}
+		writeJSON(w, map[string]interface{}{
+			"model":    app.llamaServer.GetStatus().ModelName,
+			"adapters": result,
+		})
+
+	case http.MethodPost:
+		var req struct {
+			Adapters []custommodel.LoraSelection `json:"adapters"` // Nicht aufgeführte Adapter werden deaktiviert
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		loras, err := app.customModelService.ResolveLoras(app.llamaServer.GetConfig().ModelPath, req.Adapters)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		paths := make([]string, len(loras))
+		scales := make([]llamaserver.LoraScale, len(loras))
+		for i, l := range loras {
+			paths[i] = l.Path
+			scales[i] = llamaserver.LoraScale{Path: l.Path, Scale: l.Scale}
+		}
+
+		restarted, err := app.llamaServer.EnsureLoraAdapters(r.Context(), paths, app.registeredLoraPaths())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		if err := app.llamaServer.SetLoraScales(r.Context(), scales); err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		writeJSON(w, map[string]interface{}{
+			"success":   true,
+			"restarted": restarted,
+			"adapters":  loras,
+		})
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handlePersonalInfo - GET/POST /api/personal-info
+// Manages user's personal information (stub for frontend compatibility)
+func (app *App) handlePersonalInfo(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		// Return empty personal info
+		writeJSON(w, map[string]interface{}{
+			"name":     "",
+			"email":    "",
+			"company":  "",
+			"position": "",
+		})
+	case http.MethodPost, http.MethodPut:
+		// Accept but ignore for now
+		writeJSON(w, map[string]interface{}{"success": true})
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// templateLanguage ermittelt die Sprache für Vorlagen (?lang=, sonst App-Sprache)
+func (app *App) templateLanguage(r *http.Request) experte.Language {
+	if lang := r.URL.Query().Get("lang"); lang != "" {
+		return experte.ParseLanguage(lang)
+	}
+	return experte.ParseLanguage(app.settingsService.GetLocale())
+}
+
+// handleTemplates - GET/POST /api/templates
+// Prompt-Vorlagen mit Platzhaltern ({{text}}, {{date}}, {{choice:a|b}})
+func (app *App) handleTemplates(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		list, err := app.templatesService.GetAll(app.templateLanguage(r))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, list)
+
+	case http.MethodPost:
+		var t templates.PromptTemplate
+		if err := json.NewDecoder(r.Body).Decode(&t); err != nil {
+			http.Error(w, "Invalid JSON", http.StatusBadRequest)
+			return
+		}
+		if err := app.templatesService.Create(&t); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		writeJSON(w, t)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleTemplateByID verwaltet einzelne Vorlagen sowie Import/Export
+// GET/PUT/DELETE /api/templates/{id}
+// POST /api/templates/{id}/render - Platzhalter ersetzen ({"values": {...}})
+// GET /api/templates/export - Bibliothek als JSON herunterladen
+// POST /api/templates/import - Bibliothek aus JSON importieren
+func (app *App) handleTemplateByID(w http.ResponseWriter, r *http.Request) {
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/templates/"), "/")
+	parts := strings.Split(path, "/")
+	lang := app.templateLanguage(r)
+
+	switch parts[0] {
+	case "export":
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		data, err := app.templatesService.Export(lang)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Disposition", "attachment; filename=\"prompt-templates.json\"")
+		w.Write(data)
+		return
+
+	case "import":
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		data, err := io.ReadAll(io.LimitReader(r.Body, 10<<20))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		result, err := app.templatesService.Import(data)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		writeJSON(w, result)
+		return
+	}
+
+	id, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid template ID", http.StatusBadRequest)
+		return
+	}
+
+	if len(parts) == 2 && parts[1] == "render" {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		var req struct {
+			Values map[string]string `json:"values"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid JSON", http.StatusBadRequest)
+			return
+		}
+		rendered, err := app.templatesService.Render(id, req.Values, lang)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		writeJSON(w, map[string]interface{}{"success": true, "prompt": rendered})
+		return
+	}
+	if len(parts) > 1 {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		t, err := app.templatesService.GetByID(id, lang)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if t == nil {
+			http.Error(w, "Template not found", http.StatusNotFound)
+			return
+		}
+		writeJSON(w, t)
+
+	case http.MethodPut:
+		var t templates.PromptTemplate
+		if err := json.NewDecoder(r.Body).Decode(&t); err != nil {
+			http.Error(w, "Invalid JSON", http.StatusBadRequest)
+			return
+		}
+		t.ID = id
+		if err := app.templatesService.Update(&t); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		writeJSON(w, t)
+
+	case http.MethodDelete:
+		if err := app.templatesService.Delete(id); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, map[string]interface{}{"success": true})
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// ============== Projects API Handler ==============
+
+// handleProjects - GET/POST /api/projects
+// Projekte gruppieren Chats mit gemeinsamem Experten, System-Prompt und Dokumenten
+func (app *App) handleProjects(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		projects, err := app.chatStore.ListProjects()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, projects)
+
+	case http.MethodPost:
+		var req struct {
+			Name            string `json:"name"`
+			Description     string `json:"description"`
+			SystemPrompt    string `json:"systemPrompt"`
+			DefaultExpertID *int64 `json:"defaultExpertId"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid JSON", http.StatusBadRequest)
+			return
+		}
+		project, err := app.chatStore.CreateProject(req.Name, req.Description, req.SystemPrompt, req.DefaultExpertID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		log.Printf("📁 Projekt erstellt: %s (ID %d)", project.Name, project.ID)
+		w.WriteHeader(http.StatusCreated)
+		writeJSON(w, project)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleProjectByID verwaltet einzelne Projekte, Dokumente und Chat-Zuordnungen
+// GET/PUT/DELETE /api/projects/{id}
+// GET /api/projects/{id}/chats - Chats des Projekts
+// PUT /api/projects/{id}/chats/{chatId} - Chat zuordnen
+// DELETE /api/projects/chats/{chatId} - Chat aus Projekt lösen
+// POST /api/projects/context-files - Dokument hinzufügen
+// GET /api/projects/context-files/{fileId}/content - Dokument-Inhalt
+// PATCH /api/projects/context-files/{fileId} - Anheften/Lösen ({"pinned": bool})
+// DELETE /api/projects/context-files/{fileId} - Dokument löschen
+func (app *App) handleProjectByID(w http.ResponseWriter, r *http.Request) {
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/projects/"), "/")
+	parts := strings.Split(path, "/")
+
+	switch parts[0] {
+	case "context-files":
+		app.handleProjectContextFiles(w, r, parts[1:])
+		return
+	case "chats":
+		// DELETE /api/projects/chats/{chatId}
+		if r.Method != http.MethodDelete || len(parts) != 2 {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		chatID, err := strconv.ParseInt(parts[1], 10, 64)
+		if err != nil {
+			http.Error(w, "Invalid chat ID", http.StatusBadRequest)
+			return
+		}
+		if err := app.chatStore.AssignChatToProject(chatID, nil); err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		writeJSON(w, map[string]interface{}{"success": true, "chatId": chatID})
+		return
+	}
+
+	projectID, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid project ID", http.StatusBadRequest)
+		return
+	}
+
+	// Unterpfad: /api/projects/{id}/chats[/{chatId}]
+	if len(parts) > 1 && parts[1] == "chats" {
+		switch {
+		case len(parts) == 2 && r.Method == http.MethodGet:
+			chats, err := app.chatStore.GetChatsFiltered(chat.ChatFilter{ProjectID: &projectID})
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			writeJSON(w, chats)
+		case len(parts) == 3 && r.Method == http.MethodPut:
+			chatID, err := strconv.ParseInt(parts[2], 10, 64)
+			if err != nil {
+				http.Error(w, "Invalid chat ID", http.StatusBadRequest)
+				return
+			}
+			if err := app.chatStore.AssignChatToProject(chatID, &projectID); err != nil {
+				http.Error(w, err.Error(), http.StatusNotFound)
+				return
+			}
+			project, _ := app.chatStore.GetProject(projectID)
+			writeJSON(w, project)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+		return
+	}
+	if len(parts) > 1 {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		project, err := app.chatStore.GetProject(projectID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if project == nil {
+			http.Error(w, "Project not found", http.StatusNotFound)
+			return
+		}
+		writeJSON(w, project)
+
+	case http.MethodPut:
+		var upd chat.ProjectUpdate
+		if err := json.NewDecoder(r.Body).Decode(&upd); err != nil {
+			http.Error(w, "Invalid JSON", http.StatusBadRequest)
+			return
+		}
+		project, err := app.chatStore.UpdateProject(projectID, upd)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if project == nil {
+			http.Error(w, "Project not found", http.StatusNotFound)
+			return
+		}
+		writeJSON(w, project)
+
+	case http.MethodDelete:
+		if err := app.chatStore.DeleteProject(projectID); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		log.Printf("🗑️ Projekt %d gelöscht (Chats bleiben erhalten)", projectID)
+		writeJSON(w, map[string]interface{}{"success": true})
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleProjectContextFiles verwaltet die Dokumente eines Projekts
+func (app *App) handleProjectContextFiles(w http.ResponseWriter, r *http.Request, parts []string) {
+	if len(parts) == 0 {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		var req struct {
+			ProjectID int64  `json:"projectId"`
+			Filename  string `json:"filename"`
+			Content   string `json:"content"`
+			FileType  string `json:"fileType"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid JSON", http.StatusBadRequest)
+			return
+		}
+		if req.Filename == "" || req.Content == "" {
+			http.Error(w, "filename and content required", http.StatusBadRequest)
+			return
+		}
+		file, err := app.chatStore.AddProjectFile(req.ProjectID, req.Filename, req.FileType, req.Content)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		log.Printf("📎 Projekt-Dokument hinzugefügt: %s (Projekt %d, ~%d Tokens)", file.Filename, file.ProjectID, file.EstimatedTokens)
+		w.WriteHeader(http.StatusCreated)
+		writeJSON(w, file)
+		return
+	}
+
+	fileID, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid file ID", http.StatusBadRequest)
+		return
+	}
+
+	// GET /api/projects/context-files/{fileId}/content
+	if len(parts) == 2 && parts[1] == "content" && r.Method == http.MethodGet {
+		file, err := app.chatStore.GetProjectFile(fileID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if file == nil {
+			http.Error(w, "File not found", http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Write([]byte(file.Content))
+		return
+	}
+	if len(parts) != 1 {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPatch:
+		var req struct {
+			Pinned bool `json:"pinned"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid JSON", http.StatusBadRequest)
+			return
+		}
+		if err := app.chatStore.SetProjectFilePinned(fileID, req.Pinned); err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		writeJSON(w, map[string]interface{}{"success": true, "pinned": req.Pinned})
+
+	case http.MethodDelete:
+		if err := app.chatStore.DeleteProjectFile(fileID); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, map[string]interface{}{"success": true})
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// ============== System Prompts API Handler ==============
+
+// handleSystemPrompts - GET/POST /api/system-prompts
+func (app *App) handleSystemPrompts(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		promptsList, err := app.promptsService.GetAll()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, promptsList)
+
+	case http.MethodPost:
+		var prompt prompts.SystemPromptTemplate
+		if err := json.NewDecoder(r.Body).Decode(&prompt); err != nil {
+			http.Error(w, "Invalid JSON", http.StatusBadRequest)
+			return
+		}
+
+		if prompt.Name == "" || prompt.Content == "" {
+			http.Error(w, "Name and content are required", http.StatusBadRequest)
+			return
+		}
+
+		if err := app.promptsService.Create(&prompt); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		log.Printf("System-Prompt erstellt: %s (ID: %d)", prompt.Name, prompt.ID)
+		w.WriteHeader(http.StatusCreated)
+		writeJSON(w, prompt)
 
 	default:
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -6005,6 +7344,19 @@ func (app *App) authenticateRequest(r *http.Request) (*user.User, error) {
 	return app.userService.ValidateToken(token)
 }
 
+// queueUserKey bestimmt den Fairness-Schlüssel für die Slot-Warteschlange:
+// angemeldeter Benutzer, sonst die IP-Adresse des Clients
+func (app *App) queueUserKey(r *http.Request) string {
+	if u, err := app.authenticateRequest(r); err == nil && u != nil {
+		return fmt.Sprintf("user:%d", u.ID)
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
+
 // ============================================================================
 // llama-server API Handler
 // ============================================================================
@@ -6441,79 +7793,427 @@ func (app *App) handleLlamaServerDownload(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	if req.URL == "" || req.Filename == "" {
-		http.Error(w, "url und filename erforderlich", http.StatusBadRequest)
+	if req.URL == "" || req.Filename == "" {
+		http.Error(w, "url und filename erforderlich", http.StatusBadRequest)
+		return
+	}
+
+	// Download-Logik ausgelagert
+	app.downloadGGUFModel(w, req.URL, req.Filename)
+}
+
+// downloadGGUFModel führt den eigentlichen GGUF-Download durch (SSE für Progress)
+func (app *App) downloadGGUFModel(w http.ResponseWriter, downloadURL, filename string) {
+	// SSE-Header setzen
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming nicht unterstützt", http.StatusInternalServerError)
+		return
+	}
+
+	// Progress Channel
+	progressChan := make(chan llamaserver.DownloadProgress, 100)
+
+	// Download in Goroutine starten
+	go func() {
+		err := app.llamaServer.DownloadModel(downloadURL, filename, progressChan)
+		if err != nil {
+			progressChan <- llamaserver.DownloadProgress{
+				Filename: filename,
+				Percent:  -1, // Fehler-Signal
+			}
+		}
+		close(progressChan)
+	}()
+
+	// Progress-Events senden (benannte Events für EventSource)
+	failed := false
+	for progress := range progressChan {
+		if progress.Percent < 0 {
+			// Fehler-Event
+			failed = true
+			fmt.Fprintf(w, "event: error\ndata: Download fehlgeschlagen\n\n")
+		} else if progress.Verifying {
+			fmt.Fprintf(w, "event: progress\ndata: 100%% | Prüfe SHA-256...\n\n")
+		} else {
+			// Progress-Event im Format: "50% | 2.5 GB / 5.0 GB | 45.2 MB/s"
+			downloadedMB := float64(progress.Downloaded) / (1024 * 1024)
+			totalMB := float64(progress.Total) / (1024 * 1024)
+			speedMBs := progress.Speed // Bereits in MB/s vom llamaserver
+
+			var progressMsg string
+			if totalMB >= 1024 {
+				// GB anzeigen
+				progressMsg = fmt.Sprintf("%.0f%% | %.2f GB / %.2f GB | %.1f MB/s",
+					float64(progress.Percent),
+					downloadedMB/1024,
+					totalMB/1024,
+					speedMBs)
+			} else {
+				// MB anzeigen
+				progressMsg = fmt.Sprintf("%.0f%% | %.0f MB / %.0f MB | %.1f MB/s",
+					float64(progress.Percent),
+					downloadedMB,
+					totalMB,
+					speedMBs)
+			}
+			fmt.Fprintf(w, "event: progress\ndata: %s\n\n", progressMsg)
+		}
+		flusher.Flush()
+	}
+	if failed {
+		return
+	}
+
+	// Complete-Event
+	fmt.Fprintf(w, "event: complete\ndata: Download abgeschlossen: %s\n\n", filename)
+	flusher.Flush()
+}
+
+// handleLlamaServerQueue gibt die Kennzahlen der Slot-Warteschlange zurück
+// GET /api/llamaserver/queue
+func (app *App) handleLlamaServerQueue(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	writeJSON(w, app.llamaServer.QueueMetrics())
+}
+
+// handleLlamaServerPool verwaltet den Modell-Pool (mehrere Modelle gleichzeitig geladen)
+func (app *App) handleLlamaServerPool(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, app.modelPool.Status())
+
+	case http.MethodPost:
+		var req struct {
+			MaxModels   *int `json:"maxModels"`
+			BasePort    *int `json:"basePort"`
+			IdleMinutes *int `json:"idleMinutes"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		config := app.llamaServer.GetPoolConfig()
+		if req.MaxModels != nil {
+			if *req.MaxModels < 1 || *req.MaxModels > 8 {
+				http.Error(w, "maxModels muss zwischen 1 und 8 liegen", http.StatusBadRequest)
+				return
+			}
+			config.MaxModels = *req.MaxModels
+		}
+		if req.BasePort != nil {
+			if *req.BasePort < 1024 || *req.BasePort > 65000 {
+				http.Error(w, "basePort muss zwischen 1024 und 65000 liegen", http.StatusBadRequest)
+				return
+			}
+			config.BasePort = *req.BasePort
+		}
+		if req.IdleMinutes != nil {
+			if *req.IdleMinutes < 0 {
+				http.Error(w, "idleMinutes darf nicht negativ sein", http.StatusBadRequest)
+				return
+			}
+			config.IdleMinutes = *req.IdleMinutes
+		}
+		app.llamaServer.SetPoolConfig(config)
+
+		if err := app.llamaServer.SaveConfig(app.config.DataDir); err != nil {
+			writeJSON(w, map[string]interface{}{
+				"success": false,
+				"error":   err.Error(),
+			})
+			return
+		}
+		writeJSON(w, map[string]interface{}{
+			"success": true,
+			"pool":    app.modelPool.Status(),
+		})
+
+	case http.MethodDelete:
+		model := r.URL.Query().Get("model")
+		if model == "" {
+			http.Error(w, "model required", http.StatusBadRequest)
+			return
+		}
+		if err := app.modelPool.Evict(model); err != nil {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		writeJSON(w, map[string]interface{}{
+			"success": true,
+			"pool":    app.modelPool.Status(),
+		})
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleLlamaServerDraft verwaltet Speculative Decoding (Draft-Modell)
+func (app *App) handleLlamaServerDraft(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		status := app.llamaServer.GetStatus()
+		response := map[string]interface{}{
+			"config":     app.llamaServer.GetDraftConfig(),
+			"draft":      status.Draft,
+			"generation": status.Generation,
+		}
+		if status.ModelPath != "" {
+			candidates, err := app.llamaServer.DraftCandidates(status.ModelPath)
+			if err != nil {
+				log.Printf("Draft-Kandidaten: %v", err)
+			}
+			response["candidates"] = candidates
+		}
+		writeJSON(w, response)
+
+	case http.MethodPost:
+		var req struct {
+			Enabled   *bool    `json:"enabled"`
+			Model     *string  `json:"model"` // Modellname oder leer = automatisch
+			Max       *int     `json:"max"`
+			Min       *int     `json:"min"`
+			PMin      *float64 `json:"pMin"`
+			GPULayers *int     `json:"gpuLayers"`
+			Restart   bool     `json:"restart"` // Sofort mit neuer Konfiguration neu starten
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		config := app.llamaServer.GetDraftConfig()
+		if req.Enabled != nil {
+			config.Enabled = *req.Enabled
+		}
+		if req.Model != nil {
+			config.ModelPath = ""
+			if *req.Model != "" {
+				path, err := app.llamaServer.FindModelByName(*req.Model)
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+				config.ModelPath = path
+			}
+		}
+		if req.Max != nil {
+			if *req.Max < 1 || *req.Max > 64 {
+				http.Error(w, "max muss zwischen 1 und 64 liegen", http.StatusBadRequest)
+				return
+			}
+			config.Max = *req.Max
+		}
+		if req.Min != nil {
+			if *req.Min < 0 || *req.Min > config.Max {
+				http.Error(w, "min muss zwischen 0 und max liegen", http.StatusBadRequest)
+				return
+			}
+			config.Min = *req.Min
+		}
+		if req.PMin != nil {
+			if *req.PMin < 0 || *req.PMin > 1 {
+				http.Error(w, "pMin muss zwischen 0 und 1 liegen", http.StatusBadRequest)
+				return
+			}
+			config.PMin = *req.PMin
+		}
+		if req.GPULayers != nil {
+			config.GPULayers = *req.GPULayers
+		}
+		app.llamaServer.SetDraftConfig(config)
+
+		if err := app.llamaServer.SaveConfig(app.config.DataDir); err != nil {
+			writeJSON(w, map[string]interface{}{
+				"success": false,
+				"error":   err.Error(),
+			})
+			return
+		}
+
+		restarted := false
+		if req.Restart && app.llamaServer.IsRunning() {
+			if err := app.llamaServer.Restart(); err != nil {
+				http.Error(w, "Neustart fehlgeschlagen: "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+			if err := app.llamaServer.WaitForHealthy(60 * time.Second); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			restarted = true
+		}
+		writeJSON(w, map[string]interface{}{
+			"success":   true,
+			"restarted": restarted,
+			"config":    config,
+			"draft":     app.llamaServer.GetStatus().Draft,
+		})
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleLlamaServerDraftBenchmark vergleicht die Generierung mit und ohne Draft-Modell
+func (app *App) handleLlamaServerDraftBenchmark(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var opts llamaserver.DraftBenchmarkOptions
+	if r.ContentLength > 0 {
+		if err := json.NewDecoder(r.Body).Decode(&opts); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+	}
+	if opts.Runs > 10 || opts.MaxTokens > 2048 {
+		http.Error(w, "runs maximal 10, maxTokens maximal 2048", http.StatusBadRequest)
+		return
+	}
+
+	result, err := app.llamaServer.BenchmarkDraft(r.Context(), opts)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	writeJSON(w, result)
+}
+
+// handleLlamaServerBenchmark - GET/POST/DELETE /api/llamaserver/benchmark
+// Benchmark-Job: pp/tg Tokens/s, Zeit bis zum ersten Token und VRAM-Spitze je Context-Größe
+func (app *App) handleLlamaServerBenchmark(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, map[string]interface{}{
+			"job": app.benchmarkService.Status(),
+		})
+
+	case http.MethodPost:
+		var req benchmark.StartRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		job, err := app.benchmarkService.Start(req)
+		if errors.Is(err, benchmark.ErrJobRunning) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		writeJSON(w, map[string]interface{}{
+			"success": true,
+			"job":     job,
+			"message": "Benchmark gestartet - Modelle werden nacheinander geladen, der Chat ist währenddessen eingeschränkt",
+		})
+
+	case http.MethodDelete:
+		writeJSON(w, map[string]interface{}{
+			"success": app.benchmarkService.Cancel(),
+		})
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleLlamaServerBenchmarkResults - GET /api/llamaserver/benchmark/results
+// Gespeicherte Messungen dieses Rechners (neueste zuerst) und die letzten Läufe
+func (app *App) handleLlamaServerBenchmarkResults(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	results, err := app.benchmarkService.History(r.URL.Query().Get("model"), limit)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	runs, err := app.benchmarkService.Runs(20)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, map[string]interface{}{
+		"results": results,
+		"runs":    runs,
+	})
+}
+
+// handleLlamaServerBenchmarkCompare - GET /api/llamaserver/benchmark/compare
+// Vergleich der letzten mit der vorigen Messung (z.B. nach Treiber- oder llama.cpp-Update)
+func (app *App) handleLlamaServerBenchmarkCompare(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
-	// Download-Logik ausgelagert
-	app.downloadGGUFModel(w, req.URL, req.Filename)
-}
-
-// downloadGGUFModel führt den eigentlichen GGUF-Download durch (SSE für Progress)
-func (app *App) downloadGGUFModel(w http.ResponseWriter, downloadURL, filename string) {
-	// SSE-Header setzen
-	w.Header().Set("Content-Type", "text/event-stream")
-	w.Header().Set("Cache-Control", "no-cache")
-	w.Header().Set("Connection", "keep-alive")
-	w.Header().Set("Access-Control-Allow-Origin", "*")
-
-	flusher, ok := w.(http.Flusher)
-	if !ok {
-		http.Error(w, "Streaming nicht unterstützt", http.StatusInternalServerError)
+	comparisons, err := app.benchmarkService.Compare()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	writeJSON(w, map[string]interface{}{
+		"comparisons": comparisons,
+	})
+}
 
-	// Progress Channel
-	progressChan := make(chan llamaserver.DownloadProgress, 100)
-
-	// Download in Goroutine starten
-	go func() {
-		err := app.llamaServer.DownloadModel(downloadURL, filename, progressChan)
+// handleLlamaServerSlotCache - GET/DELETE /api/llamaserver/slot-cache
+// Gespeicherte Slot-Zustände (Prompt-Cache je Experte) auflisten bzw. löschen
+func (app *App) handleLlamaServerSlotCache(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		entries, err := app.llamaServer.SlotCacheEntries()
 		if err != nil {
-			progressChan <- llamaserver.DownloadProgress{
-				Filename: filename,
-				Percent:  -1, // Fehler-Signal
-			}
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
 		}
-		close(progressChan)
-	}()
-
-	// Progress-Events senden (benannte Events für EventSource)
-	for progress := range progressChan {
-		if progress.Percent < 0 {
-			// Fehler-Event
-			fmt.Fprintf(w, "event: error\ndata: Download fehlgeschlagen\n\n")
-		} else {
-			// Progress-Event im Format: "50% | 2.5 GB / 5.0 GB | 45.2 MB/s"
-			downloadedMB := float64(progress.Downloaded) / (1024 * 1024)
-			totalMB := float64(progress.Total) / (1024 * 1024)
-			speedMBs := progress.Speed // Bereits in MB/s vom llamaserver
+		var totalMB float64
+		for _, e := range entries {
+			totalMB += e.SizeMB
+		}
+		config := app.llamaServer.GetConfig()
+		writeJSON(w, map[string]interface{}{
+			"enabled":        config.SlotSavePath != "",
+			"slotSavePath":   config.SlotSavePath,
+			"cacheTypeK":     config.CacheTypeK,
+			"cacheTypeV":     config.CacheTypeV,
+			"flashAttention": config.FlashAttention,
+			"entries":        entries,
+			"totalMB":        totalMB,
+		})
 
-			var progressMsg string
-			if totalMB >= 1024 {
-				// GB anzeigen
-				progressMsg = fmt.Sprintf("%.0f%% | %.2f GB / %.2f GB | %.1f MB/s",
-					float64(progress.Percent),
-					downloadedMB/1024,
-					totalMB/1024,
-					speedMBs)
-			} else {
-				// MB anzeigen
-				progressMsg = fmt.Sprintf("%.0f%% | %.0f MB / %.0f MB | %.1f MB/s",
-					float64(progress.Percent),
-					downloadedMB,
-					totalMB,
-					speedMBs)
-			}
-			fmt.Fprintf(w, "event: progress\ndata: %s\n\n", progressMsg)
+	case http.MethodDelete:
+		removed, err := app.llamaServer.ClearSlotCache(r.URL.Query().Get("key"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
 		}
-		flusher.Flush()
-	}
+		writeJSON(w, map[string]interface{}{
+			"success": true,
+			"removed": removed,
+		})
 
-	// Complete-Event
-	fmt.Fprintf(w, "event: complete\ndata: Download abgeschlossen: %s\n\n", filename)
-	flusher.Flush()
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
 }
 
 // handleLlamaServerConfig gibt die Konfiguration zurück oder aktualisiert sie
@@ -6525,9 +8225,15 @@ func (app *App) handleLlamaServerConfig(w http.ResponseWriter, r *http.Request)
 
 	case http.MethodPost:
 		var req struct {
-			GPULayers   *int `json:"gpuLayers"`
-			ContextSize *int `json:"contextSize"`
-			Threads     *int `json:"threads"`
+			GPULayers     *int `json:"gpuLayers"`
+			ContextSize   *int `json:"contextSize"`
+			Threads       *int `json:"threads"`
+			ParallelSlots *int `json:"parallelSlots"` // wirkt beim nächsten Start
+			// KV-Cache und Prompt-Cache (wirken beim nächsten Start)
+			CacheTypeK     *string `json:"cacheTypeK"`
+			CacheTypeV     *string `json:"cacheTypeV"`
+			FlashAttention *string `json:"flashAttention"`
+			SlotSavePath   *string `json:"slotSavePath"` // leer = Prompt-Cache aus
 		}
 
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -6546,6 +8252,34 @@ func (app *App) handleLlamaServerConfig(w http.ResponseWriter, r *http.Request)
 		if req.Threads != nil {
 			config.Threads = *req.Threads
 		}
+		if req.ParallelSlots != nil {
+			if *req.ParallelSlots < 1 || *req.ParallelSlots > 16 {
+				http.Error(w, "parallelSlots muss zwischen 1 und 16 liegen", http.StatusBadRequest)
+				return
+			}
+			app.llamaServer.SetParallelSlots(*req.ParallelSlots)
+			config.ParallelSlots = *req.ParallelSlots
+		}
+		// KV-Cache und Flash Attention wirken beim nächsten Start
+		if req.CacheTypeK != nil || req.CacheTypeV != nil || req.FlashAttention != nil {
+			if req.CacheTypeK != nil {
+				config.CacheTypeK = *req.CacheTypeK
+			}
+			if req.CacheTypeV != nil {
+				config.CacheTypeV = *req.CacheTypeV
+			}
+			if req.FlashAttention != nil {
+				config.FlashAttention = *req.FlashAttention
+			}
+			if err := app.llamaServer.SetKVCache(config.CacheTypeK, config.CacheTypeV, config.FlashAttention); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		if req.SlotSavePath != nil {
+			app.llamaServer.SetSlotSavePath(*req.SlotSavePath)
+			config.SlotSavePath = *req.SlotSavePath
+		}
 
 		// Konfiguration speichern
 		if err := app.llamaServer.SaveConfig(app.config.DataDir); err != nil {
@@ -6664,7 +8398,9 @@ func (app *App) handleLlamaServerVRAMSettings(w http.ResponseWriter, r *http.Req
 	}
 }
 
-// handleLlamaServerVRAMInfo gibt nur die aktuellen VRAM-Informationen zurück
+// handleLlamaServerVRAMInfo gibt die aktuellen VRAM-Informationen und den Offload-Plan zurück.
+// Ohne Parameter: Plan des geladenen Modells. Mit ?model=...&context=...&cacheType=...
+// wird ein Modell gegen den aktuell freien VRAM geplant.
 func (app *App) handleLlamaServerVRAMInfo(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -6672,7 +8408,80 @@ func (app *App) handleLlamaServerVRAMInfo(w http.ResponseWriter, r *http.Request
 	}
 
 	info := llamaserver.GetVRAMInfo()
-	writeJSON(w, info)
+	response := struct {
+		llamaserver.VRAMInfo
+		Plan *llamaserver.VRAMPlan `json:"plan,omitempty"`
+	}{VRAMInfo: info}
+
+	query := r.URL.Query()
+	if modelName := query.Get("model"); modelName != "" {
+		modelPath, err := app.llamaServer.FindModelByName(modelName)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Modell nicht gefunden: %s", modelName), http.StatusNotFound)
+			return
+		}
+
+		contextSize := app.llamaServer.GetContextSize()
+		if c, err := strconv.Atoi(query.Get("context")); err == nil && c > 0 {
+			contextSize = c
+		}
+		if maxContext := llamaserver.GetModelMaxContext(modelPath); contextSize > maxContext {
+			contextSize = maxContext
+		}
+
+		response.Plan = app.llamaServer.PlanVRAMWithCacheType(modelPath, contextSize, query.Get("cacheType"))
+	} else {
+		response.Plan = app.llamaServer.CurrentPlan()
+	}
+
+	writeJSON(w, response)
+}
+
+// handleLlamaServerModelsVerify prüft installierte GGUF-Modelle per SHA-256.
+// GET: Prüfsummen-Katalog, POST {"model": "..."}: Dateien erneut prüfen (ohne model: alle)
+func (app *App) handleLlamaServerModelsVerify(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		entries, err := app.llamaServer.GetChecksumCatalog()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, map[string]interface{}{
+			"entries": entries,
+			"count":   len(entries),
+		})
+
+	case http.MethodPost:
+		var req struct {
+			Model string `json:"model"`
+		}
+		if r.ContentLength > 0 {
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, "Invalid request body", http.StatusBadRequest)
+				return
+			}
+		}
+
+		results, err := app.llamaServer.VerifyInstalledModels(req.Model)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+
+		summary := make(map[string]int)
+		for _, result := range results {
+			summary[result.Status]++
+		}
+		writeJSON(w, map[string]interface{}{
+			"success": summary[integrity.StatusMismatch]+summary[integrity.StatusTruncated]+summary[integrity.StatusError] == 0,
+			"results": results,
+			"summary": summary,
+		})
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
 }
 
 // handleLlamaServerVRAMClear löscht manuell den VRAM
@@ -6682,7 +8491,8 @@ func (app *App) handleLlamaServerVRAMClear(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	// Server stoppen falls läuft
+	// Server stoppen falls läuft (inkl. Pool-Instanzen, der Pool bleibt nutzbar)
+	app.modelPool.StopAll()
 	if app.llamaServer.IsRunning() {
 		app.llamaServer.Stop()
 	}
@@ -7212,14 +9022,19 @@ func (app *App) handleSystemDBSize(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	// Anhänge liegen seit dem Blob-Speicher außerhalb der DB - separat ausweisen
+	attachmentsSize := app.chatStore.AttachmentStorageSize()
+
 	// Frontend-kompatibles Format (sizeBytes, formatted)
 	formatted := formatBytesForDisplay(totalSize)
 
 	writeJSON(w, map[string]interface{}{
-		"sizeBytes":   totalSize,
-		"formatted":   formatted,
-		"totalSize":   totalSize,
-		"totalSizeMB": float64(totalSize) / 1024 / 1024,
+		"sizeBytes":            totalSize,
+		"formatted":            formatted,
+		"totalSize":            totalSize,
+		"totalSizeMB":          float64(totalSize) / 1024 / 1024,
+		"attachmentsBytes":     attachmentsSize,
+		"attachmentsFormatted": formatBytesForDisplay(attachmentsSize),
 	})
 }
 
@@ -7710,20 +9525,27 @@ func (app *App) handleSearchSettings(w http.ResponseWriter, r *http.Request) {
 			"queryOptimizationModel":   settings.OptimizationModel,
 			"monthlySearchCount":       monthlyCount,
 			"searchCount":              monthlyCount,
-			"searchLimit":              2000,
-			"remainingSearches":        2000 - monthlyCount,
+			"searchLimit":              search.BraveMonthlyLimit,
+			"remainingSearches":        search.BraveMonthlyLimit - monthlyCount,
 			"currentMonth":             currentMonth,
+			"enableMultiQuery":         settings.EnableMultiQuery,
+			"multiQueryCount":          settings.MultiQueryCount,
+			"backends":                 maskBackendKeys(settings.Backends),
+			"availableBackends":        app.searchService.RegisteredBackends(),
 		})
 
 	case http.MethodPost:
 		var req struct {
-			BraveAPIKey         string   `json:"braveApiKey"`
-			SearXNGInstances    []string `json:"searxngInstances"`
-			CustomSearXNG       string   `json:"customSearxng"`
-			EnableQueryOptimize bool     `json:"enableQueryOptimize"`
-			EnableContentFetch  bool     `json:"enableContentFetch"`
-			EnableReRanking     bool     `json:"enableReRanking"`
-			OptimizationModel   string   `json:"optimizationModel"`
+			BraveAPIKey         string                 `json:"braveApiKey"`
+			SearXNGInstances    []string               `json:"searxngInstances"`
+			CustomSearXNG       string                 `json:"customSearxng"`
+			EnableQueryOptimize bool                   `json:"enableQueryOptimize"`
+			EnableContentFetch  bool                   `json:"enableContentFetch"`
+			EnableReRanking     bool                   `json:"enableReRanking"`
+			OptimizationModel   string                 `json:"optimizationModel"`
+			EnableMultiQuery    bool                   `json:"enableMultiQuery"`
+			MultiQueryCount     int                    `json:"multiQueryCount"` // Umformulierungen je Suche (0 = unverändert)
+			Backends            []search.BackendConfig `json:"backends"` // Reihenfolge = Fallback-Kette
 		}
 
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -7744,11 +9566,31 @@ func (app *App) handleSearchSettings(w http.ResponseWriter, r *http.Request) {
 		settings.EnableQueryOptimize = req.EnableQueryOptimize
 		settings.EnableContentFetch = req.EnableContentFetch
 		settings.EnableReRanking = req.EnableReRanking
+		settings.EnableMultiQuery = req.EnableMultiQuery
+		if req.MultiQueryCount > 0 {
+			settings.MultiQueryCount = req.MultiQueryCount
+		}
 		if req.OptimizationModel != "" {
 			settings.OptimizationModel = req.OptimizationModel
 		}
+		if req.Backends != nil {
+			// Maskierte API Keys aus der Anzeige nicht übernehmen
+			existing := make(map[string]string)
+			for _, b := range settings.Backends {
+				existing[strings.ToLower(b.Name)] = b.APIKey
+			}
+			for i := range req.Backends {
+				if strings.Contains(req.Backends[i].APIKey, "****") {
+					req.Backends[i].APIKey = existing[strings.ToLower(req.Backends[i].Name)]
+				}
+			}
+			settings.Backends = req.Backends
+		}
 
-		app.searchService.UpdateSettings(settings)
+		if err := app.searchService.UpdateSettings(settings); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 
 		writeJSON(w, map[string]interface{}{
 			"success": true,
@@ -7801,7 +9643,7 @@ func (app *App) handleSearchTest(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
 	defer cancel()
 
-	results, err := app.searchService.Search(ctx, req.Query, opts)
+	results, subQueries, err := app.searchService.SearchWithReport(ctx, req.Query, opts)
 	if err != nil {
 		writeJSON(w, map[string]interface{}{
 			"success": false,
@@ -7812,10 +9654,11 @@ func (app *App) handleSearchTest(w http.ResponseWriter, r *http.Request) {
 	}
 
 	writeJSON(w, map[string]interface{}{
-		"success": true,
-		"query":   req.Query,
-		"count":   len(results),
-		"results": results,
+		"success":    true,
+		"query":      req.Query,
+		"count":      len(results),
+		"results":    results,
+		"subQueries": subQueries, // Nur bei Multi-Query: Beitrag je Umformulierung
 	})
 }
 
@@ -7830,6 +9673,16 @@ func maskAPIKey(key string) string {
 	return key[:4] + "****" + key[len(key)-4:]
 }
 
+// maskBackendKeys maskiert die API Keys der Such-Backends für die Anzeige
+func maskBackendKeys(backends []search.BackendConfig) []search.BackendConfig {
+	masked := make([]search.BackendConfig, len(backends))
+	for i, b := range backends {
+		b.APIKey = maskAPIKey(b.APIKey)
+		masked[i] = b
+	}
+	return masked
+}
+
 // handleSearchStatus gibt den aktuellen Such-Status zurück
 func (app *App) handleSearchStatus(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
@@ -7852,7 +9705,8 @@ func (app *App) handleSearchStatus(w http.ResponseWriter, r *http.Request) {
 		"searxngInstances":    len(settings.SearXNGInstances),
 		"monthlySearchCount":  monthlyCount,
 		"currentMonth":        currentMonth,
-		"braveMonthlyLimit":   2000, // Free tier limit
+		"braveMonthlyLimit":   search.BraveMonthlyLimit,
+		"backends":            app.searchService.BackendStatus(), // Reihenfolge, Health und Latenz je Backend
 	})
 }
 
@@ -7902,7 +9756,7 @@ func (app *App) handleSearchExecute(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
 	defer cancel()
 
-	results, err := app.searchService.Search(ctx, req.Query, opts)
+	results, subQueries, err := app.searchService.SearchWithReport(ctx, req.Query, opts)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -7914,6 +9768,9 @@ func (app *App) handleSearchExecute(w http.ResponseWriter, r *http.Request) {
 		"count":   len(results),
 		"results": results,
 	}
+	if subQueries != nil {
+		response["subQueries"] = subQueries
+	}
 
 	// Optional: Format für LLM Context
 	if req.FormatForLLM {
@@ -8114,6 +9971,9 @@ func (app *App) handleModelStoreDownload(w http.ResponseWriter, r *http.Request)
 				flusher.Flush()
 				return
 			}
+			if !verifyModelDownloadSSE(w, flusher, destPath, downloadURL, modelEntry.SHA256) {
+				return
+			}
 			fmt.Fprintf(w, "event: complete\ndata: Download war bereits komplett\n\n")
 			flusher.Flush()
 			log.Printf("✅ Teildatei umbenannt: %s -> %s", tempPath, destPath)
@@ -8305,6 +10165,10 @@ func (app *App) handleModelStoreDownload(w http.ResponseWriter, r *http.Request)
 	totalTime := time.Since(startTime)
 	avgSpeed := float64(downloaded) / totalTime.Seconds() / (1024 * 1024)
 
+	if !verifyModelDownloadSSE(w, flusher, destPath, downloadURL, modelEntry.SHA256) {
+		return
+	}
+
 	fmt.Fprintf(w, "event: progress\ndata: ✅ Download abgeschlossen! (%.1f MB/s durchschnittlich)\n\n", avgSpeed)
 	flusher.Flush()
 	fmt.Fprintf(w, "event: progress\ndata: 📁 Gespeichert unter: %s\n\n", destPath)
@@ -8316,6 +10180,38 @@ func (app *App) handleModelStoreDownload(w http.ResponseWriter, r *http.Request)
 	log.Printf("✅ Download abgeschlossen: %s -> %s", modelEntry.DisplayName, destPath)
 }
 
+// verifyModelDownloadSSE prüft die SHA-256 eines heruntergeladenen Modells
+// (Registry oder HuggingFace LFS-Metadaten) und meldet das Ergebnis per SSE.
+// Beschädigte Dateien werden gelöscht.
+func verifyModelDownloadSSE(w http.ResponseWriter, flusher http.Flusher, destPath, downloadURL, expectedSHA256 string) bool {
+	fmt.Fprintf(w, "event: progress\ndata: 🔍 Prüfe SHA-256...\n\n")
+	flusher.Flush()
+
+	source := integrity.SourceRegistry
+	if expectedSHA256 == "" {
+		expectedSHA256 = integrity.FetchExpectedSHA256(downloadURL)
+		source = integrity.SourceHuggingFace
+	}
+
+	entry, err := integrity.VerifyDownload(destPath, expectedSHA256, source)
+	if err != nil {
+		log.Printf("❌ Download beschädigt, lösche %s: %v", filepath.Base(destPath), err)
+		os.Remove(destPath)
+		integrity.Forget(destPath)
+		fmt.Fprintf(w, "event: error\ndata: ❌ Download beschädigt (%v) - bitte erneut herunterladen\n\n", err)
+		flusher.Flush()
+		return false
+	}
+
+	if entry.Verified {
+		fmt.Fprintf(w, "event: progress\ndata: ✅ SHA-256 bestätigt (%s)\n\n", entry.SHA256[:16])
+	} else {
+		fmt.Fprintf(w, "event: progress\ndata: ⚠️ Keine Referenz-Prüfsumme verfügbar, SHA-256 lokal erfasst\n\n")
+	}
+	flusher.Flush()
+	return true
+}
+
 // extractParamSize extrahiert die Parametergröße (z.B. "70B") aus Model-Name oder Tags
 func extractParamSize(modelName string, tags []string) string {
 	// Pattern für Parametergrößen: 1.5B, 7B, 13B, 70B, 72B etc.
@@ -8848,13 +10744,27 @@ func (app *App) handleHuggingFaceDetails(w http.ResponseWriter, r *http.Request)
 			}
 			quant := extractQuantization(sibling.RFilename)
 
-			ggufFiles = append(ggufFiles, map[string]interface{}{
+			fileInfo := map[string]interface{}{
 				"filename":     sibling.RFilename,
 				"downloadUrl":  fmt.Sprintf("https://huggingface.co/%s/resolve/main/%s", modelId, sibling.RFilename),
 				"sizeBytes":    sizeBytes,
 				"sizeHuman":    sizeHuman,
 				"quantization": quant,
-			})
+			}
+
+			// Bereits heruntergeladen: Exakte Werte aus dem GGUF-Header statt Dateinamen-Heuristik
+			if local := app.localGGUFInfo(sibling.RFilename); local != nil {
+				if local.Quantization != "" {
+					fileInfo["quantization"] = local.Quantization
+				}
+				fileInfo["sizeBytes"] = local.FileSize
+				fileInfo["sizeHuman"] = formatFileSize(local.FileSize)
+				fileInfo["downloaded"] = true
+				fileInfo["architecture"] = local.Architecture
+				fileInfo["contextLength"] = local.ContextLength
+			}
+
+			ggufFiles = append(ggufFiles, fileInfo)
 			siblings = append(siblings, sibling.RFilename)
 		}
 	}
@@ -8928,7 +10838,20 @@ func formatFileSize(bytes int64) string {
 	}
 }
 
-// estimateGGUFSize schätzt die Größe einer GGUF-Datei basierend auf dem Dateinamen
+// localGGUFInfo liest den GGUF-Header einer bereits heruntergeladenen Datei
+// (nil wenn die Datei nicht lokal vorhanden oder nicht lesbar ist)
+func (app *App) localGGUFInfo(filename string) *gguf.ModelInfo {
+	base := filepath.Base(filename)
+	for _, dir := range []string{app.config.ModelsDir, filepath.Join(app.config.ModelsDir, "library")} {
+		if info, err := gguf.ReadInfo(filepath.Join(dir, base)); err == nil {
+			return info
+		}
+	}
+	return nil
+}
+
+// estimateGGUFSize schätzt die Größe einer GGUF-Datei basierend auf dem Dateinamen.
+// Nur für Dateien die noch nicht lokal vorliegen (siehe localGGUFInfo).
 func estimateGGUFSize(filename string) (int64, string) {
 	nameLower := strings.ToLower(filename)
 
@@ -8982,7 +10905,8 @@ func estimateGGUFSize(filename string) (int64, string) {
 	return sizeBytes, sizeHuman
 }
 
-// extractQuantization extrahiert die Quantisierung aus dem Dateinamen
+// extractQuantization extrahiert die Quantisierung aus dem Dateinamen.
+// Nur für Dateien die noch nicht lokal vorliegen (siehe localGGUFInfo).
 func extractQuantization(filename string) string {
 	nameLower := strings.ToLower(filename)
 	// Sortiert nach Spezifität (längere Patterns zuerst)
@@ -9430,6 +11354,21 @@ func (app *App) getInstalledVoicesForLocale(locale string) []string {
 // llamaServerWrapper implementiert chat.LlamaServerChatter für llamaserver.Server
 type llamaServerWrapper struct {
 	server *llamaserver.Server
+	pool   *llamaserver.ModelPool
+}
+
+// route wählt den Server für das gewünschte Modell. Ohne Modell, ohne Pool oder
+// wenn das Modell nicht zusätzlich geladen werden kann, antwortet das Hauptmodell.
+func (w *llamaServerWrapper) route(ctx context.Context, model string) (*llamaserver.Server, func()) {
+	if model == "" || w.pool == nil || !w.pool.Enabled() {
+		return w.server, func() {}
+	}
+	srv, release, err := w.pool.Acquire(ctx, model)
+	if err != nil {
+		log.Printf("⚠️ Modell-Pool: %s nicht verfügbar (%v) - verwende Hauptmodell", model, err)
+		return w.server, func() {}
+	}
+	return srv, release
 }
 
 // StreamChat implementiert chat.LlamaServerChatter Interface
@@ -9464,6 +11403,78 @@ func (w *llamaServerWrapper) StreamChatWithParams(messages []chat.LlamaMessage,
 	return w.server.StreamChatWithParams(llamaMessages, llamaParams, onChunk)
 }
 
+// StreamChatWithInfo implementiert chat.LlamaServerChatter Interface mit Priorität für die Slot-Warteschlange
+func (w *llamaServerWrapper) StreamChatWithInfo(info chat.LlamaRequestInfo, messages []chat.LlamaMessage, params chat.LlamaSamplingParams, onChunk func(content string, done bool)) error {
+	llamaMessages := make([]llamaserver.ChatMessage, len(messages))
+	for i, msg := range messages {
+		llamaMessages[i] = llamaserver.ChatMessage{
+			Role:    msg.Role,
+			Content: msg.Content,
+		}
+	}
+	llamaParams := llamaserver.SamplingParams{
+		Temperature: params.Temperature,
+		TopP:        params.TopP,
+		MaxTokens:   params.MaxTokens,
+	}
+	priority := llamaserver.PriorityInteractive
+	if info.Background {
+		priority = llamaserver.PriorityBackground
+	}
+	ctx := llamaserver.WithRequestInfo(context.Background(), llamaserver.RequestInfo{
+		Priority: priority,
+		User:     info.User,
+	})
+	srv, release := w.route(ctx, info.Model)
+	defer release()
+	return srv.StreamChatWithContext(ctx, llamaMessages, llamaParams, onChunk)
+}
+
+// GenerateJSON implementiert chat.LlamaServerChatter Interface mit erzwungener JSON-Ausgabe
+func (w *llamaServerWrapper) GenerateJSON(info chat.LlamaRequestInfo, messages []chat.LlamaMessage, schema json.RawMessage, out interface{}) error {
+	parsed, err := llamaserver.ParseSchema(schema)
+	if err != nil {
+		return err
+	}
+	llamaMessages := make([]llamaserver.ChatMessage, len(messages))
+	for i, msg := range messages {
+		llamaMessages[i] = llamaserver.ChatMessage{
+			Role:    msg.Role,
+			Content: msg.Content,
+		}
+	}
+	priority := llamaserver.PriorityInteractive
+	if info.Background {
+		priority = llamaserver.PriorityBackground
+	}
+	ctx := llamaserver.WithRequestInfo(context.Background(), llamaserver.RequestInfo{
+		Priority: priority,
+		User:     info.User,
+	})
+	srv, release := w.route(ctx, info.Model)
+	defer release()
+	return srv.GenerateJSON(ctx, llamaMessages, parsed, out)
+}
+
+// mateModel bestimmt das Modell eines Mates: eigenes Modell aus der Mate-Konfiguration,
+// sonst E-Mail-Modell für Mail-Mates bzw. Coder-Modell für Coder-Mates (leer = Hauptmodell)
+func (app *App) mateModel(mateID string) string {
+	if model, _, _, err := app.pairingManager.GetMateConfig(mateID); err == nil && model != "" {
+		return model
+	}
+	mate, ok := app.pairingManager.GetTrustedMateByID(mateID)
+	if !ok {
+		return ""
+	}
+	switch mate.Type {
+	case "mail", "email":
+		return app.settingsService.GetEmailModel()
+	case "coder":
+		return app.settingsService.GetCoderModel()
+	}
+	return ""
+}
+
 // IsRunning implementiert chat.LlamaServerChatter Interface
 func (w *llamaServerWrapper) IsRunning() bool {
 	return w.server.IsRunning()
@@ -10043,8 +12054,13 @@ WICHTIG: Schreibe NUR den reinen Dokumenttext ohne Einleitung und ohne abschlie
 			{Role: "user", Content: req.Prompt},
 		}
 
+		// Der Benutzer wartet auf das Dokument - interaktive Priorität
+		genCtx := llamaserver.WithRequestInfo(r.Context(), llamaserver.RequestInfo{
+			Priority: llamaserver.PriorityInteractive,
+			User:     app.queueUserKey(r),
+		})
 		var generatedText strings.Builder
-		err := app.llamaServer.StreamChat(messages, func(chunk string, done bool) {
+		err := app.llamaServer.StreamChatWithContext(genCtx, messages, llamaserver.DefaultSamplingParams(), func(chunk string, done bool) {
 			generatedText.WriteString(chunk)
 		})
 		if err != nil {
@@ -10287,6 +12303,7 @@ func (app *App) handleModelStoreByID(w http.ResponseWriter, r *http.Request) {
 				}
 				return
 			}
+			integrity.Forget(modelPath)
 
 			writeJSON(w, map[string]interface{}{
 				"success": true,
@@ -11325,6 +13342,56 @@ Ausgabe: GmbH gründen Anleitung Deutschland Schritte`
 	return optimized
 }
 
+// queryVariantListPrefix erkennt Aufzählungszeichen und Nummerierung ("- ", "2. ", "3) ")
+var queryVariantListPrefix = regexp.MustCompile(`^\s*(?:[-*•]|\d+[.)])\s+`)
+
+// cleanQueryVariant entfernt Listenpräfix und Anführungszeichen einer Umformulierung.
+// Führende Zahlen ohne Listenzeichen ("2024 Steuern") bleiben erhalten.
+func cleanQueryVariant(line string) string {
+	line = queryVariantListPrefix.ReplaceAllString(strings.TrimSpace(line), "")
+	return strings.Trim(line, `"'`)
+}
+
+// searchQueryVariants lässt das LLM n Umformulierungen einer Suchanfrage erzeugen (Multi-Query-Suche).
+// Jede Variante soll einen anderen Aspekt oder andere Fachbegriffe abdecken.
+func (app *App) searchQueryVariants(ctx context.Context, query string, n int) ([]string, error) {
+	if app.llamaServer == nil || !app.llamaServer.IsRunning() {
+		return nil, fmt.Errorf("llama-server läuft nicht")
+	}
+
+	systemPrompt := fmt.Sprintf(`Du erzeugst alternative Suchanfragen für eine Web-Suchmaschine.
+Formuliere die Suchanfrage des Benutzers %d-mal unterschiedlich um.
+
+REGELN:
+1. Jede Variante deckt einen anderen Aspekt ab oder verwendet andere Begriffe (Synonyme, Fachbegriffe, Englisch)
+2. Jede Variante ist 3-8 Wörter lang
+3. Genau eine Variante pro Zeile
+4. Keine Nummerierung, keine Anführungszeichen, keine Erklärungen`, n)
+
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+
+	result, err := app.llamaServer.QuickChatWithContext(ctx, systemPrompt, query)
+	if err != nil {
+		return nil, fmt.Errorf("Umformulierung fehlgeschlagen: %w", err)
+	}
+
+	var variants []string
+	for _, line := range strings.Split(result, "\n") {
+		// Aufzählungszeichen und Nummerierung entfernen, falls das Modell sie trotzdem liefert
+		line = cleanQueryVariant(line)
+		if len(line) >= 3 {
+			variants = append(variants, line)
+		}
+	}
+	if len(variants) > n {
+		variants = variants[:n]
+	}
+
+	log.Printf("Multi-Query: '%s' -> %d Varianten", query, len(variants))
+	return variants, nil
+}
+
 // formatSearchContextEnhanced erstellt einen verbesserten Kontext aus Suchergebnissen
 // mit RAG-ähnlicher Struktur für bessere LLM-Integration
 func (app *App) formatSearchContextEnhanced(results []search.SearchResult, userQuestion, searchQuery string) string {
@@ -1,6 +1,16 @@
 package main
 
-import "testing"
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"fleet-navigator/internal/chat"
+	"fleet-navigator/internal/llamaserver"
+	"fleet-navigator/internal/tools"
+)
 
 // TestDetermineFileType testet die Dateityp-Erkennung
 func TestDetermineFileType(t *testing.T) {
@@ -158,3 +168,86 @@ func TestIsIdentityOrPersonalQuestion(t *testing.T) {
 		})
 	}
 }
+
+// TestChatToolDefinitions prüft die Tool-Auswahl für den Experten-Chat
+func TestChatToolDefinitions(t *testing.T) {
+	app := &App{toolRegistry: tools.NewRegistry()}
+
+	// Ohne Web-Suche: keine Web-Tools, Mate-Tools (file_search) nie
+	if defs := app.chatToolDefinitions(false); len(defs) != 0 {
+		t.Errorf("Ohne Web-Suche: %+v", defs)
+	}
+	defs := app.chatToolDefinitions(true)
+	if len(defs) != 2 || defs[0].Function.Name != "web_fetch" || defs[1].Function.Name != "web_search" || defs[0].Type != "function" {
+		t.Fatalf("Mit Web-Suche: %+v", defs)
+	}
+
+	offered := map[string]bool{"web_search": true}
+	call := llamaserver.ToolCall{}
+	call.Function.Name = "file_search"
+	if result := app.executeChatToolCall(context.Background(), call, offered); result.Success {
+		t.Error("Nicht angebotenes Tool ausgeführt")
+	}
+	call.Function.Name = "web_search"
+	call.Function.Arguments = "{kaputt"
+	if result := app.executeChatToolCall(context.Background(), call, offered); result.Success || !strings.Contains(result.Error, "Argumente") {
+		t.Errorf("Ungültige Argumente: %+v", result)
+	}
+}
+
+// TestBuildConversationHistory prüft dass Systemnachrichten nicht als Benutzer-Nachricht gesendet werden
+func TestBuildConversationHistory(t *testing.T) {
+	at := time.Date(2026, 3, 2, 9, 30, 0, 0, time.UTC)
+	messages := []chat.StoredMessage{
+		{Role: "USER", Content: "Wie steht der DAX?"},
+		{Role: "ASSISTANT", Content: "Bei 18.000 Punkten."},
+		{Role: "SYSTEM", Content: "Alarm: DAX unter 17.500", CreatedAt: at},
+		{Role: "USER", Content: "Und jetzt?"},
+	}
+
+	history, notices := buildConversationHistory(messages)
+	if len(history) != 3 || history[0].Role != "user" || history[1].Role != "assistant" || history[2].Content != "Und jetzt?" {
+		t.Fatalf("History: %+v", history)
+	}
+	for _, m := range history {
+		if strings.Contains(m.Content, "Alarm") {
+			t.Errorf("Systemnachricht in der History: %+v", m)
+		}
+	}
+	if !strings.Contains(notices, "- [02.03.2026 09:30] Alarm: DAX unter 17.500") {
+		t.Errorf("Hinweise: %q", notices)
+	}
+
+	// Ohne Systemnachrichten kein Hinweis-Block
+	if _, notices := buildConversationHistory(messages[:2]); notices != "" {
+		t.Errorf("Unerwartete Hinweise: %q", notices)
+	}
+
+	// Nur die neuesten maxSystemNotices
+	var many []chat.StoredMessage
+	for i := 0; i < maxSystemNotices+2; i++ {
+		many = append(many, chat.StoredMessage{Role: "SYSTEM", Content: fmt.Sprintf("Alarm %d", i)})
+	}
+	if _, notices := buildConversationHistory(many); strings.Contains(notices, "Alarm 0") || !strings.Contains(notices, fmt.Sprintf("Alarm %d", maxSystemNotices+1)) {
+		t.Errorf("Begrenzung: %q", notices)
+	}
+}
+
+// TestCleanQueryVariant prüft, dass nur echte Listenpräfixe entfernt werden
+func TestCleanQueryVariant(t *testing.T) {
+	tests := map[string]string{
+		"1. GmbH gründen Kosten":     "GmbH gründen Kosten",
+		"  2) Notar Gebühren GmbH":   "Notar Gebühren GmbH",
+		"- Stammkapital Einzahlung":  "Stammkapital Einzahlung",
+		"• \"GmbH Gründung Ablauf\"": "GmbH Gründung Ablauf",
+		"2024 Steuererklärung Frist": "2024 Steuererklärung Frist",
+		"3D Drucker Vergleich":       "3D Drucker Vergleich",
+		"1.5 Grad Ziel Klimapolitik": "1.5 Grad Ziel Klimapolitik",
+		"-Minus ohne Leerzeichen":    "-Minus ohne Leerzeichen",
+	}
+	for in, want := range tests {
+		if got := cleanQueryVariant(in); got != want {
+			t.Errorf("cleanQueryVariant(%q) = %q, erwartet %q", in, got, want)
+		}
+	}
+}
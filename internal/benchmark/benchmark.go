@@ -0,0 +1,101 @@
+// Package benchmark - Modell-Benchmarks und Leistungsdatenbank je Rechner
+package benchmark
+
+import (
+	"time"
+)
+
+// Job-Status
+const (
+	StatusRunning   = "running"
+	StatusDone      = "done"
+	StatusFailed    = "failed"
+	StatusCancelled = "cancelled"
+)
+
+// Standardwerte und Grenzen eines Benchmark-Laufs
+const (
+	DefaultGenTokens = 128
+	DefaultRuns      = 2
+	MaxModels        = 10
+	MaxContextSizes  = 6
+	MinContextSize   = 512
+	MaxContextSize   = 131072
+)
+
+// DefaultContextSizes: kurzer Chat und langer Kontext (Dokumente, Experten-Prompts)
+var DefaultContextSizes = []int{4096, 16384}
+
+// StartRequest startet einen Benchmark-Lauf
+type StartRequest struct {
+	Models       []string `json:"models"`       // Dateinamen installierter Modelle
+	ContextSizes []int    `json:"contextSizes"` // leer = DefaultContextSizes
+	GenTokens    int      `json:"genTokens"`    // Tokens je Generierung (0 = DefaultGenTokens)
+	Runs         int      `json:"runs"`         // Messungen je Context-Größe (0 = DefaultRuns)
+}
+
+// Result ist die Messung eines Modells bei einer Context-Größe
+type Result struct {
+	ID           int64     `json:"id"`
+	RunID        int64     `json:"runId"`
+	Model        string    `json:"model"`
+	ContextSize  int       `json:"contextSize"`
+	PromptTokens int       `json:"promptTokens"`
+	PromptTPS    float64   `json:"promptTps"` // Prompt-Verarbeitung (pp) in Tokens/s
+	GenTokens    int       `json:"genTokens"`
+	GenTPS       float64   `json:"genTps"`     // Generierung (tg) in Tokens/s
+	TTFTMs       float64   `json:"ttftMs"`     // Zeit bis zum ersten Token
+	PeakVRAMMB   int64     `json:"peakVramMB"` // Zusätzlich belegter VRAM (Spitze gegenüber vor dem Laden)
+	Error        string    `json:"error,omitempty"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
+
+// Run ist ein Benchmark-Lauf mit der Hardware, auf der gemessen wurde
+type Run struct {
+	ID          int64      `json:"id"`
+	HardwareKey string     `json:"hardwareKey"`
+	Hardware    Hardware   `json:"hardware"`
+	Status      string     `json:"status"`
+	Error       string     `json:"error,omitempty"`
+	StartedAt   time.Time  `json:"startedAt"`
+	FinishedAt  *time.Time `json:"finishedAt,omitempty"`
+}
+
+// Hardware beschreibt den Rechner eines Laufs (aus hardware.DetectGPUs)
+type Hardware struct {
+	GPUs     []HardwareGPU `json:"gpus"`
+	CPUCores int           `json:"cpuCores"`
+	OS       string        `json:"os"`
+	Arch     string        `json:"arch"`
+}
+
+// HardwareGPU ist eine GPU eines Laufs
+type HardwareGPU struct {
+	Name          string `json:"name"`
+	Vendor        string `json:"vendor"`
+	Backend       string `json:"backend"`
+	VRAMMB        int64  `json:"vramMB"`
+	DriverVersion string `json:"driverVersion,omitempty"`
+}
+
+// Job ist der Fortschritt des laufenden (oder letzten) Benchmark-Laufs
+type Job struct {
+	Run
+	Models       []string `json:"models"`
+	ContextSizes []int    `json:"contextSizes"`
+	Total        int      `json:"total"` // Anzahl Messpunkte (Modelle × Context-Größen)
+	Done         int      `json:"done"`
+	Current      string   `json:"current,omitempty"`
+	Results      []Result `json:"results"`
+}
+
+// Comparison vergleicht die letzten beiden Messungen eines Modells bei einer Context-Größe
+type Comparison struct {
+	Model        string  `json:"model"`
+	ContextSize  int     `json:"contextSize"`
+	Latest       Result  `json:"latest"`
+	Previous     *Result `json:"previous,omitempty"`
+	GenChange    float64 `json:"genChangePercent"`    // Veränderung tg gegenüber der vorigen Messung
+	PromptChange float64 `json:"promptChangePercent"` // Veränderung pp gegenüber der vorigen Messung
+	Measurements int     `json:"measurements"`
+}
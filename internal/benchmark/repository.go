@@ -0,0 +1,187 @@
+package benchmark
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// Repository speichert Benchmark-Läufe und Messungen in SQLite
+type Repository struct {
+	db *sql.DB
+}
+
+// NewRepository erstellt ein neues Repository
+func NewRepository(dataDir string) (*Repository, error) {
+	dbPath := filepath.Join(dataDir, "benchmarks.db")
+
+	db, err := sql.Open("sqlite", dbPath)
+	if err != nil {
+		return nil, fmt.Errorf("Benchmark-DB öffnen: %w", err)
+	}
+
+	db.SetMaxOpenConns(5)
+	db.SetMaxIdleConns(2)
+
+	repo := &Repository{db: db}
+	if err := repo.createSchema(); err != nil {
+		return nil, err
+	}
+
+	return repo, nil
+}
+
+func (r *Repository) createSchema() error {
+	schema := `
+	CREATE TABLE IF NOT EXISTS benchmark_runs (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		hardware_key TEXT NOT NULL,
+		hardware TEXT NOT NULL DEFAULT '{}',
+		status TEXT NOT NULL,
+		error TEXT DEFAULT '',
+		started_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		finished_at DATETIME
+	);
+
+	CREATE TABLE IF NOT EXISTS benchmark_results (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		run_id INTEGER NOT NULL,
+		model TEXT NOT NULL,
+		context_size INTEGER NOT NULL,
+		prompt_tokens INTEGER DEFAULT 0,
+		prompt_tps REAL DEFAULT 0,
+		gen_tokens INTEGER DEFAULT 0,
+		gen_tps REAL DEFAULT 0,
+		ttft_ms REAL DEFAULT 0,
+		peak_vram_mb INTEGER DEFAULT 0,
+		error TEXT DEFAULT '',
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (run_id) REFERENCES benchmark_runs(id) ON DELETE CASCADE
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_benchmark_runs_hardware ON benchmark_runs(hardware_key);
+	CREATE INDEX IF NOT EXISTS idx_benchmark_results_model ON benchmark_results(model, context_size);
+	CREATE INDEX IF NOT EXISTS idx_benchmark_results_run ON benchmark_results(run_id);
+	`
+
+	_, err := r.db.Exec(schema)
+	if err != nil {
+		return fmt.Errorf("Benchmark-Schema erstellen: %w", err)
+	}
+
+	return nil
+}
+
+// Close schließt die Datenbankverbindung
+func (r *Repository) Close() error {
+	return r.db.Close()
+}
+
+// CreateRun legt einen neuen Lauf an
+func (r *Repository) CreateRun(run *Run) error {
+	hardware, err := json.Marshal(run.Hardware)
+	if err != nil {
+		return fmt.Errorf("Hardware-Info serialisieren: %w", err)
+	}
+	result, err := r.db.Exec(`INSERT INTO benchmark_runs (hardware_key, hardware, status, started_at) VALUES (?, ?, ?, ?)`,
+		run.HardwareKey, string(hardware), run.Status, run.StartedAt)
+	if err != nil {
+		return fmt.Errorf("Benchmark-Lauf anlegen: %w", err)
+	}
+	run.ID, err = result.LastInsertId()
+	return err
+}
+
+// FinishRun setzt Status und Ende eines Laufs
+func (r *Repository) FinishRun(id int64, status, errMsg string, finishedAt time.Time) error {
+	_, err := r.db.Exec(`UPDATE benchmark_runs SET status = ?, error = ?, finished_at = ? WHERE id = ?`,
+		status, errMsg, finishedAt, id)
+	return err
+}
+
+// GetRuns lädt die letzten Läufe (neueste zuerst)
+func (r *Repository) GetRuns(limit int) ([]Run, error) {
+	rows, err := r.db.Query(`SELECT id, hardware_key, hardware, status, COALESCE(error, ''), started_at, finished_at
+		FROM benchmark_runs ORDER BY started_at DESC, id DESC LIMIT ?`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	runs := []Run{}
+	for rows.Next() {
+		var run Run
+		var hardware string
+		var finishedAt sql.NullTime
+		if err := rows.Scan(&run.ID, &run.HardwareKey, &hardware, &run.Status, &run.Error, &run.StartedAt, &finishedAt); err != nil {
+			return nil, err
+		}
+		json.Unmarshal([]byte(hardware), &run.Hardware)
+		if finishedAt.Valid {
+			run.FinishedAt = &finishedAt.Time
+		}
+		runs = append(runs, run)
+	}
+	return runs, rows.Err()
+}
+
+// SaveResult speichert eine Messung
+func (r *Repository) SaveResult(res *Result) error {
+	result, err := r.db.Exec(`INSERT INTO benchmark_results
+		(run_id, model, context_size, prompt_tokens, prompt_tps, gen_tokens, gen_tps, ttft_ms, peak_vram_mb, error, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		res.RunID, res.Model, res.ContextSize, res.PromptTokens, res.PromptTPS, res.GenTokens, res.GenTPS,
+		res.TTFTMs, res.PeakVRAMMB, res.Error, res.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("Benchmark-Ergebnis speichern: %w", err)
+	}
+	res.ID, err = result.LastInsertId()
+	return err
+}
+
+const resultColumns = `res.id, res.run_id, res.model, res.context_size, res.prompt_tokens, res.prompt_tps, res.gen_tokens,
+	res.gen_tps, res.ttft_ms, res.peak_vram_mb, COALESCE(res.error, ''), res.created_at`
+
+func scanResult(row interface{ Scan(...interface{}) error }) (*Result, error) {
+	var res Result
+	err := row.Scan(&res.ID, &res.RunID, &res.Model, &res.ContextSize, &res.PromptTokens, &res.PromptTPS, &res.GenTokens,
+		&res.GenTPS, &res.TTFTMs, &res.PeakVRAMMB, &res.Error, &res.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// GetResults lädt die Messungen eines Rechners (neueste zuerst), optional für ein Modell
+func (r *Repository) GetResults(hardwareKey, model string, limit int) ([]Result, error) {
+	query := `SELECT ` + resultColumns + ` FROM benchmark_results res
+		JOIN benchmark_runs run ON run.id = res.run_id
+		WHERE run.hardware_key = ?`
+	args := []interface{}{hardwareKey}
+	if model != "" {
+		query += ` AND res.model = ? COLLATE NOCASE`
+		args = append(args, model)
+	}
+	query += ` ORDER BY res.created_at DESC, res.id DESC LIMIT ?`
+	args = append(args, limit)
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := []Result{}
+	for rows.Next() {
+		res, err := scanResult(rows)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, *res)
+	}
+	return results, rows.Err()
+}
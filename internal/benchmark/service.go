@@ -0,0 +1,477 @@
+package benchmark
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"fleet-navigator/internal/hardware"
+	"fleet-navigator/internal/llamaserver"
+)
+
+// ErrJobRunning wird zurückgegeben, wenn bereits ein Benchmark läuft
+var ErrJobRunning = errors.New("es läuft bereits ein Benchmark")
+
+// vramSampleInterval: Abstand der VRAM-Messungen während Laden und Messung
+const vramSampleInterval = 500 * time.Millisecond
+
+// Runner lädt Modelle und misst den Durchsatz (implementiert von *llamaserver.Server)
+type Runner interface {
+	GetConfig() llamaserver.Config
+	IsRunning() bool
+	FindModelByName(modelName string) (string, error)
+	GetContextSize() int
+	SetContextSize(size int)
+	SwitchModel(modelPath string) error
+	Stop() error
+	MeasureThroughput(ctx context.Context, opts llamaserver.ThroughputOptions) (*llamaserver.ThroughputSample, error)
+}
+
+// Service führt Benchmark-Läufe aus und wertet die Leistungsdatenbank aus
+type Service struct {
+	repo     *Repository
+	runner   Runner
+	detect   func() *hardware.GPUInfo // GPU-Erkennung (austauschbar für Tests)
+	vramUsed func() (int64, bool)     // Belegter VRAM in MB (false = unbekannt)
+	settle   time.Duration            // Wartezeit nach dem Stoppen, bevor der VRAM gemessen wird
+
+	mu          sync.Mutex
+	hardware    *Hardware
+	hardwareKey string
+	job         *Job
+	cancel      context.CancelFunc
+}
+
+// NewService erstellt einen neuen Benchmark-Service
+func NewService(repo *Repository, runner Runner) *Service {
+	return &Service{
+		repo:     repo,
+		runner:   runner,
+		detect:   hardware.DetectGPUs,
+		vramUsed: gpuMemoryUsedMB,
+		settle:   time.Second,
+	}
+}
+
+// gpuMemoryUsedMB summiert den belegten VRAM aller NVIDIA-GPUs
+func gpuMemoryUsedMB() (int64, bool) {
+	stats, err := hardware.NewMonitor().GetGPU()
+	if err != nil || len(stats) == 0 {
+		return 0, false
+	}
+	var used uint64
+	for _, gpu := range stats {
+		used += gpu.MemoryUsed
+	}
+	return int64(used), true
+}
+
+// hardwareFromGPUInfo übernimmt die für Vergleiche relevanten Angaben aus der GPU-Erkennung
+func hardwareFromGPUInfo(info *hardware.GPUInfo) Hardware {
+	hw := Hardware{GPUs: []HardwareGPU{}, CPUCores: runtime.NumCPU(), OS: runtime.GOOS, Arch: runtime.GOARCH}
+	if info == nil {
+		return hw
+	}
+	for _, gpu := range info.GPUs {
+		hw.GPUs = append(hw.GPUs, HardwareGPU{
+			Name:          gpu.Name,
+			Vendor:        string(gpu.Vendor),
+			Backend:       string(gpu.Backend),
+			VRAMMB:        gpu.VRAM / (1024 * 1024),
+			DriverVersion: gpu.DriverVersion,
+		})
+	}
+	return hw
+}
+
+// Key identifiziert den Rechner. Treiber-Versionen gehören nicht dazu -
+// gerade deren Einfluss soll im Vergleich über die Zeit sichtbar werden.
+func (h Hardware) Key() string {
+	parts := []string{fmt.Sprintf("%s/%s/%d", h.OS, h.Arch, h.CPUCores)}
+	for _, gpu := range h.GPUs {
+		parts = append(parts, fmt.Sprintf("%s|%s|%dGB", gpu.Vendor, gpu.Name, (gpu.VRAMMB+512)/1024))
+	}
+	sort.Strings(parts[1:])
+	sum := sha256.Sum256([]byte(strings.Join(parts, ";")))
+	return hex.EncodeToString(sum[:8])
+}
+
+// currentHardware gibt die Hardware dieses Rechners zurück (einmalig erkannt)
+func (s *Service) currentHardware() (Hardware, string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.hardware == nil {
+		hw := hardwareFromGPUInfo(s.detect())
+		s.hardware = &hw
+		s.hardwareKey = hw.Key()
+	}
+	return *s.hardware, s.hardwareKey
+}
+
+// Start validiert die Anfrage und startet den Benchmark im Hintergrund
+func (s *Service) Start(req StartRequest) (*Job, error) {
+	if len(req.Models) == 0 || len(req.Models) > MaxModels {
+		return nil, fmt.Errorf("zwischen 1 und %d Modelle auswählen", MaxModels)
+	}
+	if len(req.ContextSizes) == 0 {
+		req.ContextSizes = DefaultContextSizes
+	}
+	if len(req.ContextSizes) > MaxContextSizes {
+		return nil, fmt.Errorf("höchstens %d Context-Größen", MaxContextSizes)
+	}
+	for _, size := range req.ContextSizes {
+		if size < MinContextSize || size > MaxContextSize {
+			return nil, fmt.Errorf("Context-Größe %d außerhalb von %d-%d", size, MinContextSize, MaxContextSize)
+		}
+	}
+	if req.GenTokens <= 0 {
+		req.GenTokens = DefaultGenTokens
+	}
+	if req.GenTokens > 1024 {
+		return nil, fmt.Errorf("genTokens darf höchstens 1024 sein")
+	}
+	if req.Runs <= 0 {
+		req.Runs = DefaultRuns
+	}
+	if req.Runs > 5 {
+		return nil, fmt.Errorf("runs darf höchstens 5 sein")
+	}
+
+	paths := make([]string, len(req.Models))
+	models := make([]string, len(req.Models))
+	for i, name := range req.Models {
+		path, err := s.runner.FindModelByName(name)
+		if err != nil {
+			return nil, fmt.Errorf("Modell %s nicht gefunden: %w", name, err)
+		}
+		paths[i] = path
+		models[i] = filepath.Base(path)
+	}
+
+	// GPU-Erkennung bei jedem Lauf neu - Treiber können sich geändert haben
+	hw := hardwareFromGPUInfo(s.detect())
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.job != nil && s.job.Status == StatusRunning {
+		return nil, ErrJobRunning
+	}
+	job := &Job{
+		Run: Run{
+			HardwareKey: hw.Key(),
+			Hardware:    hw,
+			Status:      StatusRunning,
+			StartedAt:   time.Now(),
+		},
+		Models:       models,
+		ContextSizes: req.ContextSizes,
+		Total:        len(models) * len(req.ContextSizes),
+		Results:      []Result{},
+	}
+	if err := s.repo.CreateRun(&job.Run); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.hardware = &hw
+	s.hardwareKey = job.HardwareKey
+	s.job = job
+	s.cancel = cancel
+
+	log.Printf("⏱️ Benchmark #%d gestartet: %d Modelle × %d Context-Größen", job.ID, len(models), len(req.ContextSizes))
+	go s.run(ctx, job, paths, req)
+	return s.snapshotLocked(), nil
+}
+
+// Status gibt den Fortschritt des laufenden bzw. letzten Benchmarks zurück (nil = keiner)
+func (s *Service) Status() *Job {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.snapshotLocked()
+}
+
+// Cancel bricht den laufenden Benchmark ab
+func (s *Service) Cancel() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.job == nil || s.job.Status != StatusRunning || s.cancel == nil {
+		return false
+	}
+	s.cancel()
+	return true
+}
+
+// snapshotLocked kopiert den Job für die Ausgabe
+func (s *Service) snapshotLocked() *Job {
+	if s.job == nil {
+		return nil
+	}
+	job := *s.job
+	job.Results = append([]Result(nil), s.job.Results...)
+	return &job
+}
+
+// run misst alle Modelle und Context-Größen und stellt danach den vorherigen Zustand wieder her
+func (s *Service) run(ctx context.Context, job *Job, paths []string, req StartRequest) {
+	original := s.runner.GetConfig()
+	wasRunning := s.runner.IsRunning()
+	originalContext := s.runner.GetContextSize()
+
+	status, errMsg := StatusDone, ""
+	for i, path := range paths {
+		for _, size := range req.ContextSizes {
+			if ctx.Err() != nil {
+				break
+			}
+			s.mu.Lock()
+			job.Current = fmt.Sprintf("%s @ %d", job.Models[i], size)
+			s.mu.Unlock()
+
+			res := s.measure(ctx, job.ID, path, size, req)
+			if ctx.Err() != nil {
+				break // Abgebrochene Messung nicht speichern
+			}
+			if err := s.repo.SaveResult(&res); err != nil {
+				log.Printf("⚠️ Benchmark-Ergebnis nicht gespeichert: %v", err)
+			}
+
+			s.mu.Lock()
+			job.Results = append(job.Results, res)
+			job.Done++
+			s.mu.Unlock()
+		}
+	}
+	if ctx.Err() != nil {
+		status, errMsg = StatusCancelled, "abgebrochen"
+	} else if failed := s.countFailed(job); failed == job.Total {
+		status, errMsg = StatusFailed, "keine Messung erfolgreich"
+	}
+
+	// Vorherigen Zustand wiederherstellen
+	s.runner.SetContextSize(originalContext)
+	if wasRunning && original.ModelPath != "" {
+		if err := s.runner.SwitchModel(original.ModelPath); err != nil {
+			log.Printf("⚠️ Benchmark: vorheriges Modell nicht wieder geladen: %v", err)
+		}
+	} else {
+		s.runner.Stop()
+	}
+
+	finished := time.Now()
+	if err := s.repo.FinishRun(job.ID, status, errMsg, finished); err != nil {
+		log.Printf("⚠️ Benchmark-Lauf nicht abgeschlossen: %v", err)
+	}
+
+	s.mu.Lock()
+	job.Status = status
+	job.Error = errMsg
+	job.FinishedAt = &finished
+	job.Current = ""
+	s.cancel = nil
+	s.mu.Unlock()
+	log.Printf("⏱️ Benchmark #%d beendet: %s (%d/%d Messungen)", job.ID, status, job.Done, job.Total)
+}
+
+// countFailed zählt fehlgeschlagene Messungen eines Jobs
+func (s *Service) countFailed(job *Job) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	failed := 0
+	for _, r := range job.Results {
+		if r.Error != "" {
+			failed++
+		}
+	}
+	return failed
+}
+
+// measure lädt ein Modell mit einer Context-Größe und misst pp, tg, TTFT und VRAM-Spitze
+func (s *Service) measure(ctx context.Context, runID int64, modelPath string, contextSize int, req StartRequest) (res Result) {
+	res = Result{RunID: runID, Model: filepath.Base(modelPath), ContextSize: contextSize, CreatedAt: time.Now()}
+
+	// Ohne geladenes Modell messen, damit die VRAM-Spitze nur dieses Modell enthält
+	s.runner.Stop()
+	time.Sleep(s.settle)
+	baseline, vramKnown := s.vramUsed()
+
+	var peak int64
+	var wg sync.WaitGroup
+	samplerDone := make(chan struct{})
+	if vramKnown {
+		peak = baseline
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ticker := time.NewTicker(vramSampleInterval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-samplerDone:
+					return
+				case <-ticker.C:
+					if used, ok := s.vramUsed(); ok && used > peak {
+						peak = used
+					}
+				}
+			}
+		}()
+	}
+	defer func() {
+		close(samplerDone)
+		wg.Wait()
+		if used, ok := s.vramUsed(); vramKnown && ok && used > peak {
+			peak = used
+		}
+		if vramKnown {
+			res.PeakVRAMMB = max(peak-baseline, 0)
+		}
+	}()
+
+	s.runner.SetContextSize(contextSize)
+	if err := s.runner.SwitchModel(modelPath); err != nil {
+		res.Error = fmt.Sprintf("Laden fehlgeschlagen: %v", err)
+		log.Printf("⚠️ Benchmark %s @ %d: %s", res.Model, contextSize, res.Error)
+		return res
+	}
+	// SwitchModel begrenzt auf das Modell-Maximum
+	res.ContextSize = s.runner.GetContextSize()
+
+	// Aufwärmen: erste Anfrage nach dem Laden ist langsamer (CUDA-Graphen, Seitencache)
+	if _, err := s.runner.MeasureThroughput(ctx, llamaserver.ThroughputOptions{PromptTokens: 64, GenTokens: 8}); err != nil {
+		res.Error = fmt.Sprintf("Aufwärmen fehlgeschlagen: %v", err)
+		return res
+	}
+
+	// Prompt füllt den halben Context: gemessen wird bei realistischer Tiefe
+	opts := llamaserver.ThroughputOptions{PromptTokens: res.ContextSize / 2, GenTokens: req.GenTokens}
+	var samples []*llamaserver.ThroughputSample
+	for i := 0; i < req.Runs; i++ {
+		sample, err := s.runner.MeasureThroughput(ctx, opts)
+		if err != nil {
+			res.Error = fmt.Sprintf("Messung fehlgeschlagen: %v", err)
+			return res
+		}
+		samples = append(samples, sample)
+	}
+
+	for _, sample := range samples {
+		res.PromptTokens += sample.PromptTokens
+		res.PromptTPS += sample.PromptTPS
+		res.GenTokens += sample.GenTokens
+		res.GenTPS += sample.GenTPS
+		res.TTFTMs += sample.TimeToFirstMs
+	}
+	n := float64(len(samples))
+	res.PromptTokens /= len(samples)
+	res.GenTokens /= len(samples)
+	res.PromptTPS /= n
+	res.GenTPS /= n
+	res.TTFTMs /= n
+
+	log.Printf("⏱️ Benchmark %s @ %d: pp %.0f t/s, tg %.1f t/s, TTFT %.0f ms",
+		res.Model, res.ContextSize, res.PromptTPS, res.GenTPS, res.TTFTMs)
+	return res
+}
+
+// History lädt die Messungen dieses Rechners (optional für ein Modell)
+func (s *Service) History(model string, limit int) ([]Result, error) {
+	if limit <= 0 || limit > 1000 {
+		limit = 100
+	}
+	_, key := s.currentHardware()
+	return s.repo.GetResults(key, model, limit)
+}
+
+// Runs lädt die letzten Benchmark-Läufe (alle Rechner)
+func (s *Service) Runs(limit int) ([]Run, error) {
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	return s.repo.GetRuns(limit)
+}
+
+// Compare vergleicht je Modell und Context-Größe die letzte mit der vorigen Messung dieses Rechners
+func (s *Service) Compare() ([]Comparison, error) {
+	results, err := s.History("", 1000)
+	if err != nil {
+		return nil, err
+	}
+
+	type group struct {
+		model string
+		size  int
+	}
+	byGroup := make(map[group]*Comparison)
+	var order []group
+	for _, r := range results { // neueste zuerst
+		if r.Error != "" {
+			continue
+		}
+		g := group{strings.ToLower(r.Model), r.ContextSize}
+		c, ok := byGroup[g]
+		if !ok {
+			byGroup[g] = &Comparison{Model: r.Model, ContextSize: r.ContextSize, Latest: r, Measurements: 1}
+			order = append(order, g)
+			continue
+		}
+		c.Measurements++
+		if c.Previous == nil {
+			prev := r
+			c.Previous = &prev
+			c.GenChange = changePercent(prev.GenTPS, c.Latest.GenTPS)
+			c.PromptChange = changePercent(prev.PromptTPS, c.Latest.PromptTPS)
+		}
+	}
+
+	comparisons := make([]Comparison, 0, len(order))
+	for _, g := range order {
+		comparisons = append(comparisons, *byGroup[g])
+	}
+	sort.Slice(comparisons, func(i, j int) bool {
+		if comparisons[i].Model != comparisons[j].Model {
+			return comparisons[i].Model < comparisons[j].Model
+		}
+		return comparisons[i].ContextSize < comparisons[j].ContextSize
+	})
+	return comparisons, nil
+}
+
+// changePercent berechnet die relative Veränderung in Prozent
+func changePercent(before, after float64) float64 {
+	if before <= 0 {
+		return 0
+	}
+	return (after - before) / before * 100
+}
+
+// Performance gibt die aktuellste erfolgreiche Messung eines Modells auf diesem Rechner
+// zurück - aus dem letzten Lauf die größte gemessene Context-Größe (nil = nie gemessen)
+func (s *Service) Performance(model string) *Result {
+	results, err := s.History(model, 50)
+	if err != nil {
+		return nil
+	}
+	var best *Result
+	for i := range results {
+		r := &results[i]
+		if r.Error != "" {
+			continue
+		}
+		if best != nil && r.RunID != best.RunID {
+			break
+		}
+		if best == nil || r.ContextSize > best.ContextSize {
+			best = r
+		}
+	}
+	return best
+}
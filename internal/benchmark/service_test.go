@@ -0,0 +1,164 @@
+package benchmark
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"fleet-navigator/internal/hardware"
+	"fleet-navigator/internal/llamaserver"
+)
+
+// fakeRunner simuliert llama-server: tg sinkt mit der Context-Größe, 8K passt nicht
+type fakeRunner struct {
+	mu      sync.Mutex
+	model   string
+	context int
+	running bool
+	vram    int64
+	genTPS  float64
+}
+
+func (f *fakeRunner) GetConfig() llamaserver.Config {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return llamaserver.Config{ModelPath: f.model, ContextSize: f.context}
+}
+func (f *fakeRunner) IsRunning() bool { f.mu.Lock(); defer f.mu.Unlock(); return f.running }
+func (f *fakeRunner) FindModelByName(name string) (string, error) {
+	if name == "fehlt.gguf" {
+		return "", fmt.Errorf("nicht installiert")
+	}
+	return "/models/" + name, nil
+}
+func (f *fakeRunner) GetContextSize() int     { f.mu.Lock(); defer f.mu.Unlock(); return f.context }
+func (f *fakeRunner) SetContextSize(size int) { f.mu.Lock(); defer f.mu.Unlock(); f.context = size }
+func (f *fakeRunner) SwitchModel(path string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.context > 4096 {
+		return fmt.Errorf("VRAM reicht nicht")
+	}
+	f.model, f.running, f.vram = path, true, 3000
+	return nil
+}
+func (f *fakeRunner) Stop() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.running, f.vram = false, 0
+	return nil
+}
+func (f *fakeRunner) MeasureThroughput(ctx context.Context, opts llamaserver.ThroughputOptions) (*llamaserver.ThroughputSample, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.vram = 3500 // KV-Cache wächst während der Messung
+	return &llamaserver.ThroughputSample{
+		PromptTokens: opts.PromptTokens, PromptTPS: 1000, GenTokens: opts.GenTokens,
+		GenTPS: f.genTPS * 2048 / float64(f.context), TimeToFirstMs: 250,
+	}, nil
+}
+
+// newTestService erstellt einen Service mit Fake-Runner und fester Hardware
+func newTestService(t *testing.T, runner *fakeRunner) *Service {
+	t.Helper()
+	repo, err := NewRepository(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { repo.Close() })
+
+	s := NewService(repo, runner)
+	s.settle = 0
+	s.detect = func() *hardware.GPUInfo {
+		return &hardware.GPUInfo{GPUs: []hardware.GPU{{Name: "RTX 3060", Vendor: "nvidia", VRAM: 12 << 30, DriverVersion: "550"}}}
+	}
+	s.vramUsed = func() (int64, bool) {
+		runner.mu.Lock()
+		defer runner.mu.Unlock()
+		return 500 + runner.vram, true
+	}
+	return s
+}
+
+// waitForJob wartet bis der Benchmark beendet ist
+func waitForJob(t *testing.T, s *Service) *Job {
+	t.Helper()
+	for i := 0; i < 200; i++ {
+		if job := s.Status(); job != nil && job.Status != StatusRunning {
+			return job
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("Benchmark wird nicht fertig")
+	return nil
+}
+
+// TestBenchmarkRun prüft Messung, Speicherung, Vergleich und Wiederherstellung
+func TestBenchmarkRun(t *testing.T) {
+	runner := &fakeRunner{model: "/models/chat.gguf", context: 3072, running: true, genTPS: 40}
+	s := newTestService(t, runner)
+
+	if _, err := s.Start(StartRequest{Models: []string{"fehlt.gguf"}}); err == nil {
+		t.Error("Fehlendes Modell akzeptiert")
+	}
+	if _, err := s.Start(StartRequest{Models: []string{"qwen.gguf"}, ContextSizes: []int{100}}); err == nil {
+		t.Error("Zu kleiner Context akzeptiert")
+	}
+
+	job, err := s.Start(StartRequest{Models: []string{"qwen.gguf"}, ContextSizes: []int{2048, 4096, 8192}, GenTokens: 32})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.Total != 3 || job.HardwareKey == "" {
+		t.Fatalf("Job: %+v", job)
+	}
+	job = waitForJob(t, s)
+
+	if job.Status != StatusDone || len(job.Results) != 3 {
+		t.Fatalf("Status %s, %d Ergebnisse", job.Status, len(job.Results))
+	}
+	small, large, failed := job.Results[0], job.Results[1], job.Results[2]
+	if small.GenTPS != 40 || large.GenTPS != 20 || small.PromptTokens != 1024 || small.TTFTMs != 250 {
+		t.Errorf("Messwerte: %+v / %+v", small, large)
+	}
+	if small.PeakVRAMMB != 3500 {
+		t.Errorf("VRAM-Spitze = %d, erwartet 3500", small.PeakVRAMMB)
+	}
+	if failed.Error == "" {
+		t.Errorf("8K hätte fehlschlagen müssen: %+v", failed)
+	}
+
+	// Vorheriges Modell und Context wieder geladen
+	if cfg := runner.GetConfig(); cfg.ModelPath != "/models/chat.gguf" || cfg.ContextSize != 3072 || !runner.IsRunning() {
+		t.Errorf("Nicht wiederhergestellt: %+v", cfg)
+	}
+
+	// Leistung für Empfehlungen: größte erfolgreich gemessene Context-Größe
+	if perf := s.Performance("QWEN.gguf"); perf == nil || perf.ContextSize != 4096 {
+		t.Errorf("Performance: %+v", perf)
+	}
+
+	// Zweiter Lauf nach "Treiber-Update" ist schneller - Vergleich zeigt die Veränderung
+	runner.genTPS = 50
+	if _, err := s.Start(StartRequest{Models: []string{"qwen.gguf"}, ContextSizes: []int{2048}}); err != nil {
+		t.Fatal(err)
+	}
+	waitForJob(t, s)
+
+	comparisons, err := s.Compare()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(comparisons) != 2 {
+		t.Fatalf("Vergleiche: %+v", comparisons)
+	}
+	c := comparisons[0]
+	if c.ContextSize != 2048 || c.Previous == nil || c.Measurements != 2 || c.GenChange != 25 {
+		t.Errorf("Vergleich 2048: %+v", c)
+	}
+	if comparisons[1].Previous != nil {
+		t.Errorf("4096 nur einmal gemessen: %+v", comparisons[1])
+	}
+}
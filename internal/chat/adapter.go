@@ -3,6 +3,7 @@ package chat
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"log"
 
@@ -39,10 +40,19 @@ type LlamaSamplingParams struct {
 type LlamaServerChatter interface {
 	StreamChat(messages []LlamaMessage, onChunk func(content string, done bool)) error
 	StreamChatWithParams(messages []LlamaMessage, params LlamaSamplingParams, onChunk func(content string, done bool)) error
+	StreamChatWithInfo(info LlamaRequestInfo, messages []LlamaMessage, params LlamaSamplingParams, onChunk func(content string, done bool)) error
+	GenerateJSON(info LlamaRequestInfo, messages []LlamaMessage, schema json.RawMessage, out interface{}) error
 	IsRunning() bool
 	IsHealthy() bool
 }
 
+// LlamaRequestInfo ordnet eine Anfrage in die Slot-Warteschlange des llama-servers ein
+type LlamaRequestInfo struct {
+	User       string // Fairness-Schlüssel (Mate oder Session)
+	Background bool   // Hintergrund-Job, wird nach interaktivem Chat bedient
+	Model      string // Zielmodell (leer = geladenes Hauptmodell), wird über den Modell-Pool geroutet
+}
+
 // LlamaMessage für llama-server kompatibilität (identisch mit llamaserver.ChatMessage)
 type LlamaMessage struct {
 	Role    string `json:"role"`
@@ -57,6 +67,7 @@ type Adapter struct {
 	providerChecker  ProviderChecker
 	llamaServer      LlamaServerChatter
 	samplingProvider SamplingParamsProvider
+	mateModel        func(mateID string) string // Modell je Mate (E-Mail-, Coder-Modell)
 }
 
 // NewAdapter erstellt einen neuen Chat-Adapter
@@ -83,6 +94,21 @@ func (a *Adapter) SetSamplingProvider(provider SamplingParamsProvider) {
 	a.samplingProvider = provider
 }
 
+// SetMateModelResolver setzt die Funktion, die das Modell eines Mates bestimmt
+// (z.B. E-Mail-Modell für E-Mail-Mates, Coder-Modell für Coder-Mates)
+func (a *Adapter) SetMateModelResolver(resolver func(mateID string) string) {
+	a.mateModel = resolver
+}
+
+// mateRequestInfo erstellt die RequestInfo für eine Anfrage eines Mates
+func (a *Adapter) mateRequestInfo(mateID string, background bool) LlamaRequestInfo {
+	info := LlamaRequestInfo{User: "mate:" + mateID, Background: background}
+	if a.mateModel != nil && mateID != "" {
+		info.Model = a.mateModel(mateID)
+	}
+	return info
+}
+
 // getSamplingParams holt die aktuellen Sampling-Parameter
 func (a *Adapter) getSamplingParams() LlamaSamplingParams {
 	if a.samplingProvider == nil {
@@ -104,11 +130,33 @@ func (a *Adapter) getSamplingParams() LlamaSamplingParams {
 // Chat implementiert das ChatHandler Interface
 func (a *Adapter) Chat(sessionID, message string, onChunk func(chunk string)) (string, error) {
 	log.Printf("Chat-Adapter: Verwende llama-server")
-	return a.chatWithLlamaServer(sessionID, message, onChunk)
+	return a.chatWithLlamaServer(sessionID, message, onChunk, LlamaRequestInfo{User: sessionID})
+}
+
+// ChatInBackground führt einen Hintergrund-Job eines Mates aus (E-Mail-Klassifizierung,
+// Antwortvorschläge, Terminprüfung). Interaktiver Chat hat in der Warteschlange Vorrang.
+func (a *Adapter) ChatInBackground(mateID, sessionID, message string) (string, error) {
+	return a.chatWithLlamaServer(sessionID, message, nil, a.mateRequestInfo(mateID, true))
+}
+
+// ChatJSON führt einen Hintergrund-Job eines Mates mit strukturierter Antwort aus.
+// Die Antwort wird per JSON-Schema erzwungen, geprüft und in out dekodiert.
+// Es wird keine Session angelegt - jede Anfrage steht für sich.
+func (a *Adapter) ChatJSON(mateID, prompt string, schema json.RawMessage, out interface{}) error {
+	if a.llamaServer == nil {
+		return fmt.Errorf("llama-server ist nicht konfiguriert")
+	}
+	if !a.llamaServer.IsRunning() || !a.llamaServer.IsHealthy() {
+		return fmt.Errorf("llama-server ist nicht aktiv")
+	}
+
+	info := a.mateRequestInfo(mateID, true)
+	messages := []LlamaMessage{{Role: "user", Content: prompt}}
+	return a.llamaServer.GenerateJSON(info, messages, schema, out)
 }
 
 // chatWithLlamaServer verwendet den llama-server für Chat
-func (a *Adapter) chatWithLlamaServer(sessionID, message string, onChunk func(chunk string)) (string, error) {
+func (a *Adapter) chatWithLlamaServer(sessionID, message string, onChunk func(chunk string), info LlamaRequestInfo) (string, error) {
 	if a.llamaServer == nil {
 		return "", fmt.Errorf("llama-server ist nicht konfiguriert")
 	}
@@ -143,7 +191,7 @@ func (a *Adapter) chatWithLlamaServer(sessionID, message string, onChunk func(ch
 
 	// Antwort sammeln - MIT Sampling-Parametern
 	var fullResponse string
-	err := a.llamaServer.StreamChatWithParams(llamaMessages, samplingParams, func(content string, done bool) {
+	err := a.llamaServer.StreamChatWithInfo(info, llamaMessages, samplingParams, func(content string, done bool) {
 		fullResponse += content
 		if onChunk != nil && content != "" {
 			onChunk(content)
@@ -183,6 +231,17 @@ func (a *Adapter) ChatWithModel(sessionID, message, model string, onChunk func(c
 
 // ChatWithSystemPrompt verwendet einen custom System-Prompt (für Mates)
 func (a *Adapter) ChatWithSystemPrompt(sessionID, message, customSystemPrompt string, onChunk func(chunk string)) (string, error) {
+	return a.chatWithSystemPrompt(sessionID, message, customSystemPrompt, onChunk, LlamaRequestInfo{User: sessionID})
+}
+
+// ChatForMate führt den interaktiven Chat eines Mates mit dessen Modell aus
+// (Coder-Mates mit dem Coder-Modell). Leerer System-Prompt = Standard-Prompt.
+func (a *Adapter) ChatForMate(mateID, sessionID, message, customSystemPrompt string, onChunk func(chunk string)) (string, error) {
+	return a.chatWithSystemPrompt(sessionID, message, customSystemPrompt, onChunk, a.mateRequestInfo(mateID, false))
+}
+
+// chatWithSystemPrompt führt einen Chat mit eigenem System-Prompt über den llama-server aus
+func (a *Adapter) chatWithSystemPrompt(sessionID, message, customSystemPrompt string, onChunk func(chunk string), info LlamaRequestInfo) (string, error) {
 	if a.llamaServer == nil {
 		return "", fmt.Errorf("llama-server ist nicht konfiguriert")
 	}
@@ -224,7 +283,7 @@ func (a *Adapter) ChatWithSystemPrompt(sessionID, message, customSystemPrompt st
 
 	// Antwort sammeln - MIT Sampling-Parametern
 	var fullResponse string
-	err := a.llamaServer.StreamChatWithParams(llamaMessages, samplingParams, func(content string, done bool) {
+	err := a.llamaServer.StreamChatWithInfo(info, llamaMessages, samplingParams, func(content string, done bool) {
 		fullResponse += content
 		if onChunk != nil && content != "" {
 			onChunk(content)
@@ -0,0 +1,511 @@
+package chat
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// =============================================================================
+// ANHANG-SPEICHER (Content-Addressed Blob Store)
+// =============================================================================
+//
+// Anhänge (Bilder, Dateien) werden NICHT mehr als Base64 in messages.attachments
+// gespeichert, sondern als Dateien im Datenverzeichnis:
+//
+//	<dataDir>/attachments/<hash[0:2]>/<sha256>
+//
+// Der Dateiname ist der SHA-256 des Inhalts - identische Anhänge werden also
+// nur einmal gespeichert (Deduplizierung). Nachrichten referenzieren Anhänge
+// über die Tabelle message_attachments, messages.attachments enthält nur noch
+// die Beschreibung (ID, Name, Typ, URL) für das Frontend.
+//
+// Nicht mehr referenzierte Blobs werden von GarbageCollectAttachments entfernt.
+
+// AttachmentURLPrefix ist der HTTP-Pfad unter dem Anhänge ausgeliefert werden
+const AttachmentURLPrefix = "/api/attachments/"
+
+// defaultAttachmentGCGrace: Mindestalter bevor die GC einen unreferenzierten Blob entfernt
+const defaultAttachmentGCGrace = time.Hour
+
+// AttachmentBlob beschreibt einen gespeicherten Anhang-Blob
+type AttachmentBlob struct {
+	// Hash: SHA-256 des Inhalts (hex, 64 Zeichen) - gleichzeitig die Anhang-ID
+	Hash string `json:"id"`
+
+	// MimeType: Erkannter Content-Type (z.B. "image/png")
+	MimeType string `json:"mimeType"`
+
+	// Size: Größe in Bytes
+	Size int64 `json:"size"`
+
+	// CreatedAt: Zeitpunkt der ersten Speicherung
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// NewAttachment ist ein neuer Anhang der zusammen mit einer Nachricht gespeichert wird
+type NewAttachment struct {
+	Name     string // Anzeigename (z.B. "image_1.png")
+	Type     string // Anhang-Typ für das Frontend ("image", "pdf", ...)
+	MimeType string // Optional - wird sonst aus dem Inhalt erkannt
+	Data     []byte // Rohdaten (nicht Base64!)
+}
+
+// AttachmentRef ist die Referenz auf einen Anhang wie sie in messages.attachments steht
+type AttachmentRef struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	MimeType string `json:"mimeType,omitempty"`
+	Size     int64  `json:"size"`
+	URL      string `json:"url"`
+}
+
+// AttachmentGCResult enthält das Ergebnis einer Garbage-Collection
+type AttachmentGCResult struct {
+	RemovedBlobs int   `json:"removedBlobs"`
+	FreedBytes   int64 `json:"freedBytes"`
+}
+
+// createAttachmentSchema erstellt die Tabellen für den Anhang-Speicher
+func (s *Store) createAttachmentSchema() error {
+	schema := `
+	-- Tabelle: attachment_blobs
+	-- Ein Eintrag pro eindeutigem Inhalt (SHA-256)
+	CREATE TABLE IF NOT EXISTS attachment_blobs (
+		hash TEXT PRIMARY KEY,                 -- SHA-256 (hex)
+		mime_type TEXT DEFAULT '',             -- Content-Type
+		size INTEGER DEFAULT 0,                -- Größe in Bytes
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
+	-- Tabelle: message_attachments
+	-- Referenzen von Nachrichten auf Blobs (n:m, gleicher Blob in vielen Nachrichten)
+	CREATE TABLE IF NOT EXISTS message_attachments (
+		message_id INTEGER NOT NULL,           -- Fremdschlüssel zu messages
+		position INTEGER NOT NULL,             -- Reihenfolge innerhalb der Nachricht
+		hash TEXT NOT NULL,                    -- Fremdschlüssel zu attachment_blobs
+		name TEXT DEFAULT '',                  -- Anzeigename
+		type TEXT DEFAULT '',                  -- Anhang-Typ (image, pdf, ...)
+		PRIMARY KEY (message_id, position),
+		FOREIGN KEY (message_id) REFERENCES messages(id) ON DELETE CASCADE,
+		FOREIGN KEY (hash) REFERENCES attachment_blobs(hash)
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_message_attachments_hash ON message_attachments(hash);
+	`
+
+	if _, err := s.db.Exec(schema); err != nil {
+		return fmt.Errorf("Anhang-Schema erstellen fehlgeschlagen: %w", err)
+	}
+	return nil
+}
+
+// blobPath gibt den Dateipfad eines Blobs zurück
+func (s *Store) blobPath(hash string) string {
+	return filepath.Join(s.attachmentDir, hash[:2], hash)
+}
+
+// IsValidAttachmentID prüft ob eine ID ein gültiger SHA-256 Hex-String ist.
+// Verhindert Path-Traversal beim Ausliefern über /api/attachments/{hash}.
+func IsValidAttachmentID(id string) bool {
+	if len(id) != sha256.Size*2 {
+		return false
+	}
+	_, err := hex.DecodeString(id)
+	return err == nil && strings.ToLower(id) == id
+}
+
+// SaveAttachmentBlob speichert Rohdaten im Blob-Speicher.
+// Existiert der Inhalt bereits, wird nur der vorhandene Eintrag zurückgegeben.
+//
+// Parameter:
+//   - data: Rohdaten des Anhangs
+//   - mimeType: Content-Type (leer = automatisch erkennen)
+//
+// Rückgabe:
+//   - *AttachmentBlob: Metadaten des (ggf. bereits vorhandenen) Blobs
+//   - error: Dateisystem- oder Datenbankfehler
+func (s *Store) SaveAttachmentBlob(data []byte, mimeType string) (*AttachmentBlob, error) {
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+
+	if mimeType == "" {
+		mimeType = http.DetectContentType(data)
+	}
+
+	// Datei nur schreiben wenn noch nicht vorhanden (Deduplizierung)
+	path := s.blobPath(hash)
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return nil, fmt.Errorf("Anhang-Verzeichnis erstellen fehlgeschlagen: %w", err)
+		}
+		// Atomar schreiben: erst Temp-Datei (eindeutig pro Schreiber), dann umbenennen
+		tmp, err := os.CreateTemp(filepath.Dir(path), hash+".*.tmp")
+		if err != nil {
+			return nil, fmt.Errorf("Anhang schreiben fehlgeschlagen: %w", err)
+		}
+		tmpPath := tmp.Name()
+		_, err = tmp.Write(data)
+		if closeErr := tmp.Close(); err == nil {
+			err = closeErr
+		}
+		if err == nil {
+			err = os.Chmod(tmpPath, 0644)
+		}
+		if err != nil {
+			os.Remove(tmpPath)
+			return nil, fmt.Errorf("Anhang schreiben fehlgeschlagen: %w", err)
+		}
+		if err := os.Rename(tmpPath, path); err != nil {
+			os.Remove(tmpPath)
+			return nil, fmt.Errorf("Anhang schreiben fehlgeschlagen: %w", err)
+		}
+	}
+
+	now := time.Now()
+	if _, err := s.db.Exec(`
+		INSERT OR IGNORE INTO attachment_blobs (hash, mime_type, size, created_at)
+		VALUES (?, ?, ?, ?)
+	`, hash, mimeType, len(data), now); err != nil {
+		return nil, fmt.Errorf("Anhang registrieren fehlgeschlagen: %w", err)
+	}
+
+	return s.GetAttachmentBlob(hash)
+}
+
+// GetAttachmentBlob lädt die Metadaten eines Blobs.
+// Gibt nil zurück wenn der Blob nicht existiert.
+func (s *Store) GetAttachmentBlob(hash string) (*AttachmentBlob, error) {
+	blob := &AttachmentBlob{}
+	err := s.db.QueryRow(`
+		SELECT hash, mime_type, size, created_at FROM attachment_blobs WHERE hash = ?
+	`, hash).Scan(&blob.Hash, &blob.MimeType, &blob.Size, &blob.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return blob, nil
+}
+
+// OpenAttachment öffnet einen Blob zum Lesen.
+// Der Aufrufer muss die Datei schließen. Gibt (nil, nil, nil) zurück wenn unbekannt.
+func (s *Store) OpenAttachment(hash string) (*AttachmentBlob, *os.File, error) {
+	if !IsValidAttachmentID(hash) {
+		return nil, nil, nil
+	}
+	blob, err := s.GetAttachmentBlob(hash)
+	if err != nil || blob == nil {
+		return nil, nil, err
+	}
+	f, err := os.Open(s.blobPath(hash))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil, nil
+		}
+		return nil, nil, fmt.Errorf("Anhang öffnen fehlgeschlagen: %w", err)
+	}
+	return blob, f, nil
+}
+
+// storeAttachments speichert neue Anhänge als Blobs und gibt die Referenzen zurück.
+// Der Aufrufer hält attachmentMu bis die Referenzen verknüpft sind.
+func (s *Store) storeAttachments(attachments []NewAttachment) ([]AttachmentRef, error) {
+	refs := make([]AttachmentRef, 0, len(attachments))
+	for _, a := range attachments {
+		blob, err := s.SaveAttachmentBlob(a.Data, a.MimeType)
+		if err != nil {
+			return nil, err
+		}
+		refs = append(refs, AttachmentRef{
+			ID:       blob.Hash,
+			Name:     a.Name,
+			Type:     a.Type,
+			MimeType: blob.MimeType,
+			Size:     blob.Size,
+			URL:      AttachmentURLPrefix + blob.Hash,
+		})
+	}
+	return refs, nil
+}
+
+// linkAttachments verknüpft eine Nachricht mit ihren Anhang-Referenzen
+func (s *Store) linkAttachments(messageID int64, refs []AttachmentRef) error {
+	for i, ref := range refs {
+		if _, err := s.db.Exec(`
+			INSERT OR REPLACE INTO message_attachments (message_id, position, hash, name, type)
+			VALUES (?, ?, ?, ?, ?)
+		`, messageID, i, ref.ID, ref.Name, ref.Type); err != nil {
+			return fmt.Errorf("Anhang verknüpfen fehlgeschlagen: %w", err)
+		}
+	}
+	return nil
+}
+
+// encodeAttachmentRefs serialisiert Referenzen für messages.attachments (leer wenn keine)
+func encodeAttachmentRefs(refs []AttachmentRef) string {
+	if len(refs) == 0 {
+		return ""
+	}
+	data, _ := json.Marshal(refs)
+	return string(data)
+}
+
+// GetMessageAttachments lädt die Anhang-Referenzen einer Nachricht
+func (s *Store) GetMessageAttachments(messageID int64) ([]AttachmentRef, error) {
+	rows, err := s.db.Query(`
+		SELECT ma.hash, ma.name, ma.type, b.mime_type, b.size
+		FROM message_attachments ma
+		JOIN attachment_blobs b ON b.hash = ma.hash
+		WHERE ma.message_id = ?
+		ORDER BY ma.position ASC
+	`, messageID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	refs := make([]AttachmentRef, 0)
+	for rows.Next() {
+		var ref AttachmentRef
+		if err := rows.Scan(&ref.ID, &ref.Name, &ref.Type, &ref.MimeType, &ref.Size); err != nil {
+			return nil, err
+		}
+		ref.URL = AttachmentURLPrefix + ref.ID
+		refs = append(refs, ref)
+	}
+	return refs, nil
+}
+
+// =============================================================================
+// MIGRATION: Base64-Anhänge aus messages.attachments extrahieren
+// =============================================================================
+
+// legacyAttachment ist das alte Format in messages.attachments
+// Format: [{"name":"screenshot.png","type":"image","content":"<base64>"},...]
+type legacyAttachment struct {
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	Type    string `json:"type"`
+	Content string `json:"content"`
+	Base64  string `json:"base64"`
+	Size    int64  `json:"size"`
+}
+
+// migrateInlineAttachments verschiebt Base64-Anhänge alter Nachrichten in den Blob-Speicher.
+// Nachrichten ohne Base64-Inhalt (bereits migriert oder nur Metadaten) bleiben unverändert.
+func (s *Store) migrateInlineAttachments() {
+	rows, err := s.db.Query(`
+		SELECT id, attachments FROM messages
+		WHERE attachments IS NOT NULL
+		  AND (attachments LIKE '%"content"%' OR attachments LIKE '%"base64"%')
+	`)
+	if err != nil {
+		log.Printf("Migration Anhänge fehlgeschlagen: %v", err)
+		return
+	}
+
+	type pending struct {
+		id   int64
+		json string
+	}
+	var todo []pending
+	for rows.Next() {
+		var p pending
+		if err := rows.Scan(&p.id, &p.json); err == nil {
+			todo = append(todo, p)
+		}
+	}
+	rows.Close()
+
+	if len(todo) == 0 {
+		return
+	}
+
+	s.attachmentMu.Lock()
+	defer s.attachmentMu.Unlock()
+
+	migrated := 0
+	for _, p := range todo {
+		var legacy []legacyAttachment
+		if err := json.Unmarshal([]byte(p.json), &legacy); err != nil {
+			log.Printf("Migration Anhänge: Nachricht %d übersprungen (ungültiges JSON): %v", p.id, err)
+			continue
+		}
+
+		refs := make([]AttachmentRef, 0, len(legacy))
+		for _, la := range legacy {
+			encoded := la.Content
+			if encoded == "" {
+				encoded = la.Base64
+			}
+			if encoded == "" {
+				// Nur Metadaten (z.B. Dokument-Name) - als Referenz ohne Blob übernehmen geht nicht,
+				// daher unverändert im JSON belassen
+				refs = append(refs, AttachmentRef{Name: la.Name, Type: la.Type, Size: la.Size})
+				continue
+			}
+			data, err := DecodeBase64Attachment(encoded)
+			if err != nil {
+				log.Printf("Migration Anhänge: Nachricht %d, Anhang %q nicht dekodierbar: %v", p.id, la.Name, err)
+				continue
+			}
+			blob, err := s.SaveAttachmentBlob(data, "")
+			if err != nil {
+				log.Printf("Migration Anhänge: Nachricht %d: %v", p.id, err)
+				continue
+			}
+			refs = append(refs, AttachmentRef{
+				ID:       blob.Hash,
+				Name:     la.Name,
+				Type:     la.Type,
+				MimeType: blob.MimeType,
+				Size:     blob.Size,
+				URL:      AttachmentURLPrefix + blob.Hash,
+			})
+		}
+
+		// Nur Referenzen mit Blob verknüpfen
+		linked := make([]AttachmentRef, 0, len(refs))
+		for _, ref := range refs {
+			if ref.ID != "" {
+				linked = append(linked, ref)
+			}
+		}
+		if err := s.linkAttachments(p.id, linked); err != nil {
+			log.Printf("Migration Anhänge: Nachricht %d: %v", p.id, err)
+			continue
+		}
+		if _, err := s.db.Exec(`UPDATE messages SET attachments = ? WHERE id = ?`, encodeAttachmentRefs(refs), p.id); err != nil {
+			log.Printf("Migration Anhänge: Nachricht %d aktualisieren fehlgeschlagen: %v", p.id, err)
+			continue
+		}
+		migrated++
+	}
+
+	log.Printf("Migration: %d Nachrichten mit Base64-Anhängen in den Anhang-Speicher verschoben", migrated)
+}
+
+// DecodeBase64Attachment dekodiert Base64 (optional mit "data:...;base64," Präfix)
+func DecodeBase64Attachment(encoded string) ([]byte, error) {
+	if strings.HasPrefix(encoded, "data:") {
+		if idx := strings.Index(encoded, ","); idx >= 0 {
+			encoded = encoded[idx+1:]
+		}
+	}
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		// Manche Clients senden Base64 ohne Padding
+		data, err = base64.RawStdEncoding.DecodeString(strings.TrimRight(encoded, "="))
+	}
+	return data, err
+}
+
+// =============================================================================
+// GARBAGE COLLECTION
+// =============================================================================
+
+// GarbageCollectAttachments entfernt Blobs die von keiner Nachricht mehr referenziert werden.
+// Verwaiste Referenzen (gelöschte Nachrichten) werden zuerst bereinigt, danach
+// unreferenzierte Blobs aus Tabelle und Dateisystem entfernt. Dateien ohne
+// DB-Eintrag (z.B. nach abgebrochenem Schreiben) werden ebenfalls gelöscht.
+//
+// Die GC läuft exklusiv zu AddMessageWithAttachments und lässt Blobs und Dateien
+// jünger als attachmentGCGrace stehen (z.B. Uploads deren Nachricht noch entsteht).
+func (s *Store) GarbageCollectAttachments() (*AttachmentGCResult, error) {
+	s.attachmentMu.Lock()
+	defer s.attachmentMu.Unlock()
+
+	result := &AttachmentGCResult{}
+	cutoff := time.Now().Add(-s.attachmentGCGrace)
+
+	// Referenzen auf gelöschte Nachrichten entfernen
+	// (ON DELETE CASCADE greift nicht auf jeder Pool-Verbindung)
+	if _, err := s.db.Exec(`
+		DELETE FROM message_attachments
+		WHERE message_id NOT IN (SELECT id FROM messages)
+	`); err != nil {
+		return nil, fmt.Errorf("Anhang-Referenzen bereinigen fehlgeschlagen: %w", err)
+	}
+
+	rows, err := s.db.Query(`
+		SELECT hash, size, created_at FROM attachment_blobs
+		WHERE hash NOT IN (SELECT DISTINCT hash FROM message_attachments)
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("Verwaiste Anhänge suchen fehlgeschlagen: %w", err)
+	}
+	type orphan struct {
+		hash string
+		size int64
+	}
+	var orphans []orphan
+	for rows.Next() {
+		var o orphan
+		var createdAt time.Time
+		if err := rows.Scan(&o.hash, &o.size, &createdAt); err == nil && createdAt.Before(cutoff) {
+			orphans = append(orphans, o)
+		}
+	}
+	rows.Close()
+
+	for _, o := range orphans {
+		if _, err := s.db.Exec(`DELETE FROM attachment_blobs WHERE hash = ?`, o.hash); err != nil {
+			log.Printf("GC: Anhang %s konnte nicht entfernt werden: %v", o.hash, err)
+			continue
+		}
+		if err := os.Remove(s.blobPath(o.hash)); err != nil && !os.IsNotExist(err) {
+			log.Printf("GC: Anhang-Datei %s konnte nicht gelöscht werden: %v", o.hash, err)
+		}
+		result.RemovedBlobs++
+		result.FreedBytes += o.size
+	}
+
+	// Dateien ohne DB-Eintrag entfernen
+	filepath.Walk(s.attachmentDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() || !info.ModTime().Before(cutoff) {
+			return nil
+		}
+		name := info.Name()
+		if IsValidAttachmentID(name) {
+			if blob, err := s.GetAttachmentBlob(name); err != nil || blob != nil {
+				return nil
+			}
+		} else if !strings.HasSuffix(name, ".tmp") {
+			return nil
+		}
+		if os.Remove(path) == nil {
+			result.RemovedBlobs++
+			result.FreedBytes += info.Size()
+		}
+		return nil
+	})
+
+	if result.RemovedBlobs > 0 {
+		log.Printf("Anhang-GC: %d Blobs entfernt (%.1f KB freigegeben)", result.RemovedBlobs, float64(result.FreedBytes)/1024)
+	}
+	return result, nil
+}
+
+// AttachmentStorageSize gibt die Gesamtgröße des Anhang-Speichers in Bytes zurück
+func (s *Store) AttachmentStorageSize() int64 {
+	var total int64
+	filepath.Walk(s.attachmentDir, func(path string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			total += info.Size()
+		}
+		return nil
+	})
+	return total
+}
@@ -0,0 +1,224 @@
+package chat
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestStore erstellt einen Store in einem temporären Verzeichnis
+func newTestStore(t *testing.T) (*Store, string) {
+	t.Helper()
+	dir := t.TempDir()
+	store, err := NewStore(dir)
+	if err != nil {
+		t.Fatalf("NewStore fehlgeschlagen: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store, dir
+}
+
+// readBlob liest den Inhalt eines Blobs über OpenAttachment
+func readBlob(t *testing.T, store *Store, hash string) []byte {
+	t.Helper()
+	_, f, err := store.OpenAttachment(hash)
+	if err != nil || f == nil {
+		t.Fatalf("OpenAttachment(%s): %v", hash, err)
+	}
+	defer f.Close()
+	data, err := io.ReadAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// TestAttachmentDeduplication prüft dass identische Inhalte nur einmal gespeichert werden
+func TestAttachmentDeduplication(t *testing.T) {
+	store, _ := newTestStore(t)
+
+	chatObj, err := store.CreateChat("Test", "model")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := []byte("\x89PNG\r\n\x1a\nfake image")
+	att := []NewAttachment{{Name: "a.png", Type: "image", Data: data}}
+
+	msg1, err := store.AddMessageWithAttachments(chatObj.ID, "USER", "eins", "", 0, nil, nil, att)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg2, err := store.AddMessageWithAttachments(chatObj.ID, "USER", "zwei", "", 0, nil, nil, att)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var refs1, refs2 []AttachmentRef
+	json.Unmarshal([]byte(msg1.Attachments), &refs1)
+	json.Unmarshal([]byte(msg2.Attachments), &refs2)
+	if len(refs1) != 1 || len(refs2) != 1 || refs1[0].ID != refs2[0].ID {
+		t.Fatalf("Erwartet gleiche Anhang-ID, bekommen %v / %v", refs1, refs2)
+	}
+	if refs1[0].MimeType != "image/png" {
+		t.Errorf("MimeType = %q, erwartet image/png", refs1[0].MimeType)
+	}
+	if strings.Contains(msg1.Attachments, base64.StdEncoding.EncodeToString(data)) {
+		t.Error("Nachricht sollte keine Base64-Daten mehr enthalten")
+	}
+
+	var count int
+	store.db.QueryRow(`SELECT COUNT(*) FROM attachment_blobs`).Scan(&count)
+	if count != 1 {
+		t.Errorf("attachment_blobs = %d, erwartet 1", count)
+	}
+
+	if read := readBlob(t, store, refs1[0].ID); string(read) != string(data) {
+		t.Errorf("Blob-Inhalt = %q", read)
+	}
+}
+
+// TestMigrateInlineAttachments prüft die Auslagerung alter Base64-Anhänge
+func TestMigrateInlineAttachments(t *testing.T) {
+	store, dir := newTestStore(t)
+
+	chatObj, _ := store.CreateChat("Alt", "model")
+	legacy := `[{"type":"image","content":"` + base64.StdEncoding.EncodeToString([]byte("altes bild")) + `","name":"image_1.png"}]`
+	store.db.Exec(`INSERT INTO messages (chat_id, role, content, attachments) VALUES (?, 'USER', 'hallo', ?)`, chatObj.ID, legacy)
+	store.Close()
+
+	// Neu öffnen - Migration läuft beim Start
+	store, err := NewStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	messages, err := store.GetMessages(chatObj.ID)
+	if err != nil || len(messages) != 1 {
+		t.Fatalf("GetMessages: %v (%d Nachrichten)", err, len(messages))
+	}
+	if strings.Contains(messages[0].Attachments, `"content"`) {
+		t.Errorf("Base64-Inhalt nicht migriert: %s", messages[0].Attachments)
+	}
+
+	refs, err := store.GetMessageAttachments(messages[0].ID)
+	if err != nil || len(refs) != 1 {
+		t.Fatalf("GetMessageAttachments: %v (%d Refs)", err, len(refs))
+	}
+	if data := readBlob(t, store, refs[0].ID); string(data) != "altes bild" {
+		t.Errorf("Migrierter Inhalt = %q", data)
+	}
+}
+
+// TestGarbageCollectAttachments prüft das Entfernen verwaister Blobs
+func TestGarbageCollectAttachments(t *testing.T) {
+	store, _ := newTestStore(t)
+
+	chatObj, _ := store.CreateChat("GC", "model")
+	msg, err := store.AddMessageWithAttachments(chatObj.ID, "USER", "x", "", 0, nil, nil,
+		[]NewAttachment{{Name: "f.txt", Type: "text", Data: []byte("inhalt")}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var refs []AttachmentRef
+	json.Unmarshal([]byte(msg.Attachments), &refs)
+
+	// Noch referenziert - nichts entfernen
+	result, err := store.GarbageCollectAttachments()
+	if err != nil || result.RemovedBlobs != 0 {
+		t.Fatalf("GC vor Löschen: %+v, %v", result, err)
+	}
+
+	if err := store.DeleteMessage(chatObj.ID, msg.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	// Fremde Temp-Datei eines laufenden Schreibvorgangs
+	tmpPath := filepath.Join(filepath.Dir(store.blobPath(refs[0].ID)), refs[0].ID+".123.tmp")
+	os.WriteFile(tmpPath, []byte("halb"), 0644)
+
+	// Innerhalb der Schonfrist bleiben Blob und Temp-Datei erhalten
+	result, err = store.GarbageCollectAttachments()
+	if err != nil || result.RemovedBlobs != 0 {
+		t.Fatalf("GC in Schonfrist: %+v, %v", result, err)
+	}
+	if _, err := os.Stat(tmpPath); err != nil {
+		t.Error("Temp-Datei in Schonfrist gelöscht")
+	}
+
+	// Nach Ablauf der Schonfrist
+	store.attachmentGCGrace = -time.Minute
+	result, err = store.GarbageCollectAttachments()
+	if err != nil || result.RemovedBlobs != 2 {
+		t.Fatalf("GC nach Löschen: %+v, %v", result, err)
+	}
+	if _, err := os.Stat(store.blobPath(refs[0].ID)); !os.IsNotExist(err) {
+		t.Error("Blob-Datei sollte gelöscht sein")
+	}
+}
+
+// TestForkChatAttachments prüft, dass der Fork Anhänge unter attachmentMu verknüpft und den Blob hält
+func TestForkChatAttachments(t *testing.T) {
+	store, _ := newTestStore(t)
+	store.attachmentGCGrace = -time.Minute
+
+	chatObj, _ := store.CreateChat("Original", "model")
+	msg, err := store.AddMessageWithAttachments(chatObj.ID, "USER", "x", "", 0, nil, nil,
+		[]NewAttachment{{Name: "f.txt", Type: "text", Data: []byte("geteilt")}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var refs []AttachmentRef
+	json.Unmarshal([]byte(msg.Attachments), &refs)
+
+	// Solange die GC (bzw. ein Upload) die Sperre hält, wartet der Fork
+	store.attachmentMu.Lock()
+	done := make(chan *Chat)
+	go func() {
+		fork, err := store.ForkChat(chatObj.ID, "Fork")
+		if err != nil {
+			t.Error(err)
+		}
+		done <- fork
+	}()
+	select {
+	case <-done:
+		t.Fatal("Fork lief trotz gehaltener Anhang-Sperre durch")
+	case <-time.After(50 * time.Millisecond):
+	}
+	store.attachmentMu.Unlock()
+	fork := <-done
+	if fork == nil || len(fork.Messages) != 1 {
+		t.Fatalf("Fork: %+v", fork)
+	}
+
+	// Original löschen - der Fork referenziert den Blob weiterhin
+	if err := store.DeleteChat(chatObj.ID); err != nil {
+		t.Fatal(err)
+	}
+	if result, err := store.GarbageCollectAttachments(); err != nil || result.RemovedBlobs != 0 {
+		t.Fatalf("GC nach Löschen des Originals: %+v, %v", result, err)
+	}
+	if got := readBlob(t, store, refs[0].ID); string(got) != "geteilt" {
+		t.Errorf("Blob = %q", got)
+	}
+}
+
+// TestIsValidAttachmentID prüft die Validierung gegen Path-Traversal
+func TestIsValidAttachmentID(t *testing.T) {
+	valid := strings.Repeat("ab", 32)
+	if !IsValidAttachmentID(valid) {
+		t.Error("Gültige ID abgelehnt")
+	}
+	for _, id := range []string{"", "../etc/passwd", strings.Repeat("AB", 32), strings.Repeat("zz", 32), valid + "0"} {
+		if IsValidAttachmentID(id) {
+			t.Errorf("Ungültige ID akzeptiert: %q", id)
+		}
+	}
+}
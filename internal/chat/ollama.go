@@ -375,6 +375,13 @@ func (s *Service) GetModel() string {
 // StreamChatWithMessages sendet Messages direkt und streamt die Antwort
 // Diese Methode arbeitet ohne Session, ideal für REST API
 func (s *Service) StreamChatWithMessages(model string, messages []Message, onChunk func(content string, done bool)) error {
+	return s.StreamChatWithMessagesContext(context.Background(), model, messages, onChunk)
+}
+
+// StreamChatWithMessagesContext wie StreamChatWithMessages, aber abbrechbar über ctx.
+// Bei Abbruch wird die Verbindung geschlossen - Ollama beendet die Generierung dann selbst.
+// Der zurückgegebene Fehler enthält context.Canceled (prüfbar mit errors.Is).
+func (s *Service) StreamChatWithMessagesContext(parent context.Context, model string, messages []Message, onChunk func(content string, done bool)) error {
 	if model == "" {
 		model = s.config.Model
 	}
@@ -391,7 +398,7 @@ func (s *Service) StreamChatWithMessages(model string, messages []Message, onChu
 		return fmt.Errorf("JSON Marshal Fehler: %w", err)
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), s.config.Timeout)
+	ctx, cancel := context.WithTimeout(parent, s.config.Timeout)
 	defer cancel()
 
 	req, err := http.NewRequestWithContext(ctx, "POST", s.config.BaseURL+"/api/chat", bytes.NewBuffer(jsonBody))
@@ -438,6 +445,9 @@ func (s *Service) StreamChatWithMessages(model string, messages []Message, onChu
 	}
 
 	if err := scanner.Err(); err != nil {
+		if parent.Err() != nil {
+			return fmt.Errorf("Generierung abgebrochen: %w", parent.Err())
+		}
 		return fmt.Errorf("Stream lesen Fehler: %w", err)
 	}
 
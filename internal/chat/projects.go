@@ -0,0 +1,498 @@
+package chat
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// =============================================================================
+// PROJEKTE (Arbeitsbereiche für Chats)
+// =============================================================================
+//
+// Ein Projekt gruppiert Chats und liefert gemeinsamen Kontext:
+//   - Standard-Experte: wird verwendet wenn im Chat kein Experte gewählt ist
+//   - Projekt-System-Prompt: wird an den System-Prompt jedes Chats angehängt
+//   - Angeheftete Dokumente: werden in jeden Chat des Projekts injiziert
+//
+// Zuordnung: chats.project_id (NULL = kein Projekt)
+
+// maxProjectContextChars begrenzt den injizierten Dokument-Kontext pro Anfrage in Zeichen
+// (Runen, nicht Bytes - ~12k Tokens), damit kleine Kontextfenster nicht gesprengt werden
+const maxProjectContextChars = 48000
+
+// Project repräsentiert einen Arbeitsbereich mit gemeinsamen Einstellungen
+type Project struct {
+	ID          int64  `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+
+	// DefaultExpertID: Experte für neue Nachrichten ohne expliziten Experten (nil = keiner)
+	DefaultExpertID *int64 `json:"defaultExpertId,omitempty"`
+
+	// SystemPrompt: Gemeinsame Anweisungen für alle Chats des Projekts
+	SystemPrompt string `json:"systemPrompt"`
+
+	// ContextFiles: Dokumente des Projekts (ohne Inhalt - siehe GetProjectFile)
+	ContextFiles []ProjectFile `json:"contextFiles"`
+
+	// ChatIDs: IDs aller Chats im Projekt
+	ChatIDs []int64 `json:"chatIds"`
+
+	// EstimatedTokens: Geschätzte Tokens der angehefteten Dokumente + Prompt
+	EstimatedTokens int `json:"estimatedTokens"`
+
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// ProjectFile ist ein Dokument eines Projekts
+type ProjectFile struct {
+	ID              int64     `json:"id"`
+	ProjectID       int64     `json:"projectId"`
+	Filename        string    `json:"filename"`
+	FileType        string    `json:"fileType"`
+	Size            int64     `json:"size"`
+	EstimatedTokens int       `json:"estimatedTokens"`
+	Pinned          bool      `json:"pinned"`            // In jeden Chat injizieren
+	Content         string    `json:"content,omitempty"` // Nur bei GetProjectFile
+	CreatedAt       time.Time `json:"createdAt"`
+}
+
+// ProjectUpdate enthält die änderbaren Felder eines Projekts (nil = unverändert)
+type ProjectUpdate struct {
+	Name            *string `json:"name"`
+	Description     *string `json:"description"`
+	SystemPrompt    *string `json:"systemPrompt"`
+	DefaultExpertID *int64  `json:"defaultExpertId"`
+	// ClearDefaultExpert entfernt den Standard-Experten (JSON null ist von "nicht gesetzt" nicht unterscheidbar)
+	ClearDefaultExpert bool `json:"clearDefaultExpert"`
+}
+
+// chatColumns sind die Spalten für Chat-Listen inkl. Projekt-Zuordnung (siehe scanChat)
+const chatColumns = `id, title, model, created_at, updated_at, project_id,
+	(SELECT name FROM projects p WHERE p.id = chats.project_id)`
+
+// rowScanner abstrahiert *sql.Row und *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanChat liest eine Zeile mit chatColumns in einen Chat
+func scanChat(row rowScanner, c *Chat) error {
+	var projectID sql.NullInt64
+	var projectName sql.NullString
+	if err := row.Scan(&c.ID, &c.Title, &c.Model, &c.CreatedAt, &c.UpdatedAt, &projectID, &projectName); err != nil {
+		return err
+	}
+	if projectID.Valid {
+		c.ProjectID = &projectID.Int64
+		c.ProjectName = projectName.String
+	}
+	return nil
+}
+
+// estimateTokens schätzt Tokens grob (ca. 4 Zeichen pro Token, wie im Chat-Handler)
+func estimateTokens(text string) int {
+	return len(text) / 4
+}
+
+// createProjectSchema erstellt die Tabellen für Projekte und Projekt-Dokumente
+func (s *Store) createProjectSchema() error {
+	schema := `
+	-- Tabelle: projects
+	CREATE TABLE IF NOT EXISTS projects (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name TEXT NOT NULL,
+		description TEXT DEFAULT '',
+		default_expert_id INTEGER DEFAULT NULL,  -- Standard-Experte (optional)
+		system_prompt TEXT DEFAULT '',           -- Projekt-System-Prompt
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
+	-- Tabelle: project_files
+	-- Dokumente eines Projekts (Text-Inhalt, angeheftet = in jeden Chat injizieren)
+	CREATE TABLE IF NOT EXISTS project_files (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		project_id INTEGER NOT NULL,
+		filename TEXT NOT NULL,
+		file_type TEXT DEFAULT 'text/plain',
+		content TEXT NOT NULL,
+		pinned INTEGER DEFAULT 1,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (project_id) REFERENCES projects(id) ON DELETE CASCADE
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_project_files_project_id ON project_files(project_id);
+	CREATE INDEX IF NOT EXISTS idx_chats_project_id ON chats(project_id);
+	`
+	if _, err := s.db.Exec(schema); err != nil {
+		return fmt.Errorf("Projekt-Schema erstellen fehlgeschlagen: %w", err)
+	}
+
+	return nil
+}
+
+// CreateProject legt ein neues Projekt an
+func (s *Store) CreateProject(name, description, systemPrompt string, defaultExpertID *int64) (*Project, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, fmt.Errorf("Projektname darf nicht leer sein")
+	}
+
+	now := time.Now()
+	result, err := s.db.Exec(`
+		INSERT INTO projects (name, description, default_expert_id, system_prompt, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, name, description, defaultExpertID, systemPrompt, now, now)
+	if err != nil {
+		return nil, fmt.Errorf("Projekt erstellen fehlgeschlagen: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	return s.GetProject(id)
+}
+
+// GetProject lädt ein Projekt inkl. Dokument-Liste und Chat-IDs.
+// Gibt nil zurück wenn das Projekt nicht existiert.
+func (s *Store) GetProject(id int64) (*Project, error) {
+	p := &Project{}
+	var expertID sql.NullInt64
+	err := s.db.QueryRow(`
+		SELECT id, name, description, default_expert_id, system_prompt, created_at, updated_at
+		FROM projects WHERE id = ?
+	`, id).Scan(&p.ID, &p.Name, &p.Description, &expertID, &p.SystemPrompt, &p.CreatedAt, &p.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if expertID.Valid {
+		p.DefaultExpertID = &expertID.Int64
+	}
+
+	if err := s.loadProjectDetails(p); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// loadProjectDetails lädt Dokumente, Chat-IDs und Token-Schätzung eines Projekts
+func (s *Store) loadProjectDetails(p *Project) error {
+	rows, err := s.db.Query(`
+		SELECT id, project_id, filename, file_type, LENGTH(content), pinned, created_at
+		FROM project_files WHERE project_id = ?
+		ORDER BY created_at ASC
+	`, p.ID)
+	if err != nil {
+		return err
+	}
+	p.ContextFiles = make([]ProjectFile, 0)
+	for rows.Next() {
+		var f ProjectFile
+		if err := rows.Scan(&f.ID, &f.ProjectID, &f.Filename, &f.FileType, &f.Size, &f.Pinned, &f.CreatedAt); err != nil {
+			rows.Close()
+			return err
+		}
+		f.EstimatedTokens = int(f.Size / 4)
+		p.ContextFiles = append(p.ContextFiles, f)
+	}
+	rows.Close()
+
+	chatRows, err := s.db.Query(`SELECT id FROM chats WHERE project_id = ? ORDER BY updated_at DESC`, p.ID)
+	if err != nil {
+		return err
+	}
+	p.ChatIDs = make([]int64, 0)
+	for chatRows.Next() {
+		var id int64
+		if err := chatRows.Scan(&id); err != nil {
+			chatRows.Close()
+			return err
+		}
+		p.ChatIDs = append(p.ChatIDs, id)
+	}
+	chatRows.Close()
+
+	p.EstimatedTokens = estimateTokens(p.SystemPrompt)
+	for _, f := range p.ContextFiles {
+		if f.Pinned {
+			p.EstimatedTokens += f.EstimatedTokens
+		}
+	}
+	return nil
+}
+
+// ListProjects lädt alle Projekte (neueste Änderung zuerst)
+func (s *Store) ListProjects() ([]Project, error) {
+	rows, err := s.db.Query(`SELECT id FROM projects ORDER BY updated_at DESC`)
+	if err != nil {
+		return nil, err
+	}
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+
+	projects := make([]Project, 0, len(ids))
+	for _, id := range ids {
+		p, err := s.GetProject(id)
+		if err != nil {
+			return nil, err
+		}
+		if p != nil {
+			projects = append(projects, *p)
+		}
+	}
+	return projects, nil
+}
+
+// UpdateProject ändert ein Projekt. Nur gesetzte Felder werden übernommen.
+func (s *Store) UpdateProject(id int64, upd ProjectUpdate) (*Project, error) {
+	existing, err := s.GetProject(id)
+	if err != nil || existing == nil {
+		return nil, err
+	}
+
+	if upd.Name != nil {
+		name := strings.TrimSpace(*upd.Name)
+		if name == "" {
+			return nil, fmt.Errorf("Projektname darf nicht leer sein")
+		}
+		existing.Name = name
+	}
+	if upd.Description != nil {
+		existing.Description = *upd.Description
+	}
+	if upd.SystemPrompt != nil {
+		existing.SystemPrompt = *upd.SystemPrompt
+	}
+	if upd.DefaultExpertID != nil {
+		existing.DefaultExpertID = upd.DefaultExpertID
+	}
+	if upd.ClearDefaultExpert {
+		existing.DefaultExpertID = nil
+	}
+
+	_, err = s.db.Exec(`
+		UPDATE projects SET name = ?, description = ?, system_prompt = ?, default_expert_id = ?, updated_at = ?
+		WHERE id = ?
+	`, existing.Name, existing.Description, existing.SystemPrompt, existing.DefaultExpertID, time.Now(), id)
+	if err != nil {
+		return nil, fmt.Errorf("Projekt aktualisieren fehlgeschlagen: %w", err)
+	}
+	return s.GetProject(id)
+}
+
+// DeleteProject löscht ein Projekt und seine Dokumente.
+// Die Chats bleiben erhalten und werden nur aus dem Projekt gelöst.
+func (s *Store) DeleteProject(id int64) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`UPDATE chats SET project_id = NULL WHERE project_id = ?`, id); err != nil {
+		return fmt.Errorf("Chats lösen fehlgeschlagen: %w", err)
+	}
+	if _, err := tx.Exec(`DELETE FROM project_files WHERE project_id = ?`, id); err != nil {
+		return fmt.Errorf("Projekt-Dokumente löschen fehlgeschlagen: %w", err)
+	}
+	if _, err := tx.Exec(`DELETE FROM projects WHERE id = ?`, id); err != nil {
+		return fmt.Errorf("Projekt löschen fehlgeschlagen: %w", err)
+	}
+	return tx.Commit()
+}
+
+// touchProject aktualisiert den updated_at Timestamp eines Projekts
+func (s *Store) touchProject(id int64) {
+	s.db.Exec(`UPDATE projects SET updated_at = ? WHERE id = ?`, time.Now(), id)
+}
+
+// AddProjectFile fügt einem Projekt ein (angeheftetes) Dokument hinzu
+func (s *Store) AddProjectFile(projectID int64, filename, fileType, content string) (*ProjectFile, error) {
+	project, err := s.GetProject(projectID)
+	if err != nil {
+		return nil, err
+	}
+	if project == nil {
+		return nil, fmt.Errorf("Projekt nicht gefunden")
+	}
+	if fileType == "" {
+		fileType = "text/plain"
+	}
+
+	now := time.Now()
+	result, err := s.db.Exec(`
+		INSERT INTO project_files (project_id, filename, file_type, content, pinned, created_at)
+		VALUES (?, ?, ?, ?, 1, ?)
+	`, projectID, filename, fileType, content, now)
+	if err != nil {
+		return nil, fmt.Errorf("Projekt-Dokument speichern fehlgeschlagen: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	s.touchProject(projectID)
+
+	return &ProjectFile{
+		ID:              id,
+		ProjectID:       projectID,
+		Filename:        filename,
+		FileType:        fileType,
+		Size:            int64(len(content)),
+		EstimatedTokens: estimateTokens(content),
+		Pinned:          true,
+		CreatedAt:       now,
+	}, nil
+}
+
+// GetProjectFile lädt ein Projekt-Dokument inkl. Inhalt (nil wenn nicht gefunden)
+func (s *Store) GetProjectFile(id int64) (*ProjectFile, error) {
+	f := &ProjectFile{}
+	err := s.db.QueryRow(`
+		SELECT id, project_id, filename, file_type, content, pinned, created_at
+		FROM project_files WHERE id = ?
+	`, id).Scan(&f.ID, &f.ProjectID, &f.Filename, &f.FileType, &f.Content, &f.Pinned, &f.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	f.Size = int64(len(f.Content))
+	f.EstimatedTokens = estimateTokens(f.Content)
+	return f, nil
+}
+
+// SetProjectFilePinned heftet ein Dokument an bzw. löst es (nicht mehr injizieren)
+func (s *Store) SetProjectFilePinned(id int64, pinned bool) error {
+	result, err := s.db.Exec(`UPDATE project_files SET pinned = ? WHERE id = ?`, pinned, id)
+	if err != nil {
+		return fmt.Errorf("Dokument aktualisieren fehlgeschlagen: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("Dokument nicht gefunden")
+	}
+	return nil
+}
+
+// DeleteProjectFile löscht ein Projekt-Dokument
+func (s *Store) DeleteProjectFile(id int64) error {
+	_, err := s.db.Exec(`DELETE FROM project_files WHERE id = ?`, id)
+	return err
+}
+
+// AssignChatToProject ordnet einen Chat einem Projekt zu (nil = aus Projekt lösen)
+func (s *Store) AssignChatToProject(chatID int64, projectID *int64) error {
+	if projectID != nil {
+		project, err := s.GetProject(*projectID)
+		if err != nil {
+			return err
+		}
+		if project == nil {
+			return fmt.Errorf("Projekt nicht gefunden")
+		}
+		s.touchProject(*projectID)
+	}
+	result, err := s.db.Exec(`UPDATE chats SET project_id = ? WHERE id = ?`, projectID, chatID)
+	if err != nil {
+		return fmt.Errorf("Chat zuordnen fehlgeschlagen: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("Chat nicht gefunden")
+	}
+	return nil
+}
+
+// GetChatProject lädt das Projekt eines Chats (nil wenn der Chat keinem Projekt angehört)
+func (s *Store) GetChatProject(chatID int64) (*Project, error) {
+	var projectID sql.NullInt64
+	err := s.db.QueryRow(`SELECT project_id FROM chats WHERE id = ?`, chatID).Scan(&projectID)
+	if err == sql.ErrNoRows || (err == nil && !projectID.Valid) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s.GetProject(projectID.Int64)
+}
+
+// BuildProjectContext erstellt den System-Prompt-Zusatz eines Projekts:
+// Projekt-Anweisungen und Inhalt aller angehefteten Dokumente.
+// Sehr große Dokumente werden auf maxProjectContextChars gekürzt.
+func (s *Store) BuildProjectContext(project *Project) (string, error) {
+	if project == nil {
+		return "", nil
+	}
+
+	var sb strings.Builder
+	if strings.TrimSpace(project.SystemPrompt) != "" {
+		sb.WriteString("=== PROJEKT: " + project.Name + " ===\n")
+		sb.WriteString(strings.TrimSpace(project.SystemPrompt))
+	}
+
+	rows, err := s.db.Query(`
+		SELECT filename, content FROM project_files
+		WHERE project_id = ? AND pinned = 1
+		ORDER BY created_at ASC
+	`, project.ID)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	remaining := maxProjectContextChars
+	docsHeader := false
+	for rows.Next() {
+		var filename, content string
+		if err := rows.Scan(&filename, &content); err != nil {
+			return "", err
+		}
+		if remaining <= 0 {
+			log.Printf("Projekt %d: Dokument %q übersprungen (Kontext-Limit erreicht)", project.ID, filename)
+			continue
+		}
+		if !docsHeader {
+			if sb.Len() > 0 {
+				sb.WriteString("\n\n")
+			}
+			sb.WriteString("=== PROJEKT-DOKUMENTE ===")
+			docsHeader = true
+		}
+		if utf8.RuneCountInString(content) > remaining {
+			// Nach remaining Zeichen kürzen - Umlaute zählen einfach, kein halbes UTF-8-Zeichen im Prompt
+			cut, runes := len(content), 0
+			for i := range content {
+				if runes == remaining {
+					cut = i
+					break
+				}
+				runes++
+			}
+			content = content[:cut] + "\n[... gekürzt ...]"
+		}
+		remaining -= utf8.RuneCountInString(content)
+		sb.WriteString("\n\n--- " + filename + " ---\n" + content)
+	}
+
+	if docsHeader {
+		sb.WriteString("\n\nDiese Dokumente gehören zum Projekt und sind für alle Fragen in diesem Chat relevant.")
+	}
+	return sb.String(), nil
+}
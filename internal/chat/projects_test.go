@@ -0,0 +1,82 @@
+package chat
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// TestProjectLifecycle testet Zuordnung, Kontext und Löschen eines Projekts
+func TestProjectLifecycle(t *testing.T) {
+	store, _ := newTestStore(t)
+
+	expertID := int64(3)
+	project, err := store.CreateProject("Steuer 2025", "Belege", "Antworte als Steuerberater.", &expertID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.CreateProject("  ", "", "", nil); err == nil {
+		t.Error("Leerer Projektname sollte abgelehnt werden")
+	}
+
+	pinned, _ := store.AddProjectFile(project.ID, "belege.txt", "txt", "Rechnung Nr. 42")
+	unpinned, _ := store.AddProjectFile(project.ID, "alt.txt", "txt", "Veraltete Notiz")
+	store.SetProjectFilePinned(unpinned.ID, false)
+
+	a, _ := store.CreateChat("A", "m")
+	store.CreateChat("B", "m")
+	if err := store.AssignChatToProject(a.ID, &project.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, _ := store.GetChat(a.ID)
+	if loaded.ProjectID == nil || *loaded.ProjectID != project.ID || loaded.ProjectName != "Steuer 2025" {
+		t.Errorf("Chat-Projekt = %v / %q", loaded.ProjectID, loaded.ProjectName)
+	}
+
+	chats, _ := store.GetChatsFiltered(ChatFilter{ProjectID: &project.ID})
+	if len(chats) != 1 || chats[0].ID != a.ID {
+		t.Errorf("Projekt-Filter lieferte %d Chats, erwartet nur Chat A", len(chats))
+	}
+
+	got, _ := store.GetChatProject(a.ID)
+	if got == nil || len(got.ContextFiles) != 2 || len(got.ChatIDs) != 1 {
+		t.Fatalf("GetChatProject = %+v", got)
+	}
+	context, err := store.BuildProjectContext(got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(context, "Antworte als Steuerberater.") || !strings.Contains(context, "Rechnung Nr. 42") {
+		t.Errorf("Projekt-Kontext unvollständig: %q", context)
+	}
+	if strings.Contains(context, "Veraltete Notiz") {
+		t.Error("Nicht angeheftetes Dokument darf nicht injiziert werden")
+	}
+
+	// Kürzung am Kontext-Limit zählt Zeichen statt Bytes und trennt keine Umlaute
+	big, _ := store.AddProjectFile(project.ID, "gross.txt", "txt", strings.Repeat("ä", maxProjectContextChars))
+	context, err = store.BuildProjectContext(got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !utf8.ValidString(context) || !strings.Contains(context, "[... gekürzt ...]") {
+		t.Errorf("Gekürzter Kontext ungültig (%d Bytes)", len(context))
+	}
+	if n, want := strings.Count(context, "ä"), maxProjectContextChars-utf8.RuneCountInString("Rechnung Nr. 42"); n != want {
+		t.Errorf("Gekürztes Dokument: %d Zeichen, erwartet %d", n, want)
+	}
+	store.DeleteProjectFile(big.ID)
+
+	// Löschen: Chat bleibt erhalten, Dokumente verschwinden
+	if err := store.DeleteProject(project.ID); err != nil {
+		t.Fatal(err)
+	}
+	loaded, _ = store.GetChat(a.ID)
+	if loaded == nil || loaded.ProjectID != nil {
+		t.Errorf("Chat sollte erhalten und aus dem Projekt gelöst sein: %+v", loaded)
+	}
+	if f, _ := store.GetProjectFile(pinned.ID); f != nil {
+		t.Error("Projekt-Dokument sollte gelöscht sein")
+	}
+}
@@ -4,6 +4,7 @@
 //   - Chats: Konversations-Container mit Titel und Modell
 //   - Messages: Einzelne Nachrichten mit Rolle (USER/ASSISTANT)
 //   - Expert/Mode-Zuordnung: Fixe Verknüpfung pro Nachricht
+//   - Anhänge: Content-Addressed Blob-Speicher (siehe attachments.go)
 //
 // Datenbank: SQLite mit WAL-Modus für bessere Concurrent-Performance
 // Erstellt: 2025-12-15
@@ -15,6 +16,7 @@ import (
 	"log"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	// SQLite-Treiber (pure Go, keine CGO-Abhängigkeit)
@@ -43,6 +45,15 @@ type Chat struct {
 	// UpdatedAt: Zeitpunkt der letzten Änderung (neue Nachricht, Umbenennung, etc.)
 	UpdatedAt time.Time `json:"updatedAt"`
 
+	// ProjectID: Projekt dem der Chat angehört (nil = kein Projekt)
+	ProjectID *int64 `json:"projectId,omitempty"`
+
+	// ProjectName: Name des Projekts (nur zur Anzeige)
+	ProjectName string `json:"projectName,omitempty"`
+
+	// Tags: Schlagworte des Chats (automatisch vorgeschlagen oder manuell gesetzt)
+	Tags []Tag `json:"tags,omitempty"`
+
 	// Messages: Liste aller Nachrichten in diesem Chat (chronologisch sortiert)
 	// Wird nur bei GetChat() geladen, nicht bei GetAllChats()
 	Messages []StoredMessage `json:"messages,omitempty"`
@@ -60,6 +71,7 @@ type StoredMessage struct {
 	// Role: Absender der Nachricht
 	// - "USER": Nachricht vom Benutzer
 	// - "ASSISTANT": Antwort vom KI-Assistenten
+	// - "SYSTEM": Systemnachricht (z.B. Observer-Alarm)
 	Role string `json:"role"`
 
 	// Content: Der eigentliche Nachrichtentext (kann Markdown enthalten)
@@ -82,11 +94,15 @@ type StoredMessage struct {
 	// WICHTIG: Diese Zuordnung ist UNVERÄNDERLICH nach Erstellung!
 	ModeID *int64 `json:"modeId,omitempty"`
 
-	// Attachments: JSON-String mit Anhängen (Bilder, Dateien)
-	// Format: [{"name":"screenshot.png","type":"image","base64":"..."},...]
-	// Für User-Nachrichten: Hochgeladene Bilder/Dateien
+	// Attachments: JSON-String mit Anhang-Referenzen (Bilder, Dateien)
+	// Format: [{"id":"<sha256>","name":"screenshot.png","type":"image","url":"/api/attachments/<sha256>"},...]
+	// Die Daten selbst liegen im Blob-Speicher, nicht in der Datenbank.
 	Attachments string `json:"attachments,omitempty"`
 
+	// Interrupted: Antwort wurde vorzeitig abgebrochen (Cancel oder Client-Disconnect)
+	// Content enthält dann nur den bis dahin generierten Teil.
+	Interrupted bool `json:"interrupted,omitempty"`
+
 	// CreatedAt: Erstellungszeitpunkt der Nachricht
 	CreatedAt time.Time `json:"createdAt"`
 }
@@ -98,7 +114,14 @@ type StoredMessage struct {
 // Store ist der zentrale Datenbankzugriff für Chat-Operationen.
 // Thread-safe durch SQLite's interne Synchronisation und Connection-Pooling.
 type Store struct {
-	db *sql.DB // Datenbank-Connection-Pool
+	db            *sql.DB // Datenbank-Connection-Pool
+	attachmentDir string  // Verzeichnis des Anhang-Blob-Speichers
+
+	// attachmentMu serialisiert Speichern/Verknüpfen von Anhängen mit der Garbage-Collection,
+	// damit ein gerade gespeicherter Blob nicht vor seiner Verknüpfung entfernt wird
+	attachmentMu sync.Mutex
+	// attachmentGCGrace: Blobs und Dateien jünger als dieser Zeitraum werden von der GC nicht entfernt
+	attachmentGCGrace time.Duration
 }
 
 // NewStore erstellt einen neuen Chat-Store und initialisiert die Datenbank.
@@ -145,7 +168,7 @@ func NewStore(dataDir string) (*Store, error) {
 	db.SetMaxIdleConns(2) // 2 Verbindungen im Pool halten
 
 	// Store erstellen und Schema initialisieren
-	store := &Store{db: db}
+	store := &Store{db: db, attachmentDir: filepath.Join(dataDir, "attachments"), attachmentGCGrace: defaultAttachmentGCGrace}
 
 	if err := store.createSchema(); err != nil {
 		return nil, err
@@ -195,6 +218,22 @@ func (s *Store) createSchema() error {
 	// Migration für bestehende Datenbanken ausführen
 	s.migrateSchema()
 
+	// Tags (n:m zu Chats)
+	if err := s.createTagSchema(); err != nil {
+		return err
+	}
+
+	// Projekte (Arbeitsbereiche mit gemeinsamem Prompt und Dokumenten)
+	if err := s.createProjectSchema(); err != nil {
+		return err
+	}
+
+	// Anhang-Speicher: Tabellen anlegen und alte Base64-Anhänge auslagern
+	if err := s.createAttachmentSchema(); err != nil {
+		return err
+	}
+	s.migrateInlineAttachments()
+
 	return nil
 }
 
@@ -204,6 +243,9 @@ func (s *Store) createSchema() error {
 // Aktuelle Migrationen:
 //   - expert_id: Hinzugefügt 2025-12-15 für fixe Expert-Zuordnung
 //   - mode_id: Hinzugefügt 2025-12-15 für fixe Modus-Zuordnung
+//   - attachments: Hinzugefügt 2025-12-31 für Anhänge
+//   - interrupted: Markierung abgebrochener Antworten
+//   - title_source: Herkunft des Chat-Titels (message, generated, manual)
 func (s *Store) migrateSchema() {
 	// -------------------------------------------------------------------------
 	// Migration 1: expert_id Spalte
@@ -245,6 +287,47 @@ func (s *Store) migrateSchema() {
 	} else {
 		log.Printf("Migration: attachments Spalte zu messages hinzugefügt")
 	}
+
+	// -------------------------------------------------------------------------
+	// Migration 4: interrupted Spalte
+	// Markiert abgebrochene Antworten (Teilantwort wurde gespeichert)
+	// -------------------------------------------------------------------------
+	_, err = s.db.Exec(`ALTER TABLE messages ADD COLUMN interrupted INTEGER DEFAULT 0`)
+	if err != nil {
+		if !strings.Contains(err.Error(), "duplicate column") {
+			log.Printf("Migration interrupted fehlgeschlagen: %v", err)
+		}
+	} else {
+		log.Printf("Migration: interrupted Spalte zu messages hinzugefügt")
+	}
+
+	// -------------------------------------------------------------------------
+	// Migration 5: title_source Spalte in chats
+	// Unterscheidet abgeschnittene, generierte und manuell gesetzte Titel,
+	// damit die automatische Titel-Generierung keine Umbenennung überschreibt.
+	// Bestehende Chats gelten als "manual" (Titel nicht nachträglich ändern).
+	// -------------------------------------------------------------------------
+	_, err = s.db.Exec(`ALTER TABLE chats ADD COLUMN title_source TEXT DEFAULT 'manual'`)
+	if err != nil {
+		if !strings.Contains(err.Error(), "duplicate column") {
+			log.Printf("Migration title_source fehlgeschlagen: %v", err)
+		}
+	} else {
+		log.Printf("Migration: title_source Spalte zu chats hinzugefügt")
+	}
+
+	// -------------------------------------------------------------------------
+	// Migration 6: project_id Spalte in chats
+	// Ordnet Chats einem Projekt zu (NULL = kein Projekt)
+	// -------------------------------------------------------------------------
+	_, err = s.db.Exec(`ALTER TABLE chats ADD COLUMN project_id INTEGER DEFAULT NULL`)
+	if err != nil {
+		if !strings.Contains(err.Error(), "duplicate column") {
+			log.Printf("Migration project_id fehlgeschlagen: %v", err)
+		}
+	} else {
+		log.Printf("Migration: project_id Spalte zu chats hinzugefügt")
+	}
 }
 
 // Close schließt die Datenbankverbindung.
@@ -258,6 +341,7 @@ func (s *Store) Close() error {
 // =============================================================================
 
 // CreateChat erstellt einen neuen leeren Chat.
+// Der Titel gilt als manuell gesetzt und wird nicht automatisch ersetzt.
 //
 // Parameter:
 //   - title: Anzeigename des Chats
@@ -267,12 +351,24 @@ func (s *Store) Close() error {
 //   - *Chat: Der neu erstellte Chat mit generierter ID
 //   - error: Datenbankfehler
 func (s *Store) CreateChat(title, model string) (*Chat, error) {
+	return s.createChat(title, model, TitleSourceManual)
+}
+
+// CreateChatWithPlaceholderTitle erstellt einen Chat dessen Titel nur ein Platzhalter
+// (Anfang der ersten Nachricht) ist. Er wird nach dem ersten Austausch durch
+// einen generierten Titel ersetzt (siehe SetGeneratedTitle).
+func (s *Store) CreateChatWithPlaceholderTitle(title, model string) (*Chat, error) {
+	return s.createChat(title, model, TitleSourceMessage)
+}
+
+// createChat legt einen Chat mit der angegebenen Titel-Quelle an
+func (s *Store) createChat(title, model, titleSource string) (*Chat, error) {
 	now := time.Now()
 
 	result, err := s.db.Exec(`
-		INSERT INTO chats (title, model, created_at, updated_at)
-		VALUES (?, ?, ?, ?)
-	`, title, model, now, now)
+		INSERT INTO chats (title, model, title_source, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?)
+	`, title, model, titleSource, now, now)
 
 	if err != nil {
 		return nil, fmt.Errorf("Chat erstellen fehlgeschlagen: %w", err)
@@ -306,10 +402,7 @@ func (s *Store) GetChat(id int64) (*Chat, error) {
 	chat := &Chat{}
 
 	// Chat-Metadaten laden
-	err := s.db.QueryRow(`
-		SELECT id, title, model, created_at, updated_at
-		FROM chats WHERE id = ?
-	`, id).Scan(&chat.ID, &chat.Title, &chat.Model, &chat.CreatedAt, &chat.UpdatedAt)
+	err := scanChat(s.db.QueryRow(`SELECT `+chatColumns+` FROM chats WHERE id = ?`, id), chat)
 
 	// Chat nicht gefunden ist kein Fehler, gibt nil zurück
 	if err == sql.ErrNoRows {
@@ -326,6 +419,11 @@ func (s *Store) GetChat(id int64) (*Chat, error) {
 	}
 	chat.Messages = messages
 
+	// Tags laden (Fehler nicht kritisch)
+	if tags, err := s.GetChatTags(id); err == nil && len(tags) > 0 {
+		chat.Tags = tags
+	}
+
 	return chat, nil
 }
 
@@ -336,11 +434,7 @@ func (s *Store) GetChat(id int64) (*Chat, error) {
 //   - []Chat: Liste aller Chats (ohne Messages)
 //   - error: Datenbankfehler
 func (s *Store) GetAllChats() ([]Chat, error) {
-	rows, err := s.db.Query(`
-		SELECT id, title, model, created_at, updated_at
-		FROM chats
-		ORDER BY updated_at DESC
-	`)
+	rows, err := s.db.Query(`SELECT ` + chatColumns + ` FROM chats ORDER BY updated_at DESC`)
 	if err != nil {
 		return nil, err
 	}
@@ -350,8 +444,7 @@ func (s *Store) GetAllChats() ([]Chat, error) {
 	chats := make([]Chat, 0)
 	for rows.Next() {
 		var c Chat
-		err := rows.Scan(&c.ID, &c.Title, &c.Model, &c.CreatedAt, &c.UpdatedAt)
-		if err != nil {
+		if err := scanChat(rows, &c); err != nil {
 			return nil, err
 		}
 		chats = append(chats, c)
@@ -370,9 +463,9 @@ func (s *Store) GetAllChats() ([]Chat, error) {
 func (s *Store) UpdateChat(id int64, title, model *string) error {
 	now := time.Now()
 
-	// Titel aktualisieren wenn angegeben
+	// Titel aktualisieren wenn angegeben (gilt danach als manuell gesetzt)
 	if title != nil {
-		_, err := s.db.Exec(`UPDATE chats SET title = ?, updated_at = ? WHERE id = ?`, *title, now, id)
+		_, err := s.db.Exec(`UPDATE chats SET title = ?, title_source = ?, updated_at = ? WHERE id = ?`, *title, TitleSourceManual, now, id)
 		if err != nil {
 			return err
 		}
@@ -396,7 +489,13 @@ func (s *Store) UpdateChat(id int64, title, model *string) error {
 //   - id: Die Chat-ID
 func (s *Store) DeleteChat(id int64) error {
 	_, err := s.db.Exec("DELETE FROM chats WHERE id = ?", id)
-	return err
+	if err != nil {
+		return err
+	}
+	// Tag-Zuordnungen explizit entfernen (Foreign Keys sind nicht auf jeder Pool-Verbindung aktiv)
+	s.db.Exec("DELETE FROM chat_tags WHERE chat_id = ?", id)
+	s.deleteUnusedTags()
+	return nil
 }
 
 // =============================================================================
@@ -456,8 +555,24 @@ func (s *Store) AddMessage(chatID int64, role, content, model string, tokens int
 	}, nil
 }
 
+// AddInterruptedMessage speichert eine abgebrochene (Teil-)Antwort.
+// Wird verwendet wenn der Benutzer die Generierung stoppt oder die Verbindung abreißt.
+// Parameter wie bei AddMessage.
+func (s *Store) AddInterruptedMessage(chatID int64, role, content, model string, tokens int, expertID, modeID *int64) (*StoredMessage, error) {
+	msg, err := s.AddMessage(chatID, role, content, model, tokens, expertID, modeID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.db.Exec(`UPDATE messages SET interrupted = 1 WHERE id = ?`, msg.ID); err != nil {
+		return nil, fmt.Errorf("Nachricht als abgebrochen markieren fehlgeschlagen: %w", err)
+	}
+	msg.Interrupted = true
+	return msg, nil
+}
+
 // AddMessageWithAttachments fügt eine Nachricht mit Anhängen hinzu.
-// Anhänge werden als JSON-String gespeichert (inkl. Base64-Bilddaten).
+// Die Anhänge werden im Blob-Speicher abgelegt (dedupliziert per SHA-256),
+// die Nachricht speichert nur die Referenzen.
 //
 // Parameter:
 //   - chatID: Der Chat zu dem die Nachricht gehört
@@ -467,9 +582,23 @@ func (s *Store) AddMessage(chatID int64, role, content, model string, tokens int
 //   - tokens: Anzahl verbrauchter Tokens
 //   - expertID: Fixe Expert-Zuordnung (nil = kein Experte)
 //   - modeID: Fixe Modus-Zuordnung (nil = Standard-Modus)
-//   - attachments: JSON-String mit Anhängen (leer wenn keine)
-func (s *Store) AddMessageWithAttachments(chatID int64, role, content, model string, tokens int, expertID, modeID *int64, attachments string) (*StoredMessage, error) {
+//   - attachments: Neue Anhänge mit Rohdaten (leer wenn keine)
+func (s *Store) AddMessageWithAttachments(chatID int64, role, content, model string, tokens int, expertID, modeID *int64, attachments []NewAttachment) (*StoredMessage, error) {
+	// Blob speichern, Nachricht einfügen und verknüpfen ohne dass die GC dazwischen läuft
+	s.attachmentMu.Lock()
+	defer s.attachmentMu.Unlock()
+
+	refs, err := s.storeAttachments(attachments)
+	if err != nil {
+		return nil, err
+	}
+	return s.addMessageWithRefs(chatID, role, content, model, tokens, expertID, modeID, refs)
+}
+
+// addMessageWithRefs fügt eine Nachricht mit bereits gespeicherten Anhang-Referenzen hinzu
+func (s *Store) addMessageWithRefs(chatID int64, role, content, model string, tokens int, expertID, modeID *int64, refs []AttachmentRef) (*StoredMessage, error) {
 	now := time.Now()
+	attachments := encodeAttachmentRefs(refs)
 
 	// Nachricht in Datenbank einfügen (mit attachments)
 	result, err := s.db.Exec(`
@@ -491,6 +620,10 @@ func (s *Store) AddMessageWithAttachments(chatID int64, role, content, model str
 		return nil, err
 	}
 
+	if err := s.linkAttachments(id, refs); err != nil {
+		return nil, err
+	}
+
 	return &StoredMessage{
 		ID:          id,
 		ChatID:      chatID,
@@ -516,7 +649,7 @@ func (s *Store) AddMessageWithAttachments(chatID int64, role, content, model str
 //   - error: Datenbankfehler
 func (s *Store) GetMessages(chatID int64) ([]StoredMessage, error) {
 	rows, err := s.db.Query(`
-		SELECT id, chat_id, role, content, tokens, model, expert_id, mode_id, attachments, created_at
+		SELECT id, chat_id, role, content, tokens, model, expert_id, mode_id, attachments, COALESCE(interrupted, 0), created_at
 		FROM messages
 		WHERE chat_id = ?
 		ORDER BY created_at ASC
@@ -531,7 +664,7 @@ func (s *Store) GetMessages(chatID int64) ([]StoredMessage, error) {
 		var m StoredMessage
 		var attachments sql.NullString // Nullable Feld
 		// Alle Felder scannen inkl. nullable expert_id, mode_id und attachments
-		err := rows.Scan(&m.ID, &m.ChatID, &m.Role, &m.Content, &m.Tokens, &m.Model, &m.ExpertID, &m.ModeID, &attachments, &m.CreatedAt)
+		err := rows.Scan(&m.ID, &m.ChatID, &m.Role, &m.Content, &m.Tokens, &m.Model, &m.ExpertID, &m.ModeID, &attachments, &m.Interrupted, &m.CreatedAt)
 		if err != nil {
 			return nil, err
 		}
@@ -567,7 +700,7 @@ func (s *Store) UpdateChatTimestamp(chatID int64) error {
 //   - error: Datenbankfehler
 func (s *Store) RenameChat(id int64, newTitle string) (*Chat, error) {
 	now := time.Now()
-	_, err := s.db.Exec(`UPDATE chats SET title = ?, updated_at = ? WHERE id = ?`, newTitle, now, id)
+	_, err := s.db.Exec(`UPDATE chats SET title = ?, title_source = ?, updated_at = ? WHERE id = ?`, newTitle, TitleSourceManual, now, id)
 	if err != nil {
 		return nil, fmt.Errorf("Chat umbenennen fehlgeschlagen: %w", err)
 	}
@@ -653,14 +786,36 @@ func (s *Store) ForkChat(originalID int64, newTitle string) (*Chat, error) {
 
 	// Alle Nachrichten vom Original in den Fork kopieren
 	// WICHTIG: ExpertID und ModeID werden mitkopiert (fixe Zuordnung bleibt erhalten)
+	// Anhänge werden nur referenziert - der Blob wird nicht dupliziert. Die Sperre hält die GC
+	// fern, bis die Referenzen verknüpft sind.
+	s.attachmentMu.Lock()
 	for _, msg := range original.Messages {
-		_, err := s.AddMessage(forkedChat.ID, msg.Role, msg.Content, msg.Model, msg.Tokens, msg.ExpertID, msg.ModeID)
+		refs, err := s.GetMessageAttachments(msg.ID)
+		if err == nil {
+			_, err = s.addMessageWithRefs(forkedChat.ID, msg.Role, msg.Content, msg.Model, msg.Tokens, msg.ExpertID, msg.ModeID, refs)
+		}
 		if err != nil {
+			s.attachmentMu.Unlock()
 			// Bei Fehler: Fork-Chat wieder löschen (Transaktion simulieren)
 			s.DeleteChat(forkedChat.ID)
 			return nil, fmt.Errorf("Nachrichten kopieren fehlgeschlagen: %w", err)
 		}
 	}
+	s.attachmentMu.Unlock()
+
+	// Tags mit ihrer Herkunft übernehmen
+	for _, tag := range original.Tags {
+		if _, err := s.AddChatTags(forkedChat.ID, []string{tag.Name}, tag.Source); err != nil {
+			log.Printf("WARNUNG: Tag %q konnte nicht in Fork übernommen werden: %v", tag.Name, err)
+		}
+	}
+
+	// Fork bleibt im selben Projekt
+	if original.ProjectID != nil {
+		if err := s.AssignChatToProject(forkedChat.ID, original.ProjectID); err != nil {
+			log.Printf("WARNUNG: Projekt-Zuordnung konnte nicht in Fork übernommen werden: %v", err)
+		}
+	}
 
 	// Vollständigen Fork mit allen Nachrichten zurückgeben
 	return s.GetChat(forkedChat.ID)
@@ -698,6 +853,13 @@ func (s *Store) ExportChat(id int64) (map[string]interface{}, error) {
 		"updatedAt": chatObj.UpdatedAt.Format(time.RFC3339),
 		"messages":  make([]map[string]interface{}, 0, len(chatObj.Messages)),
 	}
+	if len(chatObj.Tags) > 0 {
+		tagNames := make([]string, 0, len(chatObj.Tags))
+		for _, tag := range chatObj.Tags {
+			tagNames = append(tagNames, tag.Name)
+		}
+		export["tags"] = tagNames
+	}
 
 	// Nachrichten in Export-Format konvertieren
 	messages := make([]map[string]interface{}, 0, len(chatObj.Messages))
@@ -717,6 +879,12 @@ func (s *Store) ExportChat(id int64) (map[string]interface{}, error) {
 		if msg.ModeID != nil {
 			msgExport["modeId"] = *msg.ModeID
 		}
+		if msg.Interrupted {
+			msgExport["interrupted"] = true
+		}
+		if refs, err := s.GetMessageAttachments(msg.ID); err == nil && len(refs) > 0 {
+			msgExport["attachments"] = refs
+		}
 		messages = append(messages, msgExport)
 	}
 	export["messages"] = messages
@@ -0,0 +1,437 @@
+package chat
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// =============================================================================
+// TAGS & AUTOMATISCHE TITEL
+// =============================================================================
+//
+// Chats können beliebig viele Tags haben (n:m über chat_tags).
+// Tags werden entweder automatisch nach dem ersten Austausch vom lokalen Modell
+// vorgeschlagen (source = "auto") oder vom Benutzer gesetzt (source = "manual").
+//
+// Der Chat-Titel wird anfangs aus der ersten Nachricht abgeschnitten
+// (title_source = "message") und danach asynchron durch einen generierten
+// Titel ersetzt - aber nur, solange der Benutzer ihn nicht selbst umbenannt hat.
+
+// Titel-Quellen (chats.title_source)
+const (
+	TitleSourceMessage   = "message"   // Erste 50 Zeichen der Nachricht
+	TitleSourceGenerated = "generated" // Vom lokalen Modell generiert
+	TitleSourceManual    = "manual"    // Vom Benutzer umbenannt
+)
+
+// Tag-Quellen (chat_tags.source)
+const (
+	TagSourceAuto   = "auto"   // Vom Modell vorgeschlagen
+	TagSourceManual = "manual" // Vom Benutzer gesetzt
+)
+
+// maxTagLength begrenzt die Länge eines Tag-Namens
+const maxTagLength = 32
+
+// Tag repräsentiert ein Schlagwort für Chats
+type Tag struct {
+	ID     int64  `json:"id"`
+	Name   string `json:"name"`
+	Source string `json:"source,omitempty"` // Nur bei Chat-Zuordnung gesetzt
+	Count  int    `json:"count,omitempty"`  // Nur bei ListTags gesetzt
+}
+
+// ChatFilter filtert die Chat-Liste
+type ChatFilter struct {
+	Tags     []string // Tag-Namen (leer = kein Filter)
+	MatchAll bool     // true = Chat muss ALLE Tags haben, false = mindestens einen
+
+	ProjectID *int64 // Nur Chats dieses Projekts (nil = alle)
+}
+
+// createTagSchema erstellt die Tabellen für Tags
+func (s *Store) createTagSchema() error {
+	schema := `
+	-- Tabelle: tags
+	CREATE TABLE IF NOT EXISTS tags (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name TEXT NOT NULL UNIQUE COLLATE NOCASE, -- Tag-Name (Groß/Klein egal)
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
+	-- Tabelle: chat_tags (n:m Chats <-> Tags)
+	CREATE TABLE IF NOT EXISTS chat_tags (
+		chat_id INTEGER NOT NULL,
+		tag_id INTEGER NOT NULL,
+		source TEXT DEFAULT 'manual',          -- auto oder manual
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (chat_id, tag_id),
+		FOREIGN KEY (chat_id) REFERENCES chats(id) ON DELETE CASCADE,
+		FOREIGN KEY (tag_id) REFERENCES tags(id) ON DELETE CASCADE
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_chat_tags_tag_id ON chat_tags(tag_id);
+	`
+	if _, err := s.db.Exec(schema); err != nil {
+		return fmt.Errorf("Tag-Schema erstellen fehlgeschlagen: %w", err)
+	}
+	return nil
+}
+
+// NormalizeTagName bereinigt einen Tag-Namen (Trim, Kleinbuchstaben, ohne #, max. Länge)
+func NormalizeTagName(name string) string {
+	name = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(name), "#"))
+	name = strings.ToLower(strings.Join(strings.Fields(name), " "))
+	if r := []rune(name); len(r) > maxTagLength {
+		name = string(r[:maxTagLength])
+	}
+	return name
+}
+
+// ensureTag legt einen Tag an (falls nicht vorhanden) und gibt seine ID zurück
+func (s *Store) ensureTag(name string) (int64, error) {
+	if _, err := s.db.Exec(`INSERT OR IGNORE INTO tags (name, created_at) VALUES (?, ?)`, name, time.Now()); err != nil {
+		return 0, fmt.Errorf("Tag anlegen fehlgeschlagen: %w", err)
+	}
+	var id int64
+	if err := s.db.QueryRow(`SELECT id FROM tags WHERE name = ?`, name).Scan(&id); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+// ListTags gibt alle Tags mit Anzahl der zugeordneten Chats zurück.
+// Sortiert nach Häufigkeit (häufigste zuerst).
+func (s *Store) ListTags() ([]Tag, error) {
+	rows, err := s.db.Query(`
+		SELECT t.id, t.name, COUNT(ct.chat_id) AS cnt
+		FROM tags t
+		LEFT JOIN chat_tags ct ON ct.tag_id = t.id
+		GROUP BY t.id
+		ORDER BY cnt DESC, t.name ASC
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := make([]Tag, 0)
+	for rows.Next() {
+		var t Tag
+		if err := rows.Scan(&t.ID, &t.Name, &t.Count); err != nil {
+			return nil, err
+		}
+		tags = append(tags, t)
+	}
+	return tags, nil
+}
+
+// GetChatTags lädt die Tags eines Chats
+func (s *Store) GetChatTags(chatID int64) ([]Tag, error) {
+	rows, err := s.db.Query(`
+		SELECT t.id, t.name, ct.source
+		FROM chat_tags ct
+		JOIN tags t ON t.id = ct.tag_id
+		WHERE ct.chat_id = ?
+		ORDER BY t.name ASC
+	`, chatID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := make([]Tag, 0)
+	for rows.Next() {
+		var t Tag
+		if err := rows.Scan(&t.ID, &t.Name, &t.Source); err != nil {
+			return nil, err
+		}
+		tags = append(tags, t)
+	}
+	return tags, nil
+}
+
+// AddChatTags ordnet einem Chat Tags zu.
+// Bereits vorhandene Zuordnungen bleiben unverändert (manuelle Tags werden nicht zu "auto").
+//
+// Parameter:
+//   - chatID: Die Chat-ID
+//   - names: Tag-Namen (werden normalisiert, leere ignoriert)
+//   - source: TagSourceAuto oder TagSourceManual
+func (s *Store) AddChatTags(chatID int64, names []string, source string) ([]Tag, error) {
+	for _, raw := range names {
+		name := NormalizeTagName(raw)
+		if name == "" {
+			continue
+		}
+		tagID, err := s.ensureTag(name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := s.db.Exec(`
+			INSERT OR IGNORE INTO chat_tags (chat_id, tag_id, source, created_at)
+			VALUES (?, ?, ?, ?)
+		`, chatID, tagID, source, time.Now()); err != nil {
+			return nil, fmt.Errorf("Tag zuordnen fehlgeschlagen: %w", err)
+		}
+	}
+	return s.GetChatTags(chatID)
+}
+
+// SetChatTags ersetzt alle Tags eines Chats (manuelle Bearbeitung).
+// Alle übergebenen Tags gelten danach als manuell gesetzt.
+func (s *Store) SetChatTags(chatID int64, names []string) ([]Tag, error) {
+	if _, err := s.db.Exec(`DELETE FROM chat_tags WHERE chat_id = ?`, chatID); err != nil {
+		return nil, fmt.Errorf("Tags zurücksetzen fehlgeschlagen: %w", err)
+	}
+	tags, err := s.AddChatTags(chatID, names, TagSourceManual)
+	if err != nil {
+		return nil, err
+	}
+	s.deleteUnusedTags()
+	return tags, nil
+}
+
+// RemoveChatTag entfernt einen Tag von einem Chat
+func (s *Store) RemoveChatTag(chatID int64, name string) error {
+	_, err := s.db.Exec(`
+		DELETE FROM chat_tags
+		WHERE chat_id = ? AND tag_id = (SELECT id FROM tags WHERE name = ?)
+	`, chatID, NormalizeTagName(name))
+	if err != nil {
+		return fmt.Errorf("Tag entfernen fehlgeschlagen: %w", err)
+	}
+	s.deleteUnusedTags()
+	return nil
+}
+
+// DeleteTag löscht einen Tag komplett (inkl. aller Zuordnungen)
+func (s *Store) DeleteTag(id int64) error {
+	if _, err := s.db.Exec(`DELETE FROM chat_tags WHERE tag_id = ?`, id); err != nil {
+		return fmt.Errorf("Tag-Zuordnungen löschen fehlgeschlagen: %w", err)
+	}
+	if _, err := s.db.Exec(`DELETE FROM tags WHERE id = ?`, id); err != nil {
+		return fmt.Errorf("Tag löschen fehlgeschlagen: %w", err)
+	}
+	return nil
+}
+
+// deleteUnusedTags entfernt Tags ohne Chat-Zuordnung
+func (s *Store) deleteUnusedTags() {
+	if _, err := s.db.Exec(`DELETE FROM tags WHERE id NOT IN (SELECT DISTINCT tag_id FROM chat_tags)`); err != nil {
+		log.Printf("WARNUNG: Unbenutzte Tags konnten nicht entfernt werden: %v", err)
+	}
+}
+
+// loadTagsForChats lädt die Tags für mehrere Chats in einer Abfrage
+func (s *Store) loadTagsForChats(chats []Chat) error {
+	if len(chats) == 0 {
+		return nil
+	}
+	rows, err := s.db.Query(`
+		SELECT ct.chat_id, t.id, t.name, ct.source
+		FROM chat_tags ct
+		JOIN tags t ON t.id = ct.tag_id
+		ORDER BY t.name ASC
+	`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	byChat := make(map[int64][]Tag)
+	for rows.Next() {
+		var chatID int64
+		var t Tag
+		if err := rows.Scan(&chatID, &t.ID, &t.Name, &t.Source); err != nil {
+			return err
+		}
+		byChat[chatID] = append(byChat[chatID], t)
+	}
+	for i := range chats {
+		chats[i].Tags = byChat[chats[i].ID]
+	}
+	return nil
+}
+
+// GetChatsFiltered lädt alle Chats (ohne Nachrichten, mit Tags) gefiltert nach Tags.
+// Ohne Filter entspricht das Ergebnis GetAllChats.
+func (s *Store) GetChatsFiltered(filter ChatFilter) ([]Chat, error) {
+	var names []string
+	for _, raw := range filter.Tags {
+		if name := NormalizeTagName(raw); name != "" {
+			names = append(names, name)
+		}
+	}
+
+	query := `SELECT ` + chatColumns + ` FROM chats`
+	var conditions []string
+	var args []interface{}
+	if len(names) > 0 {
+		placeholders := strings.TrimSuffix(strings.Repeat("?,", len(names)), ",")
+		conditions = append(conditions, `id IN (
+			SELECT ct.chat_id FROM chat_tags ct
+			JOIN tags t ON t.id = ct.tag_id
+			WHERE t.name IN (`+placeholders+`)
+			GROUP BY ct.chat_id
+			HAVING COUNT(DISTINCT t.id) >= ?
+		)`)
+		for _, n := range names {
+			args = append(args, n)
+		}
+		minMatches := 1
+		if filter.MatchAll {
+			minMatches = len(names)
+		}
+		args = append(args, minMatches)
+	}
+	if filter.ProjectID != nil {
+		conditions = append(conditions, `project_id = ?`)
+		args = append(args, *filter.ProjectID)
+	}
+	if len(conditions) > 0 {
+		query += ` WHERE ` + strings.Join(conditions, ` AND `)
+	}
+	query += ` ORDER BY updated_at DESC`
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	chats := make([]Chat, 0)
+	for rows.Next() {
+		var c Chat
+		if err := scanChat(rows, &c); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		chats = append(chats, c)
+	}
+	rows.Close()
+
+	if err := s.loadTagsForChats(chats); err != nil {
+		return nil, err
+	}
+	return chats, nil
+}
+
+// SetGeneratedTitle setzt einen generierten Titel - aber nur wenn der Titel noch
+// aus der ersten Nachricht stammt (manuelle Umbenennungen werden nie überschrieben).
+//
+// Rückgabe:
+//   - bool: true wenn der Titel geändert wurde
+func (s *Store) SetGeneratedTitle(chatID int64, title string) (bool, error) {
+	result, err := s.db.Exec(`
+		UPDATE chats SET title = ?, title_source = ?
+		WHERE id = ? AND COALESCE(title_source, ?) = ?
+	`, title, TitleSourceGenerated, chatID, TitleSourceMessage, TitleSourceMessage)
+	if err != nil {
+		return false, fmt.Errorf("Titel setzen fehlgeschlagen: %w", err)
+	}
+	affected, _ := result.RowsAffected()
+	return affected > 0, nil
+}
+
+// =============================================================================
+// PROMPT & PARSER FÜR TITEL/TAG-VORSCHLÄGE
+// =============================================================================
+
+// TitleAndTagsPrompt ist der System-Prompt für die Titel- und Tag-Generierung
+const TitleAndTagsPrompt = `Du erstellst Titel und Schlagworte für Chat-Verläufe.
+Antworte AUSSCHLIESSLICH mit einem JSON-Objekt in diesem Format:
+{"title": "Kurzer Titel", "tags": ["tag1", "tag2"]}
+
+Regeln:
+- Titel: maximal 6 Wörter, in der Sprache des Gesprächs, ohne Anführungszeichen und ohne Satzzeichen am Ende
+- Tags: 1 bis 3 kurze Schlagworte in Kleinbuchstaben (z.B. "recht", "programmierung", "finanzen")
+- Keine Erklärungen, kein Markdown`
+
+// BuildTitleRequest baut die User-Nachricht für die Titel-Generierung.
+// Lange Nachrichten werden gekürzt, damit die Anfrage schnell bleibt.
+func BuildTitleRequest(userMessage, assistantResponse string) string {
+	return fmt.Sprintf("Benutzer: %s\n\nAssistent: %s", truncateRunes(userMessage, 1000), truncateRunes(assistantResponse, 1000))
+}
+
+// ParseTitleSuggestion liest Titel und Tags aus der Modell-Antwort.
+// Tolerant gegenüber Code-Fences und Text um das JSON herum; fällt auf die
+// erste Zeile als Titel zurück wenn kein JSON gefunden wird.
+func ParseTitleSuggestion(raw string) (string, []string) {
+	raw = strings.TrimSpace(raw)
+	var parsed struct {
+		Title string   `json:"title"`
+		Tags  []string `json:"tags"`
+	}
+
+	start := strings.Index(raw, "{")
+	end := strings.LastIndex(raw, "}")
+	if start >= 0 && end > start && json.Unmarshal([]byte(raw[start:end+1]), &parsed) == nil {
+		return cleanTitle(parsed.Title), cleanTags(parsed.Tags)
+	}
+
+	// Fallback: erste nicht-leere Zeile als Titel
+	for _, line := range strings.Split(raw, "\n") {
+		line = strings.Trim(strings.TrimSpace(line), "`")
+		if line != "" {
+			return cleanTitle(line), nil
+		}
+	}
+	return "", nil
+}
+
+// cleanTitle entfernt Anführungszeichen, Präfixe und überflüssige Satzzeichen
+func cleanTitle(title string) string {
+	title = strings.TrimSpace(title)
+	for _, prefix := range []string{"Titel:", "Title:"} {
+		title = strings.TrimSpace(strings.TrimPrefix(title, prefix))
+	}
+	title = strings.Trim(title, "\"'„“”*#")
+	title = strings.TrimRight(title, ".!:; ")
+	return truncateRunes(strings.TrimSpace(title), 60)
+}
+
+// cleanTags normalisiert Tags, entfernt Duplikate und begrenzt auf 3
+func cleanTags(tags []string) []string {
+	seen := make(map[string]bool)
+	result := make([]string, 0, len(tags))
+	for _, t := range tags {
+		name := NormalizeTagName(t)
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		result = append(result, name)
+		if len(result) == 3 {
+			break
+		}
+	}
+	return result
+}
+
+// truncateRunes kürzt einen String auf max Zeichen (Unicode-sicher)
+func truncateRunes(s string, max int) string {
+	r := []rune(s)
+	if len(r) <= max {
+		return s
+	}
+	return string(r[:max])
+}
+
+// countMessages zählt die Nachrichten eines Chats
+func (s *Store) countMessages(chatID int64) (int, error) {
+	var n int
+	err := s.db.QueryRow(`SELECT COUNT(*) FROM messages WHERE chat_id = ?`, chatID).Scan(&n)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	return n, err
+}
+
+// IsFirstExchange prüft ob ein Chat genau einen Austausch (User + Assistent) enthält
+func (s *Store) IsFirstExchange(chatID int64) bool {
+	n, err := s.countMessages(chatID)
+	return err == nil && n == 2
+}
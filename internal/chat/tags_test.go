@@ -0,0 +1,103 @@
+package chat
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestParseTitleSuggestion testet das Parsen der Modell-Antwort
+func TestParseTitleSuggestion(t *testing.T) {
+	tests := []struct {
+		name      string
+		raw       string
+		wantTitle string
+		wantTags  []string
+	}{
+		{"JSON", `{"title": "Mietvertrag kündigen", "tags": ["Recht", "wohnen"]}`, "Mietvertrag kündigen", []string{"recht", "wohnen"}},
+		{"Code-Fence", "```json\n{\"title\":\"Python Installation\",\"tags\":[\"#programmierung\"]}\n```", "Python Installation", []string{"programmierung"}},
+		{"Text um JSON", `Hier: {"title": "Urlaub planen.", "tags": []} fertig`, "Urlaub planen", []string{}},
+		{"Duplikate und Limit", `{"title":"X","tags":["a","A","b","c","d"]}`, "X", []string{"a", "b", "c"}},
+		{"Fallback erste Zeile", "Titel: \"Steuererklärung 2025\"\nweiterer Text", "Steuererklärung 2025", nil},
+		{"Leer", "", "", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			title, tags := ParseTitleSuggestion(tt.raw)
+			if title != tt.wantTitle {
+				t.Errorf("Titel = %q, erwartet %q", title, tt.wantTitle)
+			}
+			if !reflect.DeepEqual(tags, tt.wantTags) {
+				t.Errorf("Tags = %#v, erwartet %#v", tags, tt.wantTags)
+			}
+		})
+	}
+}
+
+// TestSetGeneratedTitle_RespectsManualRename testet dass Umbenennungen nicht überschrieben werden
+func TestSetGeneratedTitle_RespectsManualRename(t *testing.T) {
+	store, _ := newTestStore(t)
+
+	placeholder, _ := store.CreateChatWithPlaceholderTitle("Wie kündige ich meinen Mietver...", "model")
+	changed, err := store.SetGeneratedTitle(placeholder.ID, "Mietvertrag kündigen")
+	if err != nil || !changed {
+		t.Fatalf("Platzhalter-Titel sollte ersetzt werden: changed=%v err=%v", changed, err)
+	}
+
+	renamed, _ := store.CreateChatWithPlaceholderTitle("Platzhalter", "model")
+	store.RenameChat(renamed.ID, "Mein Titel")
+	changed, _ = store.SetGeneratedTitle(renamed.ID, "Generiert")
+	if changed {
+		t.Error("Manuell umbenannter Chat darf nicht überschrieben werden")
+	}
+
+	manual, _ := store.CreateChat("Neuer Chat", "model")
+	if changed, _ := store.SetGeneratedTitle(manual.ID, "Generiert"); changed {
+		t.Error("Explizit erstellter Titel darf nicht überschrieben werden")
+	}
+}
+
+// TestGetChatsFiltered testet die Tag-Filter der Chat-Liste
+func TestGetChatsFiltered(t *testing.T) {
+	store, _ := newTestStore(t)
+
+	a, _ := store.CreateChat("A", "m")
+	b, _ := store.CreateChat("B", "m")
+	store.CreateChat("C", "m")
+
+	store.AddChatTags(a.ID, []string{"recht", "wohnen"}, TagSourceAuto)
+	store.SetChatTags(b.ID, []string{"Recht"})
+
+	ids := func(chats []Chat) []int64 {
+		var out []int64
+		for _, c := range chats {
+			out = append(out, c.ID)
+		}
+		return out
+	}
+
+	all, _ := store.GetChatsFiltered(ChatFilter{})
+	if len(all) != 3 {
+		t.Errorf("Ohne Filter: %d Chats, erwartet 3", len(all))
+	}
+
+	anyMatch, _ := store.GetChatsFiltered(ChatFilter{Tags: []string{"recht", "wohnen"}})
+	if len(anyMatch) != 2 {
+		t.Errorf("Any-Filter: %v, erwartet Chats A und B", ids(anyMatch))
+	}
+
+	allMatch, _ := store.GetChatsFiltered(ChatFilter{Tags: []string{"recht", "wohnen"}, MatchAll: true})
+	if len(allMatch) != 1 || allMatch[0].ID != a.ID {
+		t.Errorf("All-Filter: %v, erwartet nur Chat A", ids(allMatch))
+	}
+	if len(allMatch) == 1 && len(allMatch[0].Tags) != 2 {
+		t.Errorf("Chat A sollte 2 Tags haben, hat %v", allMatch[0].Tags)
+	}
+
+	// Tag entfernen - unbenutzte Tags verschwinden aus der Liste
+	store.RemoveChatTag(a.ID, "wohnen")
+	tags, _ := store.ListTags()
+	if len(tags) != 1 || tags[0].Name != "recht" || tags[0].Count != 2 {
+		t.Errorf("ListTags = %+v, erwartet nur 'recht' mit 2 Chats", tags)
+	}
+}
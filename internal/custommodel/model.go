@@ -68,6 +68,8 @@ type GgufModelConfig struct {
 	GpuLayers     int       `json:"gpuLayers"`     // Number of GPU layers (-1 = all)
 	CreatedAt     time.Time `json:"createdAt"`
 	UpdatedAt     time.Time `json:"updatedAt"`
+
+	LoraAdapters []LoraSelection `json:"loraAdapters"` // Selected LoRA adapters (never null)
 }
 
 // GgufConfigCreateRequest für das Erstellen einer GGUF-Konfiguration
@@ -83,6 +85,8 @@ type GgufConfigCreateRequest struct {
 	MaxTokens     int     `json:"maxTokens"`
 	ContextSize   int     `json:"contextSize"`
 	GpuLayers     int     `json:"gpuLayers"`
+
+	LoraAdapters []LoraSelection `json:"loraAdapters,omitempty"`
 }
 
 // GgufConfigUpdateRequest für das Aktualisieren einer GGUF-Konfiguration
@@ -97,4 +101,52 @@ type GgufConfigUpdateRequest struct {
 	MaxTokens     *int     `json:"maxTokens,omitempty"`
 	ContextSize   *int     `json:"contextSize,omitempty"`
 	GpuLayers     *int     `json:"gpuLayers,omitempty"`
+
+	LoraAdapters *[]LoraSelection `json:"loraAdapters,omitempty"` // nil = unchanged, [] = none
+}
+
+// ========== LoRA Adapter ==========
+
+// LoraAdapter repräsentiert einen registrierten LoRA-Adapter (GGUF) für llama-server
+type LoraAdapter struct {
+	ID           int64     `json:"id"`
+	Name         string    `json:"name"`         // Display name
+	FilePath     string    `json:"filePath"`     // Absolute path to the adapter GGUF
+	BaseModel    string    `json:"baseModel"`    // Compatible base GGUF (filename or path)
+	Architecture string    `json:"architecture"` // general.architecture of the adapter
+	Scale        float64   `json:"scale"`        // Default scale (1.0 = full strength)
+	Description  string    `json:"description"`  // Short description
+	CreatedAt    time.Time `json:"createdAt"`
+	UpdatedAt    time.Time `json:"updatedAt"`
+}
+
+// LoraSelection wählt einen registrierten Adapter für eine GGUF-Konfiguration aus
+type LoraSelection struct {
+	AdapterID int64   `json:"adapterId"`
+	Scale     float64 `json:"scale"` // 0 = default scale of the adapter
+}
+
+// ResolvedLora ist ein ausgewählter Adapter mit Dateipfad und effektiver Stärke
+type ResolvedLora struct {
+	AdapterID int64   `json:"adapterId"`
+	Name      string  `json:"name"`
+	Path      string  `json:"path"`
+	Scale     float64 `json:"scale"`
+}
+
+// LoraAdapterCreateRequest für das Registrieren eines LoRA-Adapters
+type LoraAdapterCreateRequest struct {
+	Name        string  `json:"name"`
+	FilePath    string  `json:"filePath"`
+	BaseModel   string  `json:"baseModel"`
+	Scale       float64 `json:"scale"`
+	Description string  `json:"description,omitempty"`
+}
+
+// LoraAdapterUpdateRequest für das Aktualisieren eines LoRA-Adapters
+type LoraAdapterUpdateRequest struct {
+	Name        *string  `json:"name,omitempty"`
+	BaseModel   *string  `json:"baseModel,omitempty"`
+	Scale       *float64 `json:"scale,omitempty"`
+	Description *string  `json:"description,omitempty"`
 }
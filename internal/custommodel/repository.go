@@ -2,6 +2,7 @@ package custommodel
 
 import (
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"path/filepath"
 	"time"
@@ -81,6 +82,19 @@ func (r *Repository) createSchema() error {
 	);
 
 	CREATE INDEX IF NOT EXISTS idx_gguf_configs_name ON gguf_model_configs(name);
+
+	-- LoRA-Adapter (für llama-server --lora)
+	CREATE TABLE IF NOT EXISTS lora_adapters (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name TEXT UNIQUE NOT NULL,
+		file_path TEXT UNIQUE NOT NULL,
+		base_model TEXT,
+		architecture TEXT,
+		scale REAL DEFAULT 1.0,
+		description TEXT,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
 	`
 
 	_, err := r.db.Exec(schema)
@@ -88,9 +102,23 @@ func (r *Repository) createSchema() error {
 		return fmt.Errorf("Custom-Models-Schema erstellen: %w", err)
 	}
 
+	r.migrate()
 	return nil
 }
 
+// migrate fügt fehlende Spalten älterer Datenbanken hinzu
+func (r *Repository) migrate() {
+	// lora_adapters Spalte für gguf_model_configs (Auswahl als JSON)
+	var count int
+	r.db.QueryRow(`
+		SELECT COUNT(*) FROM pragma_table_info('gguf_model_configs') WHERE name='lora_adapters'
+	`).Scan(&count)
+
+	if count == 0 {
+		r.db.Exec(`ALTER TABLE gguf_model_configs ADD COLUMN lora_adapters TEXT DEFAULT '[]'`)
+	}
+}
+
 // Close schließt die Datenbankverbindung
 func (r *Repository) Close() error {
 	return r.db.Close()
@@ -280,11 +308,11 @@ func (r *Repository) CreateGgufConfig(config *GgufModelConfig) error {
 	result, err := r.db.Exec(`
 		INSERT INTO gguf_model_configs (
 			name, base_model, description, system_prompt, temperature, top_p, top_k,
-			repeat_penalty, max_tokens, context_size, gpu_layers, created_at, updated_at
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			repeat_penalty, max_tokens, context_size, gpu_layers, lora_adapters, created_at, updated_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`, config.Name, config.BaseModel, config.Description, config.SystemPrompt,
 		config.Temperature, config.TopP, config.TopK, config.RepeatPenalty,
-		config.MaxTokens, config.ContextSize, config.GpuLayers, now, now)
+		config.MaxTokens, config.ContextSize, config.GpuLayers, marshalLoras(config.LoraAdapters), now, now)
 
 	if err != nil {
 		return fmt.Errorf("GGUF-Config erstellen: %w", err)
@@ -302,15 +330,16 @@ func (r *Repository) CreateGgufConfig(config *GgufModelConfig) error {
 // GetGgufConfigByID holt eine GGUF-Konfiguration nach ID
 func (r *Repository) GetGgufConfigByID(id int64) (*GgufModelConfig, error) {
 	config := &GgufModelConfig{}
+	var lorasJSON string
 
 	err := r.db.QueryRow(`
 		SELECT id, name, base_model, description, system_prompt, temperature, top_p, top_k,
-			repeat_penalty, max_tokens, context_size, gpu_layers, created_at, updated_at
+			repeat_penalty, max_tokens, context_size, gpu_layers, COALESCE(lora_adapters, '[]'), created_at, updated_at
 		FROM gguf_model_configs WHERE id = ?
 	`, id).Scan(&config.ID, &config.Name, &config.BaseModel, &config.Description,
 		&config.SystemPrompt, &config.Temperature, &config.TopP, &config.TopK,
 		&config.RepeatPenalty, &config.MaxTokens, &config.ContextSize, &config.GpuLayers,
-		&config.CreatedAt, &config.UpdatedAt)
+		&lorasJSON, &config.CreatedAt, &config.UpdatedAt)
 
 	if err == sql.ErrNoRows {
 		return nil, nil
@@ -318,6 +347,7 @@ func (r *Repository) GetGgufConfigByID(id int64) (*GgufModelConfig, error) {
 	if err != nil {
 		return nil, err
 	}
+	config.LoraAdapters = unmarshalLoras(lorasJSON)
 
 	return config, nil
 }
@@ -325,15 +355,16 @@ func (r *Repository) GetGgufConfigByID(id int64) (*GgufModelConfig, error) {
 // GetGgufConfigByName holt eine GGUF-Konfiguration nach Name
 func (r *Repository) GetGgufConfigByName(name string) (*GgufModelConfig, error) {
 	config := &GgufModelConfig{}
+	var lorasJSON string
 
 	err := r.db.QueryRow(`
 		SELECT id, name, base_model, description, system_prompt, temperature, top_p, top_k,
-			repeat_penalty, max_tokens, context_size, gpu_layers, created_at, updated_at
+			repeat_penalty, max_tokens, context_size, gpu_layers, COALESCE(lora_adapters, '[]'), created_at, updated_at
 		FROM gguf_model_configs WHERE name = ?
 	`, name).Scan(&config.ID, &config.Name, &config.BaseModel, &config.Description,
 		&config.SystemPrompt, &config.Temperature, &config.TopP, &config.TopK,
 		&config.RepeatPenalty, &config.MaxTokens, &config.ContextSize, &config.GpuLayers,
-		&config.CreatedAt, &config.UpdatedAt)
+		&lorasJSON, &config.CreatedAt, &config.UpdatedAt)
 
 	if err == sql.ErrNoRows {
 		return nil, nil
@@ -341,6 +372,7 @@ func (r *Repository) GetGgufConfigByName(name string) (*GgufModelConfig, error)
 	if err != nil {
 		return nil, err
 	}
+	config.LoraAdapters = unmarshalLoras(lorasJSON)
 
 	return config, nil
 }
@@ -349,7 +381,7 @@ func (r *Repository) GetGgufConfigByName(name string) (*GgufModelConfig, error)
 func (r *Repository) GetAllGgufConfigs() ([]GgufModelConfig, error) {
 	rows, err := r.db.Query(`
 		SELECT id, name, base_model, description, system_prompt, temperature, top_p, top_k,
-			repeat_penalty, max_tokens, context_size, gpu_layers, created_at, updated_at
+			repeat_penalty, max_tokens, context_size, gpu_layers, COALESCE(lora_adapters, '[]'), created_at, updated_at
 		FROM gguf_model_configs
 		ORDER BY created_at DESC
 	`)
@@ -361,13 +393,15 @@ func (r *Repository) GetAllGgufConfigs() ([]GgufModelConfig, error) {
 	var configs []GgufModelConfig
 	for rows.Next() {
 		var c GgufModelConfig
+		var lorasJSON string
 		err := rows.Scan(&c.ID, &c.Name, &c.BaseModel, &c.Description,
 			&c.SystemPrompt, &c.Temperature, &c.TopP, &c.TopK,
 			&c.RepeatPenalty, &c.MaxTokens, &c.ContextSize, &c.GpuLayers,
-			&c.CreatedAt, &c.UpdatedAt)
+			&lorasJSON, &c.CreatedAt, &c.UpdatedAt)
 		if err != nil {
 			return nil, err
 		}
+		c.LoraAdapters = unmarshalLoras(lorasJSON)
 		configs = append(configs, c)
 	}
 
@@ -386,11 +420,12 @@ func (r *Repository) UpdateGgufConfig(config *GgufModelConfig) error {
 		UPDATE gguf_model_configs SET
 			name = ?, base_model = ?, description = ?, system_prompt = ?,
 			temperature = ?, top_p = ?, top_k = ?, repeat_penalty = ?,
-			max_tokens = ?, context_size = ?, gpu_layers = ?, updated_at = ?
+			max_tokens = ?, context_size = ?, gpu_layers = ?, lora_adapters = ?, updated_at = ?
 		WHERE id = ?
 	`, config.Name, config.BaseModel, config.Description, config.SystemPrompt,
 		config.Temperature, config.TopP, config.TopK, config.RepeatPenalty,
-		config.MaxTokens, config.ContextSize, config.GpuLayers, config.UpdatedAt, config.ID)
+		config.MaxTokens, config.ContextSize, config.GpuLayers, marshalLoras(config.LoraAdapters),
+		config.UpdatedAt, config.ID)
 
 	return err
 }
@@ -400,3 +435,125 @@ func (r *Repository) DeleteGgufConfig(id int64) error {
 	_, err := r.db.Exec("DELETE FROM gguf_model_configs WHERE id = ?", id)
 	return err
 }
+
+// marshalLoras serialisiert eine LoRA-Auswahl als JSON
+func marshalLoras(loras []LoraSelection) string {
+	if len(loras) == 0 {
+		return "[]"
+	}
+	data, err := json.Marshal(loras)
+	if err != nil {
+		return "[]"
+	}
+	return string(data)
+}
+
+// unmarshalLoras deserialisiert eine LoRA-Auswahl (nie null)
+func unmarshalLoras(data string) []LoraSelection {
+	loras := make([]LoraSelection, 0)
+	if data != "" && data != "[]" {
+		json.Unmarshal([]byte(data), &loras)
+	}
+	return loras
+}
+
+// ========== LoRA Adapter Methods ==========
+
+const loraAdapterColumns = `id, name, file_path, COALESCE(base_model, ''), COALESCE(architecture, ''),
+	COALESCE(scale, 1.0), COALESCE(description, ''), created_at, updated_at`
+
+// scanLoraAdapter liest eine Zeile der lora_adapters Tabelle
+func scanLoraAdapter(row interface{ Scan(...interface{}) error }) (*LoraAdapter, error) {
+	a := &LoraAdapter{}
+	err := row.Scan(&a.ID, &a.Name, &a.FilePath, &a.BaseModel, &a.Architecture,
+		&a.Scale, &a.Description, &a.CreatedAt, &a.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// CreateLoraAdapter registriert einen neuen LoRA-Adapter
+func (r *Repository) CreateLoraAdapter(adapter *LoraAdapter) error {
+	now := time.Now()
+	adapter.CreatedAt = now
+	adapter.UpdatedAt = now
+
+	result, err := r.db.Exec(`
+		INSERT INTO lora_adapters (
+			name, file_path, base_model, architecture, scale, description, created_at, updated_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, adapter.Name, adapter.FilePath, adapter.BaseModel, adapter.Architecture,
+		adapter.Scale, adapter.Description, now, now)
+
+	if err != nil {
+		return fmt.Errorf("LoRA-Adapter erstellen: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	adapter.ID = id
+	return nil
+}
+
+// GetLoraAdapterByID holt einen LoRA-Adapter nach ID
+func (r *Repository) GetLoraAdapterByID(id int64) (*LoraAdapter, error) {
+	adapter, err := scanLoraAdapter(r.db.QueryRow(
+		"SELECT "+loraAdapterColumns+" FROM lora_adapters WHERE id = ?", id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return adapter, err
+}
+
+// GetLoraAdapterByName holt einen LoRA-Adapter nach Name
+func (r *Repository) GetLoraAdapterByName(name string) (*LoraAdapter, error) {
+	adapter, err := scanLoraAdapter(r.db.QueryRow(
+		"SELECT "+loraAdapterColumns+" FROM lora_adapters WHERE name = ?", name))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return adapter, err
+}
+
+// GetAllLoraAdapters holt alle registrierten LoRA-Adapter
+func (r *Repository) GetAllLoraAdapters() ([]LoraAdapter, error) {
+	rows, err := r.db.Query("SELECT " + loraAdapterColumns + " FROM lora_adapters ORDER BY name ASC")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	adapters := make([]LoraAdapter, 0)
+	for rows.Next() {
+		a, err := scanLoraAdapter(rows)
+		if err != nil {
+			return nil, err
+		}
+		adapters = append(adapters, *a)
+	}
+	return adapters, rows.Err()
+}
+
+// UpdateLoraAdapter aktualisiert einen LoRA-Adapter
+func (r *Repository) UpdateLoraAdapter(adapter *LoraAdapter) error {
+	adapter.UpdatedAt = time.Now()
+
+	_, err := r.db.Exec(`
+		UPDATE lora_adapters SET
+			name = ?, base_model = ?, architecture = ?, scale = ?, description = ?, updated_at = ?
+		WHERE id = ?
+	`, adapter.Name, adapter.BaseModel, adapter.Architecture, adapter.Scale,
+		adapter.Description, adapter.UpdatedAt, adapter.ID)
+
+	return err
+}
+
+// DeleteLoraAdapter löscht einen LoRA-Adapter
+func (r *Repository) DeleteLoraAdapter(id int64) error {
+	_, err := r.db.Exec("DELETE FROM lora_adapters WHERE id = ?", id)
+	return err
+}
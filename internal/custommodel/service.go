@@ -3,12 +3,18 @@ package custommodel
 import (
 	"fmt"
 	"log"
+	"math"
+	"os"
+	"path/filepath"
 	"strings"
+
+	"fleet-navigator/internal/gguf"
 )
 
 // Service verwaltet Custom-Model-Operationen
 type Service struct {
-	repo *Repository
+	repo      *Repository
+	modelPath func(name string) string // Löst GGUF-Dateinamen zu Pfaden auf (für LoRA-Prüfung)
 }
 
 // NewService erstellt einen neuen Service
@@ -16,6 +22,12 @@ func NewService(repo *Repository) *Service {
 	return &Service{repo: repo}
 }
 
+// SetModelPathResolver setzt die Auflösung von GGUF-Modellnamen zu Dateipfaden.
+// Ohne Resolver werden Basismodelle nur als absolute Pfade erkannt.
+func (s *Service) SetModelPathResolver(resolve func(name string) string) {
+	s.modelPath = resolve
+}
+
 // GetAll gibt alle Custom Models zurück
 func (s *Service) GetAll() ([]CustomModel, error) {
 	return s.repo.GetAll()
@@ -269,6 +281,11 @@ func (s *Service) GetGgufConfigByID(id int64) (*GgufModelConfig, error) {
 	return s.repo.GetGgufConfigByID(id)
 }
 
+// GetGgufConfigByName gibt eine GGUF-Konfiguration nach Name zurück
+func (s *Service) GetGgufConfigByName(name string) (*GgufModelConfig, error) {
+	return s.repo.GetGgufConfigByName(name)
+}
+
 // CreateGgufConfig erstellt eine neue GGUF-Konfiguration
 func (s *Service) CreateGgufConfig(req GgufConfigCreateRequest) (*GgufModelConfig, error) {
 	// Validierung
@@ -311,6 +328,10 @@ func (s *Service) CreateGgufConfig(req GgufConfigCreateRequest) (*GgufModelConfi
 		req.GpuLayers = -1 // Alle Layers auf GPU
 	}
 
+	if err := s.validateLoraSelection(req.BaseModel, req.LoraAdapters); err != nil {
+		return nil, err
+	}
+
 	config := &GgufModelConfig{
 		Name:          req.Name,
 		BaseModel:     req.BaseModel,
@@ -323,6 +344,7 @@ func (s *Service) CreateGgufConfig(req GgufConfigCreateRequest) (*GgufModelConfi
 		MaxTokens:     req.MaxTokens,
 		ContextSize:   req.ContextSize,
 		GpuLayers:     req.GpuLayers,
+		LoraAdapters:  req.LoraAdapters,
 	}
 
 	if err := s.repo.CreateGgufConfig(config); err != nil {
@@ -374,6 +396,12 @@ func (s *Service) UpdateGgufConfig(id int64, req GgufConfigUpdateRequest) (*Gguf
 	if req.GpuLayers != nil {
 		config.GpuLayers = *req.GpuLayers
 	}
+	if req.LoraAdapters != nil {
+		if err := s.validateLoraSelection(config.BaseModel, *req.LoraAdapters); err != nil {
+			return nil, err
+		}
+		config.LoraAdapters = *req.LoraAdapters
+	}
 
 	if err := s.repo.UpdateGgufConfig(config); err != nil {
 		return nil, err
@@ -400,3 +428,229 @@ func (s *Service) DeleteGgufConfig(id int64) error {
 	log.Printf("GGUF-Konfiguration gelöscht: %s (ID: %d)", config.Name, id)
 	return nil
 }
+
+// ========== LoRA Adapter Methods ==========
+
+// maxLoraScale begrenzt die Adapter-Stärke (llama.cpp akzeptiert beliebige Werte,
+// jenseits davon ist die Ausgabe aber praktisch unbrauchbar)
+const maxLoraScale = 4.0
+
+// GetAllLoraAdapters gibt alle registrierten LoRA-Adapter zurück
+func (s *Service) GetAllLoraAdapters() ([]LoraAdapter, error) {
+	return s.repo.GetAllLoraAdapters()
+}
+
+// GetLoraAdapterByID gibt einen LoRA-Adapter nach ID zurück
+func (s *Service) GetLoraAdapterByID(id int64) (*LoraAdapter, error) {
+	return s.repo.GetLoraAdapterByID(id)
+}
+
+// RegisterLoraAdapter registriert einen LoRA-Adapter nach Prüfung der GGUF-Datei
+func (s *Service) RegisterLoraAdapter(req LoraAdapterCreateRequest) (*LoraAdapter, error) {
+	if req.Name == "" {
+		return nil, fmt.Errorf("Name ist erforderlich")
+	}
+	if req.FilePath == "" {
+		return nil, fmt.Errorf("FilePath ist erforderlich")
+	}
+	if !filepath.IsAbs(req.FilePath) {
+		return nil, fmt.Errorf("FilePath muss absolut sein: %s", req.FilePath)
+	}
+	if req.Scale == 0 {
+		req.Scale = 1.0
+	}
+	if math.Abs(req.Scale) > maxLoraScale {
+		return nil, fmt.Errorf("Scale muss zwischen -%.0f und %.0f liegen", maxLoraScale, maxLoraScale)
+	}
+
+	existing, err := s.repo.GetLoraAdapterByName(req.Name)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, fmt.Errorf("LoRA-Adapter mit Name '%s' existiert bereits", req.Name)
+	}
+
+	info, err := s.checkLoraAdapter(req.FilePath, req.BaseModel)
+	if err != nil {
+		return nil, err
+	}
+
+	adapter := &LoraAdapter{
+		Name:         req.Name,
+		FilePath:     req.FilePath,
+		BaseModel:    req.BaseModel,
+		Architecture: info.Architecture,
+		Scale:        req.Scale,
+		Description:  req.Description,
+	}
+	if err := s.repo.CreateLoraAdapter(adapter); err != nil {
+		return nil, err
+	}
+
+	log.Printf("LoRA-Adapter registriert: %s (ID: %d, %s)", adapter.Name, adapter.ID, adapter.Architecture)
+	return adapter, nil
+}
+
+// UpdateLoraAdapter aktualisiert einen LoRA-Adapter
+func (s *Service) UpdateLoraAdapter(id int64, req LoraAdapterUpdateRequest) (*LoraAdapter, error) {
+	adapter, err := s.repo.GetLoraAdapterByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if adapter == nil {
+		return nil, fmt.Errorf("LoRA-Adapter nicht gefunden: %d", id)
+	}
+
+	if req.Name != nil && *req.Name != "" {
+		adapter.Name = *req.Name
+	}
+	if req.Description != nil {
+		adapter.Description = *req.Description
+	}
+	if req.Scale != nil {
+		if *req.Scale == 0 || math.Abs(*req.Scale) > maxLoraScale {
+			return nil, fmt.Errorf("Scale muss zwischen -%.0f und %.0f liegen (ohne 0)", maxLoraScale, maxLoraScale)
+		}
+		adapter.Scale = *req.Scale
+	}
+	if req.BaseModel != nil && *req.BaseModel != adapter.BaseModel {
+		if _, err := s.checkLoraAdapter(adapter.FilePath, *req.BaseModel); err != nil {
+			return nil, err
+		}
+		adapter.BaseModel = *req.BaseModel
+	}
+
+	if err := s.repo.UpdateLoraAdapter(adapter); err != nil {
+		return nil, err
+	}
+
+	log.Printf("LoRA-Adapter aktualisiert: %s (ID: %d)", adapter.Name, id)
+	return adapter, nil
+}
+
+// DeleteLoraAdapter löscht einen LoRA-Adapter und entfernt ihn aus allen GGUF-Konfigurationen
+func (s *Service) DeleteLoraAdapter(id int64) error {
+	adapter, err := s.repo.GetLoraAdapterByID(id)
+	if err != nil {
+		return err
+	}
+	if adapter == nil {
+		return fmt.Errorf("LoRA-Adapter nicht gefunden: %d", id)
+	}
+
+	configs, err := s.repo.GetAllGgufConfigs()
+	if err != nil {
+		return err
+	}
+	for i := range configs {
+		kept := configs[i].LoraAdapters[:0]
+		for _, sel := range configs[i].LoraAdapters {
+			if sel.AdapterID != id {
+				kept = append(kept, sel)
+			}
+		}
+		if len(kept) != len(configs[i].LoraAdapters) {
+			configs[i].LoraAdapters = kept
+			if err := s.repo.UpdateGgufConfig(&configs[i]); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := s.repo.DeleteLoraAdapter(id); err != nil {
+		return err
+	}
+
+	log.Printf("LoRA-Adapter gelöscht: %s (ID: %d)", adapter.Name, id)
+	return nil
+}
+
+// ResolveLoras löst eine Adapter-Auswahl zu Dateipfaden und effektiven Stärken auf.
+// Bei gesetztem baseModel werden inkompatible Adapter abgelehnt.
+func (s *Service) ResolveLoras(baseModel string, selections []LoraSelection) ([]ResolvedLora, error) {
+	resolved := make([]ResolvedLora, 0, len(selections))
+	for _, sel := range selections {
+		adapter, err := s.repo.GetLoraAdapterByID(sel.AdapterID)
+		if err != nil {
+			return nil, err
+		}
+		if adapter == nil {
+			return nil, fmt.Errorf("LoRA-Adapter nicht gefunden: %d", sel.AdapterID)
+		}
+		if baseModel != "" {
+			if _, err := s.checkLoraAdapter(adapter.FilePath, baseModel); err != nil {
+				return nil, fmt.Errorf("LoRA-Adapter '%s': %w", adapter.Name, err)
+			}
+		}
+
+		scale := sel.Scale
+		if scale == 0 {
+			scale = adapter.Scale
+		}
+		resolved = append(resolved, ResolvedLora{
+			AdapterID: adapter.ID,
+			Name:      adapter.Name,
+			Path:      adapter.FilePath,
+			Scale:     scale,
+		})
+	}
+	return resolved, nil
+}
+
+// validateLoraSelection prüft eine Auswahl gegen das Basismodell einer GGUF-Konfiguration
+func (s *Service) validateLoraSelection(baseModel string, selections []LoraSelection) error {
+	seen := make(map[int64]bool)
+	for _, sel := range selections {
+		if seen[sel.AdapterID] {
+			return fmt.Errorf("LoRA-Adapter %d mehrfach ausgewählt", sel.AdapterID)
+		}
+		seen[sel.AdapterID] = true
+		if math.Abs(sel.Scale) > maxLoraScale {
+			return fmt.Errorf("Scale muss zwischen -%.0f und %.0f liegen", maxLoraScale, maxLoraScale)
+		}
+	}
+	_, err := s.ResolveLoras(baseModel, selections)
+	return err
+}
+
+// checkLoraAdapter liest die Adapter-Datei und prüft sie gegen das Basismodell (falls auflösbar)
+func (s *Service) checkLoraAdapter(adapterPath, baseModel string) (*gguf.ModelInfo, error) {
+	info, err := gguf.ReadInfo(adapterPath)
+	if err != nil {
+		return nil, fmt.Errorf("LoRA-Adapter lesen fehlgeschlagen: %w", err)
+	}
+	if !info.IsLoraAdapter() {
+		return nil, fmt.Errorf("%s ist kein LoRA-Adapter (general.type=%q)", filepath.Base(adapterPath), info.Type)
+	}
+	if baseModel == "" {
+		return info, nil
+	}
+
+	basePath := s.resolveModelPath(baseModel)
+	if basePath == "" {
+		return nil, fmt.Errorf("Basismodell nicht gefunden: %s", baseModel)
+	}
+	base, err := gguf.ReadInfo(basePath)
+	if err != nil {
+		return nil, fmt.Errorf("Basismodell lesen fehlgeschlagen: %w", err)
+	}
+	if err := gguf.CheckLoraCompatibility(info, base); err != nil {
+		return nil, fmt.Errorf("LoRA-Adapter inkompatibel mit %s: %w", filepath.Base(basePath), err)
+	}
+	return info, nil
+}
+
+// resolveModelPath löst einen Modellnamen zum Dateipfad auf ("" = nicht gefunden)
+func (s *Service) resolveModelPath(name string) string {
+	if filepath.IsAbs(name) {
+		if _, err := os.Stat(name); err == nil {
+			return name
+		}
+		return ""
+	}
+	if s.modelPath != nil {
+		return s.modelPath(name)
+	}
+	return ""
+}
@@ -37,10 +37,19 @@ type Expert struct {
 	// Anti-Halluzinations-Prompt (optional, überschreibt Default wenn gesetzt)
 	AntiHallucinationPrompt string `json:"antiHallucinationPrompt"` // Leer = Default verwenden
 
+	// LoRA-Adapter für llama-server (nie null, immer Array)
+	LoraAdapters []LoraSelection `json:"loraAdapters"`
+
 	// Beziehung zu Modi (nie null, immer Array - wichtig fürs Frontend)
 	Modes []ExpertMode `json:"modes"`
 }
 
+// LoraSelection wählt einen registrierten LoRA-Adapter (custommodel) mit Stärke aus
+type LoraSelection struct {
+	AdapterID int64   `json:"adapterId"`
+	Scale     float64 `json:"scale"` // 0 = Standard-Stärke des Adapters
+}
+
 // ExpertMode repräsentiert einen Blickwinkel/Modus eines Experten
 // Modi können Fachgebiete sein (z.B. Strafrecht, Verkehrsrecht) mit Keywords für automatische Erkennung
 type ExpertMode struct {
@@ -76,6 +85,8 @@ type CreateExpertRequest struct {
 	WebSearchShowLinks bool `json:"webSearchShowLinks"`
 	// Anti-Halluzinations-Prompt (leer = Default)
 	AntiHallucinationPrompt string `json:"antiHallucinationPrompt"`
+	// LoRA-Adapter (optional)
+	LoraAdapters []LoraSelection `json:"loraAdapters"`
 }
 
 // UpdateExpertRequest für API
@@ -101,6 +112,8 @@ type UpdateExpertRequest struct {
 	WebSearchShowLinks *bool `json:"webSearchShowLinks,omitempty"`
 	// Anti-Halluzinations-Prompt (leer = Default, nil = nicht ändern)
 	AntiHallucinationPrompt *string `json:"antiHallucinationPrompt,omitempty"`
+	// LoRA-Adapter (nil = nicht ändern, leeres Array = keine Adapter)
+	LoraAdapters *[]LoraSelection `json:"loraAdapters,omitempty"`
 }
 
 // CreateModeRequest für API
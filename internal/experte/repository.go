@@ -153,6 +153,36 @@ func (r *Repository) migrateKeywords() {
 	if count == 0 {
 		r.db.Exec(`ALTER TABLE experts ADD COLUMN anti_hallucination_prompt TEXT DEFAULT ''`)
 	}
+
+	// lora_adapters Spalte für experts (LoRA-Auswahl als JSON)
+	r.db.QueryRow(`
+		SELECT COUNT(*) FROM pragma_table_info('experts') WHERE name='lora_adapters'
+	`).Scan(&count)
+
+	if count == 0 {
+		r.db.Exec(`ALTER TABLE experts ADD COLUMN lora_adapters TEXT DEFAULT '[]'`)
+	}
+}
+
+// marshalLoras serialisiert die LoRA-Auswahl als JSON
+func marshalLoras(loras []LoraSelection) string {
+	if len(loras) == 0 {
+		return "[]"
+	}
+	data, err := json.Marshal(loras)
+	if err != nil {
+		return "[]"
+	}
+	return string(data)
+}
+
+// unmarshalLoras deserialisiert die LoRA-Auswahl (nie null)
+func unmarshalLoras(data string) []LoraSelection {
+	loras := make([]LoraSelection, 0)
+	if data != "" && data != "[]" {
+		json.Unmarshal([]byte(data), &loras)
+	}
+	return loras
 }
 
 // Close schließt die Datenbankverbindung
@@ -185,12 +215,12 @@ func (r *Repository) CreateExpert(expert *Expert) error {
 	result, err := r.db.Exec(`
 		INSERT INTO experts (name, role, base_prompt, personality_prompt, base_model, avatar, description, voice, is_active, auto_mode_switch, sort_order,
 			default_num_ctx, default_max_tokens, default_temperature, default_top_p,
-			auto_web_search, web_search_show_links, anti_hallucination_prompt, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			auto_web_search, web_search_show_links, anti_hallucination_prompt, lora_adapters, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`, expert.Name, expert.Role, expert.BasePrompt, expert.PersonalityPrompt, expert.BaseModel, expert.Avatar, expert.Description, expert.Voice,
 		expert.IsActive, expert.AutoModeSwitch, expert.SortOrder,
 		expert.DefaultNumCtx, expert.DefaultMaxTokens, expert.DefaultTemperature, expert.DefaultTopP,
-		expert.AutoWebSearch, expert.WebSearchShowLinks, expert.AntiHallucinationPrompt, now, now)
+		expert.AutoWebSearch, expert.WebSearchShowLinks, expert.AntiHallucinationPrompt, marshalLoras(expert.LoraAdapters), now, now)
 
 	if err != nil {
 		return fmt.Errorf("Experte erstellen fehlgeschlagen: %w", err)
@@ -204,6 +234,9 @@ func (r *Repository) CreateExpert(expert *Expert) error {
 	expert.ID = id
 	expert.CreatedAt = now
 	expert.UpdatedAt = now
+	if expert.LoraAdapters == nil {
+		expert.LoraAdapters = make([]LoraSelection, 0)
+	}
 
 	// Modi erstellen falls vorhanden
 	for i := range expert.Modes {
@@ -219,17 +252,18 @@ func (r *Repository) CreateExpert(expert *Expert) error {
 // GetExpert holt einen Experten mit Modi
 func (r *Repository) GetExpert(id int64) (*Expert, error) {
 	expert := &Expert{}
+	var lorasJSON string
 
 	err := r.db.QueryRow(`
 		SELECT id, name, role, base_prompt, COALESCE(personality_prompt, ''), base_model, avatar, description, voice, is_active, auto_mode_switch, sort_order,
 			COALESCE(default_num_ctx, 16384), COALESCE(default_max_tokens, 4096), COALESCE(default_temperature, 0.7), COALESCE(default_top_p, 0.9),
-			COALESCE(auto_web_search, 0), COALESCE(web_search_show_links, 0), COALESCE(anti_hallucination_prompt, ''),
+			COALESCE(auto_web_search, 0), COALESCE(web_search_show_links, 0), COALESCE(anti_hallucination_prompt, ''), COALESCE(lora_adapters, '[]'),
 			created_at, updated_at
 		FROM experts WHERE id = ?
 	`, id).Scan(&expert.ID, &expert.Name, &expert.Role, &expert.BasePrompt, &expert.PersonalityPrompt, &expert.BaseModel,
 		&expert.Avatar, &expert.Description, &expert.Voice, &expert.IsActive, &expert.AutoModeSwitch, &expert.SortOrder,
 		&expert.DefaultNumCtx, &expert.DefaultMaxTokens, &expert.DefaultTemperature, &expert.DefaultTopP,
-		&expert.AutoWebSearch, &expert.WebSearchShowLinks, &expert.AntiHallucinationPrompt,
+		&expert.AutoWebSearch, &expert.WebSearchShowLinks, &expert.AntiHallucinationPrompt, &lorasJSON,
 		&expert.CreatedAt, &expert.UpdatedAt)
 
 	if err == sql.ErrNoRows {
@@ -238,6 +272,7 @@ func (r *Repository) GetExpert(id int64) (*Expert, error) {
 	if err != nil {
 		return nil, err
 	}
+	expert.LoraAdapters = unmarshalLoras(lorasJSON)
 
 	// Modi laden
 	modes, err := r.GetModesByExpert(id)
@@ -253,7 +288,7 @@ func (r *Repository) GetExpert(id int64) (*Expert, error) {
 func (r *Repository) GetAllExperts(onlyActive bool) ([]Expert, error) {
 	query := `SELECT id, name, role, base_prompt, COALESCE(personality_prompt, ''), base_model, avatar, description, voice, is_active, auto_mode_switch, sort_order,
 		COALESCE(default_num_ctx, 16384), COALESCE(default_max_tokens, 4096), COALESCE(default_temperature, 0.7), COALESCE(default_top_p, 0.9),
-		COALESCE(auto_web_search, 0), COALESCE(web_search_show_links, 0), COALESCE(anti_hallucination_prompt, ''),
+		COALESCE(auto_web_search, 0), COALESCE(web_search_show_links, 0), COALESCE(anti_hallucination_prompt, ''), COALESCE(lora_adapters, '[]'),
 		created_at, updated_at FROM experts`
 	if onlyActive {
 		query += " WHERE is_active = 1"
@@ -269,14 +304,16 @@ func (r *Repository) GetAllExperts(onlyActive bool) ([]Expert, error) {
 	experts := make([]Expert, 0) // Immer leeres Array, nie null
 	for rows.Next() {
 		var e Expert
+		var lorasJSON string
 		err := rows.Scan(&e.ID, &e.Name, &e.Role, &e.BasePrompt, &e.PersonalityPrompt, &e.BaseModel,
 			&e.Avatar, &e.Description, &e.Voice, &e.IsActive, &e.AutoModeSwitch, &e.SortOrder,
 			&e.DefaultNumCtx, &e.DefaultMaxTokens, &e.DefaultTemperature, &e.DefaultTopP,
-			&e.AutoWebSearch, &e.WebSearchShowLinks, &e.AntiHallucinationPrompt,
+			&e.AutoWebSearch, &e.WebSearchShowLinks, &e.AntiHallucinationPrompt, &lorasJSON,
 			&e.CreatedAt, &e.UpdatedAt)
 		if err != nil {
 			return nil, err
 		}
+		e.LoraAdapters = unmarshalLoras(lorasJSON)
 		modes, err := r.GetModesByExpert(e.ID)
 		if err != nil {
 			return nil, err
@@ -368,6 +405,11 @@ func (r *Repository) UpdateExpert(id int64, req *UpdateExpertRequest) error {
 		updates = append(updates, "anti_hallucination_prompt = ?")
 		args = append(args, *req.AntiHallucinationPrompt)
 	}
+	// LoRA-Adapter
+	if req.LoraAdapters != nil {
+		updates = append(updates, "lora_adapters = ?")
+		args = append(args, marshalLoras(*req.LoraAdapters))
+	}
 
 	if len(updates) == 0 {
 		return nil // Nichts zu aktualisieren
@@ -124,6 +124,7 @@ func (s *Service) CreateExpert(req CreateExpertRequest) (*Expert, error) {
 		Description:    req.Description,
 		IsActive:       true,
 		AutoModeSwitch: req.AutoModeSwitch,
+		LoraAdapters:   req.LoraAdapters,
 	}
 
 	// Setze Defaults
@@ -0,0 +1,322 @@
+package gguf
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ModelInfo fasst die für Fleet Navigator relevanten Modell-Eigenschaften zusammen
+type ModelInfo struct {
+	Path         string `json:"path"`
+	Architecture string `json:"architecture"` // z.B. "llama", "qwen2", "gemma2"
+	Name         string `json:"name"`         // general.name
+	SizeLabel    string `json:"sizeLabel"`    // general.size_label (z.B. "7B") oder berechnet
+	Type         string `json:"type"`         // general.type ("model", "adapter", leer bei älteren Dateien)
+
+	// Adapter-Metadaten (nur bei general.type = "adapter")
+	AdapterType   string  `json:"adapterType,omitempty"`   // adapter.type (z.B. "lora")
+	LoraAlpha     float64 `json:"loraAlpha,omitempty"`     // adapter.lora.alpha
+	LoraInputSize uint64  `json:"loraInputSize,omitempty"` // Eingangsgröße von attn_q (= embedding_length des Basismodells)
+
+	ContextLength   uint64 `json:"contextLength"` // Trainierte Context-Länge
+	BlockCount      uint64 `json:"blockCount"`    // Anzahl Layer
+	EmbeddingLength uint64 `json:"embeddingLength"`
+	HeadCount       uint64 `json:"headCount"`
+	HeadCountKV     uint64 `json:"headCountKv"`
+	KeyLength       uint64 `json:"keyLength"`
+	ValueLength     uint64 `json:"valueLength"`
+	SlidingWindow   uint64 `json:"slidingWindow,omitempty"`
+
+	FileType     uint64 `json:"fileType"`
+	Quantization string `json:"quantization"` // z.B. "Q4_K_M"
+
+	ChatTemplate   string `json:"chatTemplate,omitempty"`
+	TokenizerModel string `json:"tokenizerModel,omitempty"` // z.B. "gpt2", "llama"
+	TokenizerPre   string `json:"tokenizerPre,omitempty"`   // Pre-Tokenizer (z.B. "qwen2", "llama-bpe")
+	VocabSize      uint64 `json:"vocabSize"`
+	BOSTokenID     uint64 `json:"bosTokenId"`
+	EOSTokenID     uint64 `json:"eosTokenId"`
+
+	TensorCount    int    `json:"tensorCount"`
+	ParameterCount uint64 `json:"parameterCount"`
+	TensorBytes    uint64 `json:"tensorBytes"` // Exakte Größe aller Gewichte
+	FileSize       int64  `json:"fileSize"`
+
+	// Aufteilung der Gewichte für die Offload-Planung
+	LayerBytes  []uint64 `json:"-"` // Gewichte je Layer (blk.N.*)
+	InputBytes  uint64   `json:"-"` // token_embd (bleibt bei llama.cpp im RAM)
+	OutputBytes uint64   `json:"-"` // Output-Layer und sonstige Tensoren (output.weight, output_norm, ...)
+}
+
+// Info erstellt die Modell-Zusammenfassung aus einem geparsten Header
+func (f *File) Info() *ModelInfo {
+	arch := f.String("general.architecture")
+	key := func(suffix string) string { return arch + "." + suffix }
+
+	info := &ModelInfo{
+		Architecture:    arch,
+		Name:            f.String("general.name"),
+		SizeLabel:       f.String("general.size_label"),
+		Type:            f.String("general.type"),
+		AdapterType:     f.String("adapter.type"),
+		LoraAlpha:       f.Float("adapter.lora.alpha", 0),
+		ContextLength:   f.Uint(key("context_length"), 0),
+		BlockCount:      f.Uint(key("block_count"), 0),
+		EmbeddingLength: f.Uint(key("embedding_length"), 0),
+		HeadCount:       f.Uint(key("attention.head_count"), 0),
+		HeadCountKV:     f.Uint(key("attention.head_count_kv"), 0),
+		KeyLength:       f.Uint(key("attention.key_length"), 0),
+		ValueLength:     f.Uint(key("attention.value_length"), 0),
+		SlidingWindow:   f.Uint(key("attention.sliding_window"), 0),
+		FileType:        f.Uint("general.file_type", 0),
+		ChatTemplate:    f.String("tokenizer.chat_template"),
+		TokenizerModel:  f.String("tokenizer.ggml.model"),
+		TokenizerPre:    f.String("tokenizer.ggml.pre"),
+		VocabSize:       f.ArrayLen("tokenizer.ggml.tokens"),
+		BOSTokenID:      f.Uint("tokenizer.ggml.bos_token_id", 0),
+		EOSTokenID:      f.Uint("tokenizer.ggml.eos_token_id", 0),
+		TensorCount:     len(f.Tensors),
+	}
+
+	// Ohne GQA-Angabe: so viele KV-Heads wie Query-Heads
+	if info.HeadCountKV == 0 {
+		info.HeadCountKV = info.HeadCount
+	}
+	if info.HeadCount > 0 && info.KeyLength == 0 {
+		info.KeyLength = info.EmbeddingLength / info.HeadCount
+	}
+	if info.ValueLength == 0 {
+		info.ValueLength = info.KeyLength
+	}
+
+	byType := make(map[GGMLType]uint64)
+	info.LayerBytes = make([]uint64, info.BlockCount)
+	hasOutput := false
+	for _, t := range f.Tensors {
+		info.ParameterCount += t.Elements()
+		if info.LoraInputSize == 0 && strings.HasSuffix(t.Name, ".attn_q.weight.lora_a") && len(t.Dimensions) > 0 {
+			info.LoraInputSize = t.Dimensions[0]
+		}
+		info.TensorBytes += t.Bytes()
+		byType[t.Type] += t.Bytes()
+
+		switch layer, ok := layerIndex(t.Name); {
+		case ok:
+			for uint64(len(info.LayerBytes)) <= layer {
+				info.LayerBytes = append(info.LayerBytes, 0)
+			}
+			info.LayerBytes[layer] += t.Bytes()
+		case t.Name == "token_embd.weight":
+			info.InputBytes += t.Bytes()
+		default:
+			hasOutput = hasOutput || t.Name == "output.weight"
+			info.OutputBytes += t.Bytes()
+		}
+	}
+	// Geteilte Embeddings: llama.cpp legt token_embd zusätzlich als Output-Layer an
+	if !hasOutput {
+		info.OutputBytes += info.InputBytes
+	}
+
+	if _, ok := f.Metadata["general.file_type"]; ok {
+		info.Quantization = FileTypeName(info.FileType)
+	}
+	if info.Quantization == "" {
+		// Fallback: Typ mit dem größten Anteil an den Gewichten
+		var dominant GGMLType
+		var maxBytes uint64
+		for t, b := range byType {
+			if b > maxBytes {
+				dominant, maxBytes = t, b
+			}
+		}
+		if maxBytes > 0 {
+			info.Quantization = dominant.String()
+		}
+	}
+
+	if info.SizeLabel == "" && info.ParameterCount > 0 {
+		info.SizeLabel = FormatParameterCount(info.ParameterCount)
+	}
+	return info
+}
+
+// layerIndex gibt den Layer eines Tensors zurück ("blk.12.attn_q.weight" -> 12)
+func layerIndex(name string) (uint64, bool) {
+	rest, ok := strings.CutPrefix(name, "blk.")
+	if !ok {
+		return 0, false
+	}
+	num, _, _ := strings.Cut(rest, ".")
+	n, err := strconv.ParseUint(num, 10, 32)
+	if err != nil || n >= maxTensorCount {
+		return 0, false
+	}
+	return n, true
+}
+
+// FormatParameterCount formatiert eine Parameter-Anzahl (z.B. 7615616512 -> "7.6B")
+func FormatParameterCount(n uint64) string {
+	switch {
+	case n >= 1e9:
+		return strings.Replace(fmt.Sprintf("%.1fB", float64(n)/1e9), ".0B", "B", 1)
+	case n >= 1e6:
+		return fmt.Sprintf("%.0fM", float64(n)/1e6)
+	case n >= 1e3:
+		return fmt.Sprintf("%.0fK", float64(n)/1e3)
+	default:
+		return fmt.Sprintf("%d", n)
+	}
+}
+
+// DisplayName gibt einen lesbaren Namen zurück (general.name + Größe)
+func (m *ModelInfo) DisplayName() string {
+	name := strings.TrimSpace(m.Name)
+	if name == "" {
+		return ""
+	}
+	if m.SizeLabel != "" && !strings.Contains(strings.ToLower(name), strings.ToLower(m.SizeLabel)) {
+		name += " " + m.SizeLabel
+	}
+	return name
+}
+
+// IsLoraAdapter prüft ob die Datei ein LoRA-Adapter ist (kein vollständiges Modell)
+func (m *ModelInfo) IsLoraAdapter() bool {
+	return m.Type == "adapter" && (m.AdapterType == "" || m.AdapterType == "lora")
+}
+
+// CheckLoraCompatibility prüft ob ein LoRA-Adapter zum Basismodell passt.
+// llama.cpp lehnt unpassende Adapter erst beim Laden ab - und dann mit Server-Neustart.
+func CheckLoraCompatibility(adapter, base *ModelInfo) error {
+	if !adapter.IsLoraAdapter() {
+		return fmt.Errorf("kein LoRA-Adapter (general.type=%q, adapter.type=%q)", adapter.Type, adapter.AdapterType)
+	}
+	if base.IsLoraAdapter() {
+		return fmt.Errorf("Basismodell ist selbst ein Adapter")
+	}
+	if adapter.Architecture != base.Architecture {
+		return fmt.Errorf("Architektur %q passt nicht zum Basismodell (%q)", adapter.Architecture, base.Architecture)
+	}
+	if base.BlockCount > 0 && uint64(len(adapter.LayerBytes)) > base.BlockCount {
+		return fmt.Errorf("Adapter hat %d Layer, Basismodell nur %d", len(adapter.LayerBytes), base.BlockCount)
+	}
+	if adapter.LoraInputSize > 0 && base.EmbeddingLength > 0 && adapter.LoraInputSize != base.EmbeddingLength {
+		return fmt.Errorf("Embedding-Größe %d passt nicht zum Basismodell (%d)", adapter.LoraInputSize, base.EmbeddingLength)
+	}
+	return nil
+}
+
+// maxDraftVocabDifference entspricht SPEC_VOCAB_MAX_SIZE_DIFFERENCE in llama.cpp
+const maxDraftVocabDifference = 128
+
+// CheckDraftCompatibility prüft ob ein Modell als Draft-Modell für Speculative Decoding
+// taugt: Der Tokenizer muss identisch sein, sonst lehnt llama-server den Draft ab.
+func CheckDraftCompatibility(draft, target *ModelInfo) error {
+	if draft.IsLoraAdapter() || target.IsLoraAdapter() {
+		return fmt.Errorf("LoRA-Adapter sind keine eigenständigen Modelle")
+	}
+	if draft.TokenizerModel == "" || draft.TokenizerModel != target.TokenizerModel {
+		return fmt.Errorf("Tokenizer %q passt nicht zu %q", draft.TokenizerModel, target.TokenizerModel)
+	}
+	if draft.TokenizerPre != target.TokenizerPre {
+		return fmt.Errorf("Pre-Tokenizer %q passt nicht zu %q", draft.TokenizerPre, target.TokenizerPre)
+	}
+	diff := int64(draft.VocabSize) - int64(target.VocabSize)
+	if diff < -maxDraftVocabDifference || diff > maxDraftVocabDifference {
+		return fmt.Errorf("Vokabular %d weicht zu stark ab (%d)", draft.VocabSize, target.VocabSize)
+	}
+	if draft.BOSTokenID != target.BOSTokenID || draft.EOSTokenID != target.EOSTokenID {
+		return fmt.Errorf("Spezial-Tokens (BOS/EOS) unterscheiden sich")
+	}
+	return nil
+}
+
+// swaLayerShare gibt den Anteil der Sliding-Window-Layer je Architektur zurück
+// (Zähler, Nenner). Andere Layer haben einen Cache über den vollen Context.
+func swaLayerShare(arch string) (uint64, uint64) {
+	switch arch {
+	case "gemma2":
+		return 1, 2 // Jeder zweite Layer
+	case "gemma3":
+		return 5, 6 // 5 lokale auf 1 globalen Layer
+	case "cohere2":
+		return 3, 4
+	}
+	return 0, 1
+}
+
+// KVCacheBytes berechnet die Größe des KV-Caches für einen Context.
+//
+// Parameter:
+//   - contextSize: Context-Größe in Tokens
+//   - bytesPerElement: 2 für F16, ~1.06 für Q8_0, ~0.56 für Q4_0
+//
+// Sliding-Window-Layer (ISWA, z.B. Gemma 2/3) cachen nur das Fenster.
+func (m *ModelInfo) KVCacheBytes(contextSize int, bytesPerElement float64) uint64 {
+	if m.BlockCount == 0 || contextSize <= 0 {
+		return 0
+	}
+	perTokenPerLayer := float64(m.HeadCountKV * (m.KeyLength + m.ValueLength))
+
+	ctx := uint64(contextSize)
+	fullLayers := m.BlockCount
+	var swaLayers uint64
+	if m.SlidingWindow > 0 && m.SlidingWindow < ctx {
+		num, den := swaLayerShare(m.Architecture)
+		swaLayers = m.BlockCount * num / den
+		fullLayers -= swaLayers
+	}
+
+	tokens := float64(ctx*fullLayers + m.SlidingWindow*swaLayers)
+	return uint64(tokens * perTokenPerLayer * bytesPerElement)
+}
+
+// =============================================================================
+// Cache für gelesene Header
+// =============================================================================
+
+type cacheEntry struct {
+	size    int64
+	modTime time.Time
+	info    *ModelInfo
+}
+
+var (
+	cacheMu sync.Mutex
+	cache   = make(map[string]cacheEntry)
+)
+
+// ReadInfo liest die Modell-Informationen einer GGUF-Datei.
+// Ergebnisse werden pro Pfad gecacht, solange sich Größe und Änderungszeit nicht ändern.
+func ReadInfo(path string) (*ModelInfo, error) {
+	stat, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	cacheMu.Lock()
+	entry, ok := cache[path]
+	cacheMu.Unlock()
+	if ok && entry.size == stat.Size() && entry.modTime.Equal(stat.ModTime()) {
+		return entry.info, nil
+	}
+
+	file, err := Open(path)
+	if err != nil {
+		return nil, err
+	}
+	info := file.Info()
+	info.Path = path
+	info.FileSize = stat.Size()
+
+	cacheMu.Lock()
+	cache[path] = cacheEntry{size: stat.Size(), modTime: stat.ModTime(), info: info}
+	cacheMu.Unlock()
+
+	return info, nil
+}
@@ -0,0 +1,422 @@
+// Package gguf liest Metadaten und Tensor-Informationen aus GGUF-Modelldateien.
+//
+// Gelesen wird nur der Header (Key/Value-Metadaten + Tensor-Tabelle),
+// die eigentlichen Gewichte werden nicht geladen. Damit lassen sich
+// Architektur, Context-Länge, Layer-Anzahl, Quantisierung, Chat-Template
+// und exakte Tensor-Größen ermitteln ohne llama.cpp zu starten.
+//
+// Format-Referenz: https://github.com/ggml-org/ggml/blob/master/docs/gguf.md
+package gguf
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+)
+
+// Magic ist die Kennung am Dateianfang ("GGUF" little-endian)
+const Magic = 0x46554747
+
+// DefaultAlignment ist das Standard-Alignment des Tensor-Datenbereichs
+const DefaultAlignment = 32
+
+// Sicherheitsgrenzen gegen kaputte oder manipulierte Dateien
+const (
+	maxStringLen   = 64 << 20 // Chat-Templates können groß sein, Tokens nicht
+	maxKVCount     = 1 << 20
+	maxTensorCount = 1 << 20
+	maxArrayLen    = 1 << 26
+	maxDims        = 4 // GGML_MAX_DIMS
+
+	// maxStoredArrayValues: Größere Arrays (z.B. Tokenizer-Vokabular) werden
+	// nur gezählt, nicht gespeichert
+	maxStoredArrayValues = 256
+)
+
+// ErrNotGGUF wird zurückgegeben wenn die Datei keine GGUF-Datei ist
+var ErrNotGGUF = errors.New("keine GGUF-Datei")
+
+// ValueType ist der Typ eines Metadaten-Werts
+type ValueType uint32
+
+// Metadaten-Typen laut GGUF-Spezifikation
+const (
+	TypeUint8   ValueType = 0
+	TypeInt8    ValueType = 1
+	TypeUint16  ValueType = 2
+	TypeInt16   ValueType = 3
+	TypeUint32  ValueType = 4
+	TypeInt32   ValueType = 5
+	TypeFloat32 ValueType = 6
+	TypeBool    ValueType = 7
+	TypeString  ValueType = 8
+	TypeArray   ValueType = 9
+	TypeUint64  ValueType = 10
+	TypeInt64   ValueType = 11
+	TypeFloat64 ValueType = 12
+)
+
+// Array ist ein Metadaten-Array. Bei großen Arrays sind nur Typ und Länge bekannt.
+type Array struct {
+	Type   ValueType     `json:"type"`
+	Len    uint64        `json:"len"`
+	Values []interface{} `json:"values,omitempty"` // nil wenn Len > maxStoredArrayValues
+}
+
+// TensorInfo beschreibt einen Tensor aus der Tensor-Tabelle
+type TensorInfo struct {
+	Name       string   `json:"name"`
+	Dimensions []uint64 `json:"dimensions"`
+	Type       GGMLType `json:"type"`
+	Offset     uint64   `json:"offset"` // Relativ zum Datenbereich
+}
+
+// Elements gibt die Anzahl der Elemente (Parameter) des Tensors zurück
+func (t TensorInfo) Elements() uint64 {
+	n := uint64(1)
+	for _, d := range t.Dimensions {
+		n *= d
+	}
+	return n
+}
+
+// Bytes gibt die Größe des Tensors in Bytes zurück
+func (t TensorInfo) Bytes() uint64 {
+	return t.Type.RowBytes(t.Elements())
+}
+
+// File enthält den geparsten Header einer GGUF-Datei
+type File struct {
+	Version    uint32                 `json:"version"`
+	Metadata   map[string]interface{} `json:"metadata"`
+	Tensors    []TensorInfo           `json:"tensors"`
+	DataOffset int64                  `json:"dataOffset"` // Beginn der Tensor-Daten in der Datei
+}
+
+// MinFileSize gibt die Mindestgröße der Datei laut Tensor-Tabelle zurück
+// (Beginn des Datenbereichs + Ende des letzten Tensors). Ist die Datei kleiner,
+// wurde sie unvollständig heruntergeladen.
+func (f *File) MinFileSize() int64 {
+	var end uint64
+	for _, t := range f.Tensors {
+		end = max(end, t.Offset+t.Bytes())
+	}
+	return f.DataOffset + int64(end)
+}
+
+// Open liest den Header einer GGUF-Datei
+func Open(path string) (*File, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Read(f)
+}
+
+// Read liest einen GGUF-Header aus einem Reader
+func Read(r io.Reader) (*File, error) {
+	d := &decoder{r: bufio.NewReaderSize(r, 1<<16)}
+
+	magic, err := d.uint32()
+	if err != nil {
+		return nil, fmt.Errorf("GGUF-Header lesen: %w", err)
+	}
+	if magic != Magic {
+		return nil, ErrNotGGUF
+	}
+
+	file := &File{Metadata: make(map[string]interface{})}
+	if file.Version, err = d.uint32(); err != nil {
+		return nil, err
+	}
+	if file.Version < 2 || file.Version > 3 {
+		return nil, fmt.Errorf("GGUF-Version %d wird nicht unterstützt", file.Version)
+	}
+
+	tensorCount, err := d.uint64()
+	if err != nil {
+		return nil, err
+	}
+	kvCount, err := d.uint64()
+	if err != nil {
+		return nil, err
+	}
+	if tensorCount > maxTensorCount || kvCount > maxKVCount {
+		return nil, fmt.Errorf("GGUF-Header unplausibel: %d Tensoren, %d Metadaten", tensorCount, kvCount)
+	}
+
+	for i := uint64(0); i < kvCount; i++ {
+		key, err := d.string()
+		if err != nil {
+			return nil, fmt.Errorf("Metadaten-Key %d: %w", i, err)
+		}
+		vt, err := d.uint32()
+		if err != nil {
+			return nil, err
+		}
+		value, err := d.value(ValueType(vt))
+		if err != nil {
+			return nil, fmt.Errorf("Metadaten %q: %w", key, err)
+		}
+		file.Metadata[key] = value
+	}
+
+	file.Tensors = make([]TensorInfo, 0, tensorCount)
+	for i := uint64(0); i < tensorCount; i++ {
+		var t TensorInfo
+		if t.Name, err = d.string(); err != nil {
+			return nil, fmt.Errorf("Tensor %d: %w", i, err)
+		}
+		nDims, err := d.uint32()
+		if err != nil {
+			return nil, err
+		}
+		if nDims > maxDims {
+			return nil, fmt.Errorf("Tensor %q: %d Dimensionen", t.Name, nDims)
+		}
+		t.Dimensions = make([]uint64, nDims)
+		for j := range t.Dimensions {
+			if t.Dimensions[j], err = d.uint64(); err != nil {
+				return nil, err
+			}
+		}
+		typ, err := d.uint32()
+		if err != nil {
+			return nil, err
+		}
+		t.Type = GGMLType(typ)
+		if t.Offset, err = d.uint64(); err != nil {
+			return nil, err
+		}
+		file.Tensors = append(file.Tensors, t)
+	}
+
+	// Datenbereich beginnt am nächsten Vielfachen des Alignments
+	alignment := int64(file.Uint("general.alignment", DefaultAlignment))
+	if alignment <= 0 {
+		alignment = DefaultAlignment
+	}
+	file.DataOffset = (d.pos + alignment - 1) / alignment * alignment
+
+	return file, nil
+}
+
+// =============================================================================
+// Zugriff auf Metadaten
+// =============================================================================
+
+// String gibt einen String-Wert zurück (leer wenn nicht vorhanden)
+func (f *File) String(key string) string {
+	if s, ok := f.Metadata[key].(string); ok {
+		return s
+	}
+	return ""
+}
+
+// Uint gibt einen ganzzahligen Wert zurück (def wenn nicht vorhanden).
+// Bei Arrays (z.B. head_count pro Layer) wird das Maximum verwendet.
+func (f *File) Uint(key string, def uint64) uint64 {
+	if v, ok := toUint(f.Metadata[key]); ok {
+		return v
+	}
+	if arr, ok := f.Metadata[key].(Array); ok && len(arr.Values) > 0 {
+		var max uint64
+		for _, item := range arr.Values {
+			if v, ok := toUint(item); ok && v > max {
+				max = v
+			}
+		}
+		return max
+	}
+	return def
+}
+
+// Float gibt einen Gleitkomma-Wert zurück (def wenn nicht vorhanden)
+func (f *File) Float(key string, def float64) float64 {
+	switch v := f.Metadata[key].(type) {
+	case float32:
+		return float64(v)
+	case float64:
+		return v
+	}
+	if v, ok := toUint(f.Metadata[key]); ok {
+		return float64(v)
+	}
+	return def
+}
+
+// ArrayLen gibt die Länge eines Arrays zurück (0 wenn nicht vorhanden)
+func (f *File) ArrayLen(key string) uint64 {
+	if arr, ok := f.Metadata[key].(Array); ok {
+		return arr.Len
+	}
+	return 0
+}
+
+func toUint(v interface{}) (uint64, bool) {
+	switch n := v.(type) {
+	case uint8:
+		return uint64(n), true
+	case uint16:
+		return uint64(n), true
+	case uint32:
+		return uint64(n), true
+	case uint64:
+		return n, true
+	case int8:
+		return uint64(max(n, 0)), true
+	case int16:
+		return uint64(max(n, 0)), true
+	case int32:
+		return uint64(max(n, 0)), true
+	case int64:
+		return uint64(max(n, 0)), true
+	}
+	return 0, false
+}
+
+// =============================================================================
+// Binär-Decoder
+// =============================================================================
+
+type decoder struct {
+	r   *bufio.Reader
+	pos int64
+	buf [8]byte
+}
+
+func (d *decoder) read(n int) ([]byte, error) {
+	if _, err := io.ReadFull(d.r, d.buf[:n]); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	d.pos += int64(n)
+	return d.buf[:n], nil
+}
+
+func (d *decoder) uint8() (uint8, error) {
+	b, err := d.read(1)
+	if err != nil {
+		return 0, err
+	}
+	return b[0], nil
+}
+
+func (d *decoder) uint16() (uint16, error) {
+	b, err := d.read(2)
+	if err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint16(b), nil
+}
+
+func (d *decoder) uint32() (uint32, error) {
+	b, err := d.read(4)
+	if err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint32(b), nil
+}
+
+func (d *decoder) uint64() (uint64, error) {
+	b, err := d.read(8)
+	if err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint64(b), nil
+}
+
+func (d *decoder) string() (string, error) {
+	n, err := d.uint64()
+	if err != nil {
+		return "", err
+	}
+	if n > maxStringLen {
+		return "", fmt.Errorf("String zu lang (%d Bytes)", n)
+	}
+	b := make([]byte, n)
+	if _, err := io.ReadFull(d.r, b); err != nil {
+		return "", io.ErrUnexpectedEOF
+	}
+	d.pos += int64(n)
+	return string(b), nil
+}
+
+func (d *decoder) value(t ValueType) (interface{}, error) {
+	switch t {
+	case TypeUint8:
+		return d.uint8()
+	case TypeInt8:
+		v, err := d.uint8()
+		return int8(v), err
+	case TypeUint16:
+		return d.uint16()
+	case TypeInt16:
+		v, err := d.uint16()
+		return int16(v), err
+	case TypeUint32:
+		return d.uint32()
+	case TypeInt32:
+		v, err := d.uint32()
+		return int32(v), err
+	case TypeFloat32:
+		v, err := d.uint32()
+		return math.Float32frombits(v), err
+	case TypeBool:
+		v, err := d.uint8()
+		return v != 0, err
+	case TypeString:
+		return d.string()
+	case TypeUint64:
+		return d.uint64()
+	case TypeInt64:
+		v, err := d.uint64()
+		return int64(v), err
+	case TypeFloat64:
+		v, err := d.uint64()
+		return math.Float64frombits(v), err
+	case TypeArray:
+		return d.array()
+	}
+	return nil, fmt.Errorf("unbekannter Metadaten-Typ %d", t)
+}
+
+func (d *decoder) array() (Array, error) {
+	et, err := d.uint32()
+	if err != nil {
+		return Array{}, err
+	}
+	n, err := d.uint64()
+	if err != nil {
+		return Array{}, err
+	}
+	if n > maxArrayLen {
+		return Array{}, fmt.Errorf("Array zu lang (%d Elemente)", n)
+	}
+	if ValueType(et) == TypeArray {
+		return Array{}, fmt.Errorf("verschachtelte Arrays werden nicht unterstützt")
+	}
+
+	arr := Array{Type: ValueType(et), Len: n}
+	store := n <= maxStoredArrayValues
+	if store {
+		arr.Values = make([]interface{}, 0, n)
+	}
+	for i := uint64(0); i < n; i++ {
+		v, err := d.value(arr.Type)
+		if err != nil {
+			return Array{}, err
+		}
+		if store {
+			arr.Values = append(arr.Values, v)
+		}
+	}
+	return arr, nil
+}
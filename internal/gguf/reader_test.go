@@ -0,0 +1,214 @@
+package gguf
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// testModel erzeugt einen synthetischen Header eines kleinen Llama-Modells
+func testModel(t *testing.T, extra ...KV) []byte {
+	t.Helper()
+	tokens := make([]string, 300) // > maxStoredArrayValues
+	for i := range tokens {
+		tokens[i] = "tok"
+	}
+	kvs := append([]KV{
+		{"general.architecture", "llama"},
+		{"general.name", "Test Llama"},
+		{"general.file_type", uint32(15)},
+		{"general.alignment", uint32(32)},
+		{"llama.context_length", uint32(8192)},
+		{"llama.block_count", uint32(4)},
+		{"llama.embedding_length", uint32(256)},
+		{"llama.attention.head_count", uint32(8)},
+		{"llama.attention.head_count_kv", uint32(2)},
+		{"tokenizer.ggml.model", "gpt2"},
+		{"tokenizer.ggml.tokens", tokens},
+		{"tokenizer.chat_template", "{{ messages }}"},
+	}, extra...)
+	tensors := []TensorInfo{
+		{Name: "token_embd.weight", Dimensions: []uint64{256, 300}, Type: 12},   // Q4_K
+		{Name: "blk.0.attn_q.weight", Dimensions: []uint64{256, 256}, Type: 14}, // Q6_K
+		{Name: "output_norm.weight", Dimensions: []uint64{256}, Type: 0},        // F32
+	}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, kvs, tensors); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// TestReadModelInfo prüft das Auslesen der Metadaten und Tensor-Größen
+func TestReadModelInfo(t *testing.T) {
+	file, err := Read(bytes.NewReader(testModel(t)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if file.Version != 3 || len(file.Tensors) != 3 {
+		t.Fatalf("Version %d, %d Tensoren", file.Version, len(file.Tensors))
+	}
+	if file.DataOffset%32 != 0 {
+		t.Errorf("DataOffset %d nicht aligned", file.DataOffset)
+	}
+
+	info := file.Info()
+	checks := []struct {
+		name      string
+		got, want interface{}
+	}{
+		{"Architektur", info.Architecture, "llama"},
+		{"Context", info.ContextLength, uint64(8192)},
+		{"Layer", info.BlockCount, uint64(4)},
+		{"KV-Heads", info.HeadCountKV, uint64(2)},
+		{"KeyLength (embd/heads)", info.KeyLength, uint64(32)},
+		{"Quantisierung", info.Quantization, "Q4_K_M"},
+		{"Tokenizer", info.TokenizerModel, "gpt2"},
+		{"Vokabular", info.VocabSize, uint64(300)},
+		{"Chat-Template", info.ChatTemplate, "{{ messages }}"},
+		{"Parameter", info.ParameterCount, uint64(256*300 + 256*256 + 256)},
+		// Q4_K: 76800/256*144, Q6_K: 65536/256*210, F32: 256*4
+		{"Tensor-Bytes", info.TensorBytes, uint64(300*144 + 256*210 + 1024)},
+		{"Anzeigename", info.DisplayName(), "Test Llama 143K"},
+		{"Layer-Slots", len(info.LayerBytes), 4},
+		{"Layer 0", info.LayerBytes[0], uint64(256 * 210)},
+		{"Input", info.InputBytes, uint64(300 * 144)},
+		// Kein output.weight: token_embd wird als Output mitgezählt
+		{"Output", info.OutputBytes, uint64(1024 + 300*144)},
+	}
+	for _, c := range checks {
+		if c.got != c.want {
+			t.Errorf("%s = %v, erwartet %v", c.name, c.got, c.want)
+		}
+	}
+
+	// Großes Token-Array wird nur gezählt
+	if arr := file.Metadata["tokenizer.ggml.tokens"].(Array); arr.Values != nil {
+		t.Error("Tokenizer-Vokabular sollte nicht gespeichert werden")
+	}
+}
+
+// TestKVCacheBytes prüft die KV-Cache-Berechnung inkl. Sliding Window
+func TestKVCacheBytes(t *testing.T) {
+	info := &ModelInfo{Architecture: "llama", BlockCount: 32, HeadCountKV: 8, KeyLength: 128, ValueLength: 128}
+	// 8192 * 32 Layer * 8 * 256 * 2 Bytes = 1 GiB
+	if got := info.KVCacheBytes(8192, 2); got != 1<<30 {
+		t.Errorf("KV-Cache = %d, erwartet %d", got, 1<<30)
+	}
+
+	swa := *info
+	swa.Architecture = "gemma2"
+	swa.SlidingWindow = 4096
+	// Hälfte der Layer nur mit 4096 Tokens
+	if got, want := swa.KVCacheBytes(8192, 2), uint64(3<<28); got != want {
+		t.Errorf("ISWA KV-Cache = %d, erwartet %d", got, want)
+	}
+}
+
+// TestCheckLoraCompatibility prüft die Zuordnung von LoRA-Adaptern zum Basismodell
+func TestCheckLoraCompatibility(t *testing.T) {
+	base, err := Read(bytes.NewReader(testModel(t)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	adapter := func(arch string, embd uint64) *ModelInfo {
+		var buf bytes.Buffer
+		err := Write(&buf, []KV{
+			{"general.architecture", arch},
+			{"general.type", "adapter"},
+			{"adapter.type", "lora"},
+			{"adapter.lora.alpha", float32(16)},
+		}, []TensorInfo{
+			{Name: "blk.0.attn_q.weight.lora_a", Dimensions: []uint64{embd, 8}, Type: 1},
+			{Name: "blk.0.attn_q.weight.lora_b", Dimensions: []uint64{8, 256}, Type: 1},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		file, err := Read(bytes.NewReader(buf.Bytes()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return file.Info()
+	}
+
+	lora := adapter("llama", 256)
+	if !lora.IsLoraAdapter() || lora.LoraAlpha != 16 || lora.LoraInputSize != 256 {
+		t.Fatalf("Adapter-Metadaten: %+v", lora)
+	}
+	if err := CheckLoraCompatibility(lora, base.Info()); err != nil {
+		t.Errorf("Passender Adapter abgelehnt: %v", err)
+	}
+
+	for name, incompatible := range map[string]*ModelInfo{
+		"Architektur":  adapter("qwen2", 256),
+		"Embedding":    adapter("llama", 512),
+		"kein Adapter": base.Info(),
+	} {
+		if err := CheckLoraCompatibility(incompatible, base.Info()); err == nil {
+			t.Errorf("%s: Inkompatibler Adapter akzeptiert", name)
+		}
+	}
+}
+
+// TestCheckDraftCompatibility prüft die Tokenizer-Prüfung für Draft-Modelle
+func TestCheckDraftCompatibility(t *testing.T) {
+	target := &ModelInfo{TokenizerModel: "gpt2", TokenizerPre: "qwen2", VocabSize: 152064, EOSTokenID: 151645}
+
+	tests := []struct {
+		name       string
+		draft      ModelInfo
+		compatible bool
+	}{
+		{"gleiche Familie", ModelInfo{TokenizerModel: "gpt2", TokenizerPre: "qwen2", VocabSize: 151936, EOSTokenID: 151645}, true},
+		{"anderer Tokenizer", ModelInfo{TokenizerModel: "llama", TokenizerPre: "qwen2", VocabSize: 152064, EOSTokenID: 151645}, false},
+		{"anderer Pre-Tokenizer", ModelInfo{TokenizerModel: "gpt2", TokenizerPre: "llama-bpe", VocabSize: 152064, EOSTokenID: 151645}, false},
+		{"Vokabular zu klein", ModelInfo{TokenizerModel: "gpt2", TokenizerPre: "qwen2", VocabSize: 128256, EOSTokenID: 151645}, false},
+		{"anderes EOS", ModelInfo{TokenizerModel: "gpt2", TokenizerPre: "qwen2", VocabSize: 152064, EOSTokenID: 2}, false},
+	}
+	for _, tt := range tests {
+		err := CheckDraftCompatibility(&tt.draft, target)
+		if (err == nil) != tt.compatible {
+			t.Errorf("%s: kompatibel=%v, Fehler %v", tt.name, tt.compatible, err)
+		}
+	}
+}
+
+// TestReadInvalid prüft die Fehlerbehandlung bei ungültigen Dateien
+func TestReadInvalid(t *testing.T) {
+	if _, err := Read(bytes.NewReader([]byte("NOPE1234"))); !errors.Is(err, ErrNotGGUF) {
+		t.Errorf("Falsche Magic: %v", err)
+	}
+
+	// Magic + Nullen (wie leere Platzhalter-Dateien)
+	if _, err := Read(bytes.NewReader([]byte{0x47, 0x47, 0x55, 0x46, 0, 0, 0, 0})); err == nil {
+		t.Error("Version 0 sollte abgelehnt werden")
+	}
+
+	data := testModel(t)
+	if _, err := Read(bytes.NewReader(data[:len(data)/2])); err == nil {
+		t.Error("Abgeschnittener Header sollte fehlschlagen")
+	}
+}
+
+// TestReadInfoCache prüft dass ReadInfo Änderungen an der Datei erkennt
+func TestReadInfoCache(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "model.gguf")
+	os.WriteFile(path, testModel(t), 0644)
+
+	info, err := ReadInfo(path)
+	if err != nil || info.ContextLength != 8192 {
+		t.Fatalf("ReadInfo: %+v, %v", info, err)
+	}
+
+	os.WriteFile(path, testModel(t, KV{"llama.context_length", uint32(32768)}), 0644)
+	// Doppelter Key: der spätere Wert gewinnt, die Dateigröße ändert sich
+	info, _ = ReadInfo(path)
+	if info.ContextLength != 32768 {
+		t.Errorf("Cache nicht invalidiert: Context %d", info.ContextLength)
+	}
+}
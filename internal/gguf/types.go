@@ -0,0 +1,109 @@
+package gguf
+
+import "fmt"
+
+// GGMLType ist der Datentyp eines Tensors (ggml_type)
+type GGMLType uint32
+
+// typeTraits: Name, Elemente pro Block und Bytes pro Block je Tensor-Typ
+var typeTraits = map[GGMLType]struct {
+	name      string
+	blockSize uint64
+	typeSize  uint64
+}{
+	0:  {"F32", 1, 4},
+	1:  {"F16", 1, 2},
+	2:  {"Q4_0", 32, 18},
+	3:  {"Q4_1", 32, 20},
+	6:  {"Q5_0", 32, 22},
+	7:  {"Q5_1", 32, 24},
+	8:  {"Q8_0", 32, 34},
+	9:  {"Q8_1", 32, 36},
+	10: {"Q2_K", 256, 84},
+	11: {"Q3_K", 256, 110},
+	12: {"Q4_K", 256, 144},
+	13: {"Q5_K", 256, 176},
+	14: {"Q6_K", 256, 210},
+	15: {"Q8_K", 256, 292},
+	16: {"IQ2_XXS", 256, 66},
+	17: {"IQ2_XS", 256, 74},
+	18: {"IQ3_XXS", 256, 98},
+	19: {"IQ1_S", 256, 50},
+	20: {"IQ4_NL", 32, 18},
+	21: {"IQ3_S", 256, 110},
+	22: {"IQ2_S", 256, 82},
+	23: {"IQ4_XS", 256, 136},
+	24: {"I8", 1, 1},
+	25: {"I16", 1, 2},
+	26: {"I32", 1, 4},
+	27: {"I64", 1, 8},
+	28: {"F64", 1, 8},
+	29: {"IQ1_M", 256, 56},
+	30: {"BF16", 1, 2},
+	34: {"TQ1_0", 256, 54},
+	35: {"TQ2_0", 256, 66},
+}
+
+// String gibt den Namen des Typs zurück (z.B. "Q4_K")
+func (t GGMLType) String() string {
+	if traits, ok := typeTraits[t]; ok {
+		return traits.name
+	}
+	return fmt.Sprintf("TYPE_%d", uint32(t))
+}
+
+// MarshalText serialisiert den Typ als Namen (für JSON)
+func (t GGMLType) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+// RowBytes berechnet die Bytes für n Elemente dieses Typs.
+// Unbekannte Typen werden wie F16 behandelt.
+func (t GGMLType) RowBytes(n uint64) uint64 {
+	traits, ok := typeTraits[t]
+	if !ok {
+		return n * 2
+	}
+	return (n + traits.blockSize - 1) / traits.blockSize * traits.typeSize
+}
+
+// fileTypeNames: general.file_type (llama_ftype) als Quantisierungs-Bezeichnung
+var fileTypeNames = map[uint64]string{
+	0:  "F32",
+	1:  "F16",
+	2:  "Q4_0",
+	3:  "Q4_1",
+	7:  "Q8_0",
+	8:  "Q5_0",
+	9:  "Q5_1",
+	10: "Q2_K",
+	11: "Q3_K_S",
+	12: "Q3_K_M",
+	13: "Q3_K_L",
+	14: "Q4_K_S",
+	15: "Q4_K_M",
+	16: "Q5_K_S",
+	17: "Q5_K_M",
+	18: "Q6_K",
+	19: "IQ2_XXS",
+	20: "IQ2_XS",
+	21: "Q2_K_S",
+	22: "IQ3_XS",
+	23: "IQ3_XXS",
+	24: "IQ1_S",
+	25: "IQ4_NL",
+	26: "IQ3_S",
+	27: "IQ3_M",
+	28: "IQ2_S",
+	29: "IQ2_M",
+	30: "IQ4_XS",
+	31: "IQ1_M",
+	32: "BF16",
+	36: "TQ1_0",
+	37: "TQ2_0",
+}
+
+// FileTypeName gibt die Quantisierungs-Bezeichnung für general.file_type zurück
+func FileTypeName(fileType uint64) string {
+	return fileTypeNames[fileType]
+}
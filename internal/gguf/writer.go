@@ -0,0 +1,144 @@
+package gguf
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+)
+
+// KV ist ein Metadaten-Eintrag für Write (Reihenfolge bleibt erhalten)
+type KV struct {
+	Key   string
+	Value interface{}
+}
+
+// Write schreibt einen GGUF-Header (Version 3) mit Metadaten und Tensor-Tabelle.
+// Tensor-Daten werden nicht geschrieben - gedacht für Tests und Werkzeuge,
+// die synthetische Header erzeugen.
+//
+// Unterstützte Werte: uint8..uint64, int8..int64, float32, float64, bool,
+// string, []string, []uint32, []int32, []float32
+func Write(w io.Writer, kvs []KV, tensors []TensorInfo) error {
+	e := &encoder{w: w}
+	e.put(uint32(Magic))
+	e.put(uint32(3))
+	e.put(uint64(len(tensors)))
+	e.put(uint64(len(kvs)))
+
+	for _, kv := range kvs {
+		e.string(kv.Key)
+		if err := e.value(kv.Value, true); err != nil {
+			return fmt.Errorf("Metadaten %q: %w", kv.Key, err)
+		}
+	}
+
+	for _, t := range tensors {
+		e.string(t.Name)
+		e.put(uint32(len(t.Dimensions)))
+		for _, d := range t.Dimensions {
+			e.put(d)
+		}
+		e.put(uint32(t.Type))
+		e.put(t.Offset)
+	}
+	return e.err
+}
+
+type encoder struct {
+	w   io.Writer
+	err error
+}
+
+func (e *encoder) put(v interface{}) {
+	if e.err == nil {
+		e.err = binary.Write(e.w, binary.LittleEndian, v)
+	}
+}
+
+func (e *encoder) string(s string) {
+	e.put(uint64(len(s)))
+	if e.err == nil {
+		_, e.err = io.WriteString(e.w, s)
+	}
+}
+
+// value schreibt einen Wert; withType schreibt vorher den Typ (nicht bei Array-Elementen)
+func (e *encoder) value(v interface{}, withType bool) error {
+	typ := func(t ValueType) {
+		if withType {
+			e.put(uint32(t))
+		}
+	}
+	switch x := v.(type) {
+	case uint8:
+		typ(TypeUint8)
+		e.put(x)
+	case int8:
+		typ(TypeInt8)
+		e.put(x)
+	case uint16:
+		typ(TypeUint16)
+		e.put(x)
+	case int16:
+		typ(TypeInt16)
+		e.put(x)
+	case uint32:
+		typ(TypeUint32)
+		e.put(x)
+	case int32:
+		typ(TypeInt32)
+		e.put(x)
+	case float32:
+		typ(TypeFloat32)
+		e.put(math.Float32bits(x))
+	case bool:
+		typ(TypeBool)
+		var b uint8
+		if x {
+			b = 1
+		}
+		e.put(b)
+	case string:
+		typ(TypeString)
+		e.string(x)
+	case uint64:
+		typ(TypeUint64)
+		e.put(x)
+	case int64:
+		typ(TypeInt64)
+		e.put(x)
+	case float64:
+		typ(TypeFloat64)
+		e.put(math.Float64bits(x))
+	case []string:
+		e.arrayHeader(TypeString, len(x))
+		for _, s := range x {
+			e.string(s)
+		}
+	case []uint32:
+		e.arrayHeader(TypeUint32, len(x))
+		for _, n := range x {
+			e.put(n)
+		}
+	case []int32:
+		e.arrayHeader(TypeInt32, len(x))
+		for _, n := range x {
+			e.put(n)
+		}
+	case []float32:
+		e.arrayHeader(TypeFloat32, len(x))
+		for _, n := range x {
+			e.put(math.Float32bits(n))
+		}
+	default:
+		return fmt.Errorf("nicht unterstützter Typ %T", v)
+	}
+	return e.err
+}
+
+func (e *encoder) arrayHeader(elem ValueType, n int) {
+	e.put(uint32(TypeArray))
+	e.put(uint32(elem))
+	e.put(uint64(n))
+}
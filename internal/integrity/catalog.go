@@ -0,0 +1,124 @@
+package integrity
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// CatalogFileName ist der Name des Prüfsummen-Katalogs in jedem Modell-Verzeichnis
+const CatalogFileName = ".checksums.json"
+
+// Entry ist ein Eintrag im Prüfsummen-Katalog
+type Entry struct {
+	File       string    `json:"file"`
+	SHA256     string    `json:"sha256"`
+	Size       int64     `json:"size"`
+	ModTime    time.Time `json:"modTime"`
+	Source     string    `json:"source"`   // Herkunft der Referenz-Prüfsumme
+	Verified   bool      `json:"verified"` // Gegen eine Referenz-Prüfsumme geprüft
+	VerifiedAt time.Time `json:"verifiedAt"`
+}
+
+// catalogMu serialisiert Lese-/Schreibzugriffe auf die Katalog-Dateien
+var catalogMu sync.Mutex
+
+func catalogPath(dir string) string {
+	return filepath.Join(dir, CatalogFileName)
+}
+
+// loadCatalog liest den Katalog eines Verzeichnisses (leer wenn nicht vorhanden)
+func loadCatalog(dir string) (map[string]Entry, error) {
+	entries := make(map[string]Entry)
+	data, err := os.ReadFile(catalogPath(dir))
+	if os.IsNotExist(err) {
+		return entries, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("Prüfsummen-Katalog %s ungültig: %w", catalogPath(dir), err)
+	}
+	return entries, nil
+}
+
+// saveCatalog schreibt den Katalog atomar (temp + rename)
+func saveCatalog(dir string, entries map[string]Entry) error {
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := catalogPath(dir) + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, catalogPath(dir))
+}
+
+// Record speichert die Prüfsumme einer Datei im Katalog ihres Verzeichnisses
+func Record(path string, entry Entry) error {
+	catalogMu.Lock()
+	defer catalogMu.Unlock()
+
+	dir := filepath.Dir(path)
+	entries, err := loadCatalog(dir)
+	if err != nil {
+		// Kaputter Katalog wird neu aufgebaut
+		entries = make(map[string]Entry)
+	}
+	entry.File = filepath.Base(path)
+	entries[entry.File] = entry
+	return saveCatalog(dir, entries)
+}
+
+// Lookup gibt den Katalog-Eintrag einer Datei zurück
+func Lookup(path string) (Entry, bool) {
+	catalogMu.Lock()
+	defer catalogMu.Unlock()
+
+	entries, err := loadCatalog(filepath.Dir(path))
+	if err != nil {
+		return Entry{}, false
+	}
+	entry, ok := entries[filepath.Base(path)]
+	return entry, ok
+}
+
+// Forget entfernt eine Datei aus dem Katalog (z.B. nach dem Löschen)
+func Forget(path string) error {
+	catalogMu.Lock()
+	defer catalogMu.Unlock()
+
+	dir := filepath.Dir(path)
+	entries, err := loadCatalog(dir)
+	if err != nil {
+		return err
+	}
+	if _, ok := entries[filepath.Base(path)]; !ok {
+		return nil
+	}
+	delete(entries, filepath.Base(path))
+	return saveCatalog(dir, entries)
+}
+
+// Entries gibt alle Katalog-Einträge eines Verzeichnisses sortiert nach Dateiname zurück
+func Entries(dir string) ([]Entry, error) {
+	catalogMu.Lock()
+	entries, err := loadCatalog(dir)
+	catalogMu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]Entry, 0, len(entries))
+	for _, e := range entries {
+		list = append(list, e)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].File < list[j].File })
+	return list, nil
+}
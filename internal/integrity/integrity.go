@@ -0,0 +1,262 @@
+// Package integrity prüft heruntergeladene Modelldateien per SHA-256.
+//
+// Die erwartete Prüfsumme kommt entweder aus den LFS-Metadaten von
+// Hugging Face (X-Linked-Etag) oder aus der Modell-Registry. Geprüfte
+// Prüfsummen werden pro Verzeichnis in einem lokalen Katalog
+// (.checksums.json) abgelegt, damit installierte Dateien später erneut
+// gegen den Stand nach dem Download geprüft werden können.
+package integrity
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"fleet-navigator/internal/gguf"
+)
+
+// Herkunft der erwarteten Prüfsumme
+const (
+	SourceHuggingFace = "huggingface" // LFS-Metadaten (X-Linked-Etag)
+	SourceRegistry    = "registry"    // ModelRegistryEntry.SHA256
+	SourceLocal       = "local"       // Nur lokal berechnet, keine Referenz
+)
+
+// Status einer Prüfung
+const (
+	StatusOK         = "ok"         // Prüfsumme stimmt mit dem Katalog überein
+	StatusUnverified = "unverified" // Kein Katalog-Eintrag, Prüfsumme neu erfasst
+	StatusMismatch   = "mismatch"   // Datei wurde verändert oder ist beschädigt
+	StatusTruncated  = "truncated"  // GGUF-Datei kleiner als laut Tensor-Tabelle
+	StatusError      = "error"      // Datei nicht lesbar
+)
+
+// ErrChecksumMismatch wird zurückgegeben wenn die Prüfsumme nicht übereinstimmt
+var ErrChecksumMismatch = errors.New("SHA-256 stimmt nicht überein")
+
+// ErrTruncated wird zurückgegeben wenn eine GGUF-Datei unvollständig ist
+var ErrTruncated = errors.New("GGUF-Datei unvollständig")
+
+// NormalizeSHA256 bereinigt eine Prüfsumme (Anführungszeichen, "sha256:"-Präfix,
+// Groß-/Kleinschreibung). Gibt "" zurück wenn es keine SHA-256 ist.
+func NormalizeSHA256(value string) string {
+	value = strings.TrimPrefix(strings.TrimSpace(value), "W/")
+	value = strings.ToLower(strings.Trim(value, `"`))
+	value = strings.TrimPrefix(value, "sha256:")
+	if len(value) != sha256.Size*2 {
+		return ""
+	}
+	if _, err := hex.DecodeString(value); err != nil {
+		return ""
+	}
+	return value
+}
+
+// ExpectedSHA256FromHeaders liest die SHA-256 aus den Antwort-Headern von Hugging Face.
+// Bei LFS-Dateien enthält X-Linked-Etag (im Redirect auf das CDN) die SHA-256 des Inhalts.
+func ExpectedSHA256FromHeaders(h http.Header) string {
+	if sum := NormalizeSHA256(h.Get("X-Linked-Etag")); sum != "" {
+		return sum
+	}
+	// Manche Mirrors liefern die Prüfsumme direkt im ETag
+	return NormalizeSHA256(h.Get("ETag"))
+}
+
+// FetchExpectedSHA256 ermittelt die erwartete SHA-256 per HEAD-Request.
+// Redirects werden manuell verfolgt, da X-Linked-Etag nur im Redirect steht.
+// Gibt "" zurück wenn der Server keine Prüfsumme liefert.
+func FetchExpectedSHA256(url string) string {
+	client := &http.Client{
+		Timeout: 30 * time.Second,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	currentURL := url
+	for i := 0; i < 10; i++ {
+		req, err := http.NewRequest("HEAD", currentURL, nil)
+		if err != nil {
+			return ""
+		}
+		req.Header.Set("User-Agent", "Fleet-Navigator/0.7.0")
+
+		resp, err := client.Do(req)
+		if err != nil {
+			log.Printf("Prüfsumme abfragen fehlgeschlagen für %s: %v", currentURL, err)
+			return ""
+		}
+		resp.Body.Close()
+
+		if sum := ExpectedSHA256FromHeaders(resp.Header); sum != "" {
+			return sum
+		}
+		if resp.StatusCode < 300 || resp.StatusCode >= 400 {
+			return ""
+		}
+		location, err := resp.Location()
+		if err != nil {
+			return ""
+		}
+		currentURL = location.String()
+	}
+	return ""
+}
+
+// FileSHA256 berechnet die SHA-256 einer Datei
+func FileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.CopyBuffer(h, f, make([]byte, 1<<20)); err != nil {
+		return "", fmt.Errorf("Prüfsumme berechnen: %w", err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// checkGGUFSize prüft bei GGUF-Dateien ob alle Tensoren in der Datei liegen
+func checkGGUFSize(path string, size int64) error {
+	if !strings.HasSuffix(strings.ToLower(path), ".gguf") {
+		return nil
+	}
+	file, err := gguf.Open(path)
+	if err != nil {
+		// Kein lesbarer Header - die Prüfsumme entscheidet
+		return nil
+	}
+	if minSize := file.MinFileSize(); size < minSize {
+		return fmt.Errorf("%w: %d von %d Bytes", ErrTruncated, size, minSize)
+	}
+	return nil
+}
+
+// VerifyDownload prüft eine frisch heruntergeladene Datei und trägt sie in den Katalog ein.
+//
+// Parameter:
+//   - expected: erwartete SHA-256 ("" = keine Referenz, Prüfsumme wird nur erfasst)
+//   - source: Herkunft der erwarteten Prüfsumme (SourceHuggingFace, SourceRegistry)
+//
+// Bei Abweichung wird ErrChecksumMismatch bzw. ErrTruncated zurückgegeben;
+// die Datei bleibt liegen, der Aufrufer entscheidet über das Löschen.
+func VerifyDownload(path, expected, source string) (*Entry, error) {
+	stat, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkGGUFSize(path, stat.Size()); err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	sum, err := FileSHA256(path)
+	if err != nil {
+		return nil, err
+	}
+
+	expected = NormalizeSHA256(expected)
+	if expected != "" && sum != expected {
+		return nil, fmt.Errorf("%w: %s (erwartet %s, berechnet %s)", ErrChecksumMismatch, filepath.Base(path), expected, sum)
+	}
+
+	entry := Entry{
+		File:       filepath.Base(path),
+		SHA256:     sum,
+		Size:       stat.Size(),
+		ModTime:    stat.ModTime(),
+		Source:     SourceLocal,
+		Verified:   expected != "",
+		VerifiedAt: time.Now(),
+	}
+	if entry.Verified {
+		entry.Source = source
+		log.Printf("✅ SHA-256 geprüft: %s (%s, %.1fs)", entry.File, source, time.Since(start).Seconds())
+	} else {
+		log.Printf("SHA-256 erfasst (keine Referenz verfügbar): %s", entry.File)
+	}
+
+	if err := Record(path, entry); err != nil {
+		log.Printf("⚠️ Prüfsummen-Katalog konnte nicht gespeichert werden: %v", err)
+	}
+	return &entry, nil
+}
+
+// CheckResult ist das Ergebnis einer erneuten Prüfung einer installierten Datei
+type CheckResult struct {
+	Path     string  `json:"path"`
+	File     string  `json:"file"`
+	Size     int64   `json:"size"`
+	SHA256   string  `json:"sha256,omitempty"`
+	Expected string  `json:"expected,omitempty"` // Prüfsumme laut Katalog
+	Source   string  `json:"source,omitempty"`
+	Verified bool    `json:"verified"` // Katalog-Eintrag stammt aus einer Referenz-Prüfsumme
+	Status   string  `json:"status"`
+	Error    string  `json:"error,omitempty"`
+	Seconds  float64 `json:"seconds"`
+}
+
+// Check prüft eine installierte Datei erneut gegen den Katalog.
+// Dateien ohne Katalog-Eintrag werden erfasst (Status "unverified").
+func Check(path string) CheckResult {
+	result := CheckResult{Path: path, File: filepath.Base(path)}
+	start := time.Now()
+	defer func() { result.Seconds = time.Since(start).Seconds() }()
+
+	stat, err := os.Stat(path)
+	if err != nil {
+		result.Status, result.Error = StatusError, err.Error()
+		return result
+	}
+	result.Size = stat.Size()
+
+	if err := checkGGUFSize(path, stat.Size()); err != nil {
+		result.Status, result.Error = StatusTruncated, err.Error()
+		return result
+	}
+
+	sum, err := FileSHA256(path)
+	if err != nil {
+		result.Status, result.Error = StatusError, err.Error()
+		return result
+	}
+	result.SHA256 = sum
+
+	entry, ok := Lookup(path)
+	if !ok {
+		result.Status = StatusUnverified
+		err := Record(path, Entry{
+			File:       result.File,
+			SHA256:     sum,
+			Size:       stat.Size(),
+			ModTime:    stat.ModTime(),
+			Source:     SourceLocal,
+			VerifiedAt: time.Now(),
+		})
+		if err != nil {
+			log.Printf("⚠️ Prüfsummen-Katalog konnte nicht gespeichert werden: %v", err)
+		}
+		return result
+	}
+
+	result.Expected = entry.SHA256
+	result.Source = entry.Source
+	result.Verified = entry.Verified
+	if sum != entry.SHA256 {
+		result.Status = StatusMismatch
+		result.Error = fmt.Sprintf("%s: Datei wurde seit dem Download verändert oder ist beschädigt", ErrChecksumMismatch)
+		return result
+	}
+	result.Status = StatusOK
+	return result
+}
@@ -0,0 +1,131 @@
+package integrity
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"fleet-navigator/internal/gguf"
+)
+
+func sha(data string) string {
+	sum := sha256.Sum256([]byte(data))
+	return hex.EncodeToString(sum[:])
+}
+
+// TestNormalizeSHA256 prüft die Bereinigung von Prüfsummen aus HTTP-Headern
+func TestNormalizeSHA256(t *testing.T) {
+	valid := sha("test")
+	tests := []struct {
+		in, want string
+	}{
+		{valid, valid},
+		{`"` + valid + `"`, valid},
+		{`W/"` + valid + `"`, valid},
+		{"sha256:" + valid, valid},
+		{valid[:10], ""},
+		{`"abc123"`, ""}, // Normaler ETag (kein LFS)
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := NormalizeSHA256(tt.in); got != tt.want {
+			t.Errorf("NormalizeSHA256(%q) = %q, erwartet %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+// TestFetchExpectedSHA256 prüft das Auslesen von X-Linked-Etag aus dem Redirect
+func TestFetchExpectedSHA256(t *testing.T) {
+	want := sha("model")
+	cdn := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"cdn-etag"`)
+	}))
+	defer cdn.Close()
+
+	hub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/lfs/model.gguf" {
+			w.Header().Set("X-Linked-Etag", `"`+want+`"`)
+		}
+		http.Redirect(w, r, cdn.URL+"/blob", http.StatusFound)
+	}))
+	defer hub.Close()
+
+	if got := FetchExpectedSHA256(hub.URL + "/lfs/model.gguf"); got != want {
+		t.Errorf("LFS-Datei: %q, erwartet %q", got, want)
+	}
+	if got := FetchExpectedSHA256(hub.URL + "/plain.json"); got != "" {
+		t.Errorf("Ohne LFS-Metadaten: %q, erwartet leer", got)
+	}
+}
+
+// TestVerifyDownloadAndCheck prüft Download-Prüfung, Katalog und erneute Prüfung
+func TestVerifyDownloadAndCheck(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "voice.onnx")
+	os.WriteFile(path, []byte("original"), 0644)
+
+	if _, err := VerifyDownload(path, sha("anders"), SourceHuggingFace); !errors.Is(err, ErrChecksumMismatch) {
+		t.Fatalf("Falsche Prüfsumme nicht erkannt: %v", err)
+	}
+	if _, ok := Lookup(path); ok {
+		t.Fatal("Beschädigte Datei darf nicht im Katalog landen")
+	}
+
+	entry, err := VerifyDownload(path, `"`+sha("original")+`"`, SourceHuggingFace)
+	if err != nil || !entry.Verified || entry.Source != SourceHuggingFace {
+		t.Fatalf("VerifyDownload: %+v, %v", entry, err)
+	}
+
+	if result := Check(path); result.Status != StatusOK || !result.Verified {
+		t.Errorf("Check nach Download: %+v", result)
+	}
+
+	os.WriteFile(path, []byte("verändert"), 0644)
+	if result := Check(path); result.Status != StatusMismatch {
+		t.Errorf("Veränderte Datei: Status %s, erwartet %s", result.Status, StatusMismatch)
+	}
+
+	// Unbekannte Datei wird erfasst und ist danach prüfbar
+	other := filepath.Join(dir, "other.bin")
+	os.WriteFile(other, []byte("x"), 0644)
+	if result := Check(other); result.Status != StatusUnverified {
+		t.Errorf("Unbekannte Datei: Status %s", result.Status)
+	}
+	if result := Check(other); result.Status != StatusOK || result.Verified {
+		t.Errorf("Zweite Prüfung: %+v", result)
+	}
+
+	entries, err := Entries(dir)
+	if err != nil || len(entries) != 2 {
+		t.Fatalf("Entries: %d, %v", len(entries), err)
+	}
+	Forget(other)
+	if _, ok := Lookup(other); ok {
+		t.Error("Forget hat den Eintrag nicht entfernt")
+	}
+}
+
+// TestCheckTruncatedGGUF prüft die Erkennung abgeschnittener GGUF-Dateien
+func TestCheckTruncatedGGUF(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "model.gguf")
+	f, _ := os.Create(path)
+	err := gguf.Write(f, []gguf.KV{{Key: "general.architecture", Value: "llama"}}, []gguf.TensorInfo{
+		{Name: "blk.0.ffn.weight", Dimensions: []uint64{1024}, Type: 0}, // 4KB F32
+	})
+	f.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result := Check(path); result.Status != StatusTruncated {
+		t.Errorf("Nur Header ohne Tensor-Daten: Status %s, erwartet %s", result.Status, StatusTruncated)
+	}
+	if _, err := VerifyDownload(path, "", SourceHuggingFace); !errors.Is(err, ErrTruncated) {
+		t.Errorf("VerifyDownload: %v, erwartet ErrTruncated", err)
+	}
+}
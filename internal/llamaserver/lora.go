@@ -0,0 +1,302 @@
+package llamaserver
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"path/filepath"
+	"time"
+
+	"fleet-navigator/internal/gguf"
+)
+
+// =============================================================================
+// LoRA-ADAPTER
+// =============================================================================
+//
+// llama-server lädt Adapter nur beim Start (--lora). Die Stärke lässt sich danach
+// ohne Neustart ändern: global über POST /lora-adapters oder pro Anfrage über
+// das "lora"-Feld. Adapter werden deshalb mit --lora-init-without-apply geladen
+// (Stärke 0) und erst für Anfragen aktiviert, die sie ausgewählt haben.
+// Ein Neustart ist nur nötig, wenn ein noch nicht geladener Adapter gebraucht wird.
+
+// loraDrainTimeout begrenzt das Warten auf laufende Anfragen vor einem Adapter-Neustart
+const loraDrainTimeout = 2 * time.Minute
+
+// LoraAdapter beschreibt einen von llama-server geladenen Adapter (GET /lora-adapters)
+type LoraAdapter struct {
+	ID    int     `json:"id"`
+	Path  string  `json:"path"`
+	Scale float64 `json:"scale"`
+}
+
+// LoraScale wählt einen Adapter über den Dateipfad mit einer Stärke aus
+type LoraScale struct {
+	Path  string  `json:"path"`
+	Scale float64 `json:"scale"`
+}
+
+// loraIDScale ist das Format von llama-server für Stärken (POST /lora-adapters, "lora"-Feld)
+type loraIDScale struct {
+	ID    int     `json:"id"`
+	Scale float64 `json:"scale"`
+}
+
+type loraContextKey struct{}
+
+// WithLora hängt eine Adapter-Auswahl an den Context. Anfragen mit diesem Context
+// verwenden genau diese Adapter, alle anderen geladenen Adapter mit Stärke 0.
+// Ohne Auswahl gelten die globalen Stärken des Servers.
+func WithLora(ctx context.Context, scales []LoraScale) context.Context {
+	if scales == nil {
+		scales = []LoraScale{}
+	}
+	return context.WithValue(ctx, loraContextKey{}, scales)
+}
+
+// loraFromContext gibt die Adapter-Auswahl eines Contexts zurück
+func loraFromContext(ctx context.Context) ([]LoraScale, bool) {
+	scales, ok := ctx.Value(loraContextKey{}).([]LoraScale)
+	return scales, ok
+}
+
+// GetLoadedLoras gibt die Pfade der im laufenden Prozess geladenen Adapter zurück (Index = ID)
+func (s *Server) GetLoadedLoras() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]string(nil), s.loras...)
+}
+
+// loraArgsLocked erzeugt die Start-Argumente für die gewünschten Adapter.
+// Adapter, die nicht zum Modell passen, werden übersprungen - llama-server würde sonst nicht starten.
+func (s *Server) loraArgsLocked(modelPath string) []string {
+	s.loras = nil
+	if len(s.loraPaths) == 0 {
+		return nil
+	}
+
+	base, err := gguf.ReadInfo(modelPath)
+	if err != nil {
+		log.Printf("⚠️ LoRA: Modell-Header nicht lesbar, lade keine Adapter: %v", err)
+		return nil
+	}
+
+	var args []string
+	for _, path := range s.loraPaths {
+		adapter, err := gguf.ReadInfo(path)
+		if err == nil {
+			err = gguf.CheckLoraCompatibility(adapter, base)
+		}
+		if err != nil {
+			log.Printf("⚠️ LoRA %s übersprungen: %v", filepath.Base(path), err)
+			continue
+		}
+		args = append(args, "--lora", path)
+		s.loras = append(s.loras, path)
+	}
+	if len(args) > 0 {
+		args = append(args, "--lora-init-without-apply")
+		log.Printf("🧩 LoRA: %d Adapter geladen (inaktiv bis zur Auswahl)", len(s.loras))
+	}
+	return args
+}
+
+// EnsureLoraAdapters stellt sicher, dass alle Adapter im laufenden Server geladen sind.
+// Bereits geladene Adapter bleiben erhalten (stabile IDs); fehlen welche, wird der
+// Server mit dem gleichen Modell neu gestartet - erst wenn keine Anfrage mehr generiert.
+// Vorgemerkte Adapter, die nicht mehr in registered stehen, werden beim nächsten Start
+// nicht mehr geladen. Gibt zurück, ob neu gestartet wurde.
+func (s *Server) EnsureLoraAdapters(ctx context.Context, paths, registered []string) (bool, error) {
+	// Gleichzeitige Aufrufe nacheinander - der zweite sieht die Adapter dann schon geladen
+	s.loraMu.Lock()
+	defer s.loraMu.Unlock()
+
+	s.mu.Lock()
+	loaded := make(map[string]bool, len(s.loras))
+	for _, p := range s.loras {
+		loaded[p] = true
+	}
+	keep := make(map[string]bool, len(registered)+len(paths))
+	for _, p := range registered {
+		keep[p] = true
+	}
+	for _, p := range paths {
+		keep[p] = true
+	}
+	wanted := make(map[string]bool, len(s.loraPaths))
+	pruned := s.loraPaths[:0]
+	for _, p := range s.loraPaths {
+		if keep[p] {
+			wanted[p] = true
+			pruned = append(pruned, p)
+		}
+	}
+	s.loraPaths = pruned
+
+	var missing []string
+	for _, p := range paths {
+		if loaded[p] {
+			continue
+		}
+		missing = append(missing, p)
+		if !wanted[p] {
+			wanted[p] = true
+			s.loraPaths = append(s.loraPaths, p)
+		}
+	}
+	running := s.running
+	modelPath := s.config.ModelPath
+	s.mu.Unlock()
+
+	if len(missing) == 0 || !running {
+		return false, nil
+	}
+
+	// Vor dem Neustart prüfen - ein inkompatibler Adapter würde nach dem Neustart fehlen
+	base, err := gguf.ReadInfo(modelPath)
+	if err != nil {
+		return false, fmt.Errorf("Modell-Header lesen fehlgeschlagen: %w", err)
+	}
+	for _, p := range missing {
+		adapter, err := gguf.ReadInfo(p)
+		if err != nil {
+			return false, fmt.Errorf("LoRA-Adapter lesen fehlgeschlagen: %w", err)
+		}
+		if err := gguf.CheckLoraCompatibility(adapter, base); err != nil {
+			return false, fmt.Errorf("LoRA-Adapter %s passt nicht zu %s: %w", filepath.Base(p), filepath.Base(modelPath), err)
+		}
+	}
+
+	// Laufende Generierungen nicht abbrechen: Zuteilung anhalten, bis alle Slots frei sind
+	drainCtx, cancel := context.WithTimeout(ctx, loraDrainTimeout)
+	defer cancel()
+	resume, err := s.queue.Pause(drainCtx)
+	if err != nil {
+		return false, fmt.Errorf("LoRA-Adapter nicht geladen, andere Anfragen generieren noch: %w", err)
+	}
+	defer resume()
+
+	log.Printf("🧩 LoRA: %d neue Adapter - starte llama-server neu", len(missing))
+	if err := s.Restart(); err != nil {
+		return true, fmt.Errorf("Neustart für LoRA-Adapter fehlgeschlagen: %w", err)
+	}
+	return true, s.WaitForHealthy(poolLoadTimeout)
+}
+
+// LoraAdapters fragt die geladenen Adapter mit ihren globalen Stärken ab
+func (s *Server) LoraAdapters(ctx context.Context) ([]LoraAdapter, error) {
+	if !s.IsRunning() {
+		return nil, fmt.Errorf("llama-server ist nicht aktiv")
+	}
+
+	url := fmt.Sprintf("http://localhost:%d/lora-adapters", s.config.Port)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := (&http.Client{Timeout: 10 * time.Second}).Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("llama-server nicht erreichbar: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("llama-server Fehler %d: %s", resp.StatusCode, string(body))
+	}
+
+	adapters := make([]LoraAdapter, 0)
+	if err := json.NewDecoder(resp.Body).Decode(&adapters); err != nil {
+		return nil, fmt.Errorf("LoRA-Liste dekodieren fehlgeschlagen: %w", err)
+	}
+	return adapters, nil
+}
+
+// SetLoraScales setzt die globalen Adapter-Stärken ohne Neustart.
+// Nicht aufgeführte geladene Adapter werden deaktiviert (Stärke 0).
+func (s *Server) SetLoraScales(ctx context.Context, scales []LoraScale) error {
+	if !s.IsRunning() {
+		return fmt.Errorf("llama-server ist nicht aktiv")
+	}
+
+	body, err := s.loraIDScales(scales)
+	if err != nil {
+		return err
+	}
+	jsonBody, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("JSON-Fehler: %w", err)
+	}
+
+	url := fmt.Sprintf("http://localhost:%d/lora-adapters", s.config.Port)
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonBody))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := (&http.Client{Timeout: 10 * time.Second}).Do(req)
+	if err != nil {
+		return fmt.Errorf("llama-server nicht erreichbar: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("llama-server Fehler %d: %s", resp.StatusCode, string(respBody))
+	}
+	log.Printf("🧩 LoRA: Stärken gesetzt (%d Adapter aktiv)", len(scales))
+	return nil
+}
+
+// loraIDScales übersetzt eine Auswahl per Pfad in die IDs des laufenden Prozesses
+func (s *Server) loraIDScales(scales []LoraScale) ([]loraIDScale, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	byPath := make(map[string]float64, len(scales))
+	for _, sc := range scales {
+		byPath[sc.Path] = sc.Scale
+	}
+
+	result := make([]loraIDScale, len(s.loras))
+	for id, path := range s.loras {
+		result[id] = loraIDScale{ID: id, Scale: byPath[path]}
+		delete(byPath, path)
+	}
+	for path := range byPath {
+		return nil, fmt.Errorf("LoRA-Adapter nicht geladen: %s", filepath.Base(path))
+	}
+	return result, nil
+}
+
+// applyLora setzt die Adapter-Auswahl des Contexts als "lora"-Feld im Request-Body
+func (s *Server) applyLora(ctx context.Context, body map[string]interface{}) {
+	scales, ok := loraFromContext(ctx)
+	if !ok {
+		return
+	}
+	perRequest, err := s.loraIDScales(scales)
+	if err != nil {
+		// Anfrage trotzdem beantworten - nur ohne den fehlenden Adapter
+		log.Printf("⚠️ LoRA: %v", err)
+		loaded := make(map[string]bool)
+		for _, p := range s.GetLoadedLoras() {
+			loaded[p] = true
+		}
+		var available []LoraScale
+		for _, sc := range scales {
+			if loaded[sc.Path] {
+				available = append(available, sc)
+			}
+		}
+		perRequest, _ = s.loraIDScales(available)
+	}
+	if len(perRequest) > 0 {
+		body["lora"] = perRequest
+	}
+}
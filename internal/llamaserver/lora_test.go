@@ -0,0 +1,150 @@
+package llamaserver
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"fleet-navigator/internal/gguf"
+)
+
+// writeTestGGUF schreibt einen minimalen GGUF-Header (Modell oder Adapter)
+func writeTestGGUF(t *testing.T, path string, kvs []gguf.KV, tensors []gguf.TensorInfo) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err := gguf.Write(f, kvs, tensors); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestLoraStartArgs prüft dass nur zum Modell passende Adapter geladen werden
+func TestLoraStartArgs(t *testing.T) {
+	dir := t.TempDir()
+	model := filepath.Join(dir, "base.gguf")
+	writeTestGGUF(t, model, []gguf.KV{
+		{Key: "general.architecture", Value: "llama"},
+		{Key: "llama.block_count", Value: uint32(2)},
+		{Key: "llama.embedding_length", Value: uint32(64)},
+	}, []gguf.TensorInfo{{Name: "blk.0.attn_q.weight", Dimensions: []uint64{64, 64}, Type: 1}})
+
+	adapter := func(name, arch string) string {
+		path := filepath.Join(dir, name)
+		writeTestGGUF(t, path, []gguf.KV{
+			{Key: "general.architecture", Value: arch},
+			{Key: "general.type", Value: "adapter"},
+			{Key: "adapter.type", Value: "lora"},
+		}, []gguf.TensorInfo{{Name: "blk.0.attn_q.weight.lora_a", Dimensions: []uint64{64, 4}, Type: 1}})
+		return path
+	}
+	legal := adapter("recht.gguf", "llama")
+	foreign := adapter("qwen.gguf", "qwen2")
+
+	s := NewServer(Config{})
+	s.loraPaths = []string{legal, foreign}
+	args := s.loraArgsLocked(model)
+
+	want := []string{"--lora", legal, "--lora-init-without-apply"}
+	if len(args) != len(want) {
+		t.Fatalf("Argumente: %v", args)
+	}
+	for i := range want {
+		if args[i] != want[i] {
+			t.Errorf("Argument %d = %q, erwartet %q", i, args[i], want[i])
+		}
+	}
+	if loaded := s.GetLoadedLoras(); len(loaded) != 1 || loaded[0] != legal {
+		t.Errorf("Geladene Adapter: %v", loaded)
+	}
+
+	// Server läuft nicht: Adapter werden nur für den nächsten Start vorgemerkt
+	if restarted, err := s.EnsureLoraAdapters(context.Background(), []string{"/neu.gguf"}, []string{legal, foreign}); restarted || err != nil {
+		t.Errorf("EnsureLoraAdapters ohne Server: %v, %v", restarted, err)
+	}
+	if len(s.loraPaths) != 3 {
+		t.Errorf("Vorgemerkte Adapter: %v", s.loraPaths)
+	}
+
+	// Nicht mehr registrierte Adapter werden nicht mehr vorgemerkt
+	if _, err := s.EnsureLoraAdapters(context.Background(), nil, []string{legal}); err != nil {
+		t.Fatal(err)
+	}
+	if len(s.loraPaths) != 1 || s.loraPaths[0] != legal {
+		t.Errorf("Nach dem Bereinigen: %v", s.loraPaths)
+	}
+}
+
+// TestLoraScales prüft globale Stärken und die Auswahl pro Anfrage
+func TestLoraScales(t *testing.T) {
+	var posted []loraIDScale
+	var chat map[string]interface{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/health":
+			w.Write([]byte(`{"status":"ok"}`))
+		case r.URL.Path == "/lora-adapters" && r.Method == "GET":
+			w.Write([]byte(`[{"id":0,"path":"/a.gguf","scale":0},{"id":1,"path":"/b.gguf","scale":0.5}]`))
+		case r.URL.Path == "/lora-adapters":
+			json.NewDecoder(r.Body).Decode(&posted)
+			w.Write([]byte(`{"success":true}`))
+		default:
+			json.NewDecoder(r.Body).Decode(&chat)
+			w.Write([]byte(`{"choices":[{"message":{"content":"ok"},"finish_reason":"stop"}]}`))
+		}
+	}))
+	defer ts.Close()
+
+	u, _ := url.Parse(ts.URL)
+	port, _ := strconv.Atoi(u.Port())
+	s := NewServer(Config{Port: port})
+	s.running = true
+	s.loras = []string{"/a.gguf", "/b.gguf"}
+	ctx := context.Background()
+
+	adapters, err := s.LoraAdapters(ctx)
+	if err != nil || len(adapters) != 2 || adapters[1].Scale != 0.5 {
+		t.Fatalf("LoraAdapters: %+v, %v", adapters, err)
+	}
+
+	if err := s.SetLoraScales(ctx, []LoraScale{{Path: "/b.gguf", Scale: 0.8}}); err != nil {
+		t.Fatal(err)
+	}
+	if len(posted) != 2 || posted[0].Scale != 0 || posted[1].Scale != 0.8 {
+		t.Errorf("POST /lora-adapters: %+v", posted)
+	}
+	if err := s.SetLoraScales(ctx, []LoraScale{{Path: "/fehlt.gguf", Scale: 1}}); err == nil {
+		t.Error("Nicht geladener Adapter akzeptiert")
+	}
+
+	// Pro Anfrage: nur der gewählte Adapter aktiv, fehlende werden ignoriert
+	reqCtx := WithLora(ctx, []LoraScale{{Path: "/a.gguf", Scale: 1}, {Path: "/fehlt.gguf", Scale: 1}})
+	if _, err := s.completeChat(reqCtx, map[string]interface{}{"messages": []ChatMessage{}}); err != nil {
+		t.Fatal(err)
+	}
+	lora, _ := chat["lora"].([]interface{})
+	if len(lora) != 2 {
+		t.Fatalf("lora-Feld: %v", chat["lora"])
+	}
+	if first := lora[0].(map[string]interface{}); first["scale"] != 1.0 {
+		t.Errorf("Adapter 0: %v", first)
+	}
+	if second := lora[1].(map[string]interface{}); second["scale"] != 0.0 {
+		t.Errorf("Adapter 1 sollte deaktiviert sein: %v", second)
+	}
+
+	// Ohne Auswahl: globale Stärken, kein lora-Feld
+	chat = nil
+	s.completeChat(ctx, map[string]interface{}{"messages": []ChatMessage{}})
+	if _, ok := chat["lora"]; ok {
+		t.Errorf("lora-Feld ohne Auswahl: %v", chat["lora"])
+	}
+}
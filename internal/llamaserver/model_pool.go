@@ -0,0 +1,528 @@
+package llamaserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ===== Modell-Pool =====
+//
+// Der Haupt-Server hält ein Modell geladen, ein Wechsel (Experte, Coder-,
+// E-Mail-Modell) kostet die volle Ladezeit. Mit genug VRAM/RAM hält der Pool
+// weitere Modelle in eigenen llama-server Prozessen auf eigenen Ports warm:
+//   - Ein Modell wird nur zusätzlich geladen, wenn es laut VRAM-Planer
+//     vollständig in den freien VRAM passt (bzw. ohne GPU in den freien RAM)
+//   - Reicht der Platz nicht, wird das am längsten ungenutzte Modell beendet (LRU)
+//   - Unbenutzte Modelle werden nach einer Leerlaufzeit beendet
+//   - Das Hauptmodell wird nie verdrängt
+//   - Bei MaxModels <= 1 ist der Pool aus, Modellwechsel laufen wie bisher
+
+const (
+	DefaultPoolMaxModels   = 1    // Nur das Hauptmodell (Pool aus)
+	DefaultPoolBasePort    = 2030 // Erster Port für zusätzliche Instanzen
+	DefaultPoolIdleMinutes = 15   // Leerlaufzeit bis ein Pool-Modell beendet wird
+
+	poolLoadTimeout = 90 * time.Second
+	poolPortRange   = 50 // Durchsuchte Ports ab BasePort
+)
+
+// poolSweepInterval ist der Takt der Leerlauf-Überwachung (in Tests verkürzt)
+var poolSweepInterval = 30 * time.Second
+
+var (
+	// ErrPoolDisabled: Pool ist aus, der Aufrufer wechselt das Modell des Haupt-Servers
+	ErrPoolDisabled = errors.New("Modell-Pool ist deaktiviert")
+	// ErrPoolNoCapacity: Modell passt nicht, auch nicht nach Verdrängen ungenutzter Modelle
+	ErrPoolNoCapacity = errors.New("kein Platz im Modell-Pool")
+)
+
+// PoolConfig steuert, wie viele Modelle gleichzeitig geladen bleiben
+type PoolConfig struct {
+	MaxModels   int `json:"maxModels"`   // Gleichzeitig geladene Modelle inkl. Hauptmodell (<= 1 = Pool aus)
+	BasePort    int `json:"basePort"`    // Erster Port für zusätzliche llama-server
+	IdleMinutes int `json:"idleMinutes"` // Ungenutzte Modelle nach dieser Zeit beenden (0 = nie)
+}
+
+// DefaultPoolConfig gibt die Standard-Konfiguration des Pools zurück
+func DefaultPoolConfig() PoolConfig {
+	return PoolConfig{
+		MaxModels:   DefaultPoolMaxModels,
+		BasePort:    DefaultPoolBasePort,
+		IdleMinutes: DefaultPoolIdleMinutes,
+	}
+}
+
+// Enabled prüft ob neben dem Hauptmodell weitere Modelle geladen werden dürfen
+func (c PoolConfig) Enabled() bool {
+	return c.MaxModels > 1
+}
+
+// poolMember ist ein zusätzlich geladenes Modell
+type poolMember struct {
+	server    *Server
+	modelPath string
+	port      int
+	loadedAt  time.Time
+	lastUsed  time.Time
+	inUse     int // Laufende Anfragen - solange > 0 wird nicht verdrängt
+}
+
+// ModelPool verteilt Anfragen nach Modellname auf Haupt-Server und Pool-Instanzen
+type ModelPool struct {
+	primary *Server
+
+	mu      sync.Mutex
+	members map[string]*poolMember // Schlüssel: Modellpfad
+	hits    int64
+	loads   int64
+	evicted int64
+
+	loadMu sync.Mutex // Es wird immer nur ein Modell gleichzeitig geladen
+	stop   chan struct{}
+	once   sync.Once
+
+	// Austauschbar für Tests
+	startMember func(s *Server, modelPath string) error
+	fits        func(s *Server, modelPath string) (bool, string)
+}
+
+// NewModelPool erstellt einen Pool um den Haupt-Server und startet die Leerlauf-Überwachung
+func NewModelPool(primary *Server) *ModelPool {
+	p := &ModelPool{
+		primary:     primary,
+		members:     make(map[string]*poolMember),
+		stop:        make(chan struct{}),
+		startMember: startPoolMember,
+		fits:        planFitsResident,
+	}
+	go p.sweepLoop(poolSweepInterval)
+	return p
+}
+
+// Config gibt die aktuelle Pool-Konfiguration zurück
+func (p *ModelPool) Config() PoolConfig {
+	return p.primary.GetPoolConfig()
+}
+
+// Enabled prüft ob der Pool aktiv ist
+func (p *ModelPool) Enabled() bool {
+	return p.Config().Enabled()
+}
+
+// Acquire gibt den Server zurück, der das Modell geladen hat, und lädt es bei
+// Bedarf in eine neue Instanz. Die zurückgegebene Funktion muss nach der Anfrage
+// aufgerufen werden (solange läuft, wird das Modell nicht verdrängt).
+func (p *ModelPool) Acquire(ctx context.Context, modelName string) (*Server, func(), error) {
+	if !p.Enabled() {
+		return nil, nil, ErrPoolDisabled
+	}
+	modelPath, err := p.primary.FindModelByName(modelName)
+	if err != nil {
+		return nil, nil, err
+	}
+	return p.AcquirePath(ctx, modelPath)
+}
+
+// AcquirePath arbeitet wie Acquire, aber mit dem Pfad der GGUF-Datei
+func (p *ModelPool) AcquirePath(ctx context.Context, modelPath string) (*Server, func(), error) {
+	cfg := p.Config()
+	if !cfg.Enabled() {
+		return nil, nil, ErrPoolDisabled
+	}
+
+	if srv, release := p.lease(modelPath); srv != nil {
+		return srv, release, nil
+	}
+
+	p.loadMu.Lock()
+	defer p.loadMu.Unlock()
+
+	// Während des Wartens von einer anderen Anfrage geladen?
+	if srv, release := p.lease(modelPath); srv != nil {
+		return srv, release, nil
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	member, err := p.load(cfg, modelPath)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	p.mu.Lock()
+	member.inUse++
+	p.members[modelPath] = member
+	p.loads++
+	p.mu.Unlock()
+	return member.server, p.releaseFunc(member), nil
+}
+
+// Resident prüft ob ein Modell bereits geladen ist (Haupt-Server oder Pool)
+func (p *ModelPool) Resident(modelName string) bool {
+	modelPath, err := p.primary.FindModelByName(modelName)
+	if err != nil {
+		return false
+	}
+	if p.primary.IsRunning() && p.primary.GetConfig().ModelPath == modelPath {
+		return true
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	member, ok := p.members[modelPath]
+	return ok && member.server.IsRunning()
+}
+
+// lease gibt den Server zurück, wenn das Modell schon geladen ist
+func (p *ModelPool) lease(modelPath string) (*Server, func()) {
+	if p.primary.IsRunning() && p.primary.GetConfig().ModelPath == modelPath {
+		p.mu.Lock()
+		p.hits++
+		p.mu.Unlock()
+		return p.primary, func() {}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	member, ok := p.members[modelPath]
+	if !ok {
+		return nil, nil
+	}
+	if !member.server.IsRunning() {
+		// Prozess ist abgestürzt oder wurde extern beendet
+		log.Printf("⚠️ Modell-Pool: %s läuft nicht mehr, wird neu geladen", filepath.Base(modelPath))
+		delete(p.members, modelPath)
+		return nil, nil
+	}
+	member.inUse++
+	member.lastUsed = time.Now()
+	p.hits++
+	return member.server, p.releaseFunc(member)
+}
+
+// releaseFunc gibt eine Funktion zurück, die eine Anfrage genau einmal abmeldet
+func (p *ModelPool) releaseFunc(member *poolMember) func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			p.mu.Lock()
+			defer p.mu.Unlock()
+			member.inUse--
+			member.lastUsed = time.Now()
+		})
+	}
+}
+
+// load schafft Platz und startet eine neue Instanz für das Modell (mit loadMu)
+func (p *ModelPool) load(cfg PoolConfig, modelPath string) (*poolMember, error) {
+	port, err := p.freePort(cfg)
+	if err != nil {
+		return nil, err
+	}
+	srv := p.newMemberServer(port, modelPath)
+
+	if err := p.makeRoom(cfg, srv, modelPath); err != nil {
+		return nil, err
+	}
+
+	log.Printf("📦 Modell-Pool: lade %s auf Port %d", filepath.Base(modelPath), port)
+	start := time.Now()
+	if err := p.startMember(srv, modelPath); err != nil {
+		return nil, fmt.Errorf("Pool-Instanz starten fehlgeschlagen: %w", err)
+	}
+	log.Printf("✅ Modell-Pool: %s bereit nach %.1fs", filepath.Base(modelPath), time.Since(start).Seconds())
+
+	now := time.Now()
+	return &poolMember{
+		server:    srv,
+		modelPath: modelPath,
+		port:      port,
+		loadedAt:  now,
+		lastUsed:  now,
+	}, nil
+}
+
+// makeRoom verdrängt ungenutzte Modelle (LRU), bis das neue Modell passt
+func (p *ModelPool) makeRoom(cfg PoolConfig, srv *Server, modelPath string) error {
+	for {
+		p.mu.Lock()
+		reason := ""
+		if len(p.members)+1 >= cfg.MaxModels { // Hauptmodell zählt mit
+			reason = fmt.Sprintf("maximal %d Modelle gleichzeitig", cfg.MaxModels)
+		}
+		p.mu.Unlock()
+		if reason == "" {
+			ok, why := p.fits(srv, modelPath)
+			if ok {
+				return nil
+			}
+			reason = why
+		}
+
+		p.mu.Lock()
+		victim := p.lruIdleLocked()
+		if victim == nil {
+			p.mu.Unlock()
+			return fmt.Errorf("%w: %s (%s)", ErrPoolNoCapacity, filepath.Base(modelPath), reason)
+		}
+		delete(p.members, victim.modelPath)
+		p.evicted++
+		p.mu.Unlock()
+
+		log.Printf("♻️ Modell-Pool: verdränge %s (zuletzt genutzt vor %s) - %s",
+			filepath.Base(victim.modelPath), time.Since(victim.lastUsed).Round(time.Second), reason)
+		victim.server.Stop()
+	}
+}
+
+// lruIdleLocked gibt das am längsten ungenutzte Modell ohne laufende Anfrage zurück
+func (p *ModelPool) lruIdleLocked() *poolMember {
+	var victim *poolMember
+	for _, member := range p.members {
+		if member.inUse > 0 {
+			continue
+		}
+		if victim == nil || member.lastUsed.Before(victim.lastUsed) {
+			victim = member
+		}
+	}
+	return victim
+}
+
+// freePort sucht einen freien Port ab BasePort
+func (p *ModelPool) freePort(cfg PoolConfig) (int, error) {
+	base := cfg.BasePort
+	if base <= 0 {
+		base = DefaultPoolBasePort
+	}
+
+	p.mu.Lock()
+	used := map[int]bool{p.primary.GetConfig().Port: true}
+	for _, member := range p.members {
+		used[member.port] = true
+	}
+	p.mu.Unlock()
+
+	for port := base; port < base+poolPortRange; port++ {
+		if used[port] {
+			continue
+		}
+		ln, err := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+		if err != nil {
+			continue
+		}
+		ln.Close()
+		return port, nil
+	}
+	return 0, fmt.Errorf("kein freier Port zwischen %d und %d", base, base+poolPortRange-1)
+}
+
+// newMemberServer erstellt eine Instanz mit der Konfiguration des Haupt-Servers
+func (p *ModelPool) newMemberServer(port int, modelPath string) *Server {
+	config := p.primary.GetConfig()
+	config.Port = port
+	config.ModelPath = ""
+	config.MmprojPath = "" // Start() sucht den Projektor bei Vision-Modellen selbst
+	config.VisionEnabled = false
+	// Der Pool hat den Platz bereits geplant - SmartSwap/AlwaysClear würden
+	// die anderen geladenen Modelle beenden
+	config.VRAMStrategy = StrategySmartOffload
+	if maxContext := GetModelMaxContext(modelPath); config.ContextSize > maxContext {
+		config.ContextSize = maxContext
+	}
+
+	srv := NewServer(config)
+	srv.pooled = true
+	p.primary.mu.RLock()
+	srv.navigatorPort = p.primary.navigatorPort
+	srv.templateAdapter = p.primary.templateAdapter
+	p.primary.mu.RUnlock()
+	return srv
+}
+
+// startPoolMember startet eine Instanz und wartet bis sie antwortet
+func startPoolMember(s *Server, modelPath string) error {
+	if err := s.Start(modelPath); err != nil {
+		return err
+	}
+	if err := s.WaitForHealthy(poolLoadTimeout); err != nil {
+		s.Stop()
+		return err
+	}
+	return nil
+}
+
+// planFitsResident prüft mit dem VRAM-Planer, ob das Modell zusätzlich
+// vollständig geladen werden kann (Teil-Offload wäre für ein warmes Modell zu langsam)
+func planFitsResident(s *Server, modelPath string) (bool, string) {
+	plan := s.PlanVRAM(modelPath, s.GetContextSize())
+	if plan.GPUAvailable && !plan.FullOffload {
+		return false, fmt.Sprintf("benötigt %d MB VRAM, Budget %d MB", plan.RequiredMB, plan.BudgetMB)
+	}
+	if plan.RAMFreeMB > 0 && plan.HostUsageMB > plan.RAMFreeMB {
+		return false, fmt.Sprintf("benötigt %d MB RAM, frei %d MB", plan.HostUsageMB, plan.RAMFreeMB)
+	}
+	return true, ""
+}
+
+// Evict beendet ein Pool-Modell (das Hauptmodell wird nicht beendet)
+func (p *ModelPool) Evict(modelName string) error {
+	modelPath, err := p.primary.FindModelByName(modelName)
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	member, ok := p.members[modelPath]
+	if !ok {
+		p.mu.Unlock()
+		return fmt.Errorf("Modell %s ist nicht im Pool geladen", modelName)
+	}
+	if member.inUse > 0 {
+		p.mu.Unlock()
+		return fmt.Errorf("Modell %s bearbeitet gerade %d Anfragen", modelName, member.inUse)
+	}
+	delete(p.members, modelPath)
+	p.evicted++
+	p.mu.Unlock()
+
+	log.Printf("♻️ Modell-Pool: %s manuell beendet", filepath.Base(modelPath))
+	return member.server.Stop()
+}
+
+// sweepLoop beendet regelmäßig Modelle, die länger als IdleMinutes ungenutzt sind
+func (p *ModelPool) sweepLoop(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+			p.evictIdle(time.Now())
+		}
+	}
+}
+
+// evictIdle beendet ungenutzte Modelle nach Ablauf der Leerlaufzeit
+func (p *ModelPool) evictIdle(now time.Time) int {
+	cfg := p.Config()
+	if cfg.IdleMinutes <= 0 {
+		return 0
+	}
+	idle := time.Duration(cfg.IdleMinutes) * time.Minute
+
+	var victims []*poolMember
+	p.mu.Lock()
+	for path, member := range p.members {
+		if member.inUse == 0 && now.Sub(member.lastUsed) >= idle {
+			victims = append(victims, member)
+			delete(p.members, path)
+			p.evicted++
+		}
+	}
+	p.mu.Unlock()
+
+	for _, member := range victims {
+		log.Printf("💤 Modell-Pool: %s seit %d Minuten ungenutzt, wird beendet", filepath.Base(member.modelPath), cfg.IdleMinutes)
+		member.server.Stop()
+	}
+	return len(victims)
+}
+
+// StopAll beendet alle Pool-Instanzen (z.B. vor dem Leeren des VRAM).
+// Der Pool bleibt nutzbar, später geladene Modelle werden weiter im Leerlauf beendet.
+func (p *ModelPool) StopAll() {
+	p.mu.Lock()
+	members := make([]*poolMember, 0, len(p.members))
+	for path, member := range p.members {
+		members = append(members, member)
+		delete(p.members, path)
+	}
+	p.evicted += int64(len(members))
+	p.mu.Unlock()
+
+	for _, member := range members {
+		member.server.Stop()
+	}
+}
+
+// Close beendet die Leerlauf-Überwachung und alle Pool-Instanzen (beim Herunterfahren)
+func (p *ModelPool) Close() {
+	p.once.Do(func() { close(p.stop) })
+	p.StopAll()
+}
+
+// PoolMemberStatus beschreibt ein geladenes Pool-Modell
+type PoolMemberStatus struct {
+	Model       string    `json:"model"`
+	ModelPath   string    `json:"modelPath"`
+	Port        int       `json:"port"`
+	Running     bool      `json:"running"`
+	InUse       int       `json:"inUse"`
+	LoadedAt    time.Time `json:"loadedAt"`
+	LastUsed    time.Time `json:"lastUsed"`
+	IdleSeconds int64     `json:"idleSeconds"`
+	GPULayers   int       `json:"gpuLayers"`
+	VRAMMB      int64     `json:"vramMb"`
+}
+
+// PoolStatus ist eine Momentaufnahme des Pools
+type PoolStatus struct {
+	Config    PoolConfig         `json:"config"`
+	Enabled   bool               `json:"enabled"`
+	Primary   string             `json:"primary"` // Modell des Haupt-Servers
+	Members   []PoolMemberStatus `json:"members"` // Nach letzter Nutzung sortiert (neueste zuerst)
+	Hits      int64              `json:"hits"`
+	Loads     int64              `json:"loads"`
+	Evictions int64              `json:"evictions"`
+}
+
+// Status gibt eine Momentaufnahme des Pools zurück
+func (p *ModelPool) Status() PoolStatus {
+	cfg := p.Config()
+	status := PoolStatus{
+		Config:  cfg,
+		Enabled: cfg.Enabled(),
+		Members: []PoolMemberStatus{},
+	}
+	if p.primary.IsRunning() {
+		status.Primary = filepath.Base(p.primary.GetConfig().ModelPath)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	status.Hits = p.hits
+	status.Loads = p.loads
+	status.Evictions = p.evicted
+
+	now := time.Now()
+	for _, member := range p.members {
+		ms := PoolMemberStatus{
+			Model:       filepath.Base(member.modelPath),
+			ModelPath:   member.modelPath,
+			Port:        member.port,
+			Running:     member.server.IsRunning(),
+			InUse:       member.inUse,
+			LoadedAt:    member.loadedAt,
+			LastUsed:    member.lastUsed,
+			IdleSeconds: int64(now.Sub(member.lastUsed).Seconds()),
+		}
+		if plan := member.server.CurrentPlan(); plan != nil {
+			ms.GPULayers = plan.GPULayers
+			ms.VRAMMB = plan.GPUUsageMB
+		}
+		status.Members = append(status.Members, ms)
+	}
+	sort.Slice(status.Members, func(i, j int) bool {
+		return status.Members[i].LastUsed.After(status.Members[j].LastUsed)
+	})
+	return status
+}
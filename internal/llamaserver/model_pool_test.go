@@ -0,0 +1,156 @@
+package llamaserver
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// newTestPool erstellt einen Pool mit drei Modell-Dateien und simuliertem Start
+func newTestPool(t *testing.T, maxModels int) (*ModelPool, *[]string) {
+	t.Helper()
+	dir := t.TempDir()
+	for _, name := range []string{"alpha.gguf", "beta.gguf", "gamma.gguf"} {
+		os.WriteFile(filepath.Join(dir, name), []byte("gguf"), 0644)
+	}
+
+	primary := NewServer(Config{
+		ModelsDir:   dir,
+		ContextSize: 4096,
+		Pool:        PoolConfig{MaxModels: maxModels, BasePort: 39100, IdleMinutes: 10},
+	})
+	pool := NewModelPool(primary)
+	t.Cleanup(pool.Close)
+
+	var started []string
+	pool.startMember = func(s *Server, modelPath string) error {
+		started = append(started, filepath.Base(modelPath))
+		s.running = true
+		s.config.ModelPath = modelPath
+		return nil
+	}
+	pool.fits = func(s *Server, modelPath string) (bool, string) { return true, "" }
+	return pool, &started
+}
+
+// TestModelPool_LRUEviction prüft Wiederverwendung, LRU-Verdrängung und Schutz laufender Anfragen
+func TestModelPool_LRUEviction(t *testing.T) {
+	pool, started := newTestPool(t, 3) // Hauptmodell + 2 Pool-Modelle
+	ctx := context.Background()
+
+	alpha, releaseAlpha, err := pool.Acquire(ctx, "alpha")
+	if err != nil {
+		t.Fatalf("alpha: %v", err)
+	}
+	beta, releaseBeta, err := pool.Acquire(ctx, "beta")
+	if err != nil {
+		t.Fatalf("beta: %v", err)
+	}
+	if alpha == beta || alpha.GetConfig().Port == beta.GetConfig().Port {
+		t.Fatal("Jedes Modell braucht eine eigene Instanz mit eigenem Port")
+	}
+	releaseAlpha()
+
+	// Bereits geladenes Modell wird wiederverwendet
+	again, releaseAgain, _ := pool.Acquire(ctx, "alpha")
+	if again != alpha || len(*started) != 2 {
+		t.Fatalf("alpha neu geladen: %v", *started)
+	}
+	releaseAgain()
+
+	// beta ist noch in Benutzung - alpha wird verdrängt
+	if _, releaseGamma, err := pool.Acquire(ctx, "gamma"); err != nil {
+		t.Fatalf("gamma: %v", err)
+	} else {
+		defer releaseGamma()
+	}
+	if pool.Resident("alpha") || !pool.Resident("beta") || !pool.Resident("gamma") {
+		t.Errorf("Nach Verdrängung: %+v", pool.Status().Members)
+	}
+	if alpha.IsRunning() {
+		t.Error("Verdrängte Instanz läuft noch")
+	}
+
+	// Alle Modelle in Benutzung - kein Platz
+	if _, _, err := pool.Acquire(ctx, "alpha"); !errors.Is(err, ErrPoolNoCapacity) {
+		t.Errorf("Erwartet ErrPoolNoCapacity, erhalten %v", err)
+	}
+	releaseBeta()
+
+	status := pool.Status()
+	if status.Loads != 3 || status.Evictions != 1 || status.Hits != 1 {
+		t.Errorf("Status: loads=%d evictions=%d hits=%d", status.Loads, status.Evictions, status.Hits)
+	}
+}
+
+// TestModelPool_IdleAndDisabled prüft Leerlauf-Verdrängung und deaktivierten Pool
+func TestModelPool_IdleAndDisabled(t *testing.T) {
+	pool, _ := newTestPool(t, 3)
+	_, release, err := pool.Acquire(context.Background(), "alpha")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Laufende Anfrage schützt vor Leerlauf-Verdrängung
+	if n := pool.evictIdle(time.Now().Add(time.Hour)); n != 0 {
+		t.Errorf("%d Modelle trotz laufender Anfrage beendet", n)
+	}
+	release()
+	if n := pool.evictIdle(time.Now().Add(5 * time.Minute)); n != 0 {
+		t.Errorf("%d Modelle vor Ablauf der Leerlaufzeit beendet", n)
+	}
+	if n := pool.evictIdle(time.Now().Add(11 * time.Minute)); n != 1 {
+		t.Errorf("Leerlauf: %d Modelle beendet, erwartet 1", n)
+	}
+
+	disabled, _ := newTestPool(t, 1)
+	if _, _, err := disabled.Acquire(context.Background(), "alpha"); !errors.Is(err, ErrPoolDisabled) {
+		t.Errorf("Erwartet ErrPoolDisabled, erhalten %v", err)
+	}
+}
+
+// TestModelPool_StopAll prüft, dass der Pool nach StopAll weiter arbeitet
+// und später geladene Modelle im Leerlauf beendet werden
+func TestModelPool_StopAll(t *testing.T) {
+	interval := poolSweepInterval
+	poolSweepInterval = 5 * time.Millisecond
+	defer func() { poolSweepInterval = interval }()
+
+	pool, started := newTestPool(t, 3)
+	ctx := context.Background()
+	alpha, release, err := pool.Acquire(ctx, "alpha")
+	if err != nil {
+		t.Fatal(err)
+	}
+	release()
+
+	pool.StopAll()
+	if pool.Resident("alpha") || alpha.IsRunning() {
+		t.Fatal("Pool-Instanz läuft nach StopAll weiter")
+	}
+
+	// Erneut laden, dann Leerlauf simulieren: die Überwachung läuft noch
+	if _, release, err = pool.Acquire(ctx, "alpha"); err != nil {
+		t.Fatalf("Acquire nach StopAll: %v", err)
+	}
+	release()
+	if len(*started) != 2 {
+		t.Fatalf("Starts: %v", *started)
+	}
+	pool.mu.Lock()
+	for _, member := range pool.members {
+		member.lastUsed = time.Now().Add(-time.Hour)
+	}
+	pool.mu.Unlock()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for pool.Resident("alpha") {
+		if time.Now().After(deadline) {
+			t.Fatal("Modell nach StopAll nicht mehr im Leerlauf beendet")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
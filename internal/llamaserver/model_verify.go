@@ -0,0 +1,80 @@
+package llamaserver
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"fleet-navigator/internal/integrity"
+)
+
+// VerifyInstalledModels prüft installierte GGUF-Dateien erneut per SHA-256 gegen
+// den Prüfsummen-Katalog. Ist modelName gesetzt, wird nur dieses Modell geprüft.
+func (s *Server) VerifyInstalledModels(modelName string) ([]integrity.CheckResult, error) {
+	var paths []string
+	if modelName != "" {
+		path, err := s.FindModelByName(modelName)
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, path)
+	} else {
+		models, err := s.GetAvailableModels()
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range models {
+			paths = append(paths, m.Path)
+		}
+	}
+
+	results := make([]integrity.CheckResult, 0, len(paths))
+	for _, path := range paths {
+		result := integrity.Check(path)
+		switch result.Status {
+		case integrity.StatusOK, integrity.StatusUnverified:
+			log.Printf("Modell-Prüfung %s: %s (%.1fs)", result.File, result.Status, result.Seconds)
+		default:
+			log.Printf("❌ Modell-Prüfung %s: %s - %s", result.File, result.Status, result.Error)
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// GetChecksumCatalog gibt alle erfassten Prüfsummen im Modell-Verzeichnis zurück
+// (inkl. Unterverzeichnisse wie library/)
+func (s *Server) GetChecksumCatalog() ([]integrity.Entry, error) {
+	if s.config.ModelsDir == "" {
+		return nil, fmt.Errorf("Kein Modell-Verzeichnis konfiguriert")
+	}
+
+	models, err := s.GetAvailableModels()
+	if err != nil {
+		return nil, err
+	}
+	dirs := map[string]bool{s.config.ModelsDir: true}
+	for _, m := range models {
+		dirs[filepath.Dir(m.Path)] = true
+	}
+
+	var entries []integrity.Entry
+	for dir := range dirs {
+		list, err := integrity.Entries(dir)
+		if err != nil {
+			log.Printf("⚠️ Prüfsummen-Katalog %s: %v", dir, err)
+			continue
+		}
+		for _, e := range list {
+			// Relativer Pfad, damit library/-Modelle unterscheidbar bleiben
+			if rel, err := filepath.Rel(s.config.ModelsDir, filepath.Join(dir, e.File)); err == nil && !strings.HasPrefix(rel, "..") {
+				e.File = rel
+			}
+			entries = append(entries, e)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].File < entries[j].File })
+	return entries, nil
+}
@@ -0,0 +1,57 @@
+package llamaserver
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"fleet-navigator/internal/integrity"
+)
+
+// TestDownloadModelWithChecksum prüft die SHA-256-Prüfung nach dem Download
+func TestDownloadModelWithChecksum(t *testing.T) {
+	content := []byte("GGUF-Testinhalt")
+	sum := sha256.Sum256(content)
+	lfsHash := hex.EncodeToString(sum[:])
+
+	// HuggingFace-Verhalten: /resolve/ leitet mit X-Linked-Etag auf das CDN um
+	hf := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/resolve/") {
+			w.Header().Set("X-Linked-Etag", `"`+lfsHash+`"`)
+			http.Redirect(w, r, "http://"+r.Host+"/cdn/blob", http.StatusFound)
+			return
+		}
+		w.Write(content)
+	}))
+	defer hf.Close()
+
+	modelsDir := t.TempDir()
+	server := NewServer(Config{ModelsDir: modelsDir})
+
+	if err := server.DownloadModel(hf.URL+"/resolve/ok.gguf", "ok.gguf", nil); err != nil {
+		t.Fatalf("Download mit passender LFS-Prüfsumme: %v", err)
+	}
+	entry, ok := integrity.Lookup(filepath.Join(modelsDir, "ok.gguf"))
+	if !ok || !entry.Verified || entry.SHA256 != lfsHash || entry.Source != integrity.SourceHuggingFace {
+		t.Errorf("Katalog-Eintrag: %+v (gefunden: %v)", entry, ok)
+	}
+
+	// Registry-Prüfsumme hat Vorrang und passt nicht -> Datei wird gelöscht
+	wrong := strings.Repeat("0", 64)
+	if err := server.DownloadModelWithChecksum(hf.URL+"/resolve/bad.gguf", "bad.gguf", wrong, nil); err == nil {
+		t.Fatal("Falsche Prüfsumme sollte fehlschlagen")
+	}
+	if _, err := os.Stat(filepath.Join(modelsDir, "bad.gguf")); !os.IsNotExist(err) {
+		t.Error("Beschädigter Download wurde nicht gelöscht")
+	}
+
+	results, err := server.VerifyInstalledModels("")
+	if err != nil || len(results) != 1 || results[0].Status != integrity.StatusOK {
+		t.Errorf("VerifyInstalledModels: %+v, %v", results, err)
+	}
+}
@@ -0,0 +1,393 @@
+package llamaserver
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// =============================================================================
+// KV-CACHE UND PROMPT-CACHE
+// =============================================================================
+//
+// Lange Experten-System-Prompts werden nach jedem Neustart oder Modellwechsel
+// komplett neu verarbeitet. Mit --slot-save-path kann llama-server den Zustand
+// eines Slots (KV-Cache der verarbeiteten Tokens) auf die Platte schreiben und
+// wieder laden (POST /slots/{id}?action=save|restore). Die Slot-Warteschlange
+// teilt jeder Anfrage einen eigenen Slot-Index zu, auf den sie gelegt wird (id_slot) -
+// so arbeiten Laden/Speichern nie auf einem Slot, in dem gerade generiert wird.
+// Anfragen mit Prompt-Cache-Schlüssel bevorzugen den Slot, der ihren Zustand noch
+// enthält; sonst wird der gespeicherte Zustand vorher geladen und danach wieder
+// gespeichert. llama-server verwendet dann den gemeinsamen Präfix (System-Prompt) weiter.
+
+const (
+	// Flash Attention (--flash-attn)
+	FlashAttentionAuto = "auto"
+	FlashAttentionOn   = "on"
+	FlashAttentionOff  = "off"
+
+	// slotActionTimeout: Speichern/Laden eines Slots (großer Context = mehrere hundert MB)
+	slotActionTimeout = 60 * time.Second
+
+	// slotCacheExt: Dateiendung der gespeicherten Slot-Zustände
+	slotCacheExt = ".bin"
+)
+
+// SlotCacheEntry beschreibt einen gespeicherten Slot-Zustand
+type SlotCacheEntry struct {
+	Key      string    `json:"key"`
+	File     string    `json:"file"`
+	SizeMB   float64   `json:"sizeMB"`
+	Modified time.Time `json:"modified"`
+}
+
+// slotActionResult ist die Antwort von POST /slots/{id}?action=save|restore
+type slotActionResult struct {
+	Saved    int `json:"n_saved"`
+	Restored int `json:"n_restored"`
+}
+
+type slotCacheContextKey struct{}
+
+// grantedSlotKey trägt den von der Warteschlange zugeteilten Slot-Index (siehe acquireSlot)
+type grantedSlotKey struct{}
+
+// WithSlotCache ordnet die Anfragen eines Contexts einem Prompt-Cache zu (z.B. "expert-3").
+// Ohne --slot-save-path hat der Schlüssel keine Wirkung.
+func WithSlotCache(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, slotCacheContextKey{}, key)
+}
+
+// slotCacheFromContext gibt den Prompt-Cache-Schlüssel eines Contexts zurück
+func slotCacheFromContext(ctx context.Context) (string, bool) {
+	key, ok := ctx.Value(slotCacheContextKey{}).(string)
+	return key, ok && key != ""
+}
+
+// grantedSlotFromContext gibt den zugeteilten Slot-Index zurück
+func grantedSlotFromContext(ctx context.Context) (int, bool) {
+	slot, ok := ctx.Value(grantedSlotKey{}).(int)
+	return slot, ok
+}
+
+// ValidateKVCacheType prüft einen KV-Cache-Typ (leer = Standard)
+func ValidateKVCacheType(cacheType string) error {
+	if cacheType == "" {
+		return nil
+	}
+	if _, ok := kvCacheTypeBytes[strings.ToLower(cacheType)]; !ok {
+		types := make([]string, 0, len(kvCacheTypeBytes))
+		for t := range kvCacheTypeBytes {
+			types = append(types, t)
+		}
+		sort.Strings(types)
+		return fmt.Errorf("unbekannter KV-Cache-Typ %q (erlaubt: %s)", cacheType, strings.Join(types, ", "))
+	}
+	return nil
+}
+
+// isQuantizedCacheType: quantisierte V-Caches brauchen Flash Attention
+func isQuantizedCacheType(cacheType string) bool {
+	switch strings.ToLower(cacheType) {
+	case "", "f32", "f16", "bf16":
+		return false
+	}
+	return true
+}
+
+// SetKVCache setzt KV-Cache-Typen und Flash Attention (wirkt beim nächsten Start)
+func (s *Server) SetKVCache(cacheTypeK, cacheTypeV, flashAttention string) error {
+	if err := ValidateKVCacheType(cacheTypeK); err != nil {
+		return err
+	}
+	if err := ValidateKVCacheType(cacheTypeV); err != nil {
+		return err
+	}
+	switch flashAttention {
+	case "", FlashAttentionAuto, FlashAttentionOn, FlashAttentionOff:
+	default:
+		return fmt.Errorf("flashAttention muss auto, on oder off sein")
+	}
+	if flashAttention == FlashAttentionOff && isQuantizedCacheType(cacheTypeV) {
+		return fmt.Errorf("quantisierter V-Cache (%s) braucht Flash Attention", cacheTypeV)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.config.CacheTypeK = strings.ToLower(cacheTypeK)
+	s.config.CacheTypeV = strings.ToLower(cacheTypeV)
+	s.config.FlashAttention = flashAttention
+	return nil
+}
+
+// SetSlotSavePath setzt das Verzeichnis für gespeicherte Slot-Zustände (leer = deaktiviert)
+func (s *Server) SetSlotSavePath(path string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.config.SlotSavePath = path
+}
+
+// cacheArgsLocked erzeugt die Start-Argumente für KV-Cache, Flash Attention und Slot-Speicher
+func (s *Server) cacheArgsLocked() []string {
+	s.slotOwners = nil
+
+	var args []string
+	fa := s.config.FlashAttention
+	if fa != "" {
+		args = append(args, "--flash-attn", fa)
+	}
+	if k := s.config.CacheTypeK; k != "" && k != DefaultKVCacheType {
+		args = append(args, "--cache-type-k", k)
+	}
+	if v := s.config.CacheTypeV; v != "" && v != DefaultKVCacheType {
+		if fa == FlashAttentionOff && isQuantizedCacheType(v) {
+			// llama-server würde sonst nicht starten
+			log.Printf("⚠️ V-Cache %s braucht Flash Attention - verwende %s", v, DefaultKVCacheType)
+		} else {
+			args = append(args, "--cache-type-v", v)
+		}
+	}
+
+	if path := s.config.SlotSavePath; path != "" {
+		if err := os.MkdirAll(path, 0755); err != nil {
+			log.Printf("⚠️ Slot-Verzeichnis nicht anlegbar, Prompt-Cache deaktiviert: %v", err)
+		} else {
+			args = append(args, "--slot-save-path", path)
+			s.slotOwners = make(map[int]string)
+		}
+	}
+	return args
+}
+
+// slotCacheFile erzeugt den Dateinamen eines Prompt-Caches. Der Zustand gilt nur
+// für das gleiche Modell, die gleichen Cache-Typen und die gleichen LoRA-Adapter.
+func (s *Server) slotCacheFile(key string, loras []LoraScale) string {
+	s.mu.RLock()
+	h := sha256.New()
+	fmt.Fprintf(h, "%s|%s|%s", filepath.Base(s.config.ModelPath), s.config.CacheTypeK, s.config.CacheTypeV)
+	s.mu.RUnlock()
+	for _, l := range loras {
+		fmt.Fprintf(h, "|%s=%.3f", filepath.Base(l.Path), l.Scale)
+	}
+	return sanitizeSlotKey(key) + "-" + hex.EncodeToString(h.Sum(nil)[:6]) + slotCacheExt
+}
+
+// sanitizeSlotKey erlaubt nur Zeichen, die llama-server in Dateinamen akzeptiert
+func sanitizeSlotKey(key string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' {
+			return r
+		}
+		return '_'
+	}, key)
+}
+
+// preferredSlot gibt den Slot zurück, den eine Anfrage bevorzugt: den Slot der ihren
+// Prompt-Cache noch enthält, sonst den per Hash zugeordneten (-1 = beliebig)
+func (s *Server) preferredSlot(ctx context.Context) int {
+	key, ok := slotCacheFromContext(ctx)
+	if !ok {
+		return -1
+	}
+	loras, _ := loraFromContext(ctx)
+	file := s.slotCacheFile(key, loras)
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.slotOwners == nil {
+		return -1
+	}
+	for slot, owner := range s.slotOwners {
+		if owner == file {
+			return slot
+		}
+	}
+	return slotForKey(key, s.parallelSlotsLocked())
+}
+
+// slotForKey verteilt Schlüssel stabil auf die Slots
+func slotForKey(key string, slots int) int {
+	if slots <= 1 {
+		return 0
+	}
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return int(h.Sum32() % uint32(slots))
+}
+
+// prepareSlotCache legt eine Anfrage auf den von der Warteschlange zugeteilten Slot
+// (ctx aus acquireSlot) und lädt bei Prompt-Cache-Schlüssel den gespeicherten Zustand,
+// falls der Slot gerade etwas anderes enthält.
+// Die zurückgegebene Funktion speichert den Slot nach erfolgreicher Anfrage.
+func (s *Server) prepareSlotCache(ctx context.Context, body map[string]interface{}) func(ok bool) {
+	noop := func(bool) {}
+
+	slot, granted := grantedSlotFromContext(ctx)
+	key, keyed := slotCacheFromContext(ctx)
+	s.mu.Lock()
+	if s.slotOwners == nil || !granted {
+		s.mu.Unlock()
+		return noop
+	}
+	body["id_slot"] = slot
+	if !keyed {
+		// Ungebundene Anfrage überschreibt den Inhalt ihres Slots
+		s.slotOwners[slot] = ""
+		s.mu.Unlock()
+		return noop
+	}
+	owner := s.slotOwners[slot]
+	dir := s.config.SlotSavePath
+	s.mu.Unlock()
+
+	loras, _ := loraFromContext(ctx)
+	file := s.slotCacheFile(key, loras)
+
+	if owner != file {
+		if _, err := os.Stat(filepath.Join(dir, file)); err == nil {
+			start := time.Now()
+			if result, err := s.slotAction(ctx, slot, "restore", file); err != nil {
+				log.Printf("⚠️ Prompt-Cache %s nicht geladen: %v", key, err)
+			} else {
+				log.Printf("♻️ Prompt-Cache %s geladen: %d Tokens in Slot %d (%v)", key, result.Restored, slot, time.Since(start).Round(time.Millisecond))
+			}
+		}
+	}
+
+	return func(ok bool) {
+		if !ok {
+			s.setSlotOwner(slot, "")
+			return
+		}
+		// Eigener Context: der Anfrage-Context ist nach dem Stream evtl. schon beendet
+		saveCtx, cancel := context.WithTimeout(context.Background(), slotActionTimeout)
+		defer cancel()
+		if result, err := s.slotAction(saveCtx, slot, "save", file); err != nil {
+			log.Printf("⚠️ Prompt-Cache %s nicht gespeichert: %v", key, err)
+			s.setSlotOwner(slot, "")
+		} else {
+			log.Printf("💾 Prompt-Cache %s gespeichert: %d Tokens aus Slot %d", key, result.Saved, slot)
+			s.setSlotOwner(slot, file)
+		}
+	}
+}
+
+// setSlotOwner merkt sich, welcher Prompt-Cache in einem Slot liegt
+func (s *Server) setSlotOwner(slot int, file string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.slotOwners != nil {
+		s.slotOwners[slot] = file
+	}
+}
+
+// slotAction führt POST /slots/{id}?action=save|restore aus
+func (s *Server) slotAction(ctx context.Context, slot int, action, file string) (*slotActionResult, error) {
+	jsonBody, err := json.Marshal(map[string]string{"filename": file})
+	if err != nil {
+		return nil, fmt.Errorf("JSON-Fehler: %w", err)
+	}
+
+	url := fmt.Sprintf("http://localhost:%d/slots/%d?action=%s", s.config.Port, slot, action)
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonBody))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := (&http.Client{Timeout: slotActionTimeout}).Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("llama-server nicht erreichbar: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("llama-server Fehler %d: %s", resp.StatusCode, string(body))
+	}
+
+	var result slotActionResult
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("Slot-Antwort dekodieren fehlgeschlagen: %w", err)
+	}
+	return &result, nil
+}
+
+// SlotCacheEntries listet die gespeicherten Slot-Zustände
+func (s *Server) SlotCacheEntries() ([]SlotCacheEntry, error) {
+	dir := s.GetConfig().SlotSavePath
+	entries := make([]SlotCacheEntry, 0)
+	if dir == "" {
+		return entries, nil
+	}
+
+	files, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return entries, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Slot-Verzeichnis lesen fehlgeschlagen: %w", err)
+	}
+	for _, f := range files {
+		if f.IsDir() || !strings.HasSuffix(f.Name(), slotCacheExt) {
+			continue
+		}
+		info, err := f.Info()
+		if err != nil {
+			continue
+		}
+		key := strings.TrimSuffix(f.Name(), slotCacheExt)
+		if i := strings.LastIndex(key, "-"); i > 0 {
+			key = key[:i]
+		}
+		entries = append(entries, SlotCacheEntry{
+			Key:      key,
+			File:     f.Name(),
+			SizeMB:   float64(info.Size()) / mb,
+			Modified: info.ModTime(),
+		})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Modified.After(entries[j].Modified) })
+	return entries, nil
+}
+
+// ClearSlotCache löscht gespeicherte Slot-Zustände eines Schlüssels (leer = alle).
+// Gibt die Anzahl gelöschter Dateien zurück.
+func (s *Server) ClearSlotCache(key string) (int, error) {
+	entries, err := s.SlotCacheEntries()
+	if err != nil {
+		return 0, err
+	}
+	dir := s.GetConfig().SlotSavePath
+
+	removed := 0
+	for _, e := range entries {
+		if key != "" && e.Key != sanitizeSlotKey(key) {
+			continue
+		}
+		if err := os.Remove(filepath.Join(dir, e.File)); err != nil {
+			return removed, fmt.Errorf("Prompt-Cache löschen fehlgeschlagen: %w", err)
+		}
+		removed++
+	}
+
+	// Geladene Slots gelten nicht mehr als gespeichert
+	s.mu.Lock()
+	if s.slotOwners != nil {
+		s.slotOwners = make(map[int]string)
+	}
+	s.mu.Unlock()
+	return removed, nil
+}
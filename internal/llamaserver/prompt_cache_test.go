@@ -0,0 +1,170 @@
+package llamaserver
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// TestCacheArgs prüft die Start-Argumente für KV-Cache und Slot-Speicher
+func TestCacheArgs(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "slots")
+	s := NewServer(Config{})
+	if err := s.SetKVCache("q8_0", "q4_0", FlashAttentionOn); err != nil {
+		t.Fatal(err)
+	}
+	s.SetSlotSavePath(dir)
+
+	args := s.cacheArgsLocked()
+	want := []string{"--flash-attn", "on", "--cache-type-k", "q8_0", "--cache-type-v", "q4_0", "--slot-save-path", dir}
+	if strings.Join(args, " ") != strings.Join(want, " ") {
+		t.Errorf("Argumente: %v", args)
+	}
+	if _, err := os.Stat(dir); err != nil || s.slotOwners == nil {
+		t.Errorf("Slot-Verzeichnis nicht angelegt: %v", err)
+	}
+
+	// Ungültige Kombinationen werden abgelehnt
+	for _, tc := range []struct{ k, v, fa string }{
+		{"q3_k", "", ""},
+		{"", "", "yes"},
+		{"f16", "q8_0", FlashAttentionOff},
+	} {
+		if err := s.SetKVCache(tc.k, tc.v, tc.fa); err == nil {
+			t.Errorf("SetKVCache(%q, %q, %q) akzeptiert", tc.k, tc.v, tc.fa)
+		}
+	}
+
+	// Standard: keine zusätzlichen Argumente
+	s = NewServer(Config{CacheTypeK: DefaultKVCacheType, CacheTypeV: DefaultKVCacheType})
+	if args := s.cacheArgsLocked(); len(args) != 0 {
+		t.Errorf("Argumente ohne Konfiguration: %v", args)
+	}
+}
+
+// TestSlotCache prüft Laden und Speichern des Slot-Zustands je Experte
+func TestSlotCache(t *testing.T) {
+	dir := t.TempDir()
+	var actions []string
+	var chat map[string]interface{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/health":
+			w.Write([]byte(`{"status":"ok"}`))
+		case strings.HasPrefix(r.URL.Path, "/slots/"):
+			var body struct {
+				Filename string `json:"filename"`
+			}
+			json.NewDecoder(r.Body).Decode(&body)
+			action := r.URL.Query().Get("action")
+			actions = append(actions, r.URL.Path+" "+action)
+			if action == "save" {
+				os.WriteFile(filepath.Join(dir, body.Filename), []byte("kv"), 0644)
+			}
+			w.Write([]byte(`{"n_saved":1200,"n_restored":1200}`))
+		default:
+			json.NewDecoder(r.Body).Decode(&chat)
+			w.Write([]byte(`{"choices":[{"message":{"content":"ok"},"finish_reason":"stop"}]}`))
+		}
+	}))
+	defer ts.Close()
+
+	u, _ := url.Parse(ts.URL)
+	port, _ := strconv.Atoi(u.Port())
+	s := NewServer(Config{Port: port, ModelPath: "/models/qwen-7b.gguf", SlotSavePath: dir})
+	s.cacheArgsLocked()
+	s.running = true
+
+	expert := WithSlotCache(context.Background(), "expert-3")
+	request := func(ctx context.Context) {
+		t.Helper()
+		chat = nil
+		ctx, release, err := s.acquireSlot(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer release()
+		if _, err := s.completeChat(ctx, map[string]interface{}{"messages": []ChatMessage{}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Erste Anfrage: nichts zu laden, danach gespeichert
+	request(expert)
+	if chat["id_slot"] != 0.0 || len(actions) != 1 || actions[0] != "/slots/0 save" {
+		t.Fatalf("Erste Anfrage: id_slot=%v, Aktionen %v", chat["id_slot"], actions)
+	}
+
+	// Slot enthält noch den Experten: kein erneutes Laden
+	request(expert)
+	if len(actions) != 2 || actions[1] != "/slots/0 save" {
+		t.Fatalf("Zweite Anfrage: %v", actions)
+	}
+
+	// Fremde Anfrage dazwischen: Zustand wird vor der nächsten Experten-Anfrage geladen
+	request(context.Background())
+	if chat["id_slot"] != 0.0 {
+		t.Errorf("id_slot ohne Schlüssel: %v", chat["id_slot"])
+	}
+	request(expert)
+	if len(actions) != 4 || actions[2] != "/slots/0 restore" {
+		t.Fatalf("Nach Wechsel: %v", actions)
+	}
+
+	entries, err := s.SlotCacheEntries()
+	if err != nil || len(entries) != 1 || entries[0].Key != "expert-3" {
+		t.Fatalf("Einträge: %+v, %v", entries, err)
+	}
+	if removed, err := s.ClearSlotCache("expert-3"); removed != 1 || err != nil {
+		t.Errorf("ClearSlotCache: %d, %v", removed, err)
+	}
+}
+
+// TestSlotCacheParallel prüft, dass gleichzeitige Anfragen eigene Slots erhalten
+// und ein Experte den Slot mit seinem Zustand bevorzugt
+func TestSlotCacheParallel(t *testing.T) {
+	s := NewServer(Config{ModelPath: "/models/qwen-7b.gguf", SlotSavePath: t.TempDir(), ParallelSlots: 2})
+	s.cacheArgsLocked()
+
+	// Beide Experten auf denselben Hash-Slot legen: der zweite muss trotzdem ausweichen
+	a := WithSlotCache(context.Background(), "expert-a")
+	ctxA, releaseA, err := s.acquireSlot(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	slotA, _ := grantedSlotFromContext(ctxA)
+	s.setSlotOwner(slotA, s.slotCacheFile("expert-b", nil))
+
+	ctxB, releaseB, err := s.acquireSlot(WithSlotCache(context.Background(), "expert-b"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	slotB, _ := grantedSlotFromContext(ctxB)
+	if slotA == slotB {
+		t.Fatalf("Beide Anfragen auf Slot %d", slotA)
+	}
+	bodyB := map[string]interface{}{}
+	s.prepareSlotCache(ctxB, bodyB)
+	if bodyB["id_slot"] != slotB {
+		t.Errorf("id_slot: %v, zugeteilt %d", bodyB["id_slot"], slotB)
+	}
+	releaseA()
+	releaseB()
+
+	// Freie Slots: Experte B bekommt den Slot, der seinen Zustand enthält
+	ctxB, releaseB, err = s.acquireSlot(WithSlotCache(context.Background(), "expert-b"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer releaseB()
+	if slot, _ := grantedSlotFromContext(ctxB); slot != slotA {
+		t.Errorf("Bevorzugter Slot %d, erhalten %d", slotA, slot)
+	}
+}
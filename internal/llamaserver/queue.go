@@ -0,0 +1,475 @@
+package llamaserver
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ===== Slot-Warteschlange =====
+//
+// llama-server bedient mit --parallel N genau N Anfragen gleichzeitig. Alle
+// weiteren Anfragen warten hier, statt unkontrolliert im llama-server um einen
+// Slot zu konkurrieren:
+//   - Interaktiver Chat wird vor Hintergrund-Jobs der Mates bedient
+//   - Innerhalb einer Priorität kommen die Benutzer reihum dran (Round-Robin),
+//     damit ein Mate mit 200 E-Mails nicht alle anderen Benutzer blockiert
+//   - Wartende erfahren ihre Position (z.B. für SSE-Clients)
+
+// Priority bestimmt die Reihenfolge in der Warteschlange
+type Priority int
+
+const (
+	PriorityInteractive Priority = iota // Chat im Browser/Mate - jemand wartet auf die Antwort
+	PriorityBackground                  // Mate-Jobs (E-Mail-Klassifizierung, Titel, Log-Analyse)
+	numPriorities
+)
+
+// String gibt den Namen der Priorität zurück (für Metriken und Logs)
+func (p Priority) String() string {
+	switch p {
+	case PriorityInteractive:
+		return "interactive"
+	case PriorityBackground:
+		return "background"
+	default:
+		return "unknown"
+	}
+}
+
+const (
+	DefaultParallelSlots  = 1  // --parallel: ohne Konfiguration ein Slot (VRAM wie bisher)
+	DefaultMaxQueueLength = 64 // Maximal wartende Anfragen, danach ErrQueueFull
+)
+
+// ErrQueueFull wird zurückgegeben wenn zu viele Anfragen warten
+var ErrQueueFull = errors.New("Warteschlange des llama-servers ist voll")
+
+// RequestInfo beschreibt eine Anfrage für die Warteschlange
+type RequestInfo struct {
+	Priority Priority
+	User     string // Fairness-Schlüssel (Benutzer, Mate, Session)
+	// OnQueued wird im Goroutine des Aufrufers mit der aktuellen Position
+	// aufgerufen (1 = als nächstes dran) und mit 0 sobald ein Slot frei ist.
+	// Wird nicht aufgerufen wenn sofort ein Slot frei ist.
+	OnQueued func(position int)
+}
+
+type requestInfoKey struct{}
+
+// WithRequestInfo hängt Priorität und Benutzer an einen Context an.
+// StreamChatWithContext reiht die Anfrage damit in die Warteschlange ein.
+func WithRequestInfo(ctx context.Context, info RequestInfo) context.Context {
+	return context.WithValue(ctx, requestInfoKey{}, info)
+}
+
+// requestInfoFromContext liest die RequestInfo aus dem Context.
+// Ohne Angabe gilt eine Anfrage als Hintergrund-Job des Systems.
+func requestInfoFromContext(ctx context.Context) RequestInfo {
+	if info, ok := ctx.Value(requestInfoKey{}).(RequestInfo); ok {
+		if info.User == "" {
+			info.User = "system"
+		}
+		return info
+	}
+	return RequestInfo{Priority: PriorityBackground, User: "system"}
+}
+
+// queueWaiter ist eine wartende Anfrage
+type queueWaiter struct {
+	info     RequestInfo
+	enqueued time.Time
+	position int
+	granted  bool
+	prefer   int           // Bevorzugter Slot (-1 = beliebig)
+	slot     int           // Zugeteilter Slot (gültig sobald granted)
+	ready    chan struct{} // geschlossen sobald ein Slot zugeteilt ist
+	moved    chan struct{} // Signal: Position hat sich geändert (Puffer 1)
+}
+
+// userQueue sind die wartenden Anfragen eines Benutzers (FIFO)
+type userQueue struct {
+	user    string
+	waiters []*queueWaiter
+}
+
+// priorityLevel verwaltet alle Wartenden einer Priorität
+type priorityLevel struct {
+	users     []*userQueue // Round-Robin-Reihenfolge, der Erste ist als nächstes dran
+	served    int64
+	queued    int64 // Anfragen die warten mussten
+	canceled  int64
+	totalWait time.Duration
+	maxWait   time.Duration
+}
+
+func (l *priorityLevel) waiting() int {
+	n := 0
+	for _, u := range l.users {
+		n += len(u.waiters)
+	}
+	return n
+}
+
+func (l *priorityLevel) push(w *queueWaiter) {
+	for _, u := range l.users {
+		if u.user == w.info.User {
+			u.waiters = append(u.waiters, w)
+			return
+		}
+	}
+	l.users = append(l.users, &userQueue{user: w.info.User, waiters: []*queueWaiter{w}})
+}
+
+// pop entnimmt die nächste Anfrage; der Benutzer wandert danach ans Ende
+func (l *priorityLevel) pop() *queueWaiter {
+	if len(l.users) == 0 {
+		return nil
+	}
+	u := l.users[0]
+	w := u.waiters[0]
+	u.waiters = u.waiters[1:]
+	l.users = l.users[1:]
+	if len(u.waiters) > 0 {
+		l.users = append(l.users, u)
+	}
+	return w
+}
+
+// remove entfernt eine abgebrochene Anfrage
+func (l *priorityLevel) remove(w *queueWaiter) bool {
+	for i, u := range l.users {
+		for j, candidate := range u.waiters {
+			if candidate != w {
+				continue
+			}
+			u.waiters = append(u.waiters[:j], u.waiters[j+1:]...)
+			if len(u.waiters) == 0 {
+				l.users = append(l.users[:i], l.users[i+1:]...)
+			}
+			return true
+		}
+	}
+	return false
+}
+
+// order gibt die Wartenden in Bedienreihenfolge zurück (reihum je Benutzer)
+func (l *priorityLevel) order() []*queueWaiter {
+	var result []*queueWaiter
+	for round := 0; ; round++ {
+		added := false
+		for _, u := range l.users {
+			if round < len(u.waiters) {
+				result = append(result, u.waiters[round])
+				added = true
+			}
+		}
+		if !added {
+			return result
+		}
+	}
+}
+
+func (l *priorityLevel) recordServed(wait time.Duration) {
+	l.served++
+	l.totalWait += wait
+	if wait > l.maxWait {
+		l.maxWait = wait
+	}
+}
+
+// SlotQueue verteilt die Slots des llama-servers auf die Anfragen
+type SlotQueue struct {
+	mu       sync.Mutex
+	slots    int
+	active   int
+	busy     []bool        // Belegung je Slot-Index (llama-server id_slot)
+	paused   int           // > 0: keine Zuteilung (siehe Pause)
+	idle     chan struct{} // geschlossen sobald kein Slot mehr belegt ist
+	maxQueue int
+	rejected int64
+	levels   [numPriorities]*priorityLevel
+}
+
+// NewSlotQueue erstellt eine Warteschlange für die angegebene Anzahl Slots
+func NewSlotQueue(slots int) *SlotQueue {
+	q := &SlotQueue{maxQueue: DefaultMaxQueueLength}
+	for i := range q.levels {
+		q.levels[i] = &priorityLevel{}
+	}
+	q.SetSlots(slots)
+	return q
+}
+
+// SetSlots ändert die Anzahl gleichzeitiger Anfragen (nach Neustart mit --parallel)
+func (q *SlotQueue) SetSlots(slots int) {
+	if slots < 1 {
+		slots = 1
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.slots = slots
+	// Belegte Slots über der neuen Anzahl bleiben bis zur Freigabe vermerkt
+	for len(q.busy) < slots {
+		q.busy = append(q.busy, false)
+	}
+	q.dispatchLocked()
+}
+
+// Acquire wartet auf einen freien Slot. Die zurückgegebene Funktion gibt den
+// Slot wieder frei und muss genau einmal aufgerufen werden (mehrfach ist harmlos).
+// Bei Abbruch über ctx wird die Anfrage aus der Warteschlange entfernt.
+func (q *SlotQueue) Acquire(ctx context.Context, info RequestInfo) (func(), error) {
+	_, release, err := q.AcquireSlot(ctx, info, -1)
+	return release, err
+}
+
+// AcquireSlot wie Acquire, teilt aber einen bestimmten Slot-Index zu, auf den die
+// Anfrage gelegt werden kann (id_slot). Ist prefer frei, wird er bevorzugt.
+// Kein anderer Aufrufer erhält diesen Index bis zur Freigabe.
+func (q *SlotQueue) AcquireSlot(ctx context.Context, info RequestInfo, prefer int) (int, func(), error) {
+	if info.Priority < 0 || info.Priority >= numPriorities {
+		info.Priority = PriorityBackground
+	}
+	level := q.levels[info.Priority]
+
+	q.mu.Lock()
+	if q.paused == 0 && q.active < q.slots && q.waitingLocked() == 0 {
+		slot := q.takeSlotLocked(prefer)
+		level.recordServed(0)
+		q.mu.Unlock()
+		return slot, q.releaseFunc(slot), nil
+	}
+	if q.waitingLocked() >= q.maxQueue {
+		q.rejected++
+		q.mu.Unlock()
+		return 0, nil, ErrQueueFull
+	}
+
+	w := &queueWaiter{
+		info:     info,
+		enqueued: time.Now(),
+		prefer:   prefer,
+		ready:    make(chan struct{}),
+		moved:    make(chan struct{}, 1),
+	}
+	level.push(w)
+	level.queued++
+	q.dispatchLocked()
+	q.mu.Unlock()
+
+	reported := 0
+	for {
+		select {
+		case <-w.ready:
+			if reported > 0 && info.OnQueued != nil {
+				info.OnQueued(0)
+			}
+			return w.slot, q.releaseFunc(w.slot), nil
+
+		case <-w.moved:
+			q.mu.Lock()
+			position := w.position
+			q.mu.Unlock()
+			if position > 0 && position != reported && info.OnQueued != nil {
+				info.OnQueued(position)
+				reported = position
+			}
+
+		case <-ctx.Done():
+			q.mu.Lock()
+			if w.granted {
+				// Slot wurde gleichzeitig zugeteilt - sofort wieder freigeben
+				q.freeSlotLocked(w.slot)
+			} else if level.remove(w) {
+				level.canceled++
+			}
+			q.dispatchLocked()
+			q.mu.Unlock()
+			return 0, nil, ctx.Err()
+		}
+	}
+}
+
+// releaseFunc gibt eine Funktion zurück, die einen Slot genau einmal freigibt
+func (q *SlotQueue) releaseFunc(slot int) func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			q.mu.Lock()
+			defer q.mu.Unlock()
+			q.freeSlotLocked(slot)
+			q.dispatchLocked()
+		})
+	}
+}
+
+// takeSlotLocked belegt den bevorzugten Slot falls frei, sonst den ersten freien.
+// Aufrufer stellt sicher, dass active < slots.
+func (q *SlotQueue) takeSlotLocked(prefer int) int {
+	slot := -1
+	if prefer >= 0 && prefer < q.slots && !q.busy[prefer] {
+		slot = prefer
+	} else {
+		for i := 0; i < q.slots; i++ {
+			if !q.busy[i] {
+				slot = i
+				break
+			}
+		}
+	}
+	if slot < 0 {
+		// Nur möglich wenn belegte Slots über einer verkleinerten Anzahl liegen
+		slot = 0
+	}
+	q.busy[slot] = true
+	q.active++
+	return slot
+}
+
+// freeSlotLocked gibt einen Slot-Index frei
+func (q *SlotQueue) freeSlotLocked(slot int) {
+	if slot >= 0 && slot < len(q.busy) {
+		q.busy[slot] = false
+	}
+	q.active--
+	if q.active == 0 && q.idle != nil {
+		close(q.idle)
+		q.idle = nil
+	}
+}
+
+// Pause hält die Zuteilung an und wartet, bis alle laufenden Anfragen ihren Slot
+// freigegeben haben (z.B. vor einem Neustart des llama-servers). Wartende Anfragen
+// bleiben in der Warteschlange; die zurückgegebene Funktion setzt die Zuteilung fort.
+// Bei Abbruch über ctx wird die Zuteilung sofort fortgesetzt.
+func (q *SlotQueue) Pause(ctx context.Context) (func(), error) {
+	q.mu.Lock()
+	q.paused++
+	var once sync.Once
+	resume := func() {
+		once.Do(func() {
+			q.mu.Lock()
+			defer q.mu.Unlock()
+			q.paused--
+			q.dispatchLocked()
+		})
+	}
+
+	for q.active > 0 {
+		if q.idle == nil {
+			q.idle = make(chan struct{})
+		}
+		idle := q.idle
+		q.mu.Unlock()
+		select {
+		case <-idle:
+		case <-ctx.Done():
+			resume()
+			return nil, ctx.Err()
+		}
+		q.mu.Lock()
+	}
+	q.mu.Unlock()
+	return resume, nil
+}
+
+// dispatchLocked teilt freie Slots zu und aktualisiert die Positionen
+func (q *SlotQueue) dispatchLocked() {
+	for q.paused == 0 && q.active < q.slots {
+		var next *queueWaiter
+		var level *priorityLevel
+		for _, l := range q.levels {
+			if next = l.pop(); next != nil {
+				level = l
+				break
+			}
+		}
+		if next == nil {
+			break
+		}
+		next.granted = true
+		next.slot = q.takeSlotLocked(next.prefer)
+		level.recordServed(time.Since(next.enqueued))
+		close(next.ready)
+	}
+
+	position := 0
+	for _, l := range q.levels {
+		for _, w := range l.order() {
+			position++
+			if w.position == position {
+				continue
+			}
+			w.position = position
+			select {
+			case w.moved <- struct{}{}:
+			default:
+			}
+		}
+	}
+}
+
+func (q *SlotQueue) waitingLocked() int {
+	n := 0
+	for _, l := range q.levels {
+		n += l.waiting()
+	}
+	return n
+}
+
+// PriorityMetrics sind die Kennzahlen einer Priorität
+type PriorityMetrics struct {
+	Priority  string  `json:"priority"`
+	Waiting   int     `json:"waiting"`
+	Served    int64   `json:"served"`
+	Queued    int64   `json:"queued"`   // Davon mussten warten
+	Canceled  int64   `json:"canceled"` // Während des Wartens abgebrochen
+	AvgWaitMs float64 `json:"avgWaitMs"`
+	MaxWaitMs int64   `json:"maxWaitMs"`
+}
+
+// QueueMetrics sind die Kennzahlen der Warteschlange
+type QueueMetrics struct {
+	Slots         int               `json:"slots"`
+	Active        int               `json:"active"`
+	Waiting       int               `json:"waiting"`
+	MaxQueue      int               `json:"maxQueue"`
+	Rejected      int64             `json:"rejected"`
+	Priorities    []PriorityMetrics `json:"priorities"`
+	WaitingByUser map[string]int    `json:"waitingByUser"`
+}
+
+// Metrics gibt eine Momentaufnahme der Warteschlange zurück
+func (q *SlotQueue) Metrics() QueueMetrics {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	m := QueueMetrics{
+		Slots:         q.slots,
+		Active:        q.active,
+		Waiting:       q.waitingLocked(),
+		MaxQueue:      q.maxQueue,
+		Rejected:      q.rejected,
+		WaitingByUser: make(map[string]int),
+	}
+	for i, l := range q.levels {
+		pm := PriorityMetrics{
+			Priority:  Priority(i).String(),
+			Waiting:   l.waiting(),
+			Served:    l.served,
+			Queued:    l.queued,
+			Canceled:  l.canceled,
+			MaxWaitMs: l.maxWait.Milliseconds(),
+		}
+		if l.served > 0 {
+			pm.AvgWaitMs = float64(l.totalWait.Milliseconds()) / float64(l.served)
+		}
+		m.Priorities = append(m.Priorities, pm)
+		for _, u := range l.users {
+			m.WaitingByUser[u.user] += len(u.waiters)
+		}
+	}
+	return m
+}
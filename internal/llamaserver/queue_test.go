@@ -0,0 +1,227 @@
+package llamaserver
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// waitForWaiting wartet bis die erwartete Anzahl Anfragen in der Warteschlange steht
+func waitForWaiting(t *testing.T, q *SlotQueue, n int) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for q.Metrics().Waiting != n {
+		if time.Now().After(deadline) {
+			t.Fatalf("Erwartet %d Wartende, sind %d", n, q.Metrics().Waiting)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// TestSlotQueue_PriorityAndFairness prüft die Bedienreihenfolge:
+// interaktiv vor Hintergrund, innerhalb einer Priorität reihum je Benutzer
+func TestSlotQueue_PriorityAndFairness(t *testing.T) {
+	q := NewSlotQueue(1)
+	release, err := q.Acquire(context.Background(), RequestInfo{Priority: PriorityInteractive, User: "blocker"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var mu sync.Mutex
+	var order []string
+	var wg sync.WaitGroup
+	enqueue := func(name string, info RequestInfo) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			rel, err := q.Acquire(context.Background(), info)
+			if err != nil {
+				t.Errorf("%s: %v", name, err)
+				return
+			}
+			mu.Lock()
+			order = append(order, name)
+			mu.Unlock()
+			rel()
+		}()
+	}
+
+	// Ein Mate mit drei Mails, danach ein zweiter Mate und zwei Chat-Benutzer
+	mate := RequestInfo{Priority: PriorityBackground, User: "mate:a"}
+	enqueue("mate-a-1", mate)
+	waitForWaiting(t, q, 1)
+	enqueue("mate-a-2", mate)
+	waitForWaiting(t, q, 2)
+	enqueue("mate-a-3", mate)
+	waitForWaiting(t, q, 3)
+	enqueue("mate-b-1", RequestInfo{Priority: PriorityBackground, User: "mate:b"})
+	waitForWaiting(t, q, 4)
+	enqueue("anna-1", RequestInfo{Priority: PriorityInteractive, User: "user:1"})
+	waitForWaiting(t, q, 5)
+	enqueue("anna-2", RequestInfo{Priority: PriorityInteractive, User: "user:1"})
+	waitForWaiting(t, q, 6)
+	enqueue("ben-1", RequestInfo{Priority: PriorityInteractive, User: "user:2"})
+	waitForWaiting(t, q, 7)
+
+	release()
+	wg.Wait()
+
+	want := []string{"anna-1", "ben-1", "anna-2", "mate-a-1", "mate-b-1", "mate-a-2", "mate-a-3"}
+	if len(order) != len(want) {
+		t.Fatalf("Reihenfolge %v, erwartet %v", order, want)
+	}
+	for i := range want {
+		if order[i] != want[i] {
+			t.Fatalf("Reihenfolge %v, erwartet %v", order, want)
+		}
+	}
+
+	m := q.Metrics()
+	if m.Active != 0 || m.Waiting != 0 {
+		t.Errorf("Nach Abschluss: %d aktiv, %d wartend", m.Active, m.Waiting)
+	}
+	if m.Priorities[0].Served != 4 || m.Priorities[0].Queued != 3 || m.Priorities[1].Served != 4 {
+		t.Errorf("Metriken: %+v", m.Priorities)
+	}
+}
+
+// TestSlotQueue_PositionAndCancel prüft Positionsmeldungen und Abbruch beim Warten
+func TestSlotQueue_PositionAndCancel(t *testing.T) {
+	q := NewSlotQueue(1)
+	release, _ := q.Acquire(context.Background(), RequestInfo{User: "blocker"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancelled := make(chan error, 1)
+	go func() {
+		_, err := q.Acquire(ctx, RequestInfo{Priority: PriorityBackground, User: "mate:a"})
+		cancelled <- err
+	}()
+	waitForWaiting(t, q, 1)
+
+	positions := make(chan int, 10)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		rel, err := q.Acquire(context.Background(), RequestInfo{
+			Priority: PriorityInteractive,
+			User:     "user:1",
+			OnQueued: func(position int) { positions <- position },
+		})
+		if err == nil {
+			rel()
+		}
+	}()
+
+	// Interaktive Anfrage überholt den Mate-Job
+	if pos := <-positions; pos != 1 {
+		t.Errorf("Position %d, erwartet 1", pos)
+	}
+
+	cancel()
+	if err := <-cancelled; !errors.Is(err, context.Canceled) {
+		t.Errorf("Abbruch: %v", err)
+	}
+	release()
+	<-done
+	if pos := <-positions; pos != 0 {
+		t.Errorf("Slot-Meldung %d, erwartet 0", pos)
+	}
+
+	m := q.Metrics()
+	if m.Priorities[1].Canceled != 1 || m.Active != 0 {
+		t.Errorf("Metriken nach Abbruch: %+v", m)
+	}
+}
+
+// TestSlotQueue_Full prüft die Begrenzung der Warteschlange
+func TestSlotQueue_Full(t *testing.T) {
+	q := NewSlotQueue(2)
+	q.maxQueue = 1
+	r1, _ := q.Acquire(context.Background(), RequestInfo{User: "a"})
+	r2, _ := q.Acquire(context.Background(), RequestInfo{User: "b"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go q.Acquire(ctx, RequestInfo{User: "c"})
+	waitForWaiting(t, q, 1)
+
+	if _, err := q.Acquire(context.Background(), RequestInfo{User: "d"}); !errors.Is(err, ErrQueueFull) {
+		t.Errorf("Erwartet ErrQueueFull, erhalten %v", err)
+	}
+	if m := q.Metrics(); m.Rejected != 1 || m.Active != 2 {
+		t.Errorf("Metriken: %+v", m)
+	}
+	r1()
+	r2()
+}
+
+// TestSlotQueue_Pause prüft, dass Pause auf laufende Anfragen wartet und
+// währenddessen keine neuen Slots zuteilt
+func TestSlotQueue_Pause(t *testing.T) {
+	q := NewSlotQueue(2)
+	release, err := q.Acquire(context.Background(), RequestInfo{Priority: PriorityInteractive})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Abbruch während eine Anfrage läuft: Zuteilung geht normal weiter
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := q.Pause(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Pause trotz laufender Anfrage: %v", err)
+	}
+
+	paused := make(chan func())
+	go func() {
+		resume, err := q.Pause(context.Background())
+		if err != nil {
+			t.Error(err)
+		}
+		paused <- resume
+	}()
+	waitFor := time.After(2 * time.Second)
+	for {
+		q.mu.Lock()
+		p := q.paused
+		q.mu.Unlock()
+		if p > 0 {
+			break
+		}
+		select {
+		case <-waitFor:
+			t.Fatal("Pause nicht gestartet")
+		default:
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	// Neue Anfragen warten, obwohl ein Slot frei ist
+	granted := make(chan struct{})
+	go func() {
+		rel, err := q.Acquire(context.Background(), RequestInfo{Priority: PriorityInteractive})
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		close(granted)
+		rel()
+	}()
+	waitForWaiting(t, q, 1)
+
+	release()
+	resume := <-paused
+	select {
+	case <-granted:
+		t.Fatal("Slot während der Pause zugeteilt")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	resume()
+	select {
+	case <-granted:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Anfrage nach der Pause nicht bedient")
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -17,6 +18,9 @@ import (
 	"strings"
 	"sync"
 	"time"
+
+	"fleet-navigator/internal/gguf"
+	"fleet-navigator/internal/integrity"
 )
 
 // VRAMStrategy definiert die VRAM-Management-Strategie
@@ -53,6 +57,17 @@ type Config struct {
 	// Multi-GPU Support
 	MainGPU      int          `json:"mainGpu"`      // --main-gpu: GPU-Index für diesen Server (-1 = auto)
 	Backend      string       `json:"backend"`      // cuda, rocm, vulkan, cpu
+	// Parallele Anfragen
+	ParallelSlots int `json:"parallelSlots"` // --parallel: Slots, jeder mit vollem Context (KV-Cache wächst mit)
+	// Mehrere Modelle gleichzeitig geladen halten
+	Pool PoolConfig `json:"pool"`
+	// Speculative Decoding mit kleinem Draft-Modell
+	Draft DraftConfig `json:"draft"`
+	// KV-Cache und Prompt-Cache
+	CacheTypeK     string `json:"cacheTypeK"`     // --cache-type-k: f16, q8_0, q4_0, ... (leer = f16)
+	CacheTypeV     string `json:"cacheTypeV"`     // --cache-type-v: quantisiert nur mit Flash Attention
+	FlashAttention string `json:"flashAttention"` // --flash-attn: auto, on, off (leer = llama.cpp-Standard)
+	SlotSavePath   string `json:"slotSavePath"`   // --slot-save-path: Slot-Zustände je Experte (leer = aus)
 }
 
 // DefaultConfig gibt die Standard-Konfiguration zurück
@@ -83,6 +98,13 @@ func DefaultConfig(dataDir string) Config {
 		UseMlock:     false,             // Standard: mlock deaktiviert (braucht Berechtigung)
 		MainGPU:      -1,                // -1 = automatische Auswahl
 		Backend:      "auto",            // auto = beste verfügbare (cuda > rocm > vulkan > cpu)
+		ParallelSlots: DefaultParallelSlots,
+		Pool:          DefaultPoolConfig(),
+		Draft:         DefaultDraftConfig(),
+		CacheTypeK:     DefaultKVCacheType,
+		CacheTypeV:     DefaultKVCacheType,
+		FlashAttention: FlashAttentionAuto,
+		SlotSavePath:   filepath.Join(dataDir, "slots"),
 	}
 }
 
@@ -104,6 +126,20 @@ type Server struct {
 	templateAdapter TemplateAdapter    // Für Model-Template-Adaption
 	watchdog        *Watchdog          // Watchdog für Auto-Restart
 	watchdogEnabled bool               // Watchdog aktiviert?
+	plan            *VRAMPlan          // Offload-Plan des geladenen Modells
+	queue           *SlotQueue         // Warteschlange vor den Slots des llama-servers
+	pooled          bool               // Instanz des Modell-Pools: beendet keine fremden llama-server
+	loraPaths       []string           // Gewünschte LoRA-Adapter (werden beim Start geladen)
+	loras           []string           // Im laufenden Prozess geladene Adapter (Index = llama-server ID)
+	loraMu          sync.Mutex         // Serialisiert Adapter-Neustarts (EnsureLoraAdapters)
+	draftModel      string             // Geladenes Draft-Modell (Speculative Decoding)
+	draftAuto       bool               // Draft-Modell automatisch gewählt
+	stats           generationStats    // Tokens/s und Draft-Akzeptanz seit dem Start
+	slotOwners      map[int]string     // Prompt-Cache-Datei je Slot (nil = --slot-save-path aus)
+
+	// Automatisch gewählte Draft-Modelle je Hauptmodell (eigene Sperre, auch unter s.mu nutzbar)
+	draftMu    sync.Mutex
+	draftPairs map[string]draftPair
 }
 
 // NewServer erstellt einen neuen Server-Manager
@@ -111,6 +147,7 @@ func NewServer(config Config) *Server {
 	return &Server{
 		config:        config,
 		navigatorPort: 2025, // Default Fleet Navigator Port
+		queue:         NewSlotQueue(config.ParallelSlots),
 	}
 }
 
@@ -346,8 +383,9 @@ func (s *Server) Start(modelPath string) error {
 		return fmt.Errorf("Modell nicht gefunden: %s", modelPath)
 	}
 
-	// VRAM-Bedarf schätzen
-	requiredVRAM := EstimateModelVRAM(modelPath)
+	// VRAM-Bedarf planen
+	plan := s.PlanVRAM(modelPath, s.config.ContextSize)
+	requiredVRAM := plan.RequiredMB
 	gpuLayers := s.config.GPULayers
 
 	// VRAM-Strategie anwenden
@@ -360,17 +398,14 @@ func (s *Server) Start(modelPath string) error {
 			time.Sleep(1 * time.Second)
 		}
 		ClearVRAM()
-
-	case StrategySmartSwap:
-		// Nur VRAM löschen wenn nötig (Standard)
-		log.Printf("VRAM-Strategie: SmartSwap - verwende %d GPU-Layer, benötigt ~%dMB VRAM", gpuLayers, requiredVRAM)
-		if err := s.EnsureVRAMAvailable(requiredVRAM); err != nil {
-			log.Printf("WARNUNG: VRAM-Prüfung fehlgeschlagen: %v", err)
-		}
+		plan = s.PlanVRAM(modelPath, s.config.ContextSize)
+		gpuLayers = plan.limitGPULayers(gpuLayers)
 
 	case StrategySmartOffload:
 		// Automatisches Offloading berechnen
-		gpuLayers = s.CalculateOptimalGPULayers(modelPath, requiredVRAM)
+		if plan.GPUAvailable {
+			gpuLayers = plan.GPULayers
+		}
 		log.Printf("VRAM-Strategie: SmartOffload - verwende %d GPU-Layer", gpuLayers)
 
 	case StrategyManual:
@@ -378,11 +413,16 @@ func (s *Server) Start(modelPath string) error {
 		log.Printf("VRAM-Strategie: Manual - keine automatische VRAM-Verwaltung")
 
 	default:
-		// Fallback auf SmartSwap
+		// SmartSwap (Standard): Nur VRAM löschen wenn nötig
+		log.Printf("VRAM-Strategie: SmartSwap - verwende %d GPU-Layer, benötigt ~%dMB VRAM", gpuLayers, requiredVRAM)
 		if err := s.EnsureVRAMAvailable(requiredVRAM); err != nil {
 			log.Printf("WARNUNG: VRAM-Prüfung fehlgeschlagen: %v", err)
+			// Nach dem Aufräumen neu planen, statt einen OOM zu riskieren
+			plan = s.PlanVRAM(modelPath, s.config.ContextSize)
+			gpuLayers = plan.limitGPULayers(gpuLayers)
 		}
 	}
+	logPlan(plan)
 
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -393,6 +433,8 @@ func (s *Server) Start(modelPath string) error {
 
 	ctx, cancel := context.WithCancel(context.Background())
 	s.cancelFunc = cancel
+	s.plan = plan
+	slots := s.parallelSlotsLocked()
 
 	// Kommando vorbereiten
 	args := []string{
@@ -400,9 +442,11 @@ func (s *Server) Start(modelPath string) error {
 		"--port", fmt.Sprintf("%d", s.config.Port),
 		"--host", s.config.Host,
 		"-ngl", fmt.Sprintf("%d", gpuLayers),
-		"-c", fmt.Sprintf("%d", s.config.ContextSize),
+		"-c", fmt.Sprintf("%d", s.config.ContextSize*slots), // llama-server teilt -c auf die Slots auf
+		"--parallel", fmt.Sprintf("%d", slots),
 		"--jinja", // Aktiviert natives Function Calling für Qwen, Llama 3.x, etc.
 	}
+	s.queue.SetSlots(slots)
 
 	if s.config.Threads > 0 {
 		args = append(args, "-t", fmt.Sprintf("%d", s.config.Threads))
@@ -451,6 +495,16 @@ func (s *Server) Start(modelPath string) error {
 		}
 	}
 
+	// KV-Cache-Typ, Flash Attention und Slot-Speicher für den Prompt-Cache
+	args = append(args, s.cacheArgsLocked()...)
+
+	// LoRA-Adapter (inaktiv geladen, Stärke pro Anfrage)
+	args = append(args, s.loraArgsLocked(modelPath)...)
+
+	// Speculative Decoding: Draft-Modell mit gleichem Tokenizer
+	args = append(args, s.draftArgsLocked(modelPath, gpuLayers)...)
+	s.stats.reset()
+
 	s.cmd = exec.CommandContext(ctx, s.config.BinaryPath, args...)
 
 	// Library Path setzen (wichtig für libmtmd.so und andere llama.cpp Libraries)
@@ -525,61 +579,16 @@ func (s *Server) Start(modelPath string) error {
 	return nil
 }
 
-// CalculateOptimalGPULayers berechnet die optimale Anzahl GPU-Layer basierend auf VRAM
-func (s *Server) CalculateOptimalGPULayers(modelPath string, requiredVRAM int64) int {
-	info := GetVRAMInfo()
-	if !info.Available {
+// CalculateOptimalGPULayers berechnet die optimale Anzahl GPU-Layer basierend auf VRAM.
+// Nutzt den VRAM-Planer (Gewichte je Layer, KV-Cache, Compute-Buffer).
+func (s *Server) CalculateOptimalGPULayers(modelPath string, contextSize int) int {
+	plan := s.PlanVRAM(modelPath, contextSize)
+	if !plan.GPUAvailable {
 		log.Printf("nvidia-smi nicht verfügbar, verwende Standard GPU-Layer: %d", s.config.GPULayers)
 		return s.config.GPULayers
 	}
-
-	// Verfügbarer VRAM minus Reserve
-	availableVRAM := info.FreeMB - int64(s.config.VRAMReserve)
-	if availableVRAM < 0 {
-		availableVRAM = 0
-	}
-
-	// Wenn genug VRAM vorhanden, alle Layer auf GPU
-	if availableVRAM >= requiredVRAM {
-		log.Printf("Genug VRAM verfügbar (%dMB >= %dMB), alle Layer auf GPU", availableVRAM, requiredVRAM)
-		return 99
-	}
-
-	// Anzahl Layer basierend auf Modellgröße schätzen
-	// Typische Layer-Anzahl pro Modellgröße:
-	// 7B = 32 Layer, 13B = 40 Layer, 70B = 80 Layer
-	modelInfo, _ := os.Stat(modelPath)
-	modelSizeGB := float64(modelInfo.Size()) / (1024 * 1024 * 1024)
-
-	var totalLayers int
-	switch {
-	case modelSizeGB < 3:
-		totalLayers = 24 // 1-3B Modelle
-	case modelSizeGB < 6:
-		totalLayers = 32 // 7B Modelle
-	case modelSizeGB < 10:
-		totalLayers = 40 // 13B Modelle
-	case modelSizeGB < 25:
-		totalLayers = 48 // 32B Modelle
-	default:
-		totalLayers = 80 // 70B+ Modelle
-	}
-
-	// Berechne Anteil der Layer die auf GPU passen
-	ratio := float64(availableVRAM) / float64(requiredVRAM)
-	if ratio > 1.0 {
-		ratio = 1.0
-	}
-
-	optimalLayers := int(float64(totalLayers) * ratio)
-	if optimalLayers < 1 {
-		optimalLayers = 1 // Mindestens 1 Layer auf GPU für Beschleunigung
-	}
-
-	log.Printf("SmartOffload: %dMB VRAM verfügbar, %dMB benötigt, %.0f%% auf GPU (%d von %d Layer)",
-		availableVRAM, requiredVRAM, ratio*100, optimalLayers, totalLayers)
-
-	return optimalLayers
+	logPlan(plan)
+	return plan.GPULayers
 }
 
 // Stop stoppt den llama-server (auch extern gestartete)
@@ -602,12 +611,16 @@ func (s *Server) Stop() error {
 			s.cmd.Process.Kill()
 		}
 		s.running = false
+		s.loras = nil
+		s.draftModel = ""
+		s.slotOwners = nil
 		log.Printf("llama-server gestoppt (intern)")
 	}
 
 	// Auch extern gestartete llama-server auf dem konfigurierten Port beenden
-	// Finde Prozess auf Port und beende ihn
-	if s.IsHealthy() {
+	// Finde Prozess auf Port und beende ihn (nicht bei Pool-Instanzen - der
+	// pkill-Fallback würde alle anderen geladenen Modelle mitnehmen)
+	if !s.pooled && s.IsHealthy() {
 		log.Printf("Extern gestarteter llama-server auf Port %d gefunden, beende...", s.config.Port)
 
 		// Finde PID des Prozesses auf dem Port
@@ -802,14 +815,30 @@ func EstimateModelVRAM(modelPath string) int64 {
 	return EstimateModelVRAMWithContext(modelPath, 8192) // Standard: 8K Context
 }
 
-// EstimateModelVRAMWithContext schätzt den VRAM-Bedarf mit spezifischem Context
+// EstimateModelVRAMWithContext schätzt den VRAM-Bedarf mit spezifischem Context.
+// Liest die Modell-Architektur aus dem GGUF-Header; nur wenn das nicht möglich ist,
+// wird aus Dateigröße und Dateiname geschätzt.
 func EstimateModelVRAMWithContext(modelPath string, contextSize int) int64 {
 	info, err := os.Stat(modelPath)
 	if err != nil {
 		return 6000 // Standard: 6GB für mittleres Modell
 	}
 
-	fileSizeMB := info.Size() / (1024 * 1024)
+	if meta, err := gguf.ReadInfo(modelPath); err == nil && meta.BlockCount > 0 && meta.TensorBytes > 0 {
+		plan := PlanGPUOffload(modelPath, PlanOptions{ContextSize: contextSize})
+		log.Printf("VRAM-Schätzung %s (GGUF: %s %s, %s): Gewichte=%.1fGB, Context=%d, Layers=%d, KV-Cache=%dMB, Compute=%dMB, Gesamt=%dMB (%.1fGB)",
+			plan.Model, meta.Architecture, meta.SizeLabel, meta.Quantization, float64(plan.WeightsMB)/1024,
+			contextSize, plan.TotalLayers, plan.KVCacheMB, plan.ComputeBufferMB, plan.RequiredMB, float64(plan.RequiredMB)/1024)
+		return plan.RequiredMB
+	}
+
+	return estimateVRAMFromFilename(modelPath, info.Size(), contextSize)
+}
+
+// estimateVRAMFromFilename schätzt den VRAM-Bedarf aus Dateigröße und Dateiname
+// (Fallback wenn der GGUF-Header nicht lesbar ist)
+func estimateVRAMFromFilename(modelPath string, fileSize int64, contextSize int) int64 {
+	fileSizeMB := fileSize / (1024 * 1024)
 	modelName := strings.ToLower(filepath.Base(modelPath))
 
 	// Modell-Parameter aus Dateiname schätzen
@@ -874,7 +903,7 @@ func EstimateModelVRAMWithContext(modelPath string, contextSize int) int64 {
 	}
 
 	// CUDA Overhead (Context, Scratch Buffer, etc.)
-	cudaOverheadMB := int64(800) // 800MB sicherere Schätzung
+	cudaOverheadMB := int64(heuristicOverheadMB) // 800MB sicherere Schätzung
 
 	// Gesamt: Modell + KV-Cache + Overhead
 	estimatedMB := int64(float64(fileSizeMB)*1.05) + kvCacheMB + cudaOverheadMB
@@ -905,32 +934,30 @@ func CheckVRAMAvailable(modelPath string, contextSize int) error {
 
 	if availableMB < requiredMB {
 		modelName := filepath.Base(modelPath)
-		modelNameLower := strings.ToLower(modelName)
-
-		// Spezifische Empfehlungen basierend auf Modell und Problem
-		var suggestion string
-		deficit := requiredMB - availableMB
-
-		if strings.Contains(modelNameLower, "q8") || strings.Contains(modelNameLower, "f16") {
-			suggestion = "Empfehlung: Lade die Q4_K_M Version (ca. 50% weniger VRAM)"
-		} else if strings.Contains(modelNameLower, "9b") || strings.Contains(modelNameLower, "13b") || strings.Contains(modelNameLower, "14b") {
-			suggestion = "Empfehlung: Lade ein 7B Modell oder eine kleinere Quantisierung (Q3_K_M, IQ4_XS)"
-		} else if contextSize > 4096 && deficit < 2000 {
-			suggestion = fmt.Sprintf("Empfehlung: Reduziere Context von %d auf 4096 (spart ~%.0f MB)", contextSize, float64(deficit)*0.8)
-		} else {
-			suggestion = "Optionen: 1) Kleinere Quantisierung (Q4_K_M, Q3_K_M), 2) Kleineres Modell (7B statt 9B), 3) Context reduzieren"
-		}
-
 		return &VRAMError{
 			Required:   requiredMB,
 			Available:  availableMB,
 			ModelName:  modelName,
-			Suggestion: suggestion,
+			Suggestion: vramSuggestion(modelName, contextSize, requiredMB-availableMB),
 		}
 	}
 	return nil
 }
 
+// vramSuggestion gibt eine Empfehlung basierend auf Modell und VRAM-Defizit zurück
+func vramSuggestion(modelName string, contextSize int, deficit int64) string {
+	modelNameLower := strings.ToLower(modelName)
+
+	if strings.Contains(modelNameLower, "q8") || strings.Contains(modelNameLower, "f16") {
+		return "Empfehlung: Lade die Q4_K_M Version (ca. 50% weniger VRAM)"
+	} else if strings.Contains(modelNameLower, "9b") || strings.Contains(modelNameLower, "13b") || strings.Contains(modelNameLower, "14b") {
+		return "Empfehlung: Lade ein 7B Modell oder eine kleinere Quantisierung (Q3_K_M, IQ4_XS)"
+	} else if contextSize > 4096 && deficit < 2000 {
+		return fmt.Sprintf("Empfehlung: Reduziere Context von %d auf 4096 (spart ~%.0f MB)", contextSize, float64(deficit)*0.8)
+	}
+	return "Optionen: 1) Kleinere Quantisierung (Q4_K_M, Q3_K_M), 2) Kleineres Modell (7B statt 9B), 3) Context reduzieren, 4) VRAM-Strategie Smart Offload"
+}
+
 // CheckVRAMAvailableWithTotalGPU prüft ob genug VRAM für das Modell verfügbar ist,
 // wobei der gesamte GPU-Speicher berücksichtigt wird (für Model-Switch Szenarien)
 func CheckVRAMAvailableWithTotalGPU(modelPath string, contextSize int) error {
@@ -985,15 +1012,18 @@ func (s *Server) GetStatus() Status {
 	running := s.running || healthy
 
 	return Status{
-		Running:     running,
-		Healthy:     healthy,
-		Port:        s.config.Port,
-		ModelName:   s.modelName,
-		ModelPath:   s.config.ModelPath,
-		BinaryPath:  s.config.BinaryPath,
-		BinaryFound: s.config.BinaryPath != "",
-		ContextSize: s.config.ContextSize,
-		GPULayers:   s.config.GPULayers,
+		Running:       running,
+		Healthy:       healthy,
+		Port:          s.config.Port,
+		ModelName:     s.modelName,
+		ModelPath:     s.config.ModelPath,
+		BinaryPath:    s.config.BinaryPath,
+		BinaryFound:   s.config.BinaryPath != "",
+		ContextSize:   s.config.ContextSize,
+		GPULayers:     s.config.GPULayers,
+		ParallelSlots: s.parallelSlotsLocked(),
+		Draft:         s.draftStatusLocked(),
+		Generation:    s.stats.snapshot(),
 	}
 }
 
@@ -1023,27 +1053,26 @@ func (s *Server) SwitchModel(modelPath string) error {
 		contextToUse = maxContext
 	}
 
-	// ERSTE PRÜFUNG: Passt das Modell überhaupt in den gesamten GPU-Speicher?
-	// Diese Prüfung verhindert, dass wir das aktuelle Modell stoppen nur um festzustellen,
-	// dass das neue Modell sowieso nicht passt.
-	if err := CheckVRAMAvailableWithTotalGPU(modelPath, contextToUse); err != nil {
-		log.Printf("VRAM-Fehler (Gesamtprüfung): %s", err.Error())
-		return err
-	}
-
-	// ZWEITE PRÜFUNG: Ist genug freier VRAM vorhanden?
-	if err := CheckVRAMAvailable(modelPath, contextToUse); err != nil {
-		// Bei VRAM-Fehler: Prüfen ob nach Stoppen des aktuellen Modells genug frei wäre
-		if vramErr, ok := err.(*VRAMError); ok {
-			currentModelSize := EstimateModelVRAMWithContext(s.config.ModelPath, s.config.ContextSize)
-			if vramErr.Available+currentModelSize >= vramErr.Required {
-				// Nach Stoppen des aktuellen Modells sollte es passen
-				log.Printf("VRAM-Warnung: %s - nach Stoppen des aktuellen Modells sollte es passen", err.Error())
-			} else {
-				// Auch nach Stoppen nicht genug VRAM
-				log.Printf("VRAM-Fehler: %s", err.Error())
-				return err
+	// VRAM-Plan für das neue Modell - so gerechnet, als wäre das aktuelle Modell
+	// bereits gestoppt. Damit stoppen wir das aktuelle Modell nicht, nur um
+	// festzustellen, dass das neue Modell sowieso nicht passt.
+	if plan := s.planForSwitch(modelPath, contextToUse); plan.GPUAvailable && !plan.FullOffload {
+		logPlan(plan)
+		switch {
+		case s.config.VRAMStrategy == StrategySmartOffload:
+			// Teil-Offload ist gewollt - Start() verwendet den Plan
+			log.Printf("VRAM-Plan: Teil-Offload mit -ngl %d", plan.GPULayers)
+		case s.config.VRAMStrategy == StrategyManual:
+			log.Printf("VRAM-Warnung: %s benötigt ~%dMB, Budget %dMB (Manual - keine Prüfung)", plan.Model, plan.RequiredMB, plan.BudgetMB)
+		default:
+			err := &VRAMError{
+				Required:   plan.RequiredMB,
+				Available:  plan.BudgetMB,
+				ModelName:  plan.Model,
+				Suggestion: vramSuggestion(plan.Model, contextToUse, plan.RequiredMB-plan.BudgetMB),
 			}
+			log.Printf("VRAM-Fehler: %s", err.Error())
+			return err
 		}
 	}
 
@@ -1078,8 +1107,13 @@ func (s *Server) WaitForHealthy(timeout time.Duration) error {
 	return fmt.Errorf("llama-server nicht bereit nach %v", timeout)
 }
 
-// GetModelMaxContext gibt den maximalen Context für ein Modell zurück
+// GetModelMaxContext gibt den maximalen Context für ein Modell zurück.
+// Primär aus dem GGUF-Header ({arch}.context_length), sonst anhand des Dateinamens.
 func GetModelMaxContext(modelPath string) int {
+	if meta, err := gguf.ReadInfo(modelPath); err == nil && meta.ContextLength > 0 {
+		return int(meta.ContextLength)
+	}
+
 	modelName := strings.ToLower(filepath.Base(modelPath))
 
 	// Bekannte Modelle mit kleineren Context-Limits
@@ -1239,20 +1273,58 @@ type ModelInfo struct {
 
 // Status enthält den Server-Status
 type Status struct {
-	Running     bool   `json:"running"`
-	Healthy     bool   `json:"healthy"`
-	Port        int    `json:"port"`
-	ModelName   string `json:"modelName"`
-	ModelPath   string `json:"modelPath"`
-	BinaryPath  string `json:"binaryPath"`
-	BinaryFound bool   `json:"binaryFound"`
-	ContextSize int    `json:"contextSize"`
-	GPULayers   int    `json:"gpuLayers"`
-}
-
-// DownloadModel lädt ein GGUF-Modell von Hugging Face herunter
-// Mit Resume-Unterstützung: Unterbrochene Downloads werden fortgesetzt
+	Running       bool   `json:"running"`
+	Healthy       bool   `json:"healthy"`
+	Port          int    `json:"port"`
+	ModelName     string `json:"modelName"`
+	ModelPath     string `json:"modelPath"`
+	BinaryPath    string `json:"binaryPath"`
+	BinaryFound   bool   `json:"binaryFound"`
+	ContextSize   int    `json:"contextSize"` // Context pro Slot
+	GPULayers     int    `json:"gpuLayers"`
+	ParallelSlots int    `json:"parallelSlots"`
+	// Speculative Decoding und gemessene Geschwindigkeit
+	Draft      *DraftStatus    `json:"draft,omitempty"`
+	Generation GenerationStats `json:"generation"`
+}
+
+// DownloadModel lädt ein GGUF-Modell von Hugging Face herunter und prüft die SHA-256
+// gegen die LFS-Metadaten von Hugging Face
 func (s *Server) DownloadModel(url, filename string, progressChan chan<- DownloadProgress) error {
+	return s.DownloadModelWithChecksum(url, filename, "", progressChan)
+}
+
+// DownloadModelWithChecksum lädt ein GGUF-Modell herunter und prüft es anschließend.
+// expectedSHA256 stammt z.B. aus der Modell-Registry; ist sie leer, wird die
+// Prüfsumme aus den LFS-Metadaten von Hugging Face verwendet.
+// Bei falscher Prüfsumme wird die Datei gelöscht.
+func (s *Server) DownloadModelWithChecksum(url, filename, expectedSHA256 string, progressChan chan<- DownloadProgress) error {
+	source := integrity.SourceRegistry
+	if integrity.NormalizeSHA256(expectedSHA256) == "" {
+		expectedSHA256 = integrity.FetchExpectedSHA256(url)
+		source = integrity.SourceHuggingFace
+	}
+
+	if err := s.downloadModelFile(url, filename, progressChan); err != nil {
+		return err
+	}
+
+	destPath := filepath.Join(s.config.ModelsDir, filename)
+	if progressChan != nil {
+		progressChan <- DownloadProgress{Percent: 100, Filename: filename, Verifying: true}
+	}
+	if _, err := integrity.VerifyDownload(destPath, expectedSHA256, source); err != nil {
+		log.Printf("❌ Download beschädigt, lösche %s: %v", filename, err)
+		os.Remove(destPath)
+		integrity.Forget(destPath)
+		return fmt.Errorf("Download-Prüfung fehlgeschlagen: %w", err)
+	}
+	return nil
+}
+
+// downloadModelFile lädt die Datei herunter (ohne Prüfung)
+// Mit Resume-Unterstützung: Unterbrochene Downloads werden fortgesetzt
+func (s *Server) downloadModelFile(url, filename string, progressChan chan<- DownloadProgress) error {
 	destPath := filepath.Join(s.config.ModelsDir, filename)
 	tempPath := destPath + ".downloading"
 
@@ -1516,6 +1588,7 @@ type DownloadProgress struct {
 	Speed      float64 `json:"speed,omitempty"`      // MB/s
 	Multi      bool    `json:"multi,omitempty"`      // True wenn Multi-Connection
 	Connections int    `json:"connections,omitempty"` // Anzahl parallele Verbindungen
+	Verifying   bool   `json:"verifying,omitempty"`   // True während die SHA-256 geprüft wird
 }
 
 // downloadModelMulti - Multi-Connection Download für große Dateien (8x schneller)
@@ -1971,6 +2044,63 @@ func (s *Server) RestartWithContextSize(newContextSize int) (int, error) {
 	return estimatedSeconds, nil
 }
 
+// SetParallelSlots setzt die Anzahl paralleler Slots (wirkt beim nächsten Start)
+func (s *Server) SetParallelSlots(slots int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.config.ParallelSlots = slots
+	log.Printf("Parallele Slots auf %d gesetzt (wirkt nach Neustart)", slots)
+}
+
+// GetParallelSlots gibt die konfigurierte Anzahl paralleler Slots zurück
+func (s *Server) GetParallelSlots() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.parallelSlotsLocked()
+}
+
+// parallelSlotsLocked gibt die Slot-Anzahl zurück (mindestens 1, alte Konfigurationen haben 0)
+func (s *Server) parallelSlotsLocked() int {
+	if s.config.ParallelSlots < 1 {
+		return 1
+	}
+	return s.config.ParallelSlots
+}
+
+// GetPoolConfig gibt die Konfiguration des Modell-Pools zurück
+func (s *Server) GetPoolConfig() PoolConfig {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.config.Pool
+}
+
+// SetPoolConfig setzt die Konfiguration des Modell-Pools (wirkt beim nächsten Laden)
+func (s *Server) SetPoolConfig(config PoolConfig) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.config.Pool = config
+}
+
+// acquireSlot reiht eine Anfrage in die Slot-Warteschlange ein.
+// Der zurückgegebene Context trägt den zugeteilten Slot-Index (siehe prepareSlotCache).
+func (s *Server) acquireSlot(ctx context.Context) (context.Context, func(), error) {
+	info := requestInfoFromContext(ctx)
+	slot, release, err := s.queue.AcquireSlot(ctx, info, s.preferredSlot(ctx))
+	if err != nil {
+		if errors.Is(err, ErrQueueFull) {
+			log.Printf("⚠️ Anfrage von %s abgelehnt: %v", info.User, err)
+			return ctx, nil, err
+		}
+		return ctx, nil, fmt.Errorf("Warten auf freien Slot abgebrochen: %w", err)
+	}
+	return context.WithValue(ctx, grantedSlotKey{}, slot), release, nil
+}
+
+// QueueMetrics gibt die Kennzahlen der Slot-Warteschlange zurück
+func (s *Server) QueueMetrics() QueueMetrics {
+	return s.queue.Metrics()
+}
+
 // SetGPULayers setzt die Anzahl der GPU-Layers
 func (s *Server) SetGPULayers(layers int) {
 	s.mu.Lock()
@@ -2005,6 +2135,10 @@ func (s *Server) GetVRAMSettings() VRAMSettings {
 type ChatMessage struct {
 	Role    string `json:"role"`
 	Content string `json:"content"`
+
+	// Tool-Calling: Aufrufe der Assistenten-Nachricht bzw. Bezug der "tool"-Antwort
+	ToolCalls  []ToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string     `json:"tool_call_id,omitempty"`
 }
 
 // SamplingParams enthält die Sampling-Parameter für LLM-Anfragen
@@ -2037,6 +2171,36 @@ type ToolCall struct {
 	} `json:"function"`
 }
 
+// toolCallModels sind Modell-Familien deren Chat-Template (--jinja) natives Function Calling unterstützt
+var toolCallModels = []string{
+	"qwen2.5", "qwen-2.5", "qwen3", "qwq",
+	"llama-3.1", "llama3.1", "llama-3.2", "llama3.2", "llama-3.3", "llama3.3",
+	"mistral-nemo", "mistral-small", "ministral",
+	"hermes", "functionary", "firefunction", "command-r", "granite",
+}
+
+// SupportsToolCalling prüft anhand des Modellnamens ob das Modell Tool-Aufrufe beherrscht
+func SupportsToolCalling(modelName string) bool {
+	name := strings.ToLower(modelName)
+	for _, family := range toolCallModels {
+		if strings.Contains(name, family) {
+			return true
+		}
+	}
+	return false
+}
+
+// toolCallDelta ist ein Teilstück eines Tool-Aufrufs im Stream
+type toolCallDelta struct {
+	Index    int    `json:"index"`
+	ID       string `json:"id"`
+	Type     string `json:"type"`
+	Function struct {
+		Name      string `json:"name"`
+		Arguments string `json:"arguments"`
+	} `json:"function"`
+}
+
 // ChatResponse repräsentiert eine vollständige Chat-Antwort (nicht-streaming)
 type ChatResponse struct {
 	Content      string     `json:"content"`
@@ -2224,10 +2388,29 @@ func (s *Server) StreamChat(messages []ChatMessage, onChunk func(content string,
 
 // StreamChatWithParams sendet eine Chat-Anfrage mit expliziten Sampling-Parametern
 func (s *Server) StreamChatWithParams(messages []ChatMessage, params SamplingParams, onChunk func(content string, done bool)) error {
+	return s.StreamChatWithContext(context.Background(), messages, params, onChunk)
+}
+
+// StreamChatWithContext sendet eine Chat-Anfrage die über ctx abgebrochen werden kann.
+//
+// Bei Abbruch (Cancel-Endpoint oder Client-Disconnect) wird die HTTP-Verbindung zum
+// llama-server geschlossen. Der llama-server erkennt das beim nächsten Token, bricht
+// die Generierung ab und gibt den Slot sofort frei.
+// Der zurückgegebene Fehler enthält dann ctx.Err() (prüfbar mit errors.Is).
+//
+// Die Anfrage wartet vorher in der Slot-Warteschlange; Priorität und Benutzer
+// kommen aus WithRequestInfo (ohne Angabe: Hintergrund-Job).
+func (s *Server) StreamChatWithContext(ctx context.Context, messages []ChatMessage, params SamplingParams, onChunk func(content string, done bool)) error {
 	if !s.IsRunning() || !s.IsHealthy() {
 		return fmt.Errorf("llama-server ist nicht aktiv")
 	}
 
+	ctx, release, err := s.acquireSlot(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+
 	// Defaults setzen falls nicht gesetzt
 	if params.Temperature == 0 {
 		params.Temperature = 0.7
@@ -2251,6 +2434,12 @@ func (s *Server) StreamChatWithParams(messages []ChatMessage, params SamplingPar
 		"top_p":       params.TopP,
 		"max_tokens":  params.MaxTokens,
 	}
+	s.applyLora(ctx, requestBody)
+
+	// Prompt-Cache: Slot-Zustand vorher laden, nach erfolgreicher Antwort speichern
+	finishSlot := s.prepareSlotCache(ctx, requestBody)
+	streamed := false
+	defer func() { finishSlot(streamed) }()
 
 	jsonBody, err := json.Marshal(requestBody)
 	if err != nil {
@@ -2258,7 +2447,7 @@ func (s *Server) StreamChatWithParams(messages []ChatMessage, params SamplingPar
 	}
 
 	url := fmt.Sprintf("http://localhost:%d/v1/chat/completions", s.config.Port)
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonBody))
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonBody))
 	if err != nil {
 		return fmt.Errorf("Request-Fehler: %w", err)
 	}
@@ -2267,6 +2456,9 @@ func (s *Server) StreamChatWithParams(messages []ChatMessage, params SamplingPar
 	client := &http.Client{Timeout: 5 * time.Minute} // Längeres Timeout für Streaming
 	resp, err := client.Do(req)
 	if err != nil {
+		if ctx.Err() != nil {
+			return fmt.Errorf("Generierung abgebrochen: %w", ctx.Err())
+		}
 		return fmt.Errorf("llama-server nicht erreichbar: %w", err)
 	}
 	defer resp.Body.Close()
@@ -2284,6 +2476,9 @@ func (s *Server) StreamChatWithParams(messages []ChatMessage, params SamplingPar
 			if err == io.EOF {
 				break
 			}
+			if ctx.Err() != nil {
+				return fmt.Errorf("Generierung abgebrochen: %w", ctx.Err())
+			}
 			return fmt.Errorf("Stream-Lesefehler: %w", err)
 		}
 
@@ -2311,11 +2506,13 @@ func (s *Server) StreamChatWithParams(messages []ChatMessage, params SamplingPar
 				} `json:"delta"`
 				FinishReason *string `json:"finish_reason"`
 			} `json:"choices"`
+			Timings *llamaTimings `json:"timings"` // Nur im letzten Chunk
 		}
 
 		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
 			continue // Ungültige JSON-Zeilen ignorieren
 		}
+		s.stats.record(chunk.Timings)
 
 		if len(chunk.Choices) > 0 {
 			content := chunk.Choices[0].Delta.Content
@@ -2326,17 +2523,37 @@ func (s *Server) StreamChatWithParams(messages []ChatMessage, params SamplingPar
 		}
 	}
 
+	streamed = true
 	return nil
 }
 
 // StreamChatWithTools sendet eine Chat-Anfrage mit Tool-Support
-// Gibt Content und eventuelle ToolCalls zurück
-func (s *Server) StreamChatWithTools(messages []ChatMessage, tools []Tool, onChunk func(content string, done bool)) (*ChatResponse, error) {
+// Gibt Content und eventuelle ToolCalls zurück.
+//
+// Wie StreamChatWithContext: wartet mit Priorität aus WithRequestInfo in der
+// Slot-Warteschlange, ist über ctx abbrechbar und berücksichtigt WithLora und WithSlotCache.
+func (s *Server) StreamChatWithTools(ctx context.Context, messages []ChatMessage, params SamplingParams, tools []Tool, onChunk func(content string, done bool)) (*ChatResponse, error) {
 	if !s.IsRunning() || !s.IsHealthy() {
 		return nil, fmt.Errorf("llama-server ist nicht aktiv")
 	}
 
-	params := DefaultSamplingParams()
+	ctx, release, err := s.acquireSlot(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	// Defaults setzen falls nicht gesetzt
+	defaults := DefaultSamplingParams()
+	if params.Temperature == 0 {
+		params.Temperature = defaults.Temperature
+	}
+	if params.TopP == 0 {
+		params.TopP = defaults.TopP
+	}
+	if params.MaxTokens == 0 {
+		params.MaxTokens = defaults.MaxTokens
+	}
 
 	// Für Gemma-Modelle: System-Prompt in User-Nachricht einbetten
 	processedMessages := s.adaptMessagesForModel(messages)
@@ -2355,6 +2572,12 @@ func (s *Server) StreamChatWithTools(messages []ChatMessage, tools []Tool, onChu
 		requestBody["tools"] = tools
 		requestBody["tool_choice"] = "auto" // LLM entscheidet selbst
 	}
+	s.applyLora(ctx, requestBody)
+
+	// Prompt-Cache: Slot-Zustand vorher laden, nach erfolgreicher Antwort speichern
+	finishSlot := s.prepareSlotCache(ctx, requestBody)
+	streamed := false
+	defer func() { finishSlot(streamed) }()
 
 	jsonBody, err := json.Marshal(requestBody)
 	if err != nil {
@@ -2362,7 +2585,7 @@ func (s *Server) StreamChatWithTools(messages []ChatMessage, tools []Tool, onChu
 	}
 
 	url := fmt.Sprintf("http://localhost:%d/v1/chat/completions", s.config.Port)
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonBody))
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonBody))
 	if err != nil {
 		return nil, fmt.Errorf("Request-Fehler: %w", err)
 	}
@@ -2371,6 +2594,9 @@ func (s *Server) StreamChatWithTools(messages []ChatMessage, tools []Tool, onChu
 	client := &http.Client{Timeout: 5 * time.Minute}
 	resp, err := client.Do(req)
 	if err != nil {
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("Generierung abgebrochen: %w", ctx.Err())
+		}
 		return nil, fmt.Errorf("llama-server nicht erreichbar: %w", err)
 	}
 	defer resp.Body.Close()
@@ -2384,6 +2610,7 @@ func (s *Server) StreamChatWithTools(messages []ChatMessage, tools []Tool, onChu
 	response := &ChatResponse{}
 	var contentBuilder strings.Builder
 	var toolCalls []ToolCall
+	toolCallIndex := make(map[int]int) // Stream-Index -> Position in toolCalls
 
 	// SSE Stream lesen
 	reader := bufio.NewReader(resp.Body)
@@ -2393,6 +2620,9 @@ func (s *Server) StreamChatWithTools(messages []ChatMessage, tools []Tool, onChu
 			if err == io.EOF {
 				break
 			}
+			if ctx.Err() != nil {
+				return nil, fmt.Errorf("Generierung abgebrochen: %w", ctx.Err())
+			}
 			return nil, fmt.Errorf("Stream-Lesefehler: %w", err)
 		}
 
@@ -2417,16 +2647,18 @@ func (s *Server) StreamChatWithTools(messages []ChatMessage, tools []Tool, onChu
 		var chunk struct {
 			Choices []struct {
 				Delta struct {
-					Content   string     `json:"content"`
-					ToolCalls []ToolCall `json:"tool_calls"`
+					Content   string          `json:"content"`
+					ToolCalls []toolCallDelta `json:"tool_calls"`
 				} `json:"delta"`
 				FinishReason *string `json:"finish_reason"`
 			} `json:"choices"`
+			Timings *llamaTimings `json:"timings"` // Nur im letzten Chunk
 		}
 
 		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
 			continue
 		}
+		s.stats.record(chunk.Timings)
 
 		if len(chunk.Choices) > 0 {
 			choice := chunk.Choices[0]
@@ -2439,9 +2671,23 @@ func (s *Server) StreamChatWithTools(messages []ChatMessage, tools []Tool, onChu
 				}
 			}
 
-			// Tool-Calls sammeln
-			if len(choice.Delta.ToolCalls) > 0 {
-				toolCalls = append(toolCalls, choice.Delta.ToolCalls...)
+			// Tool-Calls sammeln: Name und Argumente kommen in Teilstücken pro Index
+			for _, delta := range choice.Delta.ToolCalls {
+				pos, ok := toolCallIndex[delta.Index]
+				if !ok {
+					pos = len(toolCalls)
+					toolCallIndex[delta.Index] = pos
+					toolCalls = append(toolCalls, ToolCall{Type: "function"})
+				}
+				call := &toolCalls[pos]
+				if delta.ID != "" {
+					call.ID = delta.ID
+				}
+				if delta.Type != "" {
+					call.Type = delta.Type
+				}
+				call.Function.Name += delta.Function.Name
+				call.Function.Arguments += delta.Function.Arguments
 			}
 
 			// Finish Reason
@@ -2454,19 +2700,25 @@ func (s *Server) StreamChatWithTools(messages []ChatMessage, tools []Tool, onChu
 	response.Content = contentBuilder.String()
 	response.ToolCalls = toolCalls
 
+	streamed = true
 	return response, nil
 }
 
 // QuickChat führt einen einfachen, nicht-streamenden Chat durch
 // Ideal für kurze Anfragen wie Query-Optimierung
 func (s *Server) QuickChat(systemPrompt, userMessage string) (string, error) {
+	return s.QuickChatWithContext(context.Background(), systemPrompt, userMessage)
+}
+
+// QuickChatWithContext wie QuickChat, abbrechbar über ctx (inkl. Wartezeit in der Warteschlange)
+func (s *Server) QuickChatWithContext(ctx context.Context, systemPrompt, userMessage string) (string, error) {
 	messages := []ChatMessage{
 		{Role: "system", Content: systemPrompt},
 		{Role: "user", Content: userMessage},
 	}
 
 	var result strings.Builder
-	err := s.StreamChat(messages, func(content string, done bool) {
+	err := s.StreamChatWithContext(ctx, messages, DefaultSamplingParams(), func(content string, done bool) {
 		result.WriteString(content)
 	})
 
@@ -2483,30 +2735,15 @@ func (s *Server) QuickChatWithTimeout(systemPrompt, userMessage string, timeout
 		return "", fmt.Errorf("llama-server ist nicht aktiv")
 	}
 
-	// Request mit Timeout
+	// Request mit Timeout - bricht auch das Warten auf einen Slot ab
 	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
 
-	resultChan := make(chan string, 1)
-	errChan := make(chan error, 1)
-
-	go func() {
-		result, err := s.QuickChat(systemPrompt, userMessage)
-		if err != nil {
-			errChan <- err
-			return
-		}
-		resultChan <- result
-	}()
-
-	select {
-	case result := <-resultChan:
-		return result, nil
-	case err := <-errChan:
-		return "", err
-	case <-ctx.Done():
+	result, err := s.QuickChatWithContext(ctx, systemPrompt, userMessage)
+	if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
 		return "", fmt.Errorf("timeout nach %v", timeout)
 	}
+	return result, err
 }
 
 // ===== Vision/Multimodal Support =====
@@ -1,11 +1,19 @@
 package llamaserver
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
+
+	"fleet-navigator/internal/gguf"
 )
 
 // createFakeGGUF erstellt eine Fake-GGUF-Datei mit der GGUF Magic Number
@@ -698,6 +706,43 @@ func TestGetModelMaxContext(t *testing.T) {
 	}
 }
 
+// TestGGUFHeaderOverridesFilename testet dass Metadaten aus dem GGUF-Header
+// Vorrang vor der Dateinamen-Heuristik haben
+func TestGGUFHeaderOverridesFilename(t *testing.T) {
+	// Dateiname deutet auf Gemma 2 (8K) hin, Header sagt 32K
+	modelPath := filepath.Join(t.TempDir(), "gemma-2-9b-it-q4_k_m.gguf")
+	f, err := os.Create(modelPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = gguf.Write(f, []gguf.KV{
+		{Key: "general.architecture", Value: "llama"},
+		{Key: "llama.context_length", Value: uint32(32768)},
+		{Key: "llama.block_count", Value: uint32(32)},
+		{Key: "llama.embedding_length", Value: uint32(4096)},
+		{Key: "llama.attention.head_count", Value: uint32(32)},
+		{Key: "llama.attention.head_count_kv", Value: uint32(8)},
+	}, []gguf.TensorInfo{
+		// 4 GiB F16-Gewichte (nur Tensor-Tabelle, keine Daten)
+		{Name: "blk.0.ffn.weight", Dimensions: []uint64{1 << 16, 1 << 15}, Type: 1},
+	})
+	f.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := GetModelMaxContext(modelPath); got != 32768 {
+		t.Errorf("GetModelMaxContext = %d, erwartet 32768 aus dem Header", got)
+	}
+
+	// Gewichte 4096MB + KV-Cache 1024MB (8192 * 32 * 8 * 256 * 2 Bytes)
+	// + Compute-Buffer 512MB (8192 * 512 * 32 Heads * 4 Bytes) + 350MB CUDA-Kontext
+	want := int64(4096 + 1024 + 512 + cudaContextMB)
+	if got := EstimateModelVRAMWithContext(modelPath, 8192); got != want {
+		t.Errorf("EstimateModelVRAMWithContext = %d MB, erwartet %d", got, want)
+	}
+}
+
 // TestVRAMError_ErrorMessage testet die Fehlerformatierung
 func TestVRAMError_ErrorMessage(t *testing.T) {
 	err := &VRAMError{
@@ -839,3 +884,126 @@ func TestEstimateModelVRAM_FunctionExists(t *testing.T) {
 		t.Errorf("EstimateModelVRAM sollte 6000 für nicht-existente Datei zurückgeben, bekam: %d", result)
 	}
 }
+
+// newFakeLlamaServer startet einen Fake-llama-server (health + streaming completions)
+// und gibt einen Server zurück der darauf zeigt
+func newFakeLlamaServer(t *testing.T, completions http.HandlerFunc) *Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	mux.HandleFunc("/v1/chat/completions", completions)
+	ts := httptest.NewServer(mux)
+	t.Cleanup(ts.Close)
+
+	port, _ := strconv.Atoi(ts.URL[strings.LastIndex(ts.URL, ":")+1:])
+	s := NewServer(Config{Port: port})
+	s.running = true
+	return s
+}
+
+// TestStreamChatWithContext_Cancel testet dass ein Abbruch die Generierung stoppt
+func TestStreamChatWithContext_Cancel(t *testing.T) {
+	clientGone := make(chan struct{})
+	s := newFakeLlamaServer(t, func(w http.ResponseWriter, r *http.Request) {
+		flusher := w.(http.Flusher)
+		for i := 0; ; i++ {
+			select {
+			case <-r.Context().Done():
+				// Verbindung geschlossen = llama-server gibt den Slot frei
+				close(clientGone)
+				return
+			case <-time.After(10 * time.Millisecond):
+			}
+			fmt.Fprintf(w, "data: {\"choices\":[{\"delta\":{\"content\":\"t%d \"}}]}\n\n", i)
+			flusher.Flush()
+		}
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var received strings.Builder
+	err := s.StreamChatWithContext(ctx, []ChatMessage{{Role: "user", Content: "Hallo"}}, DefaultSamplingParams(),
+		func(content string, done bool) {
+			received.WriteString(content)
+			if strings.Count(received.String(), " ") >= 3 {
+				cancel()
+			}
+		})
+
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Erwartet context.Canceled, bekam: %v", err)
+	}
+	if received.Len() == 0 {
+		t.Error("Teilantwort sollte vor dem Abbruch empfangen worden sein")
+	}
+
+	select {
+	case <-clientGone:
+	case <-time.After(2 * time.Second):
+		t.Error("Fake-Server hat den Verbindungsabbruch nicht bemerkt")
+	}
+}
+
+// TestStreamChatWithContext_Complete testet den normalen Ablauf ohne Abbruch
+func TestStreamChatWithContext_Complete(t *testing.T) {
+	s := newFakeLlamaServer(t, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"content\":\"Hallo\"}}]}\n\n")
+		fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"content\":\" Welt\"},\"finish_reason\":\"stop\"}]}\n\n")
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	})
+
+	var result strings.Builder
+	err := s.StreamChatWithContext(context.Background(), []ChatMessage{{Role: "user", Content: "Hi"}}, DefaultSamplingParams(),
+		func(content string, done bool) { result.WriteString(content) })
+	if err != nil {
+		t.Fatalf("Unerwarteter Fehler: %v", err)
+	}
+	if result.String() != "Hallo Welt" {
+		t.Errorf("Antwort = %q, erwartet 'Hallo Welt'", result.String())
+	}
+}
+
+// TestStreamChatWithTools_Cancel testet dass Tool-Chats über ctx abbrechbar sind
+func TestStreamChatWithTools_Cancel(t *testing.T) {
+	s := newFakeLlamaServer(t, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"content\":\"Hallo\"}}]}\n\n")
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	_, err := s.StreamChatWithTools(ctx, []ChatMessage{{Role: "user", Content: "Hi"}}, DefaultSamplingParams(), nil,
+		func(content string, done bool) {
+			if content != "" {
+				cancel()
+			}
+		})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Erwartet context.Canceled, bekam: %v", err)
+	}
+}
+
+// TestStreamChatWithTools_ToolCallDeltas testet das Zusammensetzen gestreamter Tool-Aufrufe
+func TestStreamChatWithTools_ToolCallDeltas(t *testing.T) {
+	s := newFakeLlamaServer(t, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `data: {"choices":[{"delta":{"tool_calls":[{"index":0,"id":"c1","type":"function","function":{"name":"observer_latest","arguments":"{\"codes\":"}}]}}]}`+"\n\n")
+		fmt.Fprint(w, `data: {"choices":[{"delta":{"tool_calls":[{"index":0,"function":{"arguments":"[\"DAX\"]}"}}]}}]}`+"\n\n")
+		fmt.Fprint(w, `data: {"choices":[{"delta":{"tool_calls":[{"index":1,"id":"c2","function":{"name":"observer_history","arguments":"{}"}}]},"finish_reason":"tool_calls"}]}`+"\n\n")
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	})
+
+	resp, err := s.StreamChatWithTools(context.Background(), []ChatMessage{{Role: "user", Content: "DAX?"}}, DefaultSamplingParams(), nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.FinishReason != "tool_calls" || len(resp.ToolCalls) != 2 {
+		t.Fatalf("Antwort: %+v", resp)
+	}
+	if c := resp.ToolCalls[0]; c.ID != "c1" || c.Function.Name != "observer_latest" || c.Function.Arguments != `{"codes":["DAX"]}` {
+		t.Errorf("Erster Aufruf: %+v", c)
+	}
+	if c := resp.ToolCalls[1]; c.ID != "c2" || c.Type != "function" || c.Function.Name != "observer_history" {
+		t.Errorf("Zweiter Aufruf: %+v", c)
+	}
+}
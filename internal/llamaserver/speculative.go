@@ -0,0 +1,533 @@
+package llamaserver
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"fleet-navigator/internal/gguf"
+)
+
+// =============================================================================
+// SPECULATIVE DECODING
+// =============================================================================
+//
+// Ein kleines Draft-Modell mit identischem Tokenizer schlägt mehrere Tokens vor,
+// das Hauptmodell prüft sie in einem Durchgang. Bei hoher Akzeptanz steigt die
+// Generierungsgeschwindigkeit deutlich - vor allem auf CPU-lastigen Systemen.
+
+const (
+	DefaultDraftMax  = 16  // --draft-max: maximale Tokens pro Entwurf
+	DefaultDraftPMin = 0.8 // --draft-p-min: minimale Wahrscheinlichkeit für Draft-Tokens
+
+	// Draft-Modelle müssen deutlich kleiner sein, sonst bringt Speculative Decoding nichts
+	draftMaxSizeRatio = 4
+)
+
+// DraftConfig konfiguriert Speculative Decoding mit einem Draft-Modell
+type DraftConfig struct {
+	Enabled   bool    `json:"enabled"`
+	ModelPath string  `json:"modelPath"` // -md: leer = passendes kleines Modell automatisch wählen
+	Max       int     `json:"max"`       // --draft-max
+	Min       int     `json:"min"`       // --draft-min (0 = llama.cpp-Standard)
+	PMin      float64 `json:"pMin"`      // --draft-p-min (0 = llama.cpp-Standard)
+	GPULayers int     `json:"gpuLayers"` // -ngld (-1 = automatisch: GPU wenn Hauptmodell auf GPU)
+}
+
+// DefaultDraftConfig gibt die Standard-Konfiguration zurück (deaktiviert)
+func DefaultDraftConfig() DraftConfig {
+	return DraftConfig{
+		Max:       DefaultDraftMax,
+		PMin:      DefaultDraftPMin,
+		GPULayers: -1,
+	}
+}
+
+// DraftStatus beschreibt das geladene Draft-Modell
+type DraftStatus struct {
+	Enabled   bool    `json:"enabled"`
+	Model     string  `json:"model,omitempty"` // Dateiname, leer = kein passendes Modell
+	ModelPath string  `json:"modelPath,omitempty"`
+	Auto      bool    `json:"auto"` // Automatisch gewählt
+	Max       int     `json:"max"`
+	Min       int     `json:"min"`
+	PMin      float64 `json:"pMin"`
+}
+
+// DraftCandidate ist ein installiertes Modell, bewertet als Draft für ein Hauptmodell
+type DraftCandidate struct {
+	Name       string `json:"name"`
+	Path       string `json:"path"`
+	SizeBytes  int64  `json:"sizeBytes"`
+	Compatible bool   `json:"compatible"`
+	Reason     string `json:"reason,omitempty"` // Grund, falls nicht geeignet
+}
+
+// GetDraftConfig gibt die Draft-Konfiguration zurück
+func (s *Server) GetDraftConfig() DraftConfig {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.config.Draft
+}
+
+// SetDraftConfig setzt die Draft-Konfiguration (wirksam ab dem nächsten Start)
+func (s *Server) SetDraftConfig(config DraftConfig) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.config.Draft = config
+
+	s.draftMu.Lock()
+	s.draftPairs = nil
+	s.draftMu.Unlock()
+}
+
+// draftPair merkt sich das automatisch gewählte Draft-Modell eines Hauptmodells
+type draftPair struct {
+	models string // Signatur der Modell-Liste bei der Wahl
+	path   string // "" = kein passendes Modell installiert
+}
+
+// modelListSignature beschreibt die installierten Modelle (Pfad, Größe, Änderungszeit)
+func modelListSignature(models []ModelInfo) string {
+	var b strings.Builder
+	for _, m := range models {
+		fmt.Fprintf(&b, "%s|%d|%d\n", m.Path, m.Size, m.Modified.UnixNano())
+	}
+	return b.String()
+}
+
+// DraftCandidates bewertet alle installierten Modelle als Draft für das Hauptmodell.
+// Geeignete Modelle stehen vorne, das kleinste zuerst.
+func (s *Server) DraftCandidates(targetPath string) ([]DraftCandidate, error) {
+	models, err := s.GetAvailableModels()
+	if err != nil {
+		return nil, err
+	}
+	return draftCandidates(targetPath, models)
+}
+
+// draftCandidates bewertet die übergebenen Modelle als Draft für das Hauptmodell
+func draftCandidates(targetPath string, models []ModelInfo) ([]DraftCandidate, error) {
+	target, err := gguf.ReadInfo(targetPath)
+	if err != nil {
+		return nil, fmt.Errorf("Modell-Header lesen fehlgeschlagen: %w", err)
+	}
+
+	candidates := make([]DraftCandidate, 0, len(models))
+	for _, m := range models {
+		if m.Path == targetPath || isMmprojFile(m.Name) {
+			continue
+		}
+		c := DraftCandidate{Name: m.Name, Path: m.Path, SizeBytes: m.Size}
+		info, err := gguf.ReadInfo(m.Path)
+		switch {
+		case err != nil:
+			c.Reason = "GGUF-Header nicht lesbar"
+		case info.TensorBytes*draftMaxSizeRatio > target.TensorBytes:
+			c.Reason = fmt.Sprintf("zu groß (mindestens %dx kleiner als das Hauptmodell nötig)", draftMaxSizeRatio)
+		default:
+			if err := gguf.CheckDraftCompatibility(info, target); err != nil {
+				c.Reason = err.Error()
+			} else {
+				c.Compatible = true
+			}
+		}
+		candidates = append(candidates, c)
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].Compatible != candidates[j].Compatible {
+			return candidates[i].Compatible
+		}
+		return candidates[i].SizeBytes < candidates[j].SizeBytes
+	})
+	return candidates, nil
+}
+
+// FindDraftModel wählt automatisch das kleinste passende Draft-Modell. Die Wahl wird je
+// Hauptmodell zwischengespeichert, bis sich die installierten Modelle ändern - sonst
+// würden bei jeder VRAM-Planung die Header aller Modelle neu gelesen.
+func (s *Server) FindDraftModel(targetPath string) (string, error) {
+	models, err := s.GetAvailableModels()
+	if err != nil {
+		return "", err
+	}
+	signature := modelListSignature(models)
+
+	s.draftMu.Lock()
+	pair, ok := s.draftPairs[targetPath]
+	s.draftMu.Unlock()
+
+	if !ok || pair.models != signature {
+		candidates, err := draftCandidates(targetPath, models)
+		if err != nil {
+			return "", err
+		}
+		pair = draftPair{models: signature}
+		if len(candidates) > 0 && candidates[0].Compatible {
+			pair.path = candidates[0].Path
+		}
+		s.draftMu.Lock()
+		if s.draftPairs == nil {
+			s.draftPairs = make(map[string]draftPair)
+		}
+		s.draftPairs[targetPath] = pair
+		s.draftMu.Unlock()
+	}
+
+	if pair.path == "" {
+		return "", fmt.Errorf("kein passendes Draft-Modell für %s installiert", filepath.Base(targetPath))
+	}
+	return pair.path, nil
+}
+
+// isMmprojFile erkennt Vision-Projektoren, die keine eigenständigen Modelle sind
+func isMmprojFile(name string) bool {
+	return strings.Contains(strings.ToLower(name), "mmproj")
+}
+
+// draftReserveMB schätzt den VRAM-Bedarf des Draft-Modells (Gewichte, KV-Cache und
+// Compute-Buffer) für den Offload-Plan des Hauptmodells. cfg ist eine Kopie der
+// Draft-Konfiguration, opts sind die Plan-Optionen des Hauptmodells -
+// der Draft bekommt je Slot einen eigenen Context gleicher Größe.
+func (s *Server) draftReserveMB(modelPath string, cfg DraftConfig, opts PlanOptions) int64 {
+	if !cfg.Enabled || cfg.GPULayers == 0 {
+		return 0
+	}
+	draftPath := cfg.ModelPath
+	if draftPath == "" {
+		path, err := s.FindDraftModel(modelPath)
+		if err != nil {
+			return 0
+		}
+		draftPath = path
+	}
+
+	opts.GPUAvailable = false
+	opts.ExtraMB = 0
+	plan := PlanGPUOffload(draftPath, opts)
+	// Der CUDA-Kontext wird mit dem Hauptmodell geteilt
+	return max(plan.RequiredMB-plan.OverheadMB, 0)
+}
+
+// draftArgsLocked erzeugt die Start-Argumente für Speculative Decoding
+func (s *Server) draftArgsLocked(modelPath string, gpuLayers int) []string {
+	s.draftModel = ""
+	s.draftAuto = false
+	cfg := s.config.Draft
+	if !cfg.Enabled {
+		return nil
+	}
+
+	draftPath := cfg.ModelPath
+	if draftPath == "" {
+		path, err := s.FindDraftModel(modelPath)
+		if err != nil {
+			log.Printf("⚠️ Speculative Decoding: %v", err)
+			return nil
+		}
+		draftPath = path
+		s.draftAuto = true
+	} else {
+		// Explizites Draft-Modell trotzdem prüfen - llama-server startet sonst nicht
+		draft, err := gguf.ReadInfo(draftPath)
+		if err == nil {
+			var target *gguf.ModelInfo
+			if target, err = gguf.ReadInfo(modelPath); err == nil {
+				err = gguf.CheckDraftCompatibility(draft, target)
+			}
+		}
+		if err != nil {
+			log.Printf("⚠️ Draft-Modell %s übersprungen: %v", filepath.Base(draftPath), err)
+			return nil
+		}
+	}
+
+	draftMax := cfg.Max
+	if draftMax <= 0 {
+		draftMax = DefaultDraftMax
+	}
+	ngld := cfg.GPULayers
+	if ngld < 0 {
+		ngld = 0
+		if gpuLayers > 0 {
+			ngld = 99 // Ganz auf die GPU, wenn das Hauptmodell dort liegt (im VRAM-Plan reserviert, siehe draftReserveMB)
+		}
+	}
+
+	args := []string{
+		"-md", draftPath,
+		"--draft-max", fmt.Sprintf("%d", draftMax),
+		"-ngld", fmt.Sprintf("%d", ngld),
+	}
+	if cfg.Min > 0 {
+		args = append(args, "--draft-min", fmt.Sprintf("%d", cfg.Min))
+	}
+	if cfg.PMin > 0 {
+		args = append(args, "--draft-p-min", fmt.Sprintf("%.2f", cfg.PMin))
+	}
+
+	s.draftModel = draftPath
+	log.Printf("⚡ Speculative Decoding: Draft-Modell %s (max %d Tokens, auto: %v)", filepath.Base(draftPath), draftMax, s.draftAuto)
+	return args
+}
+
+// draftStatusLocked gibt den Draft-Status für GetStatus zurück
+func (s *Server) draftStatusLocked() *DraftStatus {
+	cfg := s.config.Draft
+	if !cfg.Enabled {
+		return nil
+	}
+	status := &DraftStatus{
+		Enabled:   true,
+		ModelPath: s.draftModel,
+		Auto:      s.draftAuto,
+		Max:       cfg.Max,
+		Min:       cfg.Min,
+		PMin:      cfg.PMin,
+	}
+	if s.draftModel != "" {
+		status.Model = filepath.Base(s.draftModel)
+	}
+	return status
+}
+
+// =============================================================================
+// Generierungs-Statistik (aus den "timings" der llama-server-Antworten)
+// =============================================================================
+
+// llamaTimings sind die Zeitmessungen, die llama-server mit jeder Antwort liefert
+type llamaTimings struct {
+	PromptN        int     `json:"prompt_n"`
+	PromptMs       float64 `json:"prompt_ms"`
+	PredictedN     int     `json:"predicted_n"`
+	PredictedMs    float64 `json:"predicted_ms"`
+	DraftN         int     `json:"draft_n"`
+	DraftNAccepted int     `json:"draft_n_accepted"`
+}
+
+// GenerationStats fasst die Generierungen seit dem letzten Start zusammen
+type GenerationStats struct {
+	Requests        int64   `json:"requests"`
+	PredictedTokens int64   `json:"predictedTokens"`
+	TokensPerSecond float64 `json:"tokensPerSecond"`
+	DraftTokens     int64   `json:"draftTokens"`    // Vom Draft-Modell vorgeschlagen
+	DraftAccepted   int64   `json:"draftAccepted"`  // Davon vom Hauptmodell übernommen
+	AcceptanceRate  float64 `json:"acceptanceRate"` // 0-1, nur mit Draft-Modell
+}
+
+// generationStats sammelt die Zeitmessungen thread-sicher
+type generationStats struct {
+	mu          sync.Mutex
+	requests    int64
+	predicted   int64
+	predictedMs float64
+	draft       int64
+	accepted    int64
+}
+
+// record übernimmt die Zeitmessung einer Antwort
+func (g *generationStats) record(t *llamaTimings) {
+	if t == nil || t.PredictedN == 0 {
+		return
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.requests++
+	g.predicted += int64(t.PredictedN)
+	g.predictedMs += t.PredictedMs
+	g.draft += int64(t.DraftN)
+	g.accepted += int64(t.DraftNAccepted)
+}
+
+// reset setzt die Statistik zurück (beim Start eines neuen Prozesses)
+func (g *generationStats) reset() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.requests, g.predicted, g.predictedMs, g.draft, g.accepted = 0, 0, 0, 0, 0
+}
+
+// snapshot gibt die aktuelle Statistik zurück
+func (g *generationStats) snapshot() GenerationStats {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	stats := GenerationStats{
+		Requests:        g.requests,
+		PredictedTokens: g.predicted,
+		DraftTokens:     g.draft,
+		DraftAccepted:   g.accepted,
+	}
+	if g.predictedMs > 0 {
+		stats.TokensPerSecond = float64(g.predicted) / g.predictedMs * 1000
+	}
+	if g.draft > 0 {
+		stats.AcceptanceRate = float64(g.accepted) / float64(g.draft)
+	}
+	return stats
+}
+
+// =============================================================================
+// A/B-Benchmark
+// =============================================================================
+
+// DraftBenchmarkOptions steuert den Vergleich mit und ohne Draft-Modell
+type DraftBenchmarkOptions struct {
+	Prompt    string `json:"prompt"`
+	MaxTokens int    `json:"maxTokens"`
+	Runs      int    `json:"runs"`
+}
+
+// DraftBenchmarkVariant ist das Ergebnis einer Variante
+type DraftBenchmarkVariant struct {
+	Label           string  `json:"label"`
+	DraftMax        int     `json:"draftMax"`
+	Runs            int     `json:"runs"`
+	PredictedTokens int     `json:"predictedTokens"`
+	TokensPerSecond float64 `json:"tokensPerSecond"`
+	AcceptanceRate  float64 `json:"acceptanceRate"`
+}
+
+// DraftBenchmarkResult vergleicht die Geschwindigkeit mit und ohne Draft-Modell
+type DraftBenchmarkResult struct {
+	Model      string                  `json:"model"`
+	DraftModel string                  `json:"draftModel,omitempty"`
+	Variants   []DraftBenchmarkVariant `json:"variants"`
+	Speedup    float64                 `json:"speedup,omitempty"` // mit / ohne Draft
+	Note       string                  `json:"note,omitempty"`
+}
+
+const defaultDraftBenchmarkPrompt = "Erkläre in etwa 200 Wörtern, wie ein Verbrennungsmotor funktioniert."
+
+// BenchmarkDraft vergleicht die Generierung mit und ohne Speculative Decoding im
+// laufenden Prozess: Die Variante "ohne" setzt speculative.n_max pro Anfrage auf 0,
+// ein Neustart ist nicht nötig. Greedy Sampling macht die Läufe vergleichbar.
+func (s *Server) BenchmarkDraft(ctx context.Context, opts DraftBenchmarkOptions) (*DraftBenchmarkResult, error) {
+	if !s.IsRunning() || !s.IsHealthy() {
+		return nil, fmt.Errorf("llama-server ist nicht aktiv")
+	}
+	if opts.Prompt == "" {
+		opts.Prompt = defaultDraftBenchmarkPrompt
+	}
+	if opts.MaxTokens <= 0 {
+		opts.MaxTokens = 256
+	}
+	if opts.Runs <= 0 {
+		opts.Runs = 2
+	}
+
+	s.mu.RLock()
+	result := &DraftBenchmarkResult{Model: s.modelName}
+	draftMax := s.config.Draft.Max
+	if s.draftModel != "" {
+		result.DraftModel = filepath.Base(s.draftModel)
+	}
+	s.mu.RUnlock()
+	if draftMax <= 0 {
+		draftMax = DefaultDraftMax
+	}
+
+	variants := []DraftBenchmarkVariant{{Label: "ohne Draft", DraftMax: 0}}
+	if result.DraftModel != "" {
+		variants = append(variants, DraftBenchmarkVariant{Label: "mit Draft", DraftMax: draftMax})
+	} else {
+		result.Note = "Kein Draft-Modell geladen - nur Basismessung"
+	}
+
+	for i := range variants {
+		v := &variants[i]
+		var predictedMs float64
+		var draft, accepted int
+		for run := 0; run < opts.Runs; run++ {
+			body := map[string]interface{}{
+				"messages":    []ChatMessage{{Role: "user", Content: opts.Prompt}},
+				"stream":      false,
+				"temperature": 0,
+				"max_tokens":  opts.MaxTokens,
+			}
+			if result.DraftModel != "" {
+				body["speculative.n_max"] = v.DraftMax
+			}
+			timings, err := s.timedCompletion(ctx, body)
+			if err != nil {
+				return nil, fmt.Errorf("Benchmark (%s) fehlgeschlagen: %w", v.Label, err)
+			}
+			v.Runs++
+			v.PredictedTokens += timings.PredictedN
+			predictedMs += timings.PredictedMs
+			draft += timings.DraftN
+			accepted += timings.DraftNAccepted
+		}
+		if predictedMs > 0 {
+			v.TokensPerSecond = float64(v.PredictedTokens) / predictedMs * 1000
+		}
+		if draft > 0 {
+			v.AcceptanceRate = float64(accepted) / float64(draft)
+		}
+		log.Printf("⚡ Draft-Benchmark %s: %.1f Tokens/s, Akzeptanz %.0f%%", v.Label, v.TokensPerSecond, v.AcceptanceRate*100)
+	}
+
+	result.Variants = variants
+	if len(variants) == 2 && variants[0].TokensPerSecond > 0 {
+		result.Speedup = variants[1].TokensPerSecond / variants[0].TokensPerSecond
+	}
+	return result, nil
+}
+
+// timedCompletion sendet eine nicht-streamende Anfrage und gibt nur die Zeitmessung zurück.
+// Die Messung fließt nicht in die Generierungs-Statistik ein.
+func (s *Server) timedCompletion(ctx context.Context, requestBody map[string]interface{}) (*llamaTimings, error) {
+	ctx, release, err := s.acquireSlot(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	// Auf den zugeteilten Slot legen, damit Prompt-Cache-Slots nicht überschrieben werden
+	finishSlot := s.prepareSlotCache(ctx, requestBody)
+	completed := false
+	defer func() { finishSlot(completed) }()
+
+	jsonBody, err := json.Marshal(requestBody)
+	if err != nil {
+		return nil, fmt.Errorf("JSON-Fehler: %w", err)
+	}
+
+	url := fmt.Sprintf("http://localhost:%d/v1/chat/completions", s.config.Port)
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonBody))
+	if err != nil {
+		return nil, fmt.Errorf("Request-Fehler: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := (&http.Client{Timeout: 5 * time.Minute}).Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("llama-server nicht erreichbar: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("llama-server Fehler %d: %s", resp.StatusCode, string(body))
+	}
+
+	var response struct {
+		Timings *llamaTimings `json:"timings"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("Response-Decode-Fehler: %w", err)
+	}
+	if response.Timings == nil {
+		return nil, fmt.Errorf("llama-server liefert keine Zeitmessung (zu alte Version?)")
+	}
+	completed = true
+	return response.Timings, nil
+}
@@ -0,0 +1,169 @@
+package llamaserver
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"fleet-navigator/internal/gguf"
+)
+
+// writeTestModel schreibt ein Modell mit Tokenizer-Metadaten und Gewichten der angegebenen Größe
+func writeTestModel(t *testing.T, path, tokenizerPre string, weights uint64) {
+	t.Helper()
+	writeTestGGUF(t, path, []gguf.KV{
+		{Key: "general.architecture", Value: "qwen2"},
+		{Key: "tokenizer.ggml.model", Value: "gpt2"},
+		{Key: "tokenizer.ggml.pre", Value: tokenizerPre},
+		{Key: "tokenizer.ggml.eos_token_id", Value: uint32(151645)},
+	}, []gguf.TensorInfo{{Name: "blk.0.ffn_up.weight", Dimensions: []uint64{weights}, Type: 0}})
+}
+
+// TestDraftCandidates prüft die automatische Wahl des Draft-Modells
+func TestDraftCandidates(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "qwen-7b.gguf")
+	writeTestModel(t, target, "qwen2", 40000)
+	writeTestModel(t, filepath.Join(dir, "qwen-3b.gguf"), "qwen2", 20000)     // zu groß
+	writeTestModel(t, filepath.Join(dir, "qwen-1.5b.gguf"), "qwen2", 8000)    // passt
+	writeTestModel(t, filepath.Join(dir, "qwen-0.5b.gguf"), "qwen2", 3000)    // passt, kleiner
+	writeTestModel(t, filepath.Join(dir, "llama-1b.gguf"), "llama-bpe", 2000) // anderer Tokenizer
+
+	s := NewServer(Config{ModelsDir: dir, Draft: DefaultDraftConfig()})
+	candidates, err := s.DraftCandidates(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(candidates) != 4 {
+		t.Fatalf("%d Kandidaten: %+v", len(candidates), candidates)
+	}
+	if candidates[0].Name != "qwen-0.5b.gguf" || !candidates[1].Compatible || candidates[2].Compatible {
+		t.Errorf("Reihenfolge/Bewertung: %+v", candidates)
+	}
+
+	// Deaktiviert: keine Argumente
+	if args := s.draftArgsLocked(target, 99); args != nil {
+		t.Errorf("Draft trotz Deaktivierung: %v", args)
+	}
+
+	s.config.Draft.Enabled = true
+	args := s.draftArgsLocked(target, 0)
+	want := []string{"-md", filepath.Join(dir, "qwen-0.5b.gguf"), "--draft-max", "16", "-ngld", "0", "--draft-p-min", "0.80"}
+	if len(args) != len(want) {
+		t.Fatalf("Argumente: %v", args)
+	}
+	for i := range want {
+		if args[i] != want[i] {
+			t.Errorf("Argument %d = %q, erwartet %q", i, args[i], want[i])
+		}
+	}
+	if status := s.draftStatusLocked(); status == nil || !status.Auto || status.Model != "qwen-0.5b.gguf" {
+		t.Errorf("Draft-Status: %+v", status)
+	}
+
+	// Explizit gewähltes, inkompatibles Modell wird nicht geladen
+	s.config.Draft.ModelPath = filepath.Join(dir, "llama-1b.gguf")
+	if args := s.draftArgsLocked(target, 0); args != nil {
+		t.Errorf("Inkompatibler Draft geladen: %v", args)
+	}
+}
+
+// TestDraftReserve prüft, dass der VRAM-Plan das Draft-Modell mit einplant
+func TestDraftReserve(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "qwen-7b.gguf")
+	writeTestModel(t, target, "qwen2", 40000)
+	writeTestModel(t, filepath.Join(dir, "qwen-0.5b.gguf"), "qwen2", 3000)
+
+	s := NewServer(Config{ModelsDir: dir, Draft: DefaultDraftConfig()})
+	if opts := s.planOptions(target, 4096, true, 8000); opts.ExtraMB != 0 {
+		t.Errorf("Reserve ohne Draft: %d MB", opts.ExtraMB)
+	}
+
+	s.config.Draft.Enabled = true
+	opts := s.planOptions(target, 4096, true, 8000)
+	if opts.ExtraMB <= 0 {
+		t.Fatalf("Keine Reserve für das Draft-Modell: %+v", opts)
+	}
+	if plan := PlanGPUOffload(target, opts); plan.ExtraMB != opts.ExtraMB {
+		t.Errorf("Plan-Reserve: %d MB, erwartet %d MB", plan.ExtraMB, opts.ExtraMB)
+	}
+
+	// Automatische Wahl wird zwischengespeichert, bis sich die Modell-Liste ändert
+	if pair := s.draftPairs[target]; pair.path != filepath.Join(dir, "qwen-0.5b.gguf") {
+		t.Fatalf("Zwischengespeichert: %+v", s.draftPairs)
+	}
+	smaller := filepath.Join(dir, "qwen-0.3b.gguf")
+	writeTestModel(t, smaller, "qwen2", 1000)
+	if path, err := s.FindDraftModel(target); err != nil || path != smaller {
+		t.Errorf("Nach neuem Modell: %q, %v", path, err)
+	}
+
+	// Draft auf der CPU (-ngld 0) braucht keinen VRAM
+	cfg := s.GetDraftConfig()
+	cfg.GPULayers = 0
+	s.SetDraftConfig(cfg)
+	if s.draftPairs != nil {
+		t.Error("Zwischenspeicher nach SetDraftConfig nicht geleert")
+	}
+	if opts := s.planOptions(target, 4096, true, 8000); opts.ExtraMB != 0 {
+		t.Errorf("Reserve für CPU-Draft: %d MB", opts.ExtraMB)
+	}
+}
+
+// TestBenchmarkDraft prüft den A/B-Vergleich und die Statistik aus den Timings
+func TestBenchmarkDraft(t *testing.T) {
+	var nMax []interface{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/health" {
+			w.Write([]byte(`{"status":"ok"}`))
+			return
+		}
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		nMax = append(nMax, body["speculative.n_max"])
+
+		// Ohne Draft 20 Tokens/s, mit Draft 40 Tokens/s bei 75% Akzeptanz
+		timings := map[string]interface{}{"predicted_n": 100, "predicted_ms": 5000.0}
+		if n, _ := body["speculative.n_max"].(float64); n > 0 {
+			timings = map[string]interface{}{"predicted_n": 100, "predicted_ms": 2500.0, "draft_n": 80, "draft_n_accepted": 60}
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"choices": []map[string]interface{}{{"message": map[string]string{"content": "ok"}}},
+			"timings": timings,
+		})
+	}))
+	defer ts.Close()
+
+	u, _ := url.Parse(ts.URL)
+	port, _ := strconv.Atoi(u.Port())
+	s := NewServer(Config{Port: port, Draft: DefaultDraftConfig()})
+	s.running = true
+	s.draftModel = "/models/qwen-0.5b.gguf"
+
+	result, err := s.BenchmarkDraft(context.Background(), DraftBenchmarkOptions{Runs: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Variants) != 2 || len(nMax) != 4 || nMax[0] != 0.0 || nMax[2] != 16.0 {
+		t.Fatalf("Varianten: %+v, n_max: %v", result.Variants, nMax)
+	}
+	if result.Variants[1].AcceptanceRate != 0.75 || result.Speedup != 2 {
+		t.Errorf("Ergebnis: %+v, Speedup %.2f", result.Variants, result.Speedup)
+	}
+
+	// Benchmarks verfälschen die Live-Statistik nicht, normale Anfragen schon
+	if stats := s.GetStatus().Generation; stats.Requests != 0 {
+		t.Errorf("Benchmark in Statistik gezählt: %+v", stats)
+	}
+	s.completeChat(context.Background(), map[string]interface{}{"speculative.n_max": 16})
+	stats := s.GetStatus().Generation
+	if stats.Requests != 1 || stats.TokensPerSecond != 40 || stats.AcceptanceRate != 0.75 {
+		t.Errorf("Statistik: %+v", stats)
+	}
+}
@@ -0,0 +1,479 @@
+package llamaserver
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ===== Strukturierte Ausgabe (JSON-Schema / GBNF) =====
+//
+// llama-server kann die Ausgabe per Grammatik erzwingen: Ein JSON-Schema wird
+// serverseitig in eine GBNF-Grammatik übersetzt, alternativ wird eine
+// GBNF-Grammatik direkt übergeben. Das Modell kann dann nur noch Antworten im
+// geforderten Format erzeugen. Die Antwort wird zusätzlich gegen das Schema
+// geprüft (z.B. bei abgeschnittener Ausgabe oder älteren llama-server-Versionen).
+
+// Ausgabeformate (angelehnt an das OpenAI response_format)
+const (
+	FormatText       = "text"        // Freitext (Standard)
+	FormatJSONObject = "json_object" // Beliebiges JSON-Objekt
+	FormatJSONSchema = "json_schema" // JSON passend zu einem Schema
+	FormatGrammar    = "grammar"     // Eigene GBNF-Grammatik
+)
+
+// ErrInvalidStructuredOutput wird zurückgegeben wenn die Antwort nicht zum Format passt
+var ErrInvalidStructuredOutput = errors.New("Antwort entspricht nicht dem geforderten Format")
+
+// SchemaType ist das "type"-Feld eines JSON-Schemas (String oder Liste)
+type SchemaType []string
+
+// UnmarshalJSON akzeptiert "string" und ["string", "null"]
+func (t *SchemaType) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*t = SchemaType{single}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return fmt.Errorf("ungültiges Schema-Feld type: %s", string(data))
+	}
+	*t = list
+	return nil
+}
+
+// MarshalJSON schreibt einen einzelnen Typ als String
+func (t SchemaType) MarshalJSON() ([]byte, error) {
+	if len(t) == 1 {
+		return json.Marshal(t[0])
+	}
+	return json.Marshal([]string(t))
+}
+
+// Schema ist die von der Validierung unterstützte Teilmenge von JSON-Schema.
+// Unbekannte Schlüsselwörter (pattern, $defs, ...) werden unverändert an
+// llama-server weitergegeben, aber nicht lokal geprüft.
+type Schema struct {
+	Type                 SchemaType         `json:"type,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *bool              `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Enum                 []interface{}      `json:"enum,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty"`
+	MaxItems             *int               `json:"maxItems,omitempty"`
+
+	raw json.RawMessage // Original-JSON (Reihenfolge der Properties bleibt erhalten)
+}
+
+// ParseSchema liest ein JSON-Schema
+func ParseSchema(data []byte) (*Schema, error) {
+	var schema Schema
+	if err := json.Unmarshal(data, &schema); err != nil {
+		return nil, fmt.Errorf("JSON-Schema ungültig: %w", err)
+	}
+	return &schema, nil
+}
+
+// MustParseSchema wie ParseSchema, bricht bei Fehlern ab (für feste Schemas im Code)
+func MustParseSchema(data string) *Schema {
+	schema, err := ParseSchema([]byte(data))
+	if err != nil {
+		panic(err)
+	}
+	return schema
+}
+
+// UnmarshalJSON merkt sich das Original-JSON des Schemas
+func (s *Schema) UnmarshalJSON(data []byte) error {
+	type plain Schema
+	if err := json.Unmarshal(data, (*plain)(s)); err != nil {
+		return err
+	}
+	s.raw = append(json.RawMessage(nil), data...)
+	return nil
+}
+
+// MarshalJSON gibt das Original-JSON zurück (falls vorhanden)
+func (s *Schema) MarshalJSON() ([]byte, error) {
+	if len(s.raw) > 0 {
+		return s.raw, nil
+	}
+	type plain Schema
+	return json.Marshal((*plain)(s))
+}
+
+// Validate prüft einen mit encoding/json dekodierten Wert gegen das Schema
+func (s *Schema) Validate(value interface{}) error {
+	if err := s.validate("$", value); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidStructuredOutput, err)
+	}
+	return nil
+}
+
+func (s *Schema) validate(path string, value interface{}) error {
+	if s == nil {
+		return nil
+	}
+	if len(s.Type) > 0 && !s.matchesType(value) {
+		return fmt.Errorf("%s: erwartet %s, erhalten %s", path, strings.Join(s.Type, "|"), jsonTypeName(value))
+	}
+	if len(s.Enum) > 0 && !enumContains(s.Enum, value) {
+		return fmt.Errorf("%s: Wert %v nicht erlaubt", path, value)
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for _, name := range s.Required {
+			if _, ok := v[name]; !ok {
+				return fmt.Errorf("%s: Pflichtfeld %q fehlt", path, name)
+			}
+		}
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			prop, ok := s.Properties[name]
+			if !ok {
+				if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+					return fmt.Errorf("%s: unbekanntes Feld %q", path, name)
+				}
+				continue
+			}
+			if err := prop.validate(path+"."+name, v[name]); err != nil {
+				return err
+			}
+		}
+
+	case []interface{}:
+		if s.MinItems != nil && len(v) < *s.MinItems {
+			return fmt.Errorf("%s: mindestens %d Einträge erwartet", path, *s.MinItems)
+		}
+		if s.MaxItems != nil && len(v) > *s.MaxItems {
+			return fmt.Errorf("%s: höchstens %d Einträge erlaubt", path, *s.MaxItems)
+		}
+		for i, item := range v {
+			if err := s.Items.validate(fmt.Sprintf("%s[%d]", path, i), item); err != nil {
+				return err
+			}
+		}
+
+	case string:
+		length := len([]rune(v))
+		if s.MinLength != nil && length < *s.MinLength {
+			return fmt.Errorf("%s: mindestens %d Zeichen erwartet", path, *s.MinLength)
+		}
+		if s.MaxLength != nil && length > *s.MaxLength {
+			return fmt.Errorf("%s: höchstens %d Zeichen erlaubt", path, *s.MaxLength)
+		}
+
+	case float64:
+		if s.Minimum != nil && v < *s.Minimum {
+			return fmt.Errorf("%s: %v kleiner als Minimum %v", path, v, *s.Minimum)
+		}
+		if s.Maximum != nil && v > *s.Maximum {
+			return fmt.Errorf("%s: %v größer als Maximum %v", path, v, *s.Maximum)
+		}
+	}
+	return nil
+}
+
+func (s *Schema) matchesType(value interface{}) bool {
+	actual := jsonTypeName(value)
+	for _, t := range s.Type {
+		if t == actual || (t == "number" && actual == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonTypeName gibt den JSON-Schema-Typ eines dekodierten Werts zurück
+func jsonTypeName(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case float64:
+		if v == float64(int64(v)) {
+			return "integer"
+		}
+		return "number"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
+
+func enumContains(enum []interface{}, value interface{}) bool {
+	encoded, _ := json.Marshal(value)
+	for _, allowed := range enum {
+		if candidate, _ := json.Marshal(allowed); bytes.Equal(candidate, encoded) {
+			return true
+		}
+	}
+	return false
+}
+
+// ResponseFormat beschreibt das gewünschte Ausgabeformat einer Anfrage.
+// Neben der eigenen Form ({"type":"json_schema","schema":{...}}) wird auch das
+// OpenAI-Format ({"type":"json_schema","json_schema":{"schema":{...}}}) akzeptiert.
+type ResponseFormat struct {
+	Type       string            `json:"type"`
+	Name       string            `json:"name,omitempty"`
+	Schema     *Schema           `json:"schema,omitempty"`
+	Grammar    string            `json:"grammar,omitempty"` // GBNF (type=grammar)
+	JSONSchema *openAIJSONSchema `json:"json_schema,omitempty"`
+}
+
+// openAIJSONSchema ist das verschachtelte Schema im OpenAI-Format
+type openAIJSONSchema struct {
+	Name   string  `json:"name,omitempty"`
+	Schema *Schema `json:"schema"`
+	Strict bool    `json:"strict,omitempty"`
+}
+
+// Normalize prüft das Format und übernimmt ein Schema im OpenAI-Format
+func (f *ResponseFormat) Normalize() error {
+	if f.Type == "" {
+		f.Type = FormatText
+	}
+	if f.JSONSchema != nil {
+		if f.Schema == nil {
+			f.Schema = f.JSONSchema.Schema
+		}
+		if f.Name == "" {
+			f.Name = f.JSONSchema.Name
+		}
+		f.JSONSchema = nil
+	}
+
+	switch f.Type {
+	case FormatText, FormatJSONObject:
+		return nil
+	case FormatJSONSchema:
+		if f.Schema == nil {
+			return fmt.Errorf("response_format json_schema ohne Schema")
+		}
+		return nil
+	case FormatGrammar:
+		if strings.TrimSpace(f.Grammar) == "" {
+			return fmt.Errorf("response_format grammar ohne GBNF-Grammatik")
+		}
+		return nil
+	default:
+		return fmt.Errorf("unbekanntes response_format: %s", f.Type)
+	}
+}
+
+// requestFields gibt die Felder für die llama-server-Anfrage zurück
+// (response_format bzw. grammar)
+func (f *ResponseFormat) requestFields() (map[string]interface{}, string) {
+	switch f.Type {
+	case FormatJSONObject:
+		return map[string]interface{}{"type": "json_object"}, ""
+	case FormatJSONSchema:
+		name := f.Name
+		if name == "" {
+			name = "response"
+		}
+		return map[string]interface{}{
+			"type": "json_schema",
+			"json_schema": map[string]interface{}{
+				"name":   name,
+				"schema": f.Schema,
+				"strict": true,
+			},
+		}, ""
+	case FormatGrammar:
+		return nil, f.Grammar
+	default:
+		return nil, ""
+	}
+}
+
+// applyTo ergänzt den Request-Body um das Ausgabeformat
+func (f *ResponseFormat) applyTo(body map[string]interface{}) {
+	responseFormat, grammar := f.requestFields()
+	if responseFormat != nil {
+		body["response_format"] = responseFormat
+	}
+	if grammar != "" {
+		body["grammar"] = grammar
+	}
+}
+
+// Check prüft eine Antwort gegen das Format (Freitext und Grammatik werden nicht geprüft)
+func (f *ResponseFormat) Check(content string) error {
+	if f.Type != FormatJSONObject && f.Type != FormatJSONSchema {
+		return nil
+	}
+	var value interface{}
+	if err := json.Unmarshal([]byte(content), &value); err != nil {
+		return fmt.Errorf("%w: kein gültiges JSON (%v)", ErrInvalidStructuredOutput, err)
+	}
+	if f.Type == FormatJSONObject {
+		if _, ok := value.(map[string]interface{}); !ok {
+			return fmt.Errorf("%w: kein JSON-Objekt", ErrInvalidStructuredOutput)
+		}
+		return nil
+	}
+	return f.Schema.Validate(value)
+}
+
+// EnumGrammar erzeugt eine GBNF-Grammatik, die genau einen der Werte erlaubt
+func EnumGrammar(values ...string) string {
+	alternatives := make([]string, len(values))
+	for i, v := range values {
+		quoted, _ := json.Marshal(v) // GBNF-Strings verwenden dieselben Escapes wie JSON
+		alternatives[i] = string(quoted)
+	}
+	return "root ::= " + strings.Join(alternatives, " | ")
+}
+
+// StructuredSamplingParams sind die Standard-Parameter für strukturierte Ausgabe
+// (niedrige Temperatur - Klassifizierung statt Kreativität)
+func StructuredSamplingParams() SamplingParams {
+	return SamplingParams{
+		Temperature: 0.2,
+		TopP:        0.9,
+		MaxTokens:   2048,
+	}
+}
+
+// GenerateStructured führt eine nicht-streamende Anfrage mit erzwungenem
+// Ausgabeformat durch und gibt die geprüfte Antwort zurück.
+// Priorität und Benutzer für die Slot-Warteschlange kommen aus WithRequestInfo.
+func (s *Server) GenerateStructured(ctx context.Context, messages []ChatMessage, format ResponseFormat, params SamplingParams) (string, error) {
+	if err := format.Normalize(); err != nil {
+		return "", err
+	}
+	if !s.IsRunning() || !s.IsHealthy() {
+		return "", fmt.Errorf("llama-server ist nicht aktiv")
+	}
+
+	ctx, release, err := s.acquireSlot(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer release()
+
+	defaults := StructuredSamplingParams()
+	if params.Temperature == 0 {
+		params.Temperature = defaults.Temperature
+	}
+	if params.TopP == 0 {
+		params.TopP = defaults.TopP
+	}
+	if params.MaxTokens == 0 {
+		params.MaxTokens = defaults.MaxTokens
+	}
+
+	requestBody := map[string]interface{}{
+		"messages":    s.adaptMessagesForModel(messages),
+		"stream":      false,
+		"temperature": params.Temperature,
+		"top_p":       params.TopP,
+		"max_tokens":  params.MaxTokens,
+	}
+	format.applyTo(requestBody)
+
+	content, err := s.completeChat(ctx, requestBody)
+	if err != nil {
+		return "", err
+	}
+	if err := format.Check(content); err != nil {
+		return content, err
+	}
+	return content, nil
+}
+
+// GenerateJSON erzeugt eine Antwort passend zum Schema und dekodiert sie in out
+func (s *Server) GenerateJSON(ctx context.Context, messages []ChatMessage, schema *Schema, out interface{}) error {
+	content, err := s.GenerateStructured(ctx, messages, ResponseFormat{Type: FormatJSONSchema, Schema: schema}, StructuredSamplingParams())
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal([]byte(content), out); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidStructuredOutput, err)
+	}
+	return nil
+}
+
+// completeChat sendet eine nicht-streamende Chat-Anfrage und gibt den Inhalt zurück
+func (s *Server) completeChat(ctx context.Context, requestBody map[string]interface{}) (string, error) {
+	s.applyLora(ctx, requestBody)
+	finishSlot := s.prepareSlotCache(ctx, requestBody)
+	completed := false
+	defer func() { finishSlot(completed) }()
+
+	jsonBody, err := json.Marshal(requestBody)
+	if err != nil {
+		return "", fmt.Errorf("JSON-Fehler: %w", err)
+	}
+
+	url := fmt.Sprintf("http://localhost:%d/v1/chat/completions", s.config.Port)
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonBody))
+	if err != nil {
+		return "", fmt.Errorf("Request-Fehler: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := &http.Client{Timeout: 5 * time.Minute}
+	resp, err := client.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return "", fmt.Errorf("Generierung abgebrochen: %w", ctx.Err())
+		}
+		return "", fmt.Errorf("llama-server nicht erreichbar: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return "", fmt.Errorf("llama-server Fehler %d: %s", resp.StatusCode, string(body))
+	}
+
+	var response struct {
+		Choices []struct {
+			Message struct {
+				Content string `json:"content"`
+			} `json:"message"`
+			FinishReason string `json:"finish_reason"`
+		} `json:"choices"`
+		Timings *llamaTimings `json:"timings"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return "", fmt.Errorf("Response-Decode-Fehler: %w", err)
+	}
+	s.stats.record(response.Timings)
+	completed = true
+	if len(response.Choices) == 0 {
+		return "", fmt.Errorf("keine Antwort vom Modell")
+	}
+	if response.Choices[0].FinishReason == "length" {
+		return "", fmt.Errorf("%w: Antwort nach max_tokens abgeschnitten", ErrInvalidStructuredOutput)
+	}
+	return strings.TrimSpace(response.Choices[0].Message.Content), nil
+}
@@ -0,0 +1,138 @@
+package llamaserver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+var testClassifySchema = MustParseSchema(`{
+	"type": "object",
+	"properties": {
+		"category": {"type": "string", "enum": ["rechnung", "werbung"]},
+		"reason": {"type": "string", "maxLength": 20},
+		"score": {"type": "number", "minimum": 0, "maximum": 1},
+		"tags": {"type": "array", "items": {"type": "string"}, "maxItems": 2}
+	},
+	"required": ["category"],
+	"additionalProperties": false
+}`)
+
+// TestSchemaValidate prüft die Validierung von Modell-Antworten gegen ein JSON-Schema
+func TestSchemaValidate(t *testing.T) {
+	tests := []struct {
+		name  string
+		json  string
+		valid bool
+	}{
+		{"minimal", `{"category":"rechnung"}`, true},
+		{"vollständig", `{"category":"werbung","reason":"Newsletter","score":0.9,"tags":["a","b"]}`, true},
+		{"Pflichtfeld fehlt", `{"reason":"x"}`, false},
+		{"unbekannter Wert", `{"category":"spam"}`, false},
+		{"zusätzliches Feld", `{"category":"rechnung","extra":1}`, false},
+		{"zu lang", `{"category":"rechnung","reason":"viel zu lange Begründung"}`, false},
+		{"außerhalb Bereich", `{"category":"rechnung","score":1.5}`, false},
+		{"falscher Typ", `{"category":"rechnung","tags":"a"}`, false},
+		{"zu viele Elemente", `{"category":"rechnung","tags":["a","b","c"]}`, false},
+		{"kein Objekt", `["rechnung"]`, false},
+	}
+	for _, tt := range tests {
+		var value interface{}
+		if err := json.Unmarshal([]byte(tt.json), &value); err != nil {
+			t.Fatal(err)
+		}
+		err := testClassifySchema.Validate(value)
+		if tt.valid && err != nil {
+			t.Errorf("%s: unerwarteter Fehler: %v", tt.name, err)
+		}
+		if !tt.valid && !errors.Is(err, ErrInvalidStructuredOutput) {
+			t.Errorf("%s: Fehler erwartet, erhalten %v", tt.name, err)
+		}
+	}
+}
+
+// TestResponseFormat_Normalize prüft Eigen- und OpenAI-Format sowie ungültige Angaben
+func TestResponseFormat_Normalize(t *testing.T) {
+	var f ResponseFormat
+	openAI := `{"type":"json_schema","json_schema":{"name":"klasse","schema":{"type":"object","properties":{"b":{"type":"string"},"a":{"type":"string"}}}}}`
+	if err := json.Unmarshal([]byte(openAI), &f); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Normalize(); err != nil {
+		t.Fatalf("OpenAI-Format: %v", err)
+	}
+	if f.Schema == nil || f.Name != "klasse" || f.JSONSchema != nil {
+		t.Fatalf("OpenAI-Format nicht übernommen: %+v", f)
+	}
+
+	body := map[string]interface{}{}
+	f.applyTo(body)
+	data, _ := json.Marshal(body)
+	// Reihenfolge der Properties bleibt erhalten (llama-server erzeugt die Grammatik daraus)
+	if !strings.Contains(string(data), `"properties":{"b":{"type":"string"},"a":{"type":"string"}}`) ||
+		!strings.Contains(string(data), `"name":"klasse"`) {
+		t.Errorf("Request-Body: %s", data)
+	}
+
+	grammar := ResponseFormat{Type: FormatGrammar, Grammar: EnumGrammar("ja", "nein")}
+	body = map[string]interface{}{}
+	grammar.applyTo(body)
+	if body["grammar"] != `root ::= "ja" | "nein"` || body["response_format"] != nil {
+		t.Errorf("Grammatik-Body: %v", body)
+	}
+
+	for _, invalid := range []ResponseFormat{
+		{Type: FormatJSONSchema},
+		{Type: FormatGrammar, Grammar: "  "},
+		{Type: "xml"},
+	} {
+		if err := invalid.Normalize(); err == nil {
+			t.Errorf("Ungültiges Format akzeptiert: %+v", invalid)
+		}
+	}
+}
+
+// jsonCompletion beantwortet /v1/chat/completions ohne Streaming mit content
+// und merkt sich den Request-Body (für newFakeLlamaServer)
+func jsonCompletion(content string, received *map[string]interface{}) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(received)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"choices": []map[string]interface{}{
+				{"message": map[string]string{"content": content}, "finish_reason": "stop"},
+			},
+		})
+	}
+}
+
+// TestGenerateJSON prüft Anfrage und Dekodierung gegen einen simulierten llama-server
+func TestGenerateJSON(t *testing.T) {
+	var received map[string]interface{}
+	s := newFakeLlamaServer(t, jsonCompletion(`{"category":"rechnung","reason":"Betrag fällig"}`, &received))
+
+	var result struct {
+		Category string `json:"category"`
+		Reason   string `json:"reason"`
+	}
+	messages := []ChatMessage{{Role: "user", Content: "Klassifiziere"}}
+	if err := s.GenerateJSON(context.Background(), messages, testClassifySchema, &result); err != nil {
+		t.Fatalf("GenerateJSON: %v", err)
+	}
+	if result.Category != "rechnung" || result.Reason != "Betrag fällig" {
+		t.Errorf("Ergebnis: %+v", result)
+	}
+
+	format, _ := received["response_format"].(map[string]interface{})
+	if format["type"] != "json_schema" || received["stream"] != false {
+		t.Errorf("Request ohne Schema: %v", received)
+	}
+
+	// Antwort außerhalb des Schemas wird abgelehnt
+	s = newFakeLlamaServer(t, jsonCompletion(`{"category":"spam"}`, &received))
+	if err := s.GenerateJSON(context.Background(), messages, testClassifySchema, &result); !errors.Is(err, ErrInvalidStructuredOutput) {
+		t.Errorf("Ungültige Antwort: %v", err)
+	}
+}
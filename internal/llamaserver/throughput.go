@@ -0,0 +1,160 @@
+package llamaserver
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// =============================================================================
+// DURCHSATZ-MESSUNG
+// =============================================================================
+//
+// Misst Prompt-Verarbeitung (pp) und Generierung (tg) in Tokens/s sowie die Zeit
+// bis zum ersten Token über /completion im Streaming-Modus. Der Prompt wird nicht
+// aus dem Cache verwendet (cache_prompt=false) und die Generierung läuft bis
+// n_predict (ignore_eos), damit jede Messung die gleiche Arbeit misst.
+
+// ThroughputOptions steuert eine Durchsatz-Messung
+type ThroughputOptions struct {
+	PromptTokens int // Ungefähre Prompt-Länge (tatsächliche Länge steht im Ergebnis)
+	GenTokens    int // Zu generierende Tokens
+}
+
+// ThroughputSample ist das Ergebnis einer Durchsatz-Messung
+type ThroughputSample struct {
+	PromptTokens    int     `json:"promptTokens"`
+	PromptTPS       float64 `json:"promptTps"`
+	GenTokens       int     `json:"genTokens"`
+	GenTPS          float64 `json:"genTps"`
+	TimeToFirstMs   float64 `json:"ttftMs"`
+	TotalDurationMs float64 `json:"totalMs"`
+}
+
+// benchmarkSentence wird zum Prompt aufgefüllt (nummeriert, damit kein Text identisch ist)
+const benchmarkSentence = "Absatz %d: Die Flotte navigiert durch ruhige See, die Besatzung prüft Kurs, Wetter und Ladung. "
+
+// benchmarkPrompt erzeugt einen Prompt mit ungefähr der gewünschten Token-Anzahl.
+// Gerechnet wird mit 3 Zeichen pro Token - eher zu wenige Tokens als ein Context-Überlauf.
+func benchmarkPrompt(tokens int) string {
+	var b strings.Builder
+	for i := 1; b.Len() < tokens*3; i++ {
+		fmt.Fprintf(&b, benchmarkSentence, i)
+	}
+	b.WriteString("\nFasse den Text zusammen.")
+	return b.String()
+}
+
+// MeasureThroughput führt eine Durchsatz-Messung auf dem geladenen Modell aus
+func (s *Server) MeasureThroughput(ctx context.Context, opts ThroughputOptions) (*ThroughputSample, error) {
+	if !s.IsRunning() {
+		return nil, fmt.Errorf("llama-server ist nicht aktiv")
+	}
+	if opts.PromptTokens <= 0 {
+		opts.PromptTokens = 512
+	}
+	if opts.GenTokens <= 0 {
+		opts.GenTokens = 128
+	}
+
+	ctx, release, err := s.acquireSlot(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	body := map[string]interface{}{
+		"prompt":       benchmarkPrompt(opts.PromptTokens),
+		"n_predict":    opts.GenTokens,
+		"temperature":  0,
+		"cache_prompt": false,
+		"ignore_eos":   true,
+		"stream":       true,
+	}
+	// Auf den zugeteilten Slot legen, damit Prompt-Cache-Slots nicht überschrieben werden;
+	// die Messung hinterlässt keinen wiederverwendbaren Zustand
+	finishSlot := s.prepareSlotCache(ctx, body)
+	defer finishSlot(false)
+
+	jsonBody, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("JSON-Fehler: %w", err)
+	}
+
+	url := fmt.Sprintf("http://localhost:%d/completion", s.config.Port)
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonBody))
+	if err != nil {
+		return nil, fmt.Errorf("Request-Fehler: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	start := time.Now()
+	resp, err := (&http.Client{Timeout: 10 * time.Minute}).Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("Messung abgebrochen: %w", ctx.Err())
+		}
+		return nil, fmt.Errorf("llama-server nicht erreichbar: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("llama-server Fehler %d: %s", resp.StatusCode, string(body))
+	}
+
+	sample := &ThroughputSample{}
+	var timings *llamaTimings
+	reader := bufio.NewReader(resp.Body)
+	for timings == nil {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			if ctx.Err() != nil {
+				return nil, fmt.Errorf("Messung abgebrochen: %w", ctx.Err())
+			}
+			return nil, fmt.Errorf("Stream-Lesefehler: %w", err)
+		}
+
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "data: ") {
+			continue
+		}
+		var chunk struct {
+			Content string        `json:"content"`
+			Stop    bool          `json:"stop"`
+			Timings *llamaTimings `json:"timings"`
+		}
+		if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &chunk); err != nil {
+			continue
+		}
+		if sample.TimeToFirstMs == 0 && chunk.Content != "" {
+			sample.TimeToFirstMs = float64(time.Since(start).Microseconds()) / 1000
+		}
+		if chunk.Stop {
+			timings = chunk.Timings
+		}
+	}
+	sample.TotalDurationMs = float64(time.Since(start).Microseconds()) / 1000
+
+	if timings == nil {
+		return nil, fmt.Errorf("llama-server liefert keine Zeitmessung (zu alte Version?)")
+	}
+	sample.PromptTokens = timings.PromptN
+	sample.GenTokens = timings.PredictedN
+	if timings.PromptMs > 0 {
+		sample.PromptTPS = float64(timings.PromptN) * 1000 / timings.PromptMs
+	}
+	if timings.PredictedMs > 0 {
+		sample.GenTPS = float64(timings.PredictedN) * 1000 / timings.PredictedMs
+	}
+	return sample, nil
+}
@@ -0,0 +1,49 @@
+package llamaserver
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+)
+
+// TestMeasureThroughput prüft die Auswertung der Timings aus dem Stream
+func TestMeasureThroughput(t *testing.T) {
+	var body map[string]interface{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/health" {
+			w.Write([]byte(`{"status":"ok"}`))
+			return
+		}
+		if r.URL.Path != "/completion" {
+			t.Errorf("Unerwarteter Pfad: %s", r.URL.Path)
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		w.Write([]byte("data: {\"content\":\"Die\",\"stop\":false}\n\n"))
+		w.Write([]byte("data: {\"content\":\" Flotte\",\"stop\":false}\n\n"))
+		w.Write([]byte(`data: {"content":"","stop":true,"timings":{"prompt_n":2000,"prompt_ms":1000,"predicted_n":64,"predicted_ms":2000}}` + "\n\n"))
+	}))
+	defer ts.Close()
+
+	u, _ := url.Parse(ts.URL)
+	port, _ := strconv.Atoi(u.Port())
+	s := NewServer(Config{Port: port})
+	s.running = true
+
+	sample, err := s.MeasureThroughput(context.Background(), ThroughputOptions{PromptTokens: 2000, GenTokens: 64})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sample.PromptTPS != 2000 || sample.GenTPS != 32 || sample.GenTokens != 64 || sample.TimeToFirstMs <= 0 {
+		t.Errorf("Messung: %+v", sample)
+	}
+	if body["cache_prompt"] != false || body["ignore_eos"] != true || body["n_predict"] != 64.0 {
+		t.Errorf("Request: cache_prompt=%v ignore_eos=%v n_predict=%v", body["cache_prompt"], body["ignore_eos"], body["n_predict"])
+	}
+	if prompt, _ := body["prompt"].(string); len(prompt) < 6000 {
+		t.Errorf("Prompt zu kurz: %d Zeichen", len(prompt))
+	}
+}
@@ -62,6 +62,10 @@ type VisionChatRequest struct {
 	Stream      bool                `json:"stream"`
 	Temperature float64             `json:"temperature,omitempty"`
 	MaxTokens   int                 `json:"max_tokens,omitempty"`
+
+	// Strukturierte Ausgabe (siehe ResponseFormat)
+	ResponseFormat map[string]interface{} `json:"response_format,omitempty"`
+	Grammar        string                 `json:"grammar,omitempty"`
 }
 
 // ImageAnalysisResult enthält das Ergebnis der Bildanalyse
@@ -169,12 +173,48 @@ func (vs *VisionService) StreamAnalyzeImage(ctx context.Context, base64Image str
 	return err
 }
 
+// documentAnalysisSchema erzwingt die Antwort von AnalyzeDocument als JSON.
+// Die Reihenfolge der Felder ist die Reihenfolge, in der das Modell antwortet:
+// erst die visuelle Analyse, dann die inhaltliche.
+var documentAnalysisSchema = MustParseSchema(`{
+  "type": "object",
+  "properties": {
+    "graphicElements": {"type": "string"},
+    "layout": {"type": "string"},
+    "logoDetected": {"type": "boolean"},
+    "logoDescription": {"type": "string"},
+    "documentType": {"type": "string", "enum": ["invoice", "reminder", "contract", "letter", "form", "receipt", "notice", "offer", "cancellation", "id_card", "business_card", "photo", "diagram", "screenshot", "medical", "unknown"]},
+    "senderName": {"type": "string"},
+    "senderType": {"type": "string", "enum": ["behörde", "schule", "universität", "firma", "bank", "versicherung", "arzt", "anwalt", "verein", "privat", "werbung", "unbekannt"]},
+    "context": {"type": "string", "enum": ["official", "education", "business", "medical", "legal", "financial", "private", "advertising", "unknown"]},
+    "urgency": {"type": "string", "enum": ["critical", "high", "normal", "low", "unknown"]},
+    "actionNeeded": {"type": "boolean"},
+    "summary": {"type": "string"},
+    "language": {"type": "string"}
+  },
+  "required": ["graphicElements", "layout", "logoDetected", "documentType", "senderName", "senderType", "context", "urgency", "actionNeeded", "summary", "language"],
+  "additionalProperties": false
+}`)
+
+// documentAnalysis ist die strukturierte Antwort von AnalyzeDocument
+type documentAnalysis struct {
+	GraphicElements string `json:"graphicElements"`
+	Layout          string `json:"layout"`
+	LogoDetected    bool   `json:"logoDetected"`
+	LogoDescription string `json:"logoDescription"`
+	DocumentType    string `json:"documentType"`
+	SenderName      string `json:"senderName"`
+	SenderType      string `json:"senderType"`
+	Context         string `json:"context"`
+	Urgency         string `json:"urgency"`
+	ActionNeeded    bool   `json:"actionNeeded"`
+	Summary         string `json:"summary"`
+	Language        string `json:"language"`
+}
+
 // AnalyzeDocument analysiert ein Dokument-Bild und extrahiert strukturierte Informationen
 // inkl. Kontext-Erkennung (Logo, Absender, Dringlichkeit)
 func (vs *VisionService) AnalyzeDocument(ctx context.Context, base64Image string) (*ImageAnalysisResult, error) {
-	// Vision-Modell extrahiert Text direkt aus dem Bild
-	ocrText := "" // Text wird aus der Vision-Antwort extrahiert
-
 	// Erweiterter Prompt mit Kontext-Erkennung UND grafischen Elementen
 	prompt := `Analysiere dieses Dokument VISUELL und INHALTLICH auf Deutsch.
 
@@ -188,7 +228,7 @@ func (vs *VisionService) AnalyzeDocument(ctx context.Context, base64Image string
    - Richtig: "Logo vorhanden, aber Details nicht klar erkennbar"
 
 3. MARKIERE UNSICHERHEIT: Bei unsicheren Erkennungen schreibe "(unsicher)" dahinter.
-   Beispiel: "ABSENDER_NAME: Müller GmbH (unsicher)"
+   Beispiel: senderName "Müller GmbH (unsicher)"
 
 4. QUALITÄT ZÄHLT: Lieber weniger Information die stimmt, als viel Information die falsch ist.
 
@@ -196,146 +236,74 @@ WICHTIG: Beschreibe ALLE sichtbaren Elemente - nicht nur den Text!
 
 === VISUELLE ANALYSE ===
 
-1. GRAFISCHE_ELEMENTE: Beschreibe alle visuellen Elemente die du siehst:
-   - Logos (Position, Farben, Form)
-   - Stempel oder Siegel
-   - Unterschriften (handschriftlich?)
-   - Tabellen oder Listen
-   - Grafiken, Diagramme, Bilder
-   - Farbige Bereiche, Hervorhebungen
-   - QR-Codes, Barcodes
-   - Wasserzeichen
-
-2. LAYOUT: Beschreibe das Layout des Dokuments:
-   - Kopfzeile/Briefkopf vorhanden?
-   - Spalten-Layout?
-   - Fußzeile mit Kontaktdaten?
-   - Seitenränder, Abstände
-
-3. LOGO_ERKANNT: Ist ein Logo oder Briefkopf sichtbar? (JA/NEIN)
-   Falls JA: Beschreibe das Logo kurz (Farben, Form, Text im Logo)
+- graphicElements: Beschreibe alle visuellen Elemente die du siehst:
+  Logos (Position, Farben, Form), Stempel oder Siegel, Unterschriften (handschriftlich?),
+  Tabellen oder Listen, Grafiken, Diagramme, Bilder, farbige Bereiche, Hervorhebungen,
+  QR-Codes, Barcodes, Wasserzeichen
+
+- layout: Kopfzeile/Briefkopf, Spalten-Layout, Fußzeile mit Kontaktdaten, Seitenränder, Abstände
+
+- logoDetected: Ist ein Logo oder Briefkopf sichtbar?
+  logoDescription: Falls ja, beschreibe das Logo kurz (Farben, Form, Text im Logo)
 
 === INHALTLICHE ANALYSE ===
 
-4. DOKUMENTTYP: Was für ein Dokument ist das?
-   (Brief, Rechnung, Mahnung, Vertrag, Formular, Bescheid, Mitteilung, Quittung, Angebot, Kündigung, oder Sonstiges)
+- documentType: invoice=Rechnung, reminder=Mahnung, contract=Vertrag, letter=Brief/Mitteilung,
+  form=Formular/Antrag, receipt=Quittung, notice=Bescheid, offer=Angebot, cancellation=Kündigung,
+  id_card=Ausweis, business_card=Visitenkarte, medical=Arztbrief/Befund, photo, diagram, screenshot, unknown
 
-5. ABSENDER_NAME: Wer ist der Absender? (Name der Organisation/Firma/Behörde/Schule, oder "Unbekannt")
+- senderName: Wer ist der Absender? (Name der Organisation/Firma/Behörde/Schule, oder "Unbekannt")
 
-6. ABSENDER_TYP: Was für ein Absender ist das?
-   (behörde, schule, universität, firma, bank, versicherung, arzt, anwalt, verein, privat, werbung, oder unbekannt)
+- senderType: Was für ein Absender ist das?
 
-7. KONTEXT: In welchem Kontext ist dieses Dokument einzuordnen?
-   (official=behördlich, education=Bildung, business=geschäftlich, medical=medizinisch, legal=rechtlich, financial=finanziell, private=privat, advertising=Werbung)
+- context: official=behördlich, education=Bildung, business=geschäftlich, medical=medizinisch,
+  legal=rechtlich, financial=finanziell, private=privat, advertising=Werbung
 
-8. DRINGLICHKEIT: Wie dringend erscheint dieses Dokument?
-   (critical=Fristen/Mahnungen/Sofort handeln, high=zeitnah bearbeiten, normal=normal bearbeiten, low=kann warten)
+- urgency: critical=Fristen/Mahnungen/Sofort handeln, high=zeitnah bearbeiten,
+  normal=normal bearbeiten, low=kann warten
 
-9. HANDLUNGSBEDARF: Erfordert das Dokument eine Reaktion/Handlung? (JA/NEIN)
+- actionNeeded: Erfordert das Dokument eine Reaktion/Handlung?
 
-10. ZUSAMMENFASSUNG: Fasse den Inhalt in 1-2 Sätzen zusammen.
+- summary: Fasse den Inhalt in 1-2 Sätzen zusammen.
 
-11. SPRACHE: In welcher Sprache ist das Dokument? (deutsch/englisch/andere)
+- language: In welcher Sprache ist das Dokument? (deutsch/englisch/andere)
 
-Antworte EXAKT in diesem Format:
-GRAFISCHE_ELEMENTE: [detaillierte Beschreibung aller visuellen Elemente]
-LAYOUT: [Beschreibung des Layouts]
-LOGO_ERKANNT: [JA/NEIN - falls JA, beschreibe es]
-DOKUMENTTYP: [typ]
-ABSENDER_NAME: [name]
-ABSENDER_TYP: [typ]
-KONTEXT: [kontext]
-DRINGLICHKEIT: [stufe]
-HANDLUNGSBEDARF: [JA/NEIN]
-ZUSAMMENFASSUNG: [text]
-SPRACHE: [sprache]`
+Antworte als JSON-Objekt mit genau diesen Feldern.`
 
-	response, err := vs.chatWithImage(ctx, prompt, base64Image, false, nil)
-	if err != nil {
+	var doc documentAnalysis
+	if err := vs.chatWithImageJSON(ctx, prompt, base64Image, documentAnalysisSchema, &doc); err != nil {
 		return nil, fmt.Errorf("Bildanalyse fehlgeschlagen: %w", err)
 	}
-
-	// Response parsen mit erweiterter Kontext-Erkennung
-	result := vs.parseDocumentResponseWithContext(response, ocrText)
-	return result, nil
+	return doc.toResult(), nil
 }
 
-// parseDocumentResponseWithContext parst die erweiterte Dokument-Antwort mit Kontext
-func (vs *VisionService) parseDocumentResponseWithContext(response, ocrText string) *ImageAnalysisResult {
+// toResult überträgt die strukturierte Antwort in ein ImageAnalysisResult
+func (d *documentAnalysis) toResult() *ImageAnalysisResult {
 	result := &ImageAnalysisResult{
-		Description:   response,
-		DocumentType:  DocTypeUnknown,
-		ExtractedText: ocrText,
-		IsDocument:    true,
-		Confidence:    0.5,
-		Context:       ContextUnknown,
-		Urgency:       UrgencyUnknown,
+		Description:     d.describe(),
+		DocumentType:    DocumentType(d.DocumentType),
+		IsDocument:      true,
+		Confidence:      0.5,
+		Language:        d.Language,
+		Context:         DocContext(d.Context),
+		Urgency:         Urgency(d.Urgency),
+		ActionNeeded:    d.ActionNeeded,
+		Summary:         d.Summary,
+		GraphicElements: d.GraphicElements,
+		Layout:          d.Layout,
+		Sender: &SenderInfo{
+			Type:         d.SenderType,
+			LogoDetected: d.LogoDetected,
+		},
 	}
-
-	lines := strings.Split(response, "\n")
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		parts := strings.SplitN(line, ":", 2)
-		if len(parts) != 2 {
-			continue
-		}
-		key := strings.TrimSpace(parts[0])
-		value := strings.TrimSpace(parts[1])
-		valueLower := strings.ToLower(value)
-
-		switch key {
-		// Visuelle Analyse (NEU)
-		case "GRAFISCHE_ELEMENTE":
-			result.GraphicElements = value
-
-		case "LAYOUT":
-			result.Layout = value
-
-		case "DOKUMENTTYP":
-			result.DocumentType = vs.extractDocumentType(value)
-
-		case "LOGO_ERKANNT":
-			if result.Sender == nil {
-				result.Sender = &SenderInfo{}
-			}
-			// Logo kann "JA" oder "JA - blaues Logo mit..." sein
-			result.Sender.LogoDetected = strings.HasPrefix(valueLower, "ja")
-
-		case "ABSENDER_NAME":
-			if result.Sender == nil {
-				result.Sender = &SenderInfo{}
-			}
-			if valueLower != "unbekannt" && valueLower != "unknown" {
-				result.Sender.Name = value
-			}
-
-		case "ABSENDER_TYP":
-			if result.Sender == nil {
-				result.Sender = &SenderInfo{}
-			}
-			result.Sender.Type = valueLower
-
-		case "KONTEXT":
-			result.Context = vs.parseContext(valueLower)
-
-		case "DRINGLICHKEIT":
-			result.Urgency = vs.parseUrgency(valueLower)
-
-		case "HANDLUNGSBEDARF":
-			result.ActionNeeded = valueLower == "ja" || valueLower == "yes"
-
-		case "ZUSAMMENFASSUNG":
-			result.Summary = value
-
-		case "SPRACHE":
-			result.Language = value
-		}
+	if name := strings.ToLower(d.SenderName); name != "unbekannt" && name != "unknown" {
+		result.Sender.Name = d.SenderName
 	}
 
 	// Konfidenz basierend auf erkannten Informationen
-	if result.GraphicElements != "" && result.Sender != nil && result.Sender.Name != "" {
+	if result.GraphicElements != "" && result.Sender.Name != "" {
 		result.Confidence = 0.90 // Visuelle + Inhaltliche Erkennung
-	} else if result.Sender != nil && result.Sender.Name != "" {
+	} else if result.Sender.Name != "" {
 		result.Confidence = 0.85
 	} else if result.DocumentType != DocTypeUnknown {
 		result.Confidence = 0.75
@@ -344,44 +312,29 @@ func (vs *VisionService) parseDocumentResponseWithContext(response, ocrText stri
 	return result
 }
 
-// parseContext konvertiert String zu DocContext
-func (vs *VisionService) parseContext(s string) DocContext {
-	switch {
-	case strings.Contains(s, "official") || strings.Contains(s, "behörd") || strings.Contains(s, "amt"):
-		return ContextOfficial
-	case strings.Contains(s, "education") || strings.Contains(s, "schul") || strings.Contains(s, "uni"):
-		return ContextEducation
-	case strings.Contains(s, "business") || strings.Contains(s, "geschäft") || strings.Contains(s, "firma"):
-		return ContextBusiness
-	case strings.Contains(s, "medical") || strings.Contains(s, "medizin") || strings.Contains(s, "arzt"):
-		return ContextMedical
-	case strings.Contains(s, "legal") || strings.Contains(s, "recht") || strings.Contains(s, "anwalt"):
-		return ContextLegal
-	case strings.Contains(s, "financial") || strings.Contains(s, "finanz") || strings.Contains(s, "bank"):
-		return ContextFinancial
-	case strings.Contains(s, "private") || strings.Contains(s, "privat"):
-		return ContextPrivate
-	case strings.Contains(s, "advertising") || strings.Contains(s, "werbung"):
-		return ContextAdvertising
-	default:
-		return ContextUnknown
-	}
-}
-
-// parseUrgency konvertiert String zu Urgency
-func (vs *VisionService) parseUrgency(s string) Urgency {
-	switch {
-	case strings.Contains(s, "critical") || strings.Contains(s, "sofort") || strings.Contains(s, "frist"):
-		return UrgencyCritical
-	case strings.Contains(s, "high") || strings.Contains(s, "hoch") || strings.Contains(s, "zeitnah"):
-		return UrgencyHigh
-	case strings.Contains(s, "normal"):
-		return UrgencyNormal
-	case strings.Contains(s, "low") || strings.Contains(s, "niedrig") || strings.Contains(s, "warten"):
-		return UrgencyLow
-	default:
-		return UrgencyUnknown
-	}
+// describe erzeugt die lesbare Beschreibung, die als Kontext an das Chat-Modell geht
+func (d *documentAnalysis) describe() string {
+	logo := "NEIN"
+	if d.LogoDetected {
+		logo = strings.TrimSpace("JA " + d.LogoDescription)
+	}
+	action := "NEIN"
+	if d.ActionNeeded {
+		action = "JA"
+	}
+	return strings.Join([]string{
+		"GRAFISCHE_ELEMENTE: " + d.GraphicElements,
+		"LAYOUT: " + d.Layout,
+		"LOGO_ERKANNT: " + logo,
+		"DOKUMENTTYP: " + d.DocumentType,
+		"ABSENDER_NAME: " + d.SenderName,
+		"ABSENDER_TYP: " + d.SenderType,
+		"KONTEXT: " + d.Context,
+		"DRINGLICHKEIT: " + d.Urgency,
+		"HANDLUNGSBEDARF: " + action,
+		"ZUSAMMENFASSUNG: " + d.Summary,
+		"SPRACHE: " + d.Language,
+	}, "\n")
 }
 
 // ClassifyImage klassifiziert ein Bild schnell (Dokument vs. Foto/Grafik)
@@ -439,6 +392,28 @@ Falls kein Text sichtbar ist, antworte mit: [KEIN TEXT ERKANNT]`
 
 // chatWithImage führt einen Chat mit Bild durch
 func (vs *VisionService) chatWithImage(ctx context.Context, prompt, base64Image string, stream bool, onChunk func(content string, done bool)) (string, error) {
+	return vs.chatWithImageFormat(ctx, prompt, base64Image, stream, onChunk, nil)
+}
+
+// chatWithImageJSON führt einen Chat mit Bild durch und erzwingt eine Antwort passend zum Schema
+func (vs *VisionService) chatWithImageJSON(ctx context.Context, prompt, base64Image string, schema *Schema, out interface{}) error {
+	format := &ResponseFormat{Type: FormatJSONSchema, Schema: schema}
+	response, err := vs.chatWithImageFormat(ctx, prompt, base64Image, false, nil, format)
+	if err != nil {
+		return err
+	}
+	response = strings.TrimSpace(response)
+	if err := format.Check(response); err != nil {
+		return err
+	}
+	if err := json.Unmarshal([]byte(response), out); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidStructuredOutput, err)
+	}
+	return nil
+}
+
+// chatWithImageFormat führt einen Chat mit Bild durch, optional mit erzwungenem Ausgabeformat
+func (vs *VisionService) chatWithImageFormat(ctx context.Context, prompt, base64Image string, stream bool, onChunk func(content string, done bool), format *ResponseFormat) (string, error) {
 	if !vs.IsAvailable() {
 		return "", fmt.Errorf("llama-server ist nicht verfügbar")
 	}
@@ -461,6 +436,9 @@ func (vs *VisionService) chatWithImage(ctx context.Context, prompt, base64Image
 		Temperature: 0.3, // Niedrigere Temperatur für präzisere Analyse
 		MaxTokens:   4096,
 	}
+	if format != nil {
+		requestBody.ResponseFormat, requestBody.Grammar = format.requestFields()
+	}
 
 	jsonBody, err := json.Marshal(requestBody)
 	if err != nil {
@@ -601,45 +579,6 @@ func (vs *VisionService) detectImageMimeType(base64Data string) string {
 	}
 }
 
-// parseDocumentResponse parst die strukturierte Dokument-Antwort
-func (vs *VisionService) parseDocumentResponse(response string) *ImageAnalysisResult {
-	result := &ImageAnalysisResult{
-		Description:  response,
-		DocumentType: DocTypeUnknown,
-		Confidence:   0.5,
-	}
-
-	lines := strings.Split(response, "\n")
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-
-		if strings.HasPrefix(line, "DOKUMENTTYP:") {
-			typeStr := strings.TrimSpace(strings.TrimPrefix(line, "DOKUMENTTYP:"))
-			result.DocumentType = vs.extractDocumentType(typeStr)
-		}
-
-		if strings.HasPrefix(line, "IST_TEXTDOKUMENT:") {
-			value := strings.ToUpper(strings.TrimSpace(strings.TrimPrefix(line, "IST_TEXTDOKUMENT:")))
-			result.IsDocument = value == "JA" || value == "YES" || value == "TRUE"
-		}
-
-		if strings.HasPrefix(line, "TEXT_INHALT:") {
-			result.ExtractedText = strings.TrimSpace(strings.TrimPrefix(line, "TEXT_INHALT:"))
-		}
-
-		if strings.HasPrefix(line, "SPRACHE:") {
-			result.Language = strings.TrimSpace(strings.TrimPrefix(line, "SPRACHE:"))
-		}
-	}
-
-	// Konfidenz basierend auf Dokumenttyp
-	if result.DocumentType != DocTypeUnknown {
-		result.Confidence = 0.8
-	}
-
-	return result
-}
-
 // extractDocumentType extrahiert den Dokumenttyp aus einem String
 func (vs *VisionService) extractDocumentType(typeStr string) DocumentType {
 	typeLower := strings.ToLower(typeStr)
@@ -0,0 +1,449 @@
+package llamaserver
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"fleet-navigator/internal/gguf"
+
+	"github.com/shirou/gopsutil/v3/mem"
+)
+
+// =============================================================================
+// VRAM/RAM-Planer
+// =============================================================================
+//
+// Der Planer berechnet aus dem GGUF-Header, wie viel Speicher ein Modell auf
+// der GPU belegt, und wie viele Layer (-ngl) in den freien VRAM passen:
+//
+//   - Gewichte je Layer (blk.N.*) aus der Tensor-Tabelle, Output-Layer separat
+//   - KV-Cache aus Context-Länge, KV-Heads, Head-Dimensionen und Cache-Typ
+//   - Compute-Buffer (Attention-Scores und Logits eines Micro-Batches)
+//   - CUDA-Kontext
+//
+// llama.cpp lagert bei -ngl N die letzten N Layer aus, bei N > block_count
+// zusätzlich den Output-Layer. Die Token-Embeddings bleiben immer im RAM.
+
+const (
+	// DefaultKVCacheType ist der KV-Cache-Typ von llama.cpp ohne --cache-type-k/v
+	DefaultKVCacheType = "f16"
+
+	// plannerUBatch entspricht dem Standard-Micro-Batch (-ub) von llama-server
+	plannerUBatch = 512
+
+	// cudaContextMB: CUDA-Kontext, cuBLAS-Workspace und Treiber-Reserven
+	cudaContextMB = 350
+
+	// heuristicOverheadMB: Overhead der Dateinamen-Schätzung (inkl. Compute-Buffer)
+	heuristicOverheadMB = 800
+)
+
+// kvCacheTypeBytes: Bytes pro Element je KV-Cache-Typ (Blockgröße eingerechnet)
+var kvCacheTypeBytes = map[string]float64{
+	"f32":    4,
+	"f16":    2,
+	"bf16":   2,
+	"q8_0":   34.0 / 32,
+	"q5_1":   24.0 / 32,
+	"q5_0":   22.0 / 32,
+	"q4_1":   20.0 / 32,
+	"q4_0":   18.0 / 32,
+	"iq4_nl": 18.0 / 32,
+}
+
+// KVCacheBytesPerElement gibt die Bytes pro Element eines KV-Cache-Typs zurück
+// (unbekannte Typen werden wie F16 behandelt)
+func KVCacheBytesPerElement(cacheType string) float64 {
+	if b, ok := kvCacheTypeBytes[strings.ToLower(cacheType)]; ok {
+		return b
+	}
+	return 2
+}
+
+// PlanOptions steuert die Offload-Planung
+type PlanOptions struct {
+	ContextSize  int    // Context-Größe in Tokens
+	CacheType    string // KV-Cache-Typ (f16, q8_0, q4_0, ...)
+	CacheTypeV   string // Abweichender V-Cache-Typ (leer = wie CacheType)
+	FlashAttn    bool   // Flash Attention: keine F32-Attention-Scores im Compute-Buffer
+	GPUAvailable bool   // false: keine VRAM-Information, nur Bedarf berechnen
+	FreeMB       int64  // Freier VRAM
+	ReserveMB    int64  // Für das System reservierter VRAM
+	ExtraMB      int64  // Zusätzlicher VRAM-Bedarf (Vision-Projektor, Draft-Modell)
+	RAMFreeMB    int64  // Verfügbarer Arbeitsspeicher (0 = unbekannt)
+}
+
+// cacheLabel beschreibt die KV-Cache-Typen (z.B. "q8_0" oder "K q8_0 / V q4_0")
+func (o PlanOptions) cacheLabel() string {
+	if o.CacheTypeV == "" || o.CacheTypeV == o.CacheType {
+		return o.CacheType
+	}
+	return fmt.Sprintf("K %s / V %s", o.CacheType, o.CacheTypeV)
+}
+
+// VRAMPlan beschreibt die berechnete Speicher-Aufteilung eines Modells
+type VRAMPlan struct {
+	Model        string `json:"model"`
+	Source       string `json:"source"` // "gguf" oder "heuristic"
+	Architecture string `json:"architecture,omitempty"`
+	Quantization string `json:"quantization,omitempty"`
+	ContextSize  int    `json:"contextSize"`
+	CacheType    string `json:"cacheType"`
+
+	TotalLayers  int  `json:"totalLayers"`  // Layer des Modells (block_count)
+	MaxGPULayers int  `json:"maxGpuLayers"` // -ngl für vollständigen Offload (inkl. Output-Layer)
+	GPULayers    int  `json:"gpuLayers"`    // Empfohlener -ngl Wert
+	FullOffload  bool `json:"fullOffload"`
+
+	WeightsMB       int64   `json:"weightsMb"`
+	LayerMB         float64 `json:"layerMb"` // Durchschnittliche Gewichte je Layer
+	OutputMB        int64   `json:"outputMb"`
+	KVCacheMB       int64   `json:"kvCacheMb"`
+	ComputeBufferMB int64   `json:"computeBufferMb"`
+	OverheadMB      int64   `json:"overheadMb"`
+	ExtraMB         int64   `json:"extraMb,omitempty"`
+	RequiredMB      int64   `json:"requiredMb"` // VRAM-Bedarf bei vollständigem Offload
+
+	GPUAvailable bool  `json:"gpuAvailable"`
+	FreeMB       int64 `json:"freeMb"`
+	ReserveMB    int64 `json:"reserveMb"`
+	BudgetMB     int64 `json:"budgetMb"`
+	GPUUsageMB   int64 `json:"gpuUsageMb"`  // Geplante VRAM-Belegung
+	HostUsageMB  int64 `json:"hostUsageMb"` // Geplante RAM-Belegung
+	RAMFreeMB    int64 `json:"ramFreeMb,omitempty"`
+
+	Steps    []string `json:"steps"` // Erklärung des Plans
+	Warnings []string `json:"warnings,omitempty"`
+
+	// Kosten je Layer in Bytes (Index = Layer), für die Berechnung je -ngl
+	layerWeights []uint64
+	layerKV      uint64
+	outputBytes  uint64
+	inputBytes   uint64
+	fixedBytes   uint64 // Compute-Buffer + CUDA-Kontext + Extra
+}
+
+const mb = 1024 * 1024
+
+// PlanGPUOffload berechnet den Speicherbedarf eines Modells und den größten
+// -ngl Wert, der in den freien VRAM passt.
+// Ist der GGUF-Header nicht lesbar, wird aus Dateigröße und Dateiname geschätzt.
+func PlanGPUOffload(modelPath string, opts PlanOptions) *VRAMPlan {
+	if opts.CacheType == "" {
+		opts.CacheType = DefaultKVCacheType
+	}
+
+	var plan *VRAMPlan
+	if meta, err := gguf.ReadInfo(modelPath); err == nil && meta.BlockCount > 0 && meta.TensorBytes > 0 {
+		plan = planFromGGUF(meta, opts)
+	} else {
+		plan = planFromHeuristic(modelPath, opts)
+	}
+	plan.Model = filepath.Base(modelPath)
+	plan.choose(opts)
+	return plan
+}
+
+// planFromGGUF berechnet die Kosten je Layer aus dem GGUF-Header
+func planFromGGUF(meta *gguf.ModelInfo, opts PlanOptions) *VRAMPlan {
+	bytesPerElement := KVCacheBytesPerElement(opts.CacheType)
+	if opts.CacheTypeV != "" && meta.KeyLength+meta.ValueLength > 0 {
+		// K und V mit unterschiedlichen Typen: nach Dimensionen gewichten
+		bytesPerElement = (float64(meta.KeyLength)*bytesPerElement + float64(meta.ValueLength)*KVCacheBytesPerElement(opts.CacheTypeV)) /
+			float64(meta.KeyLength+meta.ValueLength)
+	}
+	kvBytes := meta.KVCacheBytes(opts.ContextSize, bytesPerElement)
+
+	// Compute-Buffer: Attention-Scores (F32, ohne Flash Attention) und Logits
+	// eines Micro-Batches - der größte Graph, den llama.cpp reserviert
+	ubatch := uint64(min(plannerUBatch, max(opts.ContextSize, 1)))
+	computeBytes := meta.VocabSize * ubatch * 4
+	if !opts.FlashAttn {
+		computeBytes += uint64(opts.ContextSize) * ubatch * meta.HeadCount * 4
+	}
+
+	plan := &VRAMPlan{
+		Source:          "gguf",
+		Architecture:    meta.Architecture,
+		Quantization:    meta.Quantization,
+		TotalLayers:     len(meta.LayerBytes),
+		layerWeights:    meta.LayerBytes,
+		layerKV:         kvBytes / uint64(len(meta.LayerBytes)),
+		outputBytes:     meta.OutputBytes,
+		inputBytes:      meta.InputBytes,
+		ComputeBufferMB: int64(computeBytes / mb),
+		OverheadMB:      cudaContextMB,
+	}
+	plan.fixedBytes = computeBytes + uint64(cudaContextMB+max(opts.ExtraMB, 0))*mb
+
+	var layerSum uint64
+	for _, b := range meta.LayerBytes {
+		layerSum += b
+	}
+	plan.LayerMB = float64(layerSum) / float64(len(meta.LayerBytes)) / mb
+
+	plan.Steps = append(plan.Steps,
+		fmt.Sprintf("GGUF-Header: %s %s %s, %d Layer, %d KV-Heads × %d/%d Dimensionen",
+			meta.Architecture, meta.SizeLabel, meta.Quantization, plan.TotalLayers, meta.HeadCountKV, meta.KeyLength, meta.ValueLength),
+		fmt.Sprintf("Gewichte: %d Layer à %.0f MB + Output-Layer %d MB (Token-Embeddings %d MB bleiben im RAM)",
+			plan.TotalLayers, plan.LayerMB, meta.OutputBytes/mb, meta.InputBytes/mb),
+	)
+	kvStep := fmt.Sprintf("KV-Cache (%s, %d Tokens): %d MB (%.0f MB je Layer)",
+		opts.cacheLabel(), opts.ContextSize, kvBytes/mb, float64(plan.layerKV)/mb)
+	if meta.SlidingWindow > 0 && meta.SlidingWindow < uint64(opts.ContextSize) {
+		kvStep += fmt.Sprintf(", Sliding Window %d Tokens", meta.SlidingWindow)
+	}
+	plan.Steps = append(plan.Steps, kvStep,
+		fmt.Sprintf("Compute-Buffer: %d MB (Micro-Batch %d), CUDA-Kontext: %d MB", plan.ComputeBufferMB, ubatch, cudaContextMB))
+	return plan
+}
+
+// planFromHeuristic verteilt die Dateinamen-Schätzung gleichmäßig auf geschätzte Layer
+func planFromHeuristic(modelPath string, opts PlanOptions) *VRAMPlan {
+	plan := &VRAMPlan{Source: "heuristic", OverheadMB: heuristicOverheadMB}
+
+	stat, err := os.Stat(modelPath)
+	if err != nil {
+		plan.TotalLayers = 32
+		plan.layerWeights = make([]uint64, plan.TotalLayers)
+		plan.fixedBytes = uint64(6000+max(opts.ExtraMB, 0)) * mb
+		plan.Steps = append(plan.Steps, "Modelldatei nicht lesbar - Standard-Schätzung 6000 MB")
+		return plan
+	}
+
+	totalMB := estimateVRAMFromFilename(modelPath, stat.Size(), opts.ContextSize)
+	plan.TotalLayers = guessLayerCount(stat.Size())
+
+	perLayer := uint64(max(totalMB-heuristicOverheadMB, 0)) * mb / uint64(plan.TotalLayers)
+	plan.layerWeights = make([]uint64, plan.TotalLayers)
+	for i := range plan.layerWeights {
+		plan.layerWeights[i] = perLayer
+	}
+	plan.LayerMB = float64(perLayer) / mb
+	plan.fixedBytes = uint64(heuristicOverheadMB+max(opts.ExtraMB, 0)) * mb
+
+	plan.Steps = append(plan.Steps,
+		"GGUF-Header nicht lesbar - Schätzung aus Dateigröße und Dateiname",
+		fmt.Sprintf("Geschätzt: %d Layer à %.0f MB (Gewichte + KV-Cache), Overhead %d MB",
+			plan.TotalLayers, plan.LayerMB, heuristicOverheadMB),
+	)
+	return plan
+}
+
+// guessLayerCount schätzt die Layer-Anzahl aus der Dateigröße
+// (7B = 32 Layer, 13B = 40 Layer, 70B = 80 Layer)
+func guessLayerCount(fileSize int64) int {
+	sizeGB := float64(fileSize) / (1024 * 1024 * 1024)
+	switch {
+	case sizeGB < 3:
+		return 24 // 1-3B Modelle
+	case sizeGB < 6:
+		return 32 // 7B Modelle
+	case sizeGB < 10:
+		return 40 // 13B Modelle
+	case sizeGB < 25:
+		return 48 // 32B Modelle
+	default:
+		return 80 // 70B+ Modelle
+	}
+}
+
+// gpuBytes berechnet die VRAM-Belegung bei -ngl n
+func (p *VRAMPlan) gpuBytes(n int) uint64 {
+	total := p.fixedBytes
+	if n <= 0 {
+		// Auch ohne Layer auf der GPU reserviert das CUDA-Backend einen Kontext
+		return uint64(p.OverheadMB) * mb
+	}
+	layers := min(n, len(p.layerWeights))
+	for _, w := range p.layerWeights[len(p.layerWeights)-layers:] {
+		total += w + p.layerKV
+	}
+	if n > len(p.layerWeights) {
+		total += p.outputBytes
+	}
+	return total
+}
+
+// hostBytes berechnet die RAM-Belegung bei -ngl n (ohne mmap-Seitencache)
+func (p *VRAMPlan) hostBytes(n int) uint64 {
+	total := p.inputBytes
+	cpuLayers := len(p.layerWeights) - min(max(n, 0), len(p.layerWeights))
+	for _, w := range p.layerWeights[:cpuLayers] {
+		total += w + p.layerKV
+	}
+	if n <= len(p.layerWeights) {
+		total += p.outputBytes
+	}
+	return total
+}
+
+// choose wählt den größten -ngl Wert, der ins VRAM-Budget passt
+func (p *VRAMPlan) choose(opts PlanOptions) {
+	p.ContextSize = opts.ContextSize
+	p.CacheType = opts.cacheLabel()
+	p.MaxGPULayers = p.TotalLayers + 1
+	p.ExtraMB = max(opts.ExtraMB, 0)
+	p.RAMFreeMB = opts.RAMFreeMB
+
+	var weights uint64
+	for _, w := range p.layerWeights {
+		weights += w
+	}
+	p.WeightsMB = int64((weights + p.outputBytes) / mb)
+	p.OutputMB = int64(p.outputBytes / mb)
+	p.KVCacheMB = int64(p.layerKV * uint64(p.TotalLayers) / mb)
+	p.RequiredMB = int64(p.gpuBytes(p.MaxGPULayers) / mb)
+	p.Steps = append(p.Steps, fmt.Sprintf("Bedarf bei vollständigem Offload: %d MB", p.RequiredMB))
+	if p.ExtraMB > 0 {
+		p.Steps = append(p.Steps, fmt.Sprintf("Zusätzlich reserviert (Vision-Projektor/Draft-Modell): %d MB", p.ExtraMB))
+	}
+
+	p.GPUAvailable = opts.GPUAvailable
+	if !opts.GPUAvailable {
+		p.GPULayers = p.MaxGPULayers
+		p.FullOffload = true
+		p.GPUUsageMB = p.RequiredMB
+		p.HostUsageMB = int64(p.hostBytes(p.GPULayers) / mb)
+		p.Steps = append(p.Steps, "Keine VRAM-Information verfügbar - Plan ohne Budget-Prüfung")
+		return
+	}
+
+	p.FreeMB = opts.FreeMB
+	p.ReserveMB = max(opts.ReserveMB, 0)
+	p.BudgetMB = max(p.FreeMB-p.ReserveMB, 0)
+	budget := uint64(p.BudgetMB) * mb
+	p.Steps = append(p.Steps, fmt.Sprintf("Freier VRAM %d MB - Reserve %d MB = Budget %d MB", p.FreeMB, p.ReserveMB, p.BudgetMB))
+
+	// gpuBytes wächst monoton mit n - vom vollständigen Offload abwärts suchen
+	p.GPULayers = 0
+	for n := p.MaxGPULayers; n > 0; n-- {
+		if p.gpuBytes(n) <= budget {
+			p.GPULayers = n
+			break
+		}
+	}
+	p.FullOffload = p.GPULayers == p.MaxGPULayers
+	p.GPUUsageMB = int64(p.gpuBytes(p.GPULayers) / mb)
+	p.HostUsageMB = int64(p.hostBytes(p.GPULayers) / mb)
+
+	switch {
+	case p.FullOffload:
+		p.Steps = append(p.Steps, fmt.Sprintf("Modell passt vollständig auf die GPU: -ngl %d (%d MB VRAM)", p.GPULayers, p.GPUUsageMB))
+	case p.GPULayers > 0:
+		p.Steps = append(p.Steps, fmt.Sprintf("Teil-Offload: %d von %d Layern auf die GPU (-ngl %d, %d MB VRAM), Rest im RAM (%d MB)",
+			min(p.GPULayers, p.TotalLayers), p.TotalLayers, p.GPULayers, p.GPUUsageMB, p.HostUsageMB))
+		p.Warnings = append(p.Warnings, "Teil-Offload: Generierung deutlich langsamer als mit vollständigem Offload")
+	default:
+		p.Steps = append(p.Steps, fmt.Sprintf("Budget reicht nicht für Compute-Buffer und einen Layer - nur CPU (-ngl 0, %d MB RAM)", p.HostUsageMB))
+		p.Warnings = append(p.Warnings, "Kein Layer passt auf die GPU")
+	}
+
+	if p.RAMFreeMB > 0 && p.HostUsageMB > p.RAMFreeMB {
+		p.Warnings = append(p.Warnings, fmt.Sprintf("Zu wenig Arbeitsspeicher: %d MB benötigt, %d MB verfügbar", p.HostUsageMB, p.RAMFreeMB))
+	}
+}
+
+// limitGPULayers begrenzt einen konfigurierten -ngl Wert auf das, was laut Plan passt
+func (p *VRAMPlan) limitGPULayers(configured int) int {
+	if !p.GPUAvailable || p.FullOffload || configured <= p.GPULayers {
+		return configured
+	}
+	log.Printf("⚠️ -ngl %d passt nicht in %d MB VRAM, reduziere auf %d", configured, p.BudgetMB, p.GPULayers)
+	return p.GPULayers
+}
+
+// PlanVRAM erstellt einen Offload-Plan für ein Modell mit dem aktuell freien VRAM
+func (s *Server) PlanVRAM(modelPath string, contextSize int) *VRAMPlan {
+	return s.PlanVRAMWithCacheType(modelPath, contextSize, "")
+}
+
+// PlanVRAMWithCacheType plant mit einem abweichenden KV-Cache-Typ (leer = Standard)
+func (s *Server) PlanVRAMWithCacheType(modelPath string, contextSize int, cacheType string) *VRAMPlan {
+	info := GetVRAMInfo()
+	opts := s.planOptions(modelPath, contextSize, info.Available, info.FreeMB)
+	if cacheType != "" {
+		opts.CacheType = cacheType
+		opts.CacheTypeV = ""
+	}
+	return PlanGPUOffload(modelPath, opts)
+}
+
+// planForSwitch plant ein neues Modell mit dem VRAM, der nach dem Stoppen
+// des aktuellen Modells frei wäre
+func (s *Server) planForSwitch(modelPath string, contextSize int) *VRAMPlan {
+	info := GetVRAMInfo()
+	freeMB := info.FreeMB
+	if current := s.CurrentPlan(); current != nil && s.IsRunning() {
+		freeMB += current.GPUUsageMB
+		if freeMB > info.TotalMB {
+			freeMB = info.TotalMB
+		}
+	}
+	return PlanGPUOffload(modelPath, s.planOptions(modelPath, contextSize, info.Available, freeMB))
+}
+
+// planOptions befüllt die Planungs-Optionen aus der Server-Konfiguration
+func (s *Server) planOptions(modelPath string, contextSize int, gpuAvailable bool, freeMB int64) PlanOptions {
+	// Konfiguration einmal unter der Sperre lesen - Einstellungen können sich parallel ändern
+	s.mu.RLock()
+	cfg := s.config
+	slots := s.parallelSlotsLocked()
+	s.mu.RUnlock()
+
+	// Jeder Slot bekommt den vollen Context - der KV-Cache wächst mit der Slot-Anzahl
+	opts := PlanOptions{
+		ContextSize:  contextSize * slots,
+		CacheType:    DefaultKVCacheType,
+		GPUAvailable: gpuAvailable,
+		FreeMB:       freeMB,
+		ReserveMB:    int64(cfg.VRAMReserve),
+		FlashAttn:    cfg.FlashAttention == FlashAttentionOn,
+	}
+	if cfg.CacheTypeK != "" {
+		opts.CacheType = cfg.CacheTypeK
+	}
+	if v := cfg.CacheTypeV; v != "" && v != opts.CacheType {
+		opts.CacheTypeV = v
+	}
+
+	// Vision-Projektor liegt zusätzlich im VRAM
+	if cfg.VisionEnabled {
+		mmproj := cfg.MmprojPath
+		if mmproj == "" {
+			mmproj = s.findMmprojForModel(modelPath)
+		}
+		if stat, err := os.Stat(mmproj); mmproj != "" && err == nil {
+			opts.ExtraMB = stat.Size() / mb
+		}
+	}
+
+	// Draft-Modell für Speculative Decoding liegt ebenfalls im VRAM
+	opts.ExtraMB += s.draftReserveMB(modelPath, cfg.Draft, opts)
+
+	if vmem, err := mem.VirtualMemory(); err == nil {
+		opts.RAMFreeMB = int64(vmem.Available / mb)
+	}
+	return opts
+}
+
+// CurrentPlan gibt den Offload-Plan des geladenen Modells zurück (nil wenn keiner)
+func (s *Server) CurrentPlan() *VRAMPlan {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.plan
+}
+
+// logPlan schreibt die Erklärung eines Plans ins Log
+func logPlan(plan *VRAMPlan) {
+	log.Printf("VRAM-Plan %s: -ngl %d/%d, VRAM %d MB, RAM %d MB", plan.Model, plan.GPULayers, plan.MaxGPULayers, plan.GPUUsageMB, plan.HostUsageMB)
+	for _, step := range plan.Steps {
+		log.Printf("   %s", step)
+	}
+	for _, warning := range plan.Warnings {
+		log.Printf("   ⚠️ %s", warning)
+	}
+}
@@ -0,0 +1,133 @@
+package llamaserver
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"fleet-navigator/internal/gguf"
+)
+
+// writePlannerModel erzeugt ein synthetisches Modell mit 8 Layern à 1024MB (F16),
+// Output-Layer 512MB und Token-Embeddings 512MB
+func writePlannerModel(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "planner-test.gguf")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	tensors := []gguf.TensorInfo{
+		{Name: "token_embd.weight", Dimensions: []uint64{1 << 10, 1 << 18}, Type: 1},
+		{Name: "output.weight", Dimensions: []uint64{1 << 10, 1 << 18}, Type: 1},
+	}
+	for i := 0; i < 8; i++ {
+		tensors = append(tensors, gguf.TensorInfo{
+			Name: fmt.Sprintf("blk.%d.ffn_up.weight", i), Dimensions: []uint64{1 << 10, 1 << 19}, Type: 1,
+		})
+	}
+	err = gguf.Write(f, []gguf.KV{
+		{Key: "general.architecture", Value: "llama"},
+		{Key: "llama.context_length", Value: uint32(8192)},
+		{Key: "llama.block_count", Value: uint32(8)},
+		{Key: "llama.embedding_length", Value: uint32(4096)},
+		{Key: "llama.attention.head_count", Value: uint32(32)},
+		{Key: "llama.attention.head_count_kv", Value: uint32(8)},
+	}, tensors)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// TestPlanGPUOffload prüft die Wahl von -ngl anhand des VRAM-Budgets
+func TestPlanGPUOffload(t *testing.T) {
+	path := writePlannerModel(t)
+
+	// Context 4096: KV-Cache 4096 * 8 Layer * 8 * 256 * 2 Bytes = 128MB (16MB je Layer),
+	// Compute-Buffer 4096 * 512 * 32 * 4 Bytes = 256MB
+	fixedMB := int64(256 + cudaContextMB)
+	requiredMB := 8*(1024+16) + 512 + fixedMB
+
+	tests := []struct {
+		name     string
+		freeMB   int64
+		wantNGL  int
+		wantFull bool
+	}{
+		{"Alles passt", 16000, 9, true},
+		{"Ohne Output-Layer", requiredMB - 1, 8, false},
+		// Budget 5500MB: 606MB fix + 4 Layer à 1040MB = 4766MB, 5 Layer wären 5806MB
+		{"Teil-Offload", 6000, 4, false},
+		{"Nur CPU", 700, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reserve := int64(500)
+			if tt.name == "Ohne Output-Layer" {
+				reserve = 0
+			}
+			plan := PlanGPUOffload(path, PlanOptions{
+				ContextSize:  4096,
+				GPUAvailable: true,
+				FreeMB:       tt.freeMB,
+				ReserveMB:    reserve,
+			})
+			if plan.Source != "gguf" || plan.TotalLayers != 8 {
+				t.Fatalf("Plan aus %s mit %d Layern", plan.Source, plan.TotalLayers)
+			}
+			if plan.RequiredMB != requiredMB {
+				t.Errorf("RequiredMB = %d, erwartet %d", plan.RequiredMB, requiredMB)
+			}
+			if plan.GPULayers != tt.wantNGL || plan.FullOffload != tt.wantFull {
+				t.Errorf("-ngl %d (voll: %v), erwartet %d (voll: %v)", plan.GPULayers, plan.FullOffload, tt.wantNGL, tt.wantFull)
+			}
+			if plan.GPULayers > 0 && plan.GPUUsageMB > plan.BudgetMB {
+				t.Errorf("Geplanter VRAM %dMB überschreitet Budget %dMB", plan.GPUUsageMB, plan.BudgetMB)
+			}
+			if len(plan.Steps) == 0 {
+				t.Error("Plan ohne Erklärung")
+			}
+		})
+	}
+}
+
+// TestPlanGPUOffload_CacheType prüft dass ein quantisierter KV-Cache mehr Layer erlaubt
+func TestPlanGPUOffload_CacheType(t *testing.T) {
+	path := writePlannerModel(t)
+
+	opts := PlanOptions{ContextSize: 65536, GPUAvailable: true, FreeMB: 10000}
+	f16 := PlanGPUOffload(path, opts)
+	opts.CacheType = "q4_0"
+	q4 := PlanGPUOffload(path, opts)
+
+	// F16: 65536 * 8 * 8 * 256 * 2 Bytes = 2048MB, Q4_0: 18/32 davon
+	if f16.KVCacheMB != 2048 || q4.KVCacheMB != 576 {
+		t.Errorf("KV-Cache F16 %dMB / Q4_0 %dMB, erwartet 2048 / 576", f16.KVCacheMB, q4.KVCacheMB)
+	}
+	if q4.GPULayers <= f16.GPULayers {
+		t.Errorf("Q4_0-Cache sollte mehr Layer erlauben: %d <= %d", q4.GPULayers, f16.GPULayers)
+	}
+}
+
+// TestPlanGPUOffload_Heuristic prüft den Fallback ohne lesbaren GGUF-Header
+func TestPlanGPUOffload_Heuristic(t *testing.T) {
+	modelPath := filepath.Join(t.TempDir(), "llama-7b-q4_k_m.gguf")
+	if err := createFakeGGUF(modelPath, 4*1024*1024*1024); err != nil {
+		t.Fatalf("Fake-GGUF erstellen: %v", err)
+	}
+
+	plan := PlanGPUOffload(modelPath, PlanOptions{ContextSize: 8192, GPUAvailable: true, FreeMB: 4000})
+	if plan.Source != "heuristic" || plan.TotalLayers != 32 {
+		t.Fatalf("Plan aus %s mit %d Layern, erwartet heuristic/32", plan.Source, plan.TotalLayers)
+	}
+	if estimate := EstimateModelVRAMWithContext(modelPath, 8192); plan.RequiredMB < estimate-1 || plan.RequiredMB > estimate {
+		t.Errorf("RequiredMB = %d, Schätzung %d", plan.RequiredMB, estimate)
+	}
+	if plan.FullOffload || plan.GPULayers == 0 || plan.GPUUsageMB > plan.BudgetMB {
+		t.Errorf("Erwartet Teil-Offload innerhalb des Budgets: -ngl %d, %dMB von %dMB", plan.GPULayers, plan.GPUUsageMB, plan.BudgetMB)
+	}
+}
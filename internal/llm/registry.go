@@ -54,6 +54,8 @@ type ModelRegistryEntry struct {
 	OllamaName      string        `json:"ollama_name,omitempty"` // z.B. "qwen2.5:7b"
 	// Context-Größe
 	ContextSize     int           `json:"context_size,omitempty"` // Max. Context in Tokens (z.B. 32768, 131072)
+	// Integrität
+	SHA256          string        `json:"sha256,omitempty"` // Erwartete SHA-256 der GGUF-Datei (sonst aus HuggingFace LFS-Metadaten)
 }
 
 // ModelRegistry verwaltet den Modell-Katalog
@@ -0,0 +1,364 @@
+package observer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Regel-Typen für Alarme
+const (
+	AlertChanged   = "changed"    // Neuer Wert weicht vom Vorwert ab (z.B. Leitzinsänderung)
+	AlertAbove     = "above"      // Wert steigt über die Schwelle
+	AlertBelow     = "below"      // Wert fällt unter die Schwelle
+	AlertChangePct = "change_pct" // Veränderung zum Vorwert um mindestens Threshold Prozent
+	AlertNoData    = "no_data"    // Seit Days Tagen kein neuer Stichtag
+)
+
+// Zustellkanäle für Alarme
+const (
+	AlertChannelChat    = "chat"    // Systemnachricht in einem festgelegten Chat
+	AlertChannelMates   = "mates"   // Event an alle verbundenen Mates
+	AlertChannelWebhook = "webhook" // HTTP POST mit dem Alarm als JSON
+)
+
+// alertWebhookTimeout begrenzt die Zustellung per Webhook, damit ein Sammellauf nicht hängt
+const alertWebhookTimeout = 10 * time.Second
+
+// AlertRule ist eine Alarm-Regel für einen Indikator
+type AlertRule struct {
+	ID              int64      `json:"id"`
+	IndicatorCode   string     `json:"indicatorCode"`
+	Type            string     `json:"type"`
+	Threshold       float64    `json:"threshold,omitempty"` // Schwelle (above/below) bzw. Prozent (change_pct)
+	Days            int        `json:"days,omitempty"`      // Tage ohne neuen Wert (no_data)
+	Channels        []string   `json:"channels"`
+	ChatID          int64      `json:"chatId,omitempty"`     // Ziel-Chat für Kanal "chat"
+	WebhookURL      string     `json:"webhookUrl,omitempty"` // Ziel-URL für Kanal "webhook"
+	Active          bool       `json:"active"`
+	LastTriggeredAt *time.Time `json:"lastTriggeredAt,omitempty"`
+	CreatedAt       time.Time  `json:"createdAt"`
+}
+
+// AlertEvent ist ein ausgelöster Alarm (Historie)
+type AlertEvent struct {
+	ID             int64     `json:"id"`
+	RuleID         int64     `json:"ruleId"`
+	RunID          int64     `json:"runId"`
+	IndicatorCode  string    `json:"indicatorCode"`
+	RuleType       string    `json:"ruleType"`
+	Value          float64   `json:"value"`
+	PreviousValue  *float64  `json:"previousValue,omitempty"`
+	ObservedAt     time.Time `json:"observedAt"`
+	Message        string    `json:"message"`
+	Delivered      []string  `json:"delivered"`                // Erfolgreich zugestellte Kanäle
+	DeliveryErrors []string  `json:"deliveryErrors,omitempty"` // Fehler je Kanal
+	TriggeredAt    time.Time `json:"triggeredAt"`
+}
+
+// Validate prüft und normalisiert eine Regel
+func (rule *AlertRule) Validate() error {
+	rule.IndicatorCode = strings.ToUpper(strings.TrimSpace(rule.IndicatorCode))
+	if rule.IndicatorCode == "" {
+		return fmt.Errorf("Indikator-Code fehlt")
+	}
+
+	rule.Type = strings.ToLower(strings.TrimSpace(rule.Type))
+	switch rule.Type {
+	case AlertChanged, AlertAbove, AlertBelow:
+	case AlertChangePct:
+		if rule.Threshold <= 0 {
+			return fmt.Errorf("Regel %s benötigt threshold > 0 (Prozent)", rule.Type)
+		}
+	case AlertNoData:
+		if rule.Days <= 0 {
+			return fmt.Errorf("Regel %s benötigt days > 0", rule.Type)
+		}
+	default:
+		return fmt.Errorf("Unbekannter Regel-Typ: %q", rule.Type)
+	}
+
+	if len(rule.Channels) == 0 {
+		return fmt.Errorf("Mindestens ein Kanal erforderlich (chat, mates, webhook)")
+	}
+	channels := make([]string, 0, len(rule.Channels))
+	seen := make(map[string]bool)
+	for _, channel := range rule.Channels {
+		channel = strings.ToLower(strings.TrimSpace(channel))
+		if seen[channel] {
+			continue
+		}
+		seen[channel] = true
+
+		switch channel {
+		case AlertChannelChat:
+			if rule.ChatID <= 0 {
+				return fmt.Errorf("Kanal chat benötigt chatId")
+			}
+		case AlertChannelMates:
+		case AlertChannelWebhook:
+			u, err := url.Parse(rule.WebhookURL)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return fmt.Errorf("Kanal webhook benötigt eine http(s)-URL")
+			}
+		default:
+			return fmt.Errorf("Unbekannter Kanal: %q", channel)
+		}
+		channels = append(channels, channel)
+	}
+	rule.Channels = channels
+
+	return nil
+}
+
+// evaluateRule prüft eine Regel gegen die neuesten Werte (values absteigend nach Stichtag, max. 2).
+// Wert-Regeln lösen nur bei Werten aus, die der Lauf runID geliefert oder revidiert hat - so wird
+// dieselbe Änderung nicht bei jedem Lauf erneut gemeldet. Gibt nil zurück wenn die Regel nicht greift.
+func evaluateRule(rule AlertRule, ind *Indicator, values []ObservationValue, runID int64, now time.Time) *AlertEvent {
+	if len(values) == 0 {
+		return nil
+	}
+	latest := values[0]
+	var previous *ObservationValue
+	if len(values) > 1 {
+		previous = &values[1]
+	}
+
+	label := fmt.Sprintf("%s (%s)", ind.Name, ind.Code)
+	var message string
+
+	switch rule.Type {
+	case AlertNoData:
+		age := int(now.Sub(latest.ObservedAt).Hours() / 24)
+		if age <= rule.Days {
+			return nil
+		}
+		// Pro ausbleibendem Wert nur einmal melden
+		if rule.LastTriggeredAt != nil && rule.LastTriggeredAt.After(latest.CollectedAt) {
+			return nil
+		}
+		message = fmt.Sprintf("%s: seit %d Tagen kein neuer Wert (letzter Stichtag %s, Regel: %d Tage)",
+			label, age, latest.ObservedAt.Format("02.01.2006"), rule.Days)
+		previous = nil
+
+	case AlertChanged:
+		if latest.RunID != runID || previous == nil || latest.Value == previous.Value {
+			return nil
+		}
+		message = fmt.Sprintf("%s hat sich geändert: %s → %s (Stichtag %s)",
+			label, formatAlertValue(previous.Value, ind.Unit), formatAlertValue(latest.Value, ind.Unit), latest.ObservedAt.Format("02.01.2006"))
+
+	case AlertChangePct:
+		if latest.RunID != runID || previous == nil || previous.Value == 0 {
+			return nil
+		}
+		pct := (latest.Value - previous.Value) / math.Abs(previous.Value) * 100
+		if math.Abs(pct) < rule.Threshold {
+			return nil
+		}
+		message = fmt.Sprintf("%s: Veränderung %+.2f%% zum Vorwert (%s → %s, Stichtag %s, Schwelle %s%%)",
+			label, pct, formatAlertValue(previous.Value, ind.Unit), formatAlertValue(latest.Value, ind.Unit),
+			latest.ObservedAt.Format("02.01.2006"), strconv.FormatFloat(rule.Threshold, 'f', -1, 64))
+
+	case AlertAbove, AlertBelow:
+		if latest.RunID != runID {
+			return nil
+		}
+		// Nur beim Überschreiten der Schwelle melden, nicht solange der Wert darüber bleibt
+		above := rule.Type == AlertAbove
+		crossed := func(v float64) bool {
+			if above {
+				return v > rule.Threshold
+			}
+			return v < rule.Threshold
+		}
+		if !crossed(latest.Value) || (previous != nil && crossed(previous.Value)) {
+			return nil
+		}
+		direction := "über"
+		if !above {
+			direction = "unter"
+		}
+		message = fmt.Sprintf("%s liegt %s der Schwelle %s: %s (Stichtag %s)",
+			label, direction, formatAlertValue(rule.Threshold, ind.Unit), formatAlertValue(latest.Value, ind.Unit),
+			latest.ObservedAt.Format("02.01.2006"))
+
+	default:
+		return nil
+	}
+
+	event := &AlertEvent{
+		RuleID:         rule.ID,
+		RunID:          runID,
+		IndicatorCode:  ind.Code,
+		RuleType:       rule.Type,
+		Value:          latest.Value,
+		ObservedAt:     latest.ObservedAt,
+		Message:        message,
+		Delivered:      make([]string, 0),
+		DeliveryErrors: make([]string, 0),
+		TriggeredAt:    now,
+	}
+	if previous != nil {
+		prev := previous.Value
+		event.PreviousValue = &prev
+	}
+	return event
+}
+
+// formatAlertValue formatiert einen Wert mit Einheit ohne unnötige Nachkommastellen
+func formatAlertValue(value float64, unit string) string {
+	s := strconv.FormatFloat(value, 'f', -1, 64)
+	if unit == "" {
+		return s
+	}
+	return s + " " + unit
+}
+
+// evaluateAlerts prüft alle aktiven Regeln nach einem Sammellauf und stellt ausgelöste Alarme zu
+func (s *Service) evaluateAlerts(ctx context.Context, run *ObservationRun) {
+	rules, err := s.repo.GetAlertRules(true)
+	if err != nil {
+		log.Printf("Observer: Alarm-Regeln laden fehlgeschlagen: %v", err)
+		return
+	}
+
+	now := time.Now()
+	triggered := 0
+	for _, rule := range rules {
+		ind, err := s.repo.GetIndicatorByCode(rule.IndicatorCode)
+		if err != nil || ind == nil {
+			continue
+		}
+		values, err := s.repo.GetValuesByIndicator(ind.ID, 2)
+		if err != nil {
+			log.Printf("Observer: Werte für Alarm-Regel %d laden fehlgeschlagen: %v", rule.ID, err)
+			continue
+		}
+
+		event := evaluateRule(rule, ind, values, run.ID, now)
+		if event == nil {
+			continue
+		}
+		s.deliverAlert(ctx, rule, event)
+		if err := s.repo.CreateAlertEvent(event); err != nil {
+			log.Printf("Observer: %v", err)
+			continue
+		}
+		triggered++
+	}
+
+	if triggered > 0 {
+		log.Printf("Observer: %d Alarm(e) nach Sammellauf %d ausgelöst", triggered, run.ID)
+	}
+}
+
+// deliverAlert stellt einen Alarm über alle Kanäle der Regel zu und vermerkt das Ergebnis am Event
+func (s *Service) deliverAlert(ctx context.Context, rule AlertRule, event *AlertEvent) {
+	for _, channel := range rule.Channels {
+		var err error
+		switch channel {
+		case AlertChannelChat:
+			if s.OnAlertChat == nil {
+				err = fmt.Errorf("Chat-Zustellung nicht verfügbar")
+			} else {
+				err = s.OnAlertChat(rule.ChatID, "Observer-Alarm: "+event.Message)
+			}
+		case AlertChannelMates:
+			if s.OnAlertBroadcast == nil {
+				err = fmt.Errorf("Mate-Zustellung nicht verfügbar")
+			} else {
+				s.OnAlertBroadcast(*event)
+			}
+		case AlertChannelWebhook:
+			err = postAlertWebhook(ctx, rule.WebhookURL, event)
+		default:
+			err = fmt.Errorf("Unbekannter Kanal")
+		}
+
+		if err != nil {
+			log.Printf("Observer: Alarm %s/%s über %s nicht zugestellt: %v", event.IndicatorCode, event.RuleType, channel, err)
+			event.DeliveryErrors = append(event.DeliveryErrors, fmt.Sprintf("%s: %v", channel, err))
+			continue
+		}
+		event.Delivered = append(event.Delivered, channel)
+	}
+}
+
+// postAlertWebhook sendet den Alarm als JSON an eine Webhook-URL
+func postAlertWebhook(ctx context.Context, webhookURL string, event *AlertEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, alertWebhookTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhookURL, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "FleetNavigator-Observer/1.0")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("Webhook-Aufruf fehlgeschlagen: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("Webhook antwortet mit HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// CreateAlertRule legt eine Alarm-Regel an (immer aktiv)
+func (s *Service) CreateAlertRule(rule *AlertRule) error {
+	if err := rule.Validate(); err != nil {
+		return err
+	}
+
+	ind, err := s.repo.GetIndicatorByCode(rule.IndicatorCode)
+	if err != nil {
+		return err
+	}
+	if ind == nil {
+		return fmt.Errorf("Indikator nicht gefunden: %s", rule.IndicatorCode)
+	}
+
+	rule.ID = 0
+	rule.Active = true
+	rule.LastTriggeredAt = nil
+	rule.CreatedAt = time.Now()
+	if err := s.repo.CreateAlertRule(rule); err != nil {
+		return err
+	}
+
+	log.Printf("Observer: Alarm-Regel %d angelegt (%s %s -> %s)", rule.ID, rule.IndicatorCode, rule.Type, strings.Join(rule.Channels, ", "))
+	return nil
+}
+
+// GetAlertRules gibt alle Alarm-Regeln zurück
+func (s *Service) GetAlertRules() ([]AlertRule, error) {
+	return s.repo.GetAlertRules(false)
+}
+
+// DeleteAlertRule löscht eine Alarm-Regel
+func (s *Service) DeleteAlertRule(id int64) (bool, error) {
+	return s.repo.DeleteAlertRule(id)
+}
+
+// GetAlertHistory gibt die ausgelösten Alarme zurück (neueste zuerst)
+func (s *Service) GetAlertHistory(indicatorCode string, limit int) ([]AlertEvent, error) {
+	return s.repo.GetAlertEvents(strings.ToUpper(indicatorCode), limit)
+}
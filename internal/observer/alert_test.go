@@ -0,0 +1,172 @@
+package observer
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestEvaluateRule prüft die Regel-Typen gegen die letzten beiden Werte
+func TestEvaluateRule(t *testing.T) {
+	now := time.Date(2024, 10, 18, 12, 0, 0, 0, time.UTC)
+	ind := &Indicator{Code: "GOLD_EUR", Name: "Gold", Unit: "EUR"}
+	values := func(runID int64, latest, previous float64) []ObservationValue {
+		return []ObservationValue{
+			{RunID: runID, ObservedAt: now.AddDate(0, 0, -1), CollectedAt: now, Value: latest},
+			{RunID: 1, ObservedAt: now.AddDate(0, 0, -2), CollectedAt: now.AddDate(0, 0, -1), Value: previous},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		rule    AlertRule
+		values  []ObservationValue
+		want    bool
+		wantMsg string
+	}{
+		{"Änderung", AlertRule{Type: AlertChanged}, values(2, 4.25, 4.5), true, "4.5 EUR → 4.25 EUR"},
+		{"Keine Änderung", AlertRule{Type: AlertChanged}, values(2, 4.5, 4.5), false, ""},
+		{"Änderung aus früherem Lauf", AlertRule{Type: AlertChanged}, values(1, 4.25, 4.5), false, ""},
+		{"Tagesbewegung über 3%", AlertRule{Type: AlertChangePct, Threshold: 3}, values(2, 2400, 2320), true, "+3.45%"},
+		{"Rückgang über 3%", AlertRule{Type: AlertChangePct, Threshold: 3}, values(2, 2240, 2320), true, "-3.45%"},
+		{"Tagesbewegung unter 3%", AlertRule{Type: AlertChangePct, Threshold: 3}, values(2, 2350, 2320), false, ""},
+		{"Schwelle überschritten", AlertRule{Type: AlertAbove, Threshold: 2500}, values(2, 2510, 2490), true, "über der Schwelle 2500 EUR"},
+		{"Bleibt über Schwelle", AlertRule{Type: AlertAbove, Threshold: 2500}, values(2, 2520, 2510), false, ""},
+		{"Schwelle unterschritten", AlertRule{Type: AlertBelow, Threshold: 2500}, values(2, 2490, 2510), true, "unter der Schwelle"},
+		{"Kein neuer Wert", AlertRule{Type: AlertNoData, Days: 5}, []ObservationValue{{ObservedAt: now.AddDate(0, 0, -7), CollectedAt: now.AddDate(0, 0, -6)}}, true, "seit 7 Tagen"},
+		{"Bereits gemeldet", AlertRule{Type: AlertNoData, Days: 5, LastTriggeredAt: &now}, []ObservationValue{{ObservedAt: now.AddDate(0, 0, -7), CollectedAt: now.AddDate(0, 0, -6)}}, false, ""},
+		{"Aktuell", AlertRule{Type: AlertNoData, Days: 5}, values(2, 1, 1), false, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event := evaluateRule(tt.rule, ind, tt.values, 2, now)
+			if (event != nil) != tt.want {
+				t.Fatalf("Ausgelöst = %v, erwartet %v", event != nil, tt.want)
+			}
+			if event != nil && !strings.Contains(event.Message, tt.wantMsg) {
+				t.Errorf("Nachricht %q enthält nicht %q", event.Message, tt.wantMsg)
+			}
+		})
+	}
+}
+
+// TestAlertDelivery prüft Anlegen per API, Zustellung über alle Kanäle und die Historie
+func TestAlertDelivery(t *testing.T) {
+	var webhookEvents []AlertEvent
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event AlertEvent
+		json.NewDecoder(r.Body).Decode(&event)
+		webhookEvents = append(webhookEvents, event)
+	}))
+	defer webhook.Close()
+
+	service, err := NewService(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer service.Close()
+
+	type chatMessage struct {
+		chatID int64
+		text   string
+	}
+	var chatMessages []chatMessage
+	var broadcasts []AlertEvent
+	service.OnAlertChat = func(chatID int64, text string) error {
+		chatMessages = append(chatMessages, chatMessage{chatID, text})
+		return nil
+	}
+	service.OnAlertBroadcast = func(event AlertEvent) {
+		broadcasts = append(broadcasts, event)
+	}
+
+	mux := http.NewServeMux()
+	NewHandlers(service).RegisterRoutes(mux)
+	do := func(method, path string, body interface{}) *httptest.ResponseRecorder {
+		data, _ := json.Marshal(body)
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(method, path, bytes.NewReader(data)))
+		return rec
+	}
+
+	rule := map[string]interface{}{
+		"indicatorCode": "ecb_main_rate",
+		"type":          "changed",
+		"channels":      []string{"chat", "mates", "webhook"},
+		"chatId":        42,
+		"webhookUrl":    webhook.URL,
+	}
+	rec := do(http.MethodPost, "/api/observer/alerts/rules", rule)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Regel anlegen: %d %s", rec.Code, rec.Body.String())
+	}
+	var created AlertRule
+	json.NewDecoder(rec.Body).Decode(&created)
+	if created.ID == 0 || created.IndicatorCode != "ECB_MAIN_RATE" || !created.Active {
+		t.Errorf("Regel: %+v", created)
+	}
+
+	for _, invalid := range []map[string]interface{}{
+		{"indicatorCode": "UNBEKANNT", "type": "changed", "channels": []string{"mates"}},
+		{"indicatorCode": "GOLD_EUR", "type": "change_pct", "channels": []string{"mates"}},
+		{"indicatorCode": "GOLD_EUR", "type": "changed", "channels": []string{"chat"}},
+		{"indicatorCode": "GOLD_EUR", "type": "changed", "channels": []string{"webhook"}, "webhookUrl": "file:///etc/passwd"},
+	} {
+		if rec := do(http.MethodPost, "/api/observer/alerts/rules", invalid); rec.Code != http.StatusBadRequest {
+			t.Errorf("Ungültige Regel %v: %d", invalid, rec.Code)
+		}
+	}
+
+	ind, _ := service.repo.GetIndicatorByCode("ECB_MAIN_RATE")
+	day := func(d int) time.Time { return time.Date(2024, 9, d, 0, 0, 0, 0, time.UTC) }
+	store := func(runID int64, d int, value float64) *ObservationRun {
+		service.repo.StoreValues([]ObservationValue{
+			{RunID: runID, IndicatorID: ind.ID, SourceID: ind.SourceID, ObservedAt: day(d), CollectedAt: time.Now(), Value: value},
+		})
+		return &ObservationRun{ID: runID}
+	}
+
+	store(1, 11, 4.5)
+	service.evaluateAlerts(t.Context(), store(2, 18, 4.25))
+	if len(chatMessages) != 1 || chatMessages[0].chatID != 42 || !strings.Contains(chatMessages[0].text, "4.5 % → 4.25 %") {
+		t.Errorf("Chat: %+v", chatMessages)
+	}
+	if len(broadcasts) != 1 || len(webhookEvents) != 1 || webhookEvents[0].IndicatorCode != "ECB_MAIN_RATE" {
+		t.Errorf("Mates: %+v, Webhook: %+v", broadcasts, webhookEvents)
+	}
+
+	// Folgelauf ohne neue Werte löst nicht erneut aus
+	service.evaluateAlerts(t.Context(), &ObservationRun{ID: 3})
+	if len(chatMessages) != 1 {
+		t.Errorf("Erneut ausgelöst: %+v", chatMessages)
+	}
+
+	rec = do(http.MethodGet, "/api/observer/alerts?indicator=ecb_main_rate", nil)
+	var history []AlertEvent
+	json.NewDecoder(rec.Body).Decode(&history)
+	if len(history) != 1 || history[0].RuleID != created.ID || len(history[0].Delivered) != 3 ||
+		history[0].PreviousValue == nil || *history[0].PreviousValue != 4.5 {
+		t.Fatalf("Historie: %+v", history)
+	}
+
+	rules, _ := service.GetAlertRules()
+	if len(rules) != 1 || rules[0].LastTriggeredAt == nil {
+		t.Errorf("Regeln: %+v", rules)
+	}
+
+	if rec := do(http.MethodDelete, "/api/observer/alerts/rules/"+strconv.FormatInt(created.ID, 10), nil); rec.Code != http.StatusNoContent {
+		t.Errorf("Regel löschen: %d", rec.Code)
+	}
+	if rec := do(http.MethodDelete, "/api/observer/alerts/rules/"+strconv.FormatInt(created.ID, 10), nil); rec.Code != http.StatusNotFound {
+		t.Errorf("Regel erneut löschen: %d", rec.Code)
+	}
+	if history, _ := service.GetAlertHistory("", 0); len(history) != 1 {
+		t.Errorf("Historie nach Löschen der Regel: %+v", history)
+	}
+}
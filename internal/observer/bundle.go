@@ -0,0 +1,836 @@
+package observer
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+// Datensatz-Bundle: ZIP mit einer Datei je Tabelle (CSV oder Parquet) und manifest.json.
+// Gedacht für die Auswertung in pandas/R und den Abgleich zwischen Navigator-Installationen;
+// anders als der SQL-Dump enthält es keine internen IDs, verknüpft wird über Codes.
+
+const (
+	BundleFormatName = "fleet-navigator-observer-bundle"
+	BundleVersion    = 1
+
+	BundleDataCSV     = "csv"
+	BundleDataParquet = "parquet"
+
+	bundleManifestFile = "manifest.json"
+	bundleTimeLayout   = "2006-01-02T15:04:05.000Z07:00" // Millisekunden wie in Parquet
+	maxBundleFileSize  = 512 << 20
+)
+
+// ErrInvalidBundle wird zurückgegeben wenn ein Bundle nicht dem erwarteten Schema entspricht
+var ErrInvalidBundle = errors.New("ungültiges Bundle")
+
+// BundleManifest beschreibt Inhalt und Herkunft eines Bundles
+type BundleManifest struct {
+	Format     string       `json:"format"`
+	Version    int          `json:"version"`
+	ExportedAt time.Time    `json:"exportedAt"`
+	DataFormat string       `json:"dataFormat"` // csv oder parquet
+	From       *time.Time   `json:"from,omitempty"`
+	To         *time.Time   `json:"to,omitempty"`
+	Indicators []string     `json:"indicators,omitempty"` // Filter beim Export (leer = alle)
+	Files      []BundleFile `json:"files"`
+}
+
+// BundleFile beschreibt eine Tabelle im Bundle
+type BundleFile struct {
+	Name    string   `json:"name"`
+	Table   string   `json:"table"`
+	Rows    int      `json:"rows"`
+	SHA256  string   `json:"sha256"`
+	Columns []string `json:"columns"`
+}
+
+// BundleOptions steuert den Export
+type BundleOptions struct {
+	DataFormat string     // csv (Default) oder parquet
+	Indicators []string   // Indikator-Codes (leer = alle)
+	From       *time.Time // Stichtage ab
+	To         *time.Time // Stichtage bis
+}
+
+// BundleImportResult fasst einen Import zusammen
+type BundleImportResult struct {
+	ExportedAt      time.Time `json:"exportedAt"`
+	SourcesAdded    int       `json:"sourcesAdded"`
+	IndicatorsAdded int       `json:"indicatorsAdded"`
+	RunsAdded       int       `json:"runsAdded"`
+	ValuesAdded     int       `json:"valuesAdded"`
+	ValuesSkipped   int       `json:"valuesSkipped"` // (Indikator, Stichtag) bereits mit gleichem Wert vorhanden
+	Conflicts       int       `json:"conflicts"`     // Bereits mit anderem Wert vorhanden, lokaler Wert bleibt
+	Warnings        []string  `json:"warnings,omitempty"`
+}
+
+// --- Zeilen der Tabellen (gemeinsam für CSV und Parquet) ---
+
+type bundleSourceRow struct {
+	Code        string `parquet:"code"`
+	Name        string `parquet:"name"`
+	Description string `parquet:"description"`
+	URL         string `parquet:"url"`
+	SourceClass string `parquet:"source_class"`
+	Active      bool   `parquet:"active"`
+	Definition  string `parquet:"definition"` // JSON der SourceDefinition ("" = eingebaut)
+}
+
+type bundleIndicatorRow struct {
+	Code         string `parquet:"code"`
+	SourceCode   string `parquet:"source_code"`
+	Name         string `parquet:"name"`
+	Description  string `parquet:"description"`
+	Category     string `parquet:"category"`
+	Unit         string `parquet:"unit"`
+	Frequency    string `parquet:"frequency"`
+	ExternalCode string `parquet:"external_code"`
+	Active       bool   `parquet:"active"`
+}
+
+type bundleRunRow struct {
+	RunID         int64  `parquet:"run_id"`
+	Strategy      string `parquet:"strategy"`
+	Status        string `parquet:"status"`
+	StartedAt     int64  `parquet:"started_at,timestamp(millisecond)"`
+	FinishedAt    int64  `parquet:"finished_at,optional,timestamp(millisecond)"`
+	TotalRecords  int64  `parquet:"total_records"`
+	ErrorCount    int64  `parquet:"error_count"`
+	ErrorMessages string `parquet:"error_messages"`
+	IsBackfill    bool   `parquet:"is_backfill"`
+	BackfillFrom  int64  `parquet:"backfill_from,optional,timestamp(millisecond)"`
+	BackfillTo    int64  `parquet:"backfill_to,optional,timestamp(millisecond)"`
+}
+
+type bundleValueRow struct {
+	IndicatorCode string  `parquet:"indicator_code,dict"`
+	SourceCode    string  `parquet:"source_code,dict"`
+	ObservedAt    int64   `parquet:"observed_at,timestamp(millisecond)"`
+	Value         float64 `parquet:"value"`
+	ValueString   string  `parquet:"value_string"`
+	Unit          string  `parquet:"unit,dict"`
+	PeriodStart   int64   `parquet:"period_start,optional,timestamp(millisecond)"`
+	PeriodEnd     int64   `parquet:"period_end,optional,timestamp(millisecond)"`
+	RunID         int64   `parquet:"run_id"` // Verweis auf runs (0 = Auto-Backfill ohne Lauf)
+	CollectedAt   int64   `parquet:"collected_at,timestamp(millisecond)"`
+}
+
+// bundleTable beschreibt eine Tabelle: Spalten und Umwandlung von/nach CSV
+type bundleTable[T any] struct {
+	name    string
+	columns []string
+	record  func(T) []string
+	parse   func(rec []string) (T, error)
+}
+
+var bundleSources = bundleTable[bundleSourceRow]{
+	name:    "sources",
+	columns: []string{"code", "name", "description", "url", "source_class", "active", "definition"},
+	record: func(r bundleSourceRow) []string {
+		return []string{r.Code, r.Name, r.Description, r.URL, r.SourceClass, strconv.FormatBool(r.Active), r.Definition}
+	},
+	parse: func(rec []string) (bundleSourceRow, error) {
+		active, err := strconv.ParseBool(rec[5])
+		if err != nil {
+			return bundleSourceRow{}, fmt.Errorf("active: %w", err)
+		}
+		return bundleSourceRow{Code: rec[0], Name: rec[1], Description: rec[2], URL: rec[3], SourceClass: rec[4], Active: active, Definition: rec[6]}, nil
+	},
+}
+
+var bundleIndicators = bundleTable[bundleIndicatorRow]{
+	name:    "indicators",
+	columns: []string{"code", "source_code", "name", "description", "category", "unit", "frequency", "external_code", "active"},
+	record: func(r bundleIndicatorRow) []string {
+		return []string{r.Code, r.SourceCode, r.Name, r.Description, r.Category, r.Unit, r.Frequency, r.ExternalCode, strconv.FormatBool(r.Active)}
+	},
+	parse: func(rec []string) (bundleIndicatorRow, error) {
+		active, err := strconv.ParseBool(rec[8])
+		if err != nil {
+			return bundleIndicatorRow{}, fmt.Errorf("active: %w", err)
+		}
+		return bundleIndicatorRow{Code: rec[0], SourceCode: rec[1], Name: rec[2], Description: rec[3], Category: rec[4],
+			Unit: rec[5], Frequency: rec[6], ExternalCode: rec[7], Active: active}, nil
+	},
+}
+
+var bundleRuns = bundleTable[bundleRunRow]{
+	name: "runs",
+	columns: []string{"run_id", "strategy", "status", "started_at", "finished_at", "total_records", "error_count",
+		"error_messages", "is_backfill", "backfill_from", "backfill_to"},
+	record: func(r bundleRunRow) []string {
+		return []string{strconv.FormatInt(r.RunID, 10), r.Strategy, r.Status, formatBundleTime(r.StartedAt), formatBundleOptTime(r.FinishedAt),
+			strconv.FormatInt(r.TotalRecords, 10), strconv.FormatInt(r.ErrorCount, 10), r.ErrorMessages,
+			strconv.FormatBool(r.IsBackfill), formatBundleOptTime(r.BackfillFrom), formatBundleOptTime(r.BackfillTo)}
+	},
+	parse: func(rec []string) (row bundleRunRow, err error) {
+		p := bundleFieldParser{rec: rec}
+		row = bundleRunRow{
+			RunID:         p.int(0, "run_id"),
+			Strategy:      rec[1],
+			Status:        rec[2],
+			StartedAt:     p.time(3, "started_at"),
+			FinishedAt:    p.optTime(4, "finished_at"),
+			TotalRecords:  p.int(5, "total_records"),
+			ErrorCount:    p.int(6, "error_count"),
+			ErrorMessages: rec[7],
+			IsBackfill:    p.bool(8, "is_backfill"),
+			BackfillFrom:  p.optTime(9, "backfill_from"),
+			BackfillTo:    p.optTime(10, "backfill_to"),
+		}
+		return row, p.err
+	},
+}
+
+var bundleValues = bundleTable[bundleValueRow]{
+	name: "values",
+	columns: []string{"indicator_code", "source_code", "observed_at", "value", "value_string", "unit",
+		"period_start", "period_end", "run_id", "collected_at"},
+	record: func(r bundleValueRow) []string {
+		return []string{r.IndicatorCode, r.SourceCode, formatBundleTime(r.ObservedAt), strconv.FormatFloat(r.Value, 'f', -1, 64),
+			r.ValueString, r.Unit, formatBundleOptTime(r.PeriodStart), formatBundleOptTime(r.PeriodEnd),
+			strconv.FormatInt(r.RunID, 10), formatBundleTime(r.CollectedAt)}
+	},
+	parse: func(rec []string) (row bundleValueRow, err error) {
+		p := bundleFieldParser{rec: rec}
+		row = bundleValueRow{
+			IndicatorCode: rec[0],
+			SourceCode:    rec[1],
+			ObservedAt:    p.time(2, "observed_at"),
+			Value:         p.float(3, "value"),
+			ValueString:   rec[4],
+			Unit:          rec[5],
+			PeriodStart:   p.optTime(6, "period_start"),
+			PeriodEnd:     p.optTime(7, "period_end"),
+			RunID:         p.int(8, "run_id"),
+			CollectedAt:   p.time(9, "collected_at"),
+		}
+		return row, p.err
+	},
+}
+
+// bundleFieldParser parst CSV-Felder und merkt sich den ersten Fehler
+type bundleFieldParser struct {
+	rec []string
+	err error
+}
+
+func (p *bundleFieldParser) fail(column string, err error) {
+	if p.err == nil {
+		p.err = fmt.Errorf("%s: %w", column, err)
+	}
+}
+
+func (p *bundleFieldParser) int(i int, column string) int64 {
+	n, err := strconv.ParseInt(p.rec[i], 10, 64)
+	if err != nil {
+		p.fail(column, err)
+	}
+	return n
+}
+
+func (p *bundleFieldParser) float(i int, column string) float64 {
+	f, err := strconv.ParseFloat(p.rec[i], 64)
+	if err != nil {
+		p.fail(column, err)
+	}
+	return f
+}
+
+func (p *bundleFieldParser) bool(i int, column string) bool {
+	b, err := strconv.ParseBool(p.rec[i])
+	if err != nil {
+		p.fail(column, err)
+	}
+	return b
+}
+
+func (p *bundleFieldParser) time(i int, column string) int64 {
+	t, err := time.Parse(time.RFC3339, p.rec[i])
+	if err != nil {
+		p.fail(column, err)
+	}
+	return t.UnixMilli()
+}
+
+func (p *bundleFieldParser) optTime(i int, column string) int64 {
+	if p.rec[i] == "" {
+		return 0
+	}
+	return p.time(i, column)
+}
+
+func formatBundleTime(ms int64) string {
+	return time.UnixMilli(ms).UTC().Format(bundleTimeLayout)
+}
+
+// Optionale Zeitpunkte: 0 steht für "nicht gesetzt" (leeres CSV-Feld bzw. NULL in Parquet)
+func formatBundleOptTime(ms int64) string {
+	if ms == 0 {
+		return ""
+	}
+	return formatBundleTime(ms)
+}
+
+func bundleMillis(t *time.Time) int64 {
+	if t == nil || t.IsZero() {
+		return 0
+	}
+	return t.UnixMilli()
+}
+
+func bundleTime(ms int64) time.Time {
+	if ms == 0 {
+		return time.Time{}
+	}
+	return time.UnixMilli(ms).UTC()
+}
+
+// writeBundleTable schreibt eine Tabelle ins ZIP und gibt ihren Manifest-Eintrag zurück
+func writeBundleTable[T any](zw *zip.Writer, table bundleTable[T], rows []T, dataFormat string) (BundleFile, error) {
+	file := BundleFile{Name: table.name + "." + dataFormat, Table: table.name, Rows: len(rows), Columns: table.columns}
+
+	entry, err := zw.Create(file.Name)
+	if err != nil {
+		return file, err
+	}
+	hash := sha256.New()
+	out := io.MultiWriter(entry, hash)
+
+	switch dataFormat {
+	case BundleDataParquet:
+		if err := parquet.Write(out, rows, parquet.Compression(&parquet.Snappy)); err != nil {
+			return file, fmt.Errorf("%s schreiben fehlgeschlagen: %w", file.Name, err)
+		}
+	default:
+		cw := csv.NewWriter(out)
+		cw.Write(table.columns)
+		for _, row := range rows {
+			cw.Write(table.record(row))
+		}
+		cw.Flush()
+		if err := cw.Error(); err != nil {
+			return file, fmt.Errorf("%s schreiben fehlgeschlagen: %w", file.Name, err)
+		}
+	}
+
+	file.SHA256 = hex.EncodeToString(hash.Sum(nil))
+	return file, nil
+}
+
+// readBundleTable liest und prüft eine Tabelle (Prüfsumme, Spalten, Zeilenzahl)
+func readBundleTable[T any](files map[string]*zip.File, manifest *BundleManifest, table bundleTable[T]) ([]T, error) {
+	var entry *BundleFile
+	for i := range manifest.Files {
+		if manifest.Files[i].Table == table.name {
+			entry = &manifest.Files[i]
+		}
+	}
+	if entry == nil {
+		return nil, fmt.Errorf("%w: Tabelle %s fehlt im Manifest", ErrInvalidBundle, table.name)
+	}
+	if !slices.Equal(entry.Columns, table.columns) {
+		return nil, fmt.Errorf("%w: Spalten von %s passen nicht (erwartet %s)", ErrInvalidBundle, entry.Name, strings.Join(table.columns, ", "))
+	}
+
+	zf := files[entry.Name]
+	if zf == nil {
+		return nil, fmt.Errorf("%w: Datei %s fehlt", ErrInvalidBundle, entry.Name)
+	}
+	if zf.UncompressedSize64 > maxBundleFileSize {
+		return nil, fmt.Errorf("%w: %s ist zu groß", ErrInvalidBundle, entry.Name)
+	}
+	rc, err := zf.Open()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrInvalidBundle, entry.Name, err)
+	}
+	data, err := io.ReadAll(io.LimitReader(rc, maxBundleFileSize+1))
+	rc.Close()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrInvalidBundle, entry.Name, err)
+	}
+	if sum := sha256.Sum256(data); hex.EncodeToString(sum[:]) != entry.SHA256 {
+		return nil, fmt.Errorf("%w: Prüfsumme von %s stimmt nicht", ErrInvalidBundle, entry.Name)
+	}
+
+	var rows []T
+	switch manifest.DataFormat {
+	case BundleDataParquet:
+		pf, err := parquet.OpenFile(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %v", ErrInvalidBundle, entry.Name, err)
+		}
+		var columns []string
+		for _, field := range pf.Schema().Fields() {
+			columns = append(columns, field.Name())
+		}
+		if !slices.Equal(columns, table.columns) {
+			return nil, fmt.Errorf("%w: Schema von %s passt nicht (Spalten %s)", ErrInvalidBundle, entry.Name, strings.Join(columns, ", "))
+		}
+		if rows, err = parquet.Read[T](bytes.NewReader(data), int64(len(data))); err != nil {
+			return nil, fmt.Errorf("%w: %s: %v", ErrInvalidBundle, entry.Name, err)
+		}
+
+	default:
+		cr := csv.NewReader(bytes.NewReader(data))
+		cr.FieldsPerRecord = len(table.columns)
+		header, err := cr.Read()
+		if err != nil || !slices.Equal(header, table.columns) {
+			return nil, fmt.Errorf("%w: Kopfzeile von %s passt nicht (erwartet %s)", ErrInvalidBundle, entry.Name, strings.Join(table.columns, ","))
+		}
+		for line := 2; ; line++ {
+			rec, err := cr.Read()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, fmt.Errorf("%w: %s: %v", ErrInvalidBundle, entry.Name, err)
+			}
+			row, err := table.parse(rec)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %s Zeile %d: %v", ErrInvalidBundle, entry.Name, line, err)
+			}
+			rows = append(rows, row)
+		}
+	}
+
+	if len(rows) != entry.Rows {
+		return nil, fmt.Errorf("%w: %s enthält %d statt %d Zeilen", ErrInvalidBundle, entry.Name, len(rows), entry.Rows)
+	}
+	return rows, nil
+}
+
+// ExportBundle schreibt Indikatoren, Werte und Sammelläufe als Bundle (ZIP) nach w
+func (s *Service) ExportBundle(w io.Writer, opts BundleOptions) (*BundleManifest, error) {
+	if opts.DataFormat == "" {
+		opts.DataFormat = BundleDataCSV
+	}
+	if opts.DataFormat != BundleDataCSV && opts.DataFormat != BundleDataParquet {
+		return nil, fmt.Errorf("Unbekanntes Datenformat %q (csv, parquet)", opts.DataFormat)
+	}
+
+	indicators, err := s.repo.GetAllIndicators(false)
+	if err != nil {
+		return nil, fmt.Errorf("Indikatoren laden fehlgeschlagen: %w", err)
+	}
+	if len(opts.Indicators) > 0 {
+		wanted := make(map[string]bool, len(opts.Indicators))
+		for _, code := range opts.Indicators {
+			wanted[strings.ToUpper(strings.TrimSpace(code))] = true
+		}
+		filtered := indicators[:0]
+		for _, ind := range indicators {
+			if wanted[ind.Code] {
+				filtered = append(filtered, ind)
+				delete(wanted, ind.Code)
+			}
+		}
+		for code := range wanted {
+			return nil, fmt.Errorf("Indikator %s: %w", code, ErrNotFound)
+		}
+		indicators = filtered
+	}
+	sort.Slice(indicators, func(i, j int) bool { return indicators[i].Code < indicators[j].Code })
+
+	sources, err := s.repo.GetAllSources(false)
+	if err != nil {
+		return nil, fmt.Errorf("Quellen laden fehlgeschlagen: %w", err)
+	}
+	sourceByID := make(map[int64]DataSource, len(sources))
+	for _, src := range sources {
+		sourceByID[src.ID] = src
+	}
+
+	from, to := time.Time{}, time.Now().AddDate(100, 0, 0)
+	if opts.From != nil {
+		from = *opts.From
+	}
+	if opts.To != nil {
+		to = *opts.To
+	}
+
+	// Werte je Indikator, dabei referenzierte Quellen und Läufe merken
+	usedSources := make(map[int64]bool)
+	usedRuns := make(map[int64]bool)
+	indicatorRows := make([]bundleIndicatorRow, 0, len(indicators))
+	valueRows := make([]bundleValueRow, 0)
+	for _, ind := range indicators {
+		usedSources[ind.SourceID] = true
+		indicatorRows = append(indicatorRows, bundleIndicatorRow{
+			Code: ind.Code, SourceCode: sourceByID[ind.SourceID].Code, Name: ind.Name, Description: ind.Description,
+			Category: string(ind.Category), Unit: ind.Unit, Frequency: ind.Frequency, ExternalCode: ind.ExternalCode, Active: ind.Active,
+		})
+
+		values, err := s.repo.GetValuesByIndicatorDateRange(ind.ID, from, to)
+		if err != nil {
+			return nil, fmt.Errorf("Werte für %s laden fehlgeschlagen: %w", ind.Code, err)
+		}
+		for _, v := range values {
+			usedSources[v.SourceID] = true
+			if v.RunID != 0 {
+				usedRuns[v.RunID] = true
+			}
+			valueRows = append(valueRows, bundleValueRow{
+				IndicatorCode: ind.Code, SourceCode: sourceByID[v.SourceID].Code, ObservedAt: v.ObservedAt.UnixMilli(),
+				Value: v.Value, ValueString: v.ValueString, Unit: v.Unit,
+				PeriodStart: bundleMillis(&v.PeriodStart), PeriodEnd: bundleMillis(&v.PeriodEnd),
+				RunID: v.RunID, CollectedAt: v.CollectedAt.UnixMilli(),
+			})
+		}
+	}
+
+	sourceRows := make([]bundleSourceRow, 0, len(usedSources))
+	for _, src := range sources {
+		if !usedSources[src.ID] {
+			continue
+		}
+		definition, _ := encodeDefinition(src.Definition)
+		sourceRows = append(sourceRows, bundleSourceRow{
+			Code: src.Code, Name: src.Name, Description: src.Description, URL: src.URL,
+			SourceClass: string(src.SourceClass), Active: src.Active, Definition: definition,
+		})
+	}
+	sort.Slice(sourceRows, func(i, j int) bool { return sourceRows[i].Code < sourceRows[j].Code })
+
+	runs, err := s.repo.GetRunsByDateRange(time.Time{}, time.Now().AddDate(100, 0, 0))
+	if err != nil {
+		return nil, fmt.Errorf("Sammelläufe laden fehlgeschlagen: %w", err)
+	}
+	runRows := make([]bundleRunRow, 0, len(usedRuns))
+	for _, run := range runs {
+		if !usedRuns[run.ID] {
+			continue
+		}
+		runRows = append(runRows, bundleRunRow{
+			RunID: run.ID, Strategy: string(run.Strategy), Status: string(run.Status), StartedAt: run.StartedAt.UnixMilli(),
+			FinishedAt: bundleMillis(run.FinishedAt), TotalRecords: int64(run.TotalRecords), ErrorCount: int64(run.ErrorCount),
+			ErrorMessages: run.ErrorMessages, IsBackfill: run.IsBackfill,
+			BackfillFrom: bundleMillis(run.BackfillFrom), BackfillTo: bundleMillis(run.BackfillTo),
+		})
+	}
+	sort.Slice(runRows, func(i, j int) bool { return runRows[i].RunID < runRows[j].RunID })
+
+	manifest := &BundleManifest{
+		Format:     BundleFormatName,
+		Version:    BundleVersion,
+		ExportedAt: time.Now().UTC(),
+		DataFormat: opts.DataFormat,
+		From:       opts.From,
+		To:         opts.To,
+		Indicators: opts.Indicators,
+	}
+
+	zw := zip.NewWriter(w)
+	writers := []func() (BundleFile, error){
+		func() (BundleFile, error) { return writeBundleTable(zw, bundleSources, sourceRows, opts.DataFormat) },
+		func() (BundleFile, error) {
+			return writeBundleTable(zw, bundleIndicators, indicatorRows, opts.DataFormat)
+		},
+		func() (BundleFile, error) { return writeBundleTable(zw, bundleRuns, runRows, opts.DataFormat) },
+		func() (BundleFile, error) { return writeBundleTable(zw, bundleValues, valueRows, opts.DataFormat) },
+	}
+	for _, write := range writers {
+		file, err := write()
+		if err != nil {
+			return nil, err
+		}
+		manifest.Files = append(manifest.Files, file)
+	}
+
+	entry, err := zw.Create(bundleManifestFile)
+	if err != nil {
+		return nil, err
+	}
+	enc := json.NewEncoder(entry)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(manifest); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("Bundle schreiben fehlgeschlagen: %w", err)
+	}
+
+	log.Printf("Observer: Bundle exportiert (%s) - %d Indikatoren, %d Werte, %d Läufe",
+		opts.DataFormat, len(indicatorRows), len(valueRows), len(runRows))
+	return manifest, nil
+}
+
+// ImportBundle prüft ein Bundle vollständig und führt es dann mit den vorhandenen Daten zusammen.
+// Bereits vorhandene (Indikator, Stichtag)-Paare werden nicht dupliziert.
+func (s *Service) ImportBundle(r io.ReaderAt, size int64) (*BundleImportResult, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("%w: kein ZIP-Archiv: %v", ErrInvalidBundle, err)
+	}
+	files := make(map[string]*zip.File, len(zr.File))
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+
+	// Manifest
+	mf := files[bundleManifestFile]
+	if mf == nil {
+		return nil, fmt.Errorf("%w: %s fehlt", ErrInvalidBundle, bundleManifestFile)
+	}
+	rc, err := mf.Open()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidBundle, err)
+	}
+	var manifest BundleManifest
+	err = json.NewDecoder(io.LimitReader(rc, 1<<20)).Decode(&manifest)
+	rc.Close()
+	if err != nil {
+		return nil, fmt.Errorf("%w: Manifest: %v", ErrInvalidBundle, err)
+	}
+	if manifest.Format != BundleFormatName {
+		return nil, fmt.Errorf("%w: unbekanntes Format %q", ErrInvalidBundle, manifest.Format)
+	}
+	if manifest.Version < 1 || manifest.Version > BundleVersion {
+		return nil, fmt.Errorf("%w: Version %d wird nicht unterstützt (bis %d)", ErrInvalidBundle, manifest.Version, BundleVersion)
+	}
+	if manifest.DataFormat != BundleDataCSV && manifest.DataFormat != BundleDataParquet {
+		return nil, fmt.Errorf("%w: unbekanntes Datenformat %q", ErrInvalidBundle, manifest.DataFormat)
+	}
+
+	// Tabellen lesen und prüfen, bevor etwas geschrieben wird
+	sourceRows, err := readBundleTable(files, &manifest, bundleSources)
+	if err != nil {
+		return nil, err
+	}
+	indicatorRows, err := readBundleTable(files, &manifest, bundleIndicators)
+	if err != nil {
+		return nil, err
+	}
+	runRows, err := readBundleTable(files, &manifest, bundleRuns)
+	if err != nil {
+		return nil, err
+	}
+	valueRows, err := readBundleTable(files, &manifest, bundleValues)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.validateBundle(sourceRows, indicatorRows, runRows, valueRows); err != nil {
+		return nil, err
+	}
+
+	result := &BundleImportResult{ExportedAt: manifest.ExportedAt}
+
+	// Alles in einer Transaktion: ein Fehler lässt keinen halben Import zurück
+	var configured []DataSource
+	err = s.repo.inTx(func(tx *repoTx) error {
+		var err error
+		configured, err = importBundleRows(tx, result, sourceRows, indicatorRows, runRows, valueRows)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, source := range configured {
+		s.registerConfiguredSource(source)
+	}
+	if result.Conflicts > 0 {
+		result.Warnings = append(result.Warnings, fmt.Sprintf("%d Werte weichen von vorhandenen ab - lokale Werte beibehalten", result.Conflicts))
+	}
+
+	log.Printf("Observer: Bundle importiert - %d Werte neu, %d vorhanden, %d Konflikte, %d Indikatoren und %d Läufe neu",
+		result.ValuesAdded, result.ValuesSkipped, result.Conflicts, result.IndicatorsAdded, result.RunsAdded)
+	return result, nil
+}
+
+// importBundleRows schreibt die geprüften Bundle-Tabellen in die Transaktion und gibt die
+// neu angelegten konfigurierten Quellen zurück (werden erst nach dem Commit registriert)
+func importBundleRows(tx *repoTx, result *BundleImportResult, sourceRows []bundleSourceRow, indicatorRows []bundleIndicatorRow,
+	runRows []bundleRunRow, valueRows []bundleValueRow) ([]DataSource, error) {
+	// Quellen (neu angelegte bleiben inaktiv, damit der Import keine Sammlung startet)
+	sourceIDs := make(map[string]int64)
+	var configured []DataSource
+	for _, row := range sourceRows {
+		existing, err := tx.GetSourceByCode(row.Code)
+		if err != nil {
+			return nil, err
+		}
+		if existing != nil {
+			sourceIDs[row.Code] = existing.ID
+			continue
+		}
+		source := DataSource{Code: row.Code, Name: row.Name, Description: row.Description, URL: row.URL,
+			SourceClass: SourceClass(row.SourceClass), Definition: decodeDefinition(row.Definition)}
+		if err := tx.CreateSource(&source); err != nil {
+			return nil, err
+		}
+		sourceIDs[row.Code] = source.ID
+		result.SourcesAdded++
+		if row.Active {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("Quelle %s inaktiv übernommen", row.Code))
+		}
+		if source.Definition != nil {
+			configured = append(configured, source)
+		}
+	}
+
+	// Indikatoren
+	type localIndicator struct{ id, sourceID int64 }
+	indicatorIDs := make(map[string]localIndicator)
+	for _, row := range indicatorRows {
+		existing, err := tx.GetIndicatorByCode(row.Code)
+		if err != nil {
+			return nil, err
+		}
+		if existing != nil {
+			indicatorIDs[row.Code] = localIndicator{existing.ID, existing.SourceID}
+			continue
+		}
+		sourceID, ok := sourceIDs[row.SourceCode]
+		if !ok {
+			// validateBundle lief außerhalb der Transaktion - die Quelle kann inzwischen fehlen
+			src, err := tx.GetSourceByCode(row.SourceCode)
+			if err != nil {
+				return nil, err
+			}
+			if src == nil {
+				return nil, fmt.Errorf("%w: Indikator %s: Quelle %s unbekannt", ErrInvalidBundle, row.Code, row.SourceCode)
+			}
+			sourceID = src.ID
+		}
+		ind := Indicator{Code: row.Code, Name: row.Name, Description: row.Description, Category: IndicatorCategory(row.Category),
+			Unit: row.Unit, Frequency: row.Frequency, SourceID: sourceID, ExternalCode: row.ExternalCode, Active: row.Active}
+		if err := tx.CreateIndicator(&ind); err != nil {
+			return nil, err
+		}
+		indicatorIDs[row.Code] = localIndicator{ind.ID, sourceID}
+		result.IndicatorsAdded++
+	}
+
+	// Sammelläufe als Herkunftsnachweis (gleicher Startzeitpunkt = derselbe Lauf)
+	runIDs := make(map[int64]int64)
+	for _, row := range runRows {
+		startedAt := bundleTime(row.StartedAt).In(time.Local)
+		id, err := tx.FindRunByStartedAt(startedAt, time.Millisecond)
+		if err != nil {
+			return nil, err
+		}
+		if id == 0 {
+			run := &ObservationRun{
+				Strategy: Strategy(row.Strategy), StartedAt: startedAt, Status: RunStatus(row.Status),
+				TotalRecords: int(row.TotalRecords), ErrorCount: int(row.ErrorCount), ErrorMessages: row.ErrorMessages,
+				IsBackfill: row.IsBackfill, FinishedAt: optionalBundleTime(row.FinishedAt),
+				BackfillFrom: optionalBundleTime(row.BackfillFrom), BackfillTo: optionalBundleTime(row.BackfillTo),
+			}
+			if err := tx.CreateRun(run); err != nil {
+				return nil, err
+			}
+			if err := tx.UpdateRun(run); err != nil {
+				return nil, err
+			}
+			id = run.ID
+			result.RunsAdded++
+		}
+		runIDs[row.RunID] = id
+	}
+
+	// Werte
+	values := make([]ObservationValue, 0, len(valueRows))
+	for _, row := range valueRows {
+		ind := indicatorIDs[row.IndicatorCode]
+		sourceID, ok := sourceIDs[row.SourceCode]
+		if !ok {
+			sourceID = ind.sourceID
+		}
+		values = append(values, ObservationValue{
+			RunID: runIDs[row.RunID], IndicatorID: ind.id, SourceID: sourceID,
+			ObservedAt: bundleTime(row.ObservedAt), CollectedAt: bundleTime(row.CollectedAt),
+			Value: row.Value, ValueString: row.ValueString, Unit: row.Unit,
+			PeriodStart: bundleTime(row.PeriodStart), PeriodEnd: bundleTime(row.PeriodEnd),
+		})
+	}
+	var err error
+	result.ValuesAdded, result.ValuesSkipped, result.Conflicts, err = tx.MergeValues(values)
+	return configured, err
+}
+
+// validateBundle prüft Codes und Verweise zwischen den Tabellen
+func (s *Service) validateBundle(sources []bundleSourceRow, indicators []bundleIndicatorRow, runs []bundleRunRow, values []bundleValueRow) error {
+	invalid := func(format string, args ...interface{}) error {
+		return fmt.Errorf("%w: %s", ErrInvalidBundle, fmt.Sprintf(format, args...))
+	}
+	known := func(code string, inBundle map[string]bool, lookup func(string) bool) bool {
+		return inBundle[code] || lookup(code)
+	}
+	localSource := func(code string) bool {
+		src, err := s.repo.GetSourceByCode(code)
+		return err == nil && src != nil
+	}
+	localIndicator := func(code string) bool {
+		ind, err := s.repo.GetIndicatorByCode(code)
+		return err == nil && ind != nil
+	}
+
+	sourceCodes := make(map[string]bool, len(sources))
+	for _, row := range sources {
+		if _, err := normalizeCode(row.Code); err != nil || row.Code != strings.ToUpper(row.Code) {
+			return invalid("Quelle %q: ungültiger Code", row.Code)
+		}
+		if row.Definition != "" && !json.Valid([]byte(row.Definition)) {
+			return invalid("Quelle %s: Definition ist kein JSON", row.Code)
+		}
+		sourceCodes[row.Code] = true
+	}
+
+	indicatorCodes := make(map[string]bool, len(indicators))
+	for _, row := range indicators {
+		if _, err := normalizeCode(row.Code); err != nil || row.Code != strings.ToUpper(row.Code) {
+			return invalid("Indikator %q: ungültiger Code", row.Code)
+		}
+		if !known(row.SourceCode, sourceCodes, localSource) {
+			return invalid("Indikator %s: Quelle %s unbekannt", row.Code, row.SourceCode)
+		}
+		indicatorCodes[row.Code] = true
+	}
+
+	runIDs := make(map[int64]bool, len(runs))
+	for _, row := range runs {
+		runIDs[row.RunID] = true
+	}
+
+	checkedIndicators := make(map[string]bool)
+	for i, row := range values {
+		if !checkedIndicators[row.IndicatorCode] {
+			if !known(row.IndicatorCode, indicatorCodes, localIndicator) {
+				return invalid("Wert %d: Indikator %s unbekannt", i+1, row.IndicatorCode)
+			}
+			checkedIndicators[row.IndicatorCode] = true
+		}
+		if row.RunID != 0 && !runIDs[row.RunID] {
+			return invalid("Wert %d: Sammellauf %d fehlt in runs", i+1, row.RunID)
+		}
+		if math.IsNaN(row.Value) || math.IsInf(row.Value, 0) {
+			return invalid("Wert %d: %s ist keine Zahl", i+1, row.IndicatorCode)
+		}
+	}
+	return nil
+}
+
+func optionalBundleTime(ms int64) *time.Time {
+	if ms == 0 {
+		return nil
+	}
+	t := bundleTime(ms)
+	return &t
+}
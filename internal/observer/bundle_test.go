@@ -0,0 +1,253 @@
+package observer
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newBundleService legt einen Service mit Gold-Werten aus einem Sammellauf an
+func newBundleService(t *testing.T) *Service {
+	t.Helper()
+	service, err := NewService(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { service.Close() })
+
+	started := time.Date(2024, 7, 1, 6, 0, 0, 123e6, time.Local)
+	finished := started.Add(2 * time.Minute)
+	run := &ObservationRun{Strategy: StrategyModerate, StartedAt: started, Status: RunStatusRunning}
+	if err := service.repo.CreateRun(run); err != nil {
+		t.Fatal(err)
+	}
+	run.Status, run.FinishedAt, run.TotalRecords = RunStatusCompleted, &finished, 10
+	if err := service.repo.UpdateRun(run); err != nil {
+		t.Fatal(err)
+	}
+
+	ind, _ := service.repo.GetIndicatorByCode("GOLD_EUR")
+	points := weekdaySeries(time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC), 10, func(i int) float64 { return 2000.5 + float64(i) })
+	values := make([]ObservationValue, len(points))
+	for i, p := range points {
+		values[i] = ObservationValue{RunID: run.ID, IndicatorID: ind.ID, SourceID: ind.SourceID, ObservedAt: p.Date, CollectedAt: finished, Value: p.Value, Unit: "EUR"}
+	}
+	if _, _, err := service.repo.StoreValues(values); err != nil {
+		t.Fatal(err)
+	}
+	return service
+}
+
+// TestBundleRoundTrip exportiert als CSV und Parquet und importiert in eine leere Installation
+func TestBundleRoundTrip(t *testing.T) {
+	source := newBundleService(t)
+
+	for _, format := range []string{BundleDataCSV, BundleDataParquet} {
+		t.Run(format, func(t *testing.T) {
+			var buf bytes.Buffer
+			manifest, err := source.ExportBundle(&buf, BundleOptions{DataFormat: format, Indicators: []string{"gold_eur"}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(manifest.Files) != 4 || manifest.Files[3].Table != "values" || manifest.Files[3].Rows != 10 || manifest.Files[2].Rows != 1 {
+				t.Fatalf("Manifest: %+v", manifest)
+			}
+
+			target, err := NewService(t.TempDir())
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer target.Close()
+
+			result, err := target.ImportBundle(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.ValuesAdded != 10 || result.RunsAdded != 1 || result.Conflicts != 0 {
+				t.Errorf("Import: %+v", result)
+			}
+
+			ind, _ := target.repo.GetIndicatorByCode("GOLD_EUR")
+			values, _ := target.repo.GetValuesByIndicatorDateRange(ind.ID, time.Time{}, time.Now())
+			if len(values) != 10 || values[0].RunID == 0 {
+				t.Fatalf("Werte nach Import: %+v", values)
+			}
+			runs, _ := target.repo.GetRunsByDateRange(time.Time{}, time.Now())
+			if len(runs) != 1 || runs[0].TotalRecords != 10 || runs[0].FinishedAt == nil {
+				t.Errorf("Sammellauf: %+v", runs)
+			}
+
+			// Erneuter Import dupliziert weder Werte noch Läufe
+			again, err := target.ImportBundle(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if again.ValuesAdded != 0 || again.ValuesSkipped != 10 || again.RunsAdded != 0 {
+				t.Errorf("Zweiter Import: %+v", again)
+			}
+			if values, _ := target.repo.GetValuesByIndicatorDateRange(ind.ID, time.Time{}, time.Now()); len(values) != 10 {
+				t.Errorf("%d Werte nach zweitem Import", len(values))
+			}
+		})
+	}
+
+	if _, err := source.ExportBundle(io.Discard, BundleOptions{Indicators: []string{"UNBEKANNT"}}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Unbekannter Indikator: %v", err)
+	}
+}
+
+// rewriteBundle kopiert ein Bundle und lässt change einzelne Dateien verändern
+func rewriteBundle(t *testing.T, data []byte, change func(name string, content []byte) []byte) []byte {
+	t.Helper()
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	zw := zip.NewWriter(&out)
+	for _, f := range zr.File {
+		rc, _ := f.Open()
+		content, _ := io.ReadAll(rc)
+		rc.Close()
+		w, _ := zw.Create(f.Name)
+		w.Write(change(f.Name, content))
+	}
+	zw.Close()
+	return out.Bytes()
+}
+
+// TestBundleValidation prüft dass beschädigte oder fremde Bundles nichts importieren
+func TestBundleValidation(t *testing.T) {
+	source := newBundleService(t)
+	var buf bytes.Buffer
+	if _, err := source.ExportBundle(&buf, BundleOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		change func(name string, content []byte) []byte
+	}{
+		{"Prüfsumme", func(name string, content []byte) []byte {
+			if name == "values.csv" {
+				return bytes.Replace(content, []byte("2000.5"), []byte("2100.5"), 1)
+			}
+			return content
+		}},
+		{"Spalten", func(name string, content []byte) []byte {
+			if name == bundleManifestFile {
+				return bytes.Replace(content, []byte(`"value_string"`), []byte(`"text"`), 1)
+			}
+			return content
+		}},
+		{"Version", func(name string, content []byte) []byte {
+			if name == bundleManifestFile {
+				return bytes.Replace(content, []byte(`"version": 1`), []byte(`"version": 2`), 1)
+			}
+			return content
+		}},
+	}
+	for _, tt := range tests {
+		data := rewriteBundle(t, buf.Bytes(), tt.change)
+		target, err := NewService(t.TempDir())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := target.ImportBundle(bytes.NewReader(data), int64(len(data))); !errors.Is(err, ErrInvalidBundle) {
+			t.Errorf("%s: %v", tt.name, err)
+		}
+		if runs, _ := target.repo.GetRunsByDateRange(time.Time{}, time.Now()); len(runs) != 0 {
+			t.Errorf("%s: trotz Fehler importiert", tt.name)
+		}
+		target.Close()
+	}
+
+	// Fehler beim Schreiben der Werte rollt auch Läufe und Indikatoren zurück
+	target, err := NewService(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer target.Close()
+	if _, err := target.repo.db.Exec(`CREATE TRIGGER fail_values BEFORE INSERT ON observation_value
+		BEGIN SELECT RAISE(ABORT, 'Festplatte voll'); END`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := target.ImportBundle(bytes.NewReader(buf.Bytes()), int64(buf.Len())); err == nil || !strings.Contains(err.Error(), "Festplatte voll") {
+		t.Fatalf("Import trotz Schreibfehler: %v", err)
+	}
+	if runs, _ := target.repo.GetRunsByDateRange(time.Time{}, time.Now()); len(runs) != 0 {
+		t.Errorf("Halber Import: %d Läufe", len(runs))
+	}
+	// Quelle nach der Prüfung gelöscht: Fehler statt Absturz, nichts angelegt
+	indicators := []bundleIndicatorRow{{Code: "NEU_IND", SourceCode: "GELOESCHT", Name: "Neu"}}
+	err = target.repo.inTx(func(tx *repoTx) error {
+		_, err := importBundleRows(tx, &BundleImportResult{}, nil, indicators, nil, nil)
+		return err
+	})
+	if !errors.Is(err, ErrInvalidBundle) {
+		t.Errorf("Fehlende Quelle: %v", err)
+	}
+	if ind, _ := target.repo.GetIndicatorByCode("NEU_IND"); ind != nil {
+		t.Error("Indikator trotz Fehler angelegt")
+	}
+}
+
+// TestBundleHandlers prüft Export und Import über die HTTP-API
+func TestBundleHandlers(t *testing.T) {
+	source := newBundleService(t)
+	mux := http.NewServeMux()
+	NewHandlers(source).RegisterRoutes(mux)
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/observer/export?format=parquet&indicators=GOLD_EUR&from=2024-07-08", nil))
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/zip" {
+		t.Fatalf("Export: %d %s", rec.Code, rec.Body.String())
+	}
+	bundle := rec.Body.Bytes()
+
+	target, err := NewService(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer target.Close()
+	targetMux := http.NewServeMux()
+	NewHandlers(target).RegisterRoutes(targetMux)
+
+	upload := func(filename string, content []byte) *httptest.ResponseRecorder {
+		var body bytes.Buffer
+		mw := multipart.NewWriter(&body)
+		fw, _ := mw.CreateFormFile("file", filename)
+		fw.Write(content)
+		mw.Close()
+		req := httptest.NewRequest(http.MethodPost, "/api/observer/import", &body)
+		req.Header.Set("Content-Type", mw.FormDataContentType())
+		rec := httptest.NewRecorder()
+		targetMux.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec = upload("observer.zip", bundle)
+	var resp struct {
+		Result BundleImportResult `json:"result"`
+	}
+	json.Unmarshal(rec.Body.Bytes(), &resp)
+	// Ab 08.07.: 5 Werktage
+	if rec.Code != http.StatusOK || resp.Result.ValuesAdded != 5 {
+		t.Errorf("Import: %d %s", rec.Code, rec.Body.String())
+	}
+
+	if rec := upload("observer.zip", []byte("kein zip")); rec.Code != http.StatusBadRequest {
+		t.Errorf("Ungültiges Bundle: %d", rec.Code)
+	}
+	if rec := upload("observer.csv", []byte("a,b")); rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), ".zip") {
+		t.Errorf("Dateityp: %d %s", rec.Code, rec.Body.String())
+	}
+}
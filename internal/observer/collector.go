@@ -2,6 +2,7 @@ package observer
 
 import (
 	"context"
+	"sync"
 	"time"
 )
 
@@ -46,6 +47,7 @@ func (c *BaseCollector) GetName() string {
 
 // CollectorRegistry verwaltet alle verfügbaren Collectors
 type CollectorRegistry struct {
+	mu         sync.RWMutex // konfigurierte Quellen kommen zur Laufzeit hinzu
 	collectors map[string]Collector
 }
 
@@ -58,16 +60,22 @@ func NewCollectorRegistry() *CollectorRegistry {
 
 // Register registriert einen Collector
 func (r *CollectorRegistry) Register(collector Collector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
 	r.collectors[collector.GetSourceCode()] = collector
 }
 
 // Get holt einen Collector nach Code
 func (r *CollectorRegistry) Get(sourceCode string) Collector {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
 	return r.collectors[sourceCode]
 }
 
 // GetAll gibt alle registrierten Collectors zurück
 func (r *CollectorRegistry) GetAll() []Collector {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
 	result := make([]Collector, 0, len(r.collectors))
 	for _, c := range r.collectors {
 		result = append(result, c)
@@ -78,7 +86,7 @@ func (r *CollectorRegistry) GetAll() []Collector {
 // GetAvailable gibt alle verfügbaren Collectors zurück
 func (r *CollectorRegistry) GetAvailable(ctx context.Context) []Collector {
 	result := make([]Collector, 0)
-	for _, c := range r.collectors {
+	for _, c := range r.GetAll() {
 		if c.IsAvailable(ctx) {
 			result = append(result, c)
 		}
@@ -2,6 +2,7 @@ package observer
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 	"time"
 )
@@ -122,6 +123,26 @@ func (p *ContextProvider) GetFinanceContext() string {
 		sb.WriteString(fmt.Sprintf("  EPRA Eurozone (REIT): %.2f EUR\n", val.Value))
 	}
 
+	// === VERALTETE DATEN ===
+	// Das Modell soll alte Stichtage nicht als aktuelle Marktlage ausgeben
+	if stale, err := p.service.GetStaleIndicators(); err == nil && len(stale) > 0 {
+		codes := make([]string, 0, len(stale))
+		for code := range stale {
+			if _, shown := latestValues[code]; shown {
+				codes = append(codes, code)
+			}
+		}
+		sort.Strings(codes)
+		if len(codes) > 0 {
+			sb.WriteString("\n⚠️ VERALTETE DATEN (letzter verfügbarer Stichtag):\n")
+			for _, code := range codes {
+				sb.WriteString(fmt.Sprintf("  %s: Stand %s (%d Tage alt)\n", code, stale[code].Format("02.01.2006"),
+					int(time.Since(stale[code]).Hours()/24)))
+			}
+			sb.WriteString("  Diese Werte sind nicht aktuell - weise den Nutzer darauf hin und nenne den Stichtag.\n")
+		}
+	}
+
 	sb.WriteString("\n--- ENDE MARKTDATEN ---\n")
 	sb.WriteString("(Historische Simulationen auf Anfrage verfügbar)\n")
 
@@ -161,6 +182,12 @@ func (p *ContextProvider) GetEconomicSummary() string {
 }
 
 // ShouldInjectContext prüft ob Kontext für einen Experten injiziert werden soll
+//
+// Die Keyword-Erkennung injiziert die komplette Marktübersicht und übersieht
+// Umschreibungen. Tool-fähige Modelle fragen die Daten gezielt über die
+// Observer-Tools ab (siehe NewTools).
+//
+// Deprecated: stattdessen NewTools verwenden (Tool-Calling im Experten-Chat)
 func (p *ContextProvider) ShouldInjectContext(expertName string, message string) bool {
 	// Franziska (Finanzberaterin) bekommt immer Kontext
 	if strings.Contains(strings.ToLower(expertName), "franziska") ||
@@ -200,6 +227,8 @@ func (p *ContextProvider) ShouldInjectContext(expertName string, message string)
 }
 
 // GetContextForExpert generiert den passenden Kontext für einen Experten
+//
+// Deprecated: stattdessen NewTools verwenden (Tool-Calling im Experten-Chat)
 func (p *ContextProvider) GetContextForExpert(expertName string, message string) string {
 	if !p.ShouldInjectContext(expertName, message) {
 		return ""
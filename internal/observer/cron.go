@@ -0,0 +1,194 @@
+package observer
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CronSchedule ist ein geparster Cron-Ausdruck (Minute Stunde Tag Monat Wochentag)
+//
+// Unterstützt werden *, Listen (1,15), Bereiche (1-5), Schritte (*/15, 8-18/2),
+// Wochentags- und Monatsnamen (MON, JAN) sowie @hourly, @daily, @weekly und @monthly.
+// Sind Tag und Wochentag beide eingeschränkt, genügt wie bei cron einer der beiden.
+type CronSchedule struct {
+	expr    string
+	minute  uint64
+	hour    uint64
+	dom     uint64
+	month   uint64
+	dow     uint64
+	domStar bool
+	dowStar bool
+}
+
+var cronMacros = map[string]string{
+	"@hourly":  "0 * * * *",
+	"@daily":   "0 0 * * *",
+	"@weekly":  "0 0 * * 0",
+	"@monthly": "0 0 1 * *",
+}
+
+var cronMonthNames = map[string]int{
+	"JAN": 1, "FEB": 2, "MAR": 3, "APR": 4, "MAY": 5, "JUN": 6,
+	"JUL": 7, "AUG": 8, "SEP": 9, "OCT": 10, "NOV": 11, "DEC": 12,
+}
+
+var cronDayNames = map[string]int{
+	"SUN": 0, "MON": 1, "TUE": 2, "WED": 3, "THU": 4, "FRI": 5, "SAT": 6,
+}
+
+// ParseCron parst einen Cron-Ausdruck mit fünf Feldern
+func ParseCron(expr string) (*CronSchedule, error) {
+	expr = strings.TrimSpace(expr)
+	fields := strings.Fields(expr)
+	if macro, ok := cronMacros[strings.ToLower(expr)]; ok {
+		fields = strings.Fields(macro)
+	}
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("Cron-Ausdruck %q: 5 Felder erwartet (Minute Stunde Tag Monat Wochentag)", expr)
+	}
+
+	s := &CronSchedule{expr: expr}
+	var err error
+	if s.minute, err = parseCronField(fields[0], 0, 59, nil); err != nil {
+		return nil, fmt.Errorf("Cron-Ausdruck %q: Minute: %w", expr, err)
+	}
+	if s.hour, err = parseCronField(fields[1], 0, 23, nil); err != nil {
+		return nil, fmt.Errorf("Cron-Ausdruck %q: Stunde: %w", expr, err)
+	}
+	if s.dom, err = parseCronField(fields[2], 1, 31, nil); err != nil {
+		return nil, fmt.Errorf("Cron-Ausdruck %q: Tag: %w", expr, err)
+	}
+	if s.month, err = parseCronField(fields[3], 1, 12, cronMonthNames); err != nil {
+		return nil, fmt.Errorf("Cron-Ausdruck %q: Monat: %w", expr, err)
+	}
+	// 7 ist wie bei cron ebenfalls Sonntag
+	if s.dow, err = parseCronField(fields[4], 0, 7, cronDayNames); err != nil {
+		return nil, fmt.Errorf("Cron-Ausdruck %q: Wochentag: %w", expr, err)
+	}
+	if s.dow&(1<<7) != 0 {
+		s.dow |= 1
+	}
+	s.domStar = fields[2] == "*" || fields[2] == "?"
+	s.dowStar = fields[4] == "*" || fields[4] == "?"
+	return s, nil
+}
+
+// parseCronField parst ein Feld in eine Bitmaske der erlaubten Werte
+func parseCronField(field string, min, max int, names map[string]int) (uint64, error) {
+	value := func(s string) (int, error) {
+		if n, ok := names[strings.ToUpper(s)]; ok {
+			return n, nil
+		}
+		n, err := strconv.Atoi(s)
+		if err != nil || n < min || n > max {
+			return 0, fmt.Errorf("ungültiger Wert %q (%d-%d)", s, min, max)
+		}
+		return n, nil
+	}
+
+	var mask uint64
+	for _, part := range strings.Split(field, ",") {
+		rangePart, step := part, 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n < 1 {
+				return 0, fmt.Errorf("ungültige Schrittweite in %q", part)
+			}
+			rangePart, step = part[:i], n
+		}
+
+		lo, hi := min, max
+		switch {
+		case rangePart == "*" || rangePart == "?":
+		case strings.Contains(rangePart, "-"):
+			bounds := strings.SplitN(rangePart, "-", 2)
+			var err error
+			if lo, err = value(bounds[0]); err != nil {
+				return 0, err
+			}
+			if hi, err = value(bounds[1]); err != nil {
+				return 0, err
+			}
+			if lo > hi {
+				return 0, fmt.Errorf("ungültiger Bereich %q", rangePart)
+			}
+		default:
+			n, err := value(rangePart)
+			if err != nil {
+				return 0, err
+			}
+			lo = n
+			// "5/15" bedeutet ab 5 alle 15
+			if step == 1 {
+				hi = n
+			}
+		}
+
+		for n := lo; n <= hi; n += step {
+			mask |= 1 << uint(n)
+		}
+	}
+	return mask, nil
+}
+
+// String gibt den ursprünglichen Ausdruck zurück
+func (s *CronSchedule) String() string {
+	return s.expr
+}
+
+// matchesDay prüft Tag und Wochentag (ODER-Verknüpfung wenn beide eingeschränkt sind)
+func (s *CronSchedule) matchesDay(t time.Time) bool {
+	domMatch := s.dom&(1<<uint(t.Day())) != 0
+	dowMatch := s.dow&(1<<uint(t.Weekday())) != 0
+	if s.domStar || s.dowStar {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
+
+// Next gibt den ersten Zeitpunkt nach after zurück (Nullzeit wenn es keinen gibt, z.B. 30. Februar)
+func (s *CronSchedule) Next(after time.Time) time.Time {
+	loc := after.Location()
+	t := after.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		if s.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if !s.matchesDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if s.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+			continue
+		}
+		if s.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+// dailyCron wandelt die bisherige tägliche Sammelzeit ("HH:MM") in einen Cron-Ausdruck für Werktage
+func dailyCron(collectTime string) string {
+	hour, minute := 6, 0
+	parts := strings.Split(collectTime, ":")
+	if h, err := strconv.Atoi(parts[0]); err == nil && h >= 0 && h <= 23 {
+		hour = h
+	}
+	if len(parts) >= 2 {
+		if m, err := strconv.Atoi(parts[1]); err == nil && m >= 0 && m <= 59 {
+			minute = m
+		}
+	}
+	// An Wochenenden kommen keine Updates für Finanzdaten
+	return fmt.Sprintf("%d %d * * 1-5", minute, hour)
+}
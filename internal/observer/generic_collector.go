@@ -0,0 +1,749 @@
+package observer
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Formate konfigurierter Quellen
+const (
+	FormatSDMXJSON = "sdmx-json" // SDMX-JSON 1.0/2.0 (EZB, BIS, OECD)
+	FormatSDMXML   = "sdmx-ml"   // SDMX-ML 2.0/2.1 Generic oder Structure-Specific (Eurostat, Bundesbank)
+	FormatCSV      = "csv"       // CSV/SDMX-CSV (Destatis, Eurostat)
+	FormatJSON     = "json"      // Beliebiges JSON mit Pfad zu den Datenpunkten
+)
+
+// Standardfelder für SDMX-Formate
+const (
+	defaultDateField  = "TIME_PERIOD"
+	defaultValueField = "OBS_VALUE"
+)
+
+// maxResponseSize begrenzt die Antwort einer konfigurierten Quelle (20 MB)
+const maxResponseSize = 20 << 20
+
+// SourceDefinition beschreibt eine Datenquelle, die ohne eigenen Collector abgefragt wird.
+// Platzhalter im URL-Template: {key} (ExternalCode des Indikators), {start}/{end} (2006-01-02)
+// und {startPeriod}/{endPeriod} im Format der Frequenz (z.B. 2024-01 oder 2024-Q1).
+type SourceDefinition struct {
+	URLTemplate  string            `json:"urlTemplate"`
+	Format       string            `json:"format"`                 // sdmx-json, sdmx-ml, csv, json
+	Headers      map[string]string `json:"headers,omitempty"`      // Zusätzliche HTTP-Header (z.B. Accept)
+	RecordsPath  string            `json:"recordsPath,omitempty"`  // JSON: Pfad zum Array der Datenpunkte (z.B. "data.observations")
+	DateField    string            `json:"dateField,omitempty"`    // Spalte, Attribut oder Pfad des Datums (Standard: TIME_PERIOD)
+	ValueField   string            `json:"valueField,omitempty"`   // Spalte, Attribut oder Pfad des Werts (Standard: OBS_VALUE)
+	DateFormat   string            `json:"dateFormat,omitempty"`   // Go-Layout, "unix" oder "unixms" (leer = SDMX-Formate)
+	Delimiter    string            `json:"delimiter,omitempty"`    // CSV: Trennzeichen (Standard: ",")
+	DecimalComma bool              `json:"decimalComma,omitempty"` // CSV: deutsches Zahlenformat (1.234,5)
+	SkipLines    int               `json:"skipLines,omitempty"`    // CSV: Vorspann-Zeilen vor dem Header
+	Frequency    string            `json:"frequency,omitempty"`    // Standard-Frequenz der Indikatoren (D, W, M, Q, A)
+}
+
+// seriesPoint ist ein geparster Datenpunkt einer Zeitreihe
+type seriesPoint struct {
+	Date  time.Time
+	Value float64
+}
+
+// Validate prüft und normalisiert die Definition
+func (d *SourceDefinition) Validate() error {
+	d.Format = strings.ToLower(strings.TrimSpace(d.Format))
+	d.Frequency = strings.ToUpper(strings.TrimSpace(d.Frequency))
+
+	switch d.Format {
+	case FormatSDMXJSON, FormatSDMXML, FormatCSV:
+	case FormatJSON:
+		if d.DateField == "" || d.ValueField == "" {
+			return fmt.Errorf("JSON-Format benötigt dateField und valueField")
+		}
+	default:
+		return fmt.Errorf("unbekanntes Format: %q (erlaubt: sdmx-json, sdmx-ml, csv, json)", d.Format)
+	}
+
+	if _, err := d.baseURL(); err != nil {
+		return err
+	}
+	if d.Frequency != "" && !isValidFrequency(d.Frequency) {
+		return fmt.Errorf("ungültige Frequenz: %s (erlaubt: D, W, M, Q, A)", d.Frequency)
+	}
+	if len([]rune(d.Delimiter)) > 1 {
+		return fmt.Errorf("Trennzeichen muss ein einzelnes Zeichen sein")
+	}
+	if d.SkipLines < 0 {
+		return fmt.Errorf("skipLines darf nicht negativ sein")
+	}
+	return nil
+}
+
+// baseURL liefert Schema und Host des URL-Templates
+func (d *SourceDefinition) baseURL() (string, error) {
+	sample := strings.NewReplacer("{key}", "KEY", "{start}", "", "{end}", "", "{startPeriod}", "", "{endPeriod}", "").Replace(d.URLTemplate)
+	u, err := url.Parse(sample)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", fmt.Errorf("ungültiges URL-Template: %q", d.URLTemplate)
+	}
+	return u.Scheme + "://" + u.Host, nil
+}
+
+// BuildURL setzt Indikator-Key und Zeitraum in das URL-Template ein
+func (d *SourceDefinition) BuildURL(key, frequency string, from, to time.Time) string {
+	return strings.NewReplacer(
+		"{key}", url.PathEscape(key),
+		"{start}", from.Format("2006-01-02"),
+		"{end}", to.Format("2006-01-02"),
+		"{startPeriod}", formatPeriod(from, frequency),
+		"{endPeriod}", formatPeriod(to, frequency),
+	).Replace(d.URLTemplate)
+}
+
+// isValidFrequency prüft einen Frequenz-Code
+func isValidFrequency(frequency string) bool {
+	switch frequency {
+	case "D", "W", "M", "Q", "A":
+		return true
+	}
+	return false
+}
+
+// formatPeriod formatiert ein Datum als SDMX-Periode passend zur Frequenz
+func formatPeriod(t time.Time, frequency string) string {
+	switch frequency {
+	case "M":
+		return t.Format("2006-01")
+	case "Q":
+		return fmt.Sprintf("%d-Q%d", t.Year(), (int(t.Month())-1)/3+1)
+	case "A":
+		return t.Format("2006")
+	default:
+		return t.Format("2006-01-02")
+	}
+}
+
+// periodStart gibt den Beginn der Periode zurück, in der t liegt (Stichtage der Quelle sind Periodenanfänge)
+func periodStart(t time.Time, frequency string) time.Time {
+	switch frequency {
+	case "W":
+		t = t.AddDate(0, 0, -6)
+	case "M":
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	case "Q":
+		return time.Date(t.Year(), time.Month((int(t.Month())-1)/3*3+1), 1, 0, 0, 0, 0, time.UTC)
+	case "A":
+		return time.Date(t.Year(), 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// collectWindow gibt den Zeitraum zurück, in dem der aktuelle Wert gesucht wird
+func collectWindow(frequency string, now time.Time) time.Time {
+	switch frequency {
+	case "W":
+		return now.AddDate(0, 0, -8*7)
+	case "M":
+		return now.AddDate(0, -6, 0)
+	case "Q":
+		return now.AddDate(-1, 0, 0)
+	case "A":
+		return now.AddDate(-3, 0, 0)
+	default:
+		return now.AddDate(0, 0, -14)
+	}
+}
+
+// === GenericCollector ===
+
+// GenericCollector sammelt Daten einer konfigurierten Quelle anhand ihrer SourceDefinition
+type GenericCollector struct {
+	BaseCollector
+	definition SourceDefinition
+	client     *http.Client
+
+	mu         sync.RWMutex
+	indicators []string
+}
+
+// NewGenericCollector erstellt einen Collector für eine konfigurierte Quelle
+func NewGenericCollector(source DataSource) *GenericCollector {
+	c := &GenericCollector{
+		BaseCollector: BaseCollector{
+			SourceCode: source.Code,
+			Name:       source.Name,
+			BaseURL:    source.URL,
+			Timeout:    30 * time.Second,
+		},
+		client: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+	}
+	if source.Definition != nil {
+		c.definition = *source.Definition
+	}
+	if c.BaseURL == "" {
+		c.BaseURL, _ = c.definition.baseURL()
+	}
+	return c
+}
+
+// IsAvailable prüft ob der Server der Quelle antwortet
+func (c *GenericCollector) IsAvailable(ctx context.Context) bool {
+	req, err := http.NewRequestWithContext(ctx, "GET", c.BaseURL, nil)
+	if err != nil {
+		return false
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		log.Printf("%s nicht erreichbar: %v", c.SourceCode, err)
+		return false
+	}
+	defer resp.Body.Close()
+
+	// Viele APIs haben keine Startseite - nur Serverfehler zählen als nicht verfügbar
+	return resp.StatusCode < http.StatusInternalServerError
+}
+
+// Collect sammelt den jeweils neuesten Wert aller Indikatoren
+func (c *GenericCollector) Collect(ctx context.Context, indicators []Indicator) (*CollectorResult, error) {
+	result := &CollectorResult{
+		SourceCode:  c.SourceCode,
+		CollectedAt: time.Now(),
+		Values:      make([]ObservationValue, 0),
+		Success:     true,
+	}
+
+	now := time.Now()
+	for _, indicator := range indicators {
+		values, err := c.fetchIndicator(ctx, indicator, collectWindow(indicator.Frequency, now), now)
+		if err != nil {
+			log.Printf("%s: Fehler bei %s: %v", c.SourceCode, indicator.Code, err)
+			result.ErrorMessage += fmt.Sprintf("%s: %v; ", indicator.Code, err)
+			continue
+		}
+
+		// Nur die letzte Beobachtung - wie lastNObservations=1 bei den SDMX-Collectors
+		if len(values) > 0 {
+			result.Values = append(result.Values, values[len(values)-1])
+		}
+	}
+
+	if result.ErrorMessage != "" {
+		result.Success = false
+	}
+
+	return result, nil
+}
+
+// CollectHistorical sammelt historische Daten (Backfill)
+func (c *GenericCollector) CollectHistorical(ctx context.Context, indicator Indicator, from, to time.Time) (*CollectorResult, error) {
+	result := &CollectorResult{
+		SourceCode:  c.SourceCode,
+		CollectedAt: time.Now(),
+		Values:      make([]ObservationValue, 0),
+		Success:     true,
+	}
+
+	values, err := c.fetchIndicator(ctx, indicator, from, to)
+	if err != nil {
+		result.Success = false
+		result.ErrorMessage = err.Error()
+		return result, err
+	}
+
+	result.Values = values
+	return result, nil
+}
+
+// SetSupportedIndicators setzt die Indikatoren der Quelle (aus der Datenbank)
+func (c *GenericCollector) SetSupportedIndicators(codes []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.indicators = codes
+}
+
+// GetSupportedIndicators gibt die unterstützten Indikator-Codes zurück
+func (c *GenericCollector) GetSupportedIndicators() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return append([]string(nil), c.indicators...)
+}
+
+// fetchIndicator holt die Datenpunkte eines Indikators im Zeitraum (aufsteigend sortiert)
+func (c *GenericCollector) fetchIndicator(ctx context.Context, indicator Indicator, from, to time.Time) ([]ObservationValue, error) {
+	frequency := indicator.Frequency
+	if frequency == "" {
+		frequency = c.definition.Frequency
+	}
+	requestURL := c.definition.BuildURL(indicator.ExternalCode, frequency, from, to)
+
+	log.Printf("%s: Abrufen von %s", c.SourceCode, requestURL)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", requestURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	for name, value := range c.definition.Headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("HTTP-Fehler: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("%s API Fehler %d: %s", c.SourceCode, resp.StatusCode, string(body))
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return nil, err
+	}
+
+	points, err := c.definition.parse(data)
+	if err != nil {
+		return nil, err
+	}
+
+	// Zeitraum einhalten, auch wenn die API die Parameter ignoriert
+	start := periodStart(from, frequency)
+	now := time.Now()
+	values := make([]ObservationValue, 0, len(points))
+	for _, p := range points {
+		if p.Date.Before(start) || p.Date.After(to) {
+			continue
+		}
+		values = append(values, ObservationValue{
+			IndicatorID: indicator.ID,
+			SourceID:    indicator.SourceID,
+			ObservedAt:  p.Date,
+			CollectedAt: now,
+			Value:       p.Value,
+			Unit:        indicator.Unit,
+			PeriodStart: p.Date,
+			PeriodEnd:   p.Date,
+		})
+	}
+
+	log.Printf("%s: %d Werte für %s abgerufen", c.SourceCode, len(values), indicator.Code)
+	return values, nil
+}
+
+// === Parser ===
+
+// parse wertet die Antwort im Format der Definition aus
+func (d *SourceDefinition) parse(data []byte) ([]seriesPoint, error) {
+	var points []seriesPoint
+	var err error
+
+	switch d.Format {
+	case FormatSDMXJSON:
+		points, err = d.parseSDMXJSON(data)
+	case FormatSDMXML:
+		points, err = d.parseSDMXML(data)
+	case FormatCSV:
+		points, err = d.parseCSV(data)
+	case FormatJSON:
+		points, err = d.parseJSON(data)
+	default:
+		return nil, fmt.Errorf("unbekanntes Format: %s", d.Format)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(points, func(i, j int) bool { return points[i].Date.Before(points[j].Date) })
+	return points, nil
+}
+
+func (d *SourceDefinition) dateField() string {
+	if d.DateField != "" {
+		return d.DateField
+	}
+	return defaultDateField
+}
+
+func (d *SourceDefinition) valueField() string {
+	if d.ValueField != "" {
+		return d.ValueField
+	}
+	return defaultValueField
+}
+
+// parseDate parst ein Datum mit dem konfigurierten Layout oder den SDMX-Formaten
+func (d *SourceDefinition) parseDate(raw string) (time.Time, error) {
+	raw = strings.TrimSpace(raw)
+
+	switch d.DateFormat {
+	case "":
+	case "unix", "unixms":
+		n, err := strconv.ParseInt(strings.SplitN(raw, ".", 2)[0], 10, 64)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("ungültiger Zeitstempel: %s", raw)
+		}
+		if d.DateFormat == "unixms" {
+			return time.UnixMilli(n).UTC(), nil
+		}
+		return time.Unix(n, 0).UTC(), nil
+	default:
+		return time.Parse(d.DateFormat, raw)
+	}
+
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return t, nil
+	}
+	// Eurostat-Monate: "2024M01" oder "2024-M01"
+	if i := strings.Index(raw, "M"); i >= 4 && len(raw) == i+3 {
+		if t, err := time.Parse("2006-01", strings.TrimSuffix(raw[:i], "-")+"-"+raw[i+1:]); err == nil {
+			return t, nil
+		}
+	}
+	return parseECBDate(raw)
+}
+
+// parseValue parst einen Zahlenwert (leere und fehlende Werte liefern ok=false)
+func (d *SourceDefinition) parseValue(raw string) (float64, bool) {
+	raw = strings.TrimSpace(raw)
+	switch raw {
+	case "", ".", "-", "...", "NaN", "NA", "null":
+		return 0, false
+	}
+	if d.DecimalComma {
+		raw = strings.ReplaceAll(raw, ".", "")
+		raw = strings.ReplaceAll(raw, ",", ".")
+	}
+	value, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		return 0, false
+	}
+	return value, true
+}
+
+// sdmxJSONMessage deckt SDMX-JSON 1.0 (dataSets/structure) und 2.0 (data.dataSets/data.structures) ab
+type sdmxJSONMessage struct {
+	sdmxJSONData
+	Data *sdmxJSONData `json:"data"`
+}
+
+type sdmxJSONData struct {
+	DataSets []struct {
+		Series map[string]struct {
+			Observations map[string][]interface{} `json:"observations"`
+		} `json:"series"`
+		Observations map[string][]interface{} `json:"observations"` // Flaches Format (AllDimensions)
+	} `json:"dataSets"`
+	Structure  *sdmxJSONStructure  `json:"structure"`
+	Structures []sdmxJSONStructure `json:"structures"`
+}
+
+type sdmxJSONStructure struct {
+	Dimensions struct {
+		Observation []struct {
+			ID     string `json:"id"`
+			Values []struct {
+				ID    string `json:"id"`
+				Start string `json:"start,omitempty"`
+			} `json:"values"`
+		} `json:"observation"`
+	} `json:"dimensions"`
+}
+
+// parseSDMXJSON parst SDMX-JSON (nur die erste Serie - ein Key soll genau eine Zeitreihe liefern)
+func (d *SourceDefinition) parseSDMXJSON(data []byte) ([]seriesPoint, error) {
+	var msg sdmxJSONMessage
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return nil, fmt.Errorf("JSON-Parse-Fehler: %w", err)
+	}
+
+	body := &msg.sdmxJSONData
+	if msg.Data != nil {
+		body = msg.Data
+	}
+	structure := body.Structure
+	if structure == nil && len(body.Structures) > 0 {
+		structure = &body.Structures[0]
+	}
+	if len(body.DataSets) == 0 || structure == nil {
+		return []seriesPoint{}, nil
+	}
+
+	// Zeit-Dimension suchen
+	dims := structure.Dimensions.Observation
+	timePos := -1
+	for i, dim := range dims {
+		if dim.ID == d.dateField() {
+			timePos = i
+			break
+		}
+	}
+	if timePos == -1 {
+		if len(dims) != 1 {
+			return nil, fmt.Errorf("Zeit-Dimension %s nicht gefunden", d.dateField())
+		}
+		timePos = 0
+	}
+
+	dates := make([]time.Time, len(dims[timePos].Values))
+	valid := make([]bool, len(dates))
+	for i, val := range dims[timePos].Values {
+		raw := val.ID
+		if raw == "" {
+			raw = val.Start
+		}
+		t, err := d.parseDate(raw)
+		if err != nil {
+			continue
+		}
+		dates[i], valid[i] = t, true
+	}
+
+	dataSet := body.DataSets[0]
+	observations := dataSet.Observations
+	if len(dataSet.Series) > 0 {
+		keys := make([]string, 0, len(dataSet.Series))
+		for key := range dataSet.Series {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		if len(keys) > 1 {
+			log.Printf("Observer: SDMX-Antwort enthält %d Serien, verwende %s", len(keys), keys[0])
+		}
+		observations = dataSet.Series[keys[0]].Observations
+	}
+
+	points := make([]seriesPoint, 0, len(observations))
+	for obsKey, obsValues := range observations {
+		if len(obsValues) == 0 {
+			continue
+		}
+		parts := strings.Split(obsKey, ":")
+		if timePos >= len(parts) {
+			continue
+		}
+		idx, err := strconv.Atoi(parts[timePos])
+		if err != nil || idx < 0 || idx >= len(dates) || !valid[idx] {
+			continue
+		}
+
+		var value float64
+		switch v := obsValues[0].(type) {
+		case float64:
+			value = v
+		case string:
+			var ok bool
+			if value, ok = d.parseValue(v); !ok {
+				continue
+			}
+		default:
+			continue
+		}
+		points = append(points, seriesPoint{Date: dates[idx], Value: value})
+	}
+	return points, nil
+}
+
+// parseSDMXML parst SDMX-ML Generic (ObsDimension/ObsValue) und Structure-Specific (Obs-Attribute)
+func (d *SourceDefinition) parseSDMXML(data []byte) ([]seriesPoint, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	points := make([]seriesPoint, 0)
+
+	seriesCount := 0
+	inObs := false
+	var date, value string
+
+	for {
+		tok, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("XML-Parse-Fehler: %w", err)
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "Series":
+				seriesCount++
+				if seriesCount == 2 {
+					log.Printf("Observer: SDMX-Antwort enthält mehrere Serien, verwende die erste")
+				}
+			case "Obs":
+				inObs = seriesCount <= 1
+				date, value = xmlAttr(t, d.dateField()), xmlAttr(t, d.valueField())
+			case "ObsDimension":
+				if id := xmlAttr(t, "id"); inObs && (id == "" || id == d.dateField()) {
+					date = xmlAttr(t, "value")
+				}
+			case "ObsValue":
+				if inObs {
+					value = xmlAttr(t, "value")
+				}
+			case "Time": // SDMX-ML 2.0: <generic:Time>2024-01</generic:Time>
+				if inObs {
+					var text string
+					if err := decoder.DecodeElement(&text, &t); err == nil {
+						date = text
+					}
+				}
+			}
+		case xml.EndElement:
+			if t.Name.Local != "Obs" || !inObs {
+				continue
+			}
+			inObs = false
+			observedAt, err := d.parseDate(date)
+			if err != nil {
+				continue
+			}
+			if v, ok := d.parseValue(value); ok {
+				points = append(points, seriesPoint{Date: observedAt, Value: v})
+			}
+		}
+	}
+	return points, nil
+}
+
+// xmlAttr liefert ein Attribut unabhängig vom Namespace
+func xmlAttr(el xml.StartElement, name string) string {
+	for _, attr := range el.Attr {
+		if attr.Name.Local == name {
+			return attr.Value
+		}
+	}
+	return ""
+}
+
+// parseCSV parst CSV mit Header-Zeile (Spalten per Name, ohne Groß-/Kleinschreibung)
+func (d *SourceDefinition) parseCSV(data []byte) ([]seriesPoint, error) {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")) // UTF-8 BOM
+	for i := 0; i < d.SkipLines; i++ {
+		idx := bytes.IndexByte(data, '\n')
+		if idx == -1 {
+			return nil, fmt.Errorf("CSV hat weniger als %d Zeilen", d.SkipLines)
+		}
+		data = data[idx+1:]
+	}
+
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.LazyQuotes = true
+	reader.FieldsPerRecord = -1
+	if d.Delimiter != "" {
+		reader.Comma = []rune(d.Delimiter)[0]
+	}
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("CSV-Header-Fehler: %w", err)
+	}
+
+	dateIdx, valueIdx := -1, -1
+	for i, col := range header {
+		col = strings.TrimSpace(col)
+		if strings.EqualFold(col, d.dateField()) {
+			dateIdx = i
+		}
+		if strings.EqualFold(col, d.valueField()) {
+			valueIdx = i
+		}
+	}
+	if dateIdx == -1 || valueIdx == -1 {
+		return nil, fmt.Errorf("Spalten %s/%s nicht gefunden in: %v", d.dateField(), d.valueField(), header)
+	}
+
+	points := make([]seriesPoint, 0)
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil || len(record) <= dateIdx || len(record) <= valueIdx {
+			continue
+		}
+
+		observedAt, err := d.parseDate(record[dateIdx])
+		if err != nil {
+			continue // Fußzeilen, Anmerkungen
+		}
+		if value, ok := d.parseValue(record[valueIdx]); ok {
+			points = append(points, seriesPoint{Date: observedAt, Value: value})
+		}
+	}
+	return points, nil
+}
+
+// parseJSON parst beliebiges JSON: recordsPath zeigt auf ein Array, date-/valueField sind Pfade je Eintrag
+func (d *SourceDefinition) parseJSON(data []byte) ([]seriesPoint, error) {
+	var root interface{}
+	if err := json.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("JSON-Parse-Fehler: %w", err)
+	}
+
+	records, ok := jsonPath(root, d.RecordsPath).([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("kein Array unter Pfad %q", d.RecordsPath)
+	}
+
+	points := make([]seriesPoint, 0, len(records))
+	for _, record := range records {
+		var rawDate string
+		switch v := jsonPath(record, d.DateField).(type) {
+		case string:
+			rawDate = v
+		case float64:
+			rawDate = strconv.FormatFloat(v, 'f', -1, 64)
+		default:
+			continue
+		}
+		observedAt, err := d.parseDate(rawDate)
+		if err != nil {
+			continue
+		}
+
+		switch v := jsonPath(record, d.ValueField).(type) {
+		case float64:
+			points = append(points, seriesPoint{Date: observedAt, Value: v})
+		case string:
+			if value, ok := d.parseValue(v); ok {
+				points = append(points, seriesPoint{Date: observedAt, Value: value})
+			}
+		}
+	}
+	return points, nil
+}
+
+// jsonPath folgt einem Pfad wie "data.observations" oder "prices.0" (leer = Wurzel)
+func jsonPath(node interface{}, path string) interface{} {
+	if path == "" {
+		return node
+	}
+	for _, part := range strings.Split(path, ".") {
+		switch n := node.(type) {
+		case map[string]interface{}:
+			node = n[part]
+		case []interface{}:
+			idx, err := strconv.Atoi(part)
+			if err != nil || idx < 0 || idx >= len(n) {
+				return nil
+			}
+			node = n[idx]
+		default:
+			return nil
+		}
+	}
+	return node
+}
@@ -0,0 +1,259 @@
+package observer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newFixtureServer liefert die aufgezeichneten Antworten aus testdata und merkt sich die Anfragen
+func newFixtureServer(t *testing.T) (*httptest.Server, *[]string) {
+	t.Helper()
+	var requests []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.URL.RequestURI())
+		name := filepath.Base(r.URL.Path)
+		data, err := os.ReadFile(filepath.Join("testdata", name))
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(data)
+	}))
+	t.Cleanup(ts.Close)
+	return ts, &requests
+}
+
+// TestGenericCollectorFormats prüft alle Formate gegen aufgezeichnete Antworten
+func TestGenericCollectorFormats(t *testing.T) {
+	ts, requests := newFixtureServer(t)
+	from := time.Date(2024, 7, 15, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 12, 31, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		def        SourceDefinition
+		key        string
+		frequency  string
+		wantURL    string
+		wantCount  int
+		wantLast   string
+		wantValue  float64
+		wantErrStr string
+	}{
+		{
+			name:      "SDMX-JSON (EZB)",
+			def:       SourceDefinition{URLTemplate: ts.URL + "/{key}/ecb_sdmx.json?startPeriod={startPeriod}&endPeriod={endPeriod}", Format: "SDMX-JSON"},
+			key:       "FM.D.U2.EUR.4F.KR.DFR.LEV",
+			frequency: "D",
+			wantURL:   "/FM.D.U2.EUR.4F.KR.DFR.LEV/ecb_sdmx.json?startPeriod=2024-07-15&endPeriod=2024-12-31",
+			wantCount: 3, wantLast: "2024-09-18", wantValue: 3.65,
+		},
+		{
+			name:      "SDMX-ML Structure-Specific (Eurostat), Juli zählt trotz Stichtag 15.07.",
+			def:       SourceDefinition{URLTemplate: ts.URL + "/eurostat_hicp.xml?startPeriod={startPeriod}", Format: FormatSDMXML},
+			frequency: "M",
+			wantURL:   "/eurostat_hicp.xml?startPeriod=2024-07",
+			wantCount: 3, wantLast: "2024-09-01", wantValue: 1.8,
+		},
+		{
+			name:      "SDMX-ML Generic (Bundesbank), nur erste Serie",
+			def:       SourceDefinition{URLTemplate: ts.URL + "/bundesbank_generic.xml", Format: FormatSDMXML},
+			frequency: "M",
+			wantCount: 2, wantLast: "2024-08-01", wantValue: 2.23,
+		},
+		{
+			name: "CSV mit Vorspann und Dezimalkomma (Destatis)",
+			def: SourceDefinition{URLTemplate: ts.URL + "/destatis_arbeitslose.csv", Format: FormatCSV,
+				Delimiter: ";", DecimalComma: true, SkipLines: 3, DateField: "Zeit", ValueField: "Arbeitslosenquote"},
+			frequency: "M",
+			wantCount: 3, wantLast: "2024-09-01", wantValue: 6.0,
+		},
+		{
+			name: "JSON-Pfad",
+			def: SourceDefinition{URLTemplate: ts.URL + "/estr_history.json?from={start}", Format: FormatJSON,
+				RecordsPath: "data.observations", DateField: "date", ValueField: "value"},
+			frequency: "D",
+			wantURL:   "/estr_history.json?from=2024-07-15",
+			wantCount: 3, wantLast: "2024-10-03", wantValue: 3.41,
+		},
+		{
+			name:       "Falsche Spalte",
+			def:        SourceDefinition{URLTemplate: ts.URL + "/destatis_arbeitslose.csv", Format: FormatCSV, Delimiter: ";", SkipLines: 3},
+			wantErrStr: "nicht gefunden",
+		},
+		{
+			name:       "HTTP-Fehler",
+			def:        SourceDefinition{URLTemplate: ts.URL + "/fehlt.json", Format: FormatSDMXJSON},
+			wantErrStr: "404",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.def.Validate(); err != nil {
+				t.Fatal(err)
+			}
+			*requests = nil
+			c := NewGenericCollector(DataSource{Code: "TEST", Name: "Test", Definition: &tt.def})
+			ind := Indicator{ID: 7, Code: "TEST_IND", SourceID: 3, ExternalCode: tt.key, Frequency: tt.frequency, Unit: "%"}
+
+			result, err := c.CollectHistorical(context.Background(), ind, from, to)
+			if tt.wantErrStr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErrStr) {
+					t.Fatalf("Fehler %v, erwartet %q", err, tt.wantErrStr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantURL != "" && (len(*requests) != 1 || (*requests)[0] != tt.wantURL) {
+				t.Errorf("Anfrage %v, erwartet %s", *requests, tt.wantURL)
+			}
+			if len(result.Values) != tt.wantCount {
+				t.Fatalf("%d Werte, erwartet %d: %+v", len(result.Values), tt.wantCount, result.Values)
+			}
+			last := result.Values[len(result.Values)-1]
+			if last.ObservedAt.Format("2006-01-02") != tt.wantLast || last.Value != tt.wantValue {
+				t.Errorf("Letzter Wert %s = %v, erwartet %s = %v", last.ObservedAt.Format("2006-01-02"), last.Value, tt.wantLast, tt.wantValue)
+			}
+			if last.IndicatorID != 7 || last.SourceID != 3 || last.Unit != "%" {
+				t.Errorf("Zuordnung: %+v", last)
+			}
+		})
+	}
+}
+
+// TestSourceDefinitionValidate prüft die Validierung der Definition
+func TestSourceDefinitionValidate(t *testing.T) {
+	invalid := []SourceDefinition{
+		{URLTemplate: "https://example.org/{key}", Format: "xlsx"},
+		{URLTemplate: "ftp://example.org/{key}", Format: FormatCSV},
+		{URLTemplate: "https://example.org/{key}", Format: FormatJSON, DateField: "date"},
+		{URLTemplate: "https://example.org/{key}", Format: FormatCSV, Frequency: "H"},
+		{URLTemplate: "https://example.org/{key}", Format: FormatCSV, Delimiter: ";;"},
+	}
+	for _, def := range invalid {
+		if err := def.Validate(); err == nil {
+			t.Errorf("Ungültige Definition akzeptiert: %+v", def)
+		}
+	}
+
+	def := SourceDefinition{URLTemplate: "https://example.org/data/{key}?c={startPeriod}", Format: " CSV ", Frequency: "q"}
+	if err := def.Validate(); err != nil || def.Format != FormatCSV || def.Frequency != "Q" {
+		t.Errorf("Normalisierung: %+v, %v", def, err)
+	}
+	got := def.BuildURL("A+B", "Q", time.Date(2024, 5, 3, 0, 0, 0, 0, time.UTC), time.Now())
+	if got != "https://example.org/data/A+B?c=2024-Q2" {
+		t.Errorf("URL = %s", got)
+	}
+}
+
+// TestConfiguredSourceAPI legt Quelle und Indikator über die API an und sammelt per Backfill
+func TestConfiguredSourceAPI(t *testing.T) {
+	ts, _ := newFixtureServer(t)
+	dataDir := t.TempDir()
+
+	service, err := NewService(dataDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mux := http.NewServeMux()
+	NewHandlers(service).RegisterRoutes(mux)
+
+	post := func(path string, body interface{}) *httptest.ResponseRecorder {
+		data, _ := json.Marshal(body)
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, path, bytes.NewReader(data)))
+		return rec
+	}
+
+	source := map[string]interface{}{
+		"code": "eurostat",
+		"name": "Eurostat",
+		"definition": map[string]interface{}{
+			"urlTemplate": ts.URL + "/{key}?startPeriod={startPeriod}&endPeriod={endPeriod}",
+			"format":      "sdmx-ml",
+			"frequency":   "M",
+		},
+	}
+	if rec := post("/api/observer/sources", source); rec.Code != http.StatusCreated {
+		t.Fatalf("Quelle anlegen: %d %s", rec.Code, rec.Body.String())
+	}
+	if rec := post("/api/observer/sources", source); rec.Code != http.StatusConflict {
+		t.Errorf("Doppelte Quelle: %d", rec.Code)
+	}
+	if rec := post("/api/observer/sources", map[string]interface{}{"code": "ECB", "name": "X",
+		"definition": map[string]string{"urlTemplate": ts.URL, "format": "csv"}}); rec.Code != http.StatusConflict {
+		t.Errorf("Eingebaute Quelle überschrieben: %d", rec.Code)
+	}
+
+	indicator := map[string]string{
+		"sourceCode":   "EUROSTAT",
+		"code":         "hicp_de_eurostat",
+		"name":         "HICP Deutschland (Eurostat)",
+		"category":     string(CategoryInflation),
+		"unit":         "%",
+		"externalCode": "eurostat_hicp.xml",
+	}
+	rec := post("/api/observer/indicators", indicator)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Indikator anlegen: %d %s", rec.Code, rec.Body.String())
+	}
+	var created Indicator
+	json.NewDecoder(rec.Body).Decode(&created)
+	if created.Code != "HICP_DE_EUROSTAT" || created.Frequency != "M" || created.ID == 0 {
+		t.Errorf("Indikator: %+v", created)
+	}
+	indicator["sourceCode"] = "ECB"
+	indicator["code"] = "ECB_EXTRA"
+	if rec := post("/api/observer/indicators", indicator); rec.Code != http.StatusBadRequest {
+		t.Errorf("Indikator für eingebaute Quelle: %d", rec.Code)
+	}
+
+	// Nur die konfigurierte Quelle sammeln (keine echten APIs im Test)
+	config := service.GetConfig()
+	config.ActiveSources = []string{"EUROSTAT"}
+	service.SetConfig(config)
+
+	run, err := service.Backfill(context.Background(), time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 10, 31, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if run.Status != RunStatusCompleted || run.TotalRecords != 4 {
+		t.Errorf("Lauf: %+v", run)
+	}
+	history, err := service.GetIndicatorHistory("HICP_DE_EUROSTAT")
+	if err != nil || len(history.Values) != 4 {
+		t.Fatalf("Historie: %+v, %v", history, err)
+	}
+	service.Close()
+
+	// Nach Neustart: Definition aus der Datenbank, Collector wieder registriert und aktiv
+	restarted, err := NewService(dataDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer restarted.Close()
+
+	src, _ := restarted.repo.GetSourceByCode("EUROSTAT")
+	if src == nil || src.Definition == nil || src.Definition.Format != FormatSDMXML {
+		t.Fatalf("Quelle nach Neustart: %+v", src)
+	}
+	collector := restarted.registry.Get("EUROSTAT")
+	if collector == nil || len(collector.GetSupportedIndicators()) != 1 {
+		t.Fatalf("Collector nach Neustart: %+v", collector)
+	}
+	active := restarted.GetConfig().ActiveSources
+	if active[len(active)-1] != "EUROSTAT" {
+		t.Errorf("Aktive Quellen: %v", active)
+	}
+}
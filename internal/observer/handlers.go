@@ -1,8 +1,10 @@
 package observer
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -35,9 +37,20 @@ func (h *Handlers) RegisterRoutes(mux *http.ServeMux) {
 	// Daten
 	mux.HandleFunc("/api/observer/indicators", h.handleIndicators)
 	mux.HandleFunc("/api/observer/sources", h.handleSources)
+	mux.HandleFunc("/api/observer/sources/preview", h.handleSourcePreview)
 	mux.HandleFunc("/api/observer/values/latest", h.handleLatestValues)
 	mux.HandleFunc("/api/observer/values/", h.handleIndicatorValues)
 	mux.HandleFunc("/api/observer/runs", h.handleRuns)
+	mux.HandleFunc("/api/observer/query", h.handleQuery)
+
+	// Datenqualität
+	mux.HandleFunc("/api/observer/quality", h.handleQualityOverview)
+	mux.HandleFunc("/api/observer/quality/", h.handleQualityReport)
+
+	// Alarme
+	mux.HandleFunc("/api/observer/alerts", h.handleAlertHistory)
+	mux.HandleFunc("/api/observer/alerts/rules", h.handleAlertRules)
+	mux.HandleFunc("/api/observer/alerts/rules/", h.handleAlertRule)
 
 	// Aktionen
 	mux.HandleFunc("/api/observer/run", h.handleRunNow)
@@ -47,6 +60,7 @@ func (h *Handlers) RegisterRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("/api/observer/simulate", h.handleSimulate)
 	mux.HandleFunc("/api/observer/simulate/indicators", h.handleSimulatableIndicators)
 	mux.HandleFunc("/api/observer/simulate/periods", h.handleSimulationPeriods)
+	mux.HandleFunc("/api/observer/simulate/portfolio", h.handleSimulatePortfolio)
 
 	// Asset-Klassen
 	mux.HandleFunc("/api/observer/asset-classes", h.handleAssetClasses)
@@ -91,6 +105,7 @@ func (h *Handlers) handleStatus(w http.ResponseWriter, r *http.Request) {
 	if h.service.scheduler != nil {
 		response["nextRun"] = h.service.scheduler.GetNextRun()
 		response["schedulerRunning"] = h.service.scheduler.IsRunning()
+		response["schedule"] = h.service.scheduler.GetSchedule()
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -112,6 +127,11 @@ func (h *Handlers) handleConfig(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		if err := config.Validate(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
 		if err := h.service.SetConfig(&config); err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
@@ -134,8 +154,12 @@ func (h *Handlers) handleConfig(w http.ResponseWriter, r *http.Request) {
 
 // --- Daten ---
 
-// handleIndicators gibt alle Indikatoren zurück
+// handleIndicators gibt alle Indikatoren zurück (GET) oder legt einen für eine konfigurierte Quelle an (POST)
 func (h *Handlers) handleIndicators(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodPost {
+		h.handleCreateIndicator(w, r)
+		return
+	}
 	if r.Method != http.MethodGet {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
@@ -153,8 +177,12 @@ func (h *Handlers) handleIndicators(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(indicators)
 }
 
-// handleSources gibt alle Datenquellen zurück
+// handleSources gibt alle Datenquellen zurück (GET) oder legt eine konfigurierte Quelle an (POST)
 func (h *Handlers) handleSources(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodPost {
+		h.handleCreateSource(w, r)
+		return
+	}
 	if r.Method != http.MethodGet {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
@@ -172,6 +200,88 @@ func (h *Handlers) handleSources(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(sources)
 }
 
+// handleCreateSource legt eine Quelle mit SourceDefinition an (ohne eigenen Collector)
+func (h *Handlers) handleCreateSource(w http.ResponseWriter, r *http.Request) {
+	var source DataSource
+	if err := json.NewDecoder(r.Body).Decode(&source); err != nil {
+		http.Error(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.CreateSource(&source); err != nil {
+		writeCreateError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(source)
+}
+
+// handleCreateIndicator legt einen Indikator für eine konfigurierte Quelle an
+func (h *Handlers) handleCreateIndicator(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Indicator
+		SourceCode string `json:"sourceCode"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ind := req.Indicator
+	if err := h.service.CreateIndicator(&ind, req.SourceCode); err != nil {
+		writeCreateError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(ind)
+}
+
+// handleSourcePreview ruft eine Definition probeweise ab (zum Testen vor dem Anlegen)
+func (h *Handlers) handleSourcePreview(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		Definition   SourceDefinition `json:"definition"`
+		ExternalCode string           `json:"externalCode"`
+		Frequency    string           `json:"frequency"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+
+	values, err := h.service.PreviewSource(ctx, req.Definition, req.ExternalCode, req.Frequency)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"count":  len(values),
+		"values": values,
+	})
+}
+
+// writeCreateError unterscheidet vergebene Codes (409) von ungültigen Angaben (400)
+func writeCreateError(w http.ResponseWriter, err error) {
+	if errors.Is(err, ErrAlreadyExists) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	http.Error(w, err.Error(), http.StatusBadRequest)
+}
+
 // handleLatestValues gibt die neuesten Werte aller Indikatoren zurück
 func (h *Handlers) handleLatestValues(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
@@ -226,6 +336,72 @@ func (h *Handlers) handleIndicatorValues(w http.ResponseWriter, r *http.Request)
 	json.NewEncoder(w).Encode(history)
 }
 
+// handleQuery liefert eine resampelte/abgeleitete Zeitreihe als JSON oder CSV
+// GET ?indicator=DE_10Y_YIELD&minus=ESTR&from=2024-01-01&to=2024-12-31&frequency=M&agg=mean&transform=yoy&window=3&format=csv
+func (h *Handlers) handleQuery(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	params := r.URL.Query()
+	q := SeriesQuery{
+		Indicator:   params.Get("indicator"),
+		Minus:       params.Get("minus"),
+		Frequency:   params.Get("frequency"),
+		Aggregation: params.Get("agg"),
+		Transform:   params.Get("transform"),
+	}
+	if v := params.Get("from"); v != "" {
+		from, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			http.Error(w, "Invalid from date", http.StatusBadRequest)
+			return
+		}
+		q.From = &from
+	}
+	if v := params.Get("to"); v != "" {
+		to, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			http.Error(w, "Invalid to date", http.StatusBadRequest)
+			return
+		}
+		// Enddatum einschließlich
+		to = to.Add(24*time.Hour - time.Nanosecond)
+		q.To = &to
+	}
+	if v := params.Get("window"); v != "" {
+		window, err := strconv.Atoi(v)
+		if err != nil {
+			http.Error(w, "Invalid window", http.StatusBadRequest)
+			return
+		}
+		q.Window = window
+	}
+
+	result, err := h.service.QuerySeries(q)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if params.Get("format") == "csv" {
+		// BOM für UTF-8 (für Excel-Kompatibilität)
+		csvBytes := append([]byte{0xEF, 0xBB, 0xBF}, []byte(FormatSeriesCSV(result))...)
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s_%s.csv"`, result.Query.Indicator, time.Now().Format("2006-01-02")))
+		w.Write(csvBytes)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
 // handleRuns gibt die letzten Sammelläufe zurück
 func (h *Handlers) handleRuns(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
@@ -250,6 +426,133 @@ func (h *Handlers) handleRuns(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(runs)
 }
 
+// --- Datenqualität ---
+
+// handleQualityOverview gibt Aktualität, Ausreißer, Lücken und Revisionen aller Indikatoren zurück
+func (h *Handlers) handleQualityOverview(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	overview, err := h.service.GetQualityOverview()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(overview)
+}
+
+// handleQualityReport gibt den Qualitätsbericht eines Indikators zurück
+func (h *Handlers) handleQualityReport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// /api/observer/quality/ECB_MAIN_RATE
+	code := strings.TrimPrefix(r.URL.Path, "/api/observer/quality/")
+	if code == "" {
+		http.Error(w, "Indicator code required", http.StatusBadRequest)
+		return
+	}
+
+	report, err := h.service.GetQualityReport(code)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+// --- Alarme ---
+
+// handleAlertRules gibt alle Alarm-Regeln zurück (GET) oder legt eine Regel an (POST)
+func (h *Handlers) handleAlertRules(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		rules, err := h.service.GetAlertRules()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(rules)
+
+	case http.MethodPost:
+		var rule AlertRule
+		if err := json.NewDecoder(r.Body).Decode(&rule); err != nil {
+			http.Error(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := h.service.CreateAlertRule(&rule); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(rule)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleAlertRule löscht eine Alarm-Regel
+func (h *Handlers) handleAlertRule(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// /api/observer/alerts/rules/3
+	id, err := strconv.ParseInt(strings.TrimPrefix(r.URL.Path, "/api/observer/alerts/rules/"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid rule ID", http.StatusBadRequest)
+		return
+	}
+
+	deleted, err := h.service.DeleteAlertRule(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !deleted {
+		http.Error(w, "Rule not found", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleAlertHistory gibt die ausgelösten Alarme zurück (?indicator=CODE&limit=N)
+func (h *Handlers) handleAlertHistory(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	limit := 100
+	if l := r.URL.Query().Get("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 {
+			limit = parsed
+		}
+	}
+
+	events, err := h.service.GetAlertHistory(r.URL.Query().Get("indicator"), limit)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(events)
+}
+
 // --- Aktionen ---
 
 // handleRunNow führt einen Sammellauf sofort aus
@@ -394,8 +697,54 @@ func (h *Handlers) handleExport(w http.ResponseWriter, r *http.Request) {
 			time.Now().Format("2006-01-02")))
 		json.NewEncoder(w).Encode(export)
 
+	case "bundle", "parquet":
+		// Versioniertes Datensatz-Bundle (ZIP mit CSV- bzw. Parquet-Dateien und manifest.json)
+		// ?indicators=GOLD_EUR,ESTR&from=2024-01-01&to=2024-12-31
+		opts := BundleOptions{DataFormat: BundleDataCSV}
+		if format == "parquet" {
+			opts.DataFormat = BundleDataParquet
+		}
+		params := r.URL.Query()
+		if v := params.Get("indicators"); v != "" {
+			opts.Indicators = strings.Split(v, ",")
+		}
+		if v := params.Get("from"); v != "" {
+			from, err := time.Parse("2006-01-02", v)
+			if err != nil {
+				http.Error(w, "Invalid from date", http.StatusBadRequest)
+				return
+			}
+			opts.From = &from
+		}
+		if v := params.Get("to"); v != "" {
+			to, err := time.Parse("2006-01-02", v)
+			if err != nil {
+				http.Error(w, "Invalid to date", http.StatusBadRequest)
+				return
+			}
+			// Enddatum einschließlich
+			to = to.Add(24*time.Hour - time.Nanosecond)
+			opts.To = &to
+		}
+
+		// Erst puffern, damit Fehler noch als HTTP-Status gemeldet werden können
+		var buf bytes.Buffer
+		if _, err := h.service.ExportBundle(&buf, opts); err != nil {
+			if errors.Is(err, ErrNotFound) {
+				http.Error(w, err.Error(), http.StatusNotFound)
+				return
+			}
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/zip")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=observer_%s_%s.zip",
+			opts.DataFormat, time.Now().Format("2006-01-02")))
+		w.Write(buf.Bytes())
+
 	default:
-		http.Error(w, "Unknown format. Use: sql, sqlite, json", http.StatusBadRequest)
+		http.Error(w, "Unknown format. Use: sql, sqlite, json, bundle, parquet", http.StatusBadRequest)
 	}
 }
 
@@ -419,10 +768,10 @@ func (h *Handlers) handleImport(w http.ResponseWriter, r *http.Request) {
 	}
 	defer file.Close()
 
-	// Dateiendung validieren (nur .sql erlaubt)
+	// Dateiendung validieren (.sql oder .zip-Bundle)
 	ext := strings.ToLower(filepath.Ext(header.Filename))
-	if ext != ".sql" {
-		http.Error(w, "Unsupported file type. Use .sql", http.StatusBadRequest)
+	if ext != ".sql" && ext != ".zip" {
+		http.Error(w, "Unsupported file type. Use .sql or .zip", http.StatusBadRequest)
 		return
 	}
 
@@ -431,7 +780,7 @@ func (h *Handlers) handleImport(w http.ResponseWriter, r *http.Request) {
 	log.Printf("[Observer] Import: %s", safeFilename)
 
 	// Sichere temporäre Datei erstellen (system-generierter Name)
-	tmpFile, err := os.CreateTemp("", "observer-import-*.sql")
+	tmpFile, err := os.CreateTemp("", "observer-import-*"+ext)
 	if err != nil {
 		http.Error(w, "Temp-Datei erstellen fehlgeschlagen", http.StatusInternalServerError)
 		return
@@ -454,8 +803,40 @@ func (h *Handlers) handleImport(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+	case ".zip":
+		// Bundle wird geprüft und zusammengeführt statt die Datenbank zu ersetzen
+		f, err := os.Open(tmpPath)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		defer f.Close()
+		info, err := f.Stat()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		result, err := h.service.ImportBundle(f, info.Size())
+		if err != nil {
+			if errors.Is(err, ErrInvalidBundle) {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			http.Error(w, "Import failed: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"status":  "ok",
+			"message": "Import erfolgreich",
+			"result":  result,
+		})
+		return
+
 	default:
-		http.Error(w, "Unsupported file type. Use .sql", http.StatusBadRequest)
+		http.Error(w, "Unsupported file type. Use .sql or .zip", http.StatusBadRequest)
 		return
 	}
 
@@ -504,6 +885,32 @@ func (h *Handlers) handleSimulate(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(result)
 }
 
+// handleSimulatePortfolio führt eine Portfolio-Simulation durch (mehrere Positionen, Sparplan, Rebalancing)
+func (h *Handlers) handleSimulatePortfolio(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req PortfolioRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.Period == "" && req.StartDate == nil {
+		req.Period = Period1Year // Default
+	}
+
+	result, err := h.service.SimulatePortfolio(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
 // handleSimulatableIndicators gibt alle simulierbaren Indikatoren zurück
 func (h *Handlers) handleSimulatableIndicators(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
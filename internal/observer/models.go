@@ -91,6 +91,9 @@ type DataSource struct {
 	SourceClass SourceClass `json:"sourceClass"` // Zulassungsklasse
 	Active      bool        `json:"active"`      // Aktiv für Sammlung
 	CreatedAt   time.Time   `json:"createdAt"`
+
+	// Definition beschreibt eine konfigurierte Quelle (nil = fest eingebauter Collector)
+	Definition *SourceDefinition `json:"definition,omitempty"`
 }
 
 // Indicator repräsentiert eine Kennzahl/Zeitreihe
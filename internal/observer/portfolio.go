@@ -0,0 +1,599 @@
+package observer
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// RebalanceInterval legt fest, wie oft das Portfolio auf die Zielgewichte zurückgesetzt wird
+type RebalanceInterval string
+
+const (
+	RebalanceNone      RebalanceInterval = "none"
+	RebalanceMonthly   RebalanceInterval = "monthly"
+	RebalanceQuarterly RebalanceInterval = "quarterly"
+	RebalanceYearly    RebalanceInterval = "yearly"
+)
+
+// Steuer-Parameter (Deutschland, Privatvermögen)
+const (
+	// AbgeltungssteuerRate ist Abgeltungssteuer 25% plus Solidaritätszuschlag 5,5% (ohne Kirchensteuer)
+	AbgeltungssteuerRate = 0.26375
+	// DefaultTaxAllowance ist der Sparerpauschbetrag für Alleinstehende (seit 2023)
+	DefaultTaxAllowance = 1000.0
+	// DefaultInflationCode ist die Inflationsreihe für die Realrendite
+	DefaultInflationCode = "HICP_DE"
+)
+
+const maxPortfolioAssets = 10
+
+// PortfolioAsset ist eine Position mit Zielgewicht
+type PortfolioAsset struct {
+	IndicatorCode string  `json:"indicatorCode"`
+	Weight        float64 `json:"weight"` // Zielgewicht (wird auf Summe 1 normiert, z.B. 70/30 oder 0.7/0.3)
+}
+
+// PortfolioRequest ist die Anfrage für eine Portfolio-Simulation
+type PortfolioRequest struct {
+	Assets              []PortfolioAsset  `json:"assets"`
+	InitialAmount       float64           `json:"initialAmount"`       // Einmalanlage in EUR
+	MonthlyContribution float64           `json:"monthlyContribution"` // Sparplan: Rate je Monat in EUR
+	Period              SimulationPeriod  `json:"period"`              // oder StartDate/EndDate
+	StartDate           *time.Time        `json:"startDate"`
+	EndDate             *time.Time        `json:"endDate"`
+	Rebalance           RebalanceInterval `json:"rebalance"`     // none, monthly, quarterly, yearly
+	CostPct             float64           `json:"costPct"`       // Transaktionskosten in % je Kauf/Verkauf
+	AnnualFeePct        float64           `json:"annualFeePct"`  // Laufende Kosten (TER/Depot) in % p.a.
+	ApplyTax            bool              `json:"applyTax"`      // Abgeltungssteuer auf realisierte Gewinne
+	TaxAllowance        *float64          `json:"taxAllowance"`  // Sparerpauschbetrag je Jahr (nil = 1000 €)
+	InflationCode       string            `json:"inflationCode"` // Inflationsreihe (leer = HICP_DE)
+}
+
+// PortfolioAssetResult ist das Ergebnis einer Position
+type PortfolioAssetResult struct {
+	IndicatorCode string  `json:"indicatorCode"`
+	IndicatorName string  `json:"indicatorName"`
+	Weight        float64 `json:"weight"`     // Normiertes Zielgewicht
+	StartValue    float64 `json:"startValue"` // Kurs am Start
+	EndValue      float64 `json:"endValue"`   // Kurs am Ende
+	ReturnPct     float64 `json:"returnPct"`  // Kursentwicklung in Prozent
+	EndAmount     float64 `json:"endAmount"`  // Wert der Position am Ende in EUR
+	EndWeight     float64 `json:"endWeight"`  // Tatsächliches Gewicht am Ende
+	Unit          string  `json:"unit"`
+	DataPoints    int     `json:"dataPoints"`
+}
+
+// PortfolioPoint ist ein Punkt der Wertentwicklung (Monatsende)
+type PortfolioPoint struct {
+	Date     time.Time `json:"date"`
+	Value    float64   `json:"value"`
+	Invested float64   `json:"invested"`
+}
+
+// PortfolioResult ist das Ergebnis einer Portfolio-Simulation
+type PortfolioResult struct {
+	Assets    []PortfolioAssetResult `json:"assets"`
+	StartDate time.Time              `json:"startDate"`
+	EndDate   time.Time              `json:"endDate"`
+	Rebalance RebalanceInterval      `json:"rebalance"`
+
+	// Nominal
+	Invested          float64 `json:"invested"`          // Summe Einmalanlage + Sparraten
+	EndAmount         float64 `json:"endAmount"`         // Depotwert am Ende
+	EndAmountAfterTax float64 `json:"endAmountAfterTax"` // Depotwert nach Verkauf (Kosten + Steuer)
+	ReturnAbs         float64 `json:"returnAbs"`         // EndAmount - Invested
+	ReturnPct         float64 `json:"returnPct"`         // Gewinn bezogen auf das eingezahlte Kapital
+
+	// Kennzahlen (zeitgewichtet, unabhängig von Zahlungsströmen)
+	CAGR           float64   `json:"cagr"`           // Annualisierte Rendite in %
+	VolatilityPct  float64   `json:"volatilityPct"`  // Annualisierte Volatilität in %
+	MaxDrawdownPct float64   `json:"maxDrawdownPct"` // Größter Verlust vom Hoch in % (negativ)
+	MaxDrawdownAt  time.Time `json:"maxDrawdownAt"`
+
+	// Kosten und Steuern
+	Costs         float64 `json:"costs"`         // Transaktionskosten + laufende Kosten
+	Taxes         float64 `json:"taxes"`         // Abgeführte Abgeltungssteuer (inkl. Verkauf am Ende)
+	Rebalancings  int     `json:"rebalancings"`  // Anzahl Rebalancing-Termine
+	Contributions int     `json:"contributions"` // Anzahl ausgeführter Sparraten
+
+	// Real (inflationsbereinigt, Kaufkraft zum Startdatum) - nil wenn keine Inflationsdaten
+	InflationCode string   `json:"inflationCode"`
+	InflationPct  *float64 `json:"inflationPct,omitempty"`  // Kumulierte Inflation im Zeitraum
+	RealEndAmount *float64 `json:"realEndAmount,omitempty"` // Depotwert in Kaufkraft des Startdatums
+	RealInvested  *float64 `json:"realInvested,omitempty"`  // Einzahlungen in Kaufkraft des Startdatums
+	RealCAGR      *float64 `json:"realCagr,omitempty"`
+
+	Series   []PortfolioPoint `json:"series"`
+	Warnings []string         `json:"warnings,omitempty"`
+
+	// Disclaimer (IMMER dabei!)
+	Disclaimer string `json:"disclaimer"`
+}
+
+// Validate prüft die Anfrage und normiert Codes, Gewichte und Defaults
+func (req *PortfolioRequest) Validate() error {
+	if len(req.Assets) == 0 {
+		return fmt.Errorf("Mindestens eine Position erforderlich")
+	}
+	if len(req.Assets) > maxPortfolioAssets {
+		return fmt.Errorf("Höchstens %d Positionen erlaubt", maxPortfolioAssets)
+	}
+
+	seen := make(map[string]bool)
+	var total float64
+	for i := range req.Assets {
+		asset := &req.Assets[i]
+		asset.IndicatorCode = strings.ToUpper(strings.TrimSpace(asset.IndicatorCode))
+		if asset.IndicatorCode == "" {
+			return fmt.Errorf("Position %d: indicatorCode fehlt", i+1)
+		}
+		if seen[asset.IndicatorCode] {
+			return fmt.Errorf("Position %s doppelt", asset.IndicatorCode)
+		}
+		seen[asset.IndicatorCode] = true
+		if asset.Weight <= 0 {
+			return fmt.Errorf("Position %s: Gewicht muss positiv sein", asset.IndicatorCode)
+		}
+		total += asset.Weight
+	}
+	for i := range req.Assets {
+		req.Assets[i].Weight /= total
+	}
+
+	if req.InitialAmount < 0 || req.MonthlyContribution < 0 {
+		return fmt.Errorf("Beträge dürfen nicht negativ sein")
+	}
+	if req.InitialAmount == 0 && req.MonthlyContribution == 0 {
+		return fmt.Errorf("initialAmount oder monthlyContribution erforderlich")
+	}
+	if req.CostPct < 0 || req.CostPct > 10 {
+		return fmt.Errorf("costPct muss zwischen 0 und 10 liegen")
+	}
+	if req.AnnualFeePct < 0 || req.AnnualFeePct > 5 {
+		return fmt.Errorf("annualFeePct muss zwischen 0 und 5 liegen")
+	}
+	if req.TaxAllowance != nil && *req.TaxAllowance < 0 {
+		return fmt.Errorf("taxAllowance darf nicht negativ sein")
+	}
+
+	req.Rebalance = RebalanceInterval(strings.ToLower(strings.TrimSpace(string(req.Rebalance))))
+	switch req.Rebalance {
+	case "":
+		req.Rebalance = RebalanceNone
+	case RebalanceNone, RebalanceMonthly, RebalanceQuarterly, RebalanceYearly:
+	default:
+		return fmt.Errorf("Unbekanntes Rebalancing-Intervall: %q", req.Rebalance)
+	}
+
+	req.InflationCode = strings.ToUpper(strings.TrimSpace(req.InflationCode))
+	if req.InflationCode == "" {
+		req.InflationCode = DefaultInflationCode
+	}
+	return nil
+}
+
+// SimulatePortfolio simuliert ein gewichtetes Portfolio mit Sparplan, Rebalancing, Kosten und Steuern
+func (s *Service) SimulatePortfolio(req PortfolioRequest) (*PortfolioResult, error) {
+	req.Assets = append([]PortfolioAsset(nil), req.Assets...) // Gewichte werden normiert
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	// Zeitraum bestimmen (wie Simulate)
+	endDate := time.Now()
+	var startDate time.Time
+	if req.StartDate != nil && req.EndDate != nil {
+		startDate = *req.StartDate
+		endDate = *req.EndDate
+	} else {
+		startDate = periodToStartDate(req.Period, endDate)
+	}
+	if !startDate.Before(endDate) {
+		return nil, fmt.Errorf("Startdatum muss vor dem Enddatum liegen")
+	}
+
+	// Kurse aller Positionen laden
+	indicators := make([]*Indicator, len(req.Assets))
+	series := make([][]ObservationValue, len(req.Assets))
+	for i, asset := range req.Assets {
+		indicator, err := s.repo.GetIndicatorByCode(asset.IndicatorCode)
+		if err != nil || indicator == nil {
+			return nil, fmt.Errorf("Indikator nicht gefunden: %s", asset.IndicatorCode)
+		}
+		values, err := s.repo.GetValuesByIndicatorDateRange(indicator.ID, startDate, endDate)
+		if err != nil {
+			return nil, fmt.Errorf("Fehler beim Laden der historischen Daten: %w", err)
+		}
+		if len(values) < 2 {
+			return nil, fmt.Errorf("Nicht genügend historische Daten für %s im Zeitraum %s bis %s",
+				asset.IndicatorCode, startDate.Format("02.01.2006"), endDate.Format("02.01.2006"))
+		}
+		indicators[i] = indicator
+		series[i] = values
+	}
+
+	dates, prices := buildPriceGrid(series)
+	if len(dates) < 2 {
+		return nil, fmt.Errorf("Zu wenige gemeinsame Kursdaten aller Positionen im Zeitraum")
+	}
+
+	result := runPortfolio(req, dates, prices)
+	for i := range result.Assets {
+		result.Assets[i].IndicatorName = indicators[i].Name
+		result.Assets[i].Unit = indicators[i].Unit
+		result.Assets[i].DataPoints = len(series[i])
+	}
+
+	// Inflationsbereinigung mit der Inflationsreihe des Observers
+	inflation, err := s.loadInflationRates(req.InflationCode, dates[0], dates[len(dates)-1])
+	if err != nil || len(inflation) == 0 {
+		result.Warnings = append(result.Warnings, fmt.Sprintf("Keine Inflationsdaten (%s) - keine Realrendite berechnet", req.InflationCode))
+	} else {
+		applyInflation(result, inflation, dates)
+	}
+
+	return result.PortfolioResult, nil
+}
+
+// buildPriceGrid legt alle Kursreihen auf gemeinsame Stichtage (Kurse ohne Notierung werden fortgeschrieben).
+// Die Reihe beginnt am ersten Tag, an dem alle Positionen einen Kurs haben.
+func buildPriceGrid(series [][]ObservationValue) ([]time.Time, [][]float64) {
+	dateSet := make(map[time.Time]bool)
+	for _, values := range series {
+		sort.Slice(values, func(i, j int) bool { return values[i].ObservedAt.Before(values[j].ObservedAt) })
+		for _, v := range values {
+			dateSet[truncateDay(v.ObservedAt)] = true
+		}
+	}
+	allDates := make([]time.Time, 0, len(dateSet))
+	for d := range dateSet {
+		allDates = append(allDates, d)
+	}
+	sort.Slice(allDates, func(i, j int) bool { return allDates[i].Before(allDates[j]) })
+
+	var dates []time.Time
+	var prices [][]float64
+	pos := make([]int, len(series))
+	last := make([]float64, len(series))
+	for _, d := range allDates {
+		complete := true
+		for i, values := range series {
+			for pos[i] < len(values) && !truncateDay(values[pos[i]].ObservedAt).After(d) {
+				if values[pos[i]].Value > 0 {
+					last[i] = values[pos[i]].Value
+				}
+				pos[i]++
+			}
+			if last[i] == 0 {
+				complete = false
+			}
+		}
+		if !complete {
+			continue
+		}
+		dates = append(dates, d)
+		prices = append(prices, append([]float64(nil), last...))
+	}
+	return dates, prices
+}
+
+// truncateDay schneidet die Uhrzeit ab (Stichtage verschiedener Quellen zusammenführen)
+func truncateDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// portfolioFlow ist eine Einzahlung (für die reale Bewertung)
+type portfolioFlow struct {
+	Date   time.Time
+	Amount float64
+}
+
+// portfolioRun ist das Zwischenergebnis der Simulation vor der Inflationsbereinigung
+type portfolioRun struct {
+	*PortfolioResult
+	flows []portfolioFlow
+}
+
+// portfolioState ist der Depotzustand während der Simulation
+type portfolioState struct {
+	req           *PortfolioRequest
+	units         []float64
+	costBasis     []float64 // Anschaffungskosten je Position (inkl. Kaufkosten)
+	costs         float64
+	taxes         float64
+	taxYear       int
+	allowanceLeft float64
+	lossPot       float64 // Verlustverrechnungstopf
+}
+
+// value berechnet den Depotwert
+func (p *portfolioState) value(prices []float64) float64 {
+	var total float64
+	for i, u := range p.units {
+		total += u * prices[i]
+	}
+	return total
+}
+
+// buy kauft für amount EUR (inkl. Kaufkosten)
+func (p *portfolioState) buy(i int, amount, price float64) {
+	if amount <= 0 {
+		return
+	}
+	cost := amount * p.req.CostPct / 100
+	p.costs += cost
+	p.units[i] += (amount - cost) / price
+	p.costBasis[i] += amount
+}
+
+// sell verkauft Anteile im Wert von amount EUR und gibt den Erlös nach Kosten und Steuern zurück
+func (p *portfolioState) sell(i int, amount, price float64, date time.Time) float64 {
+	if amount <= 0 || p.units[i] <= 0 {
+		return 0
+	}
+	units := math.Min(amount/price, p.units[i])
+	proceeds := units * price
+	basis := p.costBasis[i] * units / p.units[i]
+	cost := proceeds * p.req.CostPct / 100
+	tax := p.tax(proceeds-cost-basis, date)
+
+	p.costs += cost
+	p.units[i] -= units
+	p.costBasis[i] -= basis
+	return proceeds - cost - tax
+}
+
+// tax berechnet die Abgeltungssteuer auf einen realisierten Gewinn (Verlusttopf und Sparerpauschbetrag je Kalenderjahr)
+func (p *portfolioState) tax(gain float64, date time.Time) float64 {
+	if !p.req.ApplyTax {
+		return 0
+	}
+	if date.Year() != p.taxYear {
+		p.taxYear = date.Year()
+		p.allowanceLeft = DefaultTaxAllowance
+		if p.req.TaxAllowance != nil {
+			p.allowanceLeft = *p.req.TaxAllowance
+		}
+	}
+	if gain <= 0 {
+		p.lossPot -= gain
+		return 0
+	}
+
+	offset := math.Min(gain, p.lossPot)
+	gain -= offset
+	p.lossPot -= offset
+
+	free := math.Min(gain, p.allowanceLeft)
+	gain -= free
+	p.allowanceLeft -= free
+
+	tax := gain * AbgeltungssteuerRate
+	p.taxes += tax
+	return tax
+}
+
+// invest verteilt einen Betrag nach Zielgewichten
+func (p *portfolioState) invest(amount float64, prices []float64) {
+	for i, asset := range p.req.Assets {
+		p.buy(i, amount*asset.Weight, prices[i])
+	}
+}
+
+// rebalance setzt die Positionen auf die Zielgewichte zurück (Verkäufe finanzieren die Käufe)
+func (p *portfolioState) rebalance(prices []float64, date time.Time) {
+	total := p.value(prices)
+	if total <= 0 {
+		return
+	}
+
+	var cash, deficitSum float64
+	deficits := make([]float64, len(p.units))
+	for i, asset := range p.req.Assets {
+		diff := p.units[i]*prices[i] - total*asset.Weight
+		if diff > 0 {
+			cash += p.sell(i, diff, prices[i], date)
+		} else {
+			deficits[i] = -diff
+			deficitSum -= diff
+		}
+	}
+	if deficitSum == 0 {
+		return
+	}
+	for i := range deficits {
+		p.buy(i, cash*deficits[i]/deficitSum, prices[i])
+	}
+}
+
+// chargeFee belastet die laufenden Kosten eines Monats (Anteile werden reduziert)
+func (p *portfolioState) chargeFee(prices []float64) {
+	if p.req.AnnualFeePct == 0 {
+		return
+	}
+	rate := p.req.AnnualFeePct / 100 / 12
+	for i := range p.units {
+		p.costs += p.units[i] * prices[i] * rate
+		p.units[i] *= 1 - rate
+		p.costBasis[i] *= 1 - rate
+	}
+}
+
+// rebalanceDue prüft ob zwischen prev und date ein Rebalancing-Termin liegt
+func rebalanceDue(interval RebalanceInterval, prev, date time.Time) bool {
+	switch interval {
+	case RebalanceMonthly:
+		return prev.Month() != date.Month() || prev.Year() != date.Year()
+	case RebalanceQuarterly:
+		return (prev.Month()-1)/3 != (date.Month()-1)/3 || prev.Year() != date.Year()
+	case RebalanceYearly:
+		return prev.Year() != date.Year()
+	default:
+		return false
+	}
+}
+
+// runPortfolio simuliert das Portfolio auf dem Kursraster (dates aufsteigend, prices[Tag][Position])
+func runPortfolio(req PortfolioRequest, dates []time.Time, prices [][]float64) *portfolioRun {
+	n := len(req.Assets)
+	state := &portfolioState{req: &req, units: make([]float64, n), costBasis: make([]float64, n)}
+	run := &portfolioRun{PortfolioResult: &PortfolioResult{
+		StartDate:     dates[0],
+		EndDate:       dates[len(dates)-1],
+		Rebalance:     req.Rebalance,
+		InflationCode: req.InflationCode,
+		Series:        make([]PortfolioPoint, 0),
+		Disclaimer:    DisclaimerText,
+	}}
+	result := run.PortfolioResult
+
+	var returns []float64
+	twr, peak := 1.0, 1.0
+	var prevValue float64
+
+	for d, date := range dates {
+		px := prices[d]
+		var flow float64
+
+		if d == 0 {
+			flow = req.InitialAmount + req.MonthlyContribution
+			if req.MonthlyContribution > 0 {
+				result.Contributions++
+			}
+			state.invest(flow, px)
+		} else if prev := dates[d-1]; prev.Month() != date.Month() || prev.Year() != date.Year() {
+			// Monatswechsel: laufende Kosten, Sparrate, ggf. Rebalancing
+			state.chargeFee(px)
+			if req.MonthlyContribution > 0 {
+				flow = req.MonthlyContribution
+				state.invest(flow, px)
+				result.Contributions++
+			}
+			if rebalanceDue(req.Rebalance, prev, date) {
+				state.rebalance(px, date)
+				result.Rebalancings++
+			}
+		}
+		if flow > 0 {
+			result.Invested += flow
+			run.flows = append(run.flows, portfolioFlow{Date: date, Amount: flow})
+		}
+
+		value := state.value(px)
+		if d > 0 && prevValue > 0 {
+			r := (value-flow)/prevValue - 1
+			returns = append(returns, r)
+			twr *= 1 + r
+		}
+		prevValue = value
+
+		if twr > peak {
+			peak = twr
+		}
+		if dd := (twr/peak - 1) * 100; dd < result.MaxDrawdownPct {
+			result.MaxDrawdownPct = dd
+			result.MaxDrawdownAt = date
+		}
+
+		// Monatsende bzw. letzter Tag als Punkt der Wertentwicklung
+		if d == len(dates)-1 || dates[d+1].Month() != date.Month() {
+			result.Series = append(result.Series, PortfolioPoint{Date: date, Value: value, Invested: result.Invested})
+		}
+	}
+
+	last := prices[len(prices)-1]
+	result.EndAmount = state.value(last)
+	result.ReturnAbs = result.EndAmount - result.Invested
+	if result.Invested > 0 {
+		result.ReturnPct = result.ReturnAbs / result.Invested * 100
+	}
+
+	years := result.EndDate.Sub(result.StartDate).Hours() / 24 / 365.25
+	if years > 0 {
+		result.CAGR = (math.Pow(twr, 1/years) - 1) * 100
+		if len(returns) > 1 {
+			_, std := meanStd(returns)
+			result.VolatilityPct = std * math.Sqrt(float64(len(returns))/years) * 100
+		}
+	}
+
+	// Positionen am Ende
+	result.Assets = make([]PortfolioAssetResult, n)
+	for i, asset := range req.Assets {
+		amount := state.units[i] * last[i]
+		ar := PortfolioAssetResult{
+			IndicatorCode: asset.IndicatorCode,
+			Weight:        asset.Weight,
+			StartValue:    prices[0][i],
+			EndValue:      last[i],
+			ReturnPct:     (last[i]/prices[0][i] - 1) * 100,
+			EndAmount:     amount,
+		}
+		if result.EndAmount > 0 {
+			ar.EndWeight = amount / result.EndAmount
+		}
+		result.Assets[i] = ar
+	}
+
+	// Wert nach vollständigem Verkauf am Ende (Verkaufskosten + Steuer)
+	for i := range req.Assets {
+		result.EndAmountAfterTax += state.sell(i, state.units[i]*last[i], last[i], result.EndDate)
+	}
+	result.Costs = state.costs
+	result.Taxes = state.taxes
+
+	return run
+}
+
+// loadInflationRates lädt die Jahresraten der Inflationsreihe (inkl. Vorlauf für den ersten Monat)
+func (s *Service) loadInflationRates(code string, from, to time.Time) ([]ObservationValue, error) {
+	indicator, err := s.repo.GetIndicatorByCode(code)
+	if err != nil || indicator == nil {
+		return nil, fmt.Errorf("Inflationsreihe nicht gefunden: %s", code)
+	}
+	return s.repo.GetValuesByIndicatorDateRange(indicator.ID, from.AddDate(0, -3, 0), to)
+}
+
+// applyInflation rechnet Endwert, Einzahlungen und CAGR in Kaufkraft des Startdatums um.
+// rates sind Jahresraten in % (z.B. HVPI ggü. Vorjahresmonat, aufsteigend); fehlende Monate
+// (Veröffentlichungsverzug) werden mit der letzten bekannten Rate fortgeschrieben.
+func applyInflation(run *portfolioRun, rates []ObservationValue, dates []time.Time) {
+	start := dates[0]
+	monthIndex := func(t time.Time) int { return t.Year()*12 + int(t.Month()) - 1 }
+
+	// Kumulierter Preisindex je Monat (Startmonat = 1)
+	startMonth, endMonth := monthIndex(start), monthIndex(dates[len(dates)-1])
+	index := make(map[int]float64, endMonth-startMonth+1)
+	index[startMonth] = 1
+	pos := 0
+	rate := rates[0].Value
+	for m := startMonth + 1; m <= endMonth; m++ {
+		for pos < len(rates) && monthIndex(rates[pos].ObservedAt) < m {
+			rate = rates[pos].Value
+			pos++
+		}
+		index[m] = index[m-1] * math.Pow(1+rate/100, 1.0/12)
+	}
+
+	result := run.PortfolioResult
+	cumulative := index[endMonth]
+	inflationPct := (cumulative - 1) * 100
+	realEnd := result.EndAmount / cumulative
+	var realInvested float64
+	for _, f := range run.flows {
+		realInvested += f.Amount / index[monthIndex(f.Date)]
+	}
+	result.InflationPct = &inflationPct
+	result.RealEndAmount = &realEnd
+	result.RealInvested = &realInvested
+
+	years := result.EndDate.Sub(result.StartDate).Hours() / 24 / 365.25
+	if years > 0 {
+		annualInflation := math.Pow(cumulative, 1/years) - 1
+		realCAGR := ((1+result.CAGR/100)/(1+annualInflation) - 1) * 100
+		result.RealCAGR = &realCAGR
+	}
+}
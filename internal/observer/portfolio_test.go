@@ -0,0 +1,131 @@
+package observer
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// monthlyGrid erzeugt ein Kursraster mit einem Stichtag je Monatsanfang
+func monthlyGrid(prices ...[]float64) ([]time.Time, [][]float64) {
+	dates := make([]time.Time, len(prices))
+	for i := range prices {
+		dates[i] = time.Date(2023, time.Month(i+1), 2, 0, 0, 0, 0, time.UTC)
+	}
+	return dates, prices
+}
+
+func approx(a, b float64) bool { return math.Abs(a-b) < 0.01 }
+
+// TestRunPortfolio prüft Einmalanlage, Sparplan, Drawdown, Rebalancing und Steuer
+func TestRunPortfolio(t *testing.T) {
+	twoAssets := []PortfolioAsset{{IndicatorCode: "A", Weight: 0.5}, {IndicatorCode: "B", Weight: 0.5}}
+
+	// Einmalanlage 50/50: A verdoppelt sich über einen Einbruch, B bleibt konstant
+	dates, prices := monthlyGrid([]float64{100, 10}, []float64{50, 10}, []float64{100, 10}, []float64{200, 10})
+	result := runPortfolio(PortfolioRequest{Assets: twoAssets, InitialAmount: 10000, Rebalance: RebalanceNone}, dates, prices).PortfolioResult
+	if !approx(result.EndAmount, 15000) || !approx(result.ReturnPct, 50) || result.Invested != 10000 {
+		t.Errorf("Einmalanlage: %+v", result)
+	}
+	if !approx(result.MaxDrawdownPct, -25) || !result.MaxDrawdownAt.Equal(dates[1]) {
+		t.Errorf("Drawdown %.2f am %s", result.MaxDrawdownPct, result.MaxDrawdownAt)
+	}
+	if !approx(result.Assets[0].EndAmount, 10000) || !approx(result.Assets[0].EndWeight, 2.0/3) || result.Assets[0].ReturnPct != 100 {
+		t.Errorf("Position A: %+v", result.Assets[0])
+	}
+	if result.VolatilityPct <= 0 || result.CAGR <= 50 {
+		t.Errorf("Kennzahlen: CAGR %.2f, Volatilität %.2f", result.CAGR, result.VolatilityPct)
+	}
+
+	// Monatliches Rebalancing kauft A im Tief nach und schlägt Buy-and-Hold
+	rebalanced := runPortfolio(PortfolioRequest{Assets: twoAssets, InitialAmount: 10000, Rebalance: RebalanceMonthly}, dates, prices).PortfolioResult
+	if rebalanced.Rebalancings != 3 || rebalanced.EndAmount <= result.EndAmount {
+		t.Errorf("Rebalancing: %d Termine, %.2f", rebalanced.Rebalancings, rebalanced.EndAmount)
+	}
+
+	// Sparplan bei konstanten Kursen: Einzahlungen erhöhen den Wert, aber nicht die Rendite
+	dates, prices = monthlyGrid([]float64{10}, []float64{10}, []float64{10})
+	saving := runPortfolio(PortfolioRequest{Assets: []PortfolioAsset{{IndicatorCode: "A", Weight: 1}}, MonthlyContribution: 100}, dates, prices).PortfolioResult
+	if saving.Contributions != 3 || saving.Invested != 300 || !approx(saving.EndAmount, 300) || !approx(saving.CAGR, 0) || saving.MaxDrawdownPct != 0 {
+		t.Errorf("Sparplan: %+v", saving)
+	}
+
+	// Kosten: 1% beim Kauf, 1% beim Verkauf am Ende
+	costly := runPortfolio(PortfolioRequest{Assets: []PortfolioAsset{{IndicatorCode: "A", Weight: 1}}, MonthlyContribution: 100, CostPct: 1}, dates, prices).PortfolioResult
+	if !approx(costly.EndAmount, 297) || !approx(costly.EndAmountAfterTax, 294.03) || !approx(costly.Costs, 5.97) {
+		t.Errorf("Kosten: %+v", costly)
+	}
+
+	// Abgeltungssteuer beim Verkauf am Ende: 10.000 € Gewinn abzüglich 1.000 € Sparerpauschbetrag
+	dates, prices = monthlyGrid([]float64{100}, []float64{200})
+	taxed := runPortfolio(PortfolioRequest{Assets: []PortfolioAsset{{IndicatorCode: "A", Weight: 1}}, InitialAmount: 10000, ApplyTax: true}, dates, prices).PortfolioResult
+	if !approx(taxed.Taxes, 9000*AbgeltungssteuerRate) || !approx(taxed.EndAmountAfterTax, 20000-9000*AbgeltungssteuerRate) {
+		t.Errorf("Steuer: %.2f, nach Steuer %.2f", taxed.Taxes, taxed.EndAmountAfterTax)
+	}
+}
+
+// TestSimulatePortfolioAPI prüft die Portfolio-Simulation mit gespeicherten Kursen und Inflationsreihe
+func TestSimulatePortfolioAPI(t *testing.T) {
+	service, err := NewService(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer service.Close()
+
+	start := time.Date(2023, 1, 2, 0, 0, 0, 0, time.UTC)
+	store := func(code string, value func(month int) float64) {
+		ind, _ := service.repo.GetIndicatorByCode(code)
+		var values []ObservationValue
+		for m := 0; m <= 12; m++ {
+			day := start.AddDate(0, m, 0)
+			values = append(values, ObservationValue{RunID: 1, IndicatorID: ind.ID, SourceID: ind.SourceID, ObservedAt: day, CollectedAt: day, Value: value(m)})
+		}
+		service.repo.StoreValues(values)
+	}
+	store("GOLD_EUR", func(m int) float64 { return 1800 + float64(m)*30 })
+	store("DAX", func(m int) float64 { return 15000 })
+	store("HICP_DE", func(m int) float64 { return 12 })
+
+	body, _ := json.Marshal(map[string]interface{}{
+		"assets":              []map[string]interface{}{{"indicatorCode": "gold_eur", "weight": 60}, {"indicatorCode": "DAX", "weight": 40}},
+		"initialAmount":       10000,
+		"monthlyContribution": 100,
+		"rebalance":           "yearly",
+		"startDate":           start,
+		"endDate":             start.AddDate(1, 0, 0),
+	})
+	mux := http.NewServeMux()
+	NewHandlers(service).RegisterRoutes(mux)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/observer/simulate/portfolio", bytes.NewReader(body)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Simulation: %d %s", rec.Code, rec.Body.String())
+	}
+
+	var result PortfolioResult
+	json.NewDecoder(rec.Body).Decode(&result)
+	if result.Disclaimer != DisclaimerText || len(result.Assets) != 2 || result.Assets[0].Weight != 0.6 || result.Assets[0].IndicatorName == "" {
+		t.Errorf("Ergebnis: %+v", result)
+	}
+	if result.Invested != 11300 || result.Rebalancings != 1 || len(result.Series) != 13 {
+		t.Errorf("Einzahlungen %.0f, Rebalancings %d, Punkte %d", result.Invested, result.Rebalancings, len(result.Series))
+	}
+	// 12% Jahresinflation: Kaufkraft des Endwerts sinkt um den Faktor 1.12
+	if result.InflationPct == nil || !approx(*result.InflationPct, 12) || !approx(*result.RealEndAmount, result.EndAmount/1.12) {
+		t.Fatalf("Inflation: %v / %v", result.InflationPct, result.RealEndAmount)
+	}
+	if *result.RealCAGR >= result.CAGR || *result.RealInvested >= result.Invested {
+		t.Errorf("Real: CAGR %.2f / %.2f, Einzahlungen %.2f", *result.RealCAGR, result.CAGR, *result.RealInvested)
+	}
+
+	invalid, _ := json.Marshal(map[string]interface{}{"assets": []map[string]interface{}{{"indicatorCode": "DAX", "weight": 1}}, "rebalance": "daily", "initialAmount": 100})
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/observer/simulate/portfolio", bytes.NewReader(invalid)))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Ungültiges Intervall: %d", rec.Code)
+	}
+}
@@ -0,0 +1,341 @@
+package observer
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// Qualitäts-Flags
+const (
+	FlagStale   = "stale"   // Letzter Wert älter als für die Frequenz üblich
+	FlagOutlier = "outlier" // Veränderung weicht stark vom rollierenden Mittel ab (z-Score)
+	FlagGap     = "gap"     // Fehlende Perioden zwischen zwei Beobachtungen
+)
+
+// Parameter der Ausreißer-Erkennung
+const (
+	outlierWindow    = 20  // Anzahl vorheriger Veränderungen für Mittelwert und Standardabweichung
+	outlierThreshold = 4.0 // |z| ab dem eine Veränderung als Ausreißer gilt
+	qualityMaxValues = 2000
+)
+
+// ValueRevision ist ein überschriebener Wert, den eine Quelle neu veröffentlicht hat
+type ValueRevision struct {
+	ID                  int64     `json:"id"`
+	ValueID             int64     `json:"valueId"`
+	IndicatorID         int64     `json:"indicatorId"`
+	ObservedAt          time.Time `json:"observedAt"`
+	PreviousValue       float64   `json:"previousValue"`
+	PreviousValueString string    `json:"previousValueString,omitempty"`
+	PreviousRunID       int64     `json:"previousRunId"`
+	PreviousCollectedAt time.Time `json:"previousCollectedAt"`
+	Value               float64   `json:"value"`
+	RunID               int64     `json:"runId"`
+	RevisedAt           time.Time `json:"revisedAt"`
+}
+
+// QualityFlag markiert einen auffälligen Wert oder Zustand
+type QualityFlag struct {
+	Type       string    `json:"type"`
+	ObservedAt time.Time `json:"observedAt"`
+	Value      float64   `json:"value,omitempty"`
+	ZScore     float64   `json:"zScore,omitempty"`
+	Detail     string    `json:"detail"`
+}
+
+// QualityGap ist eine Lücke in der Zeitreihe
+type QualityGap struct {
+	From    time.Time `json:"from"` // Letzte Beobachtung vor der Lücke
+	To      time.Time `json:"to"`   // Erste Beobachtung nach der Lücke
+	Missing int       `json:"missing"`
+}
+
+// QualityReport ist der Qualitätsbericht eines Indikators
+type QualityReport struct {
+	IndicatorCode  string          `json:"indicatorCode"`
+	Name           string          `json:"name"`
+	Frequency      string          `json:"frequency"`
+	ValueCount     int             `json:"valueCount"`
+	LatestObserved *time.Time      `json:"latestObserved,omitempty"`
+	LastCollected  *time.Time      `json:"lastCollected,omitempty"`
+	AgeDays        int             `json:"ageDays"`
+	Stale          bool            `json:"stale"`
+	Flags          []QualityFlag   `json:"flags"`
+	Gaps           []QualityGap    `json:"gaps"`
+	Revisions      []ValueRevision `json:"revisions"`
+	RevisionCount  int             `json:"revisionCount"`
+}
+
+// QualitySummary ist die Kurzfassung für die Übersicht aller Indikatoren
+type QualitySummary struct {
+	IndicatorCode  string     `json:"indicatorCode"`
+	Name           string     `json:"name"`
+	LatestObserved *time.Time `json:"latestObserved,omitempty"`
+	AgeDays        int        `json:"ageDays"`
+	Stale          bool       `json:"stale"`
+	Outliers       int        `json:"outliers"`
+	Gaps           int        `json:"gaps"`
+	Revisions      int        `json:"revisions"`
+}
+
+// maxAge gibt zurück, wie alt der letzte Stichtag je Frequenz höchstens sein darf.
+// Faustregeln inkl. Veröffentlichungsverzug: Monatsdaten erscheinen ca. 4-6 Wochen nach Monatsbeginn,
+// Quartalsdaten ca. 4 Monate nach Quartalsbeginn.
+func maxAge(frequency string) time.Duration {
+	day := 24 * time.Hour
+	switch frequency {
+	case "W":
+		return 14 * day
+	case "M":
+		return 95 * day
+	case "Q":
+		return 220 * day
+	case "A":
+		return 550 * day
+	default:
+		return 5 * day // Wochenende plus Feiertag
+	}
+}
+
+// IsStale prüft ob der letzte Stichtag eines Indikators veraltet ist
+func IsStale(frequency string, observedAt, now time.Time) bool {
+	return now.Sub(observedAt) > maxAge(frequency)
+}
+
+// expectedInterval ist der übliche Abstand zweier Stichtage je Frequenz
+func expectedInterval(frequency string) time.Duration {
+	day := 24 * time.Hour
+	switch frequency {
+	case "W":
+		return 7 * day
+	case "M":
+		return 31 * day
+	case "Q":
+		return 92 * day
+	case "A":
+		return 366 * day
+	default:
+		return day
+	}
+}
+
+// detectGaps findet Lücken zwischen aufeinanderfolgenden Beobachtungen (values aufsteigend sortiert)
+func detectGaps(values []ObservationValue, frequency string) []QualityGap {
+	gaps := make([]QualityGap, 0)
+	for i := 1; i < len(values); i++ {
+		from, to := values[i-1].ObservedAt, values[i].ObservedAt
+
+		missing := 0
+		if frequency == "D" || frequency == "" {
+			// Börsen- und Bankarbeitstage: Wochenenden zählen nicht, einzelne Feiertage werden toleriert
+			for d := from.AddDate(0, 0, 1); d.Before(to); d = d.AddDate(0, 0, 1) {
+				if d.Weekday() != time.Saturday && d.Weekday() != time.Sunday {
+					missing++
+				}
+			}
+			if missing <= 2 {
+				continue
+			}
+		} else {
+			interval := expectedInterval(frequency)
+			if to.Sub(from) <= interval+interval/2 {
+				continue
+			}
+			missing = int(math.Round(float64(to.Sub(from))/float64(interval))) - 1
+		}
+
+		gaps = append(gaps, QualityGap{From: from, To: to, Missing: missing})
+	}
+	return gaps
+}
+
+// detectOutliers bewertet jede Veränderung per z-Score gegenüber den vorherigen outlierWindow Veränderungen
+// (values aufsteigend sortiert). Veränderungen statt Niveaus, damit Trends nicht als Ausreißer gelten.
+func detectOutliers(values []ObservationValue) []QualityFlag {
+	flags := make([]QualityFlag, 0)
+	if len(values) < outlierWindow+2 {
+		return flags
+	}
+
+	changes := make([]float64, len(values)-1)
+	for i := 1; i < len(values); i++ {
+		changes[i-1] = values[i].Value - values[i-1].Value
+	}
+
+	for i := outlierWindow; i < len(changes); i++ {
+		window := changes[i-outlierWindow : i]
+		if !isContinuous(window) {
+			continue // Stufenreihe (z.B. Leitzins) - dort ist jede Änderung ein Ereignis, kein Ausreißer
+		}
+		mean, std := meanStd(window)
+		z := (changes[i] - mean) / std
+		if math.Abs(z) < outlierThreshold {
+			continue
+		}
+		val := values[i+1]
+		flags = append(flags, QualityFlag{
+			Type:       FlagOutlier,
+			ObservedAt: val.ObservedAt,
+			Value:      val.Value,
+			ZScore:     math.Round(z*100) / 100,
+			Detail:     fmt.Sprintf("Veränderung %+.4g gegenüber Vorwert %.4g", changes[i], values[i].Value),
+		})
+	}
+	return flags
+}
+
+// isContinuous prüft ob sich die Reihe im Fenster überwiegend bewegt (mindestens jede zweite Veränderung != 0)
+func isContinuous(changes []float64) bool {
+	moving := 0
+	for _, c := range changes {
+		if c != 0 {
+			moving++
+		}
+	}
+	return moving*2 >= len(changes)
+}
+
+// meanStd berechnet Mittelwert und Standardabweichung
+func meanStd(values []float64) (float64, float64) {
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	mean := sum / float64(len(values))
+
+	var sq float64
+	for _, v := range values {
+		sq += (v - mean) * (v - mean)
+	}
+	return mean, math.Sqrt(sq / float64(len(values)))
+}
+
+// GetQualityReport erstellt den Qualitätsbericht eines Indikators
+func (s *Service) GetQualityReport(code string) (*QualityReport, error) {
+	ind, err := s.repo.GetIndicatorByCode(code)
+	if err != nil {
+		return nil, err
+	}
+	if ind == nil {
+		return nil, fmt.Errorf("Indikator nicht gefunden: %s", code)
+	}
+	return s.buildQualityReport(ind, time.Now(), 50)
+}
+
+// GetQualityOverview fasst die Qualität aller aktiven Indikatoren zusammen
+func (s *Service) GetQualityOverview() ([]QualitySummary, error) {
+	indicators, err := s.repo.GetAllIndicators(true)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	result := make([]QualitySummary, 0, len(indicators))
+	for i := range indicators {
+		report, err := s.buildQualityReport(&indicators[i], now, 0)
+		if err != nil {
+			return nil, err
+		}
+		summary := QualitySummary{
+			IndicatorCode:  report.IndicatorCode,
+			Name:           report.Name,
+			LatestObserved: report.LatestObserved,
+			AgeDays:        report.AgeDays,
+			Stale:          report.Stale,
+			Gaps:           len(report.Gaps),
+			Revisions:      report.RevisionCount,
+		}
+		for _, flag := range report.Flags {
+			if flag.Type == FlagOutlier {
+				summary.Outliers++
+			}
+		}
+		result = append(result, summary)
+	}
+	return result, nil
+}
+
+// GetStaleIndicators gibt die aktiven Indikatoren mit veraltetem letzten Wert zurück (Code -> Stichtag)
+func (s *Service) GetStaleIndicators() (map[string]time.Time, error) {
+	indicators, err := s.repo.GetAllIndicators(true)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	stale := make(map[string]time.Time)
+	for _, ind := range indicators {
+		latest, err := s.repo.GetLatestValue(ind.ID)
+		if err != nil || latest == nil {
+			continue // Nie gesammelt - kein veralteter Wert im Kontext
+		}
+		if IsStale(ind.Frequency, latest.ObservedAt, now) {
+			stale[ind.Code] = latest.ObservedAt
+		}
+	}
+	return stale, nil
+}
+
+// buildQualityReport wertet Aktualität, Ausreißer, Lücken und Revisionen aus
+func (s *Service) buildQualityReport(ind *Indicator, now time.Time, revisionLimit int) (*QualityReport, error) {
+	report := &QualityReport{
+		IndicatorCode: ind.Code,
+		Name:          ind.Name,
+		Frequency:     ind.Frequency,
+		Flags:         make([]QualityFlag, 0),
+		Gaps:          make([]QualityGap, 0),
+		Revisions:     make([]ValueRevision, 0),
+	}
+
+	values, err := s.repo.GetValuesByIndicator(ind.ID, qualityMaxValues)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(values, func(i, j int) bool { return values[i].ObservedAt.Before(values[j].ObservedAt) })
+	report.ValueCount = len(values)
+
+	if len(values) > 0 {
+		latest := values[len(values)-1]
+		report.LatestObserved = &latest.ObservedAt
+		for _, v := range values {
+			if report.LastCollected == nil || v.CollectedAt.After(*report.LastCollected) {
+				collected := v.CollectedAt
+				report.LastCollected = &collected
+			}
+		}
+		report.AgeDays = int(now.Sub(latest.ObservedAt).Hours() / 24)
+		report.Stale = IsStale(ind.Frequency, latest.ObservedAt, now)
+		if report.Stale {
+			report.Flags = append(report.Flags, QualityFlag{
+				Type:       FlagStale,
+				ObservedAt: latest.ObservedAt,
+				Value:      latest.Value,
+				Detail:     fmt.Sprintf("Letzter Stichtag vor %d Tagen (erwartet höchstens %d)", report.AgeDays, int(maxAge(ind.Frequency).Hours()/24)),
+			})
+		}
+	}
+
+	report.Flags = append(report.Flags, detectOutliers(values)...)
+
+	report.Gaps = detectGaps(values, ind.Frequency)
+	for _, gap := range report.Gaps {
+		report.Flags = append(report.Flags, QualityFlag{
+			Type:       FlagGap,
+			ObservedAt: gap.To,
+			Detail:     fmt.Sprintf("%d fehlende Perioden seit %s", gap.Missing, gap.From.Format("02.01.2006")),
+		})
+	}
+
+	if report.RevisionCount, err = s.repo.GetRevisionCount(ind.ID); err != nil {
+		return nil, err
+	}
+	if revisionLimit > 0 {
+		if report.Revisions, err = s.repo.GetRevisions(ind.ID, revisionLimit); err != nil {
+			return nil, err
+		}
+	}
+
+	return report, nil
+}
@@ -0,0 +1,181 @@
+package observer
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestStoreValuesRevisions prüft Duplikate und Revisionen bei erneut veröffentlichten Stichtagen
+func TestStoreValuesRevisions(t *testing.T) {
+	repo, err := NewRepository(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer repo.Close()
+
+	day := func(d int) time.Time { return time.Date(2024, 9, d, 0, 0, 0, 0, time.UTC) }
+	batch := func(runID int64, values ...float64) []ObservationValue {
+		result := make([]ObservationValue, len(values))
+		for i, v := range values {
+			result[i] = ObservationValue{RunID: runID, IndicatorID: 1, SourceID: 1, ObservedAt: day(i + 1), CollectedAt: time.Now(), Value: v}
+		}
+		return result
+	}
+
+	if added, revised, err := repo.StoreValues(batch(1, 2.5, 2.6)); err != nil || added != 2 || revised != 0 {
+		t.Fatalf("Erster Lauf: %d/%d, %v", added, revised, err)
+	}
+	if added, revised, err := repo.StoreValues(batch(2, 2.5, 2.6, 2.7)); err != nil || added != 1 || revised != 0 {
+		t.Fatalf("Unveränderte Werte: %d/%d, %v", added, revised, err)
+	}
+	// Quelle revidiert den 02.09. von 2.6 auf 2.4
+	if added, revised, err := repo.StoreValues(batch(3, 2.5, 2.4, 2.7)); err != nil || added != 0 || revised != 1 {
+		t.Fatalf("Revision: %d/%d, %v", added, revised, err)
+	}
+
+	values, _ := repo.GetValuesByIndicator(1, 0)
+	if len(values) != 3 || values[1].Value != 2.4 || values[1].RunID != 3 {
+		t.Errorf("Aktuelle Werte: %+v", values)
+	}
+	revisions, err := repo.GetRevisions(1, 10)
+	if err != nil || len(revisions) != 1 {
+		t.Fatalf("Revisionen: %+v, %v", revisions, err)
+	}
+	rev := revisions[0]
+	if rev.PreviousValue != 2.6 || rev.PreviousRunID != 1 || rev.Value != 2.4 || rev.RunID != 3 || !rev.ObservedAt.Equal(day(2)) {
+		t.Errorf("Revision: %+v", rev)
+	}
+}
+
+// TestQualityDetection prüft Lücken, Ausreißer und Veraltung
+func TestQualityDetection(t *testing.T) {
+	series := func(start time.Time, step func(time.Time) time.Time, values []float64) []ObservationValue {
+		result := make([]ObservationValue, len(values))
+		d := start
+		for i, v := range values {
+			result[i] = ObservationValue{ObservedAt: d, Value: v}
+			d = step(d)
+		}
+		return result
+	}
+	nextWeekday := func(d time.Time) time.Time {
+		d = d.AddDate(0, 0, 1)
+		for d.Weekday() == time.Saturday || d.Weekday() == time.Sunday {
+			d = d.AddDate(0, 0, 1)
+		}
+		return d
+	}
+
+	// Tageswerte Mo-Fr: Wochenenden und ein Feiertag sind keine Lücke, eine fehlende Woche schon
+	daily := series(time.Date(2024, 9, 2, 0, 0, 0, 0, time.UTC), nextWeekday, make([]float64, 10))
+	daily = append(daily[:3], daily[4:]...) // Feiertag
+	if gaps := detectGaps(daily, "D"); len(gaps) != 0 {
+		t.Errorf("Feiertag als Lücke: %+v", gaps)
+	}
+	daily = append(daily, ObservationValue{ObservedAt: daily[len(daily)-1].ObservedAt.AddDate(0, 0, 14)})
+	if gaps := detectGaps(daily, "D"); len(gaps) != 1 || gaps[0].Missing != 9 {
+		t.Errorf("Lücke Tageswerte: %+v", gaps)
+	}
+
+	// Monatswerte: Juli fehlt
+	monthly := series(time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), func(d time.Time) time.Time { return d.AddDate(0, 1, 0) }, []float64{1, 2, 3})
+	monthly[2].ObservedAt = time.Date(2024, 8, 1, 0, 0, 0, 0, time.UTC)
+	if gaps := detectGaps(monthly, "M"); len(gaps) != 1 || gaps[0].Missing != 1 {
+		t.Errorf("Lücke Monatswerte: %+v", gaps)
+	}
+
+	// Kursreihe mit Trend und Rauschen, ein Spike (Datenfehler) am Index 30
+	prices := make([]float64, 40)
+	for i := range prices {
+		prices[i] = 100 + float64(i)*0.5 + math.Sin(float64(i))*0.8
+	}
+	prices[30] = 160
+	flags := detectOutliers(series(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), nextWeekday, prices))
+	if len(flags) == 0 || flags[0].Value != 160 || flags[0].ZScore < outlierThreshold {
+		t.Errorf("Spike nicht erkannt: %+v", flags)
+	}
+
+	// Leitzins (Stufenreihe): Zinsschritte sind keine Ausreißer
+	rates := make([]float64, 40)
+	for i := range rates {
+		rates[i] = 4.5
+		if i >= 25 {
+			rates[i] = 4.25
+		}
+		if i >= 35 {
+			rates[i] = 3.65
+		}
+	}
+	if flags := detectOutliers(series(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), nextWeekday, rates)); len(flags) != 0 {
+		t.Errorf("Zinsschritt als Ausreißer: %+v", flags)
+	}
+
+	now := time.Date(2024, 10, 18, 12, 0, 0, 0, time.UTC)
+	if IsStale("D", now.AddDate(0, 0, -3), now) || !IsStale("D", now.AddDate(0, 0, -10), now) {
+		t.Error("Veraltung Tageswerte falsch")
+	}
+	if IsStale("M", time.Date(2024, 8, 1, 0, 0, 0, 0, time.UTC), now) || !IsStale("M", time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), now) {
+		t.Error("Veraltung Monatswerte falsch")
+	}
+}
+
+// TestQualityReportAndContext prüft Qualitätsbericht-Endpoint und Hinweis im Finanz-Kontext
+func TestQualityReportAndContext(t *testing.T) {
+	service, err := NewService(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer service.Close()
+
+	ind, _ := service.repo.GetIndicatorByCode("ECB_MAIN_RATE")
+	dax, _ := service.repo.GetIndicatorByCode("DAX")
+	if ind == nil || dax == nil {
+		t.Fatal("Seed-Indikatoren fehlen")
+	}
+	old := time.Now().AddDate(0, 0, -30)
+	service.repo.StoreValues([]ObservationValue{
+		{RunID: 1, IndicatorID: ind.ID, SourceID: ind.SourceID, ObservedAt: old, CollectedAt: old, Value: 4.25},
+		{RunID: 1, IndicatorID: dax.ID, SourceID: dax.SourceID, ObservedAt: time.Now(), CollectedAt: time.Now(), Value: 19500},
+	})
+	service.repo.StoreValues([]ObservationValue{
+		{RunID: 2, IndicatorID: ind.ID, SourceID: ind.SourceID, ObservedAt: old, CollectedAt: time.Now(), Value: 4.5},
+	})
+
+	mux := http.NewServeMux()
+	NewHandlers(service).RegisterRoutes(mux)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/observer/quality/ECB_MAIN_RATE", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Qualitätsbericht: %d %s", rec.Code, rec.Body.String())
+	}
+	var report QualityReport
+	json.NewDecoder(rec.Body).Decode(&report)
+	if !report.Stale || report.AgeDays < 29 || report.RevisionCount != 1 || len(report.Revisions) != 1 || report.Revisions[0].PreviousValue != 4.25 {
+		t.Errorf("Bericht: %+v", report)
+	}
+	if len(report.Flags) != 1 || report.Flags[0].Type != FlagStale {
+		t.Errorf("Flags: %+v", report.Flags)
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/observer/quality/UNBEKANNT", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Unbekannter Indikator: %d", rec.Code)
+	}
+
+	context := NewContextProvider(service).GetFinanceContext()
+	if !strings.Contains(context, "VERALTETE DATEN") || !strings.Contains(context, "ECB_MAIN_RATE: Stand "+old.Format("02.01.2006")) {
+		t.Errorf("Veraltung fehlt im Kontext:\n%s", context)
+	}
+	if strings.Contains(context, "DAX: Stand") {
+		t.Errorf("Aktueller DAX als veraltet markiert:\n%s", context)
+	}
+	if !strings.Contains(context, "EZB Hauptrefinanzierungssatz: 4.50%") {
+		t.Errorf("Revidierter Wert fehlt:\n%s", context)
+	}
+}
@@ -0,0 +1,401 @@
+package observer
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Aggregationen beim Resampling
+const (
+	AggLast = "last" // Letzter Wert der Periode (Default, z.B. Monatsultimo)
+	AggMean = "mean"
+	AggMin  = "min"
+	AggMax  = "max"
+)
+
+// Abgeleitete Reihen
+const (
+	TransformChange    = "change"     // Veränderung zur Vorperiode (bei %-Einheiten in Prozentpunkten)
+	TransformChangePct = "change_pct" // Relative Veränderung zur Vorperiode in %
+	TransformYoY       = "yoy"        // Veränderung zum Vorjahreswert (relativ, bei %-Einheiten in Prozentpunkten)
+	TransformMA        = "ma"         // Gleitender Durchschnitt über Window Perioden
+)
+
+const defaultMAWindow = 20
+
+// SeriesQuery beschreibt eine Zeitreihen-Abfrage
+type SeriesQuery struct {
+	Indicator   string     `json:"indicator"`
+	Minus       string     `json:"minus,omitempty"` // Spread: Indicator minus dieser Indikator
+	From        *time.Time `json:"from,omitempty"`
+	To          *time.Time `json:"to,omitempty"`
+	Frequency   string     `json:"frequency,omitempty"`   // W, M, Q, A (leer = Rohdaten)
+	Aggregation string     `json:"aggregation,omitempty"` // last, mean, min, max
+	Transform   string     `json:"transform,omitempty"`   // change, change_pct, yoy, ma
+	Window      int        `json:"window,omitempty"`      // Perioden für ma
+}
+
+// SeriesPoint ist ein Punkt der abgefragten Reihe
+type SeriesPoint struct {
+	Date    time.Time `json:"date"`
+	Period  string    `json:"period,omitempty"` // Periode beim Resampling (z.B. "2024-Q3")
+	Value   float64   `json:"value"`
+	Display string    `json:"display"` // Formatiert mit FormatValueForDisplay
+}
+
+// SeriesResult ist das Ergebnis einer Zeitreihen-Abfrage
+type SeriesResult struct {
+	Query  SeriesQuery   `json:"query"`
+	Name   string        `json:"name"`
+	Unit   string        `json:"unit"`
+	Points []SeriesPoint `json:"points"`
+}
+
+// Validate prüft und normalisiert die Abfrage
+func (q *SeriesQuery) Validate() error {
+	q.Indicator = strings.ToUpper(strings.TrimSpace(q.Indicator))
+	q.Minus = strings.ToUpper(strings.TrimSpace(q.Minus))
+	if q.Indicator == "" {
+		return fmt.Errorf("indicator fehlt")
+	}
+	if q.Minus == q.Indicator {
+		return fmt.Errorf("Spread mit sich selbst nicht möglich")
+	}
+
+	q.Frequency = strings.ToUpper(strings.TrimSpace(q.Frequency))
+	switch q.Frequency {
+	case "", "W", "M", "Q", "A":
+	case "D":
+		q.Frequency = ""
+	default:
+		return fmt.Errorf("Ungültige Frequenz: %q (W, M, Q, A)", q.Frequency)
+	}
+
+	q.Aggregation = strings.ToLower(strings.TrimSpace(q.Aggregation))
+	switch q.Aggregation {
+	case "":
+		if q.Frequency != "" {
+			q.Aggregation = AggLast
+		}
+	case AggLast, AggMean, AggMin, AggMax:
+		if q.Frequency == "" {
+			return fmt.Errorf("Aggregation nur mit frequency möglich")
+		}
+	default:
+		return fmt.Errorf("Ungültige Aggregation: %q (last, mean, min, max)", q.Aggregation)
+	}
+
+	q.Transform = strings.ToLower(strings.TrimSpace(q.Transform))
+	switch q.Transform {
+	case "", TransformChange, TransformChangePct, TransformYoY:
+	case TransformMA:
+		if q.Window == 0 {
+			q.Window = defaultMAWindow
+		}
+		if q.Window < 2 {
+			return fmt.Errorf("window muss mindestens 2 sein")
+		}
+	default:
+		return fmt.Errorf("Ungültige Transformation: %q (change, change_pct, yoy, ma)", q.Transform)
+	}
+
+	if q.From != nil && q.To != nil && q.From.After(*q.To) {
+		return fmt.Errorf("from liegt nach to")
+	}
+	return nil
+}
+
+// QuerySeries liefert eine (optional resampelte und abgeleitete) Zeitreihe eines Indikators oder Spreads
+func (s *Service) QuerySeries(q SeriesQuery) (*SeriesResult, error) {
+	if err := q.Validate(); err != nil {
+		return nil, err
+	}
+
+	ind, err := s.repo.GetIndicatorByCode(q.Indicator)
+	if err != nil {
+		return nil, err
+	}
+	if ind == nil {
+		return nil, fmt.Errorf("Indikator %s: %w", q.Indicator, ErrNotFound)
+	}
+
+	// Für Veränderungen und Durchschnitte werden Werte vor dem Zeitraum benötigt
+	from := time.Date(1900, 1, 1, 0, 0, 0, 0, time.UTC)
+	if q.From != nil {
+		from = q.lookbackStart(*q.From, ind.Frequency)
+	}
+	to := time.Now()
+	if q.To != nil {
+		to = *q.To
+	}
+
+	values, err := s.repo.GetValuesByIndicatorDateRange(ind.ID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	points := toSeriesPoints(values)
+	unit := ind.Unit
+	name := ind.Name
+	frequency := ind.Frequency
+
+	if q.Minus != "" {
+		other, err := s.repo.GetIndicatorByCode(q.Minus)
+		if err != nil {
+			return nil, err
+		}
+		if other == nil {
+			return nil, fmt.Errorf("Indikator %s: %w", q.Minus, ErrNotFound)
+		}
+		otherValues, err := s.repo.GetValuesByIndicatorDateRange(other.ID, from.AddDate(0, 0, -int(maxAge(other.Frequency).Hours()/24)), to)
+		if err != nil {
+			return nil, err
+		}
+		points = spreadSeries(points, toSeriesPoints(otherValues), maxAge(other.Frequency))
+		name = fmt.Sprintf("%s minus %s", ind.Name, other.Name)
+		unit = changeUnit(unit)
+	}
+
+	if q.Frequency != "" {
+		points = resampleSeries(points, q.Frequency, q.Aggregation)
+		frequency = q.Frequency
+	}
+
+	switch q.Transform {
+	case TransformChange:
+		points = changeSeries(points, false)
+		unit = changeUnit(unit)
+	case TransformChangePct:
+		points = changeSeries(points, true)
+		unit = "%"
+	case TransformYoY:
+		relative := !isPercentUnit(unit)
+		points = yoySeries(points, yoyTolerance(frequency), relative)
+		if relative {
+			unit = "%"
+		} else {
+			unit = changeUnit(unit)
+		}
+	case TransformMA:
+		points = movingAverage(points, q.Window)
+	}
+
+	// Vorlauf wieder abschneiden
+	if q.From != nil {
+		cutoff := *q.From
+		if q.Frequency != "" {
+			cutoff = bucketStart(cutoff, q.Frequency) // Angebrochene erste Periode behalten
+		}
+		start := sort.Search(len(points), func(i int) bool { return !points[i].Date.Before(cutoff) })
+		points = points[start:]
+	}
+
+	for i := range points {
+		points[i].Display = FormatValueForDisplay(points[i].Value, unit)
+	}
+
+	return &SeriesResult{Query: q, Name: name, Unit: unit, Points: points}, nil
+}
+
+// lookbackStart erweitert den Zeitraum um den Vorlauf für Transformationen und Perioden-Anfang
+func (q *SeriesQuery) lookbackStart(from time.Time, indicatorFrequency string) time.Time {
+	frequency := q.Frequency
+	if frequency == "" {
+		frequency = indicatorFrequency
+	}
+	start := from
+	if q.Frequency != "" {
+		start = bucketStart(from, q.Frequency)
+	}
+	switch q.Transform {
+	case TransformYoY:
+		return start.AddDate(-1, 0, -int(expectedInterval(frequency).Hours()/24))
+	case TransformMA:
+		return start.Add(-time.Duration(q.Window+1) * expectedInterval(frequency) * 3 / 2)
+	case TransformChange, TransformChangePct:
+		return start.Add(-expectedInterval(frequency) * 3)
+	}
+	return start
+}
+
+// toSeriesPoints wandelt Messwerte in aufsteigend sortierte Punkte um
+func toSeriesPoints(values []ObservationValue) []SeriesPoint {
+	points := make([]SeriesPoint, len(values))
+	for i, v := range values {
+		points[i] = SeriesPoint{Date: v.ObservedAt, Value: v.Value}
+	}
+	sort.Slice(points, func(i, j int) bool { return points[i].Date.Before(points[j].Date) })
+	return points
+}
+
+// isPercentUnit prüft ob die Einheit bereits ein Prozentsatz ist (Zinsen, Inflationsraten)
+func isPercentUnit(unit string) bool {
+	return strings.HasPrefix(unit, "%")
+}
+
+// changeUnit gibt die Einheit einer Differenz zurück (Prozentsätze -> Prozentpunkte)
+func changeUnit(unit string) string {
+	if isPercentUnit(unit) {
+		return "pp"
+	}
+	return unit
+}
+
+// spreadSeries zieht von jedem Punkt den letzten bekannten Wert der zweiten Reihe ab (höchstens maxLag alt)
+func spreadSeries(base, other []SeriesPoint, maxLag time.Duration) []SeriesPoint {
+	result := make([]SeriesPoint, 0, len(base))
+	j := -1
+	for _, p := range base {
+		for j+1 < len(other) && !other[j+1].Date.After(p.Date) {
+			j++
+		}
+		if j < 0 || p.Date.Sub(other[j].Date) > maxLag {
+			continue
+		}
+		result = append(result, SeriesPoint{Date: p.Date, Value: p.Value - other[j].Value})
+	}
+	return result
+}
+
+// bucketStart gibt den Beginn der Periode zurück (Wochen beginnen montags)
+func bucketStart(t time.Time, frequency string) time.Time {
+	if frequency == "W" {
+		day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+		return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+	}
+	return periodStart(t, frequency)
+}
+
+// bucketLabel formatiert die Periode (Wochen als ISO-Woche)
+func bucketLabel(t time.Time, frequency string) string {
+	if frequency == "W" {
+		year, week := t.ISOWeek()
+		return fmt.Sprintf("%d-W%02d", year, week)
+	}
+	return formatPeriod(t, frequency)
+}
+
+// resampleSeries verdichtet die Punkte je Periode (points aufsteigend sortiert)
+func resampleSeries(points []SeriesPoint, frequency, aggregation string) []SeriesPoint {
+	result := make([]SeriesPoint, 0)
+	var bucket []float64
+	var current time.Time
+
+	flush := func() {
+		if len(bucket) == 0 {
+			return
+		}
+		value := bucket[len(bucket)-1]
+		switch aggregation {
+		case AggMean:
+			value, _ = meanStd(bucket)
+		case AggMin:
+			for _, v := range bucket {
+				value = math.Min(value, v)
+			}
+		case AggMax:
+			for _, v := range bucket {
+				value = math.Max(value, v)
+			}
+		}
+		result = append(result, SeriesPoint{Date: current, Period: bucketLabel(current, frequency), Value: value})
+		bucket = bucket[:0]
+	}
+
+	for _, p := range points {
+		start := bucketStart(p.Date, frequency)
+		if !start.Equal(current) {
+			flush()
+			current = start
+		}
+		bucket = append(bucket, p.Value)
+	}
+	flush()
+	return result
+}
+
+// changeSeries berechnet die Veränderung zur Vorperiode (absolut oder relativ in %)
+func changeSeries(points []SeriesPoint, relative bool) []SeriesPoint {
+	result := make([]SeriesPoint, 0, len(points))
+	for i := 1; i < len(points); i++ {
+		prev, cur := points[i-1].Value, points[i].Value
+		p := points[i]
+		if relative {
+			if prev == 0 {
+				continue
+			}
+			p.Value = (cur - prev) / math.Abs(prev) * 100
+		} else {
+			p.Value = cur - prev
+		}
+		result = append(result, p)
+	}
+	return result
+}
+
+// yoySeries vergleicht jeden Punkt mit dem letzten Wert ein Jahr zuvor (höchstens tolerance davor)
+func yoySeries(points []SeriesPoint, tolerance time.Duration, relative bool) []SeriesPoint {
+	result := make([]SeriesPoint, 0, len(points))
+	j := -1
+	for _, p := range points {
+		target := p.Date.AddDate(-1, 0, 0)
+		for j+1 < len(points) && !points[j+1].Date.After(target) {
+			j++
+		}
+		if j < 0 || target.Sub(points[j].Date) >= tolerance {
+			continue
+		}
+		prev := points[j].Value
+		if relative {
+			if prev == 0 {
+				continue
+			}
+			p.Value = (p.Value - prev) / math.Abs(prev) * 100
+		} else {
+			p.Value -= prev
+		}
+		result = append(result, p)
+	}
+	return result
+}
+
+// yoyTolerance ist der höchste Abstand des Vergleichswerts zum Vorjahresdatum
+// (bei Tageswerten fallen Vorjahrestage auf Wochenenden oder Feiertage)
+func yoyTolerance(frequency string) time.Duration {
+	if frequency == "D" || frequency == "" {
+		return maxAge("D")
+	}
+	return expectedInterval(frequency)
+}
+
+// movingAverage berechnet den gleitenden Durchschnitt (erst ab window Punkten)
+func movingAverage(points []SeriesPoint, window int) []SeriesPoint {
+	result := make([]SeriesPoint, 0, len(points))
+	var sum float64
+	for i, p := range points {
+		sum += p.Value
+		if i >= window {
+			sum -= points[i-window].Value
+		}
+		if i+1 < window {
+			continue
+		}
+		p.Value = sum / float64(window)
+		result = append(result, p)
+	}
+	return result
+}
+
+// FormatSeriesCSV schreibt das Ergebnis als CSV für deutsches Excel (Semikolon, Dezimalkomma)
+func FormatSeriesCSV(result *SeriesResult) string {
+	var b strings.Builder
+	b.WriteString("Datum;Periode;Wert;Anzeige\r\n")
+	for _, p := range result.Points {
+		value := strings.Replace(strconv.FormatFloat(math.Round(p.Value*1e6)/1e6, 'f', -1, 64), ".", ",", 1)
+		fmt.Fprintf(&b, "%s;%s;%s;%s\r\n", p.Date.Format("2006-01-02"), p.Period, value, p.Display)
+	}
+	return b.String()
+}
@@ -0,0 +1,143 @@
+package observer
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// weekdaySeries erzeugt Tageswerte Mo-Fr ab start mit value(i)
+func weekdaySeries(start time.Time, n int, value func(i int) float64) []SeriesPoint {
+	points := make([]SeriesPoint, 0, n)
+	for d := start; len(points) < n; d = d.AddDate(0, 0, 1) {
+		if d.Weekday() == time.Saturday || d.Weekday() == time.Sunday {
+			continue
+		}
+		points = append(points, SeriesPoint{Date: d, Value: value(len(points))})
+	}
+	return points
+}
+
+// TestSeriesTransforms prüft Resampling, Veränderungen, Vorjahresvergleich, Durchschnitt und Spread
+func TestSeriesTransforms(t *testing.T) {
+	// 01.07.2024 ist ein Montag: 23 Handelstage im Juli, 22 im August
+	daily := weekdaySeries(time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC), 45, func(i int) float64 { return float64(i) })
+
+	monthly := resampleSeries(daily, "M", AggLast)
+	if len(monthly) != 2 || monthly[0].Period != "2024-07" || monthly[0].Value != 22 || monthly[1].Value != 44 ||
+		!monthly[1].Date.Equal(time.Date(2024, 8, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Monat last: %+v", monthly)
+	}
+	if m := resampleSeries(daily, "M", AggMean); m[0].Value != 11 {
+		t.Errorf("Monat mean: %+v", m)
+	}
+	if m := resampleSeries(daily, "M", AggMin); m[1].Value != 23 {
+		t.Errorf("Monat min: %+v", m)
+	}
+	if q := resampleSeries(daily, "Q", AggMax); len(q) != 1 || q[0].Period != "2024-Q3" || q[0].Value != 44 {
+		t.Errorf("Quartal max: %+v", q)
+	}
+	if w := resampleSeries(daily, "W", AggLast); len(w) != 9 || w[0].Period != "2024-W27" || w[0].Value != 4 || w[0].Date.Weekday() != time.Monday {
+		t.Errorf("Woche: %+v", w[:2])
+	}
+
+	if c := changeSeries(monthly, false); len(c) != 1 || c[0].Value != 22 {
+		t.Errorf("Veränderung: %+v", c)
+	}
+	if c := changeSeries(monthly, true); c[0].Value != 100 {
+		t.Errorf("Veränderung %%: %+v", c)
+	}
+	if ma := movingAverage(daily[:5], 3); len(ma) != 3 || ma[0].Value != 1 || ma[2].Value != 3 {
+		t.Errorf("Gleitender Durchschnitt: %+v", ma)
+	}
+
+	// Vorjahr: Tageswerte über zwei Jahre, fällt der Vorjahrestag aufs Wochenende zählt der Freitag davor
+	twoYears := weekdaySeries(time.Date(2023, 7, 3, 0, 0, 0, 0, time.UTC), 600, func(i int) float64 { return 100 + float64(i) })
+	yoy := yoySeries(twoYears, yoyTolerance("D"), true)
+	if len(yoy) == 0 || yoy[0].Date.Before(time.Date(2024, 7, 3, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("Vorjahr beginnt zu früh: %+v", yoy[:1])
+	}
+	for _, p := range yoy {
+		if p.Value <= 0 {
+			t.Fatalf("Vorjahr: %+v", p)
+		}
+	}
+
+	// Spread: zweite Reihe nur montags, wird fortgeschrieben
+	weekly := make([]SeriesPoint, 0)
+	for _, p := range daily {
+		if p.Date.Weekday() == time.Monday {
+			weekly = append(weekly, SeriesPoint{Date: p.Date, Value: 1})
+		}
+	}
+	spread := spreadSeries(daily, weekly, 5*24*time.Hour)
+	if len(spread) != len(daily) || spread[4].Value != 3 {
+		t.Errorf("Spread: %+v", spread[:5])
+	}
+	if s := spreadSeries(daily, weekly[:1], 5*24*time.Hour); len(s) != 5 {
+		t.Errorf("Veralteter Spread-Partner nicht verworfen: %d", len(s))
+	}
+}
+
+// TestQueryAPI prüft den Query-Endpoint mit Spread, Monatsmittel und CSV-Ausgabe
+func TestQueryAPI(t *testing.T) {
+	service, err := NewService(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer service.Close()
+
+	store := func(code string, points []SeriesPoint) {
+		ind, _ := service.repo.GetIndicatorByCode(code)
+		values := make([]ObservationValue, len(points))
+		for i, p := range points {
+			values[i] = ObservationValue{RunID: 1, IndicatorID: ind.ID, SourceID: ind.SourceID, ObservedAt: p.Date, CollectedAt: p.Date, Value: p.Value}
+		}
+		service.repo.StoreValues(values)
+	}
+	start := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+	store("DE_10Y_YIELD", weekdaySeries(start, 45, func(i int) float64 { return 2.5 }))
+	store("ESTR", weekdaySeries(start, 45, func(i int) float64 {
+		if i < 23 {
+			return 3.7
+		}
+		return 3.5
+	}))
+
+	mux := http.NewServeMux()
+	NewHandlers(service).RegisterRoutes(mux)
+	get := func(url string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, url, nil))
+		return rec
+	}
+
+	rec := get("/api/observer/query?indicator=de_10y_yield&minus=ESTR&frequency=M&agg=mean&from=2024-07-15&to=2024-08-31")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Query: %d %s", rec.Code, rec.Body.String())
+	}
+	var result SeriesResult
+	json.NewDecoder(rec.Body).Decode(&result)
+	if result.Unit != "pp" || len(result.Points) != 2 || !approx(result.Points[0].Value, -1.2) || !approx(result.Points[1].Value, -1.0) {
+		t.Fatalf("Spread: %+v", result)
+	}
+	if result.Points[0].Display != "-1.20 pp" {
+		t.Errorf("Anzeige: %q", result.Points[0].Display)
+	}
+
+	rec = get("/api/observer/query?indicator=ESTR&frequency=M&transform=change&format=csv")
+	body := rec.Body.String()
+	if rec.Header().Get("Content-Type") != "text/csv; charset=utf-8" || !strings.Contains(body, "2024-08-01;2024-08;-0,2;-0.20 pp\r\n") {
+		t.Errorf("CSV: %q", body)
+	}
+
+	if rec := get("/api/observer/query?indicator=UNBEKANNT"); rec.Code != http.StatusNotFound {
+		t.Errorf("Unbekannter Indikator: %d", rec.Code)
+	}
+	if rec := get("/api/observer/query?indicator=ESTR&agg=median&frequency=M"); rec.Code != http.StatusBadRequest {
+		t.Errorf("Ungültige Aggregation: %d", rec.Code)
+	}
+}
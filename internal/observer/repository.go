@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"math"
 	"os"
 	"path/filepath"
 	"time"
@@ -18,6 +19,32 @@ type Repository struct {
 	dbPath string
 }
 
+// dbExecutor wird von *sql.DB und *sql.Tx erfüllt, damit Repository-Abfragen
+// auch innerhalb einer Transaktion laufen können (siehe repoTx)
+type dbExecutor interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// repoTx bietet die Repository-Methoden des Bundle-Imports in einer Transaktion
+type repoTx struct {
+	tx *sql.Tx
+}
+
+// inTx führt fn in einer Transaktion aus - bei einem Fehler wird alles zurückgerollt
+func (r *Repository) inTx(fn func(tx *repoTx) error) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(&repoTx{tx: tx}); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
 // NewRepository erstellt ein neues Observer-Repository mit eigener Datenbank
 func NewRepository(dataDir string) (*Repository, error) {
 	dbPath := filepath.Join(dataDir, "observer.db")
@@ -110,6 +137,62 @@ func (r *Repository) createSchema() error {
 		FOREIGN KEY (source_id) REFERENCES data_source(id)
 	);
 
+	-- Revisionen: vorheriger Wert, wenn eine Quelle ein Datum neu veröffentlicht
+	CREATE TABLE IF NOT EXISTS observation_revision (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		value_id INTEGER NOT NULL,
+		indicator_id INTEGER NOT NULL,
+		observed_at DATETIME NOT NULL,
+		previous_value REAL,
+		previous_value_string TEXT DEFAULT '',
+		previous_run_id INTEGER NOT NULL,
+		previous_collected_at DATETIME,
+		value REAL,
+		run_id INTEGER NOT NULL,
+		revised_at DATETIME NOT NULL,
+		FOREIGN KEY (value_id) REFERENCES observation_value(id),
+		FOREIGN KEY (indicator_id) REFERENCES indicator(id)
+	);
+
+	-- Alarm-Regeln je Indikator
+	CREATE TABLE IF NOT EXISTS alert_rule (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		indicator_code TEXT NOT NULL,
+		type TEXT NOT NULL,
+		threshold REAL DEFAULT 0,
+		days INTEGER DEFAULT 0,
+		channels TEXT DEFAULT '[]',
+		chat_id INTEGER DEFAULT 0,
+		webhook_url TEXT DEFAULT '',
+		active INTEGER DEFAULT 1,
+		last_triggered_at DATETIME,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
+	-- Alarm-Historie (append-only)
+	CREATE TABLE IF NOT EXISTS alert_event (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		rule_id INTEGER NOT NULL,
+		run_id INTEGER NOT NULL,
+		indicator_code TEXT NOT NULL,
+		rule_type TEXT NOT NULL,
+		value REAL,
+		previous_value REAL,
+		observed_at DATETIME,
+		message TEXT NOT NULL,
+		delivered TEXT DEFAULT '[]',
+		delivery_errors TEXT DEFAULT '[]',
+		triggered_at DATETIME NOT NULL
+	);
+
+	-- Zustand des Schedulers je Quelle (für Nachholen verpasster Läufe nach Neustart)
+	CREATE TABLE IF NOT EXISTS schedule_state (
+		source_code TEXT PRIMARY KEY,
+		last_run_at DATETIME,
+		last_success_at DATETIME,
+		last_error TEXT DEFAULT ''
+	);
+
 	-- Indizes für schnelle Abfragen
 	CREATE INDEX IF NOT EXISTS idx_observation_value_indicator ON observation_value(indicator_id);
 	CREATE INDEX IF NOT EXISTS idx_observation_value_observed_at ON observation_value(observed_at);
@@ -118,6 +201,8 @@ func (r *Repository) createSchema() error {
 	CREATE INDEX IF NOT EXISTS idx_observation_run_started_at ON observation_run(started_at);
 	CREATE INDEX IF NOT EXISTS idx_indicator_category ON indicator(category);
 	CREATE INDEX IF NOT EXISTS idx_indicator_active ON indicator(active);
+	CREATE INDEX IF NOT EXISTS idx_observation_revision_indicator ON observation_revision(indicator_id, observed_at);
+	CREATE INDEX IF NOT EXISTS idx_alert_event_triggered_at ON alert_event(triggered_at);
 	`
 
 	_, err := r.db.Exec(schema)
@@ -125,9 +210,23 @@ func (r *Repository) createSchema() error {
 		return fmt.Errorf("Observer-Schema erstellen fehlgeschlagen: %w", err)
 	}
 
+	r.migrateSourceDefinition()
+
 	return nil
 }
 
+// migrateSourceDefinition fügt die definition Spalte für konfigurierte Quellen hinzu
+func (r *Repository) migrateSourceDefinition() {
+	var count int
+	r.db.QueryRow(`
+		SELECT COUNT(*) FROM pragma_table_info('data_source') WHERE name='definition'
+	`).Scan(&count)
+
+	if count == 0 {
+		r.db.Exec(`ALTER TABLE data_source ADD COLUMN definition TEXT DEFAULT ''`)
+	}
+}
+
 // Close schließt die Datenbankverbindung
 func (r *Repository) Close() error {
 	return r.db.Close()
@@ -142,10 +241,24 @@ func (r *Repository) GetDBPath() string {
 
 // CreateSource erstellt eine neue Datenquelle
 func (r *Repository) CreateSource(source *DataSource) error {
-	result, err := r.db.Exec(`
-		INSERT INTO data_source (code, name, description, url, source_class, active)
-		VALUES (?, ?, ?, ?, ?, ?)
-	`, source.Code, source.Name, source.Description, source.URL, source.SourceClass, source.Active)
+	return createSource(r.db, source)
+}
+
+// CreateSource erstellt eine neue Datenquelle in der Transaktion
+func (t *repoTx) CreateSource(source *DataSource) error {
+	return createSource(t.tx, source)
+}
+
+func createSource(db dbExecutor, source *DataSource) error {
+	definition, err := encodeDefinition(source.Definition)
+	if err != nil {
+		return err
+	}
+
+	result, err := db.Exec(`
+		INSERT INTO data_source (code, name, description, url, source_class, active, definition)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, source.Code, source.Name, source.Description, source.URL, source.SourceClass, source.Active, definition)
 
 	if err != nil {
 		return fmt.Errorf("Datenquelle erstellen fehlgeschlagen: %w", err)
@@ -159,12 +272,22 @@ func (r *Repository) CreateSource(source *DataSource) error {
 
 // GetSourceByCode holt eine Quelle nach Code
 func (r *Repository) GetSourceByCode(code string) (*DataSource, error) {
+	return getSourceByCode(r.db, code)
+}
+
+// GetSourceByCode holt eine Quelle nach Code in der Transaktion
+func (t *repoTx) GetSourceByCode(code string) (*DataSource, error) {
+	return getSourceByCode(t.tx, code)
+}
+
+func getSourceByCode(db dbExecutor, code string) (*DataSource, error) {
 	source := &DataSource{}
-	err := r.db.QueryRow(`
-		SELECT id, code, name, description, url, source_class, active, created_at
+	var definition string
+	err := db.QueryRow(`
+		SELECT id, code, name, description, url, source_class, active, created_at, COALESCE(definition, '')
 		FROM data_source WHERE code = ?
 	`, code).Scan(&source.ID, &source.Code, &source.Name, &source.Description,
-		&source.URL, &source.SourceClass, &source.Active, &source.CreatedAt)
+		&source.URL, &source.SourceClass, &source.Active, &source.CreatedAt, &definition)
 
 	if err == sql.ErrNoRows {
 		return nil, nil
@@ -172,12 +295,13 @@ func (r *Repository) GetSourceByCode(code string) (*DataSource, error) {
 	if err != nil {
 		return nil, err
 	}
+	source.Definition = decodeDefinition(definition)
 	return source, nil
 }
 
 // GetAllSources holt alle Datenquellen
 func (r *Repository) GetAllSources(onlyActive bool) ([]DataSource, error) {
-	query := `SELECT id, code, name, description, url, source_class, active, created_at FROM data_source`
+	query := `SELECT id, code, name, description, url, source_class, active, created_at, COALESCE(definition, '') FROM data_source`
 	if onlyActive {
 		query += " WHERE active = 1"
 	}
@@ -192,19 +316,55 @@ func (r *Repository) GetAllSources(onlyActive bool) ([]DataSource, error) {
 	sources := make([]DataSource, 0)
 	for rows.Next() {
 		var s DataSource
-		if err := rows.Scan(&s.ID, &s.Code, &s.Name, &s.Description, &s.URL, &s.SourceClass, &s.Active, &s.CreatedAt); err != nil {
+		var definition string
+		if err := rows.Scan(&s.ID, &s.Code, &s.Name, &s.Description, &s.URL, &s.SourceClass, &s.Active, &s.CreatedAt, &definition); err != nil {
 			return nil, err
 		}
+		s.Definition = decodeDefinition(definition)
 		sources = append(sources, s)
 	}
 	return sources, nil
 }
 
+// encodeDefinition serialisiert die Definition einer konfigurierten Quelle ("" = eingebaut)
+func encodeDefinition(def *SourceDefinition) (string, error) {
+	if def == nil {
+		return "", nil
+	}
+	data, err := json.Marshal(def)
+	if err != nil {
+		return "", fmt.Errorf("Quellen-Definition serialisieren fehlgeschlagen: %w", err)
+	}
+	return string(data), nil
+}
+
+// decodeDefinition liest die Definition einer konfigurierten Quelle
+func decodeDefinition(raw string) *SourceDefinition {
+	if raw == "" {
+		return nil
+	}
+	var def SourceDefinition
+	if err := json.Unmarshal([]byte(raw), &def); err != nil {
+		log.Printf("Observer: Quellen-Definition ungültig: %v", err)
+		return nil
+	}
+	return &def
+}
+
 // --- Indicator CRUD ---
 
 // CreateIndicator erstellt einen neuen Indikator
 func (r *Repository) CreateIndicator(ind *Indicator) error {
-	result, err := r.db.Exec(`
+	return createIndicator(r.db, ind)
+}
+
+// CreateIndicator erstellt einen neuen Indikator in der Transaktion
+func (t *repoTx) CreateIndicator(ind *Indicator) error {
+	return createIndicator(t.tx, ind)
+}
+
+func createIndicator(db dbExecutor, ind *Indicator) error {
+	result, err := db.Exec(`
 		INSERT INTO indicator (code, name, description, category, unit, frequency, source_id, external_code, active)
 		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`, ind.Code, ind.Name, ind.Description, ind.Category, ind.Unit, ind.Frequency, ind.SourceID, ind.ExternalCode, ind.Active)
@@ -221,8 +381,17 @@ func (r *Repository) CreateIndicator(ind *Indicator) error {
 
 // GetIndicatorByCode holt einen Indikator nach Code
 func (r *Repository) GetIndicatorByCode(code string) (*Indicator, error) {
+	return getIndicatorByCode(r.db, code)
+}
+
+// GetIndicatorByCode holt einen Indikator nach Code in der Transaktion
+func (t *repoTx) GetIndicatorByCode(code string) (*Indicator, error) {
+	return getIndicatorByCode(t.tx, code)
+}
+
+func getIndicatorByCode(db dbExecutor, code string) (*Indicator, error) {
 	ind := &Indicator{}
-	err := r.db.QueryRow(`
+	err := db.QueryRow(`
 		SELECT id, code, name, description, category, unit, frequency, source_id, external_code, active, created_at
 		FROM indicator WHERE code = ?
 	`, code).Scan(&ind.ID, &ind.Code, &ind.Name, &ind.Description, &ind.Category,
@@ -291,7 +460,16 @@ func (r *Repository) GetIndicatorsBySource(sourceID int64) ([]Indicator, error)
 
 // CreateRun erstellt einen neuen Sammellauf
 func (r *Repository) CreateRun(run *ObservationRun) error {
-	result, err := r.db.Exec(`
+	return createRun(r.db, run)
+}
+
+// CreateRun erstellt einen neuen Sammellauf in der Transaktion
+func (t *repoTx) CreateRun(run *ObservationRun) error {
+	return createRun(t.tx, run)
+}
+
+func createRun(db dbExecutor, run *ObservationRun) error {
+	result, err := db.Exec(`
 		INSERT INTO observation_run (strategy, started_at, status, is_backfill, backfill_from, backfill_to)
 		VALUES (?, ?, ?, ?, ?, ?)
 	`, run.Strategy, run.StartedAt, run.Status, run.IsBackfill, run.BackfillFrom, run.BackfillTo)
@@ -307,7 +485,16 @@ func (r *Repository) CreateRun(run *ObservationRun) error {
 
 // UpdateRun aktualisiert einen Sammellauf
 func (r *Repository) UpdateRun(run *ObservationRun) error {
-	_, err := r.db.Exec(`
+	return updateRun(r.db, run)
+}
+
+// UpdateRun aktualisiert einen Sammellauf in der Transaktion
+func (t *repoTx) UpdateRun(run *ObservationRun) error {
+	return updateRun(t.tx, run)
+}
+
+func updateRun(db dbExecutor, run *ObservationRun) error {
+	_, err := db.Exec(`
 		UPDATE observation_run
 		SET finished_at = ?, status = ?, total_records = ?, error_count = ?, error_messages = ?
 		WHERE id = ?
@@ -412,6 +599,102 @@ func (r *Repository) CreateValues(values []ObservationValue) error {
 	return tx.Commit()
 }
 
+// StoreValues speichert Messwerte mit Revisionsverfolgung: neue Stichtage werden eingefügt,
+// unveränderte übersprungen. Liefert eine Quelle einen Stichtag mit anderem Wert erneut,
+// wird der bisherige Wert samt Run-ID in observation_revision festgehalten.
+func (r *Repository) StoreValues(values []ObservationValue) (added, revised int, err error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, 0, err
+	}
+	defer tx.Rollback()
+
+	for i := range values {
+		val := &values[i]
+		startOfDay := time.Date(val.ObservedAt.Year(), val.ObservedAt.Month(), val.ObservedAt.Day(), 0, 0, 0, 0, time.UTC)
+
+		var prev ObservationValue
+		err := tx.QueryRow(`
+			SELECT id, run_id, value, value_string, collected_at FROM observation_value
+			WHERE indicator_id = ? AND observed_at >= ? AND observed_at < ?
+			ORDER BY id DESC LIMIT 1
+		`, val.IndicatorID, startOfDay, startOfDay.Add(24*time.Hour)).Scan(&prev.ID, &prev.RunID, &prev.Value, &prev.ValueString, &prev.CollectedAt)
+
+		switch {
+		case err == sql.ErrNoRows:
+			result, err := tx.Exec(`
+				INSERT INTO observation_value (run_id, indicator_id, source_id, observed_at, collected_at,
+					value, value_string, unit, period_start, period_end, raw_response)
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			`, val.RunID, val.IndicatorID, val.SourceID, val.ObservedAt, val.CollectedAt,
+				val.Value, val.ValueString, val.Unit, val.PeriodStart, val.PeriodEnd, val.RawResponse)
+			if err != nil {
+				return 0, 0, fmt.Errorf("Messwert erstellen fehlgeschlagen: %w", err)
+			}
+			val.ID, _ = result.LastInsertId()
+			added++
+
+		case err != nil:
+			return 0, 0, err
+
+		case math.Abs(prev.Value-val.Value) < 1e-9 && prev.ValueString == val.ValueString:
+			val.ID = prev.ID // Unverändert veröffentlicht
+
+		default:
+			if _, err := tx.Exec(`
+				INSERT INTO observation_revision (value_id, indicator_id, observed_at, previous_value, previous_value_string,
+					previous_run_id, previous_collected_at, value, run_id, revised_at)
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			`, prev.ID, val.IndicatorID, val.ObservedAt, prev.Value, prev.ValueString,
+				prev.RunID, prev.CollectedAt, val.Value, val.RunID, val.CollectedAt); err != nil {
+				return 0, 0, fmt.Errorf("Revision speichern fehlgeschlagen: %w", err)
+			}
+			if _, err := tx.Exec(`
+				UPDATE observation_value SET run_id = ?, collected_at = ?, value = ?, value_string = ?, raw_response = ?
+				WHERE id = ?
+			`, val.RunID, val.CollectedAt, val.Value, val.ValueString, val.RawResponse, prev.ID); err != nil {
+				return 0, 0, fmt.Errorf("Messwert aktualisieren fehlgeschlagen: %w", err)
+			}
+			val.ID = prev.ID
+			revised++
+		}
+	}
+
+	return added, revised, tx.Commit()
+}
+
+// GetRevisions holt die Revisionen eines Indikators (neueste zuerst)
+func (r *Repository) GetRevisions(indicatorID int64, limit int) ([]ValueRevision, error) {
+	rows, err := r.db.Query(`
+		SELECT id, value_id, indicator_id, observed_at, previous_value, previous_value_string, previous_run_id,
+			previous_collected_at, value, run_id, revised_at
+		FROM observation_revision WHERE indicator_id = ?
+		ORDER BY revised_at DESC, id DESC LIMIT ?
+	`, indicatorID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	revisions := make([]ValueRevision, 0)
+	for rows.Next() {
+		var rev ValueRevision
+		if err := rows.Scan(&rev.ID, &rev.ValueID, &rev.IndicatorID, &rev.ObservedAt, &rev.PreviousValue,
+			&rev.PreviousValueString, &rev.PreviousRunID, &rev.PreviousCollectedAt, &rev.Value, &rev.RunID, &rev.RevisedAt); err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, rev)
+	}
+	return revisions, rows.Err()
+}
+
+// GetRevisionCount gibt die Anzahl der Revisionen eines Indikators zurück
+func (r *Repository) GetRevisionCount(indicatorID int64) (int, error) {
+	var count int
+	err := r.db.QueryRow(`SELECT COUNT(*) FROM observation_revision WHERE indicator_id = ?`, indicatorID).Scan(&count)
+	return count, err
+}
+
 // GetValuesByIndicator holt alle Werte eines Indikators
 func (r *Repository) GetValuesByIndicator(indicatorID int64, limit int) ([]ObservationValue, error) {
 	query := `
@@ -533,6 +816,176 @@ func (r *Repository) GetValueCount(indicatorID int64) (int, error) {
 	return count, nil
 }
 
+// --- Alarm-Regeln ---
+
+// CreateAlertRule legt eine Alarm-Regel an
+func (r *Repository) CreateAlertRule(rule *AlertRule) error {
+	channels, _ := json.Marshal(rule.Channels)
+	result, err := r.db.Exec(`
+		INSERT INTO alert_rule (indicator_code, type, threshold, days, channels, chat_id, webhook_url, active, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, rule.IndicatorCode, rule.Type, rule.Threshold, rule.Days, string(channels), rule.ChatID, rule.WebhookURL,
+		boolToInt(rule.Active), rule.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("Alarm-Regel erstellen fehlgeschlagen: %w", err)
+	}
+
+	id, _ := result.LastInsertId()
+	rule.ID = id
+	return nil
+}
+
+// GetAlertRules holt alle Alarm-Regeln
+func (r *Repository) GetAlertRules(onlyActive bool) ([]AlertRule, error) {
+	query := `
+		SELECT id, indicator_code, type, threshold, days, channels, chat_id, webhook_url, active, last_triggered_at, created_at
+		FROM alert_rule
+	`
+	if onlyActive {
+		query += " WHERE active = 1"
+	}
+	query += " ORDER BY id"
+
+	rows, err := r.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rules := make([]AlertRule, 0)
+	for rows.Next() {
+		var rule AlertRule
+		var channels string
+		if err := rows.Scan(&rule.ID, &rule.IndicatorCode, &rule.Type, &rule.Threshold, &rule.Days, &channels,
+			&rule.ChatID, &rule.WebhookURL, &rule.Active, &rule.LastTriggeredAt, &rule.CreatedAt); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(channels), &rule.Channels); err != nil {
+			log.Printf("Observer: Kanäle der Alarm-Regel %d ungültig: %v", rule.ID, err)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, rows.Err()
+}
+
+// DeleteAlertRule löscht eine Alarm-Regel (die Historie bleibt erhalten)
+func (r *Repository) DeleteAlertRule(id int64) (bool, error) {
+	result, err := r.db.Exec(`DELETE FROM alert_rule WHERE id = ?`, id)
+	if err != nil {
+		return false, err
+	}
+	affected, _ := result.RowsAffected()
+	return affected > 0, nil
+}
+
+// CreateAlertEvent speichert einen ausgelösten Alarm und merkt den Zeitpunkt an der Regel
+func (r *Repository) CreateAlertEvent(event *AlertEvent) error {
+	delivered, _ := json.Marshal(event.Delivered)
+	deliveryErrors, _ := json.Marshal(event.DeliveryErrors)
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+		INSERT INTO alert_event (rule_id, run_id, indicator_code, rule_type, value, previous_value, observed_at,
+			message, delivered, delivery_errors, triggered_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, event.RuleID, event.RunID, event.IndicatorCode, event.RuleType, event.Value, event.PreviousValue, event.ObservedAt,
+		event.Message, string(delivered), string(deliveryErrors), event.TriggeredAt)
+	if err != nil {
+		return fmt.Errorf("Alarm speichern fehlgeschlagen: %w", err)
+	}
+	if _, err := tx.Exec(`UPDATE alert_rule SET last_triggered_at = ? WHERE id = ?`, event.TriggeredAt, event.RuleID); err != nil {
+		return fmt.Errorf("Alarm-Regel aktualisieren fehlgeschlagen: %w", err)
+	}
+
+	id, _ := result.LastInsertId()
+	event.ID = id
+	return tx.Commit()
+}
+
+// GetAlertEvents holt die Alarm-Historie (neueste zuerst), optional für einen Indikator
+func (r *Repository) GetAlertEvents(indicatorCode string, limit int) ([]AlertEvent, error) {
+	query := `
+		SELECT id, rule_id, run_id, indicator_code, rule_type, value, previous_value, observed_at,
+			message, delivered, delivery_errors, triggered_at
+		FROM alert_event
+	`
+	args := []interface{}{}
+	if indicatorCode != "" {
+		query += " WHERE indicator_code = ?"
+		args = append(args, indicatorCode)
+	}
+	query += " ORDER BY triggered_at DESC, id DESC"
+	if limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", limit)
+	}
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := make([]AlertEvent, 0)
+	for rows.Next() {
+		var event AlertEvent
+		var previous sql.NullFloat64
+		var delivered, deliveryErrors string
+		if err := rows.Scan(&event.ID, &event.RuleID, &event.RunID, &event.IndicatorCode, &event.RuleType, &event.Value,
+			&previous, &event.ObservedAt, &event.Message, &delivered, &deliveryErrors, &event.TriggeredAt); err != nil {
+			return nil, err
+		}
+		if previous.Valid {
+			event.PreviousValue = &previous.Float64
+		}
+		json.Unmarshal([]byte(delivered), &event.Delivered)
+		json.Unmarshal([]byte(deliveryErrors), &event.DeliveryErrors)
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
+
+// --- Scheduler ---
+
+// SaveScheduleState speichert den Scheduler-Zustand einer Quelle
+func (r *Repository) SaveScheduleState(state ScheduleState) error {
+	_, err := r.db.Exec(`
+		INSERT INTO schedule_state (source_code, last_run_at, last_success_at, last_error)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(source_code) DO UPDATE SET
+			last_run_at = excluded.last_run_at,
+			last_success_at = excluded.last_success_at,
+			last_error = excluded.last_error
+	`, state.SourceCode, state.LastRunAt, state.LastSuccessAt, state.LastError)
+	if err != nil {
+		return fmt.Errorf("Scheduler-Zustand speichern fehlgeschlagen: %w", err)
+	}
+	return nil
+}
+
+// GetScheduleStates holt den Scheduler-Zustand aller Quellen
+func (r *Repository) GetScheduleStates() (map[string]ScheduleState, error) {
+	rows, err := r.db.Query(`SELECT source_code, last_run_at, last_success_at, last_error FROM schedule_state`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	states := make(map[string]ScheduleState)
+	for rows.Next() {
+		var state ScheduleState
+		if err := rows.Scan(&state.SourceCode, &state.LastRunAt, &state.LastSuccessAt, &state.LastError); err != nil {
+			return nil, err
+		}
+		states[state.SourceCode] = state
+	}
+	return states, rows.Err()
+}
+
 // --- Statistiken ---
 
 // GetStats holt Observer-Statistiken
@@ -598,8 +1051,9 @@ func (r *Repository) ExportToSQL(outputPath string) error {
 	f.WriteString("-- Data Sources\n")
 	sources, _ := r.GetAllSources(false)
 	for _, s := range sources {
-		f.WriteString(fmt.Sprintf("INSERT INTO data_source (id, code, name, description, url, source_class, active, created_at) VALUES (%d, '%s', '%s', '%s', '%s', '%s', %d, '%s');\n",
-			s.ID, escapeSQL(s.Code), escapeSQL(s.Name), escapeSQL(s.Description), escapeSQL(s.URL), s.SourceClass, boolToInt(s.Active), s.CreatedAt.Format(time.RFC3339)))
+		definition, _ := encodeDefinition(s.Definition)
+		f.WriteString(fmt.Sprintf("INSERT INTO data_source (id, code, name, description, url, source_class, active, created_at, definition) VALUES (%d, '%s', '%s', '%s', '%s', '%s', %d, '%s', '%s');\n",
+			s.ID, escapeSQL(s.Code), escapeSQL(s.Name), escapeSQL(s.Description), escapeSQL(s.URL), s.SourceClass, boolToInt(s.Active), s.CreatedAt.Format(time.RFC3339), escapeSQL(definition)))
 	}
 	f.WriteString("\n")
 
@@ -657,6 +1111,23 @@ func (r *Repository) ExportToSQL(outputPath string) error {
 		}
 	}
 
+	// observation_revision
+	f.WriteString("\n-- Observation Revisions\n")
+	revRows, err := r.db.Query(`SELECT id, value_id, indicator_id, observed_at, previous_value, previous_value_string,
+		previous_run_id, previous_collected_at, value, run_id, revised_at FROM observation_revision`)
+	if err == nil {
+		defer revRows.Close()
+		for revRows.Next() {
+			var rev ValueRevision
+			revRows.Scan(&rev.ID, &rev.ValueID, &rev.IndicatorID, &rev.ObservedAt, &rev.PreviousValue, &rev.PreviousValueString,
+				&rev.PreviousRunID, &rev.PreviousCollectedAt, &rev.Value, &rev.RunID, &rev.RevisedAt)
+
+			f.WriteString(fmt.Sprintf("INSERT INTO observation_revision (id, value_id, indicator_id, observed_at, previous_value, previous_value_string, previous_run_id, previous_collected_at, value, run_id, revised_at) VALUES (%d, %d, %d, '%s', %f, '%s', %d, '%s', %f, %d, '%s');\n",
+				rev.ID, rev.ValueID, rev.IndicatorID, rev.ObservedAt.Format(time.RFC3339), rev.PreviousValue, escapeSQL(rev.PreviousValueString),
+				rev.PreviousRunID, rev.PreviousCollectedAt.Format(time.RFC3339), rev.Value, rev.RunID, rev.RevisedAt.Format(time.RFC3339)))
+		}
+	}
+
 	f.WriteString("\nCOMMIT;\n")
 	f.WriteString("PRAGMA foreign_keys=ON;\n")
 
@@ -680,6 +1151,62 @@ func (r *Repository) ImportFromSQL(inputPath string) error {
 	return nil
 }
 
+// FindRunByStartedAt sucht einen Sammellauf über seinen Startzeitpunkt (0 = nicht vorhanden),
+// precision berücksichtigt die beim Export gekürzten Nachkommastellen
+func (t *repoTx) FindRunByStartedAt(startedAt time.Time, precision time.Duration) (int64, error) {
+	var id int64
+	err := t.tx.QueryRow(`
+		SELECT id FROM observation_run WHERE started_at >= ? AND started_at < ? ORDER BY id LIMIT 1
+	`, startedAt, startedAt.Add(precision)).Scan(&id)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	return id, err
+}
+
+// MergeValues übernimmt importierte Werte ohne (Indikator, Stichtag)-Paare zu duplizieren.
+// Vorhandene Werte bleiben unverändert, abweichende werden als Konflikt gezählt.
+func (t *repoTx) MergeValues(values []ObservationValue) (added, skipped, conflicts int, err error) {
+	for i := range values {
+		val := &values[i]
+		startOfDay := time.Date(val.ObservedAt.Year(), val.ObservedAt.Month(), val.ObservedAt.Day(), 0, 0, 0, 0, time.UTC)
+
+		var existing float64
+		var existingString string
+		err := t.tx.QueryRow(`
+			SELECT value, value_string FROM observation_value
+			WHERE indicator_id = ? AND observed_at >= ? AND observed_at < ?
+			ORDER BY id DESC LIMIT 1
+		`, val.IndicatorID, startOfDay, startOfDay.Add(24*time.Hour)).Scan(&existing, &existingString)
+
+		switch {
+		case err == sql.ErrNoRows:
+			result, err := t.tx.Exec(`
+				INSERT INTO observation_value (run_id, indicator_id, source_id, observed_at, collected_at,
+					value, value_string, unit, period_start, period_end, raw_response)
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, '')
+			`, val.RunID, val.IndicatorID, val.SourceID, val.ObservedAt, val.CollectedAt,
+				val.Value, val.ValueString, val.Unit, val.PeriodStart, val.PeriodEnd)
+			if err != nil {
+				return 0, 0, 0, fmt.Errorf("Messwert importieren fehlgeschlagen: %w", err)
+			}
+			val.ID, _ = result.LastInsertId()
+			added++
+
+		case err != nil:
+			return 0, 0, 0, err
+
+		case math.Abs(existing-val.Value) < 1e-9 && existingString == val.ValueString:
+			skipped++
+
+		default:
+			conflicts++
+		}
+	}
+
+	return added, skipped, conflicts, nil
+}
+
 // --- Seed-Daten ---
 
 // SeedDefaultData fügt die Standard-Quellen und -Indikatoren ein
@@ -3,29 +3,69 @@ package observer
 import (
 	"context"
 	"log"
-	"strconv"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 )
 
-// Scheduler verwaltet die zeitgesteuerte Datensammlung
+const (
+	schedulerTick   = 1 * time.Minute
+	retryBaseDelay  = 5 * time.Minute // Erste Wiederholung, danach verdoppelt
+	retryMaxDelay   = 1 * time.Hour
+	backfillEvery   = 24 * time.Hour // Auto-Backfill höchstens einmal täglich
+	catchUpTolerate = 2 * schedulerTick
+)
+
+// ScheduleState ist der gespeicherte Scheduler-Zustand einer Quelle
+type ScheduleState struct {
+	SourceCode    string     `json:"sourceCode"`
+	LastRunAt     *time.Time `json:"lastRunAt,omitempty"`
+	LastSuccessAt *time.Time `json:"lastSuccessAt,omitempty"`
+	LastError     string     `json:"lastError,omitempty"`
+}
+
+// ScheduledSource beschreibt den Zeitplan einer Quelle (für /api/observer/status)
+type ScheduledSource struct {
+	ScheduleState
+	Expression string    `json:"expression"` // Cron-Ausdruck
+	NextRun    time.Time `json:"nextRun"`
+	Attempts   int       `json:"attempts,omitempty"` // Fehlgeschlagene Versuche seit dem letzten Erfolg
+	Retry      bool      `json:"retry,omitempty"`    // Nächster Lauf ist eine Wiederholung
+	CatchUp    bool      `json:"catchUp,omitempty"`  // Nächster Lauf holt einen verpassten Termin nach
+}
+
+// scheduleEntry ist der Zeitplan einer Quelle mit geparstem Ausdruck
+type scheduleEntry struct {
+	ScheduledSource
+	schedule *CronSchedule
+}
+
+// Scheduler verwaltet die zeitgesteuerte Datensammlung je Quelle
 type Scheduler struct {
-	service     *Service
-	stopCh      chan struct{}
-	running     bool
-	mu          sync.Mutex
-	nextRun     time.Time
-	collectTime string // Format: "HH:MM"
+	service      *Service
+	stopCh       chan struct{}
+	running      bool
+	mu           sync.Mutex
+	collectTime  string            // Format: "HH:MM", für Quellen ohne eigenen Ausdruck
+	schedules    map[string]string // Source-Code → Cron-Ausdruck
+	sources      []string          // Zu sammelnde Quellen
+	maxRetries   int
+	entries      map[string]*scheduleEntry
+	states       map[string]ScheduleState // Gespeicherter Zustand (für Nachholen nach Neustart)
+	lastBackfill time.Time
 }
 
 // NewScheduler erstellt einen neuen Scheduler
 func NewScheduler(service *Service) *Scheduler {
-	return &Scheduler{
-		service:     service,
-		stopCh:      make(chan struct{}),
-		collectTime: "06:00",
+	s := &Scheduler{
+		service: service,
+		stopCh:  make(chan struct{}),
+		entries: make(map[string]*scheduleEntry),
+		states:  make(map[string]ScheduleState),
 	}
+	s.applyConfig(service.config)
+	return s
 }
 
 // Start startet den Scheduler
@@ -40,7 +80,7 @@ func (s *Scheduler) Start() {
 	s.mu.Unlock()
 
 	go s.run()
-	log.Printf("Observer Scheduler: Gestartet (Sammelzeit: %s)", s.collectTime)
+	log.Printf("Observer Scheduler: Gestartet (Sammelzeit: %s, %d eigene Zeitpläne)", s.collectTime, len(s.schedules))
 }
 
 // Stop stoppt den Scheduler
@@ -62,22 +102,59 @@ func (s *Scheduler) Reconfigure(config *ObserverConfig) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	s.applyConfig(config)
+	s.syncEntries(time.Now())
+
+	log.Printf("Observer Scheduler: Neu konfiguriert (Sammelzeit: %s, nächster Lauf: %s)",
+		s.collectTime, s.nextRunLocked().Format("2006-01-02 15:04"))
+}
+
+// applyConfig übernimmt die Scheduler-relevanten Einstellungen (mu muss gehalten werden)
+func (s *Scheduler) applyConfig(config *ObserverConfig) {
 	if config.DailyCollectionTime != "" {
 		s.collectTime = config.DailyCollectionTime
 	}
+	if s.collectTime == "" {
+		s.collectTime = "06:00"
+	}
+	s.schedules = config.Schedules
+	s.maxRetries = config.MaxRetries
+	s.sources = s.service.scheduledSources(config)
+}
 
-	// NextRun neu berechnen
-	s.nextRun = s.calculateNextRun()
+// GetNextRun gibt den nächsten geplanten Lauf über alle Quellen zurück
+func (s *Scheduler) GetNextRun() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.nextRunLocked()
+}
 
-	log.Printf("Observer Scheduler: Neu konfiguriert (Sammelzeit: %s, nächster Lauf: %s)",
-		s.collectTime, s.nextRun.Format("2006-01-02 15:04"))
+func (s *Scheduler) nextRunLocked() time.Time {
+	var next time.Time
+	for _, entry := range s.entries {
+		if next.IsZero() || entry.NextRun.Before(next) {
+			next = entry.NextRun
+		}
+	}
+	return next
 }
 
-// GetNextRun gibt den nächsten geplanten Lauf zurück
-func (s *Scheduler) GetNextRun() time.Time {
+// GetSchedule gibt die anstehenden Läufe je Quelle zurück, sortiert nach Zeitpunkt
+func (s *Scheduler) GetSchedule() []ScheduledSource {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	return s.nextRun
+
+	result := make([]ScheduledSource, 0, len(s.entries))
+	for _, entry := range s.entries {
+		result = append(result, entry.ScheduledSource)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if !result[i].NextRun.Equal(result[j].NextRun) {
+			return result[i].NextRun.Before(result[j].NextRun)
+		}
+		return result[i].SourceCode < result[j].SourceCode
+	})
+	return result
 }
 
 // IsRunning prüft ob der Scheduler läuft
@@ -89,96 +166,217 @@ func (s *Scheduler) IsRunning() bool {
 
 // run ist die Haupt-Schleife des Schedulers
 func (s *Scheduler) run() {
-	// Initial nextRun berechnen
+	s.loadStates()
+
 	s.mu.Lock()
-	s.nextRun = s.calculateNextRun()
+	stopCh := s.stopCh
+	// Neu aufbauen, damit der gespeicherte Zustand (letzter Lauf) berücksichtigt wird
+	s.entries = make(map[string]*scheduleEntry)
+	s.syncEntries(time.Now())
+	nextRun := s.nextRunLocked()
 	s.mu.Unlock()
 
-	log.Printf("Observer Scheduler: Nächster Lauf geplant für %s", s.nextRun.Format("2006-01-02 15:04"))
+	log.Printf("Observer Scheduler: Nächster Lauf geplant für %s", nextRun.Format("2006-01-02 15:04"))
 
-	ticker := time.NewTicker(1 * time.Minute)
+	ticker := time.NewTicker(schedulerTick)
 	defer ticker.Stop()
 
 	for {
 		select {
-		case <-s.stopCh:
+		case <-stopCh:
 			return
 
-		case now := <-ticker.C:
-			s.mu.Lock()
-			nextRun := s.nextRun
-			s.mu.Unlock()
-
-			// Prüfen ob es Zeit für den Lauf ist
-			if now.After(nextRun) || now.Equal(nextRun) {
-				log.Printf("Observer Scheduler: Starte geplanten Sammellauf")
-
-				// Sammellauf ausführen
-				ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
-				run, err := s.service.RunNow(ctx)
-				cancel()
-
-				if err != nil {
-					log.Printf("Observer Scheduler: Fehler beim Sammellauf: %v", err)
-				} else {
-					log.Printf("Observer Scheduler: Sammellauf %d abgeschlossen - %d Werte",
-						run.ID, run.TotalRecords)
-				}
+		case <-ticker.C:
+			// Wanduhr statt Ticker-Zeit: nach Standby liegt sie weit hinter den geplanten Läufen
+			s.tick(time.Now())
+		}
+	}
+}
 
-				// Auto-Backfill ausführen (wenn konfiguriert)
-				config := s.service.GetConfig()
-				if config.AutoBackfill {
-					ctx2, cancel2 := context.WithTimeout(context.Background(), 30*time.Minute)
-					s.service.AutoBackfill(ctx2)
-					cancel2()
-				}
+// loadStates lädt den gespeicherten Zustand aller Quellen
+func (s *Scheduler) loadStates() {
+	states, err := s.service.repo.GetScheduleStates()
+	if err != nil {
+		log.Printf("Observer Scheduler: Zustand laden fehlgeschlagen: %v", err)
+		return
+	}
+
+	s.mu.Lock()
+	s.states = states
+	s.mu.Unlock()
+}
 
-				// Nächsten Lauf berechnen
-				s.mu.Lock()
-				s.nextRun = s.calculateNextRun()
-				s.mu.Unlock()
+// syncEntries gleicht die Zeitpläne mit den zu sammelnden Quellen ab (mu muss gehalten werden)
+func (s *Scheduler) syncEntries(now time.Time) {
+	wanted := make(map[string]bool, len(s.sources))
+	for _, code := range s.sources {
+		wanted[code] = true
 
-				log.Printf("Observer Scheduler: Nächster Lauf geplant für %s", s.nextRun.Format("2006-01-02 15:04"))
+		expr := s.schedules[code]
+		if expr == "" {
+			expr = dailyCron(s.collectTime)
+		}
+		if entry, ok := s.entries[code]; ok && entry.Expression == expr {
+			continue
+		}
+
+		schedule, err := ParseCron(expr)
+		if err != nil {
+			log.Printf("Observer Scheduler: %s: %v - verwende tägliche Sammelzeit", code, err)
+			expr = dailyCron(s.collectTime)
+			schedule, _ = ParseCron(expr)
+		}
+
+		entry := &scheduleEntry{schedule: schedule}
+		entry.SourceCode = code
+		entry.Expression = expr
+		if old, ok := s.entries[code]; ok {
+			entry.ScheduleState = old.ScheduleState
+		} else if state, ok := s.states[code]; ok {
+			entry.ScheduleState = state
+		}
+
+		entry.NextRun = schedule.Next(now)
+		// Verpasster Termin seit dem letzten Lauf (Rechner war aus oder im Standby): sofort nachholen
+		if entry.LastRunAt != nil {
+			if missed := schedule.Next(*entry.LastRunAt); !missed.IsZero() && !missed.After(now) {
+				entry.NextRun = now
+				entry.CatchUp = true
 			}
 		}
+		s.entries[code] = entry
+	}
+
+	for code := range s.entries {
+		if !wanted[code] {
+			delete(s.entries, code)
+		}
 	}
 }
 
-// calculateNextRun berechnet den nächsten Laufzeitpunkt
-func (s *Scheduler) calculateNextRun() time.Time {
-	now := time.Now()
+// dueSources gibt die fälligen Quellen zurück (mu muss gehalten werden)
+func (s *Scheduler) dueSources(now time.Time) []string {
+	var due []string
+	for code, entry := range s.entries {
+		if entry.NextRun.IsZero() || now.Before(entry.NextRun) {
+			continue
+		}
+		// Im laufenden Betrieb verpasst (Standby): wird einmal nachgeholt, nicht für jeden Termin
+		if now.Sub(entry.NextRun) > catchUpTolerate {
+			entry.CatchUp = true
+		}
+		due = append(due, code)
+	}
+	sort.Strings(due)
+	return due
+}
 
-	// collectTime parsen (Format: "HH:MM")
-	parts := strings.Split(s.collectTime, ":")
-	hour := 6  // Default
-	minute := 0
+// tick führt alle fälligen Quellen in einem gemeinsamen Sammellauf aus
+func (s *Scheduler) tick(now time.Time) {
+	config := s.service.GetConfig()
 
-	if len(parts) >= 1 {
-		if h, err := strconv.Atoi(parts[0]); err == nil {
-			hour = h
+	s.mu.Lock()
+	s.applyConfig(config)
+	s.syncEntries(now)
+	due := s.dueSources(now)
+	var catchUp []string
+	for _, code := range due {
+		if s.entries[code].CatchUp {
+			catchUp = append(catchUp, code)
 		}
 	}
-	if len(parts) >= 2 {
-		if m, err := strconv.Atoi(parts[1]); err == nil {
-			minute = m
+	s.mu.Unlock()
+
+	if len(due) == 0 {
+		return
+	}
+	if len(catchUp) > 0 {
+		log.Printf("Observer Scheduler: Hole verpasste Läufe nach: %s", strings.Join(catchUp, ", "))
+	}
+	log.Printf("Observer Scheduler: Starte geplanten Sammellauf (%s)", strings.Join(due, ", "))
+
+	started := time.Now()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	run, sourceErrors, err := s.service.runSources(ctx, due)
+	cancel()
+
+	if err != nil {
+		log.Printf("Observer Scheduler: Fehler beim Sammellauf: %v", err)
+		sourceErrors = make(map[string]string, len(due))
+		for _, code := range due {
+			sourceErrors[code] = err.Error()
 		}
+	} else {
+		log.Printf("Observer Scheduler: Sammellauf %d abgeschlossen - %d Werte", run.ID, run.TotalRecords)
 	}
 
-	// Heutigen Laufzeitpunkt berechnen
-	nextRun := time.Date(now.Year(), now.Month(), now.Day(), hour, minute, 0, 0, now.Location())
+	finished := now.Add(time.Since(started))
+	s.complete(due, sourceErrors, finished)
 
-	// Wenn schon vorbei, dann morgen
-	if nextRun.Before(now) || nextRun.Equal(now) {
-		nextRun = nextRun.Add(24 * time.Hour)
+	// Auto-Backfill ausführen (wenn konfiguriert)
+	if config.AutoBackfill && finished.Sub(s.lastBackfill) >= backfillEvery {
+		s.lastBackfill = finished
+		ctx2, cancel2 := context.WithTimeout(context.Background(), 30*time.Minute)
+		s.service.AutoBackfill(ctx2)
+		cancel2()
 	}
 
-	// Wochenenden überspringen (optional - nur Werktage)
-	// Für Finanzdaten macht das Sinn, da an Wochenenden keine Updates kommen
-	for nextRun.Weekday() == time.Saturday || nextRun.Weekday() == time.Sunday {
-		nextRun = nextRun.Add(24 * time.Hour)
+	log.Printf("Observer Scheduler: Nächster Lauf geplant für %s", s.GetNextRun().Format("2006-01-02 15:04"))
+}
+
+// complete plant die nächsten Läufe nach einem Sammellauf: bei Fehlern Wiederholung mit Backoff
+func (s *Scheduler) complete(codes []string, sourceErrors map[string]string, now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, code := range codes {
+		entry, ok := s.entries[code]
+		if !ok {
+			continue
+		}
+
+		ranAt := now
+		entry.LastRunAt = &ranAt
+		entry.CatchUp = false
+		entry.Retry = false
+		regular := entry.schedule.Next(now)
+
+		if errMsg, failed := sourceErrors[code]; failed {
+			entry.LastError = errMsg
+			entry.Attempts++
+			entry.NextRun = regular
+			if entry.Attempts <= s.maxRetries {
+				if retry := now.Add(retryDelay(entry.Attempts)); regular.IsZero() || retry.Before(regular) {
+					entry.NextRun = retry
+					entry.Retry = true
+				}
+				log.Printf("Observer Scheduler: %s fehlgeschlagen (Versuch %d/%d), nächster Versuch %s",
+					code, entry.Attempts, s.maxRetries+1, entry.NextRun.Format("15:04"))
+			} else {
+				log.Printf("Observer Scheduler: %s nach %d Versuchen aufgegeben: %s", code, entry.Attempts, errMsg)
+				entry.Attempts = 0
+			}
+		} else {
+			entry.LastSuccessAt = &ranAt
+			entry.LastError = ""
+			entry.Attempts = 0
+			entry.NextRun = regular
+		}
+
+		s.states[code] = entry.ScheduleState
+		if err := s.service.repo.SaveScheduleState(entry.ScheduleState); err != nil {
+			log.Printf("Observer Scheduler: %v", err)
+		}
 	}
+}
 
-	return nextRun
+// retryDelay berechnet die Wartezeit vor dem n-ten Wiederholungsversuch (exponentiell)
+func retryDelay(attempt int) time.Duration {
+	delay := retryBaseDelay
+	for i := 1; i < attempt && delay < retryMaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, retryMaxDelay)
 }
 
 // RunManualBackfill führt einen manuellen Backfill aus
@@ -0,0 +1,148 @@
+package observer
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// TestCronSchedule prüft Parser und Berechnung des nächsten Termins
+func TestCronSchedule(t *testing.T) {
+	// 18.10.2024 ist ein Freitag
+	from := time.Date(2024, 10, 18, 6, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		expr string
+		want time.Time
+	}{
+		{"0 * * * *", time.Date(2024, 10, 18, 7, 0, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2024, 10, 18, 7, 0, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2024, 10, 18, 6, 45, 0, 0, time.UTC)},
+		{"0 6 * * 1-5", time.Date(2024, 10, 21, 6, 0, 0, 0, time.UTC)},
+		{"30 16 * * MON-FRI", time.Date(2024, 10, 18, 16, 30, 0, 0, time.UTC)},
+		{"0 9 * * 0", time.Date(2024, 10, 20, 9, 0, 0, 0, time.UTC)},
+		{"0 9 * * 7", time.Date(2024, 10, 20, 9, 0, 0, 0, time.UTC)},
+		{"0 8 1 * *", time.Date(2024, 11, 1, 8, 0, 0, 0, time.UTC)},
+		{"0 8 1,15 JAN,JUL *", time.Date(2025, 1, 1, 8, 0, 0, 0, time.UTC)},
+		// Tag und Wochentag eingeschränkt: einer von beiden genügt
+		{"0 0 25 * 6", time.Date(2024, 10, 19, 0, 0, 0, 0, time.UTC)},
+		{"0 0 30 2 *", time.Time{}},
+	}
+	for _, tt := range tests {
+		schedule, err := ParseCron(tt.expr)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.expr, err)
+		}
+		if got := schedule.Next(from); !got.Equal(tt.want) {
+			t.Errorf("%s: nächster Termin %s, erwartet %s", tt.expr, got, tt.want)
+		}
+	}
+
+	for _, invalid := range []string{"", "* * * *", "60 * * * *", "0 24 * * *", "0 6 * * 1-8", "*/0 * * * *", "5-1 * * * *", "@yearly"} {
+		if _, err := ParseCron(invalid); err == nil {
+			t.Errorf("%q nicht als ungültig erkannt", invalid)
+		}
+	}
+	if dailyCron("07:45") != "45 7 * * 1-5" {
+		t.Errorf("Tägliche Sammelzeit: %s", dailyCron("07:45"))
+	}
+}
+
+// flakyCollector schlägt die ersten failures Aufrufe fehl
+type flakyCollector struct {
+	BaseCollector
+	failures int
+	calls    int
+}
+
+func (c *flakyCollector) IsAvailable(ctx context.Context) bool { return true }
+func (c *flakyCollector) GetSupportedIndicators() []string     { return nil }
+func (c *flakyCollector) CollectHistorical(ctx context.Context, ind Indicator, from, to time.Time) (*CollectorResult, error) {
+	return nil, nil
+}
+func (c *flakyCollector) Collect(ctx context.Context, indicators []Indicator) (*CollectorResult, error) {
+	c.calls++
+	if c.calls <= c.failures {
+		return nil, fmt.Errorf("Dienst nicht erreichbar")
+	}
+	return &CollectorResult{}, nil
+}
+
+// TestSchedulerRetryAndCatchUp prüft Zeitpläne je Quelle, Wiederholung mit Backoff und Nachholen nach Neustart
+func TestSchedulerRetryAndCatchUp(t *testing.T) {
+	service, err := NewService(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer service.Close()
+
+	ecb := &flakyCollector{BaseCollector: BaseCollector{SourceCode: "ECB"}, failures: 2}
+	estr := &flakyCollector{BaseCollector: BaseCollector{SourceCode: "ESTR"}}
+	service.registry.Register(ecb)
+	service.registry.Register(estr)
+
+	config := service.GetConfig()
+	config.AutoBackfill = false
+	config.ActiveSources = []string{"ECB", "ESTR"}
+	config.Schedules = map[string]string{"ESTR": "0 * * * *"}
+	config.MaxRetries = 1
+	if err := service.SetConfig(config); err != nil {
+		t.Fatal(err)
+	}
+	scheduler := service.scheduler
+
+	// Freitag 06:00: beide Quellen fällig (ECB täglich, ESTR stündlich)
+	now := time.Date(2024, 10, 18, 6, 0, 0, 0, time.Local)
+	scheduler.mu.Lock()
+	scheduler.entries = make(map[string]*scheduleEntry)
+	scheduler.syncEntries(now.Add(-time.Minute))
+	scheduler.mu.Unlock()
+
+	scheduler.tick(now)
+	if ecb.calls != 1 || estr.calls != 1 {
+		t.Fatalf("Aufrufe ECB %d, ESTR %d", ecb.calls, estr.calls)
+	}
+	schedule := scheduler.GetSchedule()
+	if len(schedule) != 2 || schedule[0].SourceCode != "ECB" || !schedule[0].Retry || schedule[0].Attempts != 1 ||
+		schedule[0].NextRun.Sub(now).Round(time.Second) != retryBaseDelay || schedule[0].LastError == "" {
+		t.Fatalf("Wiederholung: %+v", schedule)
+	}
+	if schedule[1].SourceCode != "ESTR" || !schedule[1].NextRun.Equal(now.Add(time.Hour)) || schedule[1].LastSuccessAt == nil {
+		t.Errorf("ESTR: %+v", schedule[1])
+	}
+
+	// Zweiter Fehlschlag: Wiederholungen erschöpft, weiter nach Zeitplan am Montag
+	scheduler.tick(now.Add(retryBaseDelay + time.Minute))
+	if ecb.calls != 2 || estr.calls != 1 {
+		t.Fatalf("Aufrufe ECB %d, ESTR %d", ecb.calls, estr.calls)
+	}
+	entry := scheduler.GetSchedule()[1]
+	if entry.SourceCode != "ECB" || entry.Retry || entry.Attempts != 0 || !entry.NextRun.Equal(time.Date(2024, 10, 21, 6, 0, 0, 0, time.Local)) {
+		t.Errorf("Nach Aufgabe: %+v", entry)
+	}
+
+	// Neustart am Montag 09:30: verpasster ECB-Lauf (06:00) wird sofort nachgeholt
+	restarted := NewScheduler(service)
+	restarted.loadStates()
+	monday := time.Date(2024, 10, 21, 9, 30, 0, 0, time.Local)
+	restarted.mu.Lock()
+	restarted.syncEntries(monday)
+	restarted.mu.Unlock()
+	for _, entry := range restarted.GetSchedule() {
+		if !entry.CatchUp || !entry.NextRun.Equal(monday) {
+			t.Errorf("Nachholen %s: %+v", entry.SourceCode, entry)
+		}
+	}
+	restarted.tick(monday)
+	if ecb.calls != 3 || estr.calls != 2 {
+		t.Errorf("Nachgeholt: ECB %d, ESTR %d", ecb.calls, estr.calls)
+	}
+	if entry := restarted.GetSchedule()[0]; entry.SourceCode != "ESTR" || entry.CatchUp || !entry.NextRun.Equal(monday.Add(30*time.Minute)) {
+		t.Errorf("Nach Nachholen: %+v", entry)
+	}
+
+	if err := (&ObserverConfig{Schedules: map[string]string{"ECB": "jede Stunde"}}).Validate(); err == nil {
+		t.Error("Ungültiger Cron-Ausdruck nicht erkannt")
+	}
+}
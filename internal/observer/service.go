@@ -3,8 +3,12 @@ package observer
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"slices"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 )
@@ -20,6 +24,13 @@ type ObserverConfig struct {
 	// Sammlung täglich um diese Uhrzeit (Format: "HH:MM")
 	DailyCollectionTime string `json:"dailyCollectionTime"`
 
+	// Sammelzeitpunkte je Quelle als Cron-Ausdruck (Source-Code → Ausdruck),
+	// Quellen ohne Eintrag werden werktags um DailyCollectionTime gesammelt
+	Schedules map[string]string `json:"schedules,omitempty"`
+
+	// Wiederholungen nach einem fehlgeschlagenen Lauf (mit exponentiellem Backoff)
+	MaxRetries int `json:"maxRetries"`
+
 	// Automatisches Backfill aktiviert
 	AutoBackfill bool `json:"autoBackfill"`
 
@@ -42,8 +53,12 @@ func DefaultConfig() *ObserverConfig {
 		Enabled:             false, // Muss explizit aktiviert werden
 		Strategy:            StrategyConservative,
 		DailyCollectionTime: "06:00",
-		AutoBackfill:        true,
-		MaxBackfillDays:     365,
+		Schedules: map[string]string{
+			"COINGECKO": "0 * * * *", // Krypto handelt rund um die Uhr
+		},
+		MaxRetries:      3,
+		AutoBackfill:    true,
+		MaxBackfillDays: 365,
 		Prompt: `Der Observer ist eine neutrale, unsichtbare Systeminstanz des Fleet Navigators.
 Seine Aufgabe besteht ausschließlich darin, relevante Finanz- und Wirtschaftsdaten
 regelmäßig zu beobachten, strukturiert zu erfassen und revisionssicher abzulegen.
@@ -60,6 +75,24 @@ Aktuelle Strategie: CONSERVATIVE
 	}
 }
 
+// Validate prüft die Konfiguration (Sammelzeit und Cron-Ausdrücke)
+func (c *ObserverConfig) Validate() error {
+	if c.DailyCollectionTime != "" {
+		if _, err := time.Parse("15:04", c.DailyCollectionTime); err != nil {
+			return fmt.Errorf("Ungültige Sammelzeit %q (HH:MM)", c.DailyCollectionTime)
+		}
+	}
+	for code, expr := range c.Schedules {
+		if _, err := ParseCron(expr); err != nil {
+			return fmt.Errorf("Quelle %s: %w", code, err)
+		}
+	}
+	if c.MaxRetries < 0 || c.MaxRetries > 10 {
+		return fmt.Errorf("maxRetries muss zwischen 0 und 10 liegen")
+	}
+	return nil
+}
+
 // Service ist der Haupt-Service für den Observer
 type Service struct {
 	repo      *Repository
@@ -69,6 +102,10 @@ type Service struct {
 	scheduler *Scheduler
 	running   bool
 	runMu     sync.Mutex
+
+	// Zustellung von Alarmen, wird vom Navigator gesetzt (nil = Kanal nicht verfügbar)
+	OnAlertChat      func(chatID int64, text string) error
+	OnAlertBroadcast func(event AlertEvent)
 }
 
 // NewService erstellt einen neuen Observer-Service
@@ -100,6 +137,9 @@ func NewService(dataDir string) (*Service, error) {
 		config:   DefaultConfig(),
 	}
 
+	// Konfigurierte Quellen (SourceDefinition) als Collectors registrieren
+	service.registerConfiguredSources()
+
 	// Scheduler erstellen
 	service.scheduler = NewScheduler(service)
 
@@ -183,7 +223,8 @@ func (s *Service) RunNow(ctx context.Context) (*ObservationRun, error) {
 		s.runMu.Unlock()
 	}()
 
-	return s.collect(ctx, false, nil, nil)
+	run, _, err := s.collect(ctx, false, nil, nil, nil)
+	return run, err
 }
 
 // Backfill führt einen Backfill für fehlende Daten aus
@@ -202,11 +243,45 @@ func (s *Service) Backfill(ctx context.Context, from, to time.Time) (*Observatio
 		s.runMu.Unlock()
 	}()
 
-	return s.collect(ctx, true, &from, &to)
+	run, _, err := s.collect(ctx, true, &from, &to, nil)
+	return run, err
+}
+
+// runSources führt einen Sammellauf nur für die angegebenen Quellen aus (Scheduler)
+// und gibt zusätzlich die Fehler je Quelle zurück
+func (s *Service) runSources(ctx context.Context, sourceCodes []string) (*ObservationRun, map[string]string, error) {
+	s.runMu.Lock()
+	if s.running {
+		s.runMu.Unlock()
+		return nil, nil, fmt.Errorf("Sammellauf läuft bereits")
+	}
+	s.running = true
+	s.runMu.Unlock()
+
+	defer func() {
+		s.runMu.Lock()
+		s.running = false
+		s.runMu.Unlock()
+	}()
+
+	return s.collect(ctx, false, nil, nil, sourceCodes)
+}
+
+// scheduledSources gibt die Codes der Quellen zurück, die der Scheduler sammeln soll
+func (s *Service) scheduledSources(config *ObserverConfig) []string {
+	codes := append([]string(nil), config.ActiveSources...)
+	if len(codes) == 0 {
+		for _, collector := range s.registry.GetAll() {
+			codes = append(codes, collector.GetSourceCode())
+		}
+	}
+	sort.Strings(codes)
+	return codes
 }
 
 // collect ist die interne Sammelfunktion
-func (s *Service) collect(ctx context.Context, isBackfill bool, from, to *time.Time) (*ObservationRun, error) {
+// onlySources schränkt den Lauf auf diese Quellen ein (nil = alle aktiven), zurückgegeben werden die Fehler je Quelle
+func (s *Service) collect(ctx context.Context, isBackfill bool, from, to *time.Time, onlySources []string) (*ObservationRun, map[string]string, error) {
 	s.configMu.RLock()
 	config := *s.config
 	s.configMu.RUnlock()
@@ -226,7 +301,7 @@ func (s *Service) collect(ctx context.Context, isBackfill bool, from, to *time.T
 	}
 
 	if err := s.repo.CreateRun(run); err != nil {
-		return nil, fmt.Errorf("Run erstellen fehlgeschlagen: %w", err)
+		return nil, nil, fmt.Errorf("Run erstellen fehlgeschlagen: %w", err)
 	}
 
 	log.Printf("Observer: Sammellauf %d gestartet (Backfill: %v)", run.ID, isBackfill)
@@ -237,7 +312,7 @@ func (s *Service) collect(ctx context.Context, isBackfill bool, from, to *time.T
 		run.Status = RunStatusFailed
 		run.ErrorMessages = fmt.Sprintf("Indikatoren laden fehlgeschlagen: %v", err)
 		s.repo.UpdateRun(run)
-		return run, err
+		return run, nil, err
 	}
 
 	// Nach Quelle gruppieren
@@ -252,7 +327,7 @@ func (s *Service) collect(ctx context.Context, isBackfill bool, from, to *time.T
 		run.Status = RunStatusFailed
 		run.ErrorMessages = fmt.Sprintf("Quellen laden fehlgeschlagen: %v", err)
 		s.repo.UpdateRun(run)
-		return run, err
+		return run, nil, err
 	}
 
 	sourceMap := make(map[int64]*DataSource)
@@ -262,7 +337,9 @@ func (s *Service) collect(ctx context.Context, isBackfill bool, from, to *time.T
 
 	// Sammeln pro Quelle
 	var errors []string
+	sourceErrors := make(map[string]string)
 	totalValues := 0
+	totalRevisions := 0
 
 	for sourceID, sourceIndicators := range indicatorsBySource {
 		source := sourceMap[sourceID]
@@ -270,6 +347,10 @@ func (s *Service) collect(ctx context.Context, isBackfill bool, from, to *time.T
 			continue
 		}
 
+		if onlySources != nil && !slices.Contains(onlySources, source.Code) {
+			continue
+		}
+
 		// Collector für diese Quelle holen
 		collector := s.registry.Get(source.Code)
 		if collector == nil {
@@ -301,22 +382,21 @@ func (s *Service) collect(ctx context.Context, isBackfill bool, from, to *time.T
 				result, err = collector.CollectHistorical(ctx, ind, *from, *to)
 				if err != nil {
 					errors = append(errors, fmt.Sprintf("%s/%s: %v", source.Code, ind.Code, err))
+					sourceErrors[source.Code] = err.Error()
 					continue
 				}
 				if result != nil && len(result.Values) > 0 {
-					// Werte speichern (mit Duplikat-Check)
-					for _, val := range result.Values {
-						val.RunID = run.ID
-						// Prüfen ob bereits vorhanden
-						exists, _ := s.repo.HasValueForDate(val.IndicatorID, val.ObservedAt)
-						if !exists {
-							if err := s.repo.CreateValue(&val); err != nil {
-								log.Printf("Observer: Wert speichern fehlgeschlagen: %v", err)
-							} else {
-								totalValues++
-							}
-						}
+					// Werte speichern (Duplikate überspringen, geänderte Werte als Revision)
+					for i := range result.Values {
+						result.Values[i].RunID = run.ID
+					}
+					added, revised, err := s.repo.StoreValues(result.Values)
+					if err != nil {
+						log.Printf("Observer: Werte speichern fehlgeschlagen: %v", err)
+						continue
 					}
+					totalValues += added + revised
+					totalRevisions += revised
 				}
 			}
 		} else {
@@ -324,6 +404,7 @@ func (s *Service) collect(ctx context.Context, isBackfill bool, from, to *time.T
 			result, err = collector.Collect(ctx, sourceIndicators)
 			if err != nil {
 				errors = append(errors, fmt.Sprintf("%s: %v", source.Code, err))
+				sourceErrors[source.Code] = err.Error()
 				continue
 			}
 			if result != nil && len(result.Values) > 0 {
@@ -331,10 +412,13 @@ func (s *Service) collect(ctx context.Context, isBackfill bool, from, to *time.T
 				for i := range result.Values {
 					result.Values[i].RunID = run.ID
 				}
-				if err := s.repo.CreateValues(result.Values); err != nil {
+				added, revised, err := s.repo.StoreValues(result.Values)
+				if err != nil {
 					errors = append(errors, fmt.Sprintf("%s: Speichern fehlgeschlagen: %v", source.Code, err))
+					sourceErrors[source.Code] = fmt.Sprintf("Speichern fehlgeschlagen: %v", err)
 				} else {
-					totalValues += len(result.Values)
+					totalValues += added + revised
+					totalRevisions += revised
 				}
 			}
 		}
@@ -360,10 +444,13 @@ func (s *Service) collect(ctx context.Context, isBackfill bool, from, to *time.T
 
 	s.repo.UpdateRun(run)
 
-	log.Printf("Observer: Sammellauf %d beendet - %d Werte, %d Fehler, Status: %s",
-		run.ID, totalValues, len(errors), run.Status)
+	log.Printf("Observer: Sammellauf %d beendet - %d Werte (%d Revisionen), %d Fehler, Status: %s",
+		run.ID, totalValues, totalRevisions, len(errors), run.Status)
 
-	return run, nil
+	// Alarm-Regeln gegen die neuen Werte prüfen
+	s.evaluateAlerts(ctx, run)
+
+	return run, sourceErrors, nil
 }
 
 // AutoBackfill prüft und füllt fehlende Daten automatisch
@@ -428,18 +515,16 @@ func (s *Service) AutoBackfill(ctx context.Context) error {
 		}
 
 		if result != nil && len(result.Values) > 0 {
-			// Werte speichern (mit Duplikat-Check)
-			added := 0
-			for _, val := range result.Values {
-				exists, _ := s.repo.HasValueForDate(val.IndicatorID, val.ObservedAt)
-				if !exists {
-					val.RunID = 0 // Kein Run für Auto-Backfill
-					if err := s.repo.CreateValue(&val); err == nil {
-						added++
-					}
-				}
+			// Werte speichern (Duplikate überspringen, geänderte Werte als Revision)
+			for i := range result.Values {
+				result.Values[i].RunID = 0 // Kein Run für Auto-Backfill
+			}
+			added, revised, err := s.repo.StoreValues(result.Values)
+			if err != nil {
+				log.Printf("Observer: Werte für %s speichern fehlgeschlagen: %v", ind.Code, err)
+				continue
 			}
-			log.Printf("Observer: %d neue Werte für %s hinzugefügt", added, ind.Code)
+			log.Printf("Observer: %d neue Werte, %d Revisionen für %s", added, revised, ind.Code)
 		}
 	}
 
@@ -521,3 +606,196 @@ func (s *Service) GetCollectorStatus(ctx context.Context) map[string]bool {
 	}
 	return result
 }
+
+// === Konfigurierte Quellen ===
+
+// ErrAlreadyExists wird zurückgegeben wenn Code einer Quelle oder eines Indikators vergeben ist
+var ErrAlreadyExists = errors.New("Code bereits vergeben")
+
+// ErrNotFound wird zurückgegeben wenn ein angefragter Indikator nicht existiert
+var ErrNotFound = errors.New("nicht gefunden")
+
+// registerConfiguredSources registriert einen GenericCollector für jede konfigurierte Quelle
+func (s *Service) registerConfiguredSources() {
+	sources, err := s.repo.GetAllSources(false)
+	if err != nil {
+		log.Printf("Observer: Konfigurierte Quellen laden fehlgeschlagen: %v", err)
+		return
+	}
+
+	for _, source := range sources {
+		if source.Definition == nil {
+			continue
+		}
+		s.registerConfiguredSource(source)
+		// Selbst angelegte Quellen sind aktiv, solange die Quelle selbst aktiv ist
+		if source.Active {
+			s.config.ActiveSources = append(s.config.ActiveSources, source.Code)
+		}
+	}
+}
+
+// registerConfiguredSource registriert den Collector einer konfigurierten Quelle mit ihren Indikatoren
+func (s *Service) registerConfiguredSource(source DataSource) {
+	collector := NewGenericCollector(source)
+
+	indicators, err := s.repo.GetIndicatorsBySource(source.ID)
+	if err == nil {
+		codes := make([]string, 0, len(indicators))
+		for _, ind := range indicators {
+			codes = append(codes, ind.Code)
+		}
+		collector.SetSupportedIndicators(codes)
+	}
+
+	s.registry.Register(collector)
+}
+
+// normalizeCode prüft einen Quellen-/Indikator-Code (Großbuchstaben, Ziffern, Unterstrich)
+func normalizeCode(code string) (string, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if code == "" || len(code) > 64 {
+		return "", fmt.Errorf("Code muss 1-64 Zeichen lang sein")
+	}
+	for _, c := range code {
+		if (c < 'A' || c > 'Z') && (c < '0' || c > '9') && c != '_' {
+			return "", fmt.Errorf("ungültiger Code %q (erlaubt: A-Z, 0-9, _)", code)
+		}
+	}
+	return code, nil
+}
+
+// CreateSource legt eine konfigurierte Quelle an und registriert ihren Collector
+func (s *Service) CreateSource(source *DataSource) error {
+	code, err := normalizeCode(source.Code)
+	if err != nil {
+		return err
+	}
+	source.Code = code
+
+	if strings.TrimSpace(source.Name) == "" {
+		return fmt.Errorf("Name fehlt")
+	}
+	if source.Definition == nil {
+		return fmt.Errorf("Definition fehlt")
+	}
+	if err := source.Definition.Validate(); err != nil {
+		return err
+	}
+
+	switch source.SourceClass {
+	case "":
+		source.SourceClass = SourceClassOfficial
+	case SourceClassOfficial, SourceClassSemiOfficial, SourceClassCommercial:
+	default:
+		return fmt.Errorf("ungültige Quellenklasse: %s", source.SourceClass)
+	}
+	if source.URL == "" {
+		source.URL, _ = source.Definition.baseURL()
+	}
+
+	existing, err := s.repo.GetSourceByCode(code)
+	if err != nil {
+		return err
+	}
+	if existing != nil || s.registry.Get(code) != nil {
+		return fmt.Errorf("Quelle %s: %w", code, ErrAlreadyExists)
+	}
+
+	source.Active = true
+	if err := s.repo.CreateSource(source); err != nil {
+		return err
+	}
+	s.registerConfiguredSource(*source)
+
+	// Bei eingeschränkten aktiven Quellen die neue Quelle ergänzen
+	s.configMu.Lock()
+	if len(s.config.ActiveSources) > 0 {
+		s.config.ActiveSources = append(s.config.ActiveSources, code)
+	}
+	s.configMu.Unlock()
+
+	log.Printf("Observer: Quelle %s angelegt (%s, %s)", code, source.Definition.Format, source.Definition.URLTemplate)
+	return nil
+}
+
+// CreateIndicator legt einen Indikator für eine konfigurierte Quelle an
+func (s *Service) CreateIndicator(ind *Indicator, sourceCode string) error {
+	code, err := normalizeCode(ind.Code)
+	if err != nil {
+		return err
+	}
+	ind.Code = code
+
+	if strings.TrimSpace(ind.Name) == "" {
+		return fmt.Errorf("Name fehlt")
+	}
+	if ind.Category == "" {
+		return fmt.Errorf("Kategorie fehlt")
+	}
+
+	source, err := s.repo.GetSourceByCode(strings.ToUpper(sourceCode))
+	if err != nil {
+		return err
+	}
+	if source == nil {
+		return fmt.Errorf("Quelle nicht gefunden: %s", sourceCode)
+	}
+	if source.Definition == nil {
+		return fmt.Errorf("Quelle %s hat einen eingebauten Collector - Indikatoren nur für konfigurierte Quellen", source.Code)
+	}
+
+	ind.Frequency = strings.ToUpper(strings.TrimSpace(ind.Frequency))
+	if ind.Frequency == "" {
+		ind.Frequency = source.Definition.Frequency
+	}
+	if ind.Frequency == "" {
+		ind.Frequency = "D"
+	}
+	if !isValidFrequency(ind.Frequency) {
+		return fmt.Errorf("ungültige Frequenz: %s (erlaubt: D, W, M, Q, A)", ind.Frequency)
+	}
+
+	existing, err := s.repo.GetIndicatorByCode(code)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		return fmt.Errorf("Indikator %s: %w", code, ErrAlreadyExists)
+	}
+
+	ind.SourceID = source.ID
+	ind.Active = true
+	if err := s.repo.CreateIndicator(ind); err != nil {
+		return err
+	}
+	s.registerConfiguredSource(*source)
+
+	log.Printf("Observer: Indikator %s für Quelle %s angelegt", code, source.Code)
+	return nil
+}
+
+// PreviewSource ruft eine Definition probeweise ab, ohne etwas zu speichern
+func (s *Service) PreviewSource(ctx context.Context, def SourceDefinition, key, frequency string) ([]IndicatorValue, error) {
+	if err := def.Validate(); err != nil {
+		return nil, err
+	}
+	frequency = strings.ToUpper(frequency)
+	if frequency == "" {
+		frequency = def.Frequency
+	}
+
+	collector := NewGenericCollector(DataSource{Code: "PREVIEW", Definition: &def})
+	now := time.Now()
+	values, err := collector.fetchIndicator(ctx, Indicator{ExternalCode: key, Frequency: frequency},
+		collectWindow(frequency, now).AddDate(-1, 0, 0), now)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]IndicatorValue, len(values))
+	for i, v := range values {
+		result[i] = IndicatorValue{Date: v.ObservedAt, Value: v.Value}
+	}
+	return result, nil
+}
@@ -0,0 +1,456 @@
+package observer
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"fleet-navigator/internal/tools"
+)
+
+// Observer-Daten als Tools: ein tool-fähiges Modell fragt gezielt die benötigten
+// Reihen ab, statt dass die komplette Marktübersicht in den Kontext injiziert wird.
+// Alle Tools lesen über den Service aus dem Repository (observer.db).
+
+const (
+	defaultToolHistoryPoints = 60
+	maxToolHistoryPoints     = 500
+)
+
+// NewTools erstellt alle Observer-Tools für die Tool-Registry
+func NewTools(service *Service) []tools.Tool {
+	return []tools.Tool{
+		NewLatestValueTool(service),
+		NewHistoryTool(service),
+		NewSimulationTool(service),
+	}
+}
+
+// LatestValue ist ein aktueller Wert im Tool-Ergebnis
+type LatestValue struct {
+	Code       string    `json:"code"`
+	Name       string    `json:"name"`
+	Category   string    `json:"category"`
+	Unit       string    `json:"unit"`
+	Value      float64   `json:"value"`
+	Display    string    `json:"display"`
+	ObservedAt time.Time `json:"observedAt"`
+	Stale      bool      `json:"stale,omitempty"` // Stichtag älter als für die Frequenz erwartet
+}
+
+// LatestValueTool liefert die neuesten Werte ausgewählter Indikatoren
+type LatestValueTool struct {
+	tools.BaseTool
+	service *Service
+}
+
+// NewLatestValueTool erstellt das Tool für aktuelle Werte
+func NewLatestValueTool(service *Service) *LatestValueTool {
+	return &LatestValueTool{
+		BaseTool: tools.NewBaseTool("observer_latest", tools.ToolTypeFinance,
+			"Liefert die neuesten Werte von Finanz- und Wirtschaftsindikatoren (Zinsen, Inflation, Indizes, Rohstoffe, Krypto) mit Stichtag. Ohne Angabe von indicators werden alle verfügbaren Indikatoren mit ihren Codes geliefert.",
+			map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"indicators": map[string]interface{}{
+						"type":        "array",
+						"items":       map[string]interface{}{"type": "string"},
+						"description": "Indikator-Codes, z.B. [\"ECB_MAIN_RATE\", \"HICP_DE\", \"DAX\"] (leer = alle)",
+					},
+					"category": map[string]interface{}{
+						"type":        "string",
+						"enum":        []string{string(CategoryInterestRate), string(CategoryInflation), string(CategoryEmployment), string(CategoryGDP), string(CategoryExchange), string(CategoryStocks), string(CategoryCommodities), string(CategoryRealEstate), string(CategoryCrypto)},
+						"description": "Nur Indikatoren dieser Kategorie (nur ohne indicators)",
+					},
+				},
+			}),
+		service: service,
+	}
+}
+
+func (t *LatestValueTool) RequiresMate() bool {
+	return false
+}
+
+func (t *LatestValueTool) Execute(ctx context.Context, params map[string]interface{}) (*tools.ToolResult, error) {
+	codes, err := stringListParam(params, "indicators")
+	if err != nil {
+		return nil, tools.NewToolError(t.Name(), err.Error(), nil)
+	}
+	category, _ := params["category"].(string)
+
+	indicators, err := t.service.GetIndicators(true)
+	if err != nil {
+		return toolFailure(fmt.Errorf("Indikatoren laden fehlgeschlagen: %w", err)), nil
+	}
+	byCode := make(map[string]Indicator, len(indicators))
+	for _, ind := range indicators {
+		byCode[ind.Code] = ind
+	}
+
+	if len(codes) == 0 {
+		for _, ind := range indicators {
+			if category == "" || strings.EqualFold(string(ind.Category), category) {
+				codes = append(codes, ind.Code)
+			}
+		}
+		sort.Strings(codes)
+	}
+
+	now := time.Now()
+	result := make([]LatestValue, 0, len(codes))
+	var unknown []string
+	for _, code := range codes {
+		ind, ok := byCode[code]
+		if !ok {
+			unknown = append(unknown, code)
+			continue
+		}
+		val, err := t.service.repo.GetLatestValue(ind.ID)
+		if err != nil || val == nil {
+			continue
+		}
+		result = append(result, LatestValue{
+			Code:       ind.Code,
+			Name:       ind.Name,
+			Category:   string(ind.Category),
+			Unit:       ind.Unit,
+			Value:      val.Value,
+			Display:    FormatValueForDisplay(val.Value, ind.Unit),
+			ObservedAt: val.ObservedAt,
+			Stale:      IsStale(ind.Frequency, val.ObservedAt, now),
+		})
+	}
+	if len(result) == 0 && len(unknown) > 0 {
+		return toolFailure(fmt.Errorf("Unbekannte Indikatoren: %s", strings.Join(unknown, ", "))), nil
+	}
+
+	data := map[string]interface{}{"values": result}
+	if len(unknown) > 0 {
+		data["unknown"] = unknown
+	}
+	return &tools.ToolResult{Success: true, Data: data, Source: "observer"}, nil
+}
+
+// HistoryTool liefert eine Zeitreihe über QuerySeries
+type HistoryTool struct {
+	tools.BaseTool
+	service *Service
+}
+
+// NewHistoryTool erstellt das Tool für historische Verläufe
+func NewHistoryTool(service *Service) *HistoryTool {
+	return &HistoryTool{
+		BaseTool: tools.NewBaseTool("observer_history", tools.ToolTypeFinance,
+			"Liefert den historischen Verlauf eines Indikators in einem Zeitraum, optional verdichtet (Woche/Monat/Quartal/Jahr), als Veränderung, Vorjahresvergleich oder Spread zu einem zweiten Indikator.",
+			map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"indicator": map[string]interface{}{
+						"type":        "string",
+						"description": "Indikator-Code, z.B. DE_10Y_YIELD (siehe observer_latest)",
+					},
+					"minus": map[string]interface{}{
+						"type":        "string",
+						"description": "Optional: zweiter Indikator für den Spread indicator minus minus, z.B. ESTR",
+					},
+					"from": map[string]interface{}{
+						"type":        "string",
+						"description": "Startdatum im Format YYYY-MM-DD",
+					},
+					"to": map[string]interface{}{
+						"type":        "string",
+						"description": "Enddatum im Format YYYY-MM-DD (einschließlich)",
+					},
+					"frequency": map[string]interface{}{
+						"type":        "string",
+						"enum":        []string{"D", "W", "M", "Q", "A"},
+						"description": "Verdichtung auf Woche, Monat, Quartal oder Jahr (D = Rohdaten)",
+					},
+					"aggregation": map[string]interface{}{
+						"type":        "string",
+						"enum":        []string{AggLast, AggMean, AggMin, AggMax},
+						"description": "Wert je Periode bei frequency (default: last)",
+					},
+					"transform": map[string]interface{}{
+						"type":        "string",
+						"enum":        []string{TransformChange, TransformChangePct, TransformYoY, TransformMA},
+						"description": "Abgeleitete Reihe: Veränderung, Veränderung in %, Vorjahresvergleich oder gleitender Durchschnitt",
+					},
+					"window": map[string]interface{}{
+						"type":        "integer",
+						"description": "Perioden für den gleitenden Durchschnitt (default: 20)",
+					},
+					"maxPoints": map[string]interface{}{
+						"type":        "integer",
+						"description": fmt.Sprintf("Maximale Anzahl der neuesten Punkte (default: %d)", defaultToolHistoryPoints),
+						"default":     defaultToolHistoryPoints,
+					},
+				},
+				"required": []string{"indicator"},
+			}),
+		service: service,
+	}
+}
+
+func (t *HistoryTool) RequiresMate() bool {
+	return false
+}
+
+func (t *HistoryTool) Execute(ctx context.Context, params map[string]interface{}) (*tools.ToolResult, error) {
+	indicator, _ := params["indicator"].(string)
+	if indicator == "" {
+		return nil, tools.NewToolError(t.Name(), "indicator parameter is required", nil)
+	}
+
+	q := SeriesQuery{Indicator: indicator}
+	q.Minus, _ = params["minus"].(string)
+	q.Frequency, _ = params["frequency"].(string)
+	q.Aggregation, _ = params["aggregation"].(string)
+	q.Transform, _ = params["transform"].(string)
+	if w, ok := params["window"].(float64); ok {
+		q.Window = int(w)
+	}
+
+	from, err := dateParam(params, "from")
+	if err != nil {
+		return nil, tools.NewToolError(t.Name(), err.Error(), nil)
+	}
+	to, err := dateParam(params, "to")
+	if err != nil {
+		return nil, tools.NewToolError(t.Name(), err.Error(), nil)
+	}
+	if to != nil {
+		// Enddatum einschließlich
+		end := to.Add(24*time.Hour - time.Nanosecond)
+		to = &end
+	}
+	q.From, q.To = from, to
+
+	maxPoints := defaultToolHistoryPoints
+	if mp, ok := params["maxPoints"].(float64); ok && mp > 0 {
+		maxPoints = min(int(mp), maxToolHistoryPoints)
+	}
+
+	result, err := t.service.QuerySeries(q)
+	if err != nil {
+		return toolFailure(err), nil
+	}
+
+	// Nur die neuesten Punkte zurückgeben, damit der Kontext nicht überläuft
+	total := len(result.Points)
+	if total > maxPoints {
+		result.Points = result.Points[total-maxPoints:]
+	}
+
+	return &tools.ToolResult{
+		Success: true,
+		Data: map[string]interface{}{
+			"series":      result,
+			"totalPoints": total,
+			"truncated":   total > maxPoints,
+		},
+		Source: "observer",
+	}, nil
+}
+
+// SimulationTool führt historische Simulationen (Einzelwert oder Portfolio) aus
+type SimulationTool struct {
+	tools.BaseTool
+	service *Service
+}
+
+// NewSimulationTool erstellt das Tool für historische Simulationen
+func NewSimulationTool(service *Service) *SimulationTool {
+	return &SimulationTool{
+		BaseTool: tools.NewBaseTool("observer_simulate", tools.ToolTypeFinance,
+			"Berechnet, was aus einer Anlage in der Vergangenheit geworden wäre - Einmalanlage in einen Indikator oder Portfolio mit Sparplan, Rebalancing, Kosten, Steuer und Inflationsbereinigung. Der Disclaimer im Ergebnis muss in der Antwort genannt werden.",
+			map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"indicator": map[string]interface{}{
+						"type":        "string",
+						"description": "Indikator-Code für eine Einmalanlage, z.B. GOLD_EUR (alternativ assets)",
+					},
+					"assets": map[string]interface{}{
+						"type": "array",
+						"items": map[string]interface{}{
+							"type": "object",
+							"properties": map[string]interface{}{
+								"indicator": map[string]interface{}{"type": "string"},
+								"weight":    map[string]interface{}{"type": "number"},
+							},
+							"required": []string{"indicator", "weight"},
+						},
+						"description": "Portfolio: Indikatoren mit Gewichten, z.B. [{\"indicator\": \"DAX\", \"weight\": 60}, {\"indicator\": \"GOLD_EUR\", \"weight\": 40}]",
+					},
+					"amount": map[string]interface{}{
+						"type":        "number",
+						"description": "Einmalanlage in EUR",
+					},
+					"monthlyContribution": map[string]interface{}{
+						"type":        "number",
+						"description": "Sparrate je Monat in EUR",
+					},
+					"period": map[string]interface{}{
+						"type":        "string",
+						"enum":        []string{string(Period1Month), string(Period3Months), string(Period6Months), string(Period1Year), string(Period2Years), string(Period5Years)},
+						"description": "Zeitraum bis heute (default: 1Y), alternativ from/to",
+					},
+					"from": map[string]interface{}{
+						"type":        "string",
+						"description": "Startdatum im Format YYYY-MM-DD",
+					},
+					"to": map[string]interface{}{
+						"type":        "string",
+						"description": "Enddatum im Format YYYY-MM-DD",
+					},
+					"rebalance": map[string]interface{}{
+						"type":        "string",
+						"enum":        []string{string(RebalanceNone), string(RebalanceMonthly), string(RebalanceQuarterly), string(RebalanceYearly)},
+						"description": "Rebalancing-Intervall für Portfolios (default: none)",
+					},
+					"applyTax": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Abgeltungssteuer auf realisierte Gewinne berücksichtigen",
+					},
+				},
+			}),
+		service: service,
+	}
+}
+
+func (t *SimulationTool) RequiresMate() bool {
+	return false
+}
+
+func (t *SimulationTool) Execute(ctx context.Context, params map[string]interface{}) (*tools.ToolResult, error) {
+	indicator, _ := params["indicator"].(string)
+	amount, _ := params["amount"].(float64)
+	monthly, _ := params["monthlyContribution"].(float64)
+	applyTax, _ := params["applyTax"].(bool)
+	rebalance, _ := params["rebalance"].(string)
+
+	var assets []PortfolioAsset
+	if raw, ok := params["assets"].([]interface{}); ok {
+		for _, item := range raw {
+			entry, ok := item.(map[string]interface{})
+			if !ok {
+				return nil, tools.NewToolError(t.Name(), "assets must be a list of objects", nil)
+			}
+			code, _ := entry["indicator"].(string)
+			weight, _ := entry["weight"].(float64)
+			assets = append(assets, PortfolioAsset{IndicatorCode: code, Weight: weight})
+		}
+	}
+	if indicator == "" && len(assets) == 0 {
+		return nil, tools.NewToolError(t.Name(), "indicator or assets parameter is required", nil)
+	}
+
+	period := Period1Year
+	if p, ok := params["period"].(string); ok && p != "" {
+		period = SimulationPeriod(strings.ToUpper(p))
+	}
+	from, err := dateParam(params, "from")
+	if err != nil {
+		return nil, tools.NewToolError(t.Name(), err.Error(), nil)
+	}
+	to, err := dateParam(params, "to")
+	if err != nil {
+		return nil, tools.NewToolError(t.Name(), err.Error(), nil)
+	}
+	if from != nil && to == nil {
+		now := time.Now()
+		to = &now
+	}
+	if from == nil {
+		to = nil
+	}
+
+	// Einmalanlage in einen Indikator: einfache Simulation
+	if len(assets) == 0 && monthly == 0 && !applyTax {
+		if amount <= 0 {
+			return nil, tools.NewToolError(t.Name(), "amount must be greater than 0", nil)
+		}
+		result, err := t.service.Simulate(SimulationRequest{
+			IndicatorCode: strings.ToUpper(indicator),
+			Amount:        amount,
+			Period:        period,
+			StartDate:     from,
+			EndDate:       to,
+		})
+		if err != nil {
+			return toolFailure(err), nil
+		}
+		return &tools.ToolResult{Success: true, Data: result, Source: "observer"}, nil
+	}
+
+	if len(assets) == 0 {
+		assets = []PortfolioAsset{{IndicatorCode: indicator, Weight: 1}}
+	}
+	result, err := t.service.SimulatePortfolio(PortfolioRequest{
+		Assets:              assets,
+		InitialAmount:       amount,
+		MonthlyContribution: monthly,
+		Period:              period,
+		StartDate:           from,
+		EndDate:             to,
+		Rebalance:           RebalanceInterval(rebalance),
+		ApplyTax:            applyTax,
+	})
+	if err != nil {
+		return toolFailure(err), nil
+	}
+	// Der Verlauf ist für die Antwort nicht nötig und würde den Kontext füllen
+	result.Series = nil
+	return &tools.ToolResult{Success: true, Data: result, Source: "observer"}, nil
+}
+
+// toolFailure verpackt einen Ausführungsfehler als Tool-Ergebnis für das Modell
+func toolFailure(err error) *tools.ToolResult {
+	return &tools.ToolResult{Success: false, Error: err.Error(), Source: "observer"}
+}
+
+// stringListParam liest eine Liste von Codes (Array oder kommagetrennter String)
+func stringListParam(params map[string]interface{}, name string) ([]string, error) {
+	var raw []string
+	switch v := params[name].(type) {
+	case nil:
+		return nil, nil
+	case string:
+		raw = strings.Split(v, ",")
+	case []interface{}:
+		for _, item := range v {
+			s, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("%s must be a list of strings", name)
+			}
+			raw = append(raw, s)
+		}
+	default:
+		return nil, fmt.Errorf("%s must be a list of strings", name)
+	}
+
+	codes := make([]string, 0, len(raw))
+	for _, s := range raw {
+		if s = strings.ToUpper(strings.TrimSpace(s)); s != "" {
+			codes = append(codes, s)
+		}
+	}
+	return codes, nil
+}
+
+// dateParam liest ein optionales Datum im Format YYYY-MM-DD
+func dateParam(params map[string]interface{}, name string) (*time.Time, error) {
+	v, _ := params[name].(string)
+	if v == "" {
+		return nil, nil
+	}
+	t, err := time.Parse("2006-01-02", v)
+	if err != nil {
+		return nil, fmt.Errorf("%s must be a date in format YYYY-MM-DD", name)
+	}
+	return &t, nil
+}
@@ -0,0 +1,93 @@
+package observer
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"fleet-navigator/internal/tools"
+)
+
+// TestObserverTools prüft die Observer-Tools über die Tool-Registry mit JSON-Parametern wie vom Modell
+func TestObserverTools(t *testing.T) {
+	service, err := NewService(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer service.Close()
+
+	start := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+	store := func(code string, points []SeriesPoint) {
+		ind, _ := service.repo.GetIndicatorByCode(code)
+		values := make([]ObservationValue, len(points))
+		for i, p := range points {
+			values[i] = ObservationValue{RunID: 1, IndicatorID: ind.ID, SourceID: ind.SourceID, ObservedAt: p.Date, CollectedAt: p.Date, Value: p.Value}
+		}
+		service.repo.StoreValues(values)
+	}
+	store("GOLD_EUR", weekdaySeries(start, 45, func(i int) float64 { return 2000 + float64(i)*10 }))
+	store("ECB_MAIN_RATE", weekdaySeries(start, 45, func(i int) float64 { return 4.25 }))
+
+	registry := tools.NewRegistry()
+	for _, tool := range NewTools(service) {
+		if err := registry.Register(tool); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Parameter wie aus einem Tool-Call des Modells (JSON → map)
+	execute := func(name, params string) *tools.ToolResult {
+		t.Helper()
+		var p map[string]interface{}
+		if err := json.Unmarshal([]byte(params), &p); err != nil {
+			t.Fatal(err)
+		}
+		result, err := registry.Execute(t.Context(), name, p)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		return result
+	}
+
+	latest := execute("observer_latest", `{"indicators": ["ecb_main_rate", "UNBEKANNT"]}`)
+	data := latest.Data.(map[string]interface{})
+	values := data["values"].([]LatestValue)
+	if !latest.Success || len(values) != 1 || values[0].Value != 4.25 || values[0].Display != "4.25%" || !values[0].Stale {
+		t.Errorf("Aktueller Wert: %+v", latest)
+	}
+	if unknown := data["unknown"].([]string); len(unknown) != 1 || unknown[0] != "UNBEKANNT" {
+		t.Errorf("Unbekannte Codes: %v", unknown)
+	}
+	all := execute("observer_latest", `{}`).Data.(map[string]interface{})["values"].([]LatestValue)
+	if len(all) != 2 || all[0].Code != "ECB_MAIN_RATE" {
+		t.Errorf("Alle Werte: %+v", all)
+	}
+
+	// Monatsultimo Juli 2220, August 2440
+	history := execute("observer_history", `{"indicator": "GOLD_EUR", "frequency": "M", "transform": "change_pct", "from": "2024-07-01", "to": "2024-08-31"}`)
+	series := history.Data.(map[string]interface{})["series"].(*SeriesResult)
+	if !history.Success || len(series.Points) != 1 || !approx(series.Points[0].Value, (2440.0/2220-1)*100) {
+		t.Errorf("Verlauf: %+v", series.Points)
+	}
+	raw := execute("observer_history", `{"indicator": "GOLD_EUR", "maxPoints": 5}`).Data.(map[string]interface{})
+	if points := raw["series"].(*SeriesResult).Points; len(points) != 5 || points[4].Value != 2440 || raw["truncated"] != true {
+		t.Errorf("Begrenzung: %+v", raw)
+	}
+	if failed := execute("observer_history", `{"indicator": "UNBEKANNT"}`); failed.Success || failed.Error == "" {
+		t.Errorf("Unbekannter Indikator: %+v", failed)
+	}
+	if _, err := registry.Execute(t.Context(), "observer_history", map[string]interface{}{}); err == nil {
+		t.Error("Fehlender Indikator nicht erkannt")
+	}
+
+	single := execute("observer_simulate", `{"indicator": "gold_eur", "amount": 1000, "from": "2024-07-01", "to": "2024-08-31"}`)
+	sim := single.Data.(*SimulationResult)
+	if !single.Success || !approx(sim.EndAmount, 1220) || sim.Disclaimer != DisclaimerText {
+		t.Errorf("Einmalanlage: %+v", single)
+	}
+	plan := execute("observer_simulate", `{"indicator": "GOLD_EUR", "monthlyContribution": 100, "from": "2024-07-01", "to": "2024-08-31"}`)
+	portfolio := plan.Data.(*PortfolioResult)
+	if !plan.Success || portfolio.Invested != 200 || portfolio.Series != nil || portfolio.Disclaimer != DisclaimerText {
+		t.Errorf("Sparplan: %+v", plan)
+	}
+}
@@ -0,0 +1,337 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Built-in backend names
+const (
+	BackendBrave         = "brave"
+	BackendSearXNG       = "searxng"
+	BackendDuckDuckGo    = "duckduckgo"
+	BackendKagi          = "kagi"
+	BackendBing          = "bing"
+	BackendGoogle        = "google"
+	BackendElasticsearch = "elasticsearch"
+)
+
+// BraveMonthlyLimit is the request quota of the Brave free tier (counted in MonthlySearchCount)
+const BraveMonthlyLimit = 2000
+
+// Health tracking: after backendFailureThreshold consecutive failures a backend
+// is moved to the end of the chain until backendCooldown has passed.
+const (
+	backendFailureThreshold = 3
+	backendCooldown         = 2 * time.Minute
+)
+
+// ErrNotConfigured is returned by a BackendFactory if required settings (API key, URL) are missing.
+// The backend is then skipped silently.
+var ErrNotConfigured = errors.New("backend not configured")
+
+// Backend is a web search provider (Brave, SearXNG, DuckDuckGo, ...)
+type Backend interface {
+	Name() string
+	Search(ctx context.Context, query string, opts SearchOptions) ([]SearchResult, error)
+}
+
+// BackendEnv holds what the service shares with all backends
+type BackendEnv struct {
+	Client    *http.Client
+	UserAgent string
+}
+
+// BackendFactory creates a backend from its configuration.
+// settings gives access to legacy fields (BraveAPIKey, SearXNG instances).
+type BackendFactory func(cfg BackendConfig, settings Settings, env BackendEnv) (Backend, error)
+
+// BackendConfig configures one backend in the fallback chain.
+// The chain is tried in list order; Weight does not change that order.
+type BackendConfig struct {
+	Name    string            `json:"name"`
+	Enabled bool              `json:"enabled"`
+	Weight  float64           `json:"weight,omitempty"`  // Rank fusion weight in multi-query search only (default 1)
+	APIKey  string            `json:"apiKey,omitempty"`  // Kagi, Bing, Google, Elasticsearch (Brave: falls back to BraveAPIKey)
+	URL     string            `json:"url,omitempty"`     // Endpoint or instance (optional, required for Elasticsearch)
+	Options map[string]string `json:"options,omitempty"` // e.g. cx (Google), index/fields (Elasticsearch)
+}
+
+// EffectiveWeight returns the configured weight (1 if unset).
+// It is only used when multi-query search fuses the results of all backends.
+func (c BackendConfig) EffectiveWeight() float64 {
+	if c.Weight <= 0 {
+		return 1
+	}
+	return c.Weight
+}
+
+// DefaultBackends returns the default chain: Brave (if a key is set), SearXNG, DuckDuckGo.
+// The other backends are listed disabled so they show up in the settings.
+func DefaultBackends() []BackendConfig {
+	return []BackendConfig{
+		{Name: BackendBrave, Enabled: true, Weight: 1},
+		{Name: BackendSearXNG, Enabled: true, Weight: 1},
+		{Name: BackendDuckDuckGo, Enabled: true, Weight: 1},
+		{Name: BackendKagi, Weight: 1},
+		{Name: BackendBing, Weight: 1},
+		{Name: BackendGoogle, Weight: 1},
+		{Name: BackendElasticsearch, Weight: 1},
+	}
+}
+
+// BackendStatus reports configuration, health and latency of a backend
+type BackendStatus struct {
+	Name                string     `json:"name"`
+	Position            int        `json:"position"` // Position in the chain (1-based, 0 = not in chain)
+	Enabled             bool       `json:"enabled"`
+	Configured          bool       `json:"configured"`
+	Weight              float64    `json:"weight"`
+	Healthy             bool       `json:"healthy"`
+	Requests            int        `json:"requests"`
+	Failures            int        `json:"failures"`
+	EmptyResults        int        `json:"emptyResults"`
+	ConsecutiveFailures int        `json:"consecutiveFailures"`
+	AvgLatencyMs        int64      `json:"avgLatencyMs"`
+	LastLatencyMs       int64      `json:"lastLatencyMs"`
+	LastError           string     `json:"lastError,omitempty"`
+	LastErrorAt         *time.Time `json:"lastErrorAt,omitempty"`
+	LastSuccessAt       *time.Time `json:"lastSuccessAt,omitempty"`
+	CooldownUntil       *time.Time `json:"cooldownUntil,omitempty"`
+}
+
+// backendStats are the runtime counters behind BackendStatus
+type backendStats struct {
+	requests            int
+	failures            int
+	emptyResults        int
+	consecutiveFailures int
+	totalLatency        time.Duration
+	lastLatency         time.Duration
+	lastError           string
+	lastErrorAt         time.Time
+	lastSuccessAt       time.Time
+	cooldownUntil       time.Time
+}
+
+// chainEntry is a backend instantiated from its config
+type chainEntry struct {
+	backend Backend
+	config  BackendConfig
+	cooling bool // In cooldown after repeated failures
+}
+
+// RegisterBackend adds a backend type (e.g. a company-internal search).
+// It can then be enabled and ordered via Settings.Backends.
+func (s *Service) RegisterBackend(name string, factory BackendFactory) error {
+	s.backendsMu.Lock()
+	defer s.backendsMu.Unlock()
+
+	name = strings.ToLower(name)
+	if _, exists := s.factories[name]; exists {
+		return fmt.Errorf("search backend '%s' already registered", name)
+	}
+	s.factories[name] = factory
+	return nil
+}
+
+// RegisteredBackends returns the names of all registered backend types (sorted)
+func (s *Service) RegisteredBackends() []string {
+	s.backendsMu.RLock()
+	defer s.backendsMu.RUnlock()
+
+	names := make([]string, 0, len(s.factories))
+	for name := range s.factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// validateBackends checks the configured chain against the registered backends
+func (s *Service) validateBackends(configs []BackendConfig) error {
+	s.backendsMu.RLock()
+	defer s.backendsMu.RUnlock()
+
+	seen := make(map[string]bool, len(configs))
+	for _, cfg := range configs {
+		name := strings.ToLower(cfg.Name)
+		if _, ok := s.factories[name]; !ok {
+			return fmt.Errorf("unknown search backend '%s'", cfg.Name)
+		}
+		if seen[name] {
+			return fmt.Errorf("search backend '%s' configured twice", cfg.Name)
+		}
+		if cfg.Weight < 0 {
+			return fmt.Errorf("search backend '%s': weight must not be negative", cfg.Name)
+		}
+		seen[name] = true
+	}
+	return nil
+}
+
+// backendConfigs returns the configured chain (defaults if none is configured)
+func backendConfigs(settings Settings) []BackendConfig {
+	if len(settings.Backends) == 0 {
+		return DefaultBackends()
+	}
+	return settings.Backends
+}
+
+// backendChain instantiates the enabled, configured backends in order.
+// Backends in cooldown are moved to the end, so they are only tried as a last resort.
+func (s *Service) backendChain(settings Settings) []chainEntry {
+	env := BackendEnv{Client: s.client, UserAgent: s.userAgent}
+	now := time.Now()
+
+	var healthy, cooling []chainEntry
+	for _, cfg := range backendConfigs(settings) {
+		if !cfg.Enabled {
+			continue
+		}
+		cfg.Name = strings.ToLower(cfg.Name)
+		s.backendsMu.RLock()
+		factory, ok := s.factories[cfg.Name]
+		st := s.stats[cfg.Name]
+		cooldown := st != nil && now.Before(st.cooldownUntil)
+		s.backendsMu.RUnlock()
+		if !ok {
+			continue
+		}
+
+		backend, err := factory(cfg, settings, env)
+		if err != nil {
+			continue
+		}
+		if cooldown {
+			cooling = append(cooling, chainEntry{backend, cfg, true})
+		} else {
+			healthy = append(healthy, chainEntry{backend, cfg, false})
+		}
+	}
+	return append(healthy, cooling...)
+}
+
+// searchBackend runs one backend and records health and latency
+func (s *Service) searchBackend(ctx context.Context, backend Backend, query string, opts SearchOptions) ([]SearchResult, error) {
+	start := time.Now()
+	results, err := backend.Search(ctx, query, opts)
+	elapsed := time.Since(start)
+
+	// Aborted by the caller - says nothing about the backend
+	if errors.Is(err, context.Canceled) {
+		return nil, err
+	}
+
+	name := backend.Name()
+	s.backendsMu.Lock()
+	defer s.backendsMu.Unlock()
+
+	st := s.stats[name]
+	if st == nil {
+		st = &backendStats{}
+		s.stats[name] = st
+	}
+	st.requests++
+	st.totalLatency += elapsed
+	st.lastLatency = elapsed
+
+	if err != nil {
+		st.failures++
+		st.consecutiveFailures++
+		st.lastError = err.Error()
+		st.lastErrorAt = time.Now()
+		if st.consecutiveFailures >= backendFailureThreshold {
+			st.cooldownUntil = time.Now().Add(backendCooldown)
+		}
+		return nil, err
+	}
+
+	st.consecutiveFailures = 0
+	st.cooldownUntil = time.Time{}
+	st.lastSuccessAt = time.Now()
+	if len(results) == 0 {
+		st.emptyResults++
+	}
+
+	// Brave free tier is limited per month
+	if name == BackendBrave {
+		s.incrementSearchCount()
+	}
+
+	for i := range results {
+		if results[i].Source == "" {
+			results[i].Source = name
+		}
+	}
+	return results, nil
+}
+
+// BackendStatus returns health and latency of all registered backends,
+// in chain order first, followed by backends not in the chain.
+func (s *Service) BackendStatus() []BackendStatus {
+	settings := s.GetSettings()
+	env := BackendEnv{Client: s.client, UserAgent: s.userAgent}
+	now := time.Now()
+
+	s.backendsMu.RLock()
+	defer s.backendsMu.RUnlock()
+
+	status := func(name string, cfg BackendConfig, position int) BackendStatus {
+		bs := BackendStatus{Name: name, Position: position, Enabled: cfg.Enabled, Weight: cfg.EffectiveWeight(), Healthy: true}
+		if factory, ok := s.factories[name]; ok {
+			_, err := factory(cfg, settings, env)
+			bs.Configured = err == nil
+		}
+		if st := s.stats[name]; st != nil {
+			bs.Requests = st.requests
+			bs.Failures = st.failures
+			bs.EmptyResults = st.emptyResults
+			bs.ConsecutiveFailures = st.consecutiveFailures
+			bs.LastLatencyMs = st.lastLatency.Milliseconds()
+			if st.requests > 0 {
+				bs.AvgLatencyMs = (st.totalLatency / time.Duration(st.requests)).Milliseconds()
+			}
+			bs.LastError = st.lastError
+			if !st.lastErrorAt.IsZero() {
+				t := st.lastErrorAt
+				bs.LastErrorAt = &t
+			}
+			if !st.lastSuccessAt.IsZero() {
+				t := st.lastSuccessAt
+				bs.LastSuccessAt = &t
+			}
+			if now.Before(st.cooldownUntil) {
+				t := st.cooldownUntil
+				bs.CooldownUntil = &t
+				bs.Healthy = false
+			}
+		}
+		return bs
+	}
+
+	var result []BackendStatus
+	listed := make(map[string]bool)
+	for i, cfg := range backendConfigs(settings) {
+		name := strings.ToLower(cfg.Name)
+		listed[name] = true
+		result = append(result, status(name, cfg, i+1))
+	}
+
+	var others []string
+	for name := range s.factories {
+		if !listed[name] {
+			others = append(others, name)
+		}
+	}
+	sort.Strings(others)
+	for _, name := range others {
+		result = append(result, status(name, BackendConfig{Name: name}, 0))
+	}
+	return result
+}
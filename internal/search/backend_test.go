@@ -0,0 +1,124 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// fakeBackend liefert feste Ergebnisse oder einen Fehler
+type fakeBackend struct {
+	name    string
+	results []SearchResult
+	err     error
+	calls   *int
+}
+
+func (b *fakeBackend) Name() string { return b.name }
+func (b *fakeBackend) Search(ctx context.Context, query string, opts SearchOptions) ([]SearchResult, error) {
+	*b.calls++
+	return b.results, b.err
+}
+
+// TestBackendChain prüft Reihenfolge, Fallback, Health-Statistik und Cooldown
+func TestBackendChain(t *testing.T) {
+	s := NewService()
+	calls := map[string]*int{"intern": new(int), "extern": new(int)}
+	failing := true
+	s.RegisterBackend("intern", func(cfg BackendConfig, settings Settings, env BackendEnv) (Backend, error) {
+		if failing {
+			return &fakeBackend{name: "intern", err: errors.New("timeout"), calls: calls["intern"]}, nil
+		}
+		return &fakeBackend{name: "intern", results: []SearchResult{{Title: "Wiki", URL: "https://wiki.intern/a"}}, calls: calls["intern"]}, nil
+	})
+	s.RegisterBackend("extern", func(cfg BackendConfig, settings Settings, env BackendEnv) (Backend, error) {
+		return &fakeBackend{name: "extern", results: []SearchResult{{Title: "Web", URL: "https://example.com"}}, calls: calls["extern"]}, nil
+	})
+	if err := s.RegisterBackend("intern", nil); err == nil {
+		t.Error("Doppelte Registrierung nicht erkannt")
+	}
+
+	settings := s.GetSettings()
+	settings.Backends = []BackendConfig{
+		{Name: BackendBrave, Enabled: true}, // ohne Key übersprungen
+		{Name: "intern", Enabled: true, Weight: 2},
+		{Name: "extern", Enabled: true},
+		{Name: BackendDuckDuckGo, Enabled: false},
+	}
+	if err := s.UpdateSettings(settings); err != nil {
+		t.Fatal(err)
+	}
+
+	opts := DefaultSearchOptions()
+	opts.ReRank = false
+	for i := 0; i < backendFailureThreshold; i++ {
+		results, err := s.Search(context.Background(), "frage "+string(rune('a'+i)), opts)
+		if err != nil || len(results) != 1 || results[0].Source != "extern" {
+			t.Fatalf("Fallback: %v %+v", err, results)
+		}
+	}
+
+	status := s.BackendStatus()
+	if status[0].Name != BackendBrave || status[0].Configured || status[1].Name != "intern" || status[1].Weight != 2 {
+		t.Fatalf("Status: %+v", status)
+	}
+	if st := status[1]; st.Healthy || st.Failures != 3 || st.LastError != "timeout" || st.CooldownUntil == nil {
+		t.Errorf("Intern nach Fehlern: %+v", st)
+	}
+	if st := status[2]; !st.Healthy || st.Requests != 3 || st.LastSuccessAt == nil {
+		t.Errorf("Extern: %+v", st)
+	}
+
+	// Im Cooldown ans Ende der Kette: extern antwortet, intern wird nicht gefragt
+	failing = false
+	if results, _ := s.Search(context.Background(), "frage z", opts); results[0].Source != "extern" || *calls["intern"] != 3 {
+		t.Errorf("Cooldown: %+v, %d Aufrufe", results, *calls["intern"])
+	}
+
+	settings.Backends = []BackendConfig{{Name: "unbekannt", Enabled: true}}
+	if err := s.UpdateSettings(settings); err == nil {
+		t.Error("Unbekanntes Backend nicht erkannt")
+	}
+	settings.Backends = []BackendConfig{{Name: "extern", Enabled: true}, {Name: "EXTERN", Enabled: true}}
+	if err := s.UpdateSettings(settings); err == nil {
+		t.Error("Doppeltes Backend nicht erkannt")
+	}
+}
+
+// TestElasticsearchBackend prüft Abfrage und Auswertung gegen einen Test-Server
+func TestElasticsearchBackend(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		if r.URL.Path != "/wiki/_search" || r.Header.Get("Authorization") != "ApiKey geheim" || body["size"].(float64) != 5 {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(`{"hits": {"hits": [
+			{"_source": {"title": "Urlaubsantrag", "url": "https://wiki.intern/urlaub", "content": "Langer Text"},
+			 "highlight": {"content": ["Der <em>Urlaubsantrag</em> wird im Portal gestellt"]}},
+			{"_source": {"title": "Ohne URL"}}
+		]}}`))
+	}))
+	defer server.Close()
+
+	s := NewService()
+	settings := s.GetSettings()
+	settings.Backends = []BackendConfig{{Name: BackendElasticsearch, Enabled: true, URL: server.URL, APIKey: "geheim", Options: map[string]string{"index": "wiki"}}}
+	if err := s.UpdateSettings(settings); err != nil {
+		t.Fatal(err)
+	}
+
+	opts := DefaultSearchOptions()
+	opts.MaxResults = 5
+	results, err := s.Search(context.Background(), "urlaubsantrag", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].Source != BackendElasticsearch || results[0].Snippet != "Der Urlaubsantrag wird im Portal gestellt" {
+		t.Errorf("Ergebnisse: %+v", results)
+	}
+}
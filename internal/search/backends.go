@@ -0,0 +1,786 @@
+package search
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// registerBuiltinBackends registers all backends shipped with the Navigator
+func (s *Service) registerBuiltinBackends() {
+	s.RegisterBackend(BackendBrave, newBraveBackend)
+	s.RegisterBackend(BackendSearXNG, newSearXNGBackend)
+	s.RegisterBackend(BackendDuckDuckGo, newDuckDuckGoBackend)
+	s.RegisterBackend(BackendKagi, newKagiBackend)
+	s.RegisterBackend(BackendBing, newBingBackend)
+	s.RegisterBackend(BackendGoogle, newGoogleBackend)
+	s.RegisterBackend(BackendElasticsearch, newElasticsearchBackend)
+}
+
+// doJSON executes a request and decodes a JSON response
+func doJSON(env BackendEnv, req *http.Request, name string, into interface{}) error {
+	if req.Header.Get("Accept") == "" {
+		req.Header.Set("Accept", "application/json")
+	}
+
+	resp, err := env.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned %d", name, resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(body, into)
+}
+
+// --- Brave ---
+
+type braveBackend struct {
+	env    BackendEnv
+	apiKey string
+	apiURL string
+}
+
+func newBraveBackend(cfg BackendConfig, settings Settings, env BackendEnv) (Backend, error) {
+	apiKey := cfg.APIKey
+	if apiKey == "" {
+		apiKey = settings.BraveAPIKey
+	}
+	if apiKey == "" {
+		return nil, ErrNotConfigured
+	}
+	apiURL := cfg.URL
+	if apiURL == "" {
+		apiURL = "https://api.search.brave.com/res/v1/web/search"
+	}
+	return &braveBackend{env: env, apiKey: apiKey, apiURL: apiURL}, nil
+}
+
+func (b *braveBackend) Name() string { return BackendBrave }
+
+// Search uses Brave Search API
+func (b *braveBackend) Search(ctx context.Context, query string, opts SearchOptions) ([]SearchResult, error) {
+	apiURL := fmt.Sprintf("%s?q=%s&count=%d", b.apiURL, url.QueryEscape(query), opts.MaxResults)
+
+	// Add time filter
+	if opts.TimeFilter != "" {
+		freshnessMap := map[TimeFilter]string{
+			TimeFilterDay:   "pd",
+			TimeFilterWeek:  "pw",
+			TimeFilterMonth: "pm",
+			TimeFilterYear:  "py",
+		}
+		if freshness, ok := freshnessMap[opts.TimeFilter]; ok {
+			apiURL += "&freshness=" + freshness
+		}
+	}
+
+	// Add region
+	if opts.Region != "" {
+		apiURL += "&country=" + strings.Split(opts.Region, "-")[0]
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", apiURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Subscription-Token", b.apiKey)
+
+	var braveResp struct {
+		Web struct {
+			Results []struct {
+				Title       string `json:"title"`
+				URL         string `json:"url"`
+				Description string `json:"description"`
+			} `json:"results"`
+		} `json:"web"`
+	}
+	if err := doJSON(b.env, req, "Brave API", &braveResp); err != nil {
+		return nil, err
+	}
+
+	var results []SearchResult
+	for _, r := range braveResp.Web.Results {
+		results = append(results, SearchResult{
+			Title:   r.Title,
+			URL:     r.URL,
+			Snippet: r.Description,
+			Source:  BackendBrave,
+		})
+	}
+	return results, nil
+}
+
+// --- SearXNG ---
+
+type searxngBackend struct {
+	env       BackendEnv
+	instances []string
+}
+
+func newSearXNGBackend(cfg BackendConfig, settings Settings, env BackendEnv) (Backend, error) {
+	// Try configured/custom instance first, then the public fallbacks
+	var instances []string
+	if cfg.URL != "" {
+		instances = append(instances, cfg.URL)
+	}
+	if settings.CustomSearXNG != "" && settings.CustomSearXNG != cfg.URL {
+		instances = append(instances, settings.CustomSearXNG)
+	}
+	instances = append(instances, settings.SearXNGInstances...)
+	if len(instances) == 0 {
+		return nil, ErrNotConfigured
+	}
+	return &searxngBackend{env: env, instances: instances}, nil
+}
+
+func (b *searxngBackend) Name() string { return BackendSearXNG }
+
+// Search tries the SearXNG instances in order
+func (b *searxngBackend) Search(ctx context.Context, query string, opts SearchOptions) ([]SearchResult, error) {
+	var lastErr error
+	for _, instance := range b.instances {
+		results, err := b.searchInstance(ctx, instance, query, opts)
+		if err == nil && len(results) > 0 {
+			return results, nil
+		}
+		lastErr = err
+		if ctx.Err() != nil {
+			break
+		}
+	}
+
+	if lastErr != nil {
+		return nil, fmt.Errorf("all SearXNG instances failed: %w", lastErr)
+	}
+	return nil, nil
+}
+
+func (b *searxngBackend) searchInstance(ctx context.Context, instance, query string, opts SearchOptions) ([]SearchResult, error) {
+	apiURL := fmt.Sprintf(
+		"%s/search?q=%s&format=json&language=%s",
+		strings.TrimSuffix(instance, "/"),
+		url.QueryEscape(query),
+		opts.Region,
+	)
+
+	// Add time filter
+	if opts.TimeFilter != "" {
+		apiURL += "&time_range=" + string(opts.TimeFilter)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", apiURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", b.env.UserAgent)
+
+	var searxResp struct {
+		Results []struct {
+			Title   string `json:"title"`
+			URL     string `json:"url"`
+			Content string `json:"content"`
+		} `json:"results"`
+	}
+	if err := doJSON(b.env, req, "SearXNG", &searxResp); err != nil {
+		return nil, err
+	}
+
+	var results []SearchResult
+	for i, r := range searxResp.Results {
+		if i >= opts.MaxResults {
+			break
+		}
+		results = append(results, SearchResult{
+			Title:   r.Title,
+			URL:     r.URL,
+			Snippet: r.Content,
+			Source:  BackendSearXNG,
+		})
+	}
+	return results, nil
+}
+
+// --- DuckDuckGo ---
+
+type duckDuckGoBackend struct {
+	env BackendEnv
+}
+
+func newDuckDuckGoBackend(cfg BackendConfig, settings Settings, env BackendEnv) (Backend, error) {
+	return &duckDuckGoBackend{env: env}, nil
+}
+
+func (b *duckDuckGoBackend) Name() string { return BackendDuckDuckGo }
+
+// Search uses the instant answer API, falling back to the HTML and lite pages
+func (b *duckDuckGoBackend) Search(ctx context.Context, query string, opts SearchOptions) ([]SearchResult, error) {
+	apiURL := fmt.Sprintf(
+		"https://api.duckduckgo.com/?q=%s&format=json&no_html=1&skip_disambig=1&kl=%s",
+		url.QueryEscape(query),
+		url.QueryEscape(opts.Region),
+	)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", apiURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", b.env.UserAgent)
+
+	var ddgResp struct {
+		Abstract       string `json:"Abstract"`
+		AbstractSource string `json:"AbstractSource"`
+		AbstractURL    string `json:"AbstractURL"`
+		RelatedTopics  []struct {
+			Text     string `json:"Text"`
+			FirstURL string `json:"FirstURL"`
+		} `json:"RelatedTopics"`
+		Results []struct {
+			Text     string `json:"Text"`
+			FirstURL string `json:"FirstURL"`
+		} `json:"Results"`
+	}
+	if err := doJSON(b.env, req, "DuckDuckGo", &ddgResp); err != nil {
+		return nil, err
+	}
+
+	var results []SearchResult
+
+	// Add abstract
+	if ddgResp.Abstract != "" {
+		results = append(results, SearchResult{
+			Title:   ddgResp.AbstractSource,
+			URL:     ddgResp.AbstractURL,
+			Snippet: ddgResp.Abstract,
+			Source:  BackendDuckDuckGo,
+		})
+	}
+
+	// Add direct results
+	for _, r := range ddgResp.Results {
+		if len(results) >= opts.MaxResults {
+			break
+		}
+		results = append(results, SearchResult{
+			Title:   extractTitle(r.Text),
+			URL:     r.FirstURL,
+			Snippet: r.Text,
+			Source:  BackendDuckDuckGo,
+		})
+	}
+
+	// Add related topics
+	for _, t := range ddgResp.RelatedTopics {
+		if len(results) >= opts.MaxResults {
+			break
+		}
+		if t.FirstURL != "" {
+			results = append(results, SearchResult{
+				Title:   extractTitle(t.Text),
+				URL:     t.FirstURL,
+				Snippet: t.Text,
+				Source:  BackendDuckDuckGo,
+			})
+		}
+	}
+
+	// Fallback to HTML scraping if no results
+	if len(results) == 0 {
+		return b.searchHTML(ctx, query, opts)
+	}
+	return results, nil
+}
+
+// searchHTML scrapes html.duckduckgo.com, then lite.duckduckgo.com
+func (b *duckDuckGoBackend) searchHTML(ctx context.Context, query string, opts SearchOptions) ([]SearchResult, error) {
+	body, err := b.fetchPage(ctx, fmt.Sprintf(
+		"https://html.duckduckgo.com/html/?q=%s&kl=%s",
+		url.QueryEscape(query),
+		url.QueryEscape(opts.Region),
+	))
+	if err == nil {
+		if results := parseDDGHTML(body, opts.MaxResults); len(results) > 0 {
+			return results, nil
+		}
+	}
+
+	// Lite version has a simpler table layout
+	body, err = b.fetchPage(ctx, fmt.Sprintf(
+		"https://lite.duckduckgo.com/lite/?q=%s&kl=%s",
+		url.QueryEscape(query),
+		url.QueryEscape(opts.Region),
+	))
+	if err != nil {
+		return nil, err
+	}
+	return parseDDGLiteHTML(body, opts.MaxResults), nil
+}
+
+func (b *duckDuckGoBackend) fetchPage(ctx context.Context, pageURL string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", pageURL, nil)
+	if err != nil {
+		return "", err
+	}
+	// Set headers to mimic a real browser
+	req.Header.Set("User-Agent", b.env.UserAgent)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,image/webp,*/*;q=0.8")
+	req.Header.Set("Accept-Language", "de-DE,de;q=0.9,en;q=0.8")
+	req.Header.Set("DNT", "1")
+
+	resp, err := b.env.Client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	return string(body), nil
+}
+
+// parseDDGHTML parses the html.duckduckgo.com results page
+func parseDDGHTML(htmlContent string, maxResults int) []SearchResult {
+	var results []SearchResult
+
+	// Parse using regex patterns for result__a (title/link) and result__snippet
+	// Pattern for result links: class="result__a" href="URL">Title</a>
+	linkPattern := regexp.MustCompile(`class="result__a"[^>]*href="([^"]+)"[^>]*>([^<]+)</a>`)
+	// Pattern for snippets: class="result__snippet">...content...</a>
+	snippetPattern := regexp.MustCompile(`class="result__snippet"[^>]*>([^<]+)`)
+
+	linkMatches := linkPattern.FindAllStringSubmatch(htmlContent, -1)
+	snippetMatches := snippetPattern.FindAllStringSubmatch(htmlContent, -1)
+
+	for i, match := range linkMatches {
+		if len(results) >= maxResults {
+			break
+		}
+		if len(match) < 3 {
+			continue
+		}
+
+		resultURL := match[1]
+		resultTitle := strings.TrimSpace(match[2])
+
+		// Decode redirect URL if present
+		if strings.HasPrefix(resultURL, "//duckduckgo.com/l/?uddg=") {
+			if decoded, err := url.QueryUnescape(strings.TrimPrefix(resultURL, "//duckduckgo.com/l/?uddg=")); err == nil {
+				// Extract actual URL (before &rut=)
+				if idx := strings.Index(decoded, "&"); idx != -1 {
+					resultURL = decoded[:idx]
+				} else {
+					resultURL = decoded
+				}
+			}
+		}
+
+		// Skip DuckDuckGo internal links
+		if strings.Contains(resultURL, "duckduckgo.com") {
+			continue
+		}
+
+		snippet := ""
+		if i < len(snippetMatches) && len(snippetMatches[i]) > 1 {
+			snippet = strings.TrimSpace(snippetMatches[i][1])
+		}
+
+		results = append(results, SearchResult{
+			Title:   stripHTMLTags(resultTitle),
+			URL:     resultURL,
+			Snippet: stripHTMLTags(snippet),
+			Source:  BackendDuckDuckGo,
+		})
+	}
+
+	return results
+}
+
+// parseDDGLiteHTML parses the lite.duckduckgo.com table layout
+func parseDDGLiteHTML(htmlContent string, maxResults int) []SearchResult {
+	var results []SearchResult
+	lines := strings.Split(htmlContent, "\n")
+	var currentTitle, currentURL, currentDesc string
+
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+
+		if strings.Contains(line, `class="result-link"`) || strings.Contains(line, `rel="nofollow"`) {
+			if hrefStart := strings.Index(line, `href="`); hrefStart != -1 {
+				hrefStart += 6
+				if hrefEnd := strings.Index(line[hrefStart:], `"`); hrefEnd != -1 {
+					currentURL = line[hrefStart : hrefStart+hrefEnd]
+				}
+			}
+			if titleStart := strings.Index(line, ">"); titleStart != -1 {
+				if titleEnd := strings.Index(line[titleStart:], "</a>"); titleEnd != -1 {
+					currentTitle = strings.TrimSpace(line[titleStart+1 : titleStart+titleEnd])
+					currentTitle = stripHTMLTags(currentTitle)
+				}
+			}
+		}
+
+		if strings.Contains(line, `class="result-snippet"`) {
+			currentDesc = stripHTMLTags(line)
+		}
+
+		if currentURL != "" && currentTitle != "" && !strings.Contains(currentURL, "duckduckgo.com") {
+			results = append(results, SearchResult{
+				Title:   currentTitle,
+				URL:     currentURL,
+				Snippet: currentDesc,
+				Source:  BackendDuckDuckGo,
+			})
+			currentTitle = ""
+			currentURL = ""
+			currentDesc = ""
+
+			if len(results) >= maxResults {
+				break
+			}
+		}
+	}
+
+	return results
+}
+
+// --- Kagi ---
+
+type kagiBackend struct {
+	env    BackendEnv
+	apiKey string
+	apiURL string
+}
+
+func newKagiBackend(cfg BackendConfig, settings Settings, env BackendEnv) (Backend, error) {
+	if cfg.APIKey == "" {
+		return nil, ErrNotConfigured
+	}
+	apiURL := cfg.URL
+	if apiURL == "" {
+		apiURL = "https://kagi.com/api/v0/search"
+	}
+	return &kagiBackend{env: env, apiKey: cfg.APIKey, apiURL: apiURL}, nil
+}
+
+func (b *kagiBackend) Name() string { return BackendKagi }
+
+// Search uses the Kagi Search API
+func (b *kagiBackend) Search(ctx context.Context, query string, opts SearchOptions) ([]SearchResult, error) {
+	apiURL := fmt.Sprintf("%s?q=%s&limit=%d", b.apiURL, url.QueryEscape(query), opts.MaxResults)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", apiURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bot "+b.apiKey)
+
+	var kagiResp struct {
+		Data []struct {
+			T       int    `json:"t"` // 0 = search result, 1 = related searches
+			URL     string `json:"url"`
+			Title   string `json:"title"`
+			Snippet string `json:"snippet"`
+		} `json:"data"`
+	}
+	if err := doJSON(b.env, req, "Kagi API", &kagiResp); err != nil {
+		return nil, err
+	}
+
+	var results []SearchResult
+	for _, r := range kagiResp.Data {
+		if r.T != 0 || r.URL == "" {
+			continue
+		}
+		results = append(results, SearchResult{
+			Title:   r.Title,
+			URL:     r.URL,
+			Snippet: r.Snippet,
+			Source:  BackendKagi,
+		})
+	}
+	return results, nil
+}
+
+// --- Bing ---
+
+type bingBackend struct {
+	env    BackendEnv
+	apiKey string
+	apiURL string
+}
+
+func newBingBackend(cfg BackendConfig, settings Settings, env BackendEnv) (Backend, error) {
+	if cfg.APIKey == "" {
+		return nil, ErrNotConfigured
+	}
+	apiURL := cfg.URL
+	if apiURL == "" {
+		apiURL = "https://api.bing.microsoft.com/v7.0/search"
+	}
+	return &bingBackend{env: env, apiKey: cfg.APIKey, apiURL: apiURL}, nil
+}
+
+func (b *bingBackend) Name() string { return BackendBing }
+
+// Search uses the Bing Web Search API
+func (b *bingBackend) Search(ctx context.Context, query string, opts SearchOptions) ([]SearchResult, error) {
+	apiURL := fmt.Sprintf("%s?q=%s&count=%d&responseFilter=Webpages", b.apiURL, url.QueryEscape(query), opts.MaxResults)
+
+	freshnessMap := map[TimeFilter]string{
+		TimeFilterDay:   "Day",
+		TimeFilterWeek:  "Week",
+		TimeFilterMonth: "Month",
+	}
+	if freshness, ok := freshnessMap[opts.TimeFilter]; ok {
+		apiURL += "&freshness=" + freshness
+	}
+	// de-de -> de-DE
+	if parts := strings.Split(opts.Region, "-"); len(parts) == 2 {
+		apiURL += "&mkt=" + parts[0] + "-" + strings.ToUpper(parts[1])
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", apiURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Ocp-Apim-Subscription-Key", b.apiKey)
+
+	var bingResp struct {
+		WebPages struct {
+			Value []struct {
+				Name    string `json:"name"`
+				URL     string `json:"url"`
+				Snippet string `json:"snippet"`
+			} `json:"value"`
+		} `json:"webPages"`
+	}
+	if err := doJSON(b.env, req, "Bing API", &bingResp); err != nil {
+		return nil, err
+	}
+
+	var results []SearchResult
+	for _, r := range bingResp.WebPages.Value {
+		results = append(results, SearchResult{
+			Title:   r.Name,
+			URL:     r.URL,
+			Snippet: r.Snippet,
+			Source:  BackendBing,
+		})
+	}
+	return results, nil
+}
+
+// --- Google Programmable Search ---
+
+type googleBackend struct {
+	env    BackendEnv
+	apiKey string
+	cx     string
+	apiURL string
+}
+
+func newGoogleBackend(cfg BackendConfig, settings Settings, env BackendEnv) (Backend, error) {
+	// cx = ID of the Programmable Search Engine
+	if cfg.APIKey == "" || cfg.Options["cx"] == "" {
+		return nil, ErrNotConfigured
+	}
+	apiURL := cfg.URL
+	if apiURL == "" {
+		apiURL = "https://www.googleapis.com/customsearch/v1"
+	}
+	return &googleBackend{env: env, apiKey: cfg.APIKey, cx: cfg.Options["cx"], apiURL: apiURL}, nil
+}
+
+func (b *googleBackend) Name() string { return BackendGoogle }
+
+// Search uses the Custom Search JSON API
+func (b *googleBackend) Search(ctx context.Context, query string, opts SearchOptions) ([]SearchResult, error) {
+	// API returns at most 10 results per request
+	num := opts.MaxResults
+	if num > 10 {
+		num = 10
+	}
+	params := url.Values{}
+	params.Set("key", b.apiKey)
+	params.Set("cx", b.cx)
+	params.Set("q", query)
+	params.Set("num", fmt.Sprint(num))
+
+	dateRestrict := map[TimeFilter]string{
+		TimeFilterDay:   "d1",
+		TimeFilterWeek:  "w1",
+		TimeFilterMonth: "m1",
+		TimeFilterYear:  "y1",
+	}
+	if restrict, ok := dateRestrict[opts.TimeFilter]; ok {
+		params.Set("dateRestrict", restrict)
+	}
+	if parts := strings.Split(opts.Region, "-"); len(parts) == 2 {
+		params.Set("hl", parts[0])
+		params.Set("gl", parts[1])
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", b.apiURL+"?"+params.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var googleResp struct {
+		Items []struct {
+			Title   string `json:"title"`
+			Link    string `json:"link"`
+			Snippet string `json:"snippet"`
+		} `json:"items"`
+	}
+	if err := doJSON(b.env, req, "Google API", &googleResp); err != nil {
+		return nil, err
+	}
+
+	var results []SearchResult
+	for _, r := range googleResp.Items {
+		results = append(results, SearchResult{
+			Title:   r.Title,
+			URL:     r.Link,
+			Snippet: r.Snippet,
+			Source:  BackendGoogle,
+		})
+	}
+	return results, nil
+}
+
+// --- Elasticsearch (e.g. company wiki/intranet) ---
+
+type elasticsearchBackend struct {
+	env          BackendEnv
+	apiKey       string
+	searchURL    string
+	fields       []string
+	titleField   string
+	urlField     string
+	contentField string
+	dateField    string
+}
+
+// newElasticsearchBackend reads the options index (default: all), fields (default: title^2,content),
+// titleField, urlField, contentField and dateField (used for the time filter)
+func newElasticsearchBackend(cfg BackendConfig, settings Settings, env BackendEnv) (Backend, error) {
+	if cfg.URL == "" {
+		return nil, ErrNotConfigured
+	}
+	option := func(key, def string) string {
+		if v := cfg.Options[key]; v != "" {
+			return v
+		}
+		return def
+	}
+
+	index := option("index", "_all")
+	b := &elasticsearchBackend{
+		env:          env,
+		apiKey:       cfg.APIKey,
+		searchURL:    strings.TrimSuffix(cfg.URL, "/") + "/" + url.PathEscape(index) + "/_search",
+		titleField:   option("titleField", "title"),
+		urlField:     option("urlField", "url"),
+		contentField: option("contentField", "content"),
+		dateField:    cfg.Options["dateField"],
+	}
+	for _, field := range strings.Split(option("fields", b.titleField+"^2,"+b.contentField), ",") {
+		if field = strings.TrimSpace(field); field != "" {
+			b.fields = append(b.fields, field)
+		}
+	}
+	return b, nil
+}
+
+func (b *elasticsearchBackend) Name() string { return BackendElasticsearch }
+
+// Search runs a multi_match query with highlighting for the snippet
+func (b *elasticsearchBackend) Search(ctx context.Context, query string, opts SearchOptions) ([]SearchResult, error) {
+	var must []interface{}
+	must = append(must, map[string]interface{}{
+		"multi_match": map[string]interface{}{"query": query, "fields": b.fields},
+	})
+	ranges := map[TimeFilter]string{
+		TimeFilterDay:   "now-1d",
+		TimeFilterWeek:  "now-1w",
+		TimeFilterMonth: "now-1M",
+		TimeFilterYear:  "now-1y",
+	}
+	if gte, ok := ranges[opts.TimeFilter]; ok && b.dateField != "" {
+		must = append(must, map[string]interface{}{
+			"range": map[string]interface{}{b.dateField: map[string]interface{}{"gte": gte}},
+		})
+	}
+
+	payload, err := json.Marshal(map[string]interface{}{
+		"size":    opts.MaxResults,
+		"query":   map[string]interface{}{"bool": map[string]interface{}{"must": must}},
+		"_source": []string{b.titleField, b.urlField, b.contentField},
+		"highlight": map[string]interface{}{
+			"fields": map[string]interface{}{
+				b.contentField: map[string]interface{}{"fragment_size": 200, "number_of_fragments": 1},
+			},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", b.searchURL, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if b.apiKey != "" {
+		req.Header.Set("Authorization", "ApiKey "+b.apiKey)
+	}
+
+	var esResp struct {
+		Hits struct {
+			Hits []struct {
+				Source    map[string]interface{} `json:"_source"`
+				Highlight map[string][]string    `json:"highlight"`
+			} `json:"hits"`
+		} `json:"hits"`
+	}
+	if err := doJSON(b.env, req, "Elasticsearch", &esResp); err != nil {
+		return nil, err
+	}
+
+	var results []SearchResult
+	for _, hit := range esResp.Hits.Hits {
+		title, _ := hit.Source[b.titleField].(string)
+		link, _ := hit.Source[b.urlField].(string)
+		if link == "" {
+			continue
+		}
+		snippet := ""
+		if fragments := hit.Highlight[b.contentField]; len(fragments) > 0 {
+			snippet = stripHTMLTags(fragments[0])
+		} else if content, ok := hit.Source[b.contentField].(string); ok {
+			snippet = truncateText(content, 200)
+		}
+		results = append(results, SearchResult{
+			Title:   title,
+			URL:     link,
+			Snippet: snippet,
+			Source:  BackendElasticsearch,
+		})
+	}
+	return results, nil
+}
@@ -0,0 +1,312 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Multi-query search: the original query runs across all enabled backends, its reformulations
+// across the free ones; the result lists are merged with weighted reciprocal rank fusion.
+const (
+	defaultMultiQueryCount = 3  // Reformulations besides the original query
+	maxMultiQueryCount     = 5  // Upper bound (each one costs a request per free backend)
+	maxParallelSearches    = 6  // Concurrent backend requests
+	rrfK                   = 60 // Reciprocal rank fusion constant (Cormack et al.)
+)
+
+// trackingParams are removed when canonicalising URLs
+var trackingParams = map[string]bool{
+	"gclid": true, "fbclid": true, "msclkid": true, "mc_cid": true, "mc_eid": true,
+	"ref": true, "ref_src": true, "igshid": true, "_ga": true,
+}
+
+// meteredBackends bill or count every request against a quota (Brave: MonthlySearchCount).
+// Reformulations skip them so a chat message costs at most one request there.
+var meteredBackends = map[string]bool{
+	BackendBrave: true, BackendKagi: true, BackendBing: true, BackendGoogle: true,
+}
+
+// QueryRewriter generates up to n reformulations of a search query (e.g. with the local model)
+type QueryRewriter func(ctx context.Context, query string, n int) ([]string, error)
+
+// SubQueryReport shows what a sub-query contributed to the merged results
+type SubQueryReport struct {
+	Query    string   `json:"query"`
+	Original bool     `json:"original"`         // The query as passed to Search
+	Backends []string `json:"backends"`         // Backends that answered
+	Hits     int      `json:"hits"`             // Results before merging
+	Sources  []string `json:"sources"`          // URLs in the final results found by this sub-query
+	Errors   []string `json:"errors,omitempty"` // Failed backend requests
+}
+
+// subQueryOutcome is the raw result of one sub-query on one backend
+type subQueryOutcome struct {
+	results []SearchResult
+	err     error
+	skipped bool // Reformulation on a metered backend - not requested
+}
+
+// fusedResult collects all occurrences of a canonical URL
+type fusedResult struct {
+	result   SearchResult
+	score    float64
+	bestRank int
+	order    int
+	queries  []string
+}
+
+// SetQueryRewriter sets the function that generates query reformulations for multi-query search
+func (s *Service) SetQueryRewriter(rewriter QueryRewriter) {
+	s.settingsMu.Lock()
+	defer s.settingsMu.Unlock()
+	s.rewriter = rewriter
+}
+
+// SearchWithReport searches like Search and additionally returns the sub-query report
+// if multi-query search is enabled (nil otherwise)
+func (s *Service) SearchWithReport(ctx context.Context, query string, opts SearchOptions) ([]SearchResult, []SubQueryReport, error) {
+	if !s.GetSettings().EnableMultiQuery {
+		results, err := s.Search(ctx, query, opts)
+		return results, nil, err
+	}
+	return s.searchMulti(ctx, query, opts)
+}
+
+// searchMulti runs the query across all enabled backends and its reformulations across
+// the free ones, then fuses the results
+func (s *Service) searchMulti(ctx context.Context, query string, opts SearchOptions) ([]SearchResult, []SubQueryReport, error) {
+	if query == "" {
+		return nil, nil, fmt.Errorf("empty query")
+	}
+
+	cacheKey := "multi|" + s.buildCacheKey(query, opts)
+	if entry, ok := s.getCacheEntry(cacheKey); ok {
+		return entry.results, entry.subQueries, nil
+	}
+
+	settings := s.GetSettings()
+	chain := s.fanOutChain(settings)
+	if len(chain) == 0 {
+		return nil, nil, fmt.Errorf("no search backend enabled")
+	}
+	queries := []string{query}
+	if slices.ContainsFunc(chain, func(entry chainEntry) bool { return !meteredBackends[entry.config.Name] }) {
+		queries = s.subQueries(ctx, query, settings.MultiQueryCount)
+	}
+
+	// Fan-out: the original on every backend, reformulations on the free ones
+	outcomes := make([][]subQueryOutcome, len(queries))
+	sem := make(chan struct{}, maxParallelSearches)
+	var wg sync.WaitGroup
+	for qi, q := range queries {
+		outcomes[qi] = make([]subQueryOutcome, len(chain))
+		for bi, entry := range chain {
+			if qi > 0 && meteredBackends[entry.config.Name] {
+				outcomes[qi][bi] = subQueryOutcome{skipped: true}
+				continue
+			}
+			wg.Add(1)
+			go func(qi, bi int, q string, backend Backend) {
+				defer wg.Done()
+				sem <- struct{}{}
+				defer func() { <-sem }()
+				results, err := s.searchBackend(ctx, backend, q, opts)
+				outcomes[qi][bi] = subQueryOutcome{results: results, err: err}
+			}(qi, bi, q, entry.backend)
+		}
+	}
+	wg.Wait()
+
+	var errs []string
+	answered := false
+	for qi := range outcomes {
+		for bi, outcome := range outcomes[qi] {
+			if outcome.skipped {
+				continue
+			}
+			if outcome.err != nil {
+				errs = append(errs, fmt.Sprintf("%s (%s): %v", chain[bi].backend.Name(), queries[qi], outcome.err))
+			} else {
+				answered = true
+			}
+		}
+	}
+	if !answered {
+		return nil, nil, fmt.Errorf("all search backends failed: %s", strings.Join(errs, "; "))
+	}
+
+	results := s.postProcess(fuseResults(queries, chain, outcomes), query, opts)
+	reports := buildSubQueryReports(queries, chain, outcomes, results)
+
+	log.Printf("Multi-Query-Suche: %d Anfragen, %d Backends -> %d Ergebnisse", len(queries), len(chain), len(results))
+	s.putInCacheWithReport(cacheKey, results, reports)
+	return results, reports, nil
+}
+
+// fanOutChain returns the healthy backends (all of them if none is healthy)
+func (s *Service) fanOutChain(settings Settings) []chainEntry {
+	chain := s.backendChain(settings)
+	var healthy []chainEntry
+	for _, entry := range chain {
+		if !entry.cooling {
+			healthy = append(healthy, entry)
+		}
+	}
+	if len(healthy) == 0 {
+		return chain
+	}
+	return healthy
+}
+
+// subQueries returns the original query followed by up to n distinct reformulations
+func (s *Service) subQueries(ctx context.Context, query string, n int) []string {
+	if n <= 0 {
+		n = defaultMultiQueryCount
+	}
+	if n > maxMultiQueryCount {
+		n = maxMultiQueryCount
+	}
+
+	s.settingsMu.RLock()
+	rewriter := s.rewriter
+	s.settingsMu.RUnlock()
+
+	queries := []string{query}
+	if rewriter == nil {
+		return queries
+	}
+	variants, err := rewriter(ctx, query, n)
+	if err != nil {
+		log.Printf("Multi-Query: Umformulierung fehlgeschlagen: %v - nur Originalanfrage", err)
+		return queries
+	}
+
+	seen := map[string]bool{strings.ToLower(strings.TrimSpace(query)): true}
+	for _, v := range variants {
+		v = strings.TrimSpace(v)
+		key := strings.ToLower(v)
+		if v == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		queries = append(queries, v)
+		if len(queries) > n {
+			break
+		}
+	}
+	return queries
+}
+
+// fuseResults merges the result lists with weighted reciprocal rank fusion,
+// deduplicating by canonical URL. The best-ranked occurrence represents the URL.
+func fuseResults(queries []string, chain []chainEntry, outcomes [][]subQueryOutcome) []SearchResult {
+	fused := make(map[string]*fusedResult)
+	for qi, q := range queries {
+		for bi, outcome := range outcomes[qi] {
+			weight := chain[bi].config.EffectiveWeight()
+			for rank, r := range outcome.results {
+				key := canonicalURL(r.URL)
+				if key == "" {
+					continue
+				}
+				f := fused[key]
+				if f == nil {
+					f = &fusedResult{result: r, bestRank: rank, order: len(fused)}
+					fused[key] = f
+				} else if rank < f.bestRank {
+					snippet := f.result.Snippet
+					f.result, f.bestRank = r, rank
+					if f.result.Snippet == "" {
+						f.result.Snippet = snippet
+					}
+				} else if f.result.Snippet == "" {
+					f.result.Snippet = r.Snippet
+				}
+				f.score += weight / float64(rrfK+rank+1)
+				if !slices.Contains(f.queries, q) {
+					f.queries = append(f.queries, q)
+				}
+			}
+		}
+	}
+
+	entries := make([]*fusedResult, 0, len(fused))
+	for _, f := range fused {
+		entries = append(entries, f)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].score != entries[j].score {
+			return entries[i].score > entries[j].score
+		}
+		return entries[i].order < entries[j].order
+	})
+
+	results := make([]SearchResult, len(entries))
+	for i, f := range entries {
+		results[i] = f.result
+		results[i].Queries = f.queries
+	}
+	return results
+}
+
+// buildSubQueryReports lists per sub-query which backends answered and which final sources it found
+func buildSubQueryReports(queries []string, chain []chainEntry, outcomes [][]subQueryOutcome, results []SearchResult) []SubQueryReport {
+	reports := make([]SubQueryReport, len(queries))
+	for qi, q := range queries {
+		report := SubQueryReport{Query: q, Original: qi == 0, Backends: []string{}, Sources: []string{}}
+		for bi, outcome := range outcomes[qi] {
+			if outcome.skipped {
+				continue
+			}
+			name := chain[bi].backend.Name()
+			if outcome.err != nil {
+				report.Errors = append(report.Errors, fmt.Sprintf("%s: %v", name, outcome.err))
+				continue
+			}
+			report.Backends = append(report.Backends, name)
+			report.Hits += len(outcome.results)
+		}
+		for _, r := range results {
+			if slices.Contains(r.Queries, q) {
+				report.Sources = append(report.Sources, r.URL)
+			}
+		}
+		reports[qi] = report
+	}
+	return reports
+}
+
+// canonicalURL normalises a URL for deduplication: scheme, "www.", default ports,
+// fragment, trailing slash and tracking parameters are ignored, query parameters sorted
+func canonicalURL(raw string) string {
+	raw = strings.TrimSpace(raw)
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return strings.ToLower(raw)
+	}
+
+	host := strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+	if port := u.Port(); port != "" && port != "80" && port != "443" {
+		host += ":" + port
+	}
+
+	params := u.Query()
+	for key := range params {
+		lower := strings.ToLower(key)
+		if strings.HasPrefix(lower, "utm_") || trackingParams[lower] {
+			params.Del(key)
+		}
+	}
+
+	canonical := host + strings.TrimSuffix(u.EscapedPath(), "/")
+	if encoded := params.Encode(); encoded != "" {
+		canonical += "?" + encoded
+	}
+	return canonical
+}
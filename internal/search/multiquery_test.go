@@ -0,0 +1,172 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// queryBackend liefert Ergebnisse abhängig von der Anfrage
+type queryBackend struct {
+	name    string
+	results map[string][]SearchResult
+	mu      sync.Mutex
+	queries []string
+}
+
+func (b *queryBackend) Name() string { return b.name }
+func (b *queryBackend) Search(ctx context.Context, query string, opts SearchOptions) ([]SearchResult, error) {
+	b.mu.Lock()
+	b.queries = append(b.queries, query)
+	b.mu.Unlock()
+	if query == "kaputt" {
+		return nil, errors.New("timeout")
+	}
+	return b.results[query], nil
+}
+
+// TestMultiQuerySearch prüft Fan-out, Fusion, Deduplizierung und Report
+func TestMultiQuerySearch(t *testing.T) {
+	s := NewService()
+	web := &queryBackend{name: "web", results: map[string][]SearchResult{
+		"gmbh gründen": {
+			{Title: "Anleitung", URL: "https://www.example.com/gmbh/?utm_source=x", Source: "web"},
+			{Title: "Notar", URL: "https://notar.de/kosten", Source: "web"},
+		},
+		"gmbh gründung kosten": {
+			{Title: "Kosten", URL: "https://notar.de/kosten#tabelle", Snippet: "Notarkosten im Überblick", Source: "web"},
+		},
+	}}
+	wiki := &queryBackend{name: "wiki", results: map[string][]SearchResult{
+		"gmbh gründen": {{Title: "GmbH", URL: "http://example.com/gmbh", Source: "wiki"}},
+	}}
+	s.RegisterBackend("web", func(cfg BackendConfig, settings Settings, env BackendEnv) (Backend, error) { return web, nil })
+	s.RegisterBackend("wiki", func(cfg BackendConfig, settings Settings, env BackendEnv) (Backend, error) { return wiki, nil })
+	s.SetQueryRewriter(func(ctx context.Context, query string, n int) ([]string, error) {
+		return []string{"GmbH gründen", "gmbh gründung kosten", "kaputt", "zu viel"}, nil
+	})
+
+	settings := s.GetSettings()
+	settings.EnableMultiQuery = true
+	settings.MultiQueryCount = 2
+	settings.Backends = []BackendConfig{{Name: "web", Enabled: true}, {Name: "wiki", Enabled: true}}
+	if err := s.UpdateSettings(settings); err != nil {
+		t.Fatal(err)
+	}
+
+	opts := DefaultSearchOptions()
+	opts.ReRank = false
+	results, reports, err := s.SearchWithReport(context.Background(), "gmbh gründen", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Doppelte Umformulierung verworfen, Limit 2 Varianten: Original, Kosten, kaputt
+	if len(reports) != 3 || !reports[0].Original || reports[1].Query != "gmbh gründung kosten" || reports[2].Query != "kaputt" {
+		t.Fatalf("Reports: %+v", reports)
+	}
+	if len(web.queries) != 3 {
+		t.Errorf("Web-Backend: %v", web.queries)
+	}
+
+	// example.com/gmbh von zwei Backends -> vorne; notar.de von zwei Anfragen zusammengeführt,
+	// vertreten durch den besser platzierten Treffer
+	if len(results) != 2 {
+		t.Fatalf("Ergebnisse: %+v", results)
+	}
+	if results[0].URL != "https://www.example.com/gmbh/?utm_source=x" {
+		t.Errorf("Fusion: %+v", results)
+	}
+	if r := results[1]; r.URL != "https://notar.de/kosten#tabelle" || r.Snippet != "Notarkosten im Überblick" || len(r.Queries) != 2 {
+		t.Errorf("Zusammengeführt: %+v", r)
+	}
+
+	if r := reports[0]; r.Hits != 3 || len(r.Backends) != 2 || len(r.Sources) != 2 {
+		t.Errorf("Original: %+v", r)
+	}
+	if r := reports[1]; len(r.Sources) != 1 || r.Sources[0] != "https://notar.de/kosten#tabelle" {
+		t.Errorf("Kosten: %+v", r)
+	}
+	if r := reports[2]; len(r.Sources) != 0 || len(r.Errors) != 2 || !strings.Contains(r.Errors[0], "timeout") {
+		t.Errorf("Fehler: %+v", r)
+	}
+
+	// Zweiter Aufruf aus dem Cache, einfaches Search nutzt denselben Weg
+	if cached, err := s.Search(context.Background(), "gmbh gründen", opts); err != nil || len(cached) != 2 || len(web.queries) != 3 {
+		t.Errorf("Cache: %v %+v", err, cached)
+	}
+}
+
+// TestMultiQueryMeteredBackends prüft, dass Umformulierungen kein Brave-Kontingent verbrauchen
+func TestMultiQueryMeteredBackends(t *testing.T) {
+	s := NewService()
+	brave := &queryBackend{name: BackendBrave}
+	free := &queryBackend{name: "web"}
+	// Eingebautes Brave ersetzen (RegisterBackend lehnt vorhandene Namen ab)
+	s.factories[BackendBrave] = func(cfg BackendConfig, settings Settings, env BackendEnv) (Backend, error) { return brave, nil }
+	s.RegisterBackend("web", func(cfg BackendConfig, settings Settings, env BackendEnv) (Backend, error) { return free, nil })
+	rewrites := 0
+	s.SetQueryRewriter(func(ctx context.Context, query string, n int) ([]string, error) {
+		rewrites++
+		return []string{"variante eins", "variante zwei"}, nil
+	})
+
+	settings := s.GetSettings()
+	settings.EnableMultiQuery = true
+	settings.Backends = []BackendConfig{{Name: BackendBrave, Enabled: true}, {Name: "web", Enabled: true}}
+	if err := s.UpdateSettings(settings); err != nil {
+		t.Fatal(err)
+	}
+
+	opts := DefaultSearchOptions()
+	opts.ReRank = false
+	_, reports, err := s.SearchWithReport(context.Background(), "steuer", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(brave.queries) != 1 || brave.queries[0] != "steuer" || len(free.queries) != 3 {
+		t.Errorf("Brave: %v, Web: %v", brave.queries, free.queries)
+	}
+	if s.GetSettings().MonthlySearchCount != 1 {
+		t.Errorf("Kontingent: %d", s.GetSettings().MonthlySearchCount)
+	}
+	if r := reports[1]; len(r.Backends) != 1 || len(r.Errors) != 0 {
+		t.Errorf("Variante: %+v", r)
+	}
+
+	// Nur kostenpflichtige Backends: keine Umformulierungen, nur die Originalanfrage
+	settings.Backends = []BackendConfig{{Name: BackendBrave, Enabled: true}}
+	if err := s.UpdateSettings(settings); err != nil {
+		t.Fatal(err)
+	}
+	_, reports, err = s.SearchWithReport(context.Background(), "rente", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reports) != 1 || rewrites != 1 || len(brave.queries) != 2 {
+		t.Errorf("Nur Brave: %+v, %d Umformulierungen, %v", reports, rewrites, brave.queries)
+	}
+}
+
+// TestCanonicalURL prüft die URL-Normalisierung für die Deduplizierung
+func TestCanonicalURL(t *testing.T) {
+	same := [][2]string{
+		{"https://www.example.com/a/", "http://example.com/a"},
+		{"https://example.com:443/a?b=2&a=1", "https://example.com/a?a=1&b=2"},
+		{"https://example.com/a?utm_medium=x&id=5&fbclid=y#top", "https://example.com/a?id=5"},
+		{"HTTPS://Example.COM/a", "https://example.com/a"},
+	}
+	for _, c := range same {
+		if canonicalURL(c[0]) != canonicalURL(c[1]) {
+			t.Errorf("%s != %s (%s, %s)", c[0], c[1], canonicalURL(c[0]), canonicalURL(c[1]))
+		}
+	}
+	if canonicalURL("https://example.com/a?id=5") == canonicalURL("https://example.com/a?id=6") {
+		t.Error("Unterschiedliche Parameter zusammengeführt")
+	}
+	if canonicalURL("https://example.com:8080/a") == canonicalURL("https://example.com/a") {
+		t.Error("Abweichender Port ignoriert")
+	}
+}
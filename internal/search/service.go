@@ -2,12 +2,12 @@ package search
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
 	"regexp"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -27,12 +27,13 @@ const (
 
 // SearchResult represents a single search result
 type SearchResult struct {
-	Title       string `json:"title"`
-	URL         string `json:"url"`
-	Snippet     string `json:"snippet"`
-	Content     string `json:"content,omitempty"` // Full content if fetched
-	Source      string `json:"source"`            // brave, searxng, duckduckgo
-	Relevance   int    `json:"relevance,omitempty"`
+	Title     string   `json:"title"`
+	URL       string   `json:"url"`
+	Snippet   string   `json:"snippet"`
+	Content   string   `json:"content,omitempty"` // Full content if fetched
+	Source    string   `json:"source"`            // Backend name (brave, searxng, duckduckgo, ...)
+	Relevance int      `json:"relevance,omitempty"`
+	Queries   []string `json:"queries,omitempty"` // Multi-Query: sub-queries that found this result
 }
 
 // SearchOptions configures search behavior
@@ -61,16 +62,18 @@ func DefaultSearchOptions() SearchOptions {
 
 // Settings holds WebSearch configuration
 type Settings struct {
-	BraveAPIKey         string   `json:"braveApiKey,omitempty"`
-	SearXNGInstances    []string `json:"searxngInstances"`
-	CustomSearXNG       string   `json:"customSearxng,omitempty"`
-	EnableQueryOptimize bool     `json:"enableQueryOptimize"`
-	EnableContentFetch  bool     `json:"enableContentFetch"`
-	EnableReRanking     bool     `json:"enableReRanking"`
-	EnableMultiQuery    bool     `json:"enableMultiQuery"`
-	OptimizationModel   string   `json:"optimizationModel,omitempty"`
-	MonthlySearchCount  int      `json:"monthlySearchCount"`
-	CurrentMonth        string   `json:"currentMonth"`
+	BraveAPIKey         string          `json:"braveApiKey,omitempty"`
+	SearXNGInstances    []string        `json:"searxngInstances"`
+	CustomSearXNG       string          `json:"customSearxng,omitempty"`
+	EnableQueryOptimize bool            `json:"enableQueryOptimize"`
+	EnableContentFetch  bool            `json:"enableContentFetch"`
+	EnableReRanking     bool            `json:"enableReRanking"`
+	EnableMultiQuery    bool            `json:"enableMultiQuery"`
+	MultiQueryCount     int             `json:"multiQueryCount,omitempty"` // Umformulierungen je Suche (Default 3, max 5)
+	OptimizationModel   string          `json:"optimizationModel,omitempty"`
+	Backends            []BackendConfig `json:"backends,omitempty"` // Fallback-Kette in Reihenfolge (leer = DefaultBackends)
+	MonthlySearchCount  int             `json:"monthlySearchCount"`
+	CurrentMonth        string          `json:"currentMonth"`
 }
 
 // DefaultSettings returns default configuration
@@ -88,13 +91,15 @@ func DefaultSettings() Settings {
 		EnableContentFetch:  true,
 		EnableReRanking:     true,
 		OptimizationModel:   "llama3.2:3b",
+		Backends:            DefaultBackends(),
 	}
 }
 
 // cacheEntry stores cached results
 type cacheEntry struct {
-	results   []SearchResult
-	timestamp time.Time
+	results    []SearchResult
+	subQueries []SubQueryReport // Multi-Query only
+	timestamp  time.Time
 }
 
 type contentCacheEntry struct {
@@ -106,6 +111,7 @@ type contentCacheEntry struct {
 type Service struct {
 	client       *http.Client
 	settings     Settings
+	rewriter     QueryRewriter // Multi-Query reformulations (set by the Navigator, uses the local model)
 	settingsMu   sync.RWMutex
 
 	// Caches
@@ -118,11 +124,16 @@ type Service struct {
 	contentCacheTTL time.Duration
 
 	userAgent string
+
+	// Backends: registered factories and health stats per backend name
+	factories  map[string]BackendFactory
+	stats      map[string]*backendStats
+	backendsMu sync.RWMutex
 }
 
 // NewService creates a new search service
 func NewService() *Service {
-	return &Service{
+	s := &Service{
 		client: &http.Client{
 			Timeout: 15 * time.Second,
 		},
@@ -132,7 +143,11 @@ func NewService() *Service {
 		searchCacheTTL:  15 * time.Minute,
 		contentCacheTTL: 30 * time.Minute,
 		userAgent:       "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36",
+		factories:       make(map[string]BackendFactory),
+		stats:           make(map[string]*backendStats),
 	}
+	s.registerBuiltinBackends()
+	return s
 }
 
 // GetSettings returns current settings
@@ -142,11 +157,16 @@ func (s *Service) GetSettings() Settings {
 	return s.settings
 }
 
-// UpdateSettings updates settings
-func (s *Service) UpdateSettings(settings Settings) {
+// UpdateSettings updates settings (the backend chain is validated against the registered backends)
+func (s *Service) UpdateSettings(settings Settings) error {
+	if err := s.validateBackends(settings.Backends); err != nil {
+		return err
+	}
+
 	s.settingsMu.Lock()
 	defer s.settingsMu.Unlock()
 	s.settings = settings
+	return nil
 }
 
 // Search performs a web search with options
@@ -155,457 +175,47 @@ func (s *Service) Search(ctx context.Context, query string, opts SearchOptions)
 		return nil, fmt.Errorf("empty query")
 	}
 
+	settings := s.GetSettings()
+	if settings.EnableMultiQuery {
+		results, _, err := s.searchMulti(ctx, query, opts)
+		return results, err
+	}
+
 	// Check cache
 	cacheKey := s.buildCacheKey(query, opts)
 	if cached := s.getFromCache(cacheKey); cached != nil {
 		return cached, nil
 	}
 
-	var results []SearchResult
-	var err error
-
-	s.settingsMu.RLock()
-	hasBraveKey := s.settings.BraveAPIKey != ""
-	s.settingsMu.RUnlock()
+	chain := s.backendChain(settings)
+	if len(chain) == 0 {
+		return nil, fmt.Errorf("no search backend enabled")
+	}
 
-	// Try Brave API first if available
-	if hasBraveKey {
-		results, err = s.searchBrave(ctx, query, opts)
+	// Fallback chain: first backend with results wins
+	var errs []string
+	answered := false
+	for _, entry := range chain {
+		results, err := s.searchBackend(ctx, entry.backend, query, opts)
 		if err == nil && len(results) > 0 {
 			results = s.postProcess(results, query, opts)
 			s.putInCache(cacheKey, results)
 			return results, nil
 		}
-	}
-
-	// Fallback to SearXNG
-	results, err = s.searchSearXNG(ctx, query, opts)
-	if err == nil && len(results) > 0 {
-		results = s.postProcess(results, query, opts)
-		s.putInCache(cacheKey, results)
-		return results, nil
-	}
-
-	// Final fallback to DuckDuckGo
-	results, err = s.searchDuckDuckGo(ctx, query, opts)
-	if err != nil {
-		return nil, fmt.Errorf("all search backends failed: %w", err)
-	}
-
-	results = s.postProcess(results, query, opts)
-	s.putInCache(cacheKey, results)
-	return results, nil
-}
-
-// searchBrave uses Brave Search API
-func (s *Service) searchBrave(ctx context.Context, query string, opts SearchOptions) ([]SearchResult, error) {
-	s.settingsMu.RLock()
-	apiKey := s.settings.BraveAPIKey
-	s.settingsMu.RUnlock()
-
-	if apiKey == "" {
-		return nil, fmt.Errorf("no Brave API key configured")
-	}
-
-	// Build Brave API URL
-	apiURL := fmt.Sprintf(
-		"https://api.search.brave.com/res/v1/web/search?q=%s&count=%d",
-		url.QueryEscape(query),
-		opts.MaxResults,
-	)
-
-	// Add time filter
-	if opts.TimeFilter != "" {
-		freshnessMap := map[TimeFilter]string{
-			TimeFilterDay:   "pd",
-			TimeFilterWeek:  "pw",
-			TimeFilterMonth: "pm",
-			TimeFilterYear:  "py",
-		}
-		if freshness, ok := freshnessMap[opts.TimeFilter]; ok {
-			apiURL += "&freshness=" + freshness
-		}
-	}
-
-	// Add region
-	if opts.Region != "" {
-		apiURL += "&country=" + strings.Split(opts.Region, "-")[0]
-	}
-
-	req, err := http.NewRequestWithContext(ctx, "GET", apiURL, nil)
-	if err != nil {
-		return nil, err
-	}
-
-	req.Header.Set("Accept", "application/json")
-	req.Header.Set("X-Subscription-Token", apiKey)
-
-	resp, err := s.client.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("Brave API returned %d", resp.StatusCode)
-	}
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
-	}
-
-	// Parse Brave response
-	var braveResp struct {
-		Web struct {
-			Results []struct {
-				Title       string `json:"title"`
-				URL         string `json:"url"`
-				Description string `json:"description"`
-			} `json:"results"`
-		} `json:"web"`
-	}
-
-	if err := json.Unmarshal(body, &braveResp); err != nil {
-		return nil, err
-	}
-
-	var results []SearchResult
-	for _, r := range braveResp.Web.Results {
-		results = append(results, SearchResult{
-			Title:   r.Title,
-			URL:     r.URL,
-			Snippet: r.Description,
-			Source:  "brave",
-		})
-	}
-
-	// Increment monthly counter
-	s.incrementSearchCount()
-
-	return results, nil
-}
-
-// searchSearXNG uses SearXNG instances
-func (s *Service) searchSearXNG(ctx context.Context, query string, opts SearchOptions) ([]SearchResult, error) {
-	s.settingsMu.RLock()
-	instances := s.settings.SearXNGInstances
-	customInstance := s.settings.CustomSearXNG
-	s.settingsMu.RUnlock()
-
-	// Try custom instance first
-	if customInstance != "" {
-		instances = append([]string{customInstance}, instances...)
-	}
-
-	var lastErr error
-	for _, instance := range instances {
-		results, err := s.searchSearXNGInstance(ctx, instance, query, opts)
-		if err == nil && len(results) > 0 {
-			return results, nil
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", entry.backend.Name(), err))
+		} else {
+			answered = true
 		}
-		lastErr = err
-	}
-
-	if lastErr != nil {
-		return nil, fmt.Errorf("all SearXNG instances failed: %w", lastErr)
-	}
-	return nil, fmt.Errorf("no results from SearXNG")
-}
-
-func (s *Service) searchSearXNGInstance(ctx context.Context, instance, query string, opts SearchOptions) ([]SearchResult, error) {
-	// Build SearXNG URL
-	apiURL := fmt.Sprintf(
-		"%s/search?q=%s&format=json&language=%s",
-		strings.TrimSuffix(instance, "/"),
-		url.QueryEscape(query),
-		opts.Region,
-	)
-
-	// Add time filter
-	if opts.TimeFilter != "" {
-		apiURL += "&time_range=" + string(opts.TimeFilter)
-	}
-
-	req, err := http.NewRequestWithContext(ctx, "GET", apiURL, nil)
-	if err != nil {
-		return nil, err
-	}
-
-	req.Header.Set("User-Agent", s.userAgent)
-	req.Header.Set("Accept", "application/json")
-
-	resp, err := s.client.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("SearXNG returned %d", resp.StatusCode)
-	}
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
-	}
-
-	// Parse SearXNG response
-	var searxResp struct {
-		Results []struct {
-			Title   string `json:"title"`
-			URL     string `json:"url"`
-			Content string `json:"content"`
-		} `json:"results"`
-	}
-
-	if err := json.Unmarshal(body, &searxResp); err != nil {
-		return nil, err
-	}
-
-	var results []SearchResult
-	for i, r := range searxResp.Results {
-		if i >= opts.MaxResults {
+		if ctx.Err() != nil {
 			break
 		}
-		results = append(results, SearchResult{
-			Title:   r.Title,
-			URL:     r.URL,
-			Snippet: r.Content,
-			Source:  "searxng",
-		})
 	}
 
-	return results, nil
-}
-
-// searchDuckDuckGo as fallback
-func (s *Service) searchDuckDuckGo(ctx context.Context, query string, opts SearchOptions) ([]SearchResult, error) {
-	// Try instant answer API first
-	apiURL := fmt.Sprintf(
-		"https://api.duckduckgo.com/?q=%s&format=json&no_html=1&skip_disambig=1&kl=%s",
-		url.QueryEscape(query),
-		url.QueryEscape(opts.Region),
-	)
-
-	req, err := http.NewRequestWithContext(ctx, "GET", apiURL, nil)
-	if err != nil {
-		return nil, err
+	if !answered {
+		return nil, fmt.Errorf("all search backends failed: %s", strings.Join(errs, "; "))
 	}
-	req.Header.Set("User-Agent", s.userAgent)
-
-	resp, err := s.client.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
-	}
-
-	var ddgResp struct {
-		Abstract       string `json:"Abstract"`
-		AbstractSource string `json:"AbstractSource"`
-		AbstractURL    string `json:"AbstractURL"`
-		RelatedTopics  []struct {
-			Text     string `json:"Text"`
-			FirstURL string `json:"FirstURL"`
-		} `json:"RelatedTopics"`
-		Results []struct {
-			Text     string `json:"Text"`
-			FirstURL string `json:"FirstURL"`
-		} `json:"Results"`
-	}
-
-	if err := json.Unmarshal(body, &ddgResp); err != nil {
-		return nil, err
-	}
-
-	var results []SearchResult
-
-	// Add abstract
-	if ddgResp.Abstract != "" {
-		results = append(results, SearchResult{
-			Title:   ddgResp.AbstractSource,
-			URL:     ddgResp.AbstractURL,
-			Snippet: ddgResp.Abstract,
-			Source:  "duckduckgo",
-		})
-	}
-
-	// Add direct results
-	for _, r := range ddgResp.Results {
-		if len(results) >= opts.MaxResults {
-			break
-		}
-		results = append(results, SearchResult{
-			Title:   extractTitle(r.Text),
-			URL:     r.FirstURL,
-			Snippet: r.Text,
-			Source:  "duckduckgo",
-		})
-	}
-
-	// Add related topics
-	for _, t := range ddgResp.RelatedTopics {
-		if len(results) >= opts.MaxResults {
-			break
-		}
-		if t.FirstURL != "" {
-			results = append(results, SearchResult{
-				Title:   extractTitle(t.Text),
-				URL:     t.FirstURL,
-				Snippet: t.Text,
-				Source:  "duckduckgo",
-			})
-		}
-	}
-
-	// Fallback to HTML scraping if no results
-	if len(results) == 0 {
-		return s.searchDuckDuckGoHTML(ctx, query, opts)
-	}
-
-	return results, nil
-}
-
-// searchDuckDuckGoHTML scrapes DuckDuckGo HTML version
-func (s *Service) searchDuckDuckGoHTML(ctx context.Context, query string, opts SearchOptions) ([]SearchResult, error) {
-	// Use html.duckduckgo.com - more reliable than lite version
-	searchURL := fmt.Sprintf(
-		"https://html.duckduckgo.com/html/?q=%s&kl=%s",
-		url.QueryEscape(query),
-		url.QueryEscape(opts.Region),
-	)
-
-	req, err := http.NewRequestWithContext(ctx, "GET", searchURL, nil)
-	if err != nil {
-		return nil, err
-	}
-	// Set headers to mimic a real browser
-	req.Header.Set("User-Agent", s.userAgent)
-	req.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,image/webp,*/*;q=0.8")
-	req.Header.Set("Accept-Language", "de-DE,de;q=0.9,en;q=0.8")
-	req.Header.Set("DNT", "1")
-
-	resp, err := s.client.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
-	}
-
-	return s.parseDDGHTML(string(body), opts.MaxResults), nil
-}
-
-// parseDDGHTML parses the html.duckduckgo.com results page
-func (s *Service) parseDDGHTML(htmlContent string, maxResults int) []SearchResult {
-	var results []SearchResult
-
-	// Parse using regex patterns for result__a (title/link) and result__snippet
-	// Pattern for result links: class="result__a" href="URL">Title</a>
-	linkPattern := regexp.MustCompile(`class="result__a"[^>]*href="([^"]+)"[^>]*>([^<]+)</a>`)
-	// Pattern for snippets: class="result__snippet">...content...</a>
-	snippetPattern := regexp.MustCompile(`class="result__snippet"[^>]*>([^<]+)`)
-
-	linkMatches := linkPattern.FindAllStringSubmatch(htmlContent, -1)
-	snippetMatches := snippetPattern.FindAllStringSubmatch(htmlContent, -1)
-
-	for i, match := range linkMatches {
-		if len(results) >= maxResults {
-			break
-		}
-		if len(match) < 3 {
-			continue
-		}
-
-		resultURL := match[1]
-		resultTitle := strings.TrimSpace(match[2])
-
-		// Skip DuckDuckGo internal links
-		if strings.Contains(resultURL, "duckduckgo.com") {
-			continue
-		}
-
-		// Decode redirect URL if present
-		if strings.HasPrefix(resultURL, "//duckduckgo.com/l/?uddg=") {
-			if decoded, err := url.QueryUnescape(strings.TrimPrefix(resultURL, "//duckduckgo.com/l/?uddg=")); err == nil {
-				// Extract actual URL (before &rut=)
-				if idx := strings.Index(decoded, "&"); idx != -1 {
-					resultURL = decoded[:idx]
-				} else {
-					resultURL = decoded
-				}
-			}
-		}
-
-		snippet := ""
-		if i < len(snippetMatches) && len(snippetMatches[i]) > 1 {
-			snippet = strings.TrimSpace(snippetMatches[i][1])
-		}
-
-		results = append(results, SearchResult{
-			Title:   stripHTMLTags(resultTitle),
-			URL:     resultURL,
-			Snippet: stripHTMLTags(snippet),
-			Source:  "duckduckgo",
-		})
-	}
-
-	return results
-}
-
-func (s *Service) parseDDGLiteHTML(htmlContent string, maxResults int) []SearchResult {
-	var results []SearchResult
-	lines := strings.Split(htmlContent, "\n")
-	var currentTitle, currentURL, currentDesc string
-
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-
-		if strings.Contains(line, `class="result-link"`) || strings.Contains(line, `rel="nofollow"`) {
-			if hrefStart := strings.Index(line, `href="`); hrefStart != -1 {
-				hrefStart += 6
-				if hrefEnd := strings.Index(line[hrefStart:], `"`); hrefEnd != -1 {
-					currentURL = line[hrefStart : hrefStart+hrefEnd]
-				}
-			}
-			if titleStart := strings.Index(line, ">"); titleStart != -1 {
-				if titleEnd := strings.Index(line[titleStart:], "</a>"); titleEnd != -1 {
-					currentTitle = strings.TrimSpace(line[titleStart+1 : titleStart+titleEnd])
-					currentTitle = stripHTMLTags(currentTitle)
-				}
-			}
-		}
-
-		if strings.Contains(line, `class="result-snippet"`) {
-			currentDesc = stripHTMLTags(line)
-		}
-
-		if currentURL != "" && currentTitle != "" && !strings.Contains(currentURL, "duckduckgo.com") {
-			results = append(results, SearchResult{
-				Title:   currentTitle,
-				URL:     currentURL,
-				Snippet: currentDesc,
-				Source:  "duckduckgo",
-			})
-			currentTitle = ""
-			currentURL = ""
-			currentDesc = ""
-
-			if len(results) >= maxResults {
-				break
-			}
-		}
-	}
-
-	return results
+	return []SearchResult{}, nil
 }
 
 // postProcess applies filtering, re-ranking and content fetching
@@ -703,14 +313,10 @@ func (s *Service) reRankResults(results []SearchResult, query string) []SearchRe
 		results[i].Relevance = score
 	}
 
-	// Sort by relevance (simple bubble sort, results are usually small)
-	for i := 0; i < len(results)-1; i++ {
-		for j := i + 1; j < len(results); j++ {
-			if results[j].Relevance > results[i].Relevance {
-				results[i], results[j] = results[j], results[i]
-			}
-		}
-	}
+	// Sort by relevance, stable so ties keep the backend (or fusion) order
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Relevance > results[j].Relevance
+	})
 
 	return results
 }
@@ -903,18 +509,29 @@ func (s *Service) buildCacheKey(query string, opts SearchOptions) string {
 }
 
 func (s *Service) getFromCache(key string) []SearchResult {
+	if entry, ok := s.getCacheEntry(key); ok {
+		return entry.results
+	}
+	return nil
+}
+
+func (s *Service) getCacheEntry(key string) (cacheEntry, bool) {
 	s.cacheMu.RLock()
 	defer s.cacheMu.RUnlock()
 
 	if entry, ok := s.searchCache[key]; ok {
 		if time.Since(entry.timestamp) < s.searchCacheTTL {
-			return entry.results
+			return entry, true
 		}
 	}
-	return nil
+	return cacheEntry{}, false
 }
 
 func (s *Service) putInCache(key string, results []SearchResult) {
+	s.putInCacheWithReport(key, results, nil)
+}
+
+func (s *Service) putInCacheWithReport(key string, results []SearchResult, subQueries []SubQueryReport) {
 	s.cacheMu.Lock()
 	defer s.cacheMu.Unlock()
 
@@ -930,8 +547,9 @@ func (s *Service) putInCache(key string, results []SearchResult) {
 	}
 
 	s.searchCache[key] = cacheEntry{
-		results:   results,
-		timestamp: time.Now(),
+		results:    results,
+		subQueries: subQueries,
+		timestamp:  time.Now(),
 	}
 }
 
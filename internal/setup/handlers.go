@@ -19,6 +19,9 @@ import (
 	"strings"
 	"sync"
 	"time"
+
+	"fleet-navigator/internal/gguf"
+	"fleet-navigator/internal/integrity"
 )
 
 // i18n translations for setup messages
@@ -724,7 +727,7 @@ func (h *APIHandler) HandleComplete(w http.ResponseWriter, r *http.Request) {
 		}
 
 		// Anzeigename für UI ableiten
-		displayModelName = deriveDisplayName(state.SelectedModel)
+		displayModelName = deriveDisplayName(modelPath)
 		log.Printf("[Setup] Display-Modellname: %s", displayModelName)
 
 		// WICHTIG: Laufende Services aktualisieren!
@@ -808,11 +811,18 @@ func (h *APIHandler) HandleComplete(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-// deriveDisplayName leitet einen Anzeigenamen aus dem GGUF-Dateinamen ab
-func deriveDisplayName(ggufFilename string) string {
+// deriveDisplayName leitet einen Anzeigenamen für ein GGUF-Modell ab.
+// Bevorzugt general.name aus dem GGUF-Header, sonst aus dem Dateinamen.
+func deriveDisplayName(modelPath string) string {
+	if info, err := gguf.ReadInfo(modelPath); err == nil {
+		if name := info.DisplayName(); name != "" {
+			return name
+		}
+	}
+
 	// qwen2.5-1.5b-instruct-q4_k_m.gguf -> Qwen 2.5 1.5B
 	// Qwen2.5-7B-Instruct-Q5_K_M.gguf -> Qwen 2.5 7B
-	name := strings.TrimSuffix(ggufFilename, ".gguf")
+	name := strings.TrimSuffix(filepath.Base(modelPath), ".gguf")
 	name = strings.ToLower(name)
 
 	// Bekannte Muster erkennen
@@ -1584,6 +1594,9 @@ func (d *HuggingFaceDownloader) DownloadModel(modelID string, progressCh chan<-
 		log.Printf("[Setup] Primäre Download URL: %s", huggingfaceURL)
 	}
 
+	// Erwartete SHA-256 aus den HuggingFace LFS-Metadaten - gilt auch für den Mirror
+	expectedSHA256 := integrity.FetchExpectedSHA256(huggingfaceURL)
+
 	var err error
 
 	// Strategie: Mirror ZUERST (schneller, stabiler), HuggingFace als Fallback
@@ -1594,6 +1607,9 @@ func (d *HuggingFaceDownloader) DownloadModel(modelID string, progressCh chan<-
 			Percent: 0,
 		}
 		_, err = d.tryDownloadWithSpeedCheck(mirrorURL, destPath, modelID, progressCh, lang)
+		if err == nil {
+			err = d.verifyDownload(destPath, expectedSHA256, progressCh)
+		}
 
 		if err == nil {
 			log.Printf("[Setup] ✅ Download von Mirror erfolgreich!")
@@ -1610,6 +1626,9 @@ func (d *HuggingFaceDownloader) DownloadModel(modelID string, progressCh chan<-
 				Percent: 0,
 			}
 			_, err = d.tryDownloadWithSpeedCheck(huggingfaceURL, destPath, modelID, progressCh, lang)
+			if err == nil {
+				err = d.verifyDownload(destPath, expectedSHA256, progressCh)
+			}
 		}
 	} else {
 		// Mirror deaktiviert - direkt von HuggingFace
@@ -1619,11 +1638,32 @@ func (d *HuggingFaceDownloader) DownloadModel(modelID string, progressCh chan<-
 			Percent: 0,
 		}
 		_, err = d.tryDownloadWithSpeedCheck(huggingfaceURL, destPath, modelID, progressCh, lang)
+		if err == nil {
+			err = d.verifyDownload(destPath, expectedSHA256, progressCh)
+		}
 	}
 
 	return err
 }
 
+// verifyDownload prüft die SHA-256 eines heruntergeladenen Modells.
+// Beschädigte Dateien werden gelöscht, damit ein erneuter Download neu beginnt.
+func (d *HuggingFaceDownloader) verifyDownload(destPath, expectedSHA256 string, progressCh chan<- SetupProgress) error {
+	progressCh <- SetupProgress{
+		Step:    "model",
+		Message: "Prüfe SHA-256...",
+		Percent: 100,
+	}
+
+	if _, err := integrity.VerifyDownload(destPath, expectedSHA256, integrity.SourceHuggingFace); err != nil {
+		log.Printf("[Setup] ❌ Download beschädigt, lösche %s: %v", filepath.Base(destPath), err)
+		os.Remove(destPath)
+		integrity.Forget(destPath)
+		return fmt.Errorf("Download-Prüfung fehlgeschlagen: %w", err)
+	}
+	return nil
+}
+
 // tryDownloadWithSpeedCheck versucht Download und gibt die Geschwindigkeit zurück
 // Wenn Geschwindigkeit unter MinSpeedMBps fällt, wird der Download abgebrochen
 // Rückgabe: (durchschnittliche Geschwindigkeit in MB/s, Fehler)
@@ -3900,7 +3940,7 @@ func (h *APIHandler) HandleSetupSummary(w http.ResponseWriter, r *http.Request)
 			installed = true
 		}
 		summary.LLMModel = &ComponentStatus{
-			Name:        deriveDisplayName(state.SelectedModel),
+			Name:        deriveDisplayName(modelPath),
 			Installed:   installed,
 			Description: "KI-Sprachmodell für Textverarbeitung",
 		}
@@ -333,3 +333,55 @@ func TestAllMirrorPathsEndWithSlash(t *testing.T) {
 		}
 	}
 }
+
+// TestModelRecommendationsUseBenchmarks prüft dass gemessene Leistung die Faustregel übersteuert
+func TestModelRecommendationsUseBenchmarks(t *testing.T) {
+	s := NewService(t.TempDir())
+	s.state.SystemInfo = &SystemInfo{TotalRAM: 64, HasGPU: true, GPUName: "RTX 3060", GPUMemory: 12}
+
+	// Faustregel: größtes passendes Modell (Gemma 2 9B mit 10 GB VRAM)
+	if best := recommended(s.GetModelRecommendations()); best != "gemma-2-9b-it-Q4_K_M.gguf" {
+		t.Fatalf("Ohne Messung empfohlen: %s", best)
+	}
+
+	// Gemessen: Gemma zu langsam, Llama 8B flüssig
+	s.SetPerformanceSource(func(modelID string) *MeasuredPerformance {
+		switch modelID {
+		case "gemma-2-9b-it-Q4_K_M.gguf":
+			return &MeasuredPerformance{GenTPS: 5}
+		case "Meta-Llama-3.1-8B-Instruct-Q4_K_M.gguf":
+			return &MeasuredPerformance{GenTPS: 42, TTFTMs: 800}
+		}
+		return nil
+	})
+	recs := s.GetModelRecommendations()
+	if best := recommended(recs); best != "Meta-Llama-3.1-8B-Instruct-Q4_K_M.gguf" {
+		t.Errorf("Mit Messung empfohlen: %s", best)
+	}
+	for _, rec := range recs {
+		if rec.ModelID == "gemma-2-9b-it-Q4_K_M.gguf" && (rec.Measured == nil || rec.Recommended) {
+			t.Errorf("Gemma: %+v", rec)
+		}
+	}
+
+	// Nur langsame Messung: nächstkleineres ungemessenes Modell
+	s.SetPerformanceSource(func(modelID string) *MeasuredPerformance {
+		if modelID == "gemma-2-9b-it-Q4_K_M.gguf" {
+			return &MeasuredPerformance{GenTPS: 3}
+		}
+		return nil
+	})
+	if best := recommended(s.GetModelRecommendations()); best != "Meta-Llama-3.1-8B-Instruct-Q4_K_M.gguf" {
+		t.Errorf("Nach langsamer Messung empfohlen: %s", best)
+	}
+}
+
+// recommended gibt das empfohlene Modell zurück
+func recommended(recs []ModelRecommendation) string {
+	for _, rec := range recs {
+		if rec.Recommended {
+			return rec.ModelID
+		}
+	}
+	return ""
+}
@@ -7,6 +7,7 @@ import (
 	"path/filepath"
 	"runtime"
 	"sync"
+	"time"
 )
 
 // WizardState repräsentiert den aktuellen Zustand des Setup-Wizards
@@ -67,8 +68,26 @@ type ModelRecommendation struct {
 	Reason      string  `json:"reason,omitempty"`
 	MinRAMGB    int64   `json:"minRamGB"`
 	MinVRAMGB   int64   `json:"minVramGB"`
+	// Measured: Benchmark-Ergebnis auf diesem Rechner (nil = nicht gemessen)
+	Measured *MeasuredPerformance `json:"measured,omitempty"`
 }
 
+// MeasuredPerformance ist die auf diesem Rechner gemessene Leistung eines Modells
+type MeasuredPerformance struct {
+	GenTPS      float64   `json:"genTps"`    // Generierung in Tokens/s
+	PromptTPS   float64   `json:"promptTps"` // Prompt-Verarbeitung in Tokens/s
+	TTFTMs      float64   `json:"ttftMs"`    // Zeit bis zum ersten Token
+	PeakVRAMMB  int64     `json:"peakVramMB"`
+	ContextSize int       `json:"contextSize"`
+	MeasuredAt  time.Time `json:"measuredAt"`
+}
+
+// PerformanceSource liefert Benchmark-Ergebnisse je Modelldatei (nil = nicht gemessen)
+type PerformanceSource func(modelID string) *MeasuredPerformance
+
+// minFluentGenTPS: ab dieser Generierungsgeschwindigkeit fühlt sich ein Chat flüssig an
+const minFluentGenTPS = 8.0
+
 // SetupProgress enthält Fortschrittsinformationen
 type SetupProgress struct {
 	Step        string  `json:"step"`
@@ -87,6 +106,7 @@ type Service struct {
 	state       *WizardState
 	mu          sync.RWMutex
 	progressCh  chan SetupProgress
+	performance PerformanceSource // Gemessene Leistung (Benchmarks), optional
 }
 
 // NewService erstellt einen neuen Setup-Service
@@ -145,6 +165,14 @@ func (s *Service) GetSystemInfo() (*SystemInfo, error) {
 	return info, nil
 }
 
+// SetPerformanceSource setzt die Quelle für gemessene Modell-Leistung.
+// Gemessene Werte haben bei den Empfehlungen Vorrang vor der Faustregel.
+func (s *Service) SetPerformanceSource(source PerformanceSource) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.performance = source
+}
+
 // GetModelRecommendations gibt Modell-Empfehlungen basierend auf dem System zurück
 // Hardware-Klassifizierung:
 // - < 8GB RAM ohne GPU: ⛔ Nicht unterstützt
@@ -156,6 +184,7 @@ func (s *Service) GetSystemInfo() (*SystemInfo, error) {
 func (s *Service) GetModelRecommendations() []ModelRecommendation {
 	s.mu.RLock()
 	sysInfo := s.state.SystemInfo
+	performance := s.performance
 	s.mu.RUnlock()
 
 	// Alle verfügbaren Modelle mit Hardware-Anforderungen
@@ -207,6 +236,7 @@ func (s *Service) GetModelRecommendations() []ModelRecommendation {
 
 	recommendations := make([]ModelRecommendation, 0, len(models))
 	var bestIndex int = -1
+	measuredBest := -1 // Größtes gemessen flüssig laufendes Modell
 
 	for i, m := range models {
 		rec := ModelRecommendation{
@@ -254,9 +284,32 @@ func (s *Service) GetModelRecommendations() []ModelRecommendation {
 			}
 		}
 
+		// Benchmark auf diesem Rechner hat Vorrang vor der Faustregel
+		if performance != nil {
+			if perf := performance(m.ID); perf != nil {
+				rec.Measured = perf
+				rec.Available = true
+				if perf.GenTPS >= minFluentGenTPS {
+					rec.Reason = fmt.Sprintf("✓ Gemessen: %.0f Tokens/s, erste Antwort nach %.1fs", perf.GenTPS, perf.TTFTMs/1000)
+					measuredBest = i
+				} else {
+					rec.Reason = fmt.Sprintf("⚠️ Gemessen nur %.1f Tokens/s - zu langsam für flüssigen Chat", perf.GenTPS)
+				}
+			}
+		}
+
 		recommendations = append(recommendations, rec)
 	}
 
+	// Gemessene Werte entscheiden: größtes flüssiges Modell, sonst kein zu langsames
+	if measuredBest >= 0 {
+		bestIndex = measuredBest
+	} else {
+		for bestIndex >= 0 && (!recommendations[bestIndex].Available || recommendations[bestIndex].Measured != nil) {
+			bestIndex--
+		}
+	}
+
 	// Bestes Modell markieren
 	if bestIndex >= 0 && bestIndex < len(recommendations) {
 		recommendations[bestIndex].Recommended = true
@@ -0,0 +1,162 @@
+// Package templates - Lokalisierung der Standard-Vorlagen
+// Multi-Language Support fuer DE, EN, TR (wie bei den Experten)
+package templates
+
+import "fleet-navigator/internal/experte"
+
+// TemplateTranslation enthaelt alle uebersetzbaren Felder einer Vorlage
+type TemplateTranslation struct {
+	Name        string
+	Description string
+	Category    string
+	Prompt      string
+}
+
+// DefaultTemplates gibt die Standard-Vorlagen zurueck (Deutsch ist die Originalsprache)
+func DefaultTemplates() []PromptTemplate {
+	return []PromptTemplate{
+		{
+			BuiltinKey:  "brief",
+			Name:        "Geschäftsbrief",
+			Description: "Vorlage für formelle Geschäftsbriefe",
+			Category:    "dokumente",
+			Prompt:      "Erstelle einen {{choice:Ton:formellen|freundlichen}} Geschäftsbrief an {{text:Empfänger}} vom {{date}} mit folgendem Inhalt: {{text:Inhalt}}",
+		},
+		{
+			BuiltinKey:  "email",
+			Name:        "E-Mail",
+			Description: "Vorlage für geschäftliche E-Mails",
+			Category:    "kommunikation",
+			Prompt:      "Schreibe eine {{choice:Ton:professionelle|lockere}} E-Mail an {{text:Empfänger}} zu folgendem Thema: {{text:Thema}}",
+		},
+		{
+			BuiltinKey:  "zusammenfassung",
+			Name:        "Zusammenfassung",
+			Description: "Text zusammenfassen",
+			Category:    "analyse",
+			Prompt:      "Fasse den folgenden Text {{choice:Länge:in drei Sätzen|in Stichpunkten|ausführlich}} zusammen:\n\n{{text:Text}}",
+		},
+		{
+			BuiltinKey:  "termin",
+			Name:        "Terminanfrage",
+			Description: "Termin vorschlagen oder verschieben",
+			Category:    "kommunikation",
+			Prompt:      "Formuliere eine kurze Nachricht an {{text:Empfänger}}, um {{choice:Anliegen:einen Termin vorzuschlagen|einen Termin zu verschieben}}. Wunschtermin: {{date:Datum}}. Anlass: {{text:Anlass}}",
+		},
+		{
+			BuiltinKey:  "uebersetzung",
+			Name:        "Übersetzung",
+			Description: "Text in eine andere Sprache übersetzen",
+			Category:    "sprache",
+			Prompt:      "Übersetze den folgenden Text ins {{choice:Sprache:Englische|Deutsche|Türkische|Französische}} und behalte Ton und Formatierung bei:\n\n{{text:Text}}",
+		},
+	}
+}
+
+// templateTranslations enthaelt alle Uebersetzungen
+// Key1 = BuiltinKey, Key2 = Language
+var templateTranslations = map[string]map[experte.Language]TemplateTranslation{
+	"brief": {
+		experte.LangEN: {
+			Name:        "Business Letter",
+			Description: "Template for formal business letters",
+			Category:    "documents",
+			Prompt:      "Write a {{choice:Tone:formal|friendly}} business letter to {{text:Recipient}} dated {{date}} with the following content: {{text:Content}}",
+		},
+		experte.LangTR: {
+			Name:        "İş Mektubu",
+			Description: "Resmi iş mektupları için şablon",
+			Category:    "belgeler",
+			Prompt:      "{{text:Alıcı}} adresine {{date}} tarihli, {{choice:Ton:resmi|samimi}} bir iş mektubu yaz. İçerik: {{text:İçerik}}",
+		},
+	},
+	"email": {
+		experte.LangEN: {
+			Name:        "Email",
+			Description: "Template for business emails",
+			Category:    "communication",
+			Prompt:      "Write a {{choice:Tone:professional|casual}} email to {{text:Recipient}} about the following topic: {{text:Topic}}",
+		},
+		experte.LangTR: {
+			Name:        "E-posta",
+			Description: "İş e-postaları için şablon",
+			Category:    "iletişim",
+			Prompt:      "{{text:Alıcı}} adresine şu konuda {{choice:Ton:profesyonel|samimi}} bir e-posta yaz: {{text:Konu}}",
+		},
+	},
+	"zusammenfassung": {
+		experte.LangEN: {
+			Name:        "Summary",
+			Description: "Summarize a text",
+			Category:    "analysis",
+			Prompt:      "Summarize the following text {{choice:Length:in three sentences|as bullet points|in detail}}:\n\n{{text:Text}}",
+		},
+		experte.LangTR: {
+			Name:        "Özet",
+			Description: "Metni özetle",
+			Category:    "analiz",
+			Prompt:      "Aşağıdaki metni {{choice:Uzunluk:üç cümlede|madde işaretleriyle|ayrıntılı olarak}} özetle:\n\n{{text:Metin}}",
+		},
+	},
+	"termin": {
+		experte.LangEN: {
+			Name:        "Appointment Request",
+			Description: "Propose or reschedule an appointment",
+			Category:    "communication",
+			Prompt:      "Write a short message to {{text:Recipient}} to {{choice:Request:propose an appointment|reschedule an appointment}}. Preferred date: {{date:Date}}. Occasion: {{text:Occasion}}",
+		},
+		experte.LangTR: {
+			Name:        "Randevu Talebi",
+			Description: "Randevu öner veya ertele",
+			Category:    "iletişim",
+			Prompt:      "{{text:Alıcı}} kişisine {{choice:Talep:randevu önermek|randevuyu ertelemek}} için kısa bir mesaj yaz. Tercih edilen tarih: {{date:Tarih}}. Konu: {{text:Konu}}",
+		},
+	},
+	"uebersetzung": {
+		experte.LangEN: {
+			Name:        "Translation",
+			Description: "Translate text into another language",
+			Category:    "language",
+			Prompt:      "Translate the following text into {{choice:Language:English|German|Turkish|French}} and keep tone and formatting:\n\n{{text:Text}}",
+		},
+		experte.LangTR: {
+			Name:        "Çeviri",
+			Description: "Metni başka bir dile çevir",
+			Category:    "dil",
+			Prompt:      "Aşağıdaki metni {{choice:Dil:İngilizceye|Almancaya|Türkçeye|Fransızcaya}} çevir, ton ve biçimi koru:\n\n{{text:Metin}}",
+		},
+	},
+}
+
+// GetTemplateTranslation gibt die Uebersetzung einer Standard-Vorlage zurueck
+// Fallback: nil (Deutsch ist die Originalsprache in DefaultTemplates())
+func GetTemplateTranslation(builtinKey string, lang experte.Language) *TemplateTranslation {
+	if translations, ok := templateTranslations[builtinKey]; ok {
+		if t, ok := translations[lang]; ok {
+			return &t
+		}
+	}
+	return nil
+}
+
+// applyTranslation uebersetzt eine Standard-Vorlage (falls Uebersetzung vorhanden)
+func applyTranslation(t *PromptTemplate, lang experte.Language) {
+	if t.BuiltinKey == "" || lang == experte.LangDE {
+		return
+	}
+	if translation := GetTemplateTranslation(t.BuiltinKey, lang); translation != nil {
+		t.Name = translation.Name
+		t.Description = translation.Description
+		t.Category = translation.Category
+		t.Prompt = translation.Prompt
+	}
+}
+
+// DefaultTemplatesWithLanguage gibt die Standard-Vorlagen in einer bestimmten Sprache zurueck
+func DefaultTemplatesWithLanguage(lang experte.Language) []PromptTemplate {
+	templates := DefaultTemplates()
+	for i := range templates {
+		applyTranslation(&templates[i], lang)
+	}
+	return templates
+}
@@ -0,0 +1,183 @@
+package templates
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"fleet-navigator/internal/experte"
+)
+
+// Platzhalter-Typen
+const (
+	PlaceholderText   = "text"   // Freitext
+	PlaceholderDate   = "date"   // Datum (YYYY-MM-DD, leer = heute)
+	PlaceholderChoice = "choice" // Auswahl aus festen Optionen
+)
+
+// Placeholder beschreibt einen Platzhalter im Prompt.
+//
+// Syntax:
+//
+//	{{text}}                  Freitext
+//	{{text:Empfänger}}        Freitext mit Bezeichnung
+//	{{date}} / {{date:Frist}} Datum
+//	{{choice:formell|locker}} Auswahl
+//	{{choice:Ton:formell|locker}} Auswahl mit Bezeichnung
+//
+// Platzhalter mit gleicher Bezeichnung teilen sich einen Wert.
+// Ohne Bezeichnung wird der Schlüssel durchnummeriert (text1, text2, date1, ...).
+type Placeholder struct {
+	Key     string   `json:"key"`               // Schlüssel für die Werte beim Rendern
+	Type    string   `json:"type"`              // text, date oder choice
+	Label   string   `json:"label,omitempty"`   // Bezeichnung (optional)
+	Options []string `json:"options,omitempty"` // Nur bei choice
+}
+
+// placeholderPattern erkennt {{typ}} und {{typ:argumente}}
+var placeholderPattern = regexp.MustCompile(`\{\{\s*(text|date|choice)\s*(?::([^{}]*))?\}\}`)
+
+// parsedPlaceholder ist ein Platzhalter mit Position im Prompt
+type parsedPlaceholder struct {
+	Placeholder
+	start, end int
+}
+
+// parsePlaceholders findet alle Platzhalter inkl. Position
+func parsePlaceholders(prompt string) []parsedPlaceholder {
+	matches := placeholderPattern.FindAllStringSubmatchIndex(prompt, -1)
+	counters := make(map[string]int)
+	var result []parsedPlaceholder
+
+	for _, m := range matches {
+		p := parsedPlaceholder{start: m[0], end: m[1]}
+		p.Type = prompt[m[2]:m[3]]
+		args := ""
+		if m[4] >= 0 {
+			args = strings.TrimSpace(prompt[m[4]:m[5]])
+		}
+
+		if p.Type == PlaceholderChoice {
+			// {{choice:a|b}} oder {{choice:Label:a|b}}
+			if idx := strings.Index(args, ":"); idx >= 0 {
+				p.Label = strings.TrimSpace(args[:idx])
+				args = args[idx+1:]
+			}
+			for _, opt := range strings.Split(args, "|") {
+				if opt = strings.TrimSpace(opt); opt != "" {
+					p.Options = append(p.Options, opt)
+				}
+			}
+		} else {
+			p.Label = args
+		}
+
+		if p.Label != "" {
+			p.Key = p.Label
+		} else {
+			counters[p.Type]++
+			p.Key = p.Type + strconv.Itoa(counters[p.Type])
+		}
+		result = append(result, p)
+	}
+	return result
+}
+
+// ParsePlaceholders gibt die Platzhalter eines Prompts zurück (ohne Duplikate)
+func ParsePlaceholders(prompt string) []Placeholder {
+	seen := make(map[string]bool)
+	result := make([]Placeholder, 0)
+	for _, p := range parsePlaceholders(prompt) {
+		if seen[p.Key] {
+			continue
+		}
+		seen[p.Key] = true
+		result = append(result, p.Placeholder)
+	}
+	return result
+}
+
+// dateFormats: Ausgabeformat für Datums-Platzhalter je Sprache
+var dateFormats = map[experte.Language]string{
+	experte.LangDE: "02.01.2006",
+	experte.LangEN: "January 2, 2006",
+	experte.LangTR: "02.01.2006",
+}
+
+// Render ersetzt alle Platzhalter im Prompt durch die übergebenen Werte.
+//
+// Parameter:
+//   - prompt: Prompt mit Platzhaltern
+//   - values: Werte je Platzhalter-Schlüssel (siehe Placeholder.Key)
+//   - lang: Sprache für die Datumsformatierung
+//   - now: Aktuelles Datum (Default für leere Datums-Platzhalter)
+//
+// Rückgabe: Fehler wenn Text-Werte fehlen, ein Datum ungültig ist
+// oder eine Auswahl nicht zu den Optionen passt.
+func Render(prompt string, values map[string]string, lang experte.Language, now time.Time) (string, error) {
+	layout, ok := dateFormats[lang]
+	if !ok {
+		layout = dateFormats[experte.LangDE]
+	}
+
+	var sb strings.Builder
+	var missing []string
+	reported := make(map[string]bool)
+	last := 0
+
+	for _, p := range parsePlaceholders(prompt) {
+		sb.WriteString(prompt[last:p.start])
+		last = p.end
+
+		value := strings.TrimSpace(values[p.Key])
+		switch p.Type {
+		case PlaceholderText:
+			if value == "" {
+				if !reported[p.Key] {
+					missing = append(missing, p.Key)
+					reported[p.Key] = true
+				}
+				continue
+			}
+			sb.WriteString(value)
+
+		case PlaceholderDate:
+			date := now
+			if value != "" {
+				parsed, err := time.Parse("2006-01-02", value)
+				if err != nil {
+					return "", fmt.Errorf("ungültiges Datum für %s: %q (erwartet YYYY-MM-DD)", p.Key, value)
+				}
+				date = parsed
+			}
+			sb.WriteString(date.Format(layout))
+
+		case PlaceholderChoice:
+			if value == "" && len(p.Options) > 0 {
+				value = p.Options[0]
+			}
+			if !containsOption(p.Options, value) {
+				return "", fmt.Errorf("ungültige Auswahl für %s: %q (erlaubt: %s)", p.Key, value, strings.Join(p.Options, ", "))
+			}
+			sb.WriteString(value)
+		}
+	}
+	sb.WriteString(prompt[last:])
+
+	if len(missing) > 0 {
+		return "", fmt.Errorf("fehlende Werte: %s", strings.Join(missing, ", "))
+	}
+	return sb.String(), nil
+}
+
+// containsOption prüft ob value eine der Optionen ist
+func containsOption(options []string, value string) bool {
+	for _, opt := range options {
+		if opt == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,169 @@
+package templates
+
+import (
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// Repository verwaltet Prompt-Vorlagen in SQLite
+type Repository struct {
+	db *sql.DB
+}
+
+// NewRepository erstellt ein neues Repository
+func NewRepository(dataDir string) (*Repository, error) {
+	dbPath := filepath.Join(dataDir, "templates.db")
+
+	db, err := sql.Open("sqlite", dbPath)
+	if err != nil {
+		return nil, fmt.Errorf("templates DB öffnen: %w", err)
+	}
+
+	db.SetMaxOpenConns(5)
+	db.SetMaxIdleConns(2)
+
+	repo := &Repository{db: db}
+	if err := repo.createSchema(); err != nil {
+		return nil, err
+	}
+
+	return repo, nil
+}
+
+func (r *Repository) createSchema() error {
+	schema := `
+	CREATE TABLE IF NOT EXISTS prompt_templates (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name TEXT NOT NULL,
+		description TEXT DEFAULT '',
+		category TEXT DEFAULT '',
+		prompt TEXT NOT NULL,
+		builtin_key TEXT DEFAULT '',
+		modified INTEGER DEFAULT 0,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_templates_category ON prompt_templates(category);
+	`
+
+	_, err := r.db.Exec(schema)
+	if err != nil {
+		return fmt.Errorf("templates Schema erstellen: %w", err)
+	}
+
+	return nil
+}
+
+// Close schließt die Datenbankverbindung
+func (r *Repository) Close() error {
+	return r.db.Close()
+}
+
+const templateColumns = `id, name, description, category, prompt, builtin_key, modified, created_at, updated_at`
+
+func scanTemplate(row interface{ Scan(...interface{}) error }) (*PromptTemplate, error) {
+	var t PromptTemplate
+	var modified int
+	err := row.Scan(&t.ID, &t.Name, &t.Description, &t.Category, &t.Prompt, &t.BuiltinKey, &modified, &t.CreatedAt, &t.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	t.Modified = modified == 1
+	return &t, nil
+}
+
+// GetAll lädt alle Vorlagen (nach Kategorie und Name sortiert)
+func (r *Repository) GetAll() ([]PromptTemplate, error) {
+	rows, err := r.db.Query(`SELECT ` + templateColumns + ` FROM prompt_templates ORDER BY category, name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	templates := []PromptTemplate{}
+	for rows.Next() {
+		t, err := scanTemplate(rows)
+		if err != nil {
+			return nil, err
+		}
+		templates = append(templates, *t)
+	}
+	return templates, nil
+}
+
+// GetByID lädt eine Vorlage
+func (r *Repository) GetByID(id int64) (*PromptTemplate, error) {
+	t, err := scanTemplate(r.db.QueryRow(`SELECT `+templateColumns+` FROM prompt_templates WHERE id = ?`, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return t, err
+}
+
+// Exists prüft ob bereits eine Vorlage mit gleichem Namen und Prompt existiert
+func (r *Repository) Exists(name, prompt string) (bool, error) {
+	var count int
+	err := r.db.QueryRow(`SELECT COUNT(*) FROM prompt_templates WHERE name = ? AND prompt = ?`, name, prompt).Scan(&count)
+	return count > 0, err
+}
+
+// Create erstellt eine neue Vorlage
+func (r *Repository) Create(t *PromptTemplate) error {
+	now := time.Now()
+	modified := 0
+	if t.Modified {
+		modified = 1
+	}
+
+	result, err := r.db.Exec(`
+		INSERT INTO prompt_templates (name, description, category, prompt, builtin_key, modified, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, t.Name, t.Description, t.Category, t.Prompt, t.BuiltinKey, modified, now, now)
+	if err != nil {
+		return fmt.Errorf("template erstellen: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	t.ID = id
+	t.CreatedAt = now
+	t.UpdatedAt = now
+	return nil
+}
+
+// Update aktualisiert eine Vorlage
+func (r *Repository) Update(t *PromptTemplate) error {
+	modified := 0
+	if t.Modified {
+		modified = 1
+	}
+	t.UpdatedAt = time.Now()
+
+	_, err := r.db.Exec(`
+		UPDATE prompt_templates
+		SET name = ?, description = ?, category = ?, prompt = ?, modified = ?, updated_at = ?
+		WHERE id = ?
+	`, t.Name, t.Description, t.Category, t.Prompt, modified, t.UpdatedAt, t.ID)
+	return err
+}
+
+// Delete löscht eine Vorlage
+func (r *Repository) Delete(id int64) error {
+	_, err := r.db.Exec("DELETE FROM prompt_templates WHERE id = ?", id)
+	return err
+}
+
+// Count zählt alle Vorlagen
+func (r *Repository) Count() (int64, error) {
+	var count int64
+	err := r.db.QueryRow("SELECT COUNT(*) FROM prompt_templates").Scan(&count)
+	return count, err
+}
@@ -0,0 +1,205 @@
+package templates
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"fleet-navigator/internal/experte"
+)
+
+// Service verwaltet Prompt-Vorlagen
+type Service struct {
+	repo *Repository
+}
+
+// NewService erstellt einen neuen Service
+func NewService(repo *Repository) *Service {
+	return &Service{repo: repo}
+}
+
+// localize übersetzt unveränderte Standard-Vorlagen und parst die Platzhalter
+func localize(t *PromptTemplate, lang experte.Language) {
+	if !t.Modified {
+		applyTranslation(t, lang)
+	}
+	t.Placeholders = ParsePlaceholders(t.Prompt)
+}
+
+// GetAll gibt alle Vorlagen in der gewünschten Sprache zurück
+func (s *Service) GetAll(lang experte.Language) ([]PromptTemplate, error) {
+	templates, err := s.repo.GetAll()
+	if err != nil {
+		return nil, err
+	}
+	for i := range templates {
+		localize(&templates[i], lang)
+	}
+	return templates, nil
+}
+
+// GetByID gibt eine Vorlage in der gewünschten Sprache zurück
+func (s *Service) GetByID(id int64, lang experte.Language) (*PromptTemplate, error) {
+	t, err := s.repo.GetByID(id)
+	if err != nil || t == nil {
+		return t, err
+	}
+	localize(t, lang)
+	return t, nil
+}
+
+// validate prüft Pflichtfelder einer Vorlage
+func validate(t *PromptTemplate) error {
+	t.Name = strings.TrimSpace(t.Name)
+	if t.Name == "" {
+		return fmt.Errorf("Name darf nicht leer sein")
+	}
+	if strings.TrimSpace(t.Prompt) == "" {
+		return fmt.Errorf("Prompt darf nicht leer sein")
+	}
+	return nil
+}
+
+// Create erstellt eine neue Vorlage
+func (s *Service) Create(t *PromptTemplate) error {
+	if err := validate(t); err != nil {
+		return err
+	}
+	t.BuiltinKey = ""
+	if err := s.repo.Create(t); err != nil {
+		return err
+	}
+	t.Placeholders = ParsePlaceholders(t.Prompt)
+	return nil
+}
+
+// Update aktualisiert eine Vorlage.
+// Bearbeitete Standard-Vorlagen werden danach nicht mehr automatisch übersetzt.
+func (s *Service) Update(t *PromptTemplate) error {
+	if err := validate(t); err != nil {
+		return err
+	}
+	existing, err := s.repo.GetByID(t.ID)
+	if err != nil {
+		return err
+	}
+	if existing == nil {
+		return fmt.Errorf("Vorlage nicht gefunden")
+	}
+	t.BuiltinKey = existing.BuiltinKey
+	t.Modified = existing.BuiltinKey != ""
+	t.CreatedAt = existing.CreatedAt
+	if err := s.repo.Update(t); err != nil {
+		return err
+	}
+	t.Placeholders = ParsePlaceholders(t.Prompt)
+	return nil
+}
+
+// Delete löscht eine Vorlage
+func (s *Service) Delete(id int64) error {
+	return s.repo.Delete(id)
+}
+
+// Render rendert eine Vorlage mit den übergebenen Platzhalter-Werten
+func (s *Service) Render(id int64, values map[string]string, lang experte.Language) (string, error) {
+	t, err := s.GetByID(id, lang)
+	if err != nil {
+		return "", err
+	}
+	if t == nil {
+		return "", fmt.Errorf("Vorlage nicht gefunden")
+	}
+	return Render(t.Prompt, values, lang, time.Now())
+}
+
+// InitializeDefaults erstellt die Standard-Vorlagen beim ersten Start.
+// Gespeichert wird die deutsche Originalfassung, übersetzt wird beim Laden.
+func (s *Service) InitializeDefaults() error {
+	count, err := s.repo.Count()
+	if err != nil {
+		return err
+	}
+
+	if count > 0 {
+		log.Println("Prompt-Vorlagen bereits vorhanden, überspringe Initialisierung")
+		return nil
+	}
+
+	templates := DefaultTemplates()
+	for i := range templates {
+		if err := s.repo.Create(&templates[i]); err != nil {
+			return err
+		}
+	}
+
+	log.Printf("Erstellt: %d Standard-Prompt-Vorlagen", len(templates))
+	return nil
+}
+
+// Export exportiert alle Vorlagen in der gewünschten Sprache als JSON
+func (s *Service) Export(lang experte.Language) ([]byte, error) {
+	templates, err := s.GetAll(lang)
+	if err != nil {
+		return nil, err
+	}
+
+	bundle := ExportBundle{
+		Version:    ExportVersion,
+		ExportedAt: time.Now(),
+		Templates:  make([]ExportTemplate, 0, len(templates)),
+	}
+	for _, t := range templates {
+		bundle.Templates = append(bundle.Templates, ExportTemplate{
+			Name:        t.Name,
+			Description: t.Description,
+			Category:    t.Category,
+			Prompt:      t.Prompt,
+		})
+	}
+	return json.MarshalIndent(bundle, "", "  ")
+}
+
+// Import importiert Vorlagen aus einem JSON-Export.
+// Vorlagen mit gleichem Namen und Prompt werden übersprungen.
+func (s *Service) Import(data []byte) (*ImportResult, error) {
+	var bundle ExportBundle
+	if err := json.Unmarshal(data, &bundle); err != nil {
+		return nil, fmt.Errorf("ungültiges Export-Format: %w", err)
+	}
+	if bundle.Version > ExportVersion {
+		return nil, fmt.Errorf("Export-Version %d wird nicht unterstützt (max. %d)", bundle.Version, ExportVersion)
+	}
+
+	result := &ImportResult{}
+	for i, et := range bundle.Templates {
+		t := &PromptTemplate{
+			Name:        et.Name,
+			Description: et.Description,
+			Category:    et.Category,
+			Prompt:      et.Prompt,
+		}
+		if err := validate(t); err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("Vorlage %d: %v", i+1, err))
+			continue
+		}
+		exists, err := s.repo.Exists(t.Name, t.Prompt)
+		if err != nil {
+			return nil, err
+		}
+		if exists {
+			result.Skipped++
+			continue
+		}
+		if err := s.repo.Create(t); err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("%s: %v", t.Name, err))
+			continue
+		}
+		result.Imported++
+	}
+
+	log.Printf("Prompt-Vorlagen importiert: %d neu, %d übersprungen", result.Imported, result.Skipped)
+	return result, nil
+}
@@ -0,0 +1,133 @@
+package templates
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"fleet-navigator/internal/experte"
+)
+
+// TestParsePlaceholders prüft das Erkennen der Platzhalter-Typen
+func TestParsePlaceholders(t *testing.T) {
+	prompt := "An {{text:Empfänger}} am {{date}}: {{choice:Ton:formell|locker}} {{text}} {{text:Empfänger}} {{unbekannt}}"
+	got := ParsePlaceholders(prompt)
+
+	if len(got) != 4 {
+		t.Fatalf("Erwartet 4 Platzhalter, bekommen %d: %+v", len(got), got)
+	}
+	want := []struct{ key, typ string }{
+		{"Empfänger", PlaceholderText},
+		{"date1", PlaceholderDate},
+		{"Ton", PlaceholderChoice},
+		{"text1", PlaceholderText},
+	}
+	for i, w := range want {
+		if got[i].Key != w.key || got[i].Type != w.typ {
+			t.Errorf("Platzhalter %d = %s/%s, erwartet %s/%s", i, got[i].Key, got[i].Type, w.key, w.typ)
+		}
+	}
+	if len(got[2].Options) != 2 || got[2].Options[1] != "locker" {
+		t.Errorf("Optionen = %v", got[2].Options)
+	}
+}
+
+// TestRender prüft das Ersetzen und die Validierung der Werte
+func TestRender(t *testing.T) {
+	now := time.Date(2025, 3, 7, 10, 0, 0, 0, time.UTC)
+	prompt := "Brief an {{text:Name}} ({{text:Name}}) vom {{date}}, Ton {{choice:formell|locker}}"
+
+	tests := []struct {
+		name    string
+		values  map[string]string
+		lang    experte.Language
+		want    string
+		wantErr string
+	}{
+		{"Standardwerte", map[string]string{"Name": "Frau Kaya"}, experte.LangDE,
+			"Brief an Frau Kaya (Frau Kaya) vom 07.03.2025, Ton formell", ""},
+		{"Englisches Datum", map[string]string{"Name": "Bob", "date1": "2025-12-24", "choice1": "locker"}, experte.LangEN,
+			"Brief an Bob (Bob) vom December 24, 2025, Ton locker", ""},
+		{"Fehlender Text", map[string]string{}, experte.LangDE, "", "fehlende Werte: Name"},
+		{"Ungültiges Datum", map[string]string{"Name": "X", "date1": "24.12.2025"}, experte.LangDE, "", "ungültiges Datum"},
+		{"Ungültige Auswahl", map[string]string{"Name": "X", "choice1": "frech"}, experte.LangDE, "", "ungültige Auswahl"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Render(prompt, tt.values, tt.lang, now)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Fehler = %v, erwartet %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("Render = %q, erwartet %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestDefaultTemplatesWithLanguage prüft dass alle Standard-Vorlagen übersetzt sind
+func TestDefaultTemplatesWithLanguage(t *testing.T) {
+	german := DefaultTemplates()
+	for _, lang := range []experte.Language{experte.LangEN, experte.LangTR} {
+		translated := DefaultTemplatesWithLanguage(lang)
+		for i := range german {
+			if translated[i].Prompt == german[i].Prompt {
+				t.Errorf("Vorlage %q nicht übersetzt (%s)", german[i].BuiltinKey, lang)
+			}
+			if len(ParsePlaceholders(translated[i].Prompt)) != len(ParsePlaceholders(german[i].Prompt)) {
+				t.Errorf("Vorlage %q (%s): Anzahl Platzhalter weicht ab", german[i].BuiltinKey, lang)
+			}
+		}
+	}
+}
+
+// TestImportExport prüft den JSON-Austausch und das Überspringen von Duplikaten
+func TestImportExport(t *testing.T) {
+	repo, err := NewRepository(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer repo.Close()
+	service := NewService(repo)
+
+	if err := service.InitializeDefaults(); err != nil {
+		t.Fatal(err)
+	}
+	custom := &PromptTemplate{Name: "Bug-Report", Category: "technik", Prompt: "Beschreibe den Fehler: {{text}}"}
+	if err := service.Create(custom); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := service.Export(experte.LangDE)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Gleiche Bibliothek erneut importieren - alles vorhanden
+	result, err := service.Import(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Imported != 0 || result.Skipped != len(DefaultTemplates())+1 {
+		t.Errorf("Re-Import: %+v", result)
+	}
+
+	// In leere Bibliothek importieren
+	other, _ := NewRepository(t.TempDir())
+	defer other.Close()
+	result, err = NewService(other).Import(data)
+	if err != nil || result.Imported != len(DefaultTemplates())+1 {
+		t.Errorf("Import: %+v, %v", result, err)
+	}
+
+	if _, err := service.Import([]byte(`{"version": 99, "templates": []}`)); err == nil {
+		t.Error("Unbekannte Export-Version sollte abgelehnt werden")
+	}
+}
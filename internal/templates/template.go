@@ -0,0 +1,54 @@
+// Package templates - Prompt-Vorlagen mit typisierten Platzhaltern
+package templates
+
+import (
+	"time"
+)
+
+// PromptTemplate repräsentiert eine Prompt-Vorlage.
+// Der Prompt kann Platzhalter enthalten ({{text}}, {{date}}, {{choice:a|b}}),
+// die beim Rendern durch Benutzereingaben ersetzt werden.
+type PromptTemplate struct {
+	ID          int64  `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Category    string `json:"category"`
+	Prompt      string `json:"prompt"`
+
+	// BuiltinKey: Schlüssel der Standard-Vorlage (leer = vom Benutzer erstellt)
+	BuiltinKey string `json:"builtinKey,omitempty"`
+
+	// Modified: Standard-Vorlage wurde bearbeitet (wird dann nicht mehr übersetzt)
+	Modified bool `json:"modified"`
+
+	// Placeholders: Aus dem Prompt geparste Platzhalter (nicht gespeichert)
+	Placeholders []Placeholder `json:"placeholders"`
+
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// ExportVersion ist die aktuelle Version des Export-Formats
+const ExportVersion = 1
+
+// ExportBundle ist das JSON-Format für Import/Export einer Vorlagen-Bibliothek
+type ExportBundle struct {
+	Version    int              `json:"version"`
+	ExportedAt time.Time        `json:"exportedAt"`
+	Templates  []ExportTemplate `json:"templates"`
+}
+
+// ExportTemplate enthält die portablen Felder einer Vorlage (ohne IDs)
+type ExportTemplate struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Category    string `json:"category"`
+	Prompt      string `json:"prompt"`
+}
+
+// ImportResult fasst das Ergebnis eines Imports zusammen
+type ImportResult struct {
+	Imported int      `json:"imported"`
+	Skipped  int      `json:"skipped"` // Bereits vorhanden (gleicher Name und Prompt)
+	Errors   []string `json:"errors,omitempty"`
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"fmt"
 	"sync"
+
+	"fleet-navigator/internal/search"
 )
 
 // Registry manages all available tools
@@ -19,7 +21,7 @@ func NewRegistry() *Registry {
 	}
 
 	// Register default tools
-	r.Register(NewWebSearchTool())
+	r.Register(NewWebSearchTool(nil))
 	r.Register(NewWebFetchTool())
 	r.Register(NewFileSearchTool())
 
@@ -159,6 +161,18 @@ func (r *Registry) SetFileSearchMateProvider(provider func(string) (MateConnecti
 	}
 }
 
+// SetWebSearchService lets web_search use the shared search service (settings, backends, health stats)
+func (r *Registry) SetWebSearchService(service *search.Service) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if tool, ok := r.tools["web_search"]; ok {
+		if wst, ok := tool.(*WebSearchTool); ok {
+			wst.SetService(service)
+		}
+	}
+}
+
 // ToolInfo provides serializable tool information
 type ToolInfo struct {
 	Name         string   `json:"name"`
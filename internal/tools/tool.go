@@ -15,6 +15,7 @@ const (
 	ToolTypeFileSearch ToolType = "file_search"
 	ToolTypeCalculator ToolType = "calculator"
 	ToolTypeDateTime   ToolType = "datetime"
+	ToolTypeFinance    ToolType = "finance_data"
 )
 
 // ToolResult represents the result of a tool execution
@@ -58,6 +59,11 @@ type BaseTool struct {
 	schema      map[string]interface{}
 }
 
+// NewBaseTool creates a BaseTool for tools implemented outside this package
+func NewBaseTool(name string, toolType ToolType, description string, schema map[string]interface{}) BaseTool {
+	return BaseTool{name: name, toolType: toolType, description: description, schema: schema}
+}
+
 func (t *BaseTool) Name() string                      { return t.name }
 func (t *BaseTool) Type() ToolType                    { return t.toolType }
 func (t *BaseTool) Description() string               { return t.description }
@@ -16,6 +16,8 @@ import (
 	"strings"
 	"sync"
 	"time"
+
+	"fleet-navigator/internal/integrity"
 )
 
 // WhisperSTT verwaltet die Whisper Speech-to-Text Funktionalität
@@ -542,8 +544,33 @@ func (w *WhisperSTT) SetLanguage(language string) {
 	w.language = language
 }
 
-// downloadFile lädt eine Datei herunter mit Multi-Connection-Support für große Dateien
+// downloadFile lädt eine Datei herunter und prüft anschließend die SHA-256
+// (sofern der Server eine liefert, z.B. HuggingFace LFS-Metadaten)
 func downloadFile(url, destPath, component, file string, progressChan chan<- DownloadProgress) error {
+	expectedSHA256 := integrity.FetchExpectedSHA256(url)
+
+	if err := downloadFileUnverified(url, destPath, component, file, progressChan); err != nil {
+		return err
+	}
+
+	if progressChan != nil {
+		progressChan <- DownloadProgress{
+			Component: component,
+			File:      file,
+			Status:    "verifying",
+		}
+	}
+	if _, err := integrity.VerifyDownload(destPath, expectedSHA256, integrity.SourceHuggingFace); err != nil {
+		log.Printf("[%s] ❌ Download beschädigt, lösche %s: %v", component, filepath.Base(destPath), err)
+		os.Remove(destPath)
+		integrity.Forget(destPath)
+		return fmt.Errorf("Download-Prüfung fehlgeschlagen: %w", err)
+	}
+	return nil
+}
+
+// downloadFileUnverified lädt eine Datei herunter mit Multi-Connection-Support für große Dateien
+func downloadFileUnverified(url, destPath, component, file string, progressChan chan<- DownloadProgress) error {
 	// Prüfe ob Multi-Connection möglich ist (HEAD Request)
 	headResp, err := http.Head(url)
 	if err == nil {
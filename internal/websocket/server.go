@@ -14,6 +14,7 @@ import (
 
 	"github.com/gorilla/websocket"
 
+	"fleet-navigator/internal/search"
 	"fleet-navigator/internal/security"
 	"fleet-navigator/internal/tools"
 )
@@ -145,6 +146,24 @@ type ChatHandler interface {
 	ClearHistory(sessionID string) error
 }
 
+// BackgroundChatHandler ist optional: Hintergrund-Jobs der Mates laufen damit
+// mit niedriger Priorität, interaktiver Chat hat Vorrang
+type BackgroundChatHandler interface {
+	ChatInBackground(mateID, sessionID, message string) (string, error)
+}
+
+// StructuredChatHandler ist optional: Hintergrund-Jobs mit per JSON-Schema
+// erzwungener Antwort (Klassifizierung, Terminprüfung)
+type StructuredChatHandler interface {
+	ChatJSON(mateID, prompt string, schema json.RawMessage, out interface{}) error
+}
+
+// MateChatHandler ist optional: interaktiver Chat eines Mates mit dem für den
+// Mate konfigurierten Modell (z.B. Coder-Modell)
+type MateChatHandler interface {
+	ChatForMate(mateID, sessionID, message, systemPrompt string, onChunk func(chunk string)) (string, error)
+}
+
 // MateStats speichert Hardware-Stats von einem Mate
 type MateStats struct {
 	System      map[string]interface{} `json:"system,omitempty"`
@@ -166,6 +185,7 @@ type Server struct {
 	unregister     chan *Client
 	pairingManager *security.PairingManager
 	chatHandler    ChatHandler
+	searchService  *search.Service // Web-Suche für Mates (gleiche Backends wie der Chat)
 	mu             sync.RWMutex
 
 	// Callbacks für UI
@@ -201,6 +221,11 @@ func (s *Server) SetChatHandler(handler ChatHandler) {
 	s.chatHandler = handler
 }
 
+// SetSearchService setzt den Such-Service für WebSearch-Anfragen von Mates
+func (s *Server) SetSearchService(service *search.Service) {
+	s.searchService = service
+}
+
 // Run startet die Server-Hauptschleife
 func (s *Server) Run() {
 	for {
@@ -638,7 +663,17 @@ func (c *Client) handleChat(msg Message) {
 	// Chat mit Streaming-Callback
 	go func() {
 		var err error
-		if mateSystemPrompt != "" {
+		mateHandler, hasMateHandler := c.Server.chatHandler.(MateChatHandler)
+		if c.MateID != "" && hasMateHandler {
+			// Mate-Modell und Mate-Prompt (leer = Standard-Prompt)
+			_, err = mateHandler.ChatForMate(c.MateID, sessionID, payload.Message, mateSystemPrompt, func(chunk string) {
+				c.sendMessageWithID(MsgChatStream, msg.ID, ChatResponsePayload{
+					SessionID: sessionID,
+					Content:   chunk,
+					Done:      false,
+				})
+			})
+		} else if mateSystemPrompt != "" {
 			// Verwende Modus/Mate-spezifischen Prompt
 			_, err = c.Server.chatHandler.ChatWithSystemPrompt(sessionID, payload.Message, mateSystemPrompt, func(chunk string) {
 				c.sendMessageWithID(MsgChatStream, msg.ID, ChatResponsePayload{
@@ -1170,7 +1205,7 @@ func (c *Client) handleWebSearch(data json.RawMessage) {
 	}
 
 	// WebSearch-Tool erstellen und ausführen
-	searchTool := tools.NewWebSearchTool()
+	searchTool := tools.NewWebSearchTool(c.Server.searchService)
 	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
 	defer cancel()
 
@@ -1189,7 +1224,9 @@ func (c *Client) handleWebSearch(data json.RawMessage) {
 		return
 	}
 
-	log.Printf("✅ WebSearch erfolgreich: %d Ergebnisse", len(result.Data.([]interface{})))
+	if results, ok := result.Data.([]tools.SearchResult); ok {
+		log.Printf("✅ WebSearch erfolgreich: %d Ergebnisse (%s)", len(results), result.Source)
+	}
 
 	// Ergebnisse zurücksenden
 	c.sendEncryptedMessage("web_search_result", map[string]interface{}{
@@ -1308,6 +1345,28 @@ type EmailClassifyRequest struct {
 	PreferredModel  string            `json:"preferredModel,omitempty"`
 }
 
+// backgroundChat führt einen Hintergrund-Job des Mates aus (ohne Streaming).
+// Unterstützt der Chat-Handler keine Prioritäten, läuft er als normaler Chat.
+func (c *Client) backgroundChat(sessionID, prompt string) (string, error) {
+	if handler, ok := c.Server.chatHandler.(BackgroundChatHandler); ok {
+		return handler.ChatInBackground(c.MateID, sessionID, prompt)
+	}
+	return c.Server.chatHandler.Chat(sessionID, prompt, nil)
+}
+
+// structuredChat führt einen Hintergrund-Job des Mates mit JSON-Schema aus
+func (c *Client) structuredChat(prompt string, schema interface{}, out interface{}) error {
+	handler, ok := c.Server.chatHandler.(StructuredChatHandler)
+	if !ok {
+		return fmt.Errorf("Chat-Handler unterstützt keine strukturierte Ausgabe")
+	}
+	rawSchema, err := json.Marshal(schema)
+	if err != nil {
+		return fmt.Errorf("JSON-Schema: %w", err)
+	}
+	return handler.ChatJSON(c.MateID, prompt, rawSchema, out)
+}
+
 // handleClassifyEmail verarbeitet eine E-Mail-Klassifizierungsanfrage
 func (c *Client) handleClassifyEmail(data json.RawMessage) {
 	var req EmailClassifyRequest
@@ -1344,8 +1403,22 @@ An: %s
 Betreff: %s
 Vorschau: %s
 
-Antworte NUR mit dem Kategorienamen (kleingeschrieben, ohne Anführungszeichen oder zusätzlichen Text).
-Beispiel: wichtig`, categoryDescriptions, req.From, req.To, req.Subject, req.Preview)
+Antworte als JSON mit dem Kategorienamen (kleingeschrieben) und einer kurzen Begründung.`, categoryDescriptions, req.From, req.To, req.Subject, req.Preview)
+
+	// Antwort per JSON-Schema auf die erlaubten Kategorien beschränken
+	allowed := make([]string, len(categories))
+	for i, cat := range categories {
+		allowed[i] = strings.ToLower(cat)
+	}
+	schema := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"category": map[string]interface{}{"type": "string", "enum": allowed},
+			"reason":   map[string]interface{}{"type": "string", "maxLength": 200},
+		},
+		"required":             []string{"category", "reason"},
+		"additionalProperties": false,
+	}
 
 	// Chat-Anfrage an LLM senden
 	go func() {
@@ -1355,40 +1428,20 @@ Beispiel: wichtig`, categoryDescriptions, req.From, req.To, req.Subject, req.Pre
 			return
 		}
 
-		// Verwende eine eindeutige Session für Klassifizierung
-		sessionID := fmt.Sprintf("classify-%s-%s", c.MateID, req.MessageID)
-
-		var response string
-		response, err := c.Server.chatHandler.Chat(sessionID, prompt, nil) // Kein Streaming für Klassifizierung
-		if err != nil {
+		var result struct {
+			Category string `json:"category"`
+			Reason   string `json:"reason"`
+		}
+		if err := c.structuredChat(prompt, schema, &result); err != nil {
 			log.Printf("❌ LLM-Fehler bei Klassifizierung: %v", err)
 			c.sendClassifyResponse(req.MessageID, req.AccountEmail, "abzuarbeiten", 0.5, err.Error())
 			return
 		}
 
-		// Antwort bereinigen und validieren
-		category := strings.ToLower(strings.TrimSpace(response))
-		category = strings.Trim(category, "\"'.,!?")
-
-		// Prüfen ob gültige Kategorie
-		validCategory := false
-		for _, cat := range categories {
-			if category == strings.ToLower(cat) {
-				validCategory = true
-				category = strings.ToLower(cat)
-				break
-			}
-		}
-
-		if !validCategory {
-			log.Printf("⚠️ Ungültige Kategorie '%s', verwende 'abzuarbeiten'", category)
-			category = "abzuarbeiten"
-		}
-
-		log.Printf("✅ E-Mail klassifiziert: %s → %s", req.Subject, category)
+		log.Printf("✅ E-Mail klassifiziert: %s → %s", req.Subject, result.Category)
 
 		// Antwort verschlüsselt senden
-		c.sendClassifyResponse(req.MessageID, req.AccountEmail, category, 0.85, "")
+		c.sendClassifyResponse(req.MessageID, req.AccountEmail, result.Category, 0.85, result.Reason)
 	}()
 }
 
@@ -1443,7 +1496,7 @@ Schreibe nur den Text der Antwort, ohne Anrede-Formeln wie "Sehr geehrte/r" - di
 
 		sessionID := fmt.Sprintf("reply-%s-%s", c.MateID, req.MessageID)
 
-		response, err := c.Server.chatHandler.Chat(sessionID, prompt, nil)
+		response, err := c.backgroundChat(sessionID, prompt)
 		if err != nil {
 			log.Printf("❌ LLM-Fehler bei Antwort-Generierung: %v", err)
 			return
@@ -1476,30 +1529,48 @@ Von: %s
 Betreff: %s
 Vorschau: %s
 
-Antworte NUR mit "JA" wenn die E-Mail eine Terminanfrage, Meeting-Anfrage oder Besprechungsanfrage enthält.
-Antworte NUR mit "NEIN" wenn nicht.`, req.From, req.Subject, req.Preview)
+Setze isAppointment auf true wenn die E-Mail eine Terminanfrage, Meeting-Anfrage oder Besprechungsanfrage enthält.
+Falls ja: topic = Thema des Termins, proposedTime = vorgeschlagener Zeitpunkt (leer wenn keiner genannt).`, req.From, req.Subject, req.Preview)
+
+	schema := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"isAppointment": map[string]interface{}{"type": "boolean"},
+			"proposedTime":  map[string]interface{}{"type": "string", "maxLength": 100},
+			"topic":         map[string]interface{}{"type": "string", "maxLength": 200},
+		},
+		"required":             []string{"isAppointment", "proposedTime", "topic"},
+		"additionalProperties": false,
+	}
 
 	go func() {
 		if c.Server.chatHandler == nil {
 			return
 		}
 
-		sessionID := fmt.Sprintf("appointment-%s-%s", c.MateID, req.MessageID)
-
-		response, err := c.Server.chatHandler.Chat(sessionID, prompt, nil)
-		if err != nil {
+		var result struct {
+			IsAppointment bool   `json:"isAppointment"`
+			ProposedTime  string `json:"proposedTime"`
+			Topic         string `json:"topic"`
+		}
+		if err := c.structuredChat(prompt, schema, &result); err != nil {
 			log.Printf("❌ LLM-Fehler bei Terminprüfung: %v", err)
 			return
 		}
 
-		isAppointment := strings.Contains(strings.ToUpper(response), "JA")
-
-		if isAppointment {
+		if result.IsAppointment {
 			log.Printf("📅 Terminanfrage erkannt in: %s", req.Subject)
+			details := map[string]interface{}{}
+			if result.Topic != "" {
+				details["topic"] = result.Topic
+			}
+			if result.ProposedTime != "" {
+				details["proposedTime"] = result.ProposedTime
+			}
 			c.sendEncryptedMessage("appointment_request_detected", map[string]interface{}{
 				"messageId":      req.MessageID,
 				"senderEmail":    req.From,
-				"requestDetails": map[string]interface{}{},
+				"requestDetails": details,
 			})
 		}
 	}()
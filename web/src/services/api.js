@@ -79,8 +79,28 @@ export default {
     return response.data
   },
 
-  async getAllChats() {
-    const response = await api.get('/chat/all')
+  async getAllChats(tags = [], matchAll = false) {
+    const params = {}
+    if (tags.length > 0) {
+      params.tags = tags.join(',')
+      if (matchAll) params.match = 'all'
+    }
+    const response = await api.get('/chat/all', { params })
+    return response.data
+  },
+
+  async getAllTags() {
+    const response = await api.get('/chat/tags')
+    return response.data
+  },
+
+  async setChatTags(chatId, tags) {
+    const response = await api.put(`/chat/${chatId}/tags`, { tags })
+    return response.data
+  },
+
+  async removeChatTag(chatId, tagName) {
+    const response = await api.delete(`/chat/${chatId}/tags/${encodeURIComponent(tagName)}`)
     return response.data
   },
 
@@ -266,7 +286,7 @@ export default {
 
   // Abort request
   async abortRequest(requestId) {
-    const response = await api.post(`/chat/abort/${requestId}`)
+    const response = await api.post(`/chat/cancel/${requestId}`)
     return response.data
   },
 
@@ -271,6 +271,10 @@ export const useChatStore = defineStore('chat', () => {
               console.error('[Cache-Sync] Failed to sync:', e)
             }
           }, 500)
+          // Title and tags are generated in the background after the first exchange
+          if (messages.value.length <= 2) {
+            setTimeout(() => loadChats(), 8000)
+          }
         }
       },
       onDelegation: (expertId, expertName) => {
@@ -504,7 +504,8 @@ export const useStreamingStore = defineStore('streaming', () => {
         isStreaming: false,
         isDocumentRequest: streamingMessage.isDocumentRequest || false,
         documentType: streamingMessage.documentType || null,
-        downloadUrl: parsed.downloadUrl || null
+        downloadUrl: parsed.downloadUrl || null,
+        interrupted: parsed.interrupted || false
       }
 
       // Update context usage